@@ -0,0 +1,368 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"text/template"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+
+	"github.com/intel/tfortools"
+)
+
+var backupCommand = &command{
+	SubCommands: map[string]subCommand{
+		"create":  new(backupCreateCommand),
+		"list":    new(backupListCommand),
+		"show":    new(backupShowCommand),
+		"delete":  new(backupDeleteCommand),
+		"restore": new(backupRestoreCommand),
+	},
+}
+
+type backupCreateCommand struct {
+	Flag        flag.FlagSet
+	volume      string
+	name        string
+	description string
+}
+
+func (cmd *backupCreateCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] backup create [flags]
+
+Create a backup of a volume on the configured backup target
+
+The create flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *backupCreateCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Volume UUID")
+	cmd.Flag.StringVar(&cmd.name, "name", "", "Backup name")
+	cmd.Flag.StringVar(&cmd.description, "description", "", "Backup description")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *backupCreateCommand) run(args []string) error {
+	if cmd.volume == "" {
+		errorf("missing required -volume parameter")
+		cmd.usage()
+	}
+
+	createReq := api.RequestedBackup{
+		Name:        cmd.name,
+		Description: cmd.description,
+	}
+
+	b, err := json.Marshal(createReq)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	body := bytes.NewReader(b)
+	url := buildCiaoURL("%s/volumes/%s/backups", *tenantID, cmd.volume)
+	resp, err := sendCiaoRequest("POST", url, nil, body, api.BackupsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusAccepted {
+		fatalf("Backup creation failed: %s", resp.Status)
+	}
+
+	var backup types.Backup
+	err = unmarshalHTTPResponse(resp, &backup)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	fmt.Printf("Creating new backup: %s\n", backup.ID)
+
+	return err
+}
+
+type backupListCommand struct {
+	Flag     flag.FlagSet
+	template string
+}
+
+func (cmd *backupListCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] backup list
+
+List all backups
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, `
+The template passed to the -f option operates on a 
+
+%s`, tfortools.GenerateUsageUndecorated([]types.Backup{}))
+	fmt.Fprintln(os.Stderr, tfortools.TemplateFunctionHelp(nil))
+	os.Exit(2)
+}
+
+func (cmd *backupListCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+type backupsByCreateTime []types.Backup
+
+func (ss backupsByCreateTime) Len() int      { return len(ss) }
+func (ss backupsByCreateTime) Swap(i, j int) { ss[i], ss[j] = ss[j], ss[i] }
+func (ss backupsByCreateTime) Less(i, j int) bool {
+	return ss[i].CreateTime.Before(ss[j].CreateTime)
+}
+
+func (cmd *backupListCommand) run(args []string) error {
+	var t *template.Template
+	var err error
+	if cmd.template != "" {
+		t, err = tfortools.CreateTemplate("backup-list", cmd.template, nil)
+		if err != nil {
+			fatalf(err.Error())
+		}
+	}
+
+	url := buildCiaoURL("%s/backups", *tenantID)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.BackupsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Backup list failed: %s", resp.Status)
+	}
+
+	var backups []types.Backup
+
+	err = unmarshalHTTPResponse(resp, &backups)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	sort.Sort(backupsByCreateTime(backups))
+
+	if t != nil {
+		if err = t.Execute(os.Stdout, &backups); err != nil {
+			fatalf(err.Error())
+		}
+		return nil
+	}
+
+	for i, b := range backups {
+		fmt.Printf("Backup #%d\n", i+1)
+		dumpBackup(&b)
+		fmt.Printf("\n")
+	}
+
+	return err
+}
+
+type backupShowCommand struct {
+	Flag     flag.FlagSet
+	backup   string
+	template string
+}
+
+func (cmd *backupShowCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] backup show [flags]
+
+Show information about a backup
+
+The show flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", types.Backup{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *backupShowCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.backup, "backup", "", "Backup UUID")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *backupShowCommand) run(args []string) error {
+	if cmd.backup == "" {
+		errorf("missing required -backup parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/backups/%s", *tenantID, cmd.backup)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.BackupsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Backup show failed: %s", resp.Status)
+	}
+
+	var backup types.Backup
+
+	err = unmarshalHTTPResponse(resp, &backup)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "backup-show", cmd.template,
+			&backup, nil)
+	}
+
+	dumpBackup(&backup)
+	return nil
+}
+
+type backupDeleteCommand struct {
+	Flag   flag.FlagSet
+	backup string
+}
+
+func (cmd *backupDeleteCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] backup delete [flags]
+
+Deletes a backup
+
+The delete flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *backupDeleteCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.backup, "backup", "", "Backup UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *backupDeleteCommand) run(args []string) error {
+	if cmd.backup == "" {
+		errorf("missing required -backup parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/backups/%s", *tenantID, cmd.backup)
+	resp, err := sendCiaoRequest("DELETE", url, nil, nil, api.BackupsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusAccepted {
+		fatalf("Backup delete failed: %s", resp.Status)
+	}
+
+	return err
+}
+
+type backupRestoreCommand struct {
+	Flag        flag.FlagSet
+	backup      string
+	name        string
+	description string
+}
+
+func (cmd *backupRestoreCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] backup restore [flags]
+
+Restores a backup into a new volume
+
+The restore flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *backupRestoreCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.backup, "backup", "", "Backup UUID")
+	cmd.Flag.StringVar(&cmd.name, "name", "", "Name of the new volume")
+	cmd.Flag.StringVar(&cmd.description, "description", "", "Description of the new volume")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *backupRestoreCommand) run(args []string) error {
+	if cmd.backup == "" {
+		errorf("missing required -backup parameter")
+		cmd.usage()
+	}
+
+	restoreReq := api.RequestedRestore{
+		Name:        cmd.name,
+		Description: cmd.description,
+	}
+
+	b, err := json.Marshal(restoreReq)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	body := bytes.NewReader(b)
+	url := buildCiaoURL("%s/backups/%s/restore", *tenantID, cmd.backup)
+	resp, err := sendCiaoRequest("POST", url, nil, body, api.BackupsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusAccepted {
+		fatalf("Backup restore failed: %s", resp.Status)
+	}
+
+	var vol types.Volume
+	err = unmarshalHTTPResponse(resp, &vol)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	fmt.Printf("Restoring backup to new volume: %s\n", vol.ID)
+
+	return err
+}
+
+func dumpBackup(b *types.Backup) {
+	fmt.Printf("\tName             [%s]\n", b.Name)
+	fmt.Printf("\tUUID             [%s]\n", b.ID)
+	fmt.Printf("\tVolume           [%s]\n", b.VolumeID)
+	fmt.Printf("\tVolume Size      [%d GB]\n", b.VolumeSize)
+	fmt.Printf("\tState            [%s]\n", b.State)
+	fmt.Printf("\tCreated          [%s]\n", b.CreateTime)
+	fmt.Printf("\tDescription      [%s]\n", b.Description)
+	fmt.Printf("\tSize             [%d bytes]\n", b.Size)
+	fmt.Printf("\tChecksum         [%s]\n", b.Checksum)
+}
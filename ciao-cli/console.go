@@ -0,0 +1,155 @@
+//
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// consoleEscape is the character, Ctrl-], that detaches the terminal from
+// the console of an instance.
+const consoleEscape = 0x1d
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// makeRaw puts the terminal connected to stdin into raw mode and returns
+// a function that restores its original settings.  Nothing is done if
+// stdin is not a terminal.
+func makeRaw() (func(), error) {
+	fi, err := os.Stdin.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return func() {}, nil
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read terminal settings: %v", err)
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("Unable to set terminal to raw mode: %v", err)
+	}
+
+	return func() { _, _ = stty(strings.TrimSpace(saved)) }, nil
+}
+
+func readInput(inputCh chan<- []byte) {
+	buf := make([]byte, 1024)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			data := make([]byte, n)
+			copy(data, buf[:n])
+			inputCh <- data
+		}
+		if err != nil {
+			close(inputCh)
+			return
+		}
+	}
+}
+
+// attachConsole connects to the console WebSocket at url and copies data
+// between it and the terminal until the console is closed or the user
+// types consoleEscape.
+func attachConsole(url string) error {
+	dialer := websocket.Dialer{TLSClientConfig: clientTLSConfig()}
+	ws, resp, err := dialer.Dial(url, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+			body, _ := ioutil.ReadAll(resp.Body)
+			return fmt.Errorf("HTTP Error [%d] for console: %s", resp.StatusCode, body)
+		}
+		return fmt.Errorf("Unable to connect to console: %v", err)
+	}
+	defer func() { _ = ws.Close() }()
+
+	restore, err := makeRaw()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	fmt.Fprintf(os.Stderr, "Connected to console.  Type Ctrl-] to detach.\r\n")
+
+	doneCh := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					err = nil
+				}
+				doneCh <- err
+				return
+			}
+			if _, err := os.Stdout.Write(data); err != nil {
+				doneCh <- err
+				return
+			}
+		}
+	}()
+
+	inputCh := make(chan []byte)
+	go readInput(inputCh)
+
+	for {
+		select {
+		case err := <-doneCh:
+			fmt.Fprintf(os.Stderr, "\r\nConsole closed.\r\n")
+			return err
+		case data, ok := <-inputCh:
+			if !ok {
+				// Keep displaying the output of the console
+				// once all the input has been sent.
+				inputCh = nil
+				continue
+			}
+
+			i := bytes.IndexByte(data, consoleEscape)
+			if i != -1 {
+				data = data[:i]
+			}
+
+			if len(data) > 0 {
+				if err := ws.WriteMessage(websocket.BinaryMessage, data); err != nil {
+					return err
+				}
+			}
+
+			if i != -1 {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				_ = ws.WriteMessage(websocket.CloseMessage, msg)
+				fmt.Fprintf(os.Stderr, "\r\nDetached from console.\r\n")
+				return nil
+			}
+		}
+	}
+}
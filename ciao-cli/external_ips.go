@@ -756,7 +756,7 @@ The remove flags are:
 func (cmd *poolRemoveCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.name, "name", "", "Name of pool")
 	cmd.Flag.StringVar(&cmd.subnet, "subnet", "", "Subnet in CIDR format")
-	cmd.Flag.StringVar(&cmd.ip, "ip", "", "IPv4 Address")
+	cmd.Flag.StringVar(&cmd.ip, "ip", "", "IP Address")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -809,12 +809,22 @@ func (cmd *poolRemoveCommand) run(args []string) error {
 
 	var url string
 
+	// pools store addresses in their canonical form, which matters
+	// for IPv6 addresses as they have many textual representations.
 	if cmd.subnet != "" {
-		url = getSubnetRef(pool, cmd.subnet)
+		_, ipNet, err := net.ParseCIDR(cmd.subnet)
+		if err != nil {
+			fatalf("Invalid subnet %s: %v", cmd.subnet, err)
+		}
+		url = getSubnetRef(pool, ipNet.String())
 	}
 
 	if cmd.ip != "" {
-		url = getIPRef(pool, cmd.ip)
+		ip := net.ParseIP(cmd.ip)
+		if ip == nil {
+			fatalf("Invalid IP address %s", cmd.ip)
+		}
+		url = getIPRef(pool, ip.String())
 	}
 
 	if url == "" {
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"regexp"
@@ -39,16 +40,44 @@ const (
 	osStart  = "os-start"
 	osStop   = "os-stop"
 	osDelete = "os-delete"
+
+	osPause   = "pause"
+	osUnpause = "unpause"
+	osSuspend = "suspend"
+	osResume  = "resume"
 )
 
 var instanceCommand = &command{
 	SubCommands: map[string]subCommand{
-		"add":     new(instanceAddCommand),
-		"delete":  new(instanceDeleteCommand),
-		"list":    new(instanceListCommand),
-		"show":    new(instanceShowCommand),
-		"restart": new(instanceRestartCommand),
-		"stop":    new(instanceStopCommand),
+		"add":         new(instanceAddCommand),
+		"delete":      new(instanceDeleteCommand),
+		"list":        new(instanceListCommand),
+		"show":        new(instanceShowCommand),
+		"restart":     new(instanceRestartCommand),
+		"stop":        new(instanceStopCommand),
+		"console-log": new(instanceConsoleLogCommand),
+		"console":     new(instanceConsoleCommand),
+		"metadata":    new(instanceMetadataCommand),
+		"pause": &instanceActionCommand{
+			action:      osPause,
+			description: "Freeze the execution of a running Ciao instance",
+			done:        "paused",
+		},
+		"unpause": &instanceActionCommand{
+			action:      osUnpause,
+			description: "Continue the execution of a paused Ciao instance",
+			done:        "unpaused",
+		},
+		"suspend": &instanceActionCommand{
+			action:      osSuspend,
+			description: "Save the state of a running Ciao instance to disk and shut it down",
+			done:        "suspended",
+		},
+		"resume": &instanceActionCommand{
+			action:      osResume,
+			description: "Restore a suspended Ciao instance",
+			done:        "resumed",
+		},
 	},
 }
 
@@ -376,14 +405,59 @@ func (v *volumeFlagSlice) Set(value string) error {
 	return nil
 }
 
+// metadataFlag collects the key=value pairs passed in repeated -metadata
+// flags, implementing the flag.Value interface.
+type metadataFlag map[string]string
+
+func (m *metadataFlag) String() string {
+	var pairs []string
+	for k, v := range *m {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (m *metadataFlag) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("Invalid metadata %q, expected key=value", value)
+	}
+	if *m == nil {
+		*m = make(map[string]string)
+	}
+	(*m)[kv[0]] = kv[1]
+	return nil
+}
+
+// networkFlagSlice collects the network UUIDs passed in repeated
+// -additional-network flags, implementing the flag.Value interface.
+type networkFlagSlice []string
+
+func (n *networkFlagSlice) String() string {
+	return strings.Join(*n, ",")
+}
+
+func (n *networkFlagSlice) Set(value string) error {
+	if value == "" {
+		return fmt.Errorf("Invalid empty network UUID")
+	}
+	*n = append(*n, value)
+	return nil
+}
+
 type instanceAddCommand struct {
-	Flag      flag.FlagSet
-	workload  string
-	instances int
-	label     string
-	volumes   volumeFlagSlice
-	name      string
-	template  string
+	Flag               flag.FlagSet
+	workload           string
+	instances          int
+	label              string
+	volumes            volumeFlagSlice
+	metadata           metadataFlag
+	name               string
+	network            string
+	additionalNetworks networkFlagSlice
+	ip                 string
+	retainIP           bool
+	template           string
 }
 
 func (cmd *instanceAddCommand) usage(...string) {
@@ -405,7 +479,12 @@ func (cmd *instanceAddCommand) parseArgs(args []string) []string {
 	cmd.Flag.IntVar(&cmd.instances, "instances", 1, "Number of instances to create")
 	cmd.Flag.StringVar(&cmd.label, "label", "", "Set a frame label. This will trigger frame tracing")
 	cmd.Flag.Var(&cmd.volumes, "volume", "volume descriptor argument list")
+	cmd.Flag.Var(&cmd.metadata, "metadata", "key=value metadata available to the workload's config template. May be repeated")
 	cmd.Flag.StringVar(&cmd.name, "name", "", "Name for this instance. When multiple instances are requested this is used as a prefix")
+	cmd.Flag.StringVar(&cmd.network, "network", "", "UUID of the tenant network to attach the instance to")
+	cmd.Flag.Var(&cmd.additionalNetworks, "additional-network", "UUID of a tenant network to attach an additional NIC to. May be repeated")
+	cmd.Flag.StringVar(&cmd.ip, "ip", "", "Fixed IP address to assign to the instance")
+	cmd.Flag.BoolVar(&cmd.retainIP, "retain-ip", false, "Keep the IP address reserved once the instance is deleted")
 	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
@@ -428,6 +507,11 @@ func (cmd *instanceAddCommand) validateAddCommandArgs() {
 		cmd.usage()
 	}
 
+	if cmd.ip != "" && cmd.instances > 1 {
+		errorf("-ip cannot be used when launching more than one instance")
+		cmd.usage()
+	}
+
 	if cmd.name != "" {
 		r := regexp.MustCompile("^[a-z0-9-]{1,64}?$")
 		if !r.MatchString(cmd.name) {
@@ -462,8 +546,17 @@ func validateCreateServerRequest(server api.CreateServerRequest) error {
 }
 
 func populateCreateServerRequest(cmd *instanceAddCommand, server *api.CreateServerRequest) {
-	if cmd.label != "" {
+	if len(cmd.metadata) > 0 {
 		server.Server.Metadata = make(map[string]string)
+		for k, v := range cmd.metadata {
+			server.Server.Metadata[k] = v
+		}
+	}
+
+	if cmd.label != "" {
+		if server.Server.Metadata == nil {
+			server.Server.Metadata = make(map[string]string)
+		}
 		server.Server.Metadata["label"] = cmd.label
 	}
 
@@ -471,6 +564,10 @@ func populateCreateServerRequest(cmd *instanceAddCommand, server *api.CreateServ
 	server.Server.MaxInstances = cmd.instances
 	server.Server.MinInstances = 1
 	server.Server.Name = cmd.name
+	server.Server.NetworkID = cmd.network
+	server.Server.AdditionalNetworks = cmd.additionalNetworks
+	server.Server.IPAddress = cmd.ip
+	server.Server.RetainIP = cmd.retainIP
 
 	for _, volume := range cmd.volumes {
 		bd := api.BlockDeviceMapping{
@@ -684,12 +781,23 @@ func startStopInstance(instance string, stop bool) error {
 		return errors.New("Missing required -instance parameter")
 	}
 
-	actionBytes := []byte(osStart)
+	action := osStart
 	if stop == true {
-		actionBytes = []byte(osStop)
+		action = osStop
 	}
 
-	body := bytes.NewReader(actionBytes)
+	sendInstanceAction(instance, action)
+
+	if stop == true {
+		fmt.Printf("Instance %s stopped\n", instance)
+	} else {
+		fmt.Printf("Instance %s restarted\n", instance)
+	}
+	return nil
+}
+
+func sendInstanceAction(instance string, action string) {
+	body := bytes.NewReader([]byte(action))
 
 	url := buildCiaoURL("%s/instances/%s/action", *tenantID, instance)
 
@@ -701,12 +809,50 @@ func startStopInstance(instance string, stop bool) error {
 	if resp.StatusCode != http.StatusAccepted {
 		fatalf("Instance action failed: %s", resp.Status)
 	}
+}
 
-	if stop == true {
-		fmt.Printf("Instance %s stopped\n", instance)
-	} else {
-		fmt.Printf("Instance %s restarted\n", instance)
+// instanceActionCommand implements the subcommands that change the power
+// state of an instance without stopping it.
+type instanceActionCommand struct {
+	Flag        flag.FlagSet
+	instance    string
+	action      string
+	description string
+	done        string
+}
+
+func (cmd *instanceActionCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance %s [flags]
+
+%s
+
+The %s flags are:
+
+`, cmd.action, cmd.description, cmd.action)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceActionCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceActionCommand) run([]string) error {
+	if *tenantID == "" {
+		return errors.New("Missing required -tenant-id parameter")
+	}
+
+	if cmd.instance == "" {
+		cmd.usage()
+		return errors.New("Missing required -instance parameter")
 	}
+
+	sendInstanceAction(cmd.instance, cmd.action)
+
+	fmt.Printf("Instance %s %s\n", cmd.instance, cmd.done)
 	return nil
 }
 
@@ -873,13 +1019,233 @@ func (cmd *instanceShowCommand) run(args []string) error {
 	return nil
 }
 
+type instanceConsoleLogCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	lines    int
+}
+
+func (cmd *instanceConsoleLogCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance console-log [flags]
+
+Print the serial console log of an instance
+
+The console-log flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceConsoleLogCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.IntVar(&cmd.lines, "lines", 0, "Number of lines to print from the end of the log, 0 for the entire log")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceConsoleLogCommand) run(args []string) error {
+	if cmd.instance == "" {
+		errorf("Missing required -instance parameter")
+		cmd.usage()
+	}
+
+	if cmd.lines < 0 {
+		errorf("The -lines parameter cannot be negative")
+		cmd.usage()
+	}
+
+	var values []queryValue
+	if cmd.lines > 0 {
+		values = append(values, queryValue{
+			name:  "lines",
+			value: strconv.Itoa(cmd.lines),
+		})
+	}
+
+	var log api.ConsoleLog
+	url := buildCiaoURL("%s/instances/%s/console-log", *tenantID, cmd.instance)
+
+	resp, err := sendCiaoRequest("GET", url, values, nil, api.InstancesV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	err = unmarshalHTTPResponse(resp, &log)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	fmt.Print(log.Log)
+	return nil
+}
+
+type instanceConsoleCommand struct {
+	Flag     flag.FlagSet
+	instance string
+}
+
+func (cmd *instanceConsoleCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance console [flags]
+
+Attach the terminal to the serial console of an instance.  Containers are
+attached to their standard input and output.  Type Ctrl-] to detach.
+
+The console flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *instanceConsoleCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceConsoleCommand) run(args []string) error {
+	if cmd.instance == "" {
+		errorf("Missing required -instance parameter")
+		cmd.usage()
+	}
+
+	var token api.ConsoleToken
+	url := buildCiaoURL("%s/instances/%s/console", *tenantID, cmd.instance)
+
+	resp, err := sendCiaoRequest("POST", url, nil, nil, api.InstancesV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	err = unmarshalHTTPResponse(resp, &token)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	url = fmt.Sprintf("wss://%s:%d/%s/instances/%s/console?token=%s",
+		*controllerURL, *ciaoPort, *tenantID, cmd.instance, token.Token)
+	err = attachConsole(url)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	return nil
+}
+
+type instanceMetadataCommand struct {
+	Flag     flag.FlagSet
+	instance string
+	metadata metadataFlag
+	clear    bool
+	template string
+}
+
+func (cmd *instanceMetadataCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] instance metadata [flags]
+
+Show or replace the key/value metadata served to an instance by the
+metadata service
+
+The metadata flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", api.ServerMetadata{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *instanceMetadataCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
+	cmd.Flag.Var(&cmd.metadata, "metadata", "key=value metadata replacing the metadata of the instance. May be repeated")
+	cmd.Flag.BoolVar(&cmd.clear, "clear", false, "Remove all the metadata of the instance")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *instanceMetadataCommand) run(args []string) error {
+	if cmd.instance == "" {
+		errorf("Missing required -instance parameter")
+		cmd.usage()
+	}
+
+	if cmd.clear && len(cmd.metadata) > 0 {
+		errorf("The -clear and -metadata parameters are mutually exclusive")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/instances/%s/metadata", *tenantID, cmd.instance)
+
+	method := "GET"
+	var body io.Reader
+	if cmd.clear || len(cmd.metadata) > 0 {
+		req := api.ServerMetadata{Metadata: map[string]string(cmd.metadata)}
+		if req.Metadata == nil {
+			req.Metadata = make(map[string]string)
+		}
+
+		b, err := json.Marshal(req)
+		if err != nil {
+			fatalf(err.Error())
+		}
+		method = "PUT"
+		body = bytes.NewReader(b)
+	}
+
+	resp, err := sendCiaoRequest(method, url, nil, body, api.InstancesV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	var md api.ServerMetadata
+	err = unmarshalHTTPResponse(resp, &md)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "instance-metadata", cmd.template,
+			&md, nil)
+	}
+
+	dumpMetadata(md.Metadata)
+	return nil
+}
+
+func dumpMetadata(metadata map[string]string) {
+	var keys []string
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Printf("\tMetadata: %s=%s\n", k, metadata[k])
+	}
+}
+
 func dumpInstance(server *api.ServerDetails) {
 	fmt.Printf("\tUUID: %s\n", server.ID)
 	fmt.Printf("\tStatus: %s\n", server.Status)
 	fmt.Printf("\tPrivate IP: %s\n", server.PrivateAddresses[0].Addr)
 	fmt.Printf("\tMAC Address: %s\n", server.PrivateAddresses[0].MacAddr)
+	if server.PrivateAddresses[0].IPv6Addr != "" {
+		fmt.Printf("\tPrivate IPv6: %s\n", server.PrivateAddresses[0].IPv6Addr)
+	}
 	fmt.Printf("\tCN UUID: %s\n", server.NodeID)
 	fmt.Printf("\tTenant UUID: %s\n", server.TenantID)
+	if server.NetworkID != "" {
+		fmt.Printf("\tNetwork UUID: %s\n", server.NetworkID)
+	}
+	if server.RetainIP {
+		fmt.Printf("\tRetain IP: %v\n", server.RetainIP)
+	}
+	for _, addr := range server.PrivateAddresses[1:] {
+		fmt.Printf("\tAdditional NIC: %s %s [%s]\n", addr.Addr, addr.MacAddr,
+			addr.NetworkID)
+	}
 	if server.SSHIP != "" {
 		fmt.Printf("\tSSH IP: %s\n", server.SSHIP)
 		fmt.Printf("\tSSH Port: %d\n", server.SSHPort)
@@ -888,6 +1254,32 @@ func dumpInstance(server *api.ServerDetails) {
 	for _, vol := range server.Volumes {
 		fmt.Printf("\tVolume: %s\n", vol)
 	}
+
+	dumpMetadata(server.Metadata)
+
+	if server.Guest != nil {
+		fmt.Printf("\tGuest OS: %s\n", server.Guest.OSName)
+		fmt.Printf("\tGuest Kernel: %s\n", server.Guest.KernelRelease)
+		for _, ip := range server.Guest.IPAddresses {
+			fmt.Printf("\tGuest IP: %s\n", ip)
+		}
+	}
+
+	if server.BlockIO != nil {
+		fmt.Printf("\tDisk Read: %d bytes, %d ops\n",
+			server.BlockIO.ReadBytes, server.BlockIO.ReadOps)
+		fmt.Printf("\tDisk Written: %d bytes, %d ops\n",
+			server.BlockIO.WriteBytes, server.BlockIO.WriteOps)
+	}
+
+	if server.NetworkIO != nil {
+		fmt.Printf("\tNetwork Received: %d bytes, %d packets, %d dropped\n",
+			server.NetworkIO.RxBytes, server.NetworkIO.RxPackets,
+			server.NetworkIO.RxDropped)
+		fmt.Printf("\tNetwork Transmitted: %d bytes, %d packets, %d dropped\n",
+			server.NetworkIO.TxBytes, server.NetworkIO.TxPackets,
+			server.NetworkIO.TxDropped)
+	}
 }
 
 func listNodeInstances(node string) error {
@@ -147,3 +147,21 @@ func TestVolumeSubArgSet(t *testing.T) {
 		}
 	}
 }
+
+func TestMetadataFlagSet(t *testing.T) {
+	var m metadataFlag
+	for _, arg := range []string{"role=db", "empty=", "url=http://host/?a=b"} {
+		if err := m.Set(arg); err != nil {
+			t.Errorf("valid metadata incorrectly refused: \"%s\"", arg)
+		}
+	}
+	if m["role"] != "db" || m["empty"] != "" || m["url"] != "http://host/?a=b" {
+		t.Errorf("unexpected metadata %v", m)
+	}
+
+	for _, arg := range []string{"", "role", "=db"} {
+		if err := m.Set(arg); err == nil {
+			t.Errorf("invalid metadata incorrectly accepted: \"%s\"", arg)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+
+	"github.com/intel/tfortools"
+)
+
+var lbCommand = &command{
+	SubCommands: map[string]subCommand{
+		"create":  new(lbCreateCommand),
+		"list":    new(lbListCommand),
+		"show":    new(lbShowCommand),
+		"members": new(lbMembersCommand),
+		"delete":  new(lbDeleteCommand),
+	},
+}
+
+// memberFlagSlice collects the instance[:port] members passed in repeated
+// -member flags, implementing the flag.Value interface.
+type memberFlagSlice []api.RequestedLoadBalancerMember
+
+func (m *memberFlagSlice) String() string {
+	var members []string
+	for _, member := range *m {
+		s := member.InstanceID
+		if member.Port != 0 {
+			s += ":" + strconv.Itoa(member.Port)
+		}
+		members = append(members, s)
+	}
+	return strings.Join(members, ",")
+}
+
+func (m *memberFlagSlice) Set(value string) error {
+	member := api.RequestedLoadBalancerMember{}
+
+	parts := strings.SplitN(value, ":", 2)
+	if parts[0] == "" {
+		return fmt.Errorf("Invalid member %q, expected instance[:port]", value)
+	}
+	member.InstanceID = parts[0]
+
+	if len(parts) == 2 {
+		port, err := strconv.Atoi(parts[1])
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("Invalid member port %q", parts[1])
+		}
+		member.Port = port
+	}
+
+	*m = append(*m, member)
+	return nil
+}
+
+type lbCreateCommand struct {
+	Flag     flag.FlagSet
+	name     string
+	pool     string
+	port     int
+	protocol string
+	members  memberFlagSlice
+	interval int
+	timeout  int
+	retries  int
+	path     string
+}
+
+func (cmd *lbCreateCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] lb create [flags]
+
+Create a new load balancer.  The virtual IP of the load balancer is allocated
+from an external IP pool and traffic sent to its port is spread across the
+members passing the health check.  All the members must share a subnet.
+
+The create flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *lbCreateCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.name, "name", "", "Load balancer name")
+	cmd.Flag.StringVar(&cmd.pool, "pool", "", "Name of the pool to allocate the virtual IP from")
+	cmd.Flag.IntVar(&cmd.port, "port", 0, "Listener port")
+	cmd.Flag.StringVar(&cmd.protocol, "protocol", "tcp", "Protocol of the load balancer, tcp or http")
+	cmd.Flag.Var(&cmd.members, "member", "Member instance UUID with an optional port, instance[:port] (may be repeated)")
+	cmd.Flag.IntVar(&cmd.interval, "check-interval", 0, "Seconds between two health checks of a member")
+	cmd.Flag.IntVar(&cmd.timeout, "check-timeout", 0, "Seconds a member has to answer a health check")
+	cmd.Flag.IntVar(&cmd.retries, "check-retries", 0, "Health checks to fail or pass before a member changes state")
+	cmd.Flag.StringVar(&cmd.path, "check-path", "", "URI requested by the health checks of http load balancers")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *lbCreateCommand) run(args []string) error {
+	if cmd.port == 0 {
+		errorf("missing required -port parameter")
+		cmd.usage()
+	}
+
+	if len(cmd.members) == 0 {
+		errorf("missing required -member parameter")
+		cmd.usage()
+	}
+
+	createReq := api.RequestedLoadBalancer{
+		Name:     cmd.name,
+		Port:     cmd.port,
+		Protocol: cmd.protocol,
+		Members:  cmd.members,
+		HealthCheck: &types.LoadBalancerHealthCheck{
+			Interval: cmd.interval,
+			Timeout:  cmd.timeout,
+			Retries:  cmd.retries,
+			Path:     cmd.path,
+		},
+	}
+
+	if cmd.pool != "" {
+		createReq.PoolName = &cmd.pool
+	}
+
+	b, err := json.Marshal(createReq)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	body := bytes.NewReader(b)
+	url := buildCiaoURL("%s/load-balancers", *tenantID)
+	resp, err := sendCiaoRequest("POST", url, nil, body, api.LoadBalancersV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusCreated {
+		fatalf("Load balancer creation failed: %s", resp.Status)
+	}
+
+	var lb types.LoadBalancer
+	err = unmarshalHTTPResponse(resp, &lb)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	fmt.Printf("Created new load balancer: %s (%s)\n", lb.ID, lb.VIP)
+
+	return err
+}
+
+type lbListCommand struct {
+	Flag     flag.FlagSet
+	template string
+}
+
+func (cmd *lbListCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] lb list
+
+List all load balancers
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, `
+The template passed to the -f option operates on a
+
+%s`, tfortools.GenerateUsageUndecorated([]types.LoadBalancer{}))
+	fmt.Fprintln(os.Stderr, tfortools.TemplateFunctionHelp(nil))
+	os.Exit(2)
+}
+
+func (cmd *lbListCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+type lbsByCreateTime []types.LoadBalancer
+
+func (ss lbsByCreateTime) Len() int      { return len(ss) }
+func (ss lbsByCreateTime) Swap(i, j int) { ss[i], ss[j] = ss[j], ss[i] }
+func (ss lbsByCreateTime) Less(i, j int) bool {
+	return ss[i].CreateTime.Before(ss[j].CreateTime)
+}
+
+func (cmd *lbListCommand) run(args []string) error {
+	var t *template.Template
+	var err error
+	if cmd.template != "" {
+		t, err = tfortools.CreateTemplate("lb-list", cmd.template, nil)
+		if err != nil {
+			fatalf(err.Error())
+		}
+	}
+
+	url := buildCiaoURL("%s/load-balancers", *tenantID)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.LoadBalancersV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Load balancer list failed: %s", resp.Status)
+	}
+
+	var lbs []types.LoadBalancer
+
+	err = unmarshalHTTPResponse(resp, &lbs)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	sort.Sort(lbsByCreateTime(lbs))
+
+	if t != nil {
+		if err = t.Execute(os.Stdout, &lbs); err != nil {
+			fatalf(err.Error())
+		}
+		return nil
+	}
+
+	for i, lb := range lbs {
+		fmt.Printf("Load balancer #%d\n", i+1)
+		dumpLoadBalancer(&lb)
+		fmt.Printf("\n")
+	}
+
+	return err
+}
+
+type lbShowCommand struct {
+	Flag     flag.FlagSet
+	lb       string
+	template string
+}
+
+func (cmd *lbShowCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] lb show [flags]
+
+Show information about a load balancer
+
+The show flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", types.LoadBalancer{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *lbShowCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.lb, "lb", "", "Load balancer UUID")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *lbShowCommand) run(args []string) error {
+	if cmd.lb == "" {
+		errorf("missing required -lb parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/load-balancers/%s", *tenantID, cmd.lb)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.LoadBalancersV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Load balancer show failed: %s", resp.Status)
+	}
+
+	var lb types.LoadBalancer
+
+	err = unmarshalHTTPResponse(resp, &lb)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "lb-show", cmd.template,
+			&lb, nil)
+	}
+
+	dumpLoadBalancer(&lb)
+	return nil
+}
+
+type lbMembersCommand struct {
+	Flag    flag.FlagSet
+	lb      string
+	members memberFlagSlice
+}
+
+func (cmd *lbMembersCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] lb members [flags]
+
+Replaces the members of a load balancer.  The new members must be attached to
+the subnet of the load balancer.  Passing no -member flag removes all the
+members.
+
+The members flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *lbMembersCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.lb, "lb", "", "Load balancer UUID")
+	cmd.Flag.Var(&cmd.members, "member", "Member instance UUID with an optional port, instance[:port] (may be repeated)")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *lbMembersCommand) run(args []string) error {
+	if cmd.lb == "" {
+		errorf("missing required -lb parameter")
+		cmd.usage()
+	}
+
+	members := []api.RequestedLoadBalancerMember(cmd.members)
+	if members == nil {
+		members = []api.RequestedLoadBalancerMember{}
+	}
+
+	b, err := json.Marshal(members)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	body := bytes.NewReader(b)
+	url := buildCiaoURL("%s/load-balancers/%s/members", *tenantID, cmd.lb)
+	resp, err := sendCiaoRequest("PUT", url, nil, body, api.LoadBalancersV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Load balancer members update failed: %s", resp.Status)
+	}
+
+	return err
+}
+
+type lbDeleteCommand struct {
+	Flag flag.FlagSet
+	lb   string
+}
+
+func (cmd *lbDeleteCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] lb delete [flags]
+
+Deletes a load balancer and returns its virtual IP to its pool.
+
+The delete flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *lbDeleteCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.lb, "lb", "", "Load balancer UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *lbDeleteCommand) run(args []string) error {
+	if cmd.lb == "" {
+		errorf("missing required -lb parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/load-balancers/%s", *tenantID, cmd.lb)
+	resp, err := sendCiaoRequest("DELETE", url, nil, nil, api.LoadBalancersV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		fatalf("Load balancer delete failed: %s", resp.Status)
+	}
+
+	return err
+}
+
+func dumpLoadBalancer(lb *types.LoadBalancer) {
+	fmt.Printf("\tName         [%s]\n", lb.Name)
+	fmt.Printf("\tUUID         [%s]\n", lb.ID)
+	fmt.Printf("\tVirtual IP   [%s]\n", lb.VIP)
+	fmt.Printf("\tPool         [%s]\n", lb.PoolName)
+	fmt.Printf("\tSubnet       [%s]\n", lb.Subnet)
+	fmt.Printf("\tListener     [%s/%d]\n", lb.Protocol, lb.Port)
+	fmt.Printf("\tHealth Check [every %ds, timeout %ds, %d retries",
+		lb.HealthCheck.Interval, lb.HealthCheck.Timeout, lb.HealthCheck.Retries)
+	if lb.HealthCheck.Path != "" {
+		fmt.Printf(", path %s", lb.HealthCheck.Path)
+	}
+	fmt.Printf("]\n")
+	for _, m := range lb.Members {
+		fmt.Printf("\tMember       [%s %s:%d]\n", m.InstanceID, m.IPAddress, m.Port)
+	}
+	fmt.Printf("\tCreated      [%s]\n", lb.CreateTime)
+}
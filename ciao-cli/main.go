@@ -92,8 +92,13 @@ var commands = map[string]subCommand{
 	"trace":       traceCommand,
 	"image":       imageCommand,
 	"volume":      volumeCommand,
+	"backup":      backupCommand,
+	"network":     networkCommand,
 	"pool":        poolCommand,
 	"external-ip": externalIPCommand,
+	"reserved-ip": reservedIPCommand,
+	"lb":          lbCommand,
+	"peering":     peeringCommand,
 	"quotas":      quotasCommand,
 }
 
@@ -162,6 +167,21 @@ func buildCiaoURL(format string, args ...interface{}) string {
 	return fmt.Sprintf(prefix+format, args...)
 }
 
+func clientTLSConfig() *tls.Config {
+	tlsConfig := &tls.Config{}
+
+	if caCertPool != nil {
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if clientCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*clientCert}
+		tlsConfig.BuildNameToCertificate()
+	}
+
+	return tlsConfig
+}
+
 func sendHTTPRequestToken(method string, url string, values []queryValue, token string, body io.Reader, content string) (*http.Response, error) {
 	req, err := http.NewRequest(method, os.ExpandEnv(url), body)
 	if err != nil {
@@ -190,19 +210,8 @@ func sendHTTPRequestToken(method string, url string, values []queryValue, token
 		req.Header.Set("Accept", "application/json")
 	}
 
-	tlsConfig := &tls.Config{}
-
-	if caCertPool != nil {
-		tlsConfig.RootCAs = caCertPool
-	}
-
-	if clientCert != nil {
-		tlsConfig.Certificates = []tls.Certificate{*clientCert}
-		tlsConfig.BuildNameToCertificate()
-	}
-
 	transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig: clientTLSConfig(),
 	}
 
 	client := &http.Client{Transport: transport}
@@ -0,0 +1,318 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"text/template"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+
+	"github.com/intel/tfortools"
+)
+
+var networkCommand = &command{
+	SubCommands: map[string]subCommand{
+		"create": new(networkCreateCommand),
+		"list":   new(networkListCommand),
+		"show":   new(networkShowCommand),
+		"delete": new(networkDeleteCommand),
+	},
+}
+
+type networkCreateCommand struct {
+	Flag    flag.FlagSet
+	name    string
+	cidr    string
+	gateway string
+	start   string
+	end     string
+	v6cidr  string
+	v6mode  string
+}
+
+func (cmd *networkCreateCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] network create [flags]
+
+Create a new tenant network
+
+The create flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *networkCreateCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.name, "name", "", "Network name")
+	cmd.Flag.StringVar(&cmd.cidr, "cidr", "", "Subnet of the network, e.g., 10.10.0.0/24")
+	cmd.Flag.StringVar(&cmd.gateway, "gateway", "", "Default gateway (defaults to the first host address)")
+	cmd.Flag.StringVar(&cmd.start, "start", "", "First address assigned to instances")
+	cmd.Flag.StringVar(&cmd.end, "end", "", "Last address assigned to instances")
+	cmd.Flag.StringVar(&cmd.v6cidr, "ipv6-cidr", "", "IPv6 /64 prefix of a dual stack network (defaults to a generated ULA prefix)")
+	cmd.Flag.StringVar(&cmd.v6mode, "ipv6-mode", "", "IPv6 address assignment mode of a dual stack network, slaac or dhcpv6")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *networkCreateCommand) run(args []string) error {
+	if cmd.cidr == "" {
+		errorf("missing required -cidr parameter")
+		cmd.usage()
+	}
+
+	createReq := api.RequestedNetwork{
+		Name:            cmd.name,
+		CIDR:            cmd.cidr,
+		Gateway:         cmd.gateway,
+		AllocationStart: cmd.start,
+		AllocationEnd:   cmd.end,
+		IPv6CIDR:        cmd.v6cidr,
+		IPv6Mode:        cmd.v6mode,
+	}
+
+	b, err := json.Marshal(createReq)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	body := bytes.NewReader(b)
+	url := buildCiaoURL("%s/networks", *tenantID)
+	resp, err := sendCiaoRequest("POST", url, nil, body, api.NetworksV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusCreated {
+		fatalf("Network creation failed: %s", resp.Status)
+	}
+
+	var network types.TenantNetwork
+	err = unmarshalHTTPResponse(resp, &network)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	fmt.Printf("Created new network: %s\n", network.ID)
+
+	return err
+}
+
+type networkListCommand struct {
+	Flag     flag.FlagSet
+	template string
+}
+
+func (cmd *networkListCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] network list
+
+List all tenant networks
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, `
+The template passed to the -f option operates on a
+
+%s`, tfortools.GenerateUsageUndecorated([]types.TenantNetwork{}))
+	fmt.Fprintln(os.Stderr, tfortools.TemplateFunctionHelp(nil))
+	os.Exit(2)
+}
+
+func (cmd *networkListCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+type networksByCreateTime []types.TenantNetwork
+
+func (ss networksByCreateTime) Len() int      { return len(ss) }
+func (ss networksByCreateTime) Swap(i, j int) { ss[i], ss[j] = ss[j], ss[i] }
+func (ss networksByCreateTime) Less(i, j int) bool {
+	return ss[i].CreateTime.Before(ss[j].CreateTime)
+}
+
+func (cmd *networkListCommand) run(args []string) error {
+	var t *template.Template
+	var err error
+	if cmd.template != "" {
+		t, err = tfortools.CreateTemplate("network-list", cmd.template, nil)
+		if err != nil {
+			fatalf(err.Error())
+		}
+	}
+
+	url := buildCiaoURL("%s/networks", *tenantID)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.NetworksV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Network list failed: %s", resp.Status)
+	}
+
+	var networks []types.TenantNetwork
+
+	err = unmarshalHTTPResponse(resp, &networks)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	sort.Sort(networksByCreateTime(networks))
+
+	if t != nil {
+		if err = t.Execute(os.Stdout, &networks); err != nil {
+			fatalf(err.Error())
+		}
+		return nil
+	}
+
+	for i, n := range networks {
+		fmt.Printf("Network #%d\n", i+1)
+		dumpNetwork(&n)
+		fmt.Printf("\n")
+	}
+
+	return err
+}
+
+type networkShowCommand struct {
+	Flag     flag.FlagSet
+	network  string
+	template string
+}
+
+func (cmd *networkShowCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] network show [flags]
+
+Show information about a tenant network
+
+The show flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", types.TenantNetwork{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *networkShowCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.network, "network", "", "Network UUID")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *networkShowCommand) run(args []string) error {
+	if cmd.network == "" {
+		errorf("missing required -network parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/networks/%s", *tenantID, cmd.network)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.NetworksV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Network show failed: %s", resp.Status)
+	}
+
+	var network types.TenantNetwork
+
+	err = unmarshalHTTPResponse(resp, &network)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "network-show", cmd.template,
+			&network, nil)
+	}
+
+	dumpNetwork(&network)
+	return nil
+}
+
+type networkDeleteCommand struct {
+	Flag    flag.FlagSet
+	network string
+}
+
+func (cmd *networkDeleteCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] network delete [flags]
+
+Deletes a tenant network.  Networks with instances still attached cannot be
+deleted.
+
+The delete flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *networkDeleteCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.network, "network", "", "Network UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *networkDeleteCommand) run(args []string) error {
+	if cmd.network == "" {
+		errorf("missing required -network parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/networks/%s", *tenantID, cmd.network)
+	resp, err := sendCiaoRequest("DELETE", url, nil, nil, api.NetworksV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		fatalf("Network delete failed: %s", resp.Status)
+	}
+
+	return err
+}
+
+func dumpNetwork(n *types.TenantNetwork) {
+	fmt.Printf("\tName             [%s]\n", n.Name)
+	fmt.Printf("\tUUID             [%s]\n", n.ID)
+	fmt.Printf("\tCIDR             [%s]\n", n.CIDR)
+	fmt.Printf("\tGateway          [%s]\n", n.Gateway)
+	fmt.Printf("\tAllocation Range [%s - %s]\n", n.AllocationStart, n.AllocationEnd)
+	if n.IPv6CIDR != "" {
+		fmt.Printf("\tIPv6 CIDR        [%s]\n", n.IPv6CIDR)
+		fmt.Printf("\tIPv6 Gateway     [%s]\n", n.IPv6Gateway)
+		fmt.Printf("\tIPv6 Mode        [%s]\n", n.IPv6Mode)
+	}
+	fmt.Printf("\tCreated          [%s]\n", n.CreateTime)
+}
@@ -126,6 +126,9 @@ func dumpNode(node *types.CiaoNode) {
 	fmt.Printf("\tLoad: %d\n", node.Load)
 	fmt.Printf("\tAvailable/Total memory: %d/%d MB\n", node.MemAvailable, node.MemTotal)
 	fmt.Printf("\tAvailable/Total disk: %d/%d MB\n", node.DiskAvailable, node.DiskTotal)
+	fmt.Printf("\tUsed/Reserved disk: %d/%d MB\n", node.DiskUsed, node.DiskReserved)
+	fmt.Printf("\tAvailable dedicated CPUs: %d\n", node.DedicatedCPUs)
+	fmt.Printf("\tAvailable/Total huge pages: %d/%d MB\n", node.HugePagesAvailable, node.HugePagesTotal)
 	fmt.Printf("\tTotal Instances: %d\n", node.TotalInstances)
 	fmt.Printf("\t\tRunning Instances: %d\n", node.TotalRunningInstances)
 	fmt.Printf("\t\tPending Instances: %d\n", node.TotalPendingInstances)
@@ -0,0 +1,382 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/template"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+
+	"github.com/intel/tfortools"
+)
+
+var peeringCommand = &command{
+	SubCommands: map[string]subCommand{
+		"create":  new(peeringCreateCommand),
+		"list":    new(peeringListCommand),
+		"show":    new(peeringShowCommand),
+		"approve": new(peeringApproveCommand),
+		"delete":  new(peeringDeleteCommand),
+	},
+}
+
+func getCiaoPeeringsResource() (string, error) {
+	return getCiaoResource("network-peerings", api.NetworkPeeringsV1)
+}
+
+type peeringCreateCommand struct {
+	Flag        flag.FlagSet
+	name        string
+	network     string
+	peerTenant  string
+	peerNetwork string
+}
+
+func (cmd *peeringCreateCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] peering create [flags]
+
+Request the peering of a tenant network with a network of another tenant.
+The peering is pending until it is approved by an administrator.  The
+subnets of the peered networks, and of any networks they are already
+peered with, must not overlap.
+
+The create flags are:
+
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *peeringCreateCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.name, "name", "", "Peering name")
+	cmd.Flag.StringVar(&cmd.network, "network", "", "Network UUID")
+	cmd.Flag.StringVar(&cmd.peerTenant, "peer-tenant", "", "Tenant UUID of the peer network")
+	cmd.Flag.StringVar(&cmd.peerNetwork, "peer-network", "", "Peer network UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *peeringCreateCommand) run(args []string) error {
+	if cmd.network == "" {
+		errorf("missing required -network parameter")
+		cmd.usage()
+	}
+
+	if cmd.peerTenant == "" {
+		errorf("missing required -peer-tenant parameter")
+		cmd.usage()
+	}
+
+	if cmd.peerNetwork == "" {
+		errorf("missing required -peer-network parameter")
+		cmd.usage()
+	}
+
+	createReq := api.RequestedNetworkPeering{
+		Name:          cmd.name,
+		NetworkID:     cmd.network,
+		PeerTenantID:  cmd.peerTenant,
+		PeerNetworkID: cmd.peerNetwork,
+	}
+
+	b, err := json.Marshal(createReq)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	body := bytes.NewReader(b)
+	url := buildCiaoURL("%s/network-peerings", *tenantID)
+	resp, err := sendCiaoRequest("POST", url, nil, body, api.NetworkPeeringsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusCreated {
+		fatalf("Peering creation failed: %s", resp.Status)
+	}
+
+	var peering types.NetworkPeering
+	err = unmarshalHTTPResponse(resp, &peering)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	fmt.Printf("Created new peering: %s (%s)\n", peering.ID, peering.Status)
+
+	return err
+}
+
+type peeringListCommand struct {
+	Flag     flag.FlagSet
+	template string
+}
+
+func (cmd *peeringListCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] peering list
+
+List the network peerings of the tenant, or all the network peerings for
+administrators
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, `
+The template passed to the -f option operates on a
+
+%s`, tfortools.GenerateUsageUndecorated([]types.NetworkPeering{}))
+	fmt.Fprintln(os.Stderr, tfortools.TemplateFunctionHelp(nil))
+	os.Exit(2)
+}
+
+func (cmd *peeringListCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *peeringListCommand) run(args []string) error {
+	var t *template.Template
+	var err error
+	if cmd.template != "" {
+		t, err = tfortools.CreateTemplate("peering-list", cmd.template, nil)
+		if err != nil {
+			fatalf(err.Error())
+		}
+	}
+
+	url, err := getCiaoPeeringsResource()
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.NetworkPeeringsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Peering list failed: %s", resp.Status)
+	}
+
+	var peerings []types.NetworkPeering
+
+	err = unmarshalHTTPResponse(resp, &peerings)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	if t != nil {
+		if err = t.Execute(os.Stdout, &peerings); err != nil {
+			fatalf(err.Error())
+		}
+		return nil
+	}
+
+	for i, p := range peerings {
+		fmt.Printf("Peering #%d\n", i+1)
+		dumpPeering(&p)
+		fmt.Printf("\n")
+	}
+
+	return err
+}
+
+type peeringShowCommand struct {
+	Flag     flag.FlagSet
+	peering  string
+	template string
+}
+
+func (cmd *peeringShowCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] peering show [flags]
+
+Show information about a network peering
+
+The show flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s", tfortools.GenerateUsageDecorated("f", types.NetworkPeering{}, nil))
+	os.Exit(2)
+}
+
+func (cmd *peeringShowCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.peering, "peering", "", "Peering UUID")
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *peeringShowCommand) run(args []string) error {
+	if cmd.peering == "" {
+		errorf("missing required -peering parameter")
+		cmd.usage()
+	}
+
+	url, err := getCiaoPeeringsResource()
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	url = fmt.Sprintf("%s/%s", url, cmd.peering)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.NetworkPeeringsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Peering show failed: %s", resp.Status)
+	}
+
+	var peering types.NetworkPeering
+
+	err = unmarshalHTTPResponse(resp, &peering)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	if cmd.template != "" {
+		return tfortools.OutputToTemplate(os.Stdout, "peering-show", cmd.template,
+			&peering, nil)
+	}
+
+	dumpPeering(&peering)
+	return nil
+}
+
+type peeringApproveCommand struct {
+	Flag    flag.FlagSet
+	peering string
+}
+
+func (cmd *peeringApproveCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] peering approve [flags]
+
+Approves a pending network peering.  The CNCIs of the two networks start
+routing traffic between them.  Only administrators may approve peerings.
+
+The approve flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *peeringApproveCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.peering, "peering", "", "Peering UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *peeringApproveCommand) run(args []string) error {
+	if !checkPrivilege() {
+		fatalf("The approval of peerings is restricted to admin users")
+	}
+
+	if cmd.peering == "" {
+		errorf("missing required -peering parameter")
+		cmd.usage()
+	}
+
+	url, err := getCiaoPeeringsResource()
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	url = fmt.Sprintf("%s/%s/approve", url, cmd.peering)
+	resp, err := sendCiaoRequest("POST", url, nil, nil, api.NetworkPeeringsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Peering approval failed: %s", resp.Status)
+	}
+
+	fmt.Printf("Approved peering: %s\n", cmd.peering)
+	return err
+}
+
+type peeringDeleteCommand struct {
+	Flag    flag.FlagSet
+	peering string
+}
+
+func (cmd *peeringDeleteCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] peering delete [flags]
+
+Deletes a network peering.  Either tenant party to the peering, or an
+administrator, may delete it.
+
+The delete flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *peeringDeleteCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.peering, "peering", "", "Peering UUID")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *peeringDeleteCommand) run(args []string) error {
+	if cmd.peering == "" {
+		errorf("missing required -peering parameter")
+		cmd.usage()
+	}
+
+	url, err := getCiaoPeeringsResource()
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	url = fmt.Sprintf("%s/%s", url, cmd.peering)
+	resp, err := sendCiaoRequest("DELETE", url, nil, nil, api.NetworkPeeringsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		fatalf("Peering delete failed: %s", resp.Status)
+	}
+
+	return err
+}
+
+func dumpPeering(p *types.NetworkPeering) {
+	fmt.Printf("\tName           [%s]\n", p.Name)
+	fmt.Printf("\tUUID           [%s]\n", p.ID)
+	fmt.Printf("\tStatus         [%s]\n", p.Status)
+	fmt.Printf("\tTenant         [%s]\n", p.TenantID)
+	fmt.Printf("\tNetwork        [%s] %s\n", p.NetworkID, p.CIDR)
+	fmt.Printf("\tPeer Tenant    [%s]\n", p.PeerTenantID)
+	fmt.Printf("\tPeer Network   [%s] %s\n", p.PeerNetworkID, p.PeerCIDR)
+	fmt.Printf("\tCreated        [%s]\n", p.CreateTime)
+}
@@ -0,0 +1,166 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"text/template"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+
+	"github.com/intel/tfortools"
+)
+
+var reservedIPCommand = &command{
+	SubCommands: map[string]subCommand{
+		"list":    new(reservedIPListCommand),
+		"release": new(reservedIPReleaseCommand),
+	},
+}
+
+type reservedIPListCommand struct {
+	Flag     flag.FlagSet
+	template string
+}
+
+func (cmd *reservedIPListCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] reserved-ip list
+
+List the IP addresses retained after the deletion of instances
+`)
+	cmd.Flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, `
+The template passed to the -f option operates on a
+
+%s`, tfortools.GenerateUsageUndecorated([]types.ReservedIP{}))
+	fmt.Fprintln(os.Stderr, tfortools.TemplateFunctionHelp(nil))
+	os.Exit(2)
+}
+
+func (cmd *reservedIPListCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+type reservedIPsByCreateTime []types.ReservedIP
+
+func (ss reservedIPsByCreateTime) Len() int      { return len(ss) }
+func (ss reservedIPsByCreateTime) Swap(i, j int) { ss[i], ss[j] = ss[j], ss[i] }
+func (ss reservedIPsByCreateTime) Less(i, j int) bool {
+	return ss[i].CreateTime.Before(ss[j].CreateTime)
+}
+
+func (cmd *reservedIPListCommand) run(args []string) error {
+	var t *template.Template
+	var err error
+	if cmd.template != "" {
+		t, err = tfortools.CreateTemplate("reserved-ip-list", cmd.template, nil)
+		if err != nil {
+			fatalf(err.Error())
+		}
+	}
+
+	url := buildCiaoURL("%s/reserved_ips", *tenantID)
+	resp, err := sendCiaoRequest("GET", url, nil, nil, api.ReservedIPsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("Reserved IP list failed: %s", resp.Status)
+	}
+
+	var reserved []types.ReservedIP
+
+	err = unmarshalHTTPResponse(resp, &reserved)
+	if err != nil {
+		fatalf(err.Error())
+	}
+
+	sort.Sort(reservedIPsByCreateTime(reserved))
+
+	if t != nil {
+		if err = t.Execute(os.Stdout, &reserved); err != nil {
+			fatalf(err.Error())
+		}
+		return nil
+	}
+
+	for i, r := range reserved {
+		fmt.Printf("Reserved IP #%d\n", i+1)
+		fmt.Printf("\tIP Address    [%s]\n", r.IPAddress)
+		if r.NetworkID != "" {
+			fmt.Printf("\tNetwork UUID  [%s]\n", r.NetworkID)
+		}
+		fmt.Printf("\tInstance UUID [%s]\n", r.InstanceID)
+		fmt.Printf("\tReserved      [%s]\n", r.CreateTime)
+		fmt.Printf("\n")
+	}
+
+	return err
+}
+
+type reservedIPReleaseCommand struct {
+	Flag flag.FlagSet
+	ip   string
+}
+
+func (cmd *reservedIPReleaseCommand) usage(...string) {
+	fmt.Fprintf(os.Stderr, `usage: ciao-cli [options] reserved-ip release [flags]
+
+Releases a reserved IP address so that it may be assigned to any instance.
+
+The release flags are:
+`)
+	cmd.Flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func (cmd *reservedIPReleaseCommand) parseArgs(args []string) []string {
+	cmd.Flag.StringVar(&cmd.ip, "ip", "", "Reserved IP address")
+	cmd.Flag.Usage = func() { cmd.usage() }
+	cmd.Flag.Parse(args)
+	return cmd.Flag.Args()
+}
+
+func (cmd *reservedIPReleaseCommand) run(args []string) error {
+	if cmd.ip == "" {
+		errorf("missing required -ip parameter")
+		cmd.usage()
+	}
+
+	url := buildCiaoURL("%s/reserved_ips/%s", *tenantID, cmd.ip)
+	resp, err := sendCiaoRequest("DELETE", url, nil, nil, api.ReservedIPsV1)
+	if err != nil {
+		fatalf(err.Error())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		fatalf("Reserved IP release failed: %s", resp.Status)
+	}
+
+	return err
+}
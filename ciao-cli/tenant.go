@@ -23,6 +23,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"text/template"
 	"time"
 
@@ -96,7 +97,7 @@ func getCiaoTenantConfig(ID string) (types.TenantConfig, error) {
 	return config, err
 }
 
-func putCiaoTenantConfig(ID string, name string, bits int) error {
+func putCiaoTenantConfig(ID string, name string, bits int, dnsDomain string, dnsForwarders string) error {
 	var config types.TenantConfig
 
 	url, err := getCiaoTenantRef(ID)
@@ -121,6 +122,14 @@ func putCiaoTenantConfig(ID string, name string, bits int) error {
 		config.SubnetBits = bits
 	}
 
+	if dnsDomain != "" {
+		config.DNSDomain = dnsDomain
+	}
+
+	if dnsForwarders != "" {
+		config.DNSForwarders = splitDNSForwarders(dnsForwarders)
+	}
+
 	a, err := json.Marshal(oldconfig)
 	if err != nil {
 		fatalf(err.Error())
@@ -143,6 +152,15 @@ func putCiaoTenantConfig(ID string, name string, bits int) error {
 	return err
 }
 
+// splitDNSForwarders converts a comma separated list of upstream DNS
+// servers into a slice.
+func splitDNSForwarders(forwarders string) []string {
+	if forwarders == "" {
+		return nil
+	}
+	return strings.Split(forwarders, ",")
+}
+
 // Project represents a tenant UUID and friendly name.
 type Project struct {
 	ID   string `mapstructure:"id"`
@@ -169,18 +187,22 @@ type tenantListCommand struct {
 }
 
 type tenantUpdateCommand struct {
-	Flag       flag.FlagSet
-	name       string
-	subnetBits int
-	tenantID   string
+	Flag          flag.FlagSet
+	name          string
+	subnetBits    int
+	dnsDomain     string
+	dnsForwarders string
+	tenantID      string
 }
 
 type tenantCreateCommand struct {
-	Flag       flag.FlagSet
-	name       string
-	subnetBits int
-	tenantID   string
-	template   string
+	Flag          flag.FlagSet
+	name          string
+	subnetBits    int
+	dnsDomain     string
+	dnsForwarders string
+	tenantID      string
+	template      string
 }
 
 type tenantDeleteCommand struct {
@@ -204,6 +226,8 @@ func (cmd *tenantUpdateCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.tenantID, "for-tenant", "", "Tenant to update")
 	cmd.Flag.IntVar(&cmd.subnetBits, "subnet-bits", 0, "Number of bits in subnet mask")
 	cmd.Flag.StringVar(&cmd.name, "name", "", "Tenant name")
+	cmd.Flag.StringVar(&cmd.dnsDomain, "dns-domain", "", "Domain under which instance names are registered")
+	cmd.Flag.StringVar(&cmd.dnsForwarders, "dns-forwarders", "", "Comma separated list of upstream DNS servers")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -215,7 +239,7 @@ func (cmd *tenantUpdateCommand) run(args []string) error {
 	}
 
 	// we should not require individual parameters?
-	if cmd.name == "" && cmd.subnetBits == 0 {
+	if cmd.name == "" && cmd.subnetBits == 0 && cmd.dnsDomain == "" && cmd.dnsForwarders == "" {
 		errorf("Missing required parameters")
 		cmd.usage()
 	}
@@ -226,7 +250,7 @@ func (cmd *tenantUpdateCommand) run(args []string) error {
 		cmd.usage()
 	}
 
-	return putCiaoTenantConfig(cmd.tenantID, cmd.name, cmd.subnetBits)
+	return putCiaoTenantConfig(cmd.tenantID, cmd.name, cmd.subnetBits, cmd.dnsDomain, cmd.dnsForwarders)
 }
 
 func (cmd *tenantCreateCommand) usage(...string) {
@@ -249,6 +273,8 @@ func (cmd *tenantCreateCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.tenantID, "tenant", "", "ID for new tenant")
 	cmd.Flag.IntVar(&cmd.subnetBits, "subnet-bits", 0, "Number of bits in subnet mask")
 	cmd.Flag.StringVar(&cmd.name, "name", "", "Tenant name")
+	cmd.Flag.StringVar(&cmd.dnsDomain, "dns-domain", "", "Domain under which instance names are registered")
+	cmd.Flag.StringVar(&cmd.dnsForwarders, "dns-forwarders", "", "Comma separated list of upstream DNS servers")
 	cmd.Flag.StringVar(&cmd.template, "f", "", "Template used to format output")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
@@ -294,8 +320,10 @@ func (cmd *tenantCreateCommand) run(args []string) error {
 
 	req.ID = tuuid.String()
 	req.Config = types.TenantConfig{
-		Name:       cmd.name,
-		SubnetBits: cmd.subnetBits,
+		Name:          cmd.name,
+		SubnetBits:    cmd.subnetBits,
+		DNSDomain:     cmd.dnsDomain,
+		DNSForwarders: splitDNSForwarders(cmd.dnsForwarders),
 	}
 	b, err := json.Marshal(req)
 	if err != nil {
@@ -582,6 +610,12 @@ func listTenantConfig(t *template.Template, tenantID string) error {
 	fmt.Printf("Tenant [%s]\n", tenantID)
 	fmt.Printf("\tName: %s\n", config.Name)
 	fmt.Printf("\tSubnetBits: %d\n", config.SubnetBits)
+	if config.DNSDomain != "" {
+		fmt.Printf("\tDNS Domain: %s\n", config.DNSDomain)
+	}
+	if len(config.DNSForwarders) > 0 {
+		fmt.Printf("\tDNS Forwarders: %s\n", strings.Join(config.DNSForwarders, ","))
+	}
 
 	return nil
 }
@@ -50,6 +50,7 @@ type volumeAddCommand struct {
 	name        string
 	sourceType  string
 	source      string
+	multiAttach bool
 }
 
 func (cmd *volumeAddCommand) usage(...string) {
@@ -70,6 +71,7 @@ func (cmd *volumeAddCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.source, "source", "", "ID of image or volume to clone from")
 	cmd.Flag.IntVar(&cmd.size, "size", 1, "Size of the volume in GB")
 	cmd.Flag.StringVar(&cmd.description, "description", "", "Volume description")
+	cmd.Flag.BoolVar(&cmd.multiAttach, "multiattach", false, "Allow the volume to be attached to more than one instance")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -80,6 +82,7 @@ func (cmd *volumeAddCommand) run(args []string) error {
 		Description: cmd.description,
 		Name:        cmd.name,
 		Size:        cmd.size,
+		MultiAttach: cmd.multiAttach,
 	}
 
 	if cmd.sourceType == "image" {
@@ -326,7 +329,7 @@ func (cmd *volumeAttachCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Volume UUID")
 	cmd.Flag.StringVar(&cmd.instance, "instance", "", "Instance UUID")
 	cmd.Flag.StringVar(&cmd.mountpoint, "mountpoint", "/mnt", "Mount point")
-	cmd.Flag.StringVar(&cmd.mountpoint, "mode", "rw", "Access mode")
+	cmd.Flag.StringVar(&cmd.mode, "mode", "rw", "Access mode, rw or ro")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -384,8 +387,9 @@ func (cmd *volumeAttachCommand) run(args []string) error {
 }
 
 type volumeDetachCommand struct {
-	Flag   flag.FlagSet
-	volume string
+	Flag       flag.FlagSet
+	volume     string
+	attachment string
 }
 
 func (cmd *volumeDetachCommand) usage(...string) {
@@ -401,6 +405,7 @@ The detach flags are:
 
 func (cmd *volumeDetachCommand) parseArgs(args []string) []string {
 	cmd.Flag.StringVar(&cmd.volume, "volume", "", "Volume UUID")
+	cmd.Flag.StringVar(&cmd.attachment, "attachment", "", "Attachment UUID, detaches all attachments if not specified")
 	cmd.Flag.Usage = func() { cmd.usage() }
 	cmd.Flag.Parse(args)
 	return cmd.Flag.Args()
@@ -413,12 +418,14 @@ func (cmd *volumeDetachCommand) run(args []string) error {
 	}
 
 	type DetachRequest struct {
-		AttachmentID string `json:"attachment_id,omitempty"`
+		AttachmentID string `json:"attachment-id,omitempty"`
 	}
 	var detachReq = struct {
 		Detach DetachRequest `json:"detach"`
 	}{
-		Detach: DetachRequest{},
+		Detach: DetachRequest{
+			AttachmentID: cmd.attachment,
+		},
 	}
 
 	b, err := json.Marshal(detachReq)
@@ -452,4 +459,10 @@ func dumpVolume(v *types.Volume) {
 	fmt.Printf("\tTenantID         [%s]\n", v.TenantID)
 	fmt.Printf("\tState            [%s]\n", v.State)
 	fmt.Printf("\tDescription      [%s]\n", v.Description)
+	fmt.Printf("\tMultiAttach      [%t]\n", v.MultiAttach)
+	for _, a := range v.Attachments {
+		fmt.Printf("\tAttachment       [%s]\n", a.ID)
+		fmt.Printf("\t\tInstance [%s]\n", a.InstanceID)
+		fmt.Printf("\t\tMode     [%s]\n", a.Mode)
+	}
 }
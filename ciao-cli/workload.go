@@ -172,8 +172,17 @@ type disk struct {
 }
 
 type defaultResources struct {
-	VCPUs int `yaml:"vcpus"`
-	MemMB int `yaml:"mem_mb"`
+	VCPUs         int  `yaml:"vcpus"`
+	MemMB         int  `yaml:"mem_mb"`
+	DedicatedCPUs bool `yaml:"dedicated_cpus,omitempty"`
+	HugePages     bool `yaml:"hugepages,omitempty"`
+	NUMALocal     bool `yaml:"numa_local,omitempty"`
+	GuestAgent    bool `yaml:"guest_agent,omitempty"`
+	Console       bool `yaml:"console,omitempty"`
+	DiskIOPS      int  `yaml:"disk_iops,omitempty"`
+	DiskMBps      int  `yaml:"disk_mbps,omitempty"`
+	NetRxMbps     int  `yaml:"net_rx_mbps,omitempty"`
+	NetTxMbps     int  `yaml:"net_tx_mbps,omitempty"`
 }
 
 // we currently only use the first disk due to lack of support
@@ -185,6 +194,7 @@ type workloadOptions struct {
 	ImageName       string           `yaml:"image_name,omitempty"`
 	Defaults        defaultResources `yaml:"defaults"`
 	CloudConfigFile string           `yaml:"cloud_init,omitempty"`
+	Template        bool             `yaml:"template,omitempty"`
 	Disks           []disk           `yaml:"disks,omitempty"`
 }
 
@@ -256,6 +266,7 @@ func optToReq(opt workloadOptions, req *types.Workload) error {
 	req.FWType = opt.FWType
 	req.ImageName = opt.ImageName
 	req.Config = config
+	req.Template = opt.Template
 	req.Storage, err = optToReqStorage(opt)
 
 	if err != nil {
@@ -277,6 +288,49 @@ func optToReq(opt workloadOptions, req *types.Workload) error {
 	}
 	req.Defaults = append(req.Defaults, r)
 
+	// dedicated host resources, the guest agent and the console are
+	// optional.
+	optional := []struct {
+		requested bool
+		resource  payloads.Resource
+	}{
+		{defaults.DedicatedCPUs, payloads.DedicatedCPUs},
+		{defaults.HugePages, payloads.HugePages},
+		{defaults.NUMALocal, payloads.NUMALocal},
+		{defaults.GuestAgent, payloads.GuestAgent},
+		{defaults.Console, payloads.Console},
+	}
+	for _, o := range optional {
+		if o.requested {
+			req.Defaults = append(req.Defaults, payloads.RequestedResource{
+				Type:  o.resource,
+				Value: 1,
+			})
+		}
+	}
+
+	// I/O limits are only sent when they are set.
+	limits := []struct {
+		value    int
+		resource payloads.Resource
+	}{
+		{defaults.DiskIOPS, payloads.DiskIOPS},
+		{defaults.DiskMBps, payloads.DiskMBps},
+		{defaults.NetRxMbps, payloads.NetRxMbps},
+		{defaults.NetTxMbps, payloads.NetTxMbps},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			return fmt.Errorf("%s must not be negative", l.resource)
+		}
+		if l.value > 0 {
+			req.Defaults = append(req.Defaults, payloads.RequestedResource{
+				Type:  l.resource,
+				Value: l.value,
+			})
+		}
+	}
+
 	return nil
 }
 
@@ -292,6 +346,24 @@ func outputWorkload(w types.Workload) {
 			opt.Defaults.VCPUs = d.Value
 		} else if d.Type == payloads.MemMB {
 			opt.Defaults.MemMB = d.Value
+		} else if d.Type == payloads.DedicatedCPUs {
+			opt.Defaults.DedicatedCPUs = d.Value != 0
+		} else if d.Type == payloads.HugePages {
+			opt.Defaults.HugePages = d.Value != 0
+		} else if d.Type == payloads.NUMALocal {
+			opt.Defaults.NUMALocal = d.Value != 0
+		} else if d.Type == payloads.GuestAgent {
+			opt.Defaults.GuestAgent = d.Value != 0
+		} else if d.Type == payloads.Console {
+			opt.Defaults.Console = d.Value != 0
+		} else if d.Type == payloads.DiskIOPS {
+			opt.Defaults.DiskIOPS = d.Value
+		} else if d.Type == payloads.DiskMBps {
+			opt.Defaults.DiskMBps = d.Value
+		} else if d.Type == payloads.NetRxMbps {
+			opt.Defaults.NetRxMbps = d.Value
+		} else if d.Type == payloads.NetTxMbps {
+			opt.Defaults.NetTxMbps = d.Value
 		}
 	}
 
@@ -21,6 +21,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -57,6 +58,24 @@ const (
 
 	// InstancesV1 is the content-type string for v1 of our intances resource
 	InstancesV1 = "x.ciao.instances.v1"
+
+	// BackupsV1 is the content-type string for v1 of our backups resource
+	BackupsV1 = "x.ciao.backups.v1"
+
+	// NetworksV1 is the content-type string for v1 of our networks resource
+	NetworksV1 = "x.ciao.networks.v1"
+
+	// ReservedIPsV1 is the content-type string for v1 of our reserved IPs
+	// resource
+	ReservedIPsV1 = "x.ciao.reserved-ips.v1"
+
+	// LoadBalancersV1 is the content-type string for v1 of our load
+	// balancers resource
+	LoadBalancersV1 = "x.ciao.load-balancers.v1"
+
+	// NetworkPeeringsV1 is the content-type string for v1 of our network
+	// peerings resource
+	NetworkPeeringsV1 = "x.ciao.network-peerings.v1"
 )
 
 // ErrorImage defines all possible image handling errors
@@ -94,6 +113,64 @@ type RequestedVolume struct {
 	Description string `json:"description,omitempty"`
 	Name        string `json:"name,omitempty"`
 	ImageRef    string `json:"imageRef,omitempty"`
+	MultiAttach bool   `json:"multiattach,omitempty"`
+}
+
+// RequestedBackup contains information about a volume backup to be created.
+type RequestedBackup struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// RequestedRestore contains information about the volume to be created
+// when a backup is restored.
+type RequestedRestore struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// RequestedNetwork contains information about a tenant network to be
+// created.  Only the CIDR is mandatory.  Networks with an IPv6CIDR, or an
+// IPv6Mode, are dual stack.  A ULA prefix is generated when only the
+// IPv6Mode is specified.
+type RequestedNetwork struct {
+	Name            string `json:"name,omitempty"`
+	CIDR            string `json:"cidr"`
+	Gateway         string `json:"gateway,omitempty"`
+	AllocationStart string `json:"allocation_start,omitempty"`
+	AllocationEnd   string `json:"allocation_end,omitempty"`
+	IPv6CIDR        string `json:"ipv6_cidr,omitempty"`
+	IPv6Mode        string `json:"ipv6_mode,omitempty"`
+}
+
+// RequestedLoadBalancerMember identifies an instance receiving the traffic
+// of a load balancer.  The port defaults to the listener port.
+type RequestedLoadBalancerMember struct {
+	InstanceID string `json:"instance_id"`
+	Port       int    `json:"port,omitempty"`
+}
+
+// RequestedLoadBalancer contains information about a load balancer to be
+// created.  The listener port and at least one member are mandatory.  The
+// virtual IP is allocated from the named pool, or from any pool with free
+// addresses, and all the members must share a subnet.
+type RequestedLoadBalancer struct {
+	Name        string                         `json:"name,omitempty"`
+	PoolName    *string                        `json:"pool_name,omitempty"`
+	Port        int                            `json:"port"`
+	Protocol    string                         `json:"protocol,omitempty"`
+	Members     []RequestedLoadBalancerMember  `json:"members"`
+	HealthCheck *types.LoadBalancerHealthCheck `json:"health_check,omitempty"`
+}
+
+// RequestedNetworkPeering contains information about a network peering to
+// be created between a network of the requesting tenant and a network of
+// another tenant.
+type RequestedNetworkPeering struct {
+	Name          string `json:"name,omitempty"`
+	NetworkID     string `json:"network_id"`
+	PeerTenantID  string `json:"peer_tenant_id"`
+	PeerNetworkID string `json:"peer_network_id"`
 }
 
 // BlockDeviceMapping represents extra block devices that can be added to an instance
@@ -144,14 +221,20 @@ type CreateServerRequest struct {
 		MinInstances        int                  `json:"min_count"`
 		BlockDeviceMappings []BlockDeviceMapping `json:"block_device_mapping,omitempty"`
 		Metadata            map[string]string    `json:"metadata,omitempty"`
+		NetworkID           string               `json:"network_id,omitempty"`
+		AdditionalNetworks  []string             `json:"additional_networks,omitempty"`
+		IPAddress           string               `json:"ip_address,omitempty"`
+		RetainIP            bool                 `json:"retain_ip,omitempty"`
 	} `json:"server"`
 }
 
 // PrivateAddresses contains information about a single instance network
 // interface.
 type PrivateAddresses struct {
-	Addr    string `json:"addr"`
-	MacAddr string `json:"mac_addr"`
+	Addr      string `json:"addr"`
+	MacAddr   string `json:"mac_addr"`
+	NetworkID string `json:"network_id,omitempty"`
+	IPv6Addr  string `json:"ipv6_addr,omitempty"`
 }
 
 // ServerDetails contains information about a specific instance.
@@ -167,6 +250,47 @@ type ServerDetails struct {
 	TenantID         string             `json:"tenant_id"`
 	SSHIP            string             `json:"ssh_ip"`
 	SSHPort          int                `json:"ssh_port"`
+	NetworkID        string             `json:"network_id,omitempty"`
+	RetainIP         bool               `json:"retain_ip,omitempty"`
+	Guest            *GuestDetails      `json:"guest,omitempty"`
+	BlockIO          *BlockIODetails    `json:"block_io,omitempty"`
+	NetworkIO        *NetworkIODetails  `json:"network_io,omitempty"`
+	Metadata         map[string]string  `json:"metadata,omitempty"`
+}
+
+// ServerMetadata contains the key/value metadata of an instance.  The
+// metadata is served to the instance by the metadata service of its CNCI.
+type ServerMetadata struct {
+	Metadata map[string]string `json:"metadata"`
+}
+
+// GuestDetails contains information about an instance reported by the
+// guest agent running inside it.
+type GuestDetails struct {
+	OSName        string   `json:"os_name"`
+	OSVersion     string   `json:"os_version"`
+	KernelRelease string   `json:"kernel_release"`
+	IPAddresses   []string `json:"ip_addresses"`
+}
+
+// BlockIODetails contains the number of bytes and I/O operations read and
+// written by an instance to its disks.
+type BlockIODetails struct {
+	ReadBytes  int64 `json:"read_bytes"`
+	WriteBytes int64 `json:"write_bytes"`
+	ReadOps    int64 `json:"read_ops"`
+	WriteOps   int64 `json:"write_ops"`
+}
+
+// NetworkIODetails contains the traffic counters of the vnic of an instance,
+// as seen from inside the instance.
+type NetworkIODetails struct {
+	RxBytes   int64 `json:"rx_bytes"`
+	RxPackets int64 `json:"rx_packets"`
+	RxDropped int64 `json:"rx_dropped"`
+	TxBytes   int64 `json:"tx_bytes"`
+	TxPackets int64 `json:"tx_packets"`
+	TxDropped int64 `json:"tx_dropped"`
 }
 
 // Servers holds multiple servers including a count
@@ -180,6 +304,17 @@ type Server struct {
 	Server ServerDetails `json:"server"`
 }
 
+// ConsoleLog holds the tail of the serial console log of an instance.
+type ConsoleLog struct {
+	Log string `json:"log"`
+}
+
+// ConsoleToken holds the token with which a client can connect, once, to
+// the console session of an instance.
+type ConsoleToken struct {
+	Token string `json:"token"`
+}
+
 var (
 	//ErrInstanceNotFound is used if instance not found
 	ErrInstanceNotFound = errors.New("Instance not found")
@@ -217,12 +352,23 @@ type Response struct {
 }
 
 func errorResponse(err error) Response {
+	switch err.(type) {
+	case *types.ConfigTemplateError:
+		return Response{http.StatusBadRequest, newHTTPErrorCode(http.StatusBadRequest, err)}
+	case *types.InstanceStateError:
+		return Response{http.StatusConflict, newHTTPErrorCode(http.StatusConflict, err)}
+	}
+
 	switch err {
 	case types.ErrPoolNotFound,
 		types.ErrTenantNotFound,
 		types.ErrAddressNotFound,
 		types.ErrInstanceNotFound,
-		types.ErrWorkloadNotFound:
+		types.ErrWorkloadNotFound,
+		types.ErrBackupNotFound,
+		types.ErrNetworkNotFound,
+		types.ErrLoadBalancerNotFound,
+		types.ErrNetworkPeeringNotFound:
 		return Response{http.StatusNotFound, nil}
 
 	case types.ErrQuota,
@@ -230,19 +376,61 @@ func errorResponse(err error) Response {
 		types.ErrDuplicateSubnet,
 		types.ErrDuplicateIP,
 		types.ErrInvalidIP,
+		types.ErrSubnetTooSmall,
+		types.ErrSubnetTooLarge,
 		types.ErrPoolNotEmpty,
 		types.ErrInvalidPoolAddress,
 		types.ErrBadRequest,
 		types.ErrPoolEmpty,
 		types.ErrDuplicatePoolName,
-		types.ErrWorkloadInUse:
+		types.ErrWorkloadInUse,
+		types.ErrBackupNotAvailable,
+		types.ErrBackupsNotConfigured,
+		types.ErrConsoleLogNotSupported,
+		types.ErrConsoleTokenInvalid,
+		types.ErrInstanceNotRunning,
+		types.ErrNetworkInUse,
+		types.ErrNetworkOverlap,
+		types.ErrPeeringOverlap,
+		types.ErrNetworkFull:
 		return Response{http.StatusForbidden, nil}
 
+	case types.ErrAddressInUse:
+		return Response{http.StatusConflict, nil}
+
+	case types.ErrConsoleLogTimeout,
+		types.ErrConsoleTimeout:
+		return Response{http.StatusGatewayTimeout, nil}
+
 	default:
 		return Response{http.StatusInternalServerError, nil}
 	}
 }
 
+// newHTTPErrorCode returns the OpenStack formatted body of an error reply.
+func newHTTPErrorCode(status int, err error) HTTPReturnErrorCode {
+	return HTTPReturnErrorCode{
+		Error: HTTPErrorData{
+			Code:    status,
+			Name:    http.StatusText(status),
+			Message: err.Error(),
+		},
+	}
+}
+
+// writeError replies to a request with an OpenStack formatted error.
+func writeError(w http.ResponseWriter, status int, err error) {
+	code := newHTTPErrorCode(status, err)
+
+	b, err := json.Marshal(code)
+	if err != nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	http.Error(w, string(b), status)
+}
+
 // Handler is a custom handler for the compute APIs.
 // This custom handler allows us to more cleanly return an error and response,
 // and pass some package level context into the handler.
@@ -267,23 +455,7 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.Handler(h.Context, w, r)
 	if err != nil {
-		data := HTTPErrorData{
-			Code:    resp.status,
-			Name:    http.StatusText(resp.status),
-			Message: err.Error(),
-		}
-
-		code := HTTPReturnErrorCode{
-			Error: data,
-		}
-
-		b, err := json.Marshal(code)
-		if err != nil {
-			http.Error(w, http.StatusText(resp.status), resp.status)
-			return
-		}
-
-		http.Error(w, string(b), resp.status)
+		writeError(w, resp.status, err)
 		return
 	}
 
@@ -415,6 +587,69 @@ func listResources(c *Context, w http.ResponseWriter, r *http.Request) (Response
 		links = append(links, link)
 	}
 
+	// for the "backups" resource
+	if ok {
+		link = types.APILink{
+			Rel:        "backups",
+			Version:    BackupsV1,
+			MinVersion: BackupsV1,
+		}
+
+		link.Href = fmt.Sprintf("%s/%s/backups", c.URL, tenantID)
+		links = append(links, link)
+	}
+
+	// for the "networks" resource
+	if ok {
+		link = types.APILink{
+			Rel:        "networks",
+			Version:    NetworksV1,
+			MinVersion: NetworksV1,
+		}
+
+		link.Href = fmt.Sprintf("%s/%s/networks", c.URL, tenantID)
+		links = append(links, link)
+	}
+
+	// for the "reserved_ips" resource
+	if ok {
+		link = types.APILink{
+			Rel:        "reserved_ips",
+			Version:    ReservedIPsV1,
+			MinVersion: ReservedIPsV1,
+		}
+
+		link.Href = fmt.Sprintf("%s/%s/reserved_ips", c.URL, tenantID)
+		links = append(links, link)
+	}
+
+	// for the "load-balancers" resource
+	if ok {
+		link = types.APILink{
+			Rel:        "load-balancers",
+			Version:    LoadBalancersV1,
+			MinVersion: LoadBalancersV1,
+		}
+
+		link.Href = fmt.Sprintf("%s/%s/load-balancers", c.URL, tenantID)
+		links = append(links, link)
+	}
+
+	// for the "network-peerings" resource
+	link = types.APILink{
+		Rel:        "network-peerings",
+		Version:    NetworkPeeringsV1,
+		MinVersion: NetworkPeeringsV1,
+	}
+
+	if !ok {
+		link.Href = fmt.Sprintf("%s/network-peerings", c.URL)
+	} else {
+		link.Href = fmt.Sprintf("%s/%s/network-peerings", c.URL, tenantID)
+	}
+
+	links = append(links, link)
+
 	return Response{http.StatusOK, links}, nil
 }
 
@@ -970,7 +1205,6 @@ func listImages(context *Context, w http.ResponseWriter, r *http.Request) (Respo
 }
 
 // getImage get information about an image by image_id field
-//
 func getImage(context *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
 	vars := mux.Vars(r)
 	imageID := vars["image_id"]
@@ -1104,7 +1338,22 @@ func volumeActionAttach(bc *Context, m map[string]interface{}, tenant string, vo
 	}
 	mountPoint := val.(string)
 
-	err := bc.AttachVolume(tenant, volume, instance, mountPoint)
+	// mode is optional and defaults to read-write
+	mode := types.ReadWrite
+	val, ok = m["mode"]
+	if ok {
+		s, ok := val.(string)
+		if !ok {
+			return Response{http.StatusBadRequest, nil}, nil
+		}
+
+		mode = types.AttachmentMode(s)
+		if mode != types.ReadWrite && mode != types.ReadOnly {
+			return Response{http.StatusBadRequest, nil}, nil
+		}
+	}
+
+	err := bc.AttachVolume(tenant, volume, instance, mountPoint, mode)
 	if err != nil {
 		return errorResponse(err), err
 	}
@@ -1166,6 +1415,352 @@ func volumeAction(bc *Context, w http.ResponseWriter, r *http.Request) (Response
 	return Response{http.StatusBadRequest, nil}, err
 }
 
+func createBackup(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	volume := vars["volume_id"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req RequestedBackup
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			return Response{http.StatusBadRequest, nil}, err
+		}
+	}
+
+	backup, err := bc.CreateBackup(tenant, volume, req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, backup}, nil
+}
+
+func listBackups(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	backups, err := bc.ListBackups(tenant)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, backups}, nil
+}
+
+func showBackup(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	backup := vars["backup_id"]
+
+	b, err := bc.ShowBackup(tenant, backup)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, b}, nil
+}
+
+func deleteBackup(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	backup := vars["backup_id"]
+
+	err := bc.DeleteBackup(tenant, backup)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, nil}, nil
+}
+
+func restoreBackup(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	backup := vars["backup_id"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req RequestedRestore
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			return Response{http.StatusBadRequest, nil}, err
+		}
+	}
+
+	vol, err := bc.RestoreBackup(tenant, backup, req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusAccepted, vol}, nil
+}
+
+func createNetwork(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req RequestedNetwork
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	network, err := bc.CreateNetwork(tenant, req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusCreated, network}, nil
+}
+
+func listNetworks(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	networks, err := bc.ListNetworks(tenant)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, networks}, nil
+}
+
+func showNetwork(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	network := vars["network_id"]
+
+	n, err := bc.ShowNetwork(tenant, network)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, n}, nil
+}
+
+func deleteNetwork(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	network := vars["network_id"]
+
+	err := bc.DeleteNetwork(tenant, network)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusNoContent, nil}, nil
+}
+
+func listReservedIPs(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	reserved, err := bc.ListReservedIPs(tenant)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, reserved}, nil
+}
+
+func releaseReservedIP(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	ip := vars["ip"]
+
+	err := bc.ReleaseReservedIP(tenant, ip)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusNoContent, nil}, nil
+}
+
+func createLoadBalancer(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req RequestedLoadBalancer
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	lb, err := bc.CreateLoadBalancer(tenant, req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusCreated, lb}, nil
+}
+
+func listLoadBalancers(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	lbs, err := bc.ListLoadBalancers(tenant)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, lbs}, nil
+}
+
+func showLoadBalancer(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	lbID := vars["lb_id"]
+
+	lb, err := bc.ShowLoadBalancer(tenant, lbID)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, lb}, nil
+}
+
+func updateLoadBalancerMembers(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	lbID := vars["lb_id"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var members []RequestedLoadBalancerMember
+	err = json.Unmarshal(body, &members)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	lb, err := bc.UpdateLoadBalancerMembers(tenant, lbID, members)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, lb}, nil
+}
+
+func deleteLoadBalancer(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	lbID := vars["lb_id"]
+
+	err := bc.DeleteLoadBalancer(tenant, lbID)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusNoContent, nil}, nil
+}
+
+func createNetworkPeering(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req RequestedNetworkPeering
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	p, err := bc.CreateNetworkPeering(tenant, req)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusCreated, p}, nil
+}
+
+func listNetworkPeerings(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+
+	peerings, err := bc.ListNetworkPeerings(tenant)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, peerings}, nil
+}
+
+func showNetworkPeering(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	ID := vars["peering_id"]
+
+	p, err := bc.ShowNetworkPeering(tenant, ID)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, p}, nil
+}
+
+func approveNetworkPeering(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	ID := vars["peering_id"]
+
+	p, err := bc.ApproveNetworkPeering(ID)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, p}, nil
+}
+
+func deleteNetworkPeering(bc *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	ID := vars["peering_id"]
+
+	err := bc.DeleteNetworkPeering(tenant, ID)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusNoContent, nil}, nil
+}
+
 func createInstance(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
 	vars := mux.Vars(r)
 	tenant := vars["tenant"]
@@ -1257,6 +1852,82 @@ func deleteInstance(c *Context, w http.ResponseWriter, r *http.Request) (Respons
 	return Response{http.StatusNoContent, nil}, nil
 }
 
+func showInstanceMetadata(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	metadata, err := c.ShowServerMetadata(tenant, server)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, ServerMetadata{Metadata: metadata}}, nil
+}
+
+func updateInstanceMetadata(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	var req ServerMetadata
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return Response{http.StatusBadRequest, nil}, err
+	}
+
+	metadata, err := c.UpdateServerMetadata(tenant, server, req.Metadata)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, ServerMetadata{Metadata: metadata}}, nil
+}
+
+func showInstanceConsoleLog(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	lines := 0
+	values := r.URL.Query()
+	if len(values["lines"]) > 0 {
+		var err error
+		lines, err = strconv.Atoi(values["lines"][0])
+		if err != nil || lines < 0 {
+			return Response{http.StatusBadRequest, nil},
+				fmt.Errorf("Invalid number of lines %s", values["lines"][0])
+		}
+	}
+
+	log, err := c.GetConsoleLog(tenant, server, lines)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusOK, ConsoleLog{Log: log}}, nil
+}
+
+func openInstanceConsole(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	token, err := c.OpenConsole(tenant, server)
+	if err != nil {
+		return errorResponse(err), err
+	}
+
+	return Response{http.StatusCreated, ConsoleToken{Token: token}}, nil
+}
+
 func instanceAction(c *Context, w http.ResponseWriter, r *http.Request) (Response, error) {
 	vars := mux.Vars(r)
 	tenant := vars["tenant"]
@@ -1271,10 +1942,20 @@ func instanceAction(c *Context, w http.ResponseWriter, r *http.Request) (Respons
 
 	bodyString := string(body)
 
+	// unpause must be checked before pause as it contains it.
+
 	if strings.Contains(bodyString, "os-start") {
 		err = c.StartServer(tenant, server)
 	} else if strings.Contains(bodyString, "os-stop") {
 		err = c.StopServer(tenant, server)
+	} else if strings.Contains(bodyString, "unpause") {
+		err = c.UnpauseServer(tenant, server)
+	} else if strings.Contains(bodyString, "pause") {
+		err = c.PauseServer(tenant, server)
+	} else if strings.Contains(bodyString, "suspend") {
+		err = c.SuspendServer(tenant, server)
+	} else if strings.Contains(bodyString, "resume") {
+		err = c.ResumeServer(tenant, server)
 	} else {
 		return Response{http.StatusServiceUnavailable, nil},
 			errors.New("Unsupported Action")
@@ -1318,16 +1999,46 @@ type Service interface {
 	DeleteImage(string, string) error
 	CreateVolume(tenant string, req RequestedVolume) (types.Volume, error)
 	DeleteVolume(tenant string, volume string) error
-	AttachVolume(tenant string, volume string, instance string, mountpoint string) error
+	AttachVolume(tenant string, volume string, instance string, mountpoint string, mode types.AttachmentMode) error
 	DetachVolume(tenant string, volume string, attachment string) error
 	ListVolumesDetail(tenant string) ([]types.Volume, error)
 	ShowVolumeDetails(tenant string, volume string) (types.Volume, error)
+	CreateBackup(tenant string, volume string, req RequestedBackup) (types.Backup, error)
+	ListBackups(tenant string) ([]types.Backup, error)
+	ShowBackup(tenant string, backup string) (types.Backup, error)
+	DeleteBackup(tenant string, backup string) error
+	RestoreBackup(tenant string, backup string, req RequestedRestore) (types.Volume, error)
+	CreateNetwork(tenant string, req RequestedNetwork) (types.TenantNetwork, error)
+	ListNetworks(tenant string) ([]types.TenantNetwork, error)
+	ShowNetwork(tenant string, network string) (types.TenantNetwork, error)
+	DeleteNetwork(tenant string, network string) error
+	ListReservedIPs(tenant string) ([]types.ReservedIP, error)
+	ReleaseReservedIP(tenant string, ip string) error
+	CreateLoadBalancer(tenant string, req RequestedLoadBalancer) (types.LoadBalancer, error)
+	ListLoadBalancers(tenant string) ([]types.LoadBalancer, error)
+	ShowLoadBalancer(tenant string, lb string) (types.LoadBalancer, error)
+	UpdateLoadBalancerMembers(tenant string, lb string, members []RequestedLoadBalancerMember) (types.LoadBalancer, error)
+	DeleteLoadBalancer(tenant string, lb string) error
+	CreateNetworkPeering(tenant string, req RequestedNetworkPeering) (types.NetworkPeering, error)
+	ListNetworkPeerings(tenant string) ([]types.NetworkPeering, error)
+	ShowNetworkPeering(tenant string, peering string) (types.NetworkPeering, error)
+	ApproveNetworkPeering(peering string) (types.NetworkPeering, error)
+	DeleteNetworkPeering(tenant string, peering string) error
 	CreateServer(string, CreateServerRequest) (interface{}, error)
 	ListServersDetail(tenant string) ([]ServerDetails, error)
 	ShowServerDetails(tenant string, server string) (Server, error)
 	DeleteServer(tenant string, server string) error
 	StartServer(tenant string, server string) error
 	StopServer(tenant string, server string) error
+	PauseServer(tenant string, server string) error
+	UnpauseServer(tenant string, server string) error
+	SuspendServer(tenant string, server string) error
+	ResumeServer(tenant string, server string) error
+	ShowServerMetadata(tenant string, server string) (map[string]string, error)
+	UpdateServerMetadata(tenant string, server string, metadata map[string]string) (map[string]string, error)
+	GetConsoleLog(tenant string, server string, lines int) (string, error)
+	OpenConsole(tenant string, server string) (string, error)
+	ConnectConsole(tenant string, server string, token string) (io.ReadWriteCloser, error)
 }
 
 // Context is used to provide the services and current URL to the handlers.
@@ -1571,6 +2282,112 @@ func Routes(config Config, r *mux.Router) *mux.Router {
 	route.Methods("POST")
 	route.HeadersRegexp("Content-Type", matchContent)
 
+	// Backups
+	matchContent = fmt.Sprintf("application/(%s|json)", BackupsV1)
+	route = r.Handle("/{tenant}/volumes/{volume_id}/backups", Handler{context, createBackup, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/backups", Handler{context, listBackups, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/backups/{backup_id}", Handler{context, showBackup, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/backups/{backup_id}", Handler{context, deleteBackup, false})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/backups/{backup_id}/restore", Handler{context, restoreBackup, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	// Networks
+	matchContent = fmt.Sprintf("application/(%s|json)", NetworksV1)
+	route = r.Handle("/{tenant}/networks", Handler{context, createNetwork, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/networks", Handler{context, listNetworks, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/networks/{network_id}", Handler{context, showNetwork, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/networks/{network_id}", Handler{context, deleteNetwork, false})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	// Reserved IPs
+	matchContent = fmt.Sprintf("application/(%s|json)", ReservedIPsV1)
+	route = r.Handle("/{tenant}/reserved_ips", Handler{context, listReservedIPs, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/reserved_ips/{ip}", Handler{context, releaseReservedIP, false})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	// Load balancers
+	matchContent = fmt.Sprintf("application/(%s|json)", LoadBalancersV1)
+	route = r.Handle("/{tenant}/load-balancers", Handler{context, createLoadBalancer, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/load-balancers", Handler{context, listLoadBalancers, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/load-balancers/{lb_id}", Handler{context, showLoadBalancer, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/load-balancers/{lb_id}", Handler{context, deleteLoadBalancer, false})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/load-balancers/{lb_id}/members", Handler{context, updateLoadBalancerMembers, false})
+	route.Methods("PUT")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	// Network peerings
+	matchContent = fmt.Sprintf("application/(%s|json)", NetworkPeeringsV1)
+	route = r.Handle("/network-peerings", Handler{context, listNetworkPeerings, true})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/network-peerings/{peering_id:"+uuid.UUIDRegex+"}", Handler{context, showNetworkPeering, true})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/network-peerings/{peering_id:"+uuid.UUIDRegex+"}", Handler{context, deleteNetworkPeering, true})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/network-peerings/{peering_id:"+uuid.UUIDRegex+"}/approve", Handler{context, approveNetworkPeering, true})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/network-peerings", Handler{context, createNetworkPeering, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/network-peerings", Handler{context, listNetworkPeerings, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/network-peerings/{peering_id}", Handler{context, showNetworkPeering, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/network-peerings/{peering_id}", Handler{context, deleteNetworkPeering, false})
+	route.Methods("DELETE")
+	route.HeadersRegexp("Content-Type", matchContent)
+
 	// Instances
 	matchContent = fmt.Sprintf("application/(%s|json)", InstancesV1)
 
@@ -1594,5 +2411,25 @@ func Routes(config Config, r *mux.Router) *mux.Router {
 	route.Methods("POST")
 	route.HeadersRegexp("Content-Type", matchContent)
 
+	route = r.Handle("/{tenant}/instances/{instance_id}/metadata", Handler{context, showInstanceMetadata, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/instances/{instance_id}/metadata", Handler{context, updateInstanceMetadata, false})
+	route.Methods("PUT")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/instances/{instance_id}/console-log", Handler{context, showInstanceConsoleLog, false})
+	route.Methods("GET")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	route = r.Handle("/{tenant}/instances/{instance_id}/console", Handler{context, openInstanceConsole, false})
+	route.Methods("POST")
+	route.HeadersRegexp("Content-Type", matchContent)
+
+	// WebSocket clients are not expected to set the content type.
+	route = r.Handle("/{tenant}/instances/{instance_id}/console", consoleHandler{context})
+	route.Methods("GET")
+
 	return r
 }
@@ -18,8 +18,10 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	storage "github.com/ciao-project/ciao/ciao-storage"
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/service"
+	"github.com/gorilla/websocket"
 )
 
 type test struct {
@@ -45,7 +48,7 @@ var tests = []test{
 		"",
 		"application/text",
 		http.StatusOK,
-		`[{"rel":"pools","href":"/pools","version":"x.ciao.pools.v1","minimum_version":"x.ciao.pools.v1"},{"rel":"external-ips","href":"/external-ips","version":"x.ciao.external-ips.v1","minimum_version":"x.ciao.external-ips.v1"},{"rel":"workloads","href":"/workloads","version":"x.ciao.workloads.v1","minimum_version":"x.ciao.workloads.v1"},{"rel":"tenants","href":"/tenants","version":"x.ciao.tenants.v1","minimum_version":"x.ciao.tenants.v1"},{"rel":"node","href":"/node","version":"x.ciao.node.v1","minimum_version":"x.ciao.node.v1"},{"rel":"images","href":"/images","version":"x.ciao.images.v1","minimum_version":"x.ciao.images.v1"}]`,
+		`[{"rel":"pools","href":"/pools","version":"x.ciao.pools.v1","minimum_version":"x.ciao.pools.v1"},{"rel":"external-ips","href":"/external-ips","version":"x.ciao.external-ips.v1","minimum_version":"x.ciao.external-ips.v1"},{"rel":"workloads","href":"/workloads","version":"x.ciao.workloads.v1","minimum_version":"x.ciao.workloads.v1"},{"rel":"tenants","href":"/tenants","version":"x.ciao.tenants.v1","minimum_version":"x.ciao.tenants.v1"},{"rel":"node","href":"/node","version":"x.ciao.node.v1","minimum_version":"x.ciao.node.v1"},{"rel":"images","href":"/images","version":"x.ciao.images.v1","minimum_version":"x.ciao.images.v1"},{"rel":"network-peerings","href":"/network-peerings","version":"x.ciao.network-peerings.v1","minimum_version":"x.ciao.network-peerings.v1"}]`,
 	},
 	{
 		"GET",
@@ -244,7 +247,7 @@ var tests = []test{
 		`{"size": 10,"source_volid": null,"description":null,"name":null,"imageRef":null}`,
 		fmt.Sprintf("application/%s", VolumesV1),
 		http.StatusAccepted,
-		`{"id":"new-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"new volume","description":"newly created volume","internal":false}`,
+		`{"id":"new-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"new volume","description":"newly created volume","internal":false,"multiattach":false}`,
 	},
 	{
 		"GET",
@@ -252,7 +255,7 @@ var tests = []test{
 		"",
 		fmt.Sprintf("application/%s", VolumesV1),
 		http.StatusOK,
-		`[{"id":"new-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"my volume","description":"my volume for stuff","internal":false},{"id":"new-test-id2","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"volume 2","description":"my other volume","internal":false}]`,
+		`[{"id":"new-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"my volume","description":"my volume for stuff","internal":false,"multiattach":false},{"id":"new-test-id2","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"volume 2","description":"my other volume","internal":false,"multiattach":false}]`,
 	},
 	{
 		"GET",
@@ -260,7 +263,7 @@ var tests = []test{
 		"",
 		fmt.Sprintf("application/%s", VolumesV1),
 		http.StatusOK,
-		`{"id":"new-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"my volume","description":"my volume for stuff","internal":false}`,
+		`{"id":"new-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":123456,"tenant_id":"test-tenant-id","state":"available","created":"0001-01-01T00:00:00Z","name":"my volume","description":"my volume for stuff","internal":false,"multiattach":false}`,
 	},
 	{
 		"DELETE",
@@ -278,6 +281,38 @@ var tests = []test{
 		http.StatusAccepted,
 		"null",
 	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"attach":{"instance_uuid":"validinstanceid","mountpoint":"/dev/vdc","mode":"ro"}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"attach":{"instance_uuid":"validinstanceid","mountpoint":"/dev/vdc","mode":"wo"}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusBadRequest,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"attach":{"instance_uuid":"validinstanceid","mountpoint":"/dev/vdc","mode":1}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusBadRequest,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"attach":{"instance_uuid":"validinstanceid","mountpoint":"/dev/vdc","mode":null}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusBadRequest,
+		"null",
+	},
 	{
 		"POST",
 		"/validtenantid/volumes/validvolumeid/action",
@@ -286,6 +321,262 @@ var tests = []test{
 		http.StatusAccepted,
 		"null",
 	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/action",
+		`{"detach":{"attachment-id":"validattachmentid"}}`,
+		fmt.Sprintf("application/%s", VolumesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/volumes/validvolumeid/backups",
+		`{"name":"my backup"}`,
+		fmt.Sprintf("application/%s", BackupsV1),
+		http.StatusAccepted,
+		`{"id":"validbackupid","tenant_id":"validtenantid","volume_id":"validvolumeid","volume_size":10,"state":"creating","created":"0001-01-01T00:00:00Z","name":"my backup","description":"","size":0,"checksum":""}`,
+	},
+	{
+		"GET",
+		"/validtenantid/backups",
+		"",
+		fmt.Sprintf("application/%s", BackupsV1),
+		http.StatusOK,
+		`[{"id":"validbackupid","tenant_id":"validtenantid","volume_id":"validvolumeid","volume_size":10,"state":"available","created":"0001-01-01T00:00:00Z","name":"my backup","description":"","size":1024,"checksum":"abcdef"}]`,
+	},
+	{
+		"GET",
+		"/validtenantid/backups/validbackupid",
+		"",
+		fmt.Sprintf("application/%s", BackupsV1),
+		http.StatusOK,
+		`{"id":"validbackupid","tenant_id":"validtenantid","volume_id":"validvolumeid","volume_size":10,"state":"available","created":"0001-01-01T00:00:00Z","name":"my backup","description":"","size":1024,"checksum":"abcdef"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/backups/unknownbackupid",
+		"",
+		fmt.Sprintf("application/%s", BackupsV1),
+		http.StatusNotFound,
+		"{\"error\":{\"code\":404,\"name\":\"Not Found\",\"message\":\"Backup not found\"}}\n",
+	},
+	{
+		"DELETE",
+		"/validtenantid/backups/validbackupid",
+		"",
+		fmt.Sprintf("application/%s", BackupsV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/backups/validbackupid/restore",
+		`{"name":"restored volume"}`,
+		fmt.Sprintf("application/%s", BackupsV1),
+		http.StatusAccepted,
+		`{"id":"restored-test-id","bootable":false,"boot_index":0,"ephemeral":false,"local":false,"swap":false,"size":10,"tenant_id":"validtenantid","state":"creating","created":"0001-01-01T00:00:00Z","name":"restored volume","description":"","internal":false,"multiattach":false}`,
+	},
+	{
+		"POST",
+		"/validtenantid/networks",
+		`{"name":"my network","cidr":"10.10.0.0/24","gateway":"10.10.0.254","allocation_start":"10.10.0.10","allocation_end":"10.10.0.100"}`,
+		fmt.Sprintf("application/%s", NetworksV1),
+		http.StatusCreated,
+		`{"id":"validnetworkid","tenant_id":"validtenantid","name":"my network","cidr":"10.10.0.0/24","gateway":"10.10.0.254","allocation_start":"10.10.0.10","allocation_end":"10.10.0.100","created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/networks",
+		"",
+		fmt.Sprintf("application/%s", NetworksV1),
+		http.StatusOK,
+		`[{"id":"validnetworkid","tenant_id":"validtenantid","name":"my network","cidr":"10.10.0.0/24","gateway":"10.10.0.254","allocation_start":"10.10.0.10","allocation_end":"10.10.0.100","created":"0001-01-01T00:00:00Z"}]`,
+	},
+	{
+		"GET",
+		"/validtenantid/networks/validnetworkid",
+		"",
+		fmt.Sprintf("application/%s", NetworksV1),
+		http.StatusOK,
+		`{"id":"validnetworkid","tenant_id":"validtenantid","name":"my network","cidr":"10.10.0.0/24","gateway":"10.10.0.254","allocation_start":"10.10.0.10","allocation_end":"10.10.0.100","created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/networks/unknownnetworkid",
+		"",
+		fmt.Sprintf("application/%s", NetworksV1),
+		http.StatusNotFound,
+		"{\"error\":{\"code\":404,\"name\":\"Not Found\",\"message\":\"Network not found\"}}\n",
+	},
+	{
+		"DELETE",
+		"/validtenantid/networks/validnetworkid",
+		"",
+		fmt.Sprintf("application/%s", NetworksV1),
+		http.StatusNoContent,
+		"null",
+	},
+	{
+		"GET",
+		"/validtenantid/reserved_ips",
+		"",
+		fmt.Sprintf("application/%s", ReservedIPsV1),
+		http.StatusOK,
+		`[{"tenant_id":"validtenantid","ip_address":"172.16.0.10","instance_id":"validinstanceid","created":"0001-01-01T00:00:00Z"}]`,
+	},
+	{
+		"DELETE",
+		"/validtenantid/reserved_ips/172.16.0.10",
+		"",
+		fmt.Sprintf("application/%s", ReservedIPsV1),
+		http.StatusNoContent,
+		"null",
+	},
+	{
+		"DELETE",
+		"/validtenantid/reserved_ips/172.16.0.11",
+		"",
+		fmt.Sprintf("application/%s", ReservedIPsV1),
+		http.StatusNotFound,
+		"{\"error\":{\"code\":404,\"name\":\"Not Found\",\"message\":\"Address Not Found\"}}\n",
+	},
+	{
+		"POST",
+		"/validtenantid/load-balancers",
+		`{"name":"web","port":80,"protocol":"http","members":[{"instance_id":"validinstanceid","port":8080}]}`,
+		fmt.Sprintf("application/%s", LoadBalancersV1),
+		http.StatusCreated,
+		`{"id":"validlbid","tenant_id":"validtenantid","name":"web","vip":"10.0.0.5","pool_id":"validpoolid","pool_name":"pool","subnet":"172.16.0.0/24","port":80,"protocol":"http","members":[{"instance_id":"validinstanceid","ip_address":"172.16.0.10","port":8080}],"health_check":{"interval":5,"timeout":2,"retries":3,"path":"/"},"created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/load-balancers",
+		"",
+		fmt.Sprintf("application/%s", LoadBalancersV1),
+		http.StatusOK,
+		`[{"id":"validlbid","tenant_id":"validtenantid","name":"web","vip":"10.0.0.5","pool_id":"validpoolid","pool_name":"pool","subnet":"172.16.0.0/24","port":80,"protocol":"http","members":[{"instance_id":"validinstanceid","ip_address":"172.16.0.10","port":8080}],"health_check":{"interval":5,"timeout":2,"retries":3,"path":"/"},"created":"0001-01-01T00:00:00Z"}]`,
+	},
+	{
+		"GET",
+		"/validtenantid/load-balancers/validlbid",
+		"",
+		fmt.Sprintf("application/%s", LoadBalancersV1),
+		http.StatusOK,
+		`{"id":"validlbid","tenant_id":"validtenantid","name":"web","vip":"10.0.0.5","pool_id":"validpoolid","pool_name":"pool","subnet":"172.16.0.0/24","port":80,"protocol":"http","members":[{"instance_id":"validinstanceid","ip_address":"172.16.0.10","port":8080}],"health_check":{"interval":5,"timeout":2,"retries":3,"path":"/"},"created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/load-balancers/unknownlbid",
+		"",
+		fmt.Sprintf("application/%s", LoadBalancersV1),
+		http.StatusNotFound,
+		"{\"error\":{\"code\":404,\"name\":\"Not Found\",\"message\":\"Load balancer not found\"}}\n",
+	},
+	{
+		"PUT",
+		"/validtenantid/load-balancers/validlbid/members",
+		`[{"instance_id":"validinstanceid","port":8080}]`,
+		fmt.Sprintf("application/%s", LoadBalancersV1),
+		http.StatusOK,
+		`{"id":"validlbid","tenant_id":"validtenantid","name":"web","vip":"10.0.0.5","pool_id":"validpoolid","pool_name":"pool","subnet":"172.16.0.0/24","port":80,"protocol":"http","members":[{"instance_id":"validinstanceid","ip_address":"172.16.0.10","port":8080}],"health_check":{"interval":5,"timeout":2,"retries":3,"path":"/"},"created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"DELETE",
+		"/validtenantid/load-balancers/validlbid",
+		"",
+		fmt.Sprintf("application/%s", LoadBalancersV1),
+		http.StatusNoContent,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/network-peerings",
+		`{"name":"shared","network_id":"validnetworkid","peer_tenant_id":"peertenantid","peer_network_id":"peernetworkid"}`,
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusCreated,
+		`{"id":"5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5","name":"shared","tenant_id":"validtenantid","network_id":"validnetworkid","cidr":"172.16.0.0/24","peer_tenant_id":"peertenantid","peer_network_id":"peernetworkid","peer_cidr":"172.17.0.0/24","status":"pending","created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"POST",
+		"/validtenantid/network-peerings",
+		`{"name":"shared","network_id":"validnetworkid","peer_tenant_id":"peertenantid","peer_network_id":"overlappingnetworkid"}`,
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusForbidden,
+		"{\"error\":{\"code\":403,\"name\":\"Forbidden\",\"message\":\"Peered networks overlap\"}}\n",
+	},
+	{
+		"GET",
+		"/validtenantid/network-peerings",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusOK,
+		`[{"id":"5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5","name":"shared","tenant_id":"validtenantid","network_id":"validnetworkid","cidr":"172.16.0.0/24","peer_tenant_id":"peertenantid","peer_network_id":"peernetworkid","peer_cidr":"172.17.0.0/24","status":"pending","created":"0001-01-01T00:00:00Z"}]`,
+	},
+	{
+		"GET",
+		"/validtenantid/network-peerings/5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusOK,
+		`{"id":"5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5","name":"shared","tenant_id":"validtenantid","network_id":"validnetworkid","cidr":"172.16.0.0/24","peer_tenant_id":"peertenantid","peer_network_id":"peernetworkid","peer_cidr":"172.17.0.0/24","status":"pending","created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/network-peerings/unknownpeeringid",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusNotFound,
+		"{\"error\":{\"code\":404,\"name\":\"Not Found\",\"message\":\"Network peering not found\"}}\n",
+	},
+	{
+		"DELETE",
+		"/validtenantid/network-peerings/5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusNoContent,
+		"null",
+	},
+	{
+		"GET",
+		"/network-peerings",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusOK,
+		`[{"id":"5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5","name":"shared","tenant_id":"validtenantid","network_id":"validnetworkid","cidr":"172.16.0.0/24","peer_tenant_id":"peertenantid","peer_network_id":"peernetworkid","peer_cidr":"172.17.0.0/24","status":"pending","created":"0001-01-01T00:00:00Z"}]`,
+	},
+	{
+		"POST",
+		"/network-peerings/5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5/approve",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusOK,
+		`{"id":"5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5","name":"shared","tenant_id":"validtenantid","network_id":"validnetworkid","cidr":"172.16.0.0/24","peer_tenant_id":"peertenantid","peer_network_id":"peernetworkid","peer_cidr":"172.17.0.0/24","status":"active","created":"0001-01-01T00:00:00Z"}`,
+	},
+	{
+		"DELETE",
+		"/network-peerings/5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5",
+		"",
+		fmt.Sprintf("application/%s", NetworkPeeringsV1),
+		http.StatusNoContent,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances",
+		`{"server":{"name":"fixed-ip-test","workload_id":"validworkloadid","ip_address":"172.16.0.10","retain_ip":true}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		`{"server":{"id":"validServerID","name":"fixed-ip-test","imageRef":"","workload_id":"validworkloadid","max_count":0,"min_count":0,"ip_address":"172.16.0.10","retain_ip":true}}`,
+	},
+	{
+		"POST",
+		"/validtenantid/instances",
+		`{"server":{"name":"fixed-ip-test","workload_id":"validworkloadid","ip_address":"172.16.0.11"}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusConflict,
+		"{\"error\":{\"code\":409,\"name\":\"Conflict\",\"message\":\"Address already in use\"}}\n",
+	},
 	{
 		"POST",
 		"/validtenantid/instances",
@@ -333,6 +624,118 @@ var tests = []test{
 		http.StatusAccepted,
 		"null",
 	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"pause":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"unpause":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"suspend":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/action",
+		`{"resume":null}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusAccepted,
+		"null",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/metadata",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusOK,
+		`{"metadata":{"role":"web"}}`,
+	},
+	{
+		"PUT",
+		"/validtenantid/instances/instanceid/metadata",
+		`{"metadata":{"role":"db"}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusOK,
+		`{"metadata":{"role":"db"}}`,
+	},
+	{
+		"PUT",
+		"/validtenantid/instances/instanceid/metadata",
+		`{"metadata":{"":"db"}}`,
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusForbidden,
+		"{\"error\":{\"code\":403,\"name\":\"Forbidden\",\"message\":\"Invalid Request\"}}\n",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console-log?lines=1",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusOK,
+		`{"log":"login:\n"}`,
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console-log?lines=-1",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusBadRequest,
+		"{\"error\":{\"code\":400,\"name\":\"Bad Request\",\"message\":\"Invalid number of lines -1\"}}\n",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/containerid/console-log",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusForbidden,
+		"{\"error\":{\"code\":403,\"name\":\"Forbidden\",\"message\":\"Console logs are not available for containers\"}}\n",
+	},
+	{
+		"POST",
+		"/validtenantid/instances/instanceid/console",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusCreated,
+		`{"token":"validtoken"}`,
+	},
+	{
+		"POST",
+		"/validtenantid/instances/stoppedid/console",
+		"",
+		fmt.Sprintf("application/%s", InstancesV1),
+		http.StatusForbidden,
+		"{\"error\":{\"code\":403,\"name\":\"Forbidden\",\"message\":\"Instance is not running\"}}\n",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console",
+		"",
+		"",
+		http.StatusBadRequest,
+		"{\"error\":{\"code\":400,\"name\":\"Bad Request\",\"message\":\"Missing console token\"}}\n",
+	},
+	{
+		"GET",
+		"/validtenantid/instances/instanceid/console?token=invalidtoken",
+		"",
+		"",
+		http.StatusForbidden,
+		"{\"error\":{\"code\":403,\"name\":\"Forbidden\",\"message\":\"Invalid console token\"}}\n",
+	},
 }
 
 type testCiaoService struct{}
@@ -628,7 +1031,7 @@ func (ts testCiaoService) DeleteVolume(tenant string, volume string) error {
 	return nil
 }
 
-func (ts testCiaoService) AttachVolume(tenant string, volume string, instance string, mountpoint string) error {
+func (ts testCiaoService) AttachVolume(tenant string, volume string, instance string, mountpoint string, mode types.AttachmentMode) error {
 	return nil
 }
 
@@ -661,7 +1064,209 @@ func (ts testCiaoService) ListVolumesDetail(tenant string) ([]types.Volume, erro
 	}, nil
 }
 
+func testBackup() types.Backup {
+	return types.Backup{
+		ID:         "validbackupid",
+		TenantID:   "validtenantid",
+		VolumeID:   "validvolumeid",
+		VolumeSize: 10,
+		State:      types.BackupAvailable,
+		Name:       "my backup",
+		Size:       1024,
+		Checksum:   "abcdef",
+	}
+}
+
+func (ts testCiaoService) CreateBackup(tenant string, volume string, req RequestedBackup) (types.Backup, error) {
+	return types.Backup{
+		ID:         "validbackupid",
+		TenantID:   tenant,
+		VolumeID:   volume,
+		VolumeSize: 10,
+		State:      types.BackupCreating,
+		Name:       req.Name,
+	}, nil
+}
+
+func (ts testCiaoService) ListBackups(tenant string) ([]types.Backup, error) {
+	return []types.Backup{testBackup()}, nil
+}
+
+func (ts testCiaoService) ShowBackup(tenant string, backup string) (types.Backup, error) {
+	if backup != "validbackupid" {
+		return types.Backup{}, types.ErrBackupNotFound
+	}
+	return testBackup(), nil
+}
+
+func (ts testCiaoService) DeleteBackup(tenant string, backup string) error {
+	return nil
+}
+
+func (ts testCiaoService) RestoreBackup(tenant string, backup string, req RequestedRestore) (types.Volume, error) {
+	return types.Volume{
+		BlockDevice: storage.BlockDevice{
+			ID:   "restored-test-id",
+			Size: 10,
+		},
+		State:    types.Creating,
+		Name:     req.Name,
+		TenantID: tenant,
+	}, nil
+}
+
+func testNetwork() types.TenantNetwork {
+	return types.TenantNetwork{
+		ID:              "validnetworkid",
+		TenantID:        "validtenantid",
+		Name:            "my network",
+		CIDR:            "10.10.0.0/24",
+		Gateway:         "10.10.0.254",
+		AllocationStart: "10.10.0.10",
+		AllocationEnd:   "10.10.0.100",
+	}
+}
+
+func (ts testCiaoService) CreateNetwork(tenant string, req RequestedNetwork) (types.TenantNetwork, error) {
+	return types.TenantNetwork{
+		ID:              "validnetworkid",
+		TenantID:        tenant,
+		Name:            req.Name,
+		CIDR:            req.CIDR,
+		Gateway:         req.Gateway,
+		AllocationStart: req.AllocationStart,
+		AllocationEnd:   req.AllocationEnd,
+	}, nil
+}
+
+func (ts testCiaoService) ListNetworks(tenant string) ([]types.TenantNetwork, error) {
+	return []types.TenantNetwork{testNetwork()}, nil
+}
+
+func (ts testCiaoService) ShowNetwork(tenant string, network string) (types.TenantNetwork, error) {
+	if network != "validnetworkid" {
+		return types.TenantNetwork{}, types.ErrNetworkNotFound
+	}
+	return testNetwork(), nil
+}
+
+func (ts testCiaoService) DeleteNetwork(tenant string, network string) error {
+	return nil
+}
+
+func (ts testCiaoService) ListReservedIPs(tenant string) ([]types.ReservedIP, error) {
+	return []types.ReservedIP{
+		{
+			TenantID:   tenant,
+			IPAddress:  "172.16.0.10",
+			InstanceID: "validinstanceid",
+		},
+	}, nil
+}
+
+func (ts testCiaoService) ReleaseReservedIP(tenant string, ip string) error {
+	if ip != "172.16.0.10" {
+		return types.ErrAddressNotFound
+	}
+	return nil
+}
+
+func testLoadBalancer() types.LoadBalancer {
+	return types.LoadBalancer{
+		ID:       "validlbid",
+		TenantID: "validtenantid",
+		Name:     "web",
+		VIP:      "10.0.0.5",
+		PoolID:   "validpoolid",
+		PoolName: "pool",
+		Subnet:   "172.16.0.0/24",
+		Port:     80,
+		Protocol: "http",
+		Members: []types.LoadBalancerMember{
+			{
+				InstanceID: "validinstanceid",
+				IPAddress:  "172.16.0.10",
+				Port:       8080,
+			},
+		},
+		HealthCheck: types.LoadBalancerHealthCheck{
+			Interval: 5,
+			Timeout:  2,
+			Retries:  3,
+			Path:     "/",
+		},
+	}
+}
+
+func (ts testCiaoService) CreateLoadBalancer(tenant string, req RequestedLoadBalancer) (types.LoadBalancer, error) {
+	return testLoadBalancer(), nil
+}
+
+func (ts testCiaoService) ListLoadBalancers(tenant string) ([]types.LoadBalancer, error) {
+	return []types.LoadBalancer{testLoadBalancer()}, nil
+}
+
+func (ts testCiaoService) ShowLoadBalancer(tenant string, lb string) (types.LoadBalancer, error) {
+	if lb != "validlbid" {
+		return types.LoadBalancer{}, types.ErrLoadBalancerNotFound
+	}
+	return testLoadBalancer(), nil
+}
+
+func (ts testCiaoService) UpdateLoadBalancerMembers(tenant string, lb string, members []RequestedLoadBalancerMember) (types.LoadBalancer, error) {
+	return testLoadBalancer(), nil
+}
+
+func (ts testCiaoService) DeleteLoadBalancer(tenant string, lb string) error {
+	return nil
+}
+
+func testNetworkPeering() types.NetworkPeering {
+	return types.NetworkPeering{
+		ID:            "5f5ac8d8-3d47-4d0b-9bd0-5ab8a1c6b2c5",
+		Name:          "shared",
+		TenantID:      "validtenantid",
+		NetworkID:     "validnetworkid",
+		CIDR:          "172.16.0.0/24",
+		PeerTenantID:  "peertenantid",
+		PeerNetworkID: "peernetworkid",
+		PeerCIDR:      "172.17.0.0/24",
+		Status:        types.PeeringPending,
+	}
+}
+
+func (ts testCiaoService) CreateNetworkPeering(tenant string, req RequestedNetworkPeering) (types.NetworkPeering, error) {
+	if req.PeerNetworkID == "overlappingnetworkid" {
+		return types.NetworkPeering{}, types.ErrPeeringOverlap
+	}
+	return testNetworkPeering(), nil
+}
+
+func (ts testCiaoService) ListNetworkPeerings(tenant string) ([]types.NetworkPeering, error) {
+	return []types.NetworkPeering{testNetworkPeering()}, nil
+}
+
+func (ts testCiaoService) ShowNetworkPeering(tenant string, peering string) (types.NetworkPeering, error) {
+	if peering != testNetworkPeering().ID {
+		return types.NetworkPeering{}, types.ErrNetworkPeeringNotFound
+	}
+	return testNetworkPeering(), nil
+}
+
+func (ts testCiaoService) ApproveNetworkPeering(peering string) (types.NetworkPeering, error) {
+	p := testNetworkPeering()
+	p.Status = types.PeeringActive
+	return p, nil
+}
+
+func (ts testCiaoService) DeleteNetworkPeering(tenant string, peering string) error {
+	return nil
+}
+
 func (ts testCiaoService) CreateServer(tenant string, req CreateServerRequest) (interface{}, error) {
+	if req.Server.IPAddress == "172.16.0.11" {
+		return nil, types.ErrAddressInUse
+	}
 	req.Server.ID = "validServerID"
 	return req, nil
 }
@@ -688,6 +1293,42 @@ func (ts testCiaoService) ListServersDetail(tenant string) ([]ServerDetails, err
 	return servers, nil
 }
 
+func (ts testCiaoService) GetConsoleLog(tenant string, server string, lines int) (string, error) {
+	if server == "containerid" {
+		return "", types.ErrConsoleLogNotSupported
+	}
+
+	log := "Booting from Hard Disk...\nlogin:\n"
+	if lines == 1 {
+		log = "login:\n"
+	}
+
+	return log, nil
+}
+
+func (ts testCiaoService) OpenConsole(tenant string, server string) (string, error) {
+	if server == "stoppedid" {
+		return "", types.ErrInstanceNotRunning
+	}
+
+	return "validtoken", nil
+}
+
+// ConnectConsole returns a console that echoes its input for validtoken.
+func (ts testCiaoService) ConnectConsole(tenant string, server string, token string) (io.ReadWriteCloser, error) {
+	if token != "validtoken" {
+		return nil, types.ErrConsoleTokenInvalid
+	}
+
+	console, instance := net.Pipe()
+	go func() {
+		_, _ = io.Copy(instance, instance)
+		_ = instance.Close()
+	}()
+
+	return console, nil
+}
+
 func (ts testCiaoService) ShowServerDetails(tenant string, server string) (Server, error) {
 	s := ServerDetails{
 		NodeID:     "nodeUUID",
@@ -718,6 +1359,34 @@ func (ts testCiaoService) StopServer(tenant string, server string) error {
 	return nil
 }
 
+func (ts testCiaoService) PauseServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) UnpauseServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) SuspendServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) ResumeServer(tenant string, server string) error {
+	return nil
+}
+
+func (ts testCiaoService) ShowServerMetadata(tenant string, server string) (map[string]string, error) {
+	return map[string]string{"role": "web"}, nil
+}
+
+func (ts testCiaoService) UpdateServerMetadata(tenant string, server string, metadata map[string]string) (map[string]string, error) {
+	if metadata[""] != "" {
+		return nil, types.ErrBadRequest
+	}
+
+	return metadata, nil
+}
+
 func TestResponse(t *testing.T) {
 	var ts testCiaoService
 
@@ -747,6 +1416,40 @@ func TestResponse(t *testing.T) {
 	}
 }
 
+func TestConsoleWebSocket(t *testing.T) {
+	var ts testCiaoService
+
+	server := httptest.NewServer(Routes(Config{"", ts}, nil))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") +
+		"/validtenantid/instances/instanceid/console?token=validtoken"
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ws.Close() }()
+
+	const input = "hello\n"
+	if err := ws.WriteMessage(websocket.BinaryMessage, []byte(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	var output []byte
+	_ = ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for len(output) < len(input) {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		output = append(output, data...)
+	}
+
+	if string(output) != input {
+		t.Fatalf("expected %q from console, got %q", input, string(output))
+	}
+}
+
 func TestRoutes(t *testing.T) {
 	var ts testCiaoService
 	config := Config{"", ts}
@@ -756,3 +1459,31 @@ func TestRoutes(t *testing.T) {
 		t.Fatalf("No routes returned")
 	}
 }
+
+func TestErrorResponseConfigTemplate(t *testing.T) {
+	err := &types.ConfigTemplateError{Err: fmt.Errorf("template: config:1: unclosed action")}
+
+	resp := errorResponse(err)
+	if resp.status != http.StatusBadRequest {
+		t.Fatalf("got %v, expected %v", resp.status, http.StatusBadRequest)
+	}
+
+	code, ok := resp.response.(HTTPReturnErrorCode)
+	if !ok || code.Error.Message != err.Error() {
+		t.Fatalf("Template error not reported in response: %v", resp.response)
+	}
+}
+
+func TestErrorResponseInstanceState(t *testing.T) {
+	err := &types.InstanceStateError{Expected: payloads.Suspended}
+
+	resp := errorResponse(err)
+	if resp.status != http.StatusConflict {
+		t.Fatalf("got %v, expected %v", resp.status, http.StatusConflict)
+	}
+
+	code, ok := resp.response.(HTTPReturnErrorCode)
+	if !ok || code.Error.Message != err.Error() {
+		t.Fatalf("State error not reported in response: %v", resp.response)
+	}
+}
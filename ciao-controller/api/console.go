@@ -0,0 +1,111 @@
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Time allowed to send the close message to a console client once the
+// console session has terminated.
+const consoleCloseTimeout = time.Second
+
+var consoleUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+}
+
+// consoleHandler upgrades a request to a WebSocket connection and proxies
+// it to the console session of an instance.  The request must include the
+// token returned when the session was opened.  The output of the console
+// is sent to the client in binary messages and the contents of the messages
+// received from the client are written to the console.
+type consoleHandler struct {
+	*Context
+}
+
+func (h consoleHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tenant := vars["tenant"]
+	server := vars["instance_id"]
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeError(w, http.StatusBadRequest, errors.New("Missing console token"))
+		return
+	}
+
+	console, err := h.ConnectConsole(tenant, server, token)
+	if err != nil {
+		writeError(w, errorResponse(err).status, err)
+		return
+	}
+	defer func() { _ = console.Close() }()
+
+	ws, err := consoleUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied to the client.
+		return
+	}
+	defer func() { _ = ws.Close() }()
+
+	proxyConsole(ws, console)
+}
+
+func proxyConsole(ws *websocket.Conn, console io.ReadWriteCloser) {
+	doneCh := make(chan struct{})
+
+	go func() {
+		defer close(doneCh)
+
+		buf := make([]byte, 4096)
+		for {
+			n, err := console.Read(buf)
+			if n > 0 {
+				if err := ws.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+				_ = ws.WriteControl(websocket.CloseMessage, msg,
+					time.Now().Add(consoleCloseTimeout))
+				break
+			}
+		}
+
+		// Unblocks ReadMessage if the console session terminated first.
+		_ = ws.Close()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		if _, err := console.Write(data); err != nil {
+			break
+		}
+	}
+
+	_ = console.Close()
+	<-doneCh
+}
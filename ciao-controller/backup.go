@@ -0,0 +1,380 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/ciao-storage"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+// CreateBackup snapshots a volume and starts exporting the snapshot to the
+// backup target.  The backup is returned in the creating state and moves
+// to available, or error, once the export has finished.
+func (c *controller) CreateBackup(tenant string, volume string, req api.RequestedBackup) (types.Backup, error) {
+	if c.backupTarget == nil {
+		return types.Backup{}, types.ErrBackupsNotConfigured
+	}
+
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.Backup{}, err
+	}
+
+	info, err := c.ds.GetBlockDevice(volume)
+	if err != nil {
+		return types.Backup{}, err
+	}
+
+	if info.TenantID != tenant || info.Internal {
+		return types.Backup{}, api.ErrVolumeOwner
+	}
+
+	if info.State != types.Available && info.State != types.InUse {
+		return types.Backup{}, api.ErrVolumeNotAvailable
+	}
+
+	b := types.Backup{
+		ID:          uuid.Generate().String(),
+		TenantID:    tenant,
+		VolumeID:    volume,
+		VolumeSize:  info.Size,
+		State:       types.BackupCreating,
+		CreateTime:  time.Now(),
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	// the snapshot gives us a consistent view of the volume
+	// while it is being exported.
+	err = c.CreateBlockDeviceSnapshot(volume, b.ID)
+	if err != nil {
+		return types.Backup{}, err
+	}
+
+	err = c.ds.AddBackup(b)
+	if err != nil {
+		if dErr := c.DeleteBlockDeviceSnapshot(volume, b.ID); dErr != nil {
+			glog.Warningf("Unable to delete backup snapshot %s: %v", b.ID, dErr)
+		}
+		return types.Backup{}, err
+	}
+
+	go c.exportBackup(b)
+
+	return b, nil
+}
+
+func (c *controller) exportBackup(b types.Backup) {
+	glog.Infof("Exporting volume %s to backup %s", b.VolumeID, b.ID)
+
+	err := c.writeBackup(&b)
+
+	if dErr := c.DeleteBlockDeviceSnapshot(b.VolumeID, b.ID); dErr != nil {
+		glog.Warningf("Unable to delete backup snapshot %s: %v", b.ID, dErr)
+	}
+
+	if err != nil {
+		glog.Errorf("Unable to create backup %s: %v", b.ID, err)
+		_ = c.ds.LogError(b.TenantID, fmt.Sprintf("Backup of volume %s failed: %v", b.VolumeID, err))
+		b.State = types.BackupError
+	} else {
+		glog.Infof("Backup %s created", b.ID)
+		b.State = types.BackupAvailable
+	}
+
+	err = c.ds.UpdateBackup(b)
+	if err != nil {
+		glog.Errorf("Unable to update backup %s: %v", b.ID, err)
+	}
+}
+
+// recoverBackups fails the backups whose export was interrupted by a
+// restart of the controller, so that they can be deleted, and removes the
+// snapshots they were being exported from.  Volumes whose restore was
+// interrupted are deleted.
+func (c *controller) recoverBackups() {
+	c.recoverRestores()
+
+	for _, b := range c.ds.GetAllBackups() {
+		if b.State != types.BackupCreating {
+			continue
+		}
+
+		glog.Warningf("Backup %s interrupted by a controller restart", b.ID)
+
+		if err := c.DeleteBlockDeviceSnapshot(b.VolumeID, b.ID); err != nil {
+			glog.Warningf("Unable to delete backup snapshot %s: %v", b.ID, err)
+		}
+
+		_ = c.ds.LogError(b.TenantID, fmt.Sprintf("Backup of volume %s interrupted by a controller restart", b.VolumeID))
+
+		b.State = types.BackupError
+		if err := c.ds.UpdateBackup(b); err != nil {
+			glog.Errorf("Unable to update backup %s: %v", b.ID, err)
+		}
+	}
+}
+
+func (c *controller) recoverRestores() {
+	tenants, err := c.ds.GetAllTenants()
+	if err != nil {
+		glog.Errorf("Unable to get tenants: %v", err)
+		return
+	}
+
+	for _, t := range tenants {
+		volumes, err := c.ds.GetBlockDevices(t.ID)
+		if err != nil {
+			glog.Errorf("Unable to get volumes of tenant %s: %v", t.ID, err)
+			continue
+		}
+
+		for _, v := range volumes {
+			if v.State != types.Creating {
+				continue
+			}
+
+			glog.Warningf("Restore of volume %s interrupted by a controller restart", v.ID)
+
+			// the import may not have created the block device.
+			_ = c.DeleteBlockDevice(v.ID)
+
+			_ = c.ds.LogError(v.TenantID, fmt.Sprintf("Restore of volume %s interrupted by a controller restart", v.ID))
+
+			c.deleteRestoredVolume(v)
+		}
+	}
+}
+
+// writeBackup streams the snapshot straight to the backup target and
+// records the size and checksum of the backup in b.  The target needs to
+// know the size of the backup up front, and an exported snapshot is as
+// large as its volume.
+func (c *controller) writeBackup(b *types.Backup) error {
+	size, err := c.GetBlockDeviceSize(b.VolumeID)
+	if err != nil {
+		return errors.Wrap(err, "unable to determine backup size")
+	}
+
+	h := sha256.New()
+	pr, pw := io.Pipe()
+	exported := make(chan error, 1)
+
+	go func() {
+		err := c.ExportBlockDeviceSnapshot(b.VolumeID, b.ID, io.MultiWriter(pw, h))
+		_ = pw.CloseWithError(errors.Wrap(err, "unable to export snapshot"))
+		exported <- err
+	}()
+
+	err = c.backupTarget.Put(b.ID, pr, int64(size))
+
+	// stop the export if the target did not read all of it.
+	_ = pr.CloseWithError(errors.New("backup target stopped reading"))
+	exportErr := <-exported
+
+	if err != nil {
+		return errors.Wrap(err, "unable to store backup")
+	}
+
+	if exportErr != nil {
+		return errors.Wrap(exportErr, "unable to export snapshot")
+	}
+
+	b.Size = int64(size)
+	b.Checksum = hex.EncodeToString(h.Sum(nil))
+
+	return nil
+}
+
+func (c *controller) getTenantBackup(tenant string, backup string) (types.Backup, error) {
+	if c.backupTarget == nil {
+		return types.Backup{}, types.ErrBackupsNotConfigured
+	}
+
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.Backup{}, err
+	}
+
+	b, err := c.ds.GetBackup(backup)
+	if err != nil {
+		return types.Backup{}, err
+	}
+
+	if b.TenantID != tenant {
+		return types.Backup{}, types.ErrBackupNotFound
+	}
+
+	return b, nil
+}
+
+// ListBackups returns all the backups owned by a tenant.
+func (c *controller) ListBackups(tenant string) ([]types.Backup, error) {
+	if c.backupTarget == nil {
+		return nil, types.ErrBackupsNotConfigured
+	}
+
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ds.GetBackups(tenant)
+}
+
+// ShowBackup returns a single backup.
+func (c *controller) ShowBackup(tenant string, backup string) (types.Backup, error) {
+	return c.getTenantBackup(tenant, backup)
+}
+
+// DeleteBackup removes a backup from the backup target and the datastore.
+func (c *controller) DeleteBackup(tenant string, backup string) error {
+	b, err := c.getTenantBackup(tenant, backup)
+	if err != nil {
+		return err
+	}
+
+	if b.State == types.BackupCreating {
+		return types.ErrBackupNotAvailable
+	}
+
+	// failed backups may never have made it to the target.
+	err = c.backupTarget.Delete(b.ID)
+	if err != nil && err != storage.ErrNoBackup {
+		return err
+	}
+
+	return c.ds.DeleteBackup(b.ID)
+}
+
+// RestoreBackup creates a new volume from the contents of a backup.  The
+// volume is charged to the tenant's quota and returned in the creating
+// state, and moves to available once the backup data has been imported
+// and checked against the checksum recorded when the backup was created.
+func (c *controller) RestoreBackup(tenant string, backup string, req api.RequestedRestore) (types.Volume, error) {
+	b, err := c.getTenantBackup(tenant, backup)
+	if err != nil {
+		return types.Volume{}, err
+	}
+
+	if b.State != types.BackupAvailable {
+		return types.Volume{}, types.ErrBackupNotAvailable
+	}
+
+	res := <-c.qs.Consume(tenant,
+		payloads.RequestedResource{Type: payloads.Volume, Value: 1},
+		payloads.RequestedResource{Type: payloads.SharedDiskGiB, Value: b.VolumeSize})
+
+	if !res.Allowed() {
+		c.qs.Release(tenant, res.Resources()...)
+		return types.Volume{}, api.ErrQuota
+	}
+
+	data := types.Volume{
+		BlockDevice: storage.BlockDevice{
+			ID:   uuid.Generate().String(),
+			Size: b.VolumeSize,
+		},
+		CreateTime:  time.Now(),
+		TenantID:    tenant,
+		State:       types.Creating,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+
+	err = c.ds.AddBlockDevice(data)
+	if err != nil {
+		c.qs.Release(tenant, res.Resources()...)
+		return types.Volume{}, err
+	}
+
+	go c.importBackup(b, data)
+
+	return data, nil
+}
+
+func (c *controller) importBackup(b types.Backup, data types.Volume) {
+	glog.Infof("Restoring backup %s to volume %s", b.ID, data.ID)
+
+	bd, err := c.readBackup(b, data.ID)
+	if err != nil {
+		glog.Errorf("Unable to restore backup %s: %v", b.ID, err)
+		_ = c.ds.LogError(data.TenantID, fmt.Sprintf("Restore of backup %s failed: %v", b.ID, err))
+		c.deleteRestoredVolume(data)
+		return
+	}
+
+	glog.Infof("Backup %s restored to volume %s", b.ID, data.ID)
+
+	data.Size = bd.Size
+	data.State = types.Available
+
+	err = c.ds.UpdateBlockDevice(data)
+	if err != nil {
+		glog.Errorf("Unable to update volume %s: %v", data.ID, err)
+	}
+}
+
+// readBackup imports the backup into a new block device named volumeID.
+// The block device is removed if its data does not match the checksum of
+// the backup.
+func (c *controller) readBackup(b types.Backup, volumeID string) (storage.BlockDevice, error) {
+	r, err := c.backupTarget.Get(b.ID)
+	if err != nil {
+		return storage.BlockDevice{}, errors.Wrap(err, "unable to read backup")
+	}
+	defer func() { _ = r.Close() }()
+
+	h := sha256.New()
+
+	bd, err := c.ImportBlockDevice(volumeID, io.TeeReader(r, h), b.VolumeSize)
+	if err != nil {
+		return storage.BlockDevice{}, errors.Wrap(err, "unable to import backup")
+	}
+
+	if hex.EncodeToString(h.Sum(nil)) != b.Checksum {
+		if err := c.DeleteBlockDevice(bd.ID); err != nil {
+			glog.Warningf("Unable to delete block device %s: %v", bd.ID, err)
+		}
+		return storage.BlockDevice{}, types.ErrBackupChecksum
+	}
+
+	return bd, nil
+}
+
+// deleteRestoredVolume removes a volume whose restore did not complete
+// and returns it to the tenant's quota.
+func (c *controller) deleteRestoredVolume(data types.Volume) {
+	err := c.ds.DeleteBlockDevice(data.ID)
+	if err != nil {
+		glog.Errorf("Unable to delete volume %s: %v", data.ID, err)
+		return
+	}
+
+	c.qs.Release(data.TenantID,
+		payloads.RequestedResource{Type: payloads.Volume, Value: 1},
+		payloads.RequestedResource{Type: payloads.SharedDiskGiB, Value: data.Size})
+}
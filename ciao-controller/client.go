@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/ciao-project/ciao/ciao-controller/types"
@@ -43,7 +44,20 @@ type controllerClient interface {
 	Disconnect()
 	mapExternalIP(t types.Tenant, m types.MappedIP) error
 	unMapExternalIP(t types.Tenant, m types.MappedIP) error
-	attachVolume(volID string, instanceID string, nodeID string) error
+	updateDNS(t types.Tenant, cnciID string, subnet string, records []payloads.DNSRecord) error
+	updateMetadata(t types.Tenant, cnciID string, subnet string, instances []payloads.InstanceMetadata) error
+	updatePeering(t types.Tenant, cnciID string, subnet string, peers []payloads.PeerSubnet) error
+	updateConcentrator(nodeID string, tenantID string, subnet string, cnci *types.Instance) error
+	configureLoadBalancer(t types.Tenant, lb types.LoadBalancer) error
+	removeLoadBalancer(t types.Tenant, lb types.LoadBalancer) error
+	attachVolume(volID string, instanceID string, nodeID string, readOnly bool, shared bool) error
+	detachVolume(volID string, instanceID string, nodeID string) error
+	getConsoleLog(instanceID string, nodeID string, lines int, requestID string) error
+	openConsole(instanceID string, nodeID string, token string) error
+	pauseInstance(instanceID string, nodeID string) error
+	unpauseInstance(instanceID string, nodeID string) error
+	suspendInstance(instanceID string, nodeID string) error
+	resumeInstance(instanceID string, nodeID string, memMB int) error
 	ssntpClient() *ssntp.Client
 }
 
@@ -51,6 +65,11 @@ type ssntpClient struct {
 	ctl   *controller
 	ssntp ssntp.Client
 	name  string
+
+	// restartOnStop holds the instances that are to be restarted as soon
+	// as their node reports that they have been stopped.
+	restartLock   sync.Mutex
+	restartOnStop map[string]bool
 }
 
 func (client *ssntpClient) ConnectNotify() {
@@ -109,9 +128,7 @@ func (client *ssntpClient) releaseResources(instanceID string) error {
 		return errors.Wrapf(err, "error getting workload for instance from datastore")
 	}
 
-	resources := []payloads.RequestedResource{{Type: payloads.Instance, Value: 1}}
-	resources = append(resources, wl.Defaults...)
-	client.ctl.qs.Release(i.TenantID, resources...)
+	client.ctl.qs.Release(i.TenantID, instanceResources(i, &wl)...)
 	return nil
 }
 
@@ -146,6 +163,10 @@ func (client *ssntpClient) RemoveInstance(instanceID string) {
 		}
 	}
 
+	client.ctl.updateInstanceDNS(i)
+	client.ctl.updateInstanceMetadata(i)
+	client.ctl.removeLoadBalancerMember(i)
+
 	// notify anyone is listening for a state change
 	transitionInstanceState(i, payloads.Deleted)
 }
@@ -168,12 +189,26 @@ func (client *ssntpClient) instanceStopped(payload []byte) {
 		return
 	}
 	instanceID := event.InstanceStopped.InstanceUUID
-	glog.Infof("Stopped instance %s", instanceID)
+	if event.InstanceStopped.StopMethod != "" {
+		glog.Infof("Stopped instance %s (%s)", instanceID, event.InstanceStopped.StopMethod)
+	} else {
+		glog.Infof("Stopped instance %s", instanceID)
+	}
+
+	i := client.markInstanceStopped(instanceID)
+	if i != nil && client.takeRestartOnStop(instanceID) {
+		client.restartStoppedInstance(i)
+	}
+}
 
+// markInstanceStopped records that an instance is no longer running on
+// any node.  Stopped CNCIs are restarted straight away by their tenant's
+// CNCI manager.
+func (client *ssntpClient) markInstanceStopped(instanceID string) *types.Instance {
 	i, err := client.ctl.ds.GetInstance(instanceID)
 	if err != nil {
 		glog.Warningf("Error getting instance from datastore: %v", err)
-		return
+		return nil
 	}
 
 	err = client.ctl.ds.InstanceStopped(instanceID)
@@ -185,13 +220,153 @@ func (client *ssntpClient) instanceStopped(payload []byte) {
 		tenant, err := client.ctl.ds.GetTenant(i.TenantID)
 		if err != nil {
 			glog.Warningf("Error retrieving tenant %v", err)
-			return
+			return i
 		}
 		err = tenant.CNCIctrl.CNCIStopped(i.ID)
 		if err != nil {
 			glog.Warningf("Error stopping CNCI: %v", err)
 		}
 	}
+
+	return i
+}
+
+func (client *ssntpClient) setRestartOnStop(instanceID string) {
+	client.restartLock.Lock()
+	if client.restartOnStop == nil {
+		client.restartOnStop = make(map[string]bool)
+	}
+	client.restartOnStop[instanceID] = true
+	client.restartLock.Unlock()
+}
+
+func (client *ssntpClient) takeRestartOnStop(instanceID string) bool {
+	client.restartLock.Lock()
+	restart := client.restartOnStop[instanceID]
+	delete(client.restartOnStop, instanceID)
+	client.restartLock.Unlock()
+	return restart
+}
+
+// restartStoppedInstance restarts an instance that has been marked as
+// stopped.  CNCIs are skipped as markInstanceStopped has already taken
+// care of them.
+func (client *ssntpClient) restartStoppedInstance(i *types.Instance) {
+	if i.CNCI {
+		return
+	}
+
+	// restartInstance may block until the instance's CNCI is active so
+	// we must not call it from the SSNTP notifier.
+	go func() {
+		err := client.ctl.restartInstance(i.ID)
+		if err != nil {
+			msg := fmt.Sprintf("Unable to restart instance %s: %v", i.ID, err)
+			glog.Warning(msg)
+			_ = client.ctl.ds.LogError(i.TenantID, msg)
+		}
+	}()
+}
+
+// reconcileInstance brings the controller's view of an instance reported
+// by a node in its inventory in line with the node's.  Instances the
+// controller no longer knows about were deleted while the node was
+// disconnected and are deleted from the node.  Instances the node lost
+// when it went down are stopped and then restarted.  The decision is
+// based on the inventory alone as the node's STATS may be processed
+// first.
+func (client *ssntpClient) reconcileInstance(nodeID string, ins payloads.InstanceInventory) {
+	i, err := client.ctl.ds.GetInstance(ins.InstanceUUID)
+	if err != nil {
+		msg := fmt.Sprintf("Deleting unknown instance %s from node %s",
+			ins.InstanceUUID, nodeID)
+		glog.Info(msg)
+		_ = client.ctl.ds.LogEvent(ins.TenantUUID, msg)
+		err = client.DeleteInstance(ins.InstanceUUID, nodeID)
+		if err != nil {
+			glog.Warningf("Error deleting instance %s: %v", ins.InstanceUUID, err)
+		}
+		return
+	}
+
+	if i.NodeID != "" && i.NodeID != nodeID {
+		msg := fmt.Sprintf("Instance %s reported by node %s is assigned to node %s",
+			i.ID, nodeID, i.NodeID)
+		glog.Warning(msg)
+		_ = client.ctl.ds.LogError(i.TenantID, msg)
+		return
+	}
+
+	if !ins.Lost {
+		return
+	}
+
+	msg := fmt.Sprintf("Restarting instance %s which was lost when node %s went down",
+		i.ID, nodeID)
+	glog.Info(msg)
+	_ = client.ctl.ds.LogEvent(i.TenantID, msg)
+	client.setRestartOnStop(i.ID)
+	err = client.StopInstance(i.ID, nodeID)
+	if err != nil {
+		client.takeRestartOnStop(i.ID)
+		msg = fmt.Sprintf("Unable to stop instance %s: %v", i.ID, err)
+		glog.Warning(msg)
+		_ = client.ctl.ds.LogError(i.TenantID, msg)
+	}
+}
+
+// reconcileMissingInstance handles an instance that the controller
+// believes to be hosted by a node but which is absent from the node's
+// inventory.  The node has already stopped the instance, e.g., because
+// it shut down while the node was disconnected, so the instance is
+// marked as stopped but is not restarted.
+func (client *ssntpClient) reconcileMissingInstance(i *types.Instance) {
+	msg := fmt.Sprintf("Instance %s is no longer present on node %s",
+		i.ID, i.NodeID)
+	glog.Info(msg)
+	_ = client.ctl.ds.LogEvent(i.TenantID, msg)
+
+	_ = client.markInstanceStopped(i.ID)
+}
+
+func (client *ssntpClient) nodeInventory(payload []byte) {
+	var event payloads.EventNodeInventory
+	err := yaml.Unmarshal(payload, &event)
+	if err != nil {
+		glog.Warningf("Error unmarshalling NodeInventory: %v", err)
+		return
+	}
+
+	nodeID := event.Inventory.NodeUUID
+	glog.Infof("Reconciling %d instances reported by node %s",
+		len(event.Inventory.Instances), nodeID)
+
+	reported := make(map[string]bool)
+	for _, ins := range event.Inventory.Instances {
+		reported[ins.InstanceUUID] = true
+		client.reconcileInstance(nodeID, ins)
+	}
+
+	instances, err := client.ctl.ds.GetAllInstances()
+	if err != nil {
+		glog.Warningf("Error getting instances from datastore: %v", err)
+		return
+	}
+
+	cncis, err := client.ctl.ds.GetAllCNCIInstances()
+	if err != nil {
+		glog.Warningf("Error getting CNCIs from datastore: %v", err)
+		return
+	}
+
+	// Pending instances may be in the process of being started on the
+	// node and so are left alone.
+	for _, i := range append(instances, cncis...) {
+		if i.NodeID != nodeID || reported[i.ID] || i.State == payloads.Pending {
+			continue
+		}
+		client.reconcileMissingInstance(i)
+	}
 }
 
 func (client *ssntpClient) concentratorInstanceAdded(payload []byte) {
@@ -247,6 +422,11 @@ func (client *ssntpClient) nodeConnected(payload []byte) {
 	}
 	glog.Infof("Node %s connected", nodeConnected.Connected.NodeUUID)
 
+	if nodeConnected.Connected.NodeType == payloads.CNCINode {
+		client.cnciConnectionChanged(nodeConnected.Connected.NodeUUID, true)
+		return
+	}
+
 	client.ctl.ds.AddNode(nodeConnected.Connected.NodeUUID, nodeConnected.Connected.NodeType)
 }
 
@@ -259,9 +439,38 @@ func (client *ssntpClient) nodeDisconnected(payload []byte) {
 	}
 
 	glog.Infof("Node %s disconnected", nodeDisconnected.Disconnected.NodeUUID)
+
+	if nodeDisconnected.Disconnected.NodeType == payloads.CNCINode {
+		client.cnciConnectionChanged(nodeDisconnected.Disconnected.NodeUUID, false)
+		return
+	}
+
 	client.ctl.ds.DeleteNode(nodeDisconnected.Disconnected.NodeUUID)
 }
 
+func (client *ssntpClient) cnciConnectionChanged(instanceID string, connected bool) {
+	i, err := client.ctl.ds.GetInstance(instanceID)
+	if err != nil {
+		glog.Warningf("Error getting CNCI instance: %v", err)
+		return
+	}
+
+	tenant, err := client.ctl.ds.GetTenant(i.TenantID)
+	if err != nil || tenant == nil {
+		glog.Warningf("Error getting tenant: %v", err)
+		return
+	}
+
+	if connected {
+		err = tenant.CNCIctrl.CNCIConnected(instanceID)
+	} else {
+		err = tenant.CNCIctrl.CNCIDisconnected(instanceID)
+	}
+	if err != nil {
+		glog.Warningf("Error updating CNCI %s connection state: %v", instanceID, err)
+	}
+}
+
 func (client *ssntpClient) unassignEvent(payload []byte) {
 	var event payloads.EventPublicIPUnassigned
 	err := yaml.Unmarshal(payload, &event)
@@ -306,6 +515,40 @@ func (client *ssntpClient) assignEvent(payload []byte) {
 	client.ctl.ds.LogEvent(i.TenantID, msg)
 }
 
+func (client *ssntpClient) volumeDetached(payload []byte) {
+	var event payloads.EventVolumeDetached
+	err := yaml.Unmarshal(payload, &event)
+	if err != nil {
+		glog.Warningf("Error unmarshalling VolumeDetached: %v", err)
+		return
+	}
+	err = client.ctl.ds.VolumeDetached(event.VolumeDetached.InstanceUUID, event.VolumeDetached.VolumeUUID)
+	if err != nil {
+		glog.Warningf("Error handling VolumeDetached in datastore: %v", err)
+	}
+}
+
+func (client *ssntpClient) consoleLog(payload []byte) {
+	var event payloads.EventConsoleLog
+	err := yaml.Unmarshal(payload, &event)
+	if err != nil {
+		glog.Warningf("Error unmarshalling ConsoleLog: %v", err)
+		return
+	}
+	client.ctl.consoleLogReceived(event.ConsoleLog.RequestID,
+		consoleLogResult{log: event.ConsoleLog.Log})
+}
+
+func (client *ssntpClient) consoleOpened(payload []byte) {
+	var event payloads.EventConsoleOpened
+	err := yaml.Unmarshal(payload, &event)
+	if err != nil {
+		glog.Warningf("Error unmarshalling ConsoleOpened: %v", err)
+		return
+	}
+	client.ctl.consoleOpened(event.Console.Token, event.Console.Address, nil)
+}
+
 func (client *ssntpClient) EventNotify(event ssntp.Event, frame *ssntp.Frame) {
 	payload := frame.Payload
 
@@ -338,6 +581,18 @@ func (client *ssntpClient) EventNotify(event ssntp.Event, frame *ssntp.Frame) {
 	case ssntp.PublicIPUnassigned:
 		client.unassignEvent(payload)
 
+	case ssntp.VolumeDetached:
+		client.volumeDetached(payload)
+
+	case ssntp.ConsoleLog:
+		client.consoleLog(payload)
+
+	case ssntp.ConsoleOpened:
+		client.consoleOpened(payload)
+
+	case ssntp.NodeInventory:
+		client.nodeInventory(payload)
+
 	}
 }
 
@@ -420,6 +675,76 @@ func (client *ssntpClient) attachVolumeFailure(payload []byte) {
 	}
 }
 
+func (client *ssntpClient) detachVolumeFailure(payload []byte) {
+	var failure payloads.ErrorDetachVolumeFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling DetachVolumeFailure: %v", err)
+		return
+	}
+	err = client.ctl.ds.DetachVolumeFailure(failure.InstanceUUID, failure.VolumeUUID, failure.Reason)
+	if err != nil {
+		glog.Warningf("Error handling DetachVolumeFailure in datastore: %v", err)
+	}
+}
+
+func (client *ssntpClient) consoleLogFailure(payload []byte) {
+	var failure payloads.ErrorConsoleLogFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling ConsoleLogFailure: %v", err)
+		return
+	}
+	client.ctl.consoleLogReceived(failure.RequestID,
+		consoleLogResult{err: consoleLogFailureError(failure.Reason)})
+}
+
+func (client *ssntpClient) openConsoleFailure(payload []byte) {
+	var failure payloads.ErrorOpenConsoleFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling OpenConsoleFailure: %v", err)
+		return
+	}
+	client.ctl.consoleOpened(failure.Token, "", openConsoleFailureError(failure.Reason))
+}
+
+func (client *ssntpClient) pauseFailure(failureType ssntp.Error, payload []byte) {
+	var failure payloads.ErrorPauseFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Warningf("Error unmarshalling %s: %v", failureType, err)
+		return
+	}
+
+	i, err := client.ctl.ds.GetInstance(failure.InstanceUUID)
+	if err != nil {
+		glog.Warningf("Error getting instance from datastore: %v", err)
+		return
+	}
+
+	// The suspended resources of an instance are released when the
+	// suspend is requested and consumed when the resume is requested,
+	// so we need to undo this if the request fails.
+
+	if !i.CNCI && (failureType == ssntp.SuspendFailure || failureType == ssntp.ResumeFailure) {
+		suspended := failureType == ssntp.ResumeFailure
+		wl, err := client.ctl.ds.GetWorkload(i.TenantID, i.WorkloadID)
+		if err != nil {
+			glog.Warningf("Error getting workload for instance from datastore: %v", err)
+		} else if err = client.ctl.ds.SetInstanceSuspended(i.ID, suspended); err != nil {
+			glog.Warningf("Error updating instance: %v", err)
+		} else if suspended {
+			client.ctl.qs.Release(i.TenantID, suspendedResources(&wl)...)
+		} else {
+			<-client.ctl.qs.Consume(i.TenantID, suspendedResources(&wl)...)
+		}
+	}
+
+	msg := fmt.Sprintf("%s: %s", failureType, failure.Reason.String())
+	client.ctl.ds.LogError(i.TenantID, msg)
+}
+
 func (client *ssntpClient) assignError(payload []byte) {
 	var failure payloads.ErrorPublicIPFailure
 	err := yaml.Unmarshal(payload, &failure)
@@ -471,6 +796,19 @@ func (client *ssntpClient) ErrorNotify(err ssntp.Error, frame *ssntp.Frame) {
 	case ssntp.AttachVolumeFailure:
 		client.attachVolumeFailure(payload)
 
+	case ssntp.DetachVolumeFailure:
+		client.detachVolumeFailure(payload)
+
+	case ssntp.ConsoleLogFailure:
+		client.consoleLogFailure(payload)
+
+	case ssntp.OpenConsoleFailure:
+		client.openConsoleFailure(payload)
+
+	case ssntp.PauseFailure, ssntp.UnpauseFailure, ssntp.SuspendFailure,
+		ssntp.ResumeFailure:
+		client.pauseFailure(err, payload)
+
 	case ssntp.AssignPublicIPFailure:
 		client.assignError(payload)
 
@@ -539,6 +877,7 @@ func (client *ssntpClient) DeleteInstance(instanceID string, nodeID string) erro
 		Delete: payloads.StopCmd{
 			InstanceUUID:      instanceID,
 			WorkloadAgentUUID: nodeID,
+			GracefulTimeout:   *gracefulTimeout,
 		},
 	}
 
@@ -551,6 +890,7 @@ func (client *ssntpClient) StopInstance(instanceID string, nodeID string) error
 			InstanceUUID:      instanceID,
 			WorkloadAgentUUID: nodeID,
 			Stop:              true,
+			GracefulTimeout:   *gracefulTimeout,
 		},
 	}
 
@@ -606,6 +946,36 @@ func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
 		restartCmd.Networking.ConcentratorIP = cnci.IPAddress
 		restartCmd.Networking.Subnet = i.Subnet
 		restartCmd.Networking.PrivateIP = i.IPAddress
+
+		if i.NetworkID != "" {
+			n, err := client.ctl.ds.GetTenantNetwork(i.TenantID, i.NetworkID)
+			if err != nil {
+				return err
+			}
+			setTenantNetwork(&restartCmd.Networking, n)
+		}
+
+		for _, nic := range i.AdditionalNICs {
+			networking := payloads.NetworkResources{
+				VnicMAC:   nic.MACAddress,
+				VnicUUID:  nic.VnicUUID,
+				PrivateIP: nic.IPAddress,
+			}
+
+			n, err := client.ctl.ds.GetTenantNetwork(i.TenantID, nic.NetworkID)
+			if err != nil {
+				return err
+			}
+			setTenantNetwork(&networking, n)
+
+			err = concentratorConfig(t, &networking)
+			if err != nil {
+				return err
+			}
+
+			restartCmd.AdditionalNetworking = append(restartCmd.AdditionalNetworking,
+				networking)
+		}
 	}
 
 	if w.VMType == payloads.Docker {
@@ -617,6 +987,12 @@ func (client *ssntpClient) RestartInstance(i *types.Instance, w *types.Workload,
 		vol.ID = attachments[k].BlockID
 		vol.Bootable = attachments[k].Boot
 		vol.Ephemeral = attachments[k].Ephemeral
+		vol.ReadOnly = attachments[k].Mode == types.ReadOnly
+
+		bd, err := client.ctl.ds.GetBlockDevice(attachments[k].BlockID)
+		if err == nil {
+			vol.Shared = bd.MultiAttach
+		}
 	}
 
 	payload := payloads.Start{
@@ -694,12 +1070,14 @@ func (client *ssntpClient) RestoreNode(nodeID string) error {
 	return err
 }
 
-func (client *ssntpClient) attachVolume(volID string, instanceID string, nodeID string) error {
+func (client *ssntpClient) attachVolume(volID string, instanceID string, nodeID string, readOnly bool, shared bool) error {
 	payload := payloads.AttachVolume{
 		Attach: payloads.VolumeCmd{
 			InstanceUUID:      instanceID,
 			VolumeUUID:        volID,
 			WorkloadAgentUUID: nodeID,
+			ReadOnly:          readOnly,
+			Shared:            shared,
 		},
 	}
 
@@ -716,6 +1094,131 @@ func (client *ssntpClient) attachVolume(volID string, instanceID string, nodeID
 	return err
 }
 
+func (client *ssntpClient) detachVolume(volID string, instanceID string, nodeID string) error {
+	payload := payloads.DetachVolume{
+		Detach: payloads.VolumeCmd{
+			InstanceUUID:      instanceID,
+			VolumeUUID:        volID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("DetachVolume %s from %s\n", volID, instanceID)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.DetachVolume, y)
+
+	return err
+}
+
+func (client *ssntpClient) getConsoleLog(instanceID string, nodeID string, lines int, requestID string) error {
+	payload := payloads.GetConsoleLog{
+		ConsoleLog: payloads.ConsoleLogCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+			Lines:             lines,
+			RequestID:         requestID,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("GetConsoleLog %s\n", instanceID)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.GetConsoleLog, y)
+
+	return err
+}
+
+func (client *ssntpClient) openConsole(instanceID string, nodeID string, token string) error {
+	payload := payloads.OpenConsole{
+		Console: payloads.OpenConsoleCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+			Token:             token,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("OpenConsole %s\n", instanceID)
+
+	_, err = client.ssntp.SendCommand(ssntp.OpenConsole, y)
+
+	return err
+}
+
+func (client *ssntpClient) sendPauseCommand(cmd ssntp.Command, payload interface{},
+	instanceID string) error {
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("%s %s\n", cmd, instanceID)
+
+	_, err = client.ssntp.SendCommand(cmd, y)
+
+	return err
+}
+
+func (client *ssntpClient) pauseInstance(instanceID string, nodeID string) error {
+	payload := payloads.Pause{
+		Pause: payloads.PauseCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPauseCommand(ssntp.PauseInstance, payload, instanceID)
+}
+
+func (client *ssntpClient) unpauseInstance(instanceID string, nodeID string) error {
+	payload := payloads.Unpause{
+		Unpause: payloads.PauseCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPauseCommand(ssntp.UnpauseInstance, payload, instanceID)
+}
+
+func (client *ssntpClient) suspendInstance(instanceID string, nodeID string) error {
+	payload := payloads.Suspend{
+		Suspend: payloads.PauseCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+		},
+	}
+
+	return client.sendPauseCommand(ssntp.SuspendInstance, payload, instanceID)
+}
+
+func (client *ssntpClient) resumeInstance(instanceID string, nodeID string, memMB int) error {
+	payload := payloads.Resume{
+		Resume: payloads.PauseCmd{
+			InstanceUUID:      instanceID,
+			WorkloadAgentUUID: nodeID,
+			MemMB:             memMB,
+		},
+	}
+
+	return client.sendPauseCommand(ssntp.ResumeInstance, payload, instanceID)
+}
+
 func (client *ssntpClient) ssntpClient() *ssntp.Client {
 	return &client.ssntp
 }
@@ -783,3 +1286,167 @@ func (client *ssntpClient) unMapExternalIP(t types.Tenant, m types.MappedIP) err
 	_, err = client.ssntp.SendCommand(ssntp.ReleasePublicIP, y)
 	return err
 }
+
+func (client *ssntpClient) updateDNS(t types.Tenant, cnciID string, subnet string, records []payloads.DNSRecord) error {
+	payload := payloads.CommandUpdateDNS{
+		UpdateDNS: payloads.DNSCommand{
+			ConcentratorUUID: cnciID,
+			TenantUUID:       t.ID,
+			TenantSubnet:     subnet,
+			Domain:           t.DNSDomain,
+			Forwarders:       t.DNSForwarders,
+			Records:          records,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Request DNS update of %s with %d records\n", subnet, len(records))
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.UpdateDNS, y)
+	return err
+}
+
+func (client *ssntpClient) updateMetadata(t types.Tenant, cnciID string, subnet string, instances []payloads.InstanceMetadata) error {
+	payload := payloads.CommandUpdateMetadata{
+		UpdateMetadata: payloads.MetadataCommand{
+			ConcentratorUUID: cnciID,
+			TenantUUID:       t.ID,
+			TenantSubnet:     subnet,
+			Instances:        instances,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Request metadata update of %s with %d instances\n", subnet, len(instances))
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.UpdateMetadata, y)
+	return err
+}
+
+func (client *ssntpClient) updatePeering(t types.Tenant, cnciID string, subnet string, peers []payloads.PeerSubnet) error {
+	payload := payloads.CommandUpdatePeering{
+		UpdatePeering: payloads.PeeringCommand{
+			ConcentratorUUID: cnciID,
+			TenantUUID:       t.ID,
+			TenantSubnet:     subnet,
+			Peers:            peers,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Request peering update of %s with %d peers\n", subnet, len(peers))
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.UpdatePeering, y)
+	return err
+}
+
+func (client *ssntpClient) updateConcentrator(nodeID string, tenantID string, subnet string, cnci *types.Instance) error {
+	payload := payloads.CommandUpdateConcentrator{
+		UpdateConcentrator: payloads.ConcentratorUpdateCommand{
+			AgentUUID:        nodeID,
+			TenantUUID:       tenantID,
+			TenantSubnet:     subnet,
+			ConcentratorUUID: cnci.ID,
+			ConcentratorIP:   cnci.IPAddress,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Request concentrator update of %s on %s\n", subnet, nodeID)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.UpdateConcentrator, y)
+	return err
+}
+
+func (client *ssntpClient) configureLoadBalancer(t types.Tenant, lb types.LoadBalancer) error {
+	// get the CNCI for the subnet of the members
+	cnci, err := t.CNCIctrl.GetSubnetCNCI(lb.Subnet)
+	if err != nil {
+		return err
+	}
+
+	payload := payloads.CommandConfigureLoadBalancer{
+		ConfigureLoadBalancer: payloads.LoadBalancerCommand{
+			ConcentratorUUID: cnci.ID,
+			TenantUUID:       lb.TenantID,
+			LoadBalancerUUID: lb.ID,
+			VIP:              lb.VIP,
+			Port:             lb.Port,
+			Protocol:         lb.Protocol,
+			Members:          []payloads.LoadBalancerMember{},
+			HealthCheck: payloads.LoadBalancerHealthCheck{
+				Interval: lb.HealthCheck.Interval,
+				Timeout:  lb.HealthCheck.Timeout,
+				Retries:  lb.HealthCheck.Retries,
+				Path:     lb.HealthCheck.Path,
+			},
+		},
+	}
+
+	for _, m := range lb.Members {
+		member := payloads.LoadBalancerMember{
+			IP:   m.IPAddress,
+			Port: m.Port,
+		}
+		payload.ConfigureLoadBalancer.Members = append(payload.ConfigureLoadBalancer.Members, member)
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Request load balancer %s on %s with %d members\n", lb.ID, lb.VIP, len(lb.Members))
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.ConfigureLoadBalancer, y)
+	return err
+}
+
+func (client *ssntpClient) removeLoadBalancer(t types.Tenant, lb types.LoadBalancer) error {
+	// get the CNCI for the subnet of the members
+	cnci, err := t.CNCIctrl.GetSubnetCNCI(lb.Subnet)
+	if err != nil {
+		return err
+	}
+
+	payload := payloads.CommandRemoveLoadBalancer{
+		RemoveLoadBalancer: payloads.LoadBalancerRemoveCommand{
+			ConcentratorUUID: cnci.ID,
+			TenantUUID:       lb.TenantID,
+			LoadBalancerUUID: lb.ID,
+			VIP:              lb.VIP,
+		},
+	}
+
+	y, err := yaml.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	glog.Infof("Request removal of load balancer %s on %s\n", lb.ID, lb.VIP)
+	glog.V(1).Info(string(y))
+
+	_, err = client.ssntp.SendCommand(ssntp.RemoveLoadBalancer, y)
+	return err
+}
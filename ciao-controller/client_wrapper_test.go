@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
 )
 
@@ -111,8 +112,60 @@ func (client *ssntpClientWrapper) unMapExternalIP(t types.Tenant, m types.Mapped
 	return client.realClient.unMapExternalIP(t, m)
 }
 
-func (client *ssntpClientWrapper) attachVolume(volID string, instanceID string, nodeID string) error {
-	return client.realClient.attachVolume(volID, instanceID, nodeID)
+func (client *ssntpClientWrapper) updateDNS(t types.Tenant, cnciID string, subnet string, records []payloads.DNSRecord) error {
+	return client.realClient.updateDNS(t, cnciID, subnet, records)
+}
+
+func (client *ssntpClientWrapper) updateConcentrator(nodeID string, tenantID string, subnet string, cnci *types.Instance) error {
+	return client.realClient.updateConcentrator(nodeID, tenantID, subnet, cnci)
+}
+
+func (client *ssntpClientWrapper) updateMetadata(t types.Tenant, cnciID string, subnet string, instances []payloads.InstanceMetadata) error {
+	return client.realClient.updateMetadata(t, cnciID, subnet, instances)
+}
+
+func (client *ssntpClientWrapper) updatePeering(t types.Tenant, cnciID string, subnet string, peers []payloads.PeerSubnet) error {
+	return client.realClient.updatePeering(t, cnciID, subnet, peers)
+}
+
+func (client *ssntpClientWrapper) configureLoadBalancer(t types.Tenant, lb types.LoadBalancer) error {
+	return client.realClient.configureLoadBalancer(t, lb)
+}
+
+func (client *ssntpClientWrapper) removeLoadBalancer(t types.Tenant, lb types.LoadBalancer) error {
+	return client.realClient.removeLoadBalancer(t, lb)
+}
+
+func (client *ssntpClientWrapper) attachVolume(volID string, instanceID string, nodeID string, readOnly bool, shared bool) error {
+	return client.realClient.attachVolume(volID, instanceID, nodeID, readOnly, shared)
+}
+
+func (client *ssntpClientWrapper) detachVolume(volID string, instanceID string, nodeID string) error {
+	return client.realClient.detachVolume(volID, instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) getConsoleLog(instanceID string, nodeID string, lines int, requestID string) error {
+	return client.realClient.getConsoleLog(instanceID, nodeID, lines, requestID)
+}
+
+func (client *ssntpClientWrapper) openConsole(instanceID string, nodeID string, token string) error {
+	return client.realClient.openConsole(instanceID, nodeID, token)
+}
+
+func (client *ssntpClientWrapper) pauseInstance(instanceID string, nodeID string) error {
+	return client.realClient.pauseInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) unpauseInstance(instanceID string, nodeID string) error {
+	return client.realClient.unpauseInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) suspendInstance(instanceID string, nodeID string) error {
+	return client.realClient.suspendInstance(instanceID, nodeID)
+}
+
+func (client *ssntpClientWrapper) resumeInstance(instanceID string, nodeID string, memMB int) error {
+	return client.realClient.resumeInstance(instanceID, nodeID, memMB)
 }
 
 func (client *ssntpClientWrapper) ssntpClient() *ssntp.Client {
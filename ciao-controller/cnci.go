@@ -48,17 +48,30 @@ var (
 
 var cnciEventTimeout = (2 * time.Minute)
 
+// cnciFailoverTimeout is how long a disconnected CNCI is given to reconnect
+// before its subnet is failed over to the standby CNCI.
+var cnciFailoverTimeout = (30 * time.Second)
+
 // CNCI represents a cnci instance that manages a single subnet.
 type CNCI struct {
 	instance *types.Instance
 	ctrl     *controller
 	eventCh  *chan event
-	subnet   int
+	subnet   string
 	timer    *time.Timer
+	failover *time.Timer
+
+	// fenced is set once the subnet of the CNCI has been failed over.
+	// The CNCI may not have received its DELETE command if it was
+	// partitioned from the cluster, so the command is sent again if it
+	// reconnects.
+	fenced bool
 }
 
-// CNCIManager is a structure which defines a manager for CNCI instances
-// TBD: managing multiple CNCI instances.
+// CNCIManager is a structure which defines a manager for the CNCI instances
+// of a tenant. Each subnet is served by an active CNCI and, when the
+// cnci_standby option is set, by a standby CNCI which takes over the subnet
+// if the active one fails.
 type CNCIManager struct {
 	tenant string
 	ctrl   *controller
@@ -69,8 +82,11 @@ type CNCIManager struct {
 	// this is a map of CNCI instance IDs to CNCI structs
 	cncis map[string]*CNCI
 
-	// this is a map of subnet (integer) to CNCI structs
-	subnets map[int]*CNCI
+	// this is a map of subnet (CIDR string) to CNCI structs
+	subnets map[string]*CNCI
+
+	// this is a map of subnet (CIDR string) to standby CNCI structs
+	standbys map[string]*CNCI
 }
 
 func (c *CNCI) stop() error {
@@ -87,6 +103,29 @@ func (c *CNCI) stop() error {
 	return nil
 }
 
+// nodeID returns the ID of the node hosting the CNCI, or an empty string if
+// the CNCI is not assigned to a node.
+func (c *CNCI) nodeID() string {
+	if c.instance == nil {
+		return ""
+	}
+
+	i, err := c.ctrl.ds.GetInstance(c.instance.ID)
+	if err != nil {
+		return ""
+	}
+
+	return i.NodeID
+}
+
+// colocated returns true if the CNCIs a and b are hosted by the same node,
+// in which case they do not protect each other against the loss of the
+// node.
+func colocated(a *CNCI, b *CNCI) bool {
+	nodeID := a.nodeID()
+	return nodeID != "" && nodeID == b.nodeID()
+}
+
 func waitForEventTimeout(ch chan event, e event, timeout time.Duration) error {
 	select {
 	case recv := <-ch:
@@ -134,7 +173,7 @@ func (c *CNCIManager) Active(ID string) bool {
 	return instanceActive(cnci.instance)
 }
 
-func (c *CNCIManager) launch(subnet string) (*types.Instance, error) {
+func (c *CNCIManager) launch(subnet string, excludedNodes []string) (*types.Instance, error) {
 	glog.V(2).Infof("launching cnci for subnet %s", subnet)
 
 	b := make([]byte, 4)
@@ -151,11 +190,12 @@ func (c *CNCIManager) launch(subnet string) (*types.Instance, error) {
 	}
 
 	w := types.WorkloadRequest{
-		WorkloadID: workloadID,
-		TenantID:   c.tenant,
-		Instances:  1,
-		Subnet:     subnet,
-		Name:       name,
+		WorkloadID:    workloadID,
+		TenantID:      c.tenant,
+		Instances:     1,
+		Subnet:        subnet,
+		Name:          name,
+		ExcludedNodes: excludedNodes,
 	}
 
 	instances, err := c.ctrl.startWorkload(w)
@@ -166,20 +206,21 @@ func (c *CNCIManager) launch(subnet string) (*types.Instance, error) {
 	return instances[0], nil
 }
 
+// WaitForActive will launch a cnci if needed and wait for it to be active,
+// or wait for an existing cnci to become active.
+func (c *CNCIManager) WaitForActive(subnet int) error {
+	return c.WaitForActiveSubnetString(subnetIntToString(subnet))
+}
+
 // WaitForActiveSubnetString will, given a subnet string, launch a cnci if
 // needed and wait for it to be active, or wait for an existing cnci to become
 // active.
 func (c *CNCIManager) WaitForActiveSubnetString(subnet string) error {
-	subnetInt, err := subnetStringToInt(subnet)
+	subnet, err := canonicalSubnet(subnet)
 	if err != nil {
 		return err
 	}
-	return c.WaitForActive(subnetInt)
-}
 
-// WaitForActive will launch a cnci if needed and wait for it to be active,
-// or wait for an existing cnci to become active.
-func (c *CNCIManager) WaitForActive(subnet int) error {
 	c.cnciLock.Lock()
 
 	cnci, ok := c.subnets[subnet]
@@ -215,17 +256,8 @@ func (c *CNCIManager) WaitForActive(subnet int) error {
 
 	c.subnets[subnet] = cnci
 
-	subnetBytes := make([]byte, 2)
-	binary.BigEndian.PutUint16(subnetBytes, uint16(subnet))
-	ip := net.IPv4(172, subnetBytes[0], subnetBytes[1], 0)
-	ipNet := net.IPNet{
-		IP:   ip,
-		Mask: net.IPv4Mask(255, 255, 255, 0),
-	}
-	subnetStr := ipNet.String()
-
 	// send a launch command
-	instance, err := c.launch(subnetStr)
+	instance, err := c.launch(subnet, nil)
 	if err != nil {
 		c.cnciLock.Unlock()
 		return err
@@ -245,10 +277,66 @@ func (c *CNCIManager) WaitForActive(subnet int) error {
 	return waitForEventTimeout(ch, added, cnciEventTimeout)
 }
 
+// launchStandby will launch a standby cnci for a subnet unless the subnet
+// already has one, or is no longer in use.  The standby is never placed on
+// the node hosting the active cnci.
+func (c *CNCIManager) launchStandby(subnet string) {
+	c.cnciLock.Lock()
+	defer c.cnciLock.Unlock()
+
+	active, ok := c.subnets[subnet]
+	if !ok {
+		return
+	}
+
+	if _, ok := c.standbys[subnet]; ok {
+		return
+	}
+
+	var excludedNodes []string
+	if nodeID := active.nodeID(); nodeID != "" {
+		excludedNodes = []string{nodeID}
+	}
+
+	instance, err := c.launch(subnet, excludedNodes)
+	if err != nil {
+		glog.Warningf("Unable to launch standby CNCI for %s: %v", subnet, err)
+		return
+	}
+
+	glog.V(2).Infof("Standby CNCI instance for %s is %s", subnet, instance.ID)
+
+	instance.CNCIStandby = true
+	err = c.ctrl.ds.UpdateInstance(instance)
+	if err != nil {
+		glog.Warningf("Unable to mark CNCI %s as standby: %v", instance.ID, err)
+	}
+
+	cnci := &CNCI{
+		instance: instance,
+		ctrl:     c.ctrl,
+		subnet:   subnet,
+	}
+
+	c.cncis[instance.ID] = cnci
+	c.standbys[subnet] = cnci
+}
+
 // ScheduleRemoveSubnet will kick off a timer to remove a subnet after 5 min.
 // If a subnet is requested to be used again before the timer expires, the
 // timer will get cancelled and the subnet will not be removed.
 func (c *CNCIManager) ScheduleRemoveSubnet(subnet int) error {
+	return c.ScheduleRemoveSubnetString(subnetIntToString(subnet))
+}
+
+// ScheduleRemoveSubnetString is identical to ScheduleRemoveSubnet but takes
+// the subnet in CIDR notation.
+func (c *CNCIManager) ScheduleRemoveSubnetString(subnet string) error {
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
 	c.cnciLock.Lock()
 
 	cnci, ok := c.subnets[subnet]
@@ -269,7 +357,7 @@ func (c *CNCIManager) ScheduleRemoveSubnet(subnet int) error {
 		cnci.timer = nil
 		c.cnciLock.Unlock()
 
-		err := c.RemoveSubnet(subnet)
+		err := c.RemoveSubnetString(subnet)
 		if err != nil {
 			glog.Warningf("Unable to remove subnet: (%v)\n", err)
 		}
@@ -283,7 +371,18 @@ func (c *CNCIManager) ScheduleRemoveSubnet(subnet int) error {
 // RemoveSubnet is called when a subnet no longer is needed.
 // a cnci can be stopped.
 func (c *CNCIManager) RemoveSubnet(subnet int) error {
-	glog.V(2).Infof("RemoveSubnet %d", subnet)
+	return c.RemoveSubnetString(subnetIntToString(subnet))
+}
+
+// RemoveSubnetString is identical to RemoveSubnet but takes the subnet in
+// CIDR notation.
+func (c *CNCIManager) RemoveSubnetString(subnet string) error {
+	glog.V(2).Infof("RemoveSubnet %s", subnet)
+
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return err
+	}
 
 	c.cnciLock.Lock()
 
@@ -296,7 +395,22 @@ func (c *CNCIManager) RemoveSubnet(subnet int) error {
 
 	delete(c.subnets, subnet)
 
-	err := cnci.stop()
+	if cnci.failover != nil {
+		cnci.failover.Stop()
+		cnci.failover = nil
+	}
+
+	standby, ok := c.standbys[subnet]
+	if ok {
+		delete(c.standbys, subnet)
+
+		err = standby.stop()
+		if err != nil {
+			glog.Warningf("Unable to stop standby CNCI %s: %v", standby.instance.ID, err)
+		}
+	}
+
+	err = cnci.stop()
 	if err != nil {
 		c.cnciLock.Unlock()
 		return err
@@ -346,6 +460,13 @@ func (c *CNCIManager) CNCIStopped(id string) error {
 	}
 
 	cnci.transitionState(exited)
+
+	// a CNCI whose subnet has been failed over serves nothing
+	// anymore, so it is not worth bringing back.
+	if c.subnets[cnci.subnet] != cnci && c.standbys[cnci.subnet] != cnci {
+		return nil
+	}
+
 	c.ctrl.restartInstance(cnci.instance.ID)
 
 	return nil
@@ -364,6 +485,232 @@ func (c *CNCIManager) CNCIAdded(id string) error {
 
 	cnci.transitionState(active)
 
+	if c.standbys[cnci.subnet] == cnci && colocated(cnci, c.subnets[cnci.subnet]) {
+		glog.Warningf("Standby CNCI %s shares its node with the active CNCI of %s",
+			id, cnci.subnet)
+	}
+
+	// make sure the subnet has a standby once its active
+	// CNCI is up.
+	if *cnciStandby && c.subnets[cnci.subnet] == cnci {
+		if _, ok := c.standbys[cnci.subnet]; !ok {
+			go c.launchStandby(cnci.subnet)
+		}
+	}
+
+	// the load balancers and peerings of the subnet outlive its CNCI,
+	// a new CNCI needs to be told about them.
+	if c.subnets[cnci.subnet] == cnci {
+		go func(subnet string) {
+			t, err := c.ctrl.ds.GetTenant(c.tenant)
+			if err != nil || t == nil {
+				glog.Warningf("Unable to get tenant %s: %v", c.tenant, err)
+				return
+			}
+
+			c.ctrl.restoreLoadBalancers(t, subnet)
+			c.ctrl.restoreNetworkPeerings(t, subnet)
+		}(cnci.subnet)
+	}
+
+	return nil
+}
+
+// CNCIConnected is called when the agent of a CNCI connects to the
+// scheduler. It cancels any pending failover of the CNCI's subnet.
+func (c *CNCIManager) CNCIConnected(id string) error {
+	c.cnciLock.Lock()
+	defer c.cnciLock.Unlock()
+
+	cnci, ok := c.cncis[id]
+	if !ok {
+		return errors.New("No CNCI found")
+	}
+
+	if cnci.failover != nil {
+		glog.Infof("CNCI %s reconnected, cancelling failover", id)
+		cnci.failover.Stop()
+		cnci.failover = nil
+	}
+
+	// the subnet of a fenced CNCI is served by its replacement.
+	// It must not be allowed to serve it too.
+	if cnci.fenced {
+		glog.Warningf("Fenced CNCI %s reconnected, deleting it", id)
+		err := c.ctrl.deleteInstance(cnci.instance.ID)
+		if err != nil {
+			glog.Warningf("Unable to delete fenced CNCI %s: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// CNCIDisconnected is called when the agent of a CNCI disconnects from the
+// scheduler. If the CNCI is the active one for a subnet that has a standby,
+// the subnet will be failed over to the standby unless the CNCI reconnects
+// within cnciFailoverTimeout.
+func (c *CNCIManager) CNCIDisconnected(id string) error {
+	c.cnciLock.Lock()
+	defer c.cnciLock.Unlock()
+
+	cnci, ok := c.cncis[id]
+	if !ok {
+		return errors.New("No CNCI found")
+	}
+
+	if c.subnets[cnci.subnet] != cnci || cnci.failover != nil {
+		return nil
+	}
+
+	standby, ok := c.standbys[cnci.subnet]
+	if !ok || !instanceActive(standby.instance) {
+		glog.Warningf("CNCI %s disconnected, no standby available for %s", id, cnci.subnet)
+		return nil
+	}
+
+	// a standby on the same node is most likely gone too.
+	if colocated(cnci, standby) {
+		glog.Warningf("CNCI %s disconnected, standby %s shares its node, not failing over",
+			id, standby.instance.ID)
+		return nil
+	}
+
+	glog.Warningf("CNCI %s disconnected, failing over in %v", id, cnciFailoverTimeout)
+
+	cnci.failover = time.AfterFunc(cnciFailoverTimeout, func() {
+		err := c.failover(cnci)
+		if err != nil {
+			glog.Warningf("Unable to fail over subnet %s: %v", cnci.subnet, err)
+		}
+	})
+
+	return nil
+}
+
+// failover makes the standby CNCI of the subnet served by cnci the active
+// one, points the compute nodes hosting instances on the subnet at it and
+// replays the subnet's external IP mappings, DNS records, instance metadata
+// and load balancers.
+//
+// The failed CNCI is fenced by deleting it.  A CNCI that is only partitioned
+// from the scheduler does not receive the DELETE command and keeps running,
+// announcing the subnet's external IPs alongside its replacement, until it
+// reconnects and is deleted again.  The compute nodes are no longer tunnelled
+// to it so it does not carry any tenant traffic.
+func (c *CNCIManager) failover(cnci *CNCI) error {
+	c.cnciLock.Lock()
+
+	if cnci.failover == nil {
+		// the CNCI reconnected while the timer was firing.
+		c.cnciLock.Unlock()
+		return nil
+	}
+
+	cnci.failover = nil
+	subnet := cnci.subnet
+
+	standby, ok := c.standbys[subnet]
+	if !ok || c.subnets[subnet] != cnci {
+		c.cnciLock.Unlock()
+		return errors.New("No standby CNCI found")
+	}
+
+	delete(c.standbys, subnet)
+	c.subnets[subnet] = standby
+	cnci.fenced = true
+
+	// a pending removal of the subnet is carried over to the
+	// new CNCI.
+	removing := cnci.timer != nil
+	if removing {
+		cnci.timer.Stop()
+		cnci.timer = nil
+	}
+
+	standby.instance.CNCIStandby = false
+	err := c.ctrl.ds.UpdateInstance(standby.instance)
+	if err != nil {
+		glog.Warningf("Unable to mark CNCI %s as active: %v", standby.instance.ID, err)
+	}
+
+	c.cnciLock.Unlock()
+
+	glog.Warningf("Failing over subnet %s from CNCI %s to %s", subnet, cnci.instance.ID, standby.instance.ID)
+
+	instances, err := c.subnetInstances(subnet)
+	if err != nil {
+		return err
+	}
+
+	nodes := make(map[string]bool)
+	for _, i := range instances {
+		if i.NodeID != "" {
+			nodes[i.NodeID] = true
+		}
+	}
+
+	for nodeID := range nodes {
+		err = c.ctrl.client.updateConcentrator(nodeID, c.tenant, subnet, standby.instance)
+		if err != nil {
+			glog.Warningf("Unable to update concentrator on %s: %v", nodeID, err)
+		}
+	}
+
+	t, err := c.ctrl.ds.GetTenant(c.tenant)
+	if err != nil || t == nil {
+		return errors.Wrapf(err, "error getting tenant %s", c.tenant)
+	}
+
+	onSubnet := make(map[string]bool)
+	for _, i := range instances {
+		if i.Subnet == subnet {
+			onSubnet[i.ID] = true
+		}
+	}
+
+	for _, m := range c.ctrl.ds.GetMappedIPs(&c.tenant) {
+		if !onSubnet[m.InstanceID] {
+			continue
+		}
+
+		err = c.ctrl.client.mapExternalIP(*t, m)
+		if err != nil {
+			glog.Warningf("Unable to map %s on CNCI %s: %v", m.ExternalIP, standby.instance.ID, err)
+		}
+	}
+
+	err = c.ctrl.updateSubnetDNS(t, subnet)
+	if err != nil {
+		glog.Warningf("Unable to update DNS of %s: %v", subnet, err)
+	}
+
+	err = c.ctrl.updateSubnetMetadata(t, subnet)
+	if err != nil {
+		glog.Warningf("Unable to update metadata of %s: %v", subnet, err)
+	}
+
+	c.ctrl.restoreLoadBalancers(t, subnet)
+	c.ctrl.restoreNetworkPeerings(t, subnet)
+
+	c.cnciLock.Lock()
+	err = cnci.stop()
+	c.cnciLock.Unlock()
+	if err != nil {
+		glog.Warningf("Unable to stop failed CNCI %s: %v", cnci.instance.ID, err)
+	}
+
+	if removing {
+		err = c.ScheduleRemoveSubnetString(subnet)
+		if err != nil {
+			glog.Warningf("Unable to remove subnet (%v)", err)
+		}
+	}
+
+	if *cnciStandby {
+		c.launchStandby(subnet)
+	}
+
 	return nil
 }
 
@@ -379,14 +726,19 @@ func (c *CNCIManager) StartFailure(id string) error {
 	}
 
 	delete(c.cncis, id)
-	delete(c.subnets, cnci.subnet)
+
+	if c.standbys[cnci.subnet] == cnci {
+		delete(c.standbys, cnci.subnet)
+	} else {
+		delete(c.subnets, cnci.subnet)
+	}
 
 	cnci.transitionState(failed)
 
 	return nil
 }
 
-func (c *CNCIManager) waitForActive(subnet int) error {
+func (c *CNCIManager) waitForActive(subnet string) error {
 	c.cnciLock.RLock()
 
 	cnci, ok := c.subnets[subnet]
@@ -428,8 +780,7 @@ func (c *CNCIManager) GetInstanceCNCI(ID string) (*types.Instance, error) {
 		return nil, err
 	}
 
-	// convert subnet string to int
-	subnetInt, err := subnetStringToInt(instance.Subnet)
+	subnet, err := canonicalSubnet(instance.Subnet)
 	if err != nil {
 		return nil, err
 	}
@@ -437,7 +788,7 @@ func (c *CNCIManager) GetInstanceCNCI(ID string) (*types.Instance, error) {
 	c.cnciLock.Lock()
 	defer c.cnciLock.Unlock()
 
-	cnci, ok := c.subnets[int(subnetInt)]
+	cnci, ok := c.subnets[subnet]
 	if !ok {
 		// there is no cnci for this subnet
 		return nil, errors.New("Subnet doesn't exist")
@@ -446,23 +797,37 @@ func (c *CNCIManager) GetInstanceCNCI(ID string) (*types.Instance, error) {
 	return cnci.instance, nil
 }
 
-func subnetStringToInt(cidr string) (int, error) {
-	ipAddr, _, err := net.ParseCIDR(cidr)
+// subnetIntToString converts the integer identifying a subnet of the default
+// tenant address space into CIDR notation.
+func subnetIntToString(subnet int) string {
+	subnetBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(subnetBytes, uint16(subnet))
+	ip := net.IPv4(172, subnetBytes[0], subnetBytes[1], 0)
+	ipNet := net.IPNet{
+		IP:   ip,
+		Mask: net.IPv4Mask(255, 255, 255, 0),
+	}
+	return ipNet.String()
+}
+
+// canonicalSubnet returns the subnet of cidr in the form used to index the
+// subnets map.
+func canonicalSubnet(cidr string) (string, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
-		return 0, err
+		return "", err
 	}
 
-	ipBytes := ipAddr.To4()
-	if ipBytes == nil {
-		return 0, errors.New("Unable to convert ip to bytes")
+	if ipNet.IP.To4() == nil {
+		return "", errors.New("Unable to convert ip to bytes")
 	}
 
-	return int(binary.BigEndian.Uint16(ipBytes[1:3])), nil
+	return ipNet.String(), nil
 }
 
 // GetSubnetCNCI will return the CNCI Instance for a specific subnet string
 func (c *CNCIManager) GetSubnetCNCI(subnet string) (*types.Instance, error) {
-	subnetInt, err := subnetStringToInt(subnet)
+	subnet, err := canonicalSubnet(subnet)
 	if err != nil {
 		return nil, err
 	}
@@ -470,7 +835,7 @@ func (c *CNCIManager) GetSubnetCNCI(subnet string) (*types.Instance, error) {
 	c.cnciLock.Lock()
 	defer c.cnciLock.Unlock()
 
-	cnci, ok := c.subnets[subnetInt]
+	cnci, ok := c.subnets[subnet]
 	if !ok {
 		// there is no cnci for this subnet
 		return nil, errors.New("Subnet doesn't exist")
@@ -480,20 +845,52 @@ func (c *CNCIManager) GetSubnetCNCI(subnet string) (*types.Instance, error) {
 }
 
 func (c *CNCIManager) getInstanceCount(subnet string) (int, error) {
-	var count int
+	instances, err := c.subnetInstances(subnet)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(instances), nil
+}
+
+// subnetInstances returns the tenant instances that have a NIC on subnet.
+func (c *CNCIManager) subnetInstances(subnet string) ([]*types.Instance, error) {
+	var subnetInstances []*types.Instance
 
 	instances, err := c.ctrl.ds.GetAllInstancesFromTenant(c.tenant)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	networks, err := c.ctrl.ds.GetTenantNetworks(c.tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	// instances may also use the subnet through one of their
+	// additional NICs.
+	attached := make(map[string]bool)
+	for _, n := range networks {
+		if n.CIDR == subnet {
+			attached[n.ID] = true
+		}
 	}
 
 	for _, i := range instances {
 		if i.Subnet == subnet {
-			count++
+			subnetInstances = append(subnetInstances, i)
+			continue
+		}
+
+		for _, nic := range i.AdditionalNICs {
+			if attached[nic.NetworkID] {
+				subnetInstances = append(subnetInstances, i)
+				break
+			}
 		}
 	}
 
-	return count, nil
+	return subnetInstances, nil
 }
 
 // Shutdown cleans up a CNCIManager in anticipation of a shutdown.
@@ -508,6 +905,11 @@ func (c *CNCIManager) Shutdown() {
 			cnci.timer.Stop()
 			cnci.timer = nil
 		}
+
+		if cnci.failover != nil {
+			cnci.failover.Stop()
+			cnci.failover = nil
+		}
 	}
 }
 
@@ -516,8 +918,9 @@ func newCNCIManager(ctrl *controller, tenant string) (*CNCIManager, error) {
 		tenant: tenant,
 		ctrl:   ctrl,
 
-		cncis:   make(map[string]*CNCI),
-		subnets: make(map[int]*CNCI),
+		cncis:    make(map[string]*CNCI),
+		subnets:  make(map[string]*CNCI),
+		standbys: make(map[string]*CNCI),
 	}
 
 	instances, err := ctrl.ds.GetTenantCNCIs(tenant)
@@ -535,15 +938,20 @@ func newCNCIManager(ctrl *controller, tenant string) (*CNCIManager, error) {
 
 		cnci.instance = i
 
-		// convert cnci instance string to int for map
-		subnetInt, err := subnetStringToInt(i.Subnet)
+		subnet, err := canonicalSubnet(i.Subnet)
 		if err != nil {
 			return nil, err
 		}
 
-		cnci.subnet = subnetInt
+		cnci.subnet = subnet
 		mgr.cncis[i.ID] = &cnci
-		mgr.subnets[subnetInt] = &cnci
+
+		if i.CNCIStandby {
+			mgr.standbys[subnet] = &cnci
+			continue
+		}
+
+		mgr.subnets[subnet] = &cnci
 
 		// if we got shutdown prior to being able to remove
 		// an unused subnet, we might be left with CNCIs that
@@ -556,7 +964,7 @@ func newCNCIManager(ctrl *controller, tenant string) (*CNCIManager, error) {
 		}
 
 		if count == 0 {
-			err = mgr.ScheduleRemoveSubnet(subnetInt)
+			err = mgr.ScheduleRemoveSubnetString(subnet)
 			if err != nil {
 				// keep going, but log error.
 				glog.Warningf("Unable to remove subnet (%v)", err)
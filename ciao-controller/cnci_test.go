@@ -17,6 +17,8 @@ package main
 import (
 	"testing"
 
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
 )
 
@@ -164,3 +166,78 @@ func TestCNCIRemoved(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCNCIDisconnected(t *testing.T) {
+	subnet := "172.16.0.0/24"
+
+	mgr := &CNCIManager{
+		tenant:   "tenant",
+		ctrl:     ctl,
+		cncis:    make(map[string]*CNCI),
+		subnets:  make(map[string]*CNCI),
+		standbys: make(map[string]*CNCI),
+	}
+
+	active := &CNCI{
+		instance: &types.Instance{ID: "active", State: payloads.Running},
+		ctrl:     ctl,
+		subnet:   subnet,
+	}
+	mgr.cncis[active.instance.ID] = active
+	mgr.subnets[subnet] = active
+
+	standby := &CNCI{
+		instance: &types.Instance{ID: "standby", State: payloads.Pending, CNCIStandby: true},
+		ctrl:     ctl,
+		subnet:   subnet,
+	}
+	mgr.cncis[standby.instance.ID] = standby
+	mgr.standbys[subnet] = standby
+
+	defer mgr.Shutdown()
+
+	// a standby that is not running yet cannot take over.
+	err := mgr.CNCIDisconnected(active.instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if active.failover != nil {
+		t.Fatal("failover scheduled without an active standby")
+	}
+
+	standby.instance.State = payloads.Running
+
+	err = mgr.CNCIDisconnected(active.instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if active.failover == nil {
+		t.Fatal("failover not scheduled")
+	}
+
+	err = mgr.CNCIConnected(active.instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if active.failover != nil {
+		t.Fatal("failover not cancelled on reconnect")
+	}
+
+	// losing the standby never triggers a failover.
+	err = mgr.CNCIDisconnected(standby.instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if standby.failover != nil {
+		t.Fatal("failover scheduled for standby CNCI")
+	}
+
+	err = mgr.CNCIDisconnected("unknown")
+	if err == nil {
+		t.Fatal("expected error for unknown CNCI")
+	}
+}
@@ -74,6 +74,111 @@ func (c *controller) stopInstance(instanceID string) error {
 	return nil
 }
 
+// getAssignedInstance returns the instance instanceID if it is assigned to
+// a node and is in the given state.
+func (c *controller) getAssignedInstance(instanceID string, state string) (*types.Instance, error) {
+	i, err := c.ds.GetInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.NodeID == "" {
+		return nil, types.ErrInstanceNotAssigned
+	}
+
+	if i.State != state {
+		return nil, &types.InstanceStateError{Expected: state}
+	}
+
+	return i, nil
+}
+
+func (c *controller) pauseInstance(instanceID string) error {
+	i, err := c.getAssignedInstance(instanceID, payloads.Running)
+	if err != nil {
+		return err
+	}
+
+	go c.client.pauseInstance(instanceID, i.NodeID)
+	return nil
+}
+
+func (c *controller) unpauseInstance(instanceID string) error {
+	i, err := c.getAssignedInstance(instanceID, payloads.Paused)
+	if err != nil {
+		return err
+	}
+
+	go c.client.unpauseInstance(instanceID, i.NodeID)
+	return nil
+}
+
+// suspendInstance asks the node running an instance to save the instance's
+// state to disk and shut it down.  The VCPUs and memory of a suspended
+// instance no longer count against its tenant's quota.
+func (c *controller) suspendInstance(instanceID string) error {
+	i, err := c.getAssignedInstance(instanceID, payloads.Running)
+	if err != nil {
+		return err
+	}
+
+	if !i.CNCI {
+		wl, err := c.ds.GetWorkload(i.TenantID, i.WorkloadID)
+		if err != nil {
+			return err
+		}
+
+		err = c.ds.SetInstanceSuspended(instanceID, true)
+		if err != nil {
+			return err
+		}
+		c.qs.Release(i.TenantID, suspendedResources(&wl)...)
+	}
+
+	go c.client.suspendInstance(instanceID, i.NodeID)
+	return nil
+}
+
+// resumeInstance restores a suspended instance, provided that its tenant has
+// enough quota for the instance's VCPUs and memory.  The scheduler checks
+// that the instance's node has enough memory left to restore it.
+func (c *controller) resumeInstance(instanceID string) error {
+	i, err := c.getAssignedInstance(instanceID, payloads.Suspended)
+	if err != nil {
+		return err
+	}
+
+	wl, err := c.ds.GetWorkload(i.TenantID, i.WorkloadID)
+	if err != nil {
+		return err
+	}
+
+	if !i.CNCI {
+		resources := suspendedResources(&wl)
+		res := <-c.qs.Consume(i.TenantID, resources...)
+		if !res.Allowed() {
+			c.qs.Release(i.TenantID, res.Resources()...)
+			return types.ErrQuota
+		}
+
+		err = c.ds.SetInstanceSuspended(instanceID, false)
+		if err != nil {
+			c.qs.Release(i.TenantID, resources...)
+			return err
+		}
+	}
+
+	memMB := 0
+	for _, r := range wl.Defaults {
+		if r.Type == payloads.MemMB {
+			memMB = r.Value
+		}
+	}
+
+	go c.client.resumeInstance(instanceID, i.NodeID, memMB)
+	return nil
+}
+
 // delete an instance, wait for the deleted event.
 func (c *controller) deleteInstanceSync(instanceID string) error {
 	wait := make(chan struct{})
@@ -222,6 +327,16 @@ func (c *controller) startWorkload(w types.WorkloadRequest) ([]*types.Instance,
 		return nil, err
 	}
 
+	// containers are only ever attached to a single network.
+	if wl.VMType == payloads.Docker && len(w.AdditionalNetworks) > 0 {
+		return nil, types.ErrBadRequest
+	}
+
+	// a fixed address can only be assigned to a single instance.
+	if w.IPAddress != "" && w.Instances > 1 {
+		return nil, types.ErrBadRequest
+	}
+
 	var newInstances []*types.Instance
 
 	for i := 0; i < w.Instances && e == nil; i++ {
@@ -234,7 +349,9 @@ func (c *controller) startWorkload(w types.WorkloadRequest) ([]*types.Instance,
 			}
 		}
 
-		instance, err := newInstance(c, w.TenantID, &wl, w.Volumes, name, w.Subnet)
+		instance, err := newInstance(c, w.TenantID, &wl, w.Volumes, name, w.Subnet,
+			w.NetworkID, w.AdditionalNetworks, w.IPAddress, w.RetainIP, i,
+			w.Metadata, w.ExcludedNodes)
 		if err != nil {
 			e = errors.Wrap(err, "Error creating instance")
 			continue
@@ -257,6 +374,8 @@ func (c *controller) startWorkload(w types.WorkloadRequest) ([]*types.Instance,
 			}
 
 			newInstances = append(newInstances, instance.Instance)
+			go c.updateInstanceDNS(instance.Instance)
+			go c.updateInstanceMetadata(instance.Instance)
 			if w.TraceLabel == "" {
 				go c.client.StartWorkload(instance.newConfig.config)
 			} else {
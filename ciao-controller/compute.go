@@ -25,6 +25,7 @@ import (
 	"github.com/ciao-project/ciao/ciao-storage"
 	"github.com/ciao-project/ciao/uuid"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 )
 
 func instanceToServer(ctl *controller, instance *types.Instance) (api.ServerDetails, error) {
@@ -44,15 +45,58 @@ func instanceToServer(ctl *controller, instance *types.Instance) (api.ServerDeta
 		Status:     instance.State,
 		PrivateAddresses: []api.PrivateAddresses{
 			{
-				Addr:    instance.IPAddress,
-				MacAddr: instance.MACAddress,
+				Addr:     instance.IPAddress,
+				MacAddr:  instance.MACAddress,
+				IPv6Addr: ctl.ds.GetNICIPv6(instance.TenantID, instance.NetworkID, instance.MACAddress),
 			},
 		},
-		Volumes: volumes,
-		SSHIP:   instance.SSHIP,
-		SSHPort: instance.SSHPort,
-		Created: instance.CreateTime,
-		Name:    instance.Name,
+		Volumes:   volumes,
+		SSHIP:     instance.SSHIP,
+		SSHPort:   instance.SSHPort,
+		Created:   instance.CreateTime,
+		Name:      instance.Name,
+		NetworkID: instance.NetworkID,
+		RetainIP:  instance.RetainIP,
+		Metadata:  instance.Metadata,
+	}
+
+	for _, nic := range instance.AdditionalNICs {
+		server.PrivateAddresses = append(server.PrivateAddresses,
+			api.PrivateAddresses{
+				Addr:      nic.IPAddress,
+				MacAddr:   nic.MACAddress,
+				NetworkID: nic.NetworkID,
+				IPv6Addr:  ctl.ds.GetNICIPv6(instance.TenantID, nic.NetworkID, nic.MACAddress),
+			})
+	}
+
+	if instance.Guest != nil {
+		server.Guest = &api.GuestDetails{
+			OSName:        instance.Guest.OSName,
+			OSVersion:     instance.Guest.OSVersion,
+			KernelRelease: instance.Guest.KernelRelease,
+			IPAddresses:   instance.Guest.IPAddresses,
+		}
+	}
+
+	if instance.BlockIO != nil {
+		server.BlockIO = &api.BlockIODetails{
+			ReadBytes:  instance.BlockIO.ReadBytes,
+			WriteBytes: instance.BlockIO.WriteBytes,
+			ReadOps:    instance.BlockIO.ReadOps,
+			WriteOps:   instance.BlockIO.WriteOps,
+		}
+	}
+
+	if instance.NetworkIO != nil {
+		server.NetworkIO = &api.NetworkIODetails{
+			RxBytes:   instance.NetworkIO.RxBytes,
+			RxPackets: instance.NetworkIO.RxPackets,
+			RxDropped: instance.NetworkIO.RxDropped,
+			TxBytes:   instance.NetworkIO.TxBytes,
+			TxPackets: instance.NetworkIO.TxPackets,
+			TxDropped: instance.NetworkIO.TxDropped,
+		}
 	}
 
 	return server, nil
@@ -361,12 +405,17 @@ func (c *controller) CreateServer(tenant string, server api.CreateServerRequest)
 	label := server.Server.Metadata["label"]
 
 	w := types.WorkloadRequest{
-		WorkloadID: server.Server.WorkloadID,
-		TenantID:   tenant,
-		Instances:  nInstances,
-		TraceLabel: label,
-		Volumes:    volumes,
-		Name:       server.Server.Name,
+		WorkloadID:         server.Server.WorkloadID,
+		TenantID:           tenant,
+		Instances:          nInstances,
+		TraceLabel:         label,
+		Volumes:            volumes,
+		Name:               server.Server.Name,
+		Metadata:           server.Server.Metadata,
+		NetworkID:          server.Server.NetworkID,
+		AdditionalNetworks: server.Server.AdditionalNetworks,
+		IPAddress:          server.Server.IPAddress,
+		RetainIP:           server.Server.RetainIP,
 	}
 	var e error
 	instances, err := c.startWorkload(w)
@@ -384,9 +433,11 @@ func (c *controller) CreateServer(tenant string, server api.CreateServerRequest)
 		servers.Servers = append(servers.Servers, server)
 	}
 
-	// If no instances launcher or if none converted bail early
+	// If no instances launcher or if none converted bail early.  The
+	// cause is returned so that errors such as a fixed IP address
+	// already in use are reported to the client as such.
 	if e != nil && len(servers.Servers) == 0 {
-		return server, e
+		return server, errors.Cause(e)
 	}
 
 	servers.TotalServers = len(instances)
@@ -494,6 +545,42 @@ func (c *controller) StopServer(tenant string, ID string) error {
 	return err
 }
 
+func (c *controller) PauseServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.pauseInstance(ID)
+}
+
+func (c *controller) UnpauseServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.unpauseInstance(ID)
+}
+
+func (c *controller) SuspendServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.suspendInstance(ID)
+}
+
+func (c *controller) ResumeServer(tenant string, ID string) error {
+	_, err := c.ds.GetTenantInstance(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.resumeInstance(ID)
+}
+
 func (c *controller) createComputeRoutes(r *mux.Router) error {
 	legacyComputeRoutes(c, r)
 
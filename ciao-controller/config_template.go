@@ -0,0 +1,91 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+)
+
+// configTemplateData holds the per instance variables that can be
+// referenced from the cloud-init config of a workload.  The configs of
+// workloads created with Template set are rendered as a text/template
+// before being sent to the launcher.
+type configTemplateData struct {
+	// Name is the hostname of the instance: its name if one was
+	// requested, otherwise its UUID.
+	Name string
+
+	// UUID is the ID of the instance.
+	UUID string
+
+	// Index is the position of the instance within the batch of
+	// instances created by a single request, starting at 0.
+	Index int
+
+	// TenantID is the ID of the tenant owning the instance.
+	TenantID string
+
+	// IPAddress is the tenant network IP address of the instance.
+	IPAddress string
+
+	// CNCIIP is the IP address of the tenant's CNCI.
+	CNCIIP string
+
+	// Metadata holds the key/value metadata of the create request.
+	// Missing keys render as empty strings.
+	Metadata map[string]string
+}
+
+func parseConfigTemplate(config string) (*template.Template, error) {
+	tmpl, err := template.New("config").Option("missingkey=zero").Parse(config)
+	if err != nil {
+		return nil, &types.ConfigTemplateError{Err: err}
+	}
+
+	return tmpl, nil
+}
+
+func renderConfig(config string, data *configTemplateData) (string, error) {
+	tmpl, err := parseConfigTemplate(config)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", &types.ConfigTemplateError{Err: err}
+	}
+
+	return buf.String(), nil
+}
+
+// validateConfigTemplate checks that a workload config can be rendered,
+// so that errors in the template are reported when the workload is created
+// rather than when it is launched.
+func validateConfigTemplate(config string) error {
+	_, err := renderConfig(config, &configTemplateData{
+		Name:      "instance",
+		UUID:      "00000000-0000-0000-0000-000000000000",
+		TenantID:  "00000000-0000-0000-0000-000000000000",
+		IPAddress: "0.0.0.0",
+		CNCIIP:    "0.0.0.0",
+		Metadata:  map[string]string{},
+	})
+	return err
+}
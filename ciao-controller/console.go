@@ -0,0 +1,184 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+var consoleTimeout = 30 * time.Second
+
+// consoleTokenLifetime is the time for which a console token can be used
+// once the session has been opened.  It must be less than the time for
+// which launcher waits for a client to connect to the session.
+var consoleTokenLifetime = 30 * time.Second
+
+// consoleSession tracks a console session opened, or being opened, by the
+// node running an instance.
+type consoleSession struct {
+	tenant     string
+	instanceID string
+	address    string
+	expires    time.Time
+	openedCh   chan error
+}
+
+func newConsoleToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// OpenConsole asks the node running an instance to open a session on its
+// serial console and returns a short lived token with which a client can
+// connect to this session once, using ConnectConsole.
+func (c *controller) OpenConsole(tenant string, instanceID string) (string, error) {
+	i, err := c.ds.GetTenantInstance(tenant, instanceID)
+	if err != nil {
+		return "", types.ErrInstanceNotFound
+	}
+
+	if i.NodeID == "" {
+		return "", types.ErrInstanceNotAssigned
+	}
+
+	token, err := newConsoleToken()
+	if err != nil {
+		return "", err
+	}
+
+	session := &consoleSession{
+		tenant:     tenant,
+		instanceID: instanceID,
+		openedCh:   make(chan error, 1),
+	}
+
+	c.consolesLock.Lock()
+	if c.consoles == nil {
+		c.consoles = make(map[string]*consoleSession)
+	}
+	c.expireConsoles()
+	c.consoles[token] = session
+	c.consolesLock.Unlock()
+
+	err = c.client.openConsole(instanceID, i.NodeID, token)
+	if err != nil {
+		c.removeConsole(token)
+		return "", err
+	}
+
+	select {
+	case err = <-session.openedCh:
+		if err != nil {
+			c.removeConsole(token)
+			return "", err
+		}
+		return token, nil
+	case <-time.After(consoleTimeout):
+		c.removeConsole(token)
+		return "", types.ErrConsoleTimeout
+	}
+}
+
+// expireConsoles removes the sessions whose tokens have expired without
+// being used.  It must be called with consolesLock held.
+func (c *controller) expireConsoles() {
+	now := time.Now()
+	for token, session := range c.consoles {
+		if session.address != "" && now.After(session.expires) {
+			delete(c.consoles, token)
+		}
+	}
+}
+
+func (c *controller) removeConsole(token string) {
+	c.consolesLock.Lock()
+	delete(c.consoles, token)
+	c.consolesLock.Unlock()
+}
+
+// consoleOpened hands the result of an OpenConsole command, i.e., a
+// ConsoleOpened event or an OpenConsoleFailure error, to the OpenConsole
+// call waiting for it.
+func (c *controller) consoleOpened(token string, address string, err error) {
+	c.consolesLock.Lock()
+	defer c.consolesLock.Unlock()
+
+	session := c.consoles[token]
+	if session == nil || session.openedCh == nil {
+		return
+	}
+
+	if err == nil {
+		session.address = address
+		session.expires = time.Now().Add(consoleTokenLifetime)
+	}
+	session.openedCh <- err
+	session.openedCh = nil
+}
+
+// ConnectConsole connects to the console session associated with token,
+// which must have been returned by OpenConsole for the same tenant and
+// instance.  Tokens can only be used once.
+func (c *controller) ConnectConsole(tenant string, instanceID string, token string) (io.ReadWriteCloser, error) {
+	c.consolesLock.Lock()
+	session := c.consoles[token]
+	if session != nil && session.address != "" {
+		delete(c.consoles, token)
+	}
+	c.consolesLock.Unlock()
+
+	if session == nil || session.address == "" ||
+		session.tenant != tenant || session.instanceID != instanceID ||
+		time.Now().After(session.expires) {
+		return nil, types.ErrConsoleTokenInvalid
+	}
+
+	dialer := &net.Dialer{Timeout: consoleTimeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", session.address, c.consoleTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte(token + "\n")); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	glog.Infof("Console of %s connected to %s", instanceID, session.address)
+
+	return conn, nil
+}
+
+func openConsoleFailureError(reason payloads.OpenConsoleFailureReason) error {
+	if reason == payloads.OpenConsoleNotRunning {
+		return types.ErrInstanceNotRunning
+	}
+
+	return fmt.Errorf("Unable to open console: %s", reason)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+)
+
+var consoleLogTimeout = 30 * time.Second
+
+type consoleLogResult struct {
+	log string
+	err error
+}
+
+// GetConsoleLog asks the node running an instance for the last lines lines
+// of its serial console log, or the entire log if lines is 0, and waits for
+// the reply.
+func (c *controller) GetConsoleLog(tenant string, instanceID string, lines int) (string, error) {
+	i, err := c.ds.GetTenantInstance(tenant, instanceID)
+	if err != nil {
+		return "", types.ErrInstanceNotFound
+	}
+
+	if i.NodeID == "" {
+		return "", types.ErrInstanceNotAssigned
+	}
+
+	// Each request is tagged with an ID that launcher returns in its
+	// reply, so that a late reply to a request that has timed out is
+	// not handed to a later request.
+	requestID := uuid.Generate().String()
+	ch := make(chan consoleLogResult, 1)
+	c.consoleLogsLock.Lock()
+	if c.consoleLogs == nil {
+		c.consoleLogs = make(map[string]chan consoleLogResult)
+	}
+	c.consoleLogs[requestID] = ch
+	c.consoleLogsLock.Unlock()
+
+	err = c.client.getConsoleLog(instanceID, i.NodeID, lines, requestID)
+	if err != nil {
+		c.removeConsoleLog(requestID)
+		return "", err
+	}
+
+	select {
+	case result := <-ch:
+		return result.log, result.err
+	case <-time.After(consoleLogTimeout):
+		c.removeConsoleLog(requestID)
+		return "", types.ErrConsoleLogTimeout
+	}
+}
+
+func (c *controller) removeConsoleLog(requestID string) {
+	c.consoleLogsLock.Lock()
+	delete(c.consoleLogs, requestID)
+	c.consoleLogsLock.Unlock()
+}
+
+// consoleLogReceived hands a ConsoleLog event or a ConsoleLogFailure error
+// to the request for an instance's console log whose ID it carries.
+// Replies to requests that have timed out are discarded.
+func (c *controller) consoleLogReceived(requestID string, result consoleLogResult) {
+	c.consoleLogsLock.Lock()
+	defer c.consoleLogsLock.Unlock()
+
+	ch := c.consoleLogs[requestID]
+	if ch == nil {
+		return
+	}
+
+	ch <- result
+	delete(c.consoleLogs, requestID)
+}
+
+func consoleLogFailureError(reason payloads.ConsoleLogFailureReason) error {
+	if reason == payloads.ConsoleLogNotSupported {
+		return types.ErrConsoleLogNotSupported
+	}
+
+	return fmt.Errorf("Unable to retrieve console log: %s", reason)
+}
@@ -17,6 +17,9 @@
 package main
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -25,6 +28,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -83,6 +87,10 @@ users:
 }
 
 func addFakeCNCI(tenant *types.Tenant) (*types.Instance, error) {
+	return addFakeSubnetCNCI(tenant, "172.16.0.0/24")
+}
+
+func addFakeSubnetCNCI(tenant *types.Tenant, subnet string) (*types.Instance, error) {
 	mac, err := utils.NewHardwareAddr()
 	if err != nil {
 		return nil, err
@@ -96,7 +104,7 @@ func addFakeCNCI(tenant *types.Tenant) (*types.Instance, error) {
 		CNCI:       true,
 		IPAddress:  "192.168.0.1",
 		MACAddress: mac.String(),
-		Subnet:     "172.16.0.0/24",
+		Subnet:     subnet,
 	}
 
 	return &CNCI, ctl.ds.AddInstance(&CNCI)
@@ -259,7 +267,7 @@ func BenchmarkNewConfig(b *testing.B) {
 	b.ResetTimer()
 	noVolumes := []storage.BlockDevice{}
 	for n := 0; n < b.N; n++ {
-		_, err := newConfig(ctl, &wls[0], id.String(), tenant.ID, noVolumes, fmt.Sprintf("test-%d", n))
+		_, err := newConfig(ctl, &wls[0], id.String(), tenant.ID, noVolumes, fmt.Sprintf("test-%d", n), "", nil, "", n, nil, nil)
 		if err != nil {
 			b.Error(err)
 		}
@@ -456,6 +464,265 @@ func TestStopInstance(t *testing.T) {
 	}
 }
 
+func TestPauseInstance(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	err := ctl.unpauseInstance(instances[0].ID)
+	if err == nil {
+		t.Fatal("Expected unpause of running instance to fail")
+	}
+
+	serverCh := server.AddCmdChan(ssntp.PauseInstance)
+
+	err = ctl.pauseInstance(instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.PauseInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != instances[0].ID || result.NodeUUID != client.UUID {
+		t.Fatalf("expected %s %s, got %s %s", instances[0].ID, client.UUID,
+			result.InstanceUUID, result.NodeUUID)
+	}
+}
+
+func TestSuspendInstance(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	err := ctl.resumeInstance(instances[0].ID)
+	if _, ok := err.(*types.InstanceStateError); !ok {
+		t.Fatalf("Expected InstanceStateError resuming running instance, got %v", err)
+	}
+
+	serverCh := server.AddCmdChan(ssntp.SuspendInstance)
+
+	err = ctl.suspendInstance(instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = server.GetCmdChanResult(serverCh, ssntp.SuspendInstance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i, err := ctl.ds.GetInstance(instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !i.Suspended {
+		t.Fatal("Instance not marked as suspended")
+	}
+
+	// The node has yet to report the instance as suspended, but its
+	// resources have already been released.
+	err = ctl.suspendInstance(instances[0].ID)
+	if _, ok := err.(*types.InstanceStateError); !ok {
+		t.Fatalf("Expected InstanceStateError suspending instance twice, got %v", err)
+	}
+}
+
+func testGetConsoleLog(t *testing.T, fail bool) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	serverCh := server.AddCmdChan(ssntp.GetConsoleLog)
+
+	if fail {
+		client.ConsoleLogFail = true
+		client.ConsoleLogFailReason = payloads.ConsoleLogNotSupported
+
+		defer func() {
+			client.ConsoleLogFail = false
+			client.ConsoleLogFailReason = ""
+		}()
+	}
+
+	log, err := ctl.GetConsoleLog(instances[0].TenantID, instances[0].ID, 10)
+
+	result, serverErr := server.GetCmdChanResult(serverCh, ssntp.GetConsoleLog)
+	if serverErr != nil {
+		t.Fatal(serverErr)
+	}
+	if result.InstanceUUID != instances[0].ID || result.NodeUUID != client.UUID {
+		t.Fatalf("expected %s %s, got %s %s", instances[0].ID, client.UUID,
+			result.InstanceUUID, result.NodeUUID)
+	}
+
+	if fail {
+		if err != types.ErrConsoleLogNotSupported {
+			t.Fatalf("expected %v, got %v", types.ErrConsoleLogNotSupported, err)
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log != testutil.ConsoleLogOutput {
+		t.Fatalf("expected console log %q, got %q", testutil.ConsoleLogOutput, log)
+	}
+}
+
+func TestGetConsoleLog(t *testing.T) {
+	testGetConsoleLog(t, false)
+}
+
+func TestGetConsoleLogFailure(t *testing.T) {
+	testGetConsoleLog(t, true)
+}
+
+func TestGetConsoleLogNoInstance(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.GetConsoleLog(tenant.ID, "not-an-instance", 0)
+	if err != types.ErrInstanceNotFound {
+		t.Fatalf("expected %v, got %v", types.ErrInstanceNotFound, err)
+	}
+}
+
+func TestConsoleLogLateReply(t *testing.T) {
+	c := &controller{}
+	ch := make(chan consoleLogResult, 1)
+	c.consoleLogs = map[string]chan consoleLogResult{"current": ch}
+
+	c.consoleLogReceived("timed-out", consoleLogResult{log: "stale"})
+	select {
+	case result := <-ch:
+		t.Fatalf("reply to timed out request received: %q", result.log)
+	default:
+	}
+
+	c.consoleLogReceived("current", consoleLogResult{log: "fresh"})
+	result := <-ch
+	if result.log != "fresh" || len(c.consoleLogs) != 0 {
+		t.Fatalf("expected fresh console log, got %q", result.log)
+	}
+}
+
+func TestOpenConsole(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	agentTLS, err := ssntp.PeerTLSConfig(&ssntp.Config{
+		CAcert: ssntp.DefaultCACert,
+		Cert:   ssntp.RoleToDefaultCertName(ssntp.AGENT),
+	}, true, ssntp.Controller)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", agentTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	client.OpenConsoleAddress = ln.Addr().String()
+	defer func() { client.OpenConsoleAddress = "" }()
+
+	serverCh := server.AddCmdChan(ssntp.OpenConsole)
+
+	token, err := ctl.OpenConsole(instances[0].TenantID, instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.OpenConsole)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != instances[0].ID || result.NodeUUID != client.UUID {
+		t.Fatalf("expected %s %s, got %s %s", instances[0].ID, client.UUID,
+			result.InstanceUUID, result.NodeUUID)
+	}
+
+	tokenCh := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(tokenCh)
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		tokenCh <- line
+	}()
+
+	console, err := ctl.ConnectConsole(instances[0].TenantID, instances[0].ID, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = console.Close() }()
+
+	if line := <-tokenCh; line != token+"\n" {
+		t.Fatalf("expected token %q, got %q", token+"\n", line)
+	}
+
+	_, err = ctl.ConnectConsole(instances[0].TenantID, instances[0].ID, token)
+	if err != types.ErrConsoleTokenInvalid {
+		t.Fatalf("expected %v when reusing token, got %v", types.ErrConsoleTokenInvalid, err)
+	}
+}
+
+func TestOpenConsoleFailure(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	client.OpenConsoleFail = true
+	client.OpenConsoleFailReason = payloads.OpenConsoleNotRunning
+	defer func() {
+		client.OpenConsoleFail = false
+		client.OpenConsoleFailReason = ""
+	}()
+
+	_, err := ctl.OpenConsole(instances[0].TenantID, instances[0].ID)
+	if err != types.ErrInstanceNotRunning {
+		t.Fatalf("expected %v, got %v", types.ErrInstanceNotRunning, err)
+	}
+}
+
+func TestConnectConsoleInvalidToken(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.ConnectConsole(tenant.ID, uuid.Generate().String(), "not-a-token")
+	if err != types.ErrConsoleTokenInvalid {
+		t.Fatalf("expected %v, got %v", types.ErrConsoleTokenInvalid, err)
+	}
+}
+
 func TestRestartInstance(t *testing.T) {
 	var reason payloads.StartFailureReason
 
@@ -564,7 +831,7 @@ func TestAttachVolume(t *testing.T) {
 
 	// ok to not send workload first?
 
-	err = ctl.client.attachVolume("volID", "instanceID", client.UUID)
+	err = ctl.client.attachVolume("volID", "instanceID", client.UUID, false, false)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -609,94 +876,293 @@ func addTestBlockDevice(t *testing.T, tenantID string) types.Volume {
 	return data
 }
 
-// Note: caller should close ssntp client
-func doAttachVolumeCommand(t *testing.T, fail bool) (client *testutil.SsntpTestClient, tenant string, volume string, instanceID string) {
-	var reason payloads.StartFailureReason
+func waitForBackup(t *testing.T, ID string) types.Backup {
+	for i := 0; i < 50; i++ {
+		b, err := ctl.ds.GetBackup(ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.State != types.BackupCreating {
+			return b
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 
-	client, instances := testStartWorkload(t, 1, false, reason)
+	t.Fatalf("Timed out waiting for backup %s", ID)
+	return types.Backup{}
+}
 
-	tenantID := instances[0].TenantID
+// waitForRestore waits for the restore of a volume to finish and returns
+// the restored volume.  An error is returned if the restore failed and
+// the volume was deleted.
+func waitForRestore(t *testing.T, ID string) (types.Volume, error) {
+	for i := 0; i < 50; i++ {
+		vol, err := ctl.ds.GetBlockDevice(ID)
+		if err != nil {
+			return types.Volume{}, err
+		}
+		if vol.State != types.Creating {
+			return vol, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
 
-	sendStatsCmd(client, t)
+	t.Fatalf("Timed out waiting for volume %s", ID)
+	return types.Volume{}, nil
+}
 
-	data := addTestBlockDevice(t, tenantID)
+func TestBackupRestoreVolume(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	serverCh := server.AddCmdChan(ssntp.AttachVolume)
-	agentCh := client.AddCmdChan(ssntp.AttachVolume)
-	var serverErrorCh chan testutil.Result
-	var controllerCh chan struct{}
+	data := addTestBlockDevice(t, tenant.ID)
 
-	if fail == true {
-		serverErrorCh = server.AddErrorChan(ssntp.AttachVolumeFailure)
-		controllerCh = wrappedClient.addErrorChan(ssntp.AttachVolumeFailure)
-		client.AttachFail = true
-		client.AttachVolumeFailReason = payloads.AttachVolumeAlreadyAttached
+	req := api.RequestedBackup{
+		Name:        "backup",
+		Description: "backup of test volume",
+	}
 
-		defer func() {
-			client.AttachFail = false
-			client.AttachVolumeFailReason = ""
-		}()
+	b, err := ctl.CreateBackup(tenant.ID, data.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.State != types.BackupCreating || b.VolumeID != data.ID ||
+		b.TenantID != tenant.ID || b.Name != req.Name {
+		t.Fatalf("Unexpected backup %+v", b)
+	}
+
+	b = waitForBackup(t, b.ID)
+	if b.State != types.BackupAvailable {
+		t.Fatalf("Expected backup to be available, got %s", b.State)
 	}
 
-	err := ctl.AttachVolume(tenantID, data.ID, instances[0].ID, "")
+	// the noop driver exports no data.
+	if b.Size != 0 || b.Checksum != fmt.Sprintf("%x", sha256.Sum256(nil)) {
+		t.Fatalf("Unexpected backup size %d or checksum %s", b.Size, b.Checksum)
+	}
+
+	backups, err := ctl.ListBackups(tenant.ID)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(backups) != 1 || backups[0].ID != b.ID {
+		t.Fatalf("Expected backup %s to be listed", b.ID)
+	}
 
-	result, err := server.GetCmdChanResult(serverCh, ssntp.AttachVolume)
+	otherTenant, err := addTestTenant()
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if result.InstanceUUID != instances[0].ID ||
-		result.NodeUUID != client.UUID ||
-		result.VolumeUUID != data.ID {
-		t.Fatalf("expected %s %s %s, got %s %s %s", instances[0].ID, client.UUID, data.ID, result.InstanceUUID, result.NodeUUID, result.VolumeUUID)
+	_, err = ctl.ShowBackup(otherTenant.ID, b.ID)
+	if err != types.ErrBackupNotFound {
+		t.Fatalf("Expected ErrBackupNotFound for other tenant, got %v", err)
 	}
 
-	if fail == true {
-		_, err = client.GetCmdChanResult(agentCh, ssntp.AttachVolume)
-		if err == nil {
-			t.Fatal("Success when Failure expected")
-		}
+	vol, err := ctl.RestoreBackup(tenant.ID, b.ID, api.RequestedRestore{Name: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		_, err = server.GetErrorChanResult(serverErrorCh, ssntp.AttachVolumeFailure)
-		if err != nil {
-			t.Fatal(err)
-		}
+	if vol.ID == data.ID || vol.Name != "restored" || vol.State != types.Creating ||
+		vol.Size != data.Size {
+		t.Fatalf("Unexpected restored volume %+v", vol)
+	}
 
-		err = wrappedClient.getErrorChan(controllerCh, ssntp.AttachVolumeFailure)
-		if err != nil {
-			t.Fatal(err)
-		}
+	vol, err = waitForRestore(t, vol.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-		// at this point, the state of the block device should
-		// be set back to available.
-		data2, err := ctl.ds.GetBlockDevice(data.ID)
-		if err != nil {
-			t.Fatal(err)
-		}
+	if vol.State != types.Available {
+		t.Fatalf("Expected restored volume to be available, got %s", vol.State)
+	}
 
-		if data2.State != types.Available {
-			t.Fatalf("block device state not updated")
-		}
-	} else {
-		_, err = client.GetCmdChanResult(agentCh, ssntp.AttachVolume)
-		if err != nil {
-			t.Fatal(err)
-		}
+	_, err = ctl.ShowVolumeDetails(tenant.ID, vol.ID)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	return client, tenantID, data.ID, instances[0].ID
-}
+	err = ctl.DeleteBackup(tenant.ID, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-func TestAttachVolumeCommand(t *testing.T) {
-	client, _, _, _ := doAttachVolumeCommand(t, false)
-	client.Ssntp.Close()
+	_, err = ctl.ShowBackup(tenant.ID, b.ID)
+	if err != types.ErrBackupNotFound {
+		t.Fatalf("Expected ErrBackupNotFound for deleted backup, got %v", err)
+	}
 }
 
-func TestAttachVolumeFailure(t *testing.T) {
-	client, _, _, _ := doAttachVolumeCommand(t, true)
+func TestRestoreBackupChecksumMismatch(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := addTestBlockDevice(t, tenant.ID)
+
+	b, err := ctl.CreateBackup(tenant.ID, data.ID, api.RequestedBackup{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b = waitForBackup(t, b.ID)
+
+	b.Checksum = "corrupt"
+	err = ctl.ds.UpdateBackup(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := ctl.ds.GetBlockDevices(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vol, err := ctl.RestoreBackup(tenant.ID, b.ID, api.RequestedRestore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = waitForRestore(t, vol.ID)
+	if err != datastore.ErrNoBlockData {
+		t.Fatalf("Expected restored volume to be deleted, got %v", err)
+	}
+
+	after, err := ctl.ds.GetBlockDevices(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(devices) {
+		t.Fatalf("Volume should not have been created from corrupt backup")
+	}
+
+	err = ctl.DeleteBackup(tenant.ID, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecoverInterruptedRestore(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := types.Volume{
+		BlockDevice: storage.BlockDevice{
+			ID:   uuid.Generate().String(),
+			Size: 1,
+		},
+		TenantID: tenant.ID,
+		State:    types.Creating,
+	}
+
+	err = ctl.ds.AddBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctl.recoverBackups()
+
+	_, err = ctl.ds.GetBlockDevice(data.ID)
+	if err != datastore.ErrNoBlockData {
+		t.Fatalf("Expected interrupted restore to be deleted, got %v", err)
+	}
+}
+
+// Note: caller should close ssntp client
+func doAttachVolumeCommand(t *testing.T, fail bool) (client *testutil.SsntpTestClient, tenant string, volume string, instanceID string) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+
+	tenantID := instances[0].TenantID
+
+	sendStatsCmd(client, t)
+
+	data := addTestBlockDevice(t, tenantID)
+
+	serverCh := server.AddCmdChan(ssntp.AttachVolume)
+	agentCh := client.AddCmdChan(ssntp.AttachVolume)
+	var serverErrorCh chan testutil.Result
+	var controllerCh chan struct{}
+
+	if fail == true {
+		serverErrorCh = server.AddErrorChan(ssntp.AttachVolumeFailure)
+		controllerCh = wrappedClient.addErrorChan(ssntp.AttachVolumeFailure)
+		client.AttachFail = true
+		client.AttachVolumeFailReason = payloads.AttachVolumeAlreadyAttached
+
+		defer func() {
+			client.AttachFail = false
+			client.AttachVolumeFailReason = ""
+		}()
+	}
+
+	err := ctl.AttachVolume(tenantID, data.ID, instances[0].ID, "", types.ReadWrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.AttachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.InstanceUUID != instances[0].ID ||
+		result.NodeUUID != client.UUID ||
+		result.VolumeUUID != data.ID {
+		t.Fatalf("expected %s %s %s, got %s %s %s", instances[0].ID, client.UUID, data.ID, result.InstanceUUID, result.NodeUUID, result.VolumeUUID)
+	}
+
+	if fail == true {
+		_, err = client.GetCmdChanResult(agentCh, ssntp.AttachVolume)
+		if err == nil {
+			t.Fatal("Success when Failure expected")
+		}
+
+		_, err = server.GetErrorChanResult(serverErrorCh, ssntp.AttachVolumeFailure)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = wrappedClient.getErrorChan(controllerCh, ssntp.AttachVolumeFailure)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// at this point, the state of the block device should
+		// be set back to available.
+		data2, err := ctl.ds.GetBlockDevice(data.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data2.State != types.Available {
+			t.Fatalf("block device state not updated")
+		}
+	} else {
+		_, err = client.GetCmdChanResult(agentCh, ssntp.AttachVolume)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return client, tenantID, data.ID, instances[0].ID
+}
+
+func TestAttachVolumeCommand(t *testing.T) {
+	client, _, _, _ := doAttachVolumeCommand(t, false)
+	client.Ssntp.Close()
+}
+
+func TestAttachVolumeFailure(t *testing.T) {
+	client, _, _, _ := doAttachVolumeCommand(t, true)
 	client.Ssntp.Close()
 }
 
@@ -717,20 +1183,40 @@ func doDetachVolumeCommand(t *testing.T, fail bool) {
 	}
 
 	if fail {
+		serverErrorCh := server.AddErrorChan(ssntp.DetachVolumeFailure)
+		controllerCh := wrappedClient.addErrorChan(ssntp.DetachVolumeFailure)
+		client.DetachFail = true
+		client.DetachVolumeFailReason = payloads.DetachVolumeDetachFailure
+
+		defer func() {
+			client.DetachFail = false
+			client.DetachVolumeFailReason = ""
+		}()
+
 		err := ctl.DetachVolume(tenantID, volume, "")
-		if err == nil {
-			t.Fatal("Expected error when detaching volume from active instance")
+		if err != nil {
+			t.Fatal(err)
+		}
 
+		_, err = server.GetErrorChanResult(serverErrorCh, ssntp.DetachVolumeFailure)
+		if err != nil {
+			t.Fatal(err)
 		}
 
-		data, err := ctl.ds.GetBlockDevice(volume)
+		err = wrappedClient.getErrorChan(controllerCh, ssntp.DetachVolumeFailure)
 		if err != nil {
 			t.Fatal(err)
+		}
 
+		// at this point, the state of the block device should
+		// be set back to in use.
+		data, err := ctl.ds.GetBlockDevice(volume)
+		if err != nil {
+			t.Fatal(err)
 		}
 
 		if data.State != types.InUse {
-			t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
+			t.Fatalf("expected state %s, got %s\n", types.InUse, data.State)
 		}
 	} else {
 		serverCh := server.AddCmdChan(ssntp.DELETE)
@@ -746,56 +1232,287 @@ func doDetachVolumeCommand(t *testing.T, fail bool) {
 			t.Fatal(err)
 		}
 
-		_, err = client.GetCmdChanResult(clientCh, ssntp.DELETE)
+		_, err = client.GetCmdChanResult(clientCh, ssntp.DELETE)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.InstanceUUID != instanceID {
+			t.Fatal("Did not get correct Instance ID")
+		}
+
+		err = sendStopEvent(client, instanceID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ctl.DetachVolume(tenantID, volume, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ctl.ds.GetBlockDevice(volume)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data.State != types.Available {
+			t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
+		}
+	}
+}
+
+func TestDetachVolumeCommand(t *testing.T) {
+	doDetachVolumeCommand(t, false)
+}
+
+func TestDetachVolumeFailure(t *testing.T) {
+	doDetachVolumeCommand(t, true)
+}
+
+func TestDetachVolumeFromActiveInstance(t *testing.T) {
+	client, tenantID, volume, instanceID := doAttachVolumeCommand(t, false)
+	defer client.Ssntp.Close()
+
+	sendStatsCmd(client, t)
+
+	serverCh := server.AddCmdChan(ssntp.DetachVolume)
+	agentCh := client.AddCmdChan(ssntp.DetachVolume)
+	controllerCh := wrappedClient.addEventChan(ssntp.VolumeDetached)
+
+	err := ctl.DetachVolume(tenantID, volume, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.DetachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.InstanceUUID != instanceID ||
+		result.NodeUUID != client.UUID ||
+		result.VolumeUUID != volume {
+		t.Fatalf("expected %s %s %s, got %s %s %s", instanceID, client.UUID, volume, result.InstanceUUID, result.NodeUUID, result.VolumeUUID)
+	}
+
+	_, err = client.GetCmdChanResult(agentCh, ssntp.DetachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wrappedClient.getEventChan(controllerCh, ssntp.VolumeDetached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.State != types.Available {
+		t.Fatalf("expected state %s, got %s\n", types.Available, data.State)
+	}
+
+	attachments, err := ctl.ds.GetVolumeAttachments(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attachments) != 0 {
+		t.Fatalf("expected no attachments, got %d\n", len(attachments))
+	}
+}
+
+func TestDetachVolumeByAttachment(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.DetachVolume(tenant.ID, "invalidVolume", "attachmentID")
+	if err != api.ErrVolumeNotAttached {
+		t.Fatalf("expected %v, got %v", api.ErrVolumeNotAttached, err)
+	}
+
+	client, tenantID, volume, _ := doAttachVolumeCommand(t, false)
+	defer client.Ssntp.Close()
+
+	sendStatsCmd(client, t)
+
+	attachments, err := ctl.ds.GetVolumeAttachments(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(attachments))
+	}
+
+	err = ctl.DetachVolume(tenantID, volume, "attachmentID")
+	if err != api.ErrVolumeNotAttached {
+		t.Fatalf("expected %v, got %v", api.ErrVolumeNotAttached, err)
+	}
+
+	controllerCh := wrappedClient.addEventChan(ssntp.VolumeDetached)
+
+	err = ctl.DetachVolume(tenantID, volume, attachments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = wrappedClient.getEventChan(controllerCh, ssntp.VolumeDetached)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ctl.ds.GetBlockDevice(volume)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.State != types.Available {
+		t.Fatalf("expected state %s, got %s\n", types.Available, data.State)
+	}
+}
+
+func attachTestVolume(t *testing.T, client *testutil.SsntpTestClient, tenantID string,
+	volume string, instanceID string, mode types.AttachmentMode) {
+	agentCh := client.AddCmdChan(ssntp.AttachVolume)
+
+	err := ctl.AttachVolume(tenantID, volume, instanceID, "", mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetCmdChanResult(agentCh, ssntp.AttachVolume)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiAttachVolume(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 2, false, reason)
+	defer client.Shutdown()
+
+	tenantID := instances[0].TenantID
+
+	// testStartWorkload only waits for the first instance to start,
+	// so keep sending stats until both instances are running.
+	for tries := 0; ; tries++ {
+		sendStatsCmd(client, t)
+
+		running := 0
+		for _, instance := range instances {
+			i, err := ctl.ds.GetInstance(instance.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i.StateLock.RLock()
+			if i.State == payloads.Running && i.NodeID != "" {
+				running++
+			}
+			i.StateLock.RUnlock()
+		}
+
+		if running == len(instances) {
+			break
+		}
+
+		if tries == 10 {
+			t.Fatal("Timed out waiting for instances to start")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	data := addTestBlockDevice(t, tenantID)
+
+	// a volume that does not support multiple attachments can only
+	// be attached once.
+	attachTestVolume(t, client, tenantID, data.ID, instances[0].ID, types.ReadWrite)
+
+	err := ctl.AttachVolume(tenantID, data.ID, instances[1].ID, "", types.ReadWrite)
+	if err != api.ErrVolumeNotAvailable {
+		t.Fatalf("expected %v, got %v", api.ErrVolumeNotAvailable, err)
+	}
+
+	data.MultiAttach = true
+	data.State = types.InUse
+	err = ctl.ds.AddBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same instance cannot attach the volume twice.
+	err = ctl.AttachVolume(tenantID, data.ID, instances[0].ID, "", types.ReadWrite)
+	if err != api.ErrVolumeNotAvailable {
+		t.Fatalf("expected %v, got %v", api.ErrVolumeNotAvailable, err)
+	}
+
+	attachTestVolume(t, client, tenantID, data.ID, instances[1].ID, types.ReadOnly)
+
+	attachments, err := ctl.ds.GetVolumeAttachments(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(attachments))
+	}
+
+	for _, a := range attachments {
+		expected := types.ReadWrite
+		if a.InstanceID == instances[1].ID {
+			expected = types.ReadOnly
+		}
+		if a.Mode != expected {
+			t.Fatalf("expected mode %s, got %s", expected, a.Mode)
+		}
+	}
+
+	vol, err := ctl.ShowVolumeDetails(tenantID, data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vol.Attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %d", len(vol.Attachments))
+	}
+
+	// detaching one instance leaves the volume in use.
+	for i, a := range attachments {
+		controllerCh := wrappedClient.addEventChan(ssntp.VolumeDetached)
+
+		err = ctl.DetachVolume(tenantID, data.ID, a.ID)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if result.InstanceUUID != instanceID {
-			t.Fatal("Did not get correct Instance ID")
-		}
-
-		err = sendStopEvent(client, instanceID)
+		err = wrappedClient.getEventChan(controllerCh, ssntp.VolumeDetached)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		err = ctl.DetachVolume(tenantID, volume, "")
-		if err != nil {
-			t.Fatal(err)
+		expected := types.InUse
+		if i == len(attachments)-1 {
+			expected = types.Available
 		}
 
-		data, err := ctl.ds.GetBlockDevice(volume)
+		vol, err = ctl.ds.GetBlockDevice(data.ID)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		if data.State != types.Available {
-			t.Fatalf("expected state %s, got %s\n", types.Detaching, data.State)
+		if vol.State != expected {
+			t.Fatalf("expected state %s, got %s", expected, vol.State)
 		}
 	}
 }
 
-func TestDetachVolumeCommand(t *testing.T) {
-	doDetachVolumeCommand(t, false)
-}
-
-func TestDetachVolumeFailure(t *testing.T) {
-	doDetachVolumeCommand(t, true)
-}
-
-func TestDetachVolumeByAttachment(t *testing.T) {
-	tenant, err := addTestTenant()
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	err = ctl.DetachVolume(tenant.ID, "invalidVolume", "attachmentID")
-	if err == nil {
-		t.Fatal("Detach by attachment ID not supported yet")
-	}
-}
-
 func TestInstanceDeletedEvent(t *testing.T) {
 	var reason payloads.StartFailureReason
 
@@ -840,6 +1557,138 @@ func TestInstanceDeletedEvent(t *testing.T) {
 	}
 }
 
+func sendNodeInventory(client *testutil.SsntpTestClient, t *testing.T,
+	instances []payloads.InstanceInventory) {
+	controllerCh := wrappedClient.addEventChan(ssntp.NodeInventory)
+	go client.SendNodeInventoryEvent(instances)
+	err := wrappedClient.getEventChan(controllerCh, ssntp.NodeInventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nodeInventory returns an inventory matching the controller's view of
+// all the instances hosted by nodeID, less the instance skipID.
+func nodeInventory(t *testing.T, nodeID string, skipID string) []payloads.InstanceInventory {
+	instances, err := ctl.ds.GetAllInstances()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inventory := []payloads.InstanceInventory{}
+	for _, i := range instances {
+		if i.NodeID != nodeID || i.ID == skipID {
+			continue
+		}
+		inventory = append(inventory, payloads.InstanceInventory{
+			InstanceUUID: i.ID,
+			TenantUUID:   i.TenantID,
+			State:        i.State,
+		})
+	}
+
+	return inventory
+}
+
+func TestNodeInventoryUnknownInstance(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	unknownID := uuid.Generate().String()
+	serverCh := server.AddCmdChan(ssntp.DELETE)
+
+	inventory := nodeInventory(t, client.UUID, "")
+	inventory = append(inventory, payloads.InstanceInventory{
+		InstanceUUID: unknownID,
+		TenantUUID:   instances[0].TenantID,
+		State:        payloads.Running,
+	})
+	sendNodeInventory(client, t, inventory)
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.DELETE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != unknownID {
+		t.Fatalf("Expected unknown instance %s to be deleted, got %s",
+			unknownID, result.InstanceUUID)
+	}
+
+	i, err := ctl.ds.GetInstance(instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Running {
+		t.Errorf("Expected instance to be running, got %s", i.State)
+	}
+}
+
+func TestNodeInventoryLostInstance(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	serverCh := server.AddCmdChan(ssntp.DELETE)
+
+	inventory := nodeInventory(t, client.UUID, instances[0].ID)
+	inventory = append(inventory, payloads.InstanceInventory{
+		InstanceUUID: instances[0].ID,
+		TenantUUID:   instances[0].TenantID,
+		State:        payloads.Exited,
+		Lost:         true,
+	})
+	sendNodeInventory(client, t, inventory)
+
+	result, err := server.GetCmdChanResult(serverCh, ssntp.DELETE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != instances[0].ID {
+		t.Fatal("Did not get correct Instance ID")
+	}
+
+	serverCh = server.AddCmdChan(ssntp.START)
+
+	err = sendStopEvent(client, instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = server.GetCmdChanResult(serverCh, ssntp.START)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.InstanceUUID != instances[0].ID {
+		t.Fatal("Did not get correct Instance ID")
+	}
+}
+
+func TestNodeInventoryMissingInstance(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	sendStatsCmd(client, t)
+
+	sendNodeInventory(client, t, nodeInventory(t, client.UUID, instances[0].ID))
+
+	i, err := ctl.ds.GetInstance(instances[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.State != payloads.Exited || i.NodeID != "" {
+		t.Errorf("Expected instance to be stopped, got %s on %q", i.State, i.NodeID)
+	}
+}
+
 func TestStartFailure(t *testing.T) {
 	reason := payloads.FullCloud
 
@@ -1311,37 +2160,289 @@ func TestStorageConfig(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	tmpfile, err := ioutil.TempFile("", "test-image")
+	tmpfile, err := ioutil.TempFile("", "test-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	info, err := tmpfile.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a temporary in memory filesystem?
+	s := types.StorageResource{
+		ID:         "",
+		Bootable:   true,
+		Ephemeral:  false,
+		SourceType: types.ImageService,
+		SourceID:   info.Name(),
+	}
+
+	wls[0].Storage = []types.StorageResource{s}
+
+	id := uuid.Generate()
+
+	noVolumes := []storage.BlockDevice{}
+	_, err = newConfig(ctl, &wls[0], id.String(), tenant.ID, noVolumes, "test", "", nil, "", 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls[0].Storage = []types.StorageResource{}
+}
+
+func TestNewConfigTemplate(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wl := types.Workload{
+		ID:       uuid.Generate().String(),
+		TenantID: tenant.ID,
+		FWType:   string(payloads.EFI),
+		VMType:   payloads.Docker,
+		Template: true,
+		Config: `---
+#cloud-config
+hostname: {{.Name}}-{{.Index}}
+tenant: {{.TenantID}}
+ip: {{.IPAddress}}
+cnci: {{.CNCIIP}}
+role: {{.Metadata.role}}
+missing: "{{.Metadata.missing}}"
+...
+`,
+	}
+
+	id := uuid.Generate()
+	metadata := map[string]string{"role": "db"}
+	noVolumes := []storage.BlockDevice{}
+	config, err := newConfig(ctl, &wl, id.String(), tenant.ID, noVolumes, "web", "", nil, "", 3, metadata, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"hostname: web-3\n",
+		"tenant: " + tenant.ID + "\n",
+		"ip: " + config.ip + "\n",
+		"cnci: " + config.sc.Start.Networking.ConcentratorIP + "\n",
+		"role: db\n",
+		"missing: \"\"\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(config.config, e) {
+			t.Errorf("Rendered config does not contain %q:\n%s", e, config.config)
+		}
+	}
+}
+
+func TestCreateWorkloadConfigTemplate(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badConfigs := []string{
+		"hostname: {{.Name",
+		"hostname: {{.NoSuchField}}",
+		"hostname: {{nosuchfunc .Name}}",
+	}
+	for _, config := range badConfigs {
+		wl := types.Workload{
+			TenantID:  tenant.ID,
+			VMType:    payloads.Docker,
+			ImageName: "ubuntu",
+			Config:    config,
+			Template:  true,
+		}
+		_, err = ctl.CreateWorkload(wl)
+		if _, ok := err.(*types.ConfigTemplateError); !ok {
+			t.Errorf("Expected template error for %q, got %v", config, err)
+		}
+	}
+
+	wl := types.Workload{
+		TenantID:  tenant.ID,
+		VMType:    payloads.Docker,
+		ImageName: "ubuntu",
+		Config:    "hostname: {{.Name}}-{{.Index}}",
+		Template:  true,
+	}
+	wl, err = ctl.CreateWorkload(wl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.DeleteWorkload(tenant.ID, wl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// configs of workloads that are not templated are not parsed.
+	wl = types.Workload{
+		TenantID:  tenant.ID,
+		VMType:    payloads.Docker,
+		ImageName: "ubuntu",
+		Config:    "hostname: {{ ds.meta_data.hostname",
+	}
+	wl, err = ctl.CreateWorkload(wl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ctl.DeleteWorkload(tenant.ID, wl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewConfigNoTemplate(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wl := types.Workload{
+		ID:       uuid.Generate().String(),
+		TenantID: tenant.ID,
+		FWType:   string(payloads.EFI),
+		VMType:   payloads.Docker,
+		Config: `---
+#cloud-config
+hostname: {{ ds.meta_data.hostname }}
+...
+`,
+	}
+
+	id := uuid.Generate()
+	noVolumes := []storage.BlockDevice{}
+	config, err := newConfig(ctl, &wl, id.String(), tenant.ID, noVolumes, "web", "", nil, "", 3, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(config.config, "hostname: {{ ds.meta_data.hostname }}\n") {
+		t.Errorf("Config of untemplated workload modified:\n%s", config.config)
+	}
+}
+
+func TestNewConfigReleaseIPs(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each network has room for a single instance and is served
+	// by a fake CNCI.
+	var networks []string
+	for _, cidr := range []string{"10.30.0.0/24", "10.31.0.0/24"} {
+		start := strings.Replace(cidr, "0/24", "10", 1)
+		n, err := ctl.CreateNetwork(tenant.ID, api.RequestedNetwork{
+			CIDR:            cidr,
+			AllocationStart: start,
+			AllocationEnd:   start,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		networks = append(networks, n.ID)
+
+		_, err = addFakeSubnetCNCI(tenant, cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tenant.CNCIctrl, err = newCNCIManager(ctl, tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wl := types.Workload{
+		ID:       uuid.Generate().String(),
+		TenantID: tenant.ID,
+		FWType:   string(payloads.EFI),
+		VMType:   payloads.Docker,
+		Storage:  []types.StorageResource{{SourceType: "unknown"}},
+	}
+
+	noVolumes := []storage.BlockDevice{}
+	_, err = newConfig(ctl, &wl, uuid.Generate().String(), tenant.ID, noVolumes,
+		"web", networks[0], networks[1:], "", 0, nil, nil)
+	if err == nil {
+		t.Fatal("Expected config with unsupported storage to fail")
+	}
+
+	wl.Storage = nil
+	config, err := newConfig(ctl, &wl, uuid.Generate().String(), tenant.ID,
+		noVolumes, "web", networks[0], networks[1:], "", 0, nil, nil)
+	if err != nil {
+		t.Fatalf("Addresses of failed config not released: %v", err)
+	}
+
+	if config.ip != "10.30.0.10" || len(config.nics) != 1 ||
+		config.nics[0].IPAddress != "10.31.0.10" {
+		t.Fatalf("Unexpected addresses %s %v", config.ip, config.nics)
+	}
+}
+
+func TestNewConfigReleaseFixedIP(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retained := types.Instance{
+		ID:        uuid.Generate().String(),
+		TenantID:  tenant.ID,
+		IPAddress: "172.16.0.20",
+		RetainIP:  true,
+	}
+
+	_, _, err = ctl.ds.ClaimTenantIP(tenant.ID, retained.IPAddress)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer os.Remove(tmpfile.Name())
 
-	info, err := tmpfile.Stat()
+	err = ctl.ds.ReleaseInstanceIP(&retained)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	// a temporary in memory filesystem?
-	s := types.StorageResource{
-		ID:         "",
-		Bootable:   true,
-		Ephemeral:  false,
-		SourceType: types.ImageService,
-		SourceID:   info.Name(),
+	wl := types.Workload{
+		ID:       uuid.Generate().String(),
+		TenantID: tenant.ID,
+		FWType:   string(payloads.EFI),
+		VMType:   payloads.Docker,
+		Storage:  []types.StorageResource{{SourceType: "unknown"}},
 	}
 
-	wls[0].Storage = []types.StorageResource{s}
-
-	id := uuid.Generate()
-
 	noVolumes := []storage.BlockDevice{}
-	_, err = newConfig(ctl, &wls[0], id.String(), tenant.ID, noVolumes, "test")
+	for _, ip := range []string{"172.16.0.10", retained.IPAddress} {
+		_, err = newConfig(ctl, &wl, uuid.Generate().String(), tenant.ID,
+			noVolumes, "web", "", nil, ip, 0, nil, nil)
+		if err == nil {
+			t.Fatal("Expected config with unsupported storage to fail")
+		}
+	}
+
+	reservedIPs, err := ctl.ds.GetReservedIPs(tenant.ID)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	wls[0].Storage = []types.StorageResource{}
+	if len(reservedIPs) != 1 || reservedIPs[0].IPAddress != retained.IPAddress {
+		t.Fatalf("Reservation of %s not kept: %v", retained.IPAddress, reservedIPs)
+	}
+
+	_, reserved, err := ctl.ds.ClaimTenantIP(tenant.ID, "172.16.0.10")
+	if err != nil || reserved {
+		t.Fatalf("Fixed address of failed config not released: %v", err)
+	}
 }
 
 func createTestVolume(tenantID string, size int, t *testing.T) string {
@@ -1899,6 +3000,67 @@ func TestMapAddressNoPool(t *testing.T) {
 	}
 }
 
+func TestCreateLoadBalancer(t *testing.T) {
+	var reason payloads.StartFailureReason
+
+	client, instances := testStartWorkload(t, 1, false, reason)
+	defer client.Shutdown()
+
+	ips := []string{"10.10.0.3"}
+	poolName := "testlb"
+
+	testAddPool(t, poolName, nil, ips)
+
+	req := api.RequestedLoadBalancer{
+		PoolName: &poolName,
+		Port:     80,
+		Protocol: payloads.LoadBalancerHTTP,
+		Members: []api.RequestedLoadBalancerMember{
+			{InstanceID: instances[0].ID, Port: 8080},
+		},
+	}
+
+	lb, err := ctl.CreateLoadBalancer(instances[0].TenantID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lb.VIP != ips[0] || lb.HealthCheck.Path != "/" {
+		t.Fatalf("Unexpected load balancer %v", lb)
+	}
+
+	if len(lb.Members) != 1 || lb.Members[0].IPAddress != instances[0].IPAddress {
+		t.Fatalf("Unexpected load balancer members %v", lb.Members)
+	}
+
+	pools, err := ctl.ListPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pool := range pools {
+		if pool.Name == poolName && pool.Free != 0 {
+			t.Fatal("Pool Free not decremented")
+		}
+	}
+
+	err = ctl.DeleteLoadBalancer(instances[0].TenantID, lb.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pools, err = ctl.ListPools()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pool := range pools {
+		if pool.Name == poolName && pool.Free != 1 {
+			t.Fatal("Pool Free not incremented")
+		}
+	}
+}
+
 func TestListTenants(t *testing.T) {
 	tenants, err := ctl.ds.GetAllTenants()
 	if err != nil {
@@ -2001,6 +3163,254 @@ func TestUpdateTenant(t *testing.T) {
 	}
 }
 
+func TestUpdateTenantDNS(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		patch string
+		err   error
+	}{
+		{`{"dns_domain": "bad_domain"}`, types.ErrBadRequest},
+		{`{"dns_forwarders": ["not an ip"]}`, types.ErrBadRequest},
+		{`{"dns_domain": "tenant.ciao", "dns_forwarders": ["8.8.8.8"]}`, nil},
+	}
+
+	for _, test := range tests {
+		err = ctl.PatchTenant(tenant.ID, []byte(test.patch))
+		if err != test.err {
+			t.Fatalf("expected %v for %s, got %v", test.err, test.patch, err)
+		}
+	}
+
+	config, err := ctl.ShowTenant(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.DNSDomain != "tenant.ciao" ||
+		len(config.DNSForwarders) != 1 || config.DNSForwarders[0] != "8.8.8.8" {
+		t.Fatalf("Tenant DNS update not successful: %+v", config)
+	}
+}
+
+func TestDNSRecords(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ctl.ds.GetWorkloads(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := types.WorkloadRequest{
+		WorkloadID: wls[0].ID,
+		TenantID:   tenant.ID,
+		Instances:  2,
+		Name:       "web",
+	}
+
+	instances, err := ctl.startWorkload(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ctl.dnsRecords(tenant.ID, instances[0].Subnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	for i, r := range records {
+		name := fmt.Sprintf("web-%d", i)
+		if r.Name != name {
+			t.Fatalf("expected record %s, got %s", name, r.Name)
+		}
+
+		for _, instance := range instances {
+			if instance.Name == r.Name && instance.IPAddress != r.IP {
+				t.Fatalf("expected IP %s for %s, got %s", instance.IPAddress, r.Name, r.IP)
+			}
+		}
+	}
+}
+
+func TestMetadataRecords(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ctl.ds.GetWorkloads(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := types.WorkloadRequest{
+		WorkloadID: wls[0].ID,
+		TenantID:   tenant.ID,
+		Instances:  1,
+		Name:       "db",
+		Metadata:   map[string]string{"role": "db"},
+	}
+
+	instances, err := ctl.startWorkload(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.UpdateServerMetadata(tenant.ID, instances[0].ID, map[string]string{"": "db"})
+	if err != types.ErrBadRequest {
+		t.Fatalf("expected %v for an empty key, got %v", types.ErrBadRequest, err)
+	}
+
+	_, err = ctl.UpdateServerMetadata(tenant.ID, instances[0].ID, map[string]string{"role": "replica"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subnet, err := canonicalSubnet(instances[0].Subnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ctl.metadataRecords(tenant.ID, subnet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	md := records[0]
+	if md.InstanceUUID != instances[0].ID || md.Hostname != "db" ||
+		md.Metadata["role"] != "replica" {
+		t.Fatalf("unexpected metadata %+v", md)
+	}
+
+	if len(md.NICs) != 1 || md.NICs[0].IP != instances[0].IPAddress ||
+		md.NICs[0].Subnet != subnet {
+		t.Fatalf("unexpected NICs %+v", md.NICs)
+	}
+}
+
+func TestConfigPublicKeys(t *testing.T) {
+	config := `---
+#cloud-config
+ssh_authorized_keys:
+  - ssh-rsa AAAA1 root@ciao
+users:
+  - default
+  - name: demouser
+    ssh-authorized-keys:
+      - ssh-rsa AAAA2 demo@ciao
+...
+`
+
+	keys := configPublicKeys(config)
+	if len(keys) != 2 || keys[0] != "ssh-rsa AAAA1 root@ciao" ||
+		keys[1] != "ssh-rsa AAAA2 demo@ciao" {
+		t.Fatalf("unexpected public keys %v", keys)
+	}
+
+	if keys := configPublicKeys("not: [yaml"); len(keys) != 0 {
+		t.Fatalf("unexpected public keys %v", keys)
+	}
+}
+
+func TestNetworkPeering(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := ctl.CreateNetwork(tenant.ID, api.RequestedNetwork{CIDR: "10.20.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peer, err := ctl.CreateNetwork(peerTenant.ID, api.RequestedNetwork{CIDR: "10.21.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlap, err := ctl.CreateNetwork(peerTenant.ID, api.RequestedNetwork{CIDR: "10.20.0.0/25"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := api.RequestedNetworkPeering{
+		NetworkID:     n.ID,
+		PeerTenantID:  peerTenant.ID,
+		PeerNetworkID: overlap.ID,
+	}
+	_, err = ctl.CreateNetworkPeering(tenant.ID, req)
+	if err != types.ErrPeeringOverlap {
+		t.Fatalf("expected %v for overlapping networks, got %v", types.ErrPeeringOverlap, err)
+	}
+
+	req.PeerTenantID = tenant.ID
+	_, err = ctl.CreateNetworkPeering(tenant.ID, req)
+	if err != types.ErrBadRequest {
+		t.Fatalf("expected %v for a peering with the same tenant, got %v", types.ErrBadRequest, err)
+	}
+
+	req.PeerTenantID = peerTenant.ID
+	req.PeerNetworkID = peer.ID
+	p, err := ctl.CreateNetworkPeering(tenant.ID, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Status != types.PeeringPending || p.CIDR != n.CIDR || p.PeerCIDR != peer.CIDR {
+		t.Fatalf("unexpected peering %v", p)
+	}
+
+	peerings, err := ctl.ListNetworkPeerings(peerTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peerings) != 1 || peerings[0].ID != p.ID {
+		t.Fatalf("expected peering %s to be listed for the peer tenant, got %v", p.ID, peerings)
+	}
+
+	p, err = ctl.ApproveNetworkPeering(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = ctl.ShowNetworkPeering(peerTenant.ID, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != types.PeeringActive {
+		t.Fatalf("expected peering %s to be active, got %s", p.ID, p.Status)
+	}
+
+	err = ctl.DeleteNetwork(peerTenant.ID, peer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ctl.ShowNetworkPeering(tenant.ID, p.ID)
+	if err != types.ErrNetworkPeeringNotFound {
+		t.Fatalf("expected peering %s to be removed with its network, got %v", p.ID, err)
+	}
+}
+
 func TestCreateTenant(t *testing.T) {
 	config := types.TenantConfig{
 		Name:       "createTenant",
@@ -2067,6 +3477,8 @@ func TestMain(m *testing.M) {
 	}
 	fakeImage := fmt.Sprintf("%s/73a86d7e-93c0-480e-9c41-ab42f69b7799", dir)
 
+	ctl.backupTarget = &storage.DirTarget{Path: filepath.Join(dir, "backups")}
+
 	f, err := os.Create(fakeImage)
 	if err != nil {
 		os.RemoveAll(dir)
@@ -2101,6 +3513,11 @@ func TestMain(m *testing.M) {
 	}
 	ctl.client = wrappedClient
 
+	ctl.consoleTLS, err = ssntp.PeerTLSConfig(config, false, ssntp.AGENT|ssntp.NETAGENT)
+	if err != nil {
+		os.Exit(1)
+	}
+
 	_, _ = addComputeTestTenant()
 
 	s, err := ctl.createCiaoServer()
@@ -2123,3 +3540,111 @@ func TestMain(m *testing.M) {
 
 	os.Exit(code)
 }
+
+func TestNewTenantNetwork(t *testing.T) {
+	tests := []struct {
+		req   api.RequestedNetwork
+		valid bool
+		n     types.TenantNetwork
+	}{
+		{
+			api.RequestedNetwork{CIDR: "10.10.0.5/24"},
+			true,
+			types.TenantNetwork{
+				CIDR:            "10.10.0.0/24",
+				Gateway:         "10.10.0.1",
+				AllocationStart: "10.10.0.2",
+				AllocationEnd:   "10.10.0.254",
+			},
+		},
+		{
+			api.RequestedNetwork{
+				CIDR:            "192.168.8.0/22",
+				Gateway:         "192.168.11.254",
+				AllocationStart: "192.168.9.0",
+				AllocationEnd:   "192.168.9.255",
+			},
+			true,
+			types.TenantNetwork{
+				CIDR:            "192.168.8.0/22",
+				Gateway:         "192.168.11.254",
+				AllocationStart: "192.168.9.0",
+				AllocationEnd:   "192.168.9.255",
+			},
+		},
+		{
+			api.RequestedNetwork{
+				CIDR:     "10.10.0.0/24",
+				IPv6CIDR: "fd12:3456:789a:1::5/64",
+				IPv6Mode: types.IPv6DHCP,
+			},
+			true,
+			types.TenantNetwork{
+				CIDR:            "10.10.0.0/24",
+				Gateway:         "10.10.0.1",
+				AllocationStart: "10.10.0.2",
+				AllocationEnd:   "10.10.0.254",
+				IPv6CIDR:        "fd12:3456:789a:1::/64",
+				IPv6Gateway:     "fd12:3456:789a:1::1",
+				IPv6Mode:        types.IPv6DHCP,
+			},
+		},
+		{api.RequestedNetwork{CIDR: "10.10.0.0"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", IPv6CIDR: "fd00::/48"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", IPv6CIDR: "10.20.0.0/24"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", IPv6Mode: "dhcp"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "fd00::/64"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.0.0.0/8"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/31"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", Gateway: "10.10.1.1"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", Gateway: "10.10.0.255"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", AllocationStart: "10.10.0.0"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", AllocationStart: "10.10.0.100", AllocationEnd: "10.10.0.10"}, false, types.TenantNetwork{}},
+		{api.RequestedNetwork{CIDR: "10.10.0.0/24", AllocationStart: "10.10.0.1", AllocationEnd: "10.10.0.1"}, false, types.TenantNetwork{}},
+	}
+
+	for _, tt := range tests {
+		n, err := newTenantNetwork("tenant", tt.req)
+		if !tt.valid {
+			if err != types.ErrBadRequest {
+				t.Errorf("Expected %v to be rejected", tt.req)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %v", tt.req, err)
+			continue
+		}
+
+		if n.ID == "" || n.TenantID != "tenant" || n.CIDR != tt.n.CIDR ||
+			n.Gateway != tt.n.Gateway || n.AllocationStart != tt.n.AllocationStart ||
+			n.AllocationEnd != tt.n.AllocationEnd || n.IPv6CIDR != tt.n.IPv6CIDR ||
+			n.IPv6Gateway != tt.n.IPv6Gateway || n.IPv6Mode != tt.n.IPv6Mode {
+			t.Errorf("Unexpected network %v for %v", n, tt.req)
+		}
+	}
+}
+
+func TestNewTenantNetworkULA(t *testing.T) {
+	req := api.RequestedNetwork{CIDR: "10.10.0.0/24", IPv6Mode: types.IPv6SLAAC}
+	n, err := newTenantNetwork("tenant", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ip, prefix, err := net.ParseCIDR(n.IPv6CIDR)
+	if err != nil {
+		t.Fatalf("Invalid IPv6 prefix %s: %v", n.IPv6CIDR, err)
+	}
+
+	ones, _ := prefix.Mask.Size()
+	if ones != 64 || ip[0] != 0xfd {
+		t.Errorf("Expected a /64 ULA prefix, got %s", n.IPv6CIDR)
+	}
+
+	gateway := net.ParseIP(n.IPv6Gateway)
+	if gateway == nil || !prefix.Contains(gateway) || n.IPv6Mode != types.IPv6SLAAC {
+		t.Errorf("Unexpected IPv6 configuration %v", n)
+	}
+}
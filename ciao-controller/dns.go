@@ -0,0 +1,205 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+)
+
+var dnsLabelRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// validDNSLabel returns true if name can be used as a single DNS label.
+func validDNSLabel(name string) bool {
+	return dnsLabelRegexp.MatchString(name)
+}
+
+// validateDNSConfig checks the DNS domain and the upstream DNS servers
+// of a tenant configuration.
+func validateDNSConfig(config types.TenantConfig) error {
+	if config.DNSDomain != "" {
+		if len(config.DNSDomain) > 253 {
+			return types.ErrBadRequest
+		}
+
+		for _, label := range strings.Split(config.DNSDomain, ".") {
+			if !validDNSLabel(label) {
+				return types.ErrBadRequest
+			}
+		}
+	}
+
+	for _, f := range config.DNSForwarders {
+		if net.ParseIP(f) == nil {
+			return types.ErrBadRequest
+		}
+	}
+
+	return nil
+}
+
+// instanceSubnets returns the address of instance i on each of the
+// subnets it is attached to, indexed by subnet.
+func instanceSubnets(i *types.Instance, networks map[string]string) map[string]string {
+	addrs := make(map[string]string)
+
+	if subnet, err := canonicalSubnet(i.Subnet); err == nil {
+		addrs[subnet] = i.IPAddress
+	}
+
+	for _, nic := range i.AdditionalNICs {
+		subnet, err := canonicalSubnet(networks[nic.NetworkID])
+		if err != nil {
+			continue
+		}
+		addrs[subnet] = nic.IPAddress
+	}
+
+	return addrs
+}
+
+// tenantNetworkSubnets maps the IDs of the networks of a tenant to
+// their CIDRs.
+func (c *controller) tenantNetworkSubnets(tenantID string) (map[string]string, error) {
+	networks, err := c.ds.GetTenantNetworks(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := make(map[string]string)
+	for _, n := range networks {
+		subnets[n.ID] = n.CIDR
+	}
+
+	return subnets, nil
+}
+
+// dnsRecords returns the names of all the instances of a tenant that are
+// attached to subnet.  Instances without a name, or whose name is not a
+// valid DNS label, are not registered.
+func (c *controller) dnsRecords(tenantID string, subnet string) ([]payloads.DNSRecord, error) {
+	instances, err := c.ds.GetAllInstancesFromTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := c.tenantNetworkSubnets(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []payloads.DNSRecord
+	for _, i := range instances {
+		if i.CNCI || !validDNSLabel(i.Name) {
+			continue
+		}
+
+		ip, ok := instanceSubnets(i, networks)[subnet]
+		if !ok || ip == "" {
+			continue
+		}
+
+		records = append(records, payloads.DNSRecord{
+			Name: i.Name,
+			IP:   ip,
+		})
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Name < records[j].Name
+	})
+
+	return records, nil
+}
+
+// updateSubnetDNS sends the complete set of instance names registered on
+// a tenant subnet to the CNCI serving that subnet.
+func (c *controller) updateSubnetDNS(t *types.Tenant, subnet string) error {
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
+	cnci, err := t.CNCIctrl.GetSubnetCNCI(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find CNCI for subnet %s", subnet)
+	}
+
+	records, err := c.dnsRecords(t.ID, subnet)
+	if err != nil {
+		return errors.Wrapf(err, "unable to retrieve DNS records for subnet %s", subnet)
+	}
+
+	return c.client.updateDNS(*t, cnci.ID, subnet, records)
+}
+
+// updateInstanceDNS refreshes the DNS records of each of the subnets
+// instance i is attached to.  It is called once the instance has been
+// added to or removed from the datastore.
+func (c *controller) updateInstanceDNS(i *types.Instance) {
+	if i.CNCI || !validDNSLabel(i.Name) {
+		return
+	}
+
+	t, err := c.ds.GetTenant(i.TenantID)
+	if err != nil || t == nil {
+		glog.Warningf("Unable to update DNS for instance %s: %v", i.ID, err)
+		return
+	}
+
+	networks, err := c.tenantNetworkSubnets(i.TenantID)
+	if err != nil {
+		glog.Warningf("Unable to update DNS for instance %s: %v", i.ID, err)
+		return
+	}
+
+	for subnet := range instanceSubnets(i, networks) {
+		err = c.updateSubnetDNS(t, subnet)
+		if err != nil {
+			glog.Warningf("Unable to update DNS for instance %s: %v", i.ID, err)
+		}
+	}
+}
+
+// updateTenantDNS refreshes the DNS configuration of all the subnets of a
+// tenant served by a CNCI.  It is called when the DNS domain or the
+// upstream DNS servers of the tenant are changed.
+func (c *controller) updateTenantDNS(tenantID string) {
+	t, err := c.ds.GetTenant(tenantID)
+	if err != nil || t == nil {
+		glog.Warningf("Unable to update DNS for tenant %s: %v", tenantID, err)
+		return
+	}
+
+	cncis, err := c.ds.GetTenantCNCIs(tenantID)
+	if err != nil {
+		glog.Warningf("Unable to update DNS for tenant %s: %v", tenantID, err)
+		return
+	}
+
+	for _, cnci := range cncis {
+		err = c.updateSubnetDNS(t, cnci.Subnet)
+		if err != nil {
+			glog.Warningf("Unable to update DNS for tenant %s: %v", tenantID, err)
+		}
+	}
+}
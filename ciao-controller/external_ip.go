@@ -220,8 +220,12 @@ func (c *controller) MapAddress(tenantID string, poolName *string, instanceID st
 				break
 			}
 		} else if pool.Free > 0 {
+			// the free addresses of a pool may all be of a family
+			// the instance has no address of, try the next pool.
 			m, err = c.ds.MapExternalIP(pool.ID, instanceID)
-			break
+			if err != types.ErrPoolEmpty {
+				break
+			}
 		}
 	}
 
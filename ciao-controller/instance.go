@@ -39,6 +39,15 @@ type config struct {
 	cnci   bool
 	mac    string
 	ip     string
+	nics   []types.InstanceNIC
+
+	// reservedIP is true if the address of the instance was
+	// previously reserved by the tenant.
+	reservedIP bool
+
+	// userData is the rendered cloud-init config of the instance,
+	// also served by the metadata service of the CNCI.
+	userData string
 }
 
 type instance struct {
@@ -64,7 +73,9 @@ func isCNCIWorkload(workload *types.Workload) bool {
 }
 
 func newInstance(ctl *controller, tenantID string, workload *types.Workload,
-	volumes []storage.BlockDevice, name string, subnet string) (*instance, error) {
+	volumes []storage.BlockDevice, name string, subnet string, networkID string,
+	additionalNetworks []string, ipAddress string, retainIP bool, index int,
+	metadata map[string]string, excludedNodes []string) (*instance, error) {
 	id := uuid.Generate()
 
 	if name != "" {
@@ -78,24 +89,33 @@ func newInstance(ctl *controller, tenantID string, workload *types.Workload,
 		}
 	}
 
-	config, err := newConfig(ctl, workload, id.String(), tenantID, volumes, name)
+	config, err := newConfig(ctl, workload, id.String(), tenantID, volumes, name,
+		networkID, additionalNetworks, ipAddress, index, metadata, excludedNodes)
 	if err != nil {
 		return nil, err
 	}
 
 	newInstance := types.Instance{
-		TenantID:    tenantID,
-		WorkloadID:  workload.ID,
-		State:       payloads.Pending,
-		ID:          id.String(),
-		CNCI:        config.cnci,
-		IPAddress:   config.ip,
-		VnicUUID:    config.sc.Start.Networking.VnicUUID,
-		Subnet:      config.sc.Start.Networking.Subnet,
-		MACAddress:  config.mac,
-		CreateTime:  time.Now(),
-		Name:        name,
-		StateChange: sync.NewCond(&sync.Mutex{}),
+		TenantID:       tenantID,
+		WorkloadID:     workload.ID,
+		State:          payloads.Pending,
+		ID:             id.String(),
+		CNCI:           config.cnci,
+		IPAddress:      config.ip,
+		VnicUUID:       config.sc.Start.Networking.VnicUUID,
+		Subnet:         config.sc.Start.Networking.Subnet,
+		NetworkID:      networkID,
+		AdditionalNICs: config.nics,
+		RetainIP:       retainIP,
+		MACAddress:     config.mac,
+		CreateTime:     time.Now(),
+		Name:           name,
+		StateChange:    sync.NewCond(&sync.Mutex{}),
+	}
+
+	if !config.cnci {
+		newInstance.Metadata = metadata
+		newInstance.UserData = config.userData
 	}
 
 	if subnet != "" {
@@ -144,7 +164,10 @@ func (i *instance) Clean() error {
 		return nil
 	}
 
-	i.ctl.ds.ReleaseTenantIP(i.TenantID, i.IPAddress)
+	// the instance was never created so its address is only kept
+	// if it was taken from a reservation.
+	i.RetainIP = i.newConfig.reservedIP
+	i.ctl.ds.ReleaseInstanceIP(i.Instance)
 
 	wl, err := i.ctl.ds.GetWorkload(i.TenantID, i.WorkloadID)
 	if err != nil {
@@ -328,29 +351,59 @@ func controllerStorageResourceFromPayload(volume payloads.StorageResource) (s ty
 	return
 }
 
-func networkConfig(ctl *controller, tenant *types.Tenant, networking *payloads.NetworkResources, cnci bool) error {
+// instanceIP assigns the address of an instance from the tenant network
+// networkID, or from the default tenant address space if networkID is
+// empty.  If fixedIP is not empty that address is assigned, otherwise the
+// next free one.  reserved is true if fixedIP was reserved by the tenant.
+func instanceIP(ctl *controller, tenantID string, networkID string, fixedIP string) (ip net.IP, reserved bool, err error) {
+	if fixedIP != "" {
+		if networkID != "" {
+			return ctl.ds.ClaimNetworkIP(tenantID, networkID, fixedIP)
+		}
+		return ctl.ds.ClaimTenantIP(tenantID, fixedIP)
+	}
+
+	if networkID != "" {
+		ip, err = ctl.ds.AllocateNetworkIP(tenantID, networkID)
+	} else {
+		ip, err = ctl.ds.AllocateTenantIP(tenantID)
+	}
+
+	return ip, false, err
+}
+
+// networkConfig sets up the first NIC of an instance and reports whether
+// its address was previously reserved by the tenant.
+func networkConfig(ctl *controller, tenant *types.Tenant, networking *payloads.NetworkResources, cnci bool, networkID string, fixedIP string) (bool, error) {
 	networking.VnicUUID = uuid.Generate().String()
 
 	if cnci {
 		hwaddr, err := utils.NewHardwareAddr()
 		if err != nil {
-			return err
+			return false, err
 		}
 
 		networking.VnicMAC = hwaddr.String()
-		return nil
+		return false, nil
 	}
 
-	ipAddress, err := ctl.ds.AllocateTenantIP(tenant.ID)
+	ipAddress, reserved, err := instanceIP(ctl, tenant.ID, networkID, fixedIP)
 	if err != nil {
 		fmt.Println("Unable to allocate IP address: ", err)
-		return err
+		return false, err
+	}
+
+	// set before anything else can fail so that newConfig knows
+	// which address to release.
+	networking.PrivateIP = ipAddress.String()
+
+	if networkID != "" {
+		return reserved, tenantNetworkConfig(ctl, tenant, networking, networkID, ipAddress)
 	}
 
 	networking.VnicMAC = utils.NewTenantHardwareAddr(ipAddress).String()
 
 	// send in CIDR notation?
-	networking.PrivateIP = ipAddress.String()
 	mask := net.IPv4Mask(255, 255, 255, 0)
 	ipnet := net.IPNet{
 		IP:   ipAddress.Mask(mask),
@@ -358,6 +411,64 @@ func networkConfig(ctl *controller, tenant *types.Tenant, networking *payloads.N
 	}
 	networking.Subnet = ipnet.String()
 
+	return reserved, concentratorConfig(tenant, networking)
+}
+
+// tenantNetworkConfig sets up a NIC with an address assigned from a tenant
+// defined network, along with its subnet, gateway and DHCP range.
+func tenantNetworkConfig(ctl *controller, tenant *types.Tenant, networking *payloads.NetworkResources, networkID string, ipAddress net.IP) error {
+	n, err := ctl.ds.GetTenantNetwork(tenant.ID, networkID)
+	if err != nil {
+		return err
+	}
+
+	networking.VnicMAC = utils.NewTenantHardwareAddr(ipAddress).String()
+	networking.PrivateIP = ipAddress.String()
+	setTenantNetwork(networking, n)
+
+	return concentratorConfig(tenant, networking)
+}
+
+// additionalNetworkConfig allocates an address on each of the tenant
+// networks to which the NICs of an instance, other than the first, are
+// attached.  If a NIC cannot be set up, the addresses already allocated
+// are released.
+func additionalNetworkConfig(ctl *controller, tenant *types.Tenant, networkIDs []string) ([]payloads.NetworkResources, []types.InstanceNIC, error) {
+	var networking []payloads.NetworkResources
+	var nics []types.InstanceNIC
+
+	for _, networkID := range networkIDs {
+		n := payloads.NetworkResources{
+			VnicUUID: uuid.Generate().String(),
+		}
+
+		ipAddress, err := ctl.ds.AllocateNetworkIP(tenant.ID, networkID)
+		if err == nil {
+			err = tenantNetworkConfig(ctl, tenant, &n, networkID, ipAddress)
+			if err != nil {
+				_ = ctl.ds.ReleaseNetworkIP(tenant.ID, networkID, ipAddress.String())
+			}
+		}
+		if err != nil {
+			for _, nic := range nics {
+				_ = ctl.ds.ReleaseNetworkIP(tenant.ID, nic.NetworkID, nic.IPAddress)
+			}
+			return nil, nil, err
+		}
+
+		networking = append(networking, n)
+		nics = append(nics, types.InstanceNIC{
+			NetworkID:  networkID,
+			MACAddress: n.VnicMAC,
+			VnicUUID:   n.VnicUUID,
+			IPAddress:  n.PrivateIP,
+		})
+	}
+
+	return networking, nics, nil
+}
+
+func concentratorConfig(tenant *types.Tenant, networking *payloads.NetworkResources) error {
 	cnciInstance, err := tenant.CNCIctrl.GetSubnetCNCI(networking.Subnet)
 	if err != nil {
 		return err
@@ -414,10 +525,12 @@ func storageConfig(ctl *controller, tenant *types.Tenant, instanceID string, vol
 }
 
 func newConfig(ctl *controller, wl *types.Workload, instanceID string, tenantID string,
-	volumes []storage.BlockDevice, name string) (config, error) {
+	volumes []storage.BlockDevice, name string, networkID string,
+	additionalNetworks []string, fixedIP string, index int,
+	metadata map[string]string, excludedNodes []string) (config config, err error) {
 	var metaData userData
-	var config config
 	var networking payloads.NetworkResources
+	var additionalNetworking []payloads.NetworkResources
 	var storage []payloads.StorageResource
 
 	baseConfig := wl.Config
@@ -431,22 +544,67 @@ func newConfig(ctl *controller, wl *types.Workload, instanceID string, tenantID
 		fmt.Println("unable to get tenant")
 	}
 
-	err = networkConfig(ctl, tenant, &networking, config.cnci)
+	// the addresses assigned to the instance are released if its
+	// config cannot be completed, except that an address taken from a
+	// reservation is reserved again.
+	defer func() {
+		if err == nil || networking.PrivateIP == "" {
+			return
+		}
+
+		_ = ctl.ds.ReleaseInstanceIP(&types.Instance{
+			ID:             instanceID,
+			TenantID:       tenantID,
+			NetworkID:      networkID,
+			IPAddress:      networking.PrivateIP,
+			AdditionalNICs: config.nics,
+			RetainIP:       config.reservedIP,
+		})
+	}()
+
+	config.reservedIP, err = networkConfig(ctl, tenant, &networking, config.cnci,
+		networkID, fixedIP)
 	if err != nil {
 		return config, err
 	}
 
+	if !config.cnci {
+		additionalNetworking, config.nics, err = additionalNetworkConfig(ctl,
+			tenant, additionalNetworks)
+		if err != nil {
+			return config, err
+		}
+	}
+
 	metaData.Hostname = instanceID
 	if name != "" {
 		metaData.Hostname = name
 	}
 
+	// only workloads that ask for it have their config rendered, as
+	// cloud-init configs may contain {{ of their own.
+	if wl.Template && !config.cnci {
+		baseConfig, err = renderConfig(baseConfig, &configTemplateData{
+			Name:      metaData.Hostname,
+			UUID:      instanceID,
+			Index:     index,
+			TenantID:  tenantID,
+			IPAddress: networking.PrivateIP,
+			CNCIIP:    networking.ConcentratorIP,
+			Metadata:  metadata,
+		})
+		if err != nil {
+			return config, err
+		}
+	}
+
 	storage, err = storageConfig(ctl, tenant, instanceID, volumes)
 	if err != nil {
 		return config, err
 	}
 
 	config.ip = networking.PrivateIP
+	config.userData = baseConfig
 
 	// handle storage resources in workload definition
 	for i := range wl.Storage {
@@ -461,14 +619,16 @@ func newConfig(ctl *controller, wl *types.Workload, instanceID string, tenantID
 	// template datastore.  Estimated resources can be blank
 	// for now because we don't support it yet.
 	startCmd := payloads.StartCmd{
-		TenantUUID:          tenantID,
-		InstanceUUID:        instanceID,
-		FWType:              payloads.Firmware(fwType),
-		VMType:              wl.VMType,
-		InstancePersistence: payloads.Host,
-		RequestedResources:  defaults,
-		Networking:          networking,
-		Storage:             storage,
+		TenantUUID:           tenantID,
+		InstanceUUID:         instanceID,
+		FWType:               payloads.Firmware(fwType),
+		VMType:               wl.VMType,
+		InstancePersistence:  payloads.Host,
+		RequestedResources:   defaults,
+		Networking:           networking,
+		AdditionalNetworking: additionalNetworking,
+		Storage:              storage,
+		ExcludedNodes:        excludedNodes,
 	}
 
 	if wl.VMType == payloads.Docker {
@@ -20,6 +20,7 @@
 package datastore
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -30,6 +31,7 @@ import (
 
 	"github.com/ciao-project/ciao/ciao-controller/api"
 	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/ciao-controller/utils"
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
 	"github.com/ciao-project/ciao/uuid"
@@ -61,12 +63,21 @@ const (
 
 type tenant struct {
 	types.Tenant
-	network   map[int]map[int]bool
-	subnets   []int
-	instances map[string]*types.Instance
-	devices   map[string]types.Volume
-	workloads []types.Workload
-	images    []string
+	network     map[int]map[int]bool
+	subnets     []int
+	networks    map[string]*tenantNetwork
+	reservedIPs map[string]types.ReservedIP
+	instances   map[string]*types.Instance
+	devices     map[string]types.Volume
+	workloads   []types.Workload
+	images      []string
+}
+
+// tenantNetwork caches a tenant defined network along with the addresses
+// allocated from it.
+type tenantNetwork struct {
+	types.TenantNetwork
+	addresses map[string]bool
 }
 
 type node struct {
@@ -135,6 +146,11 @@ type persistentStore interface {
 	deleteMappedIP(ID string) error
 	getMappedIPs() map[string]types.MappedIP
 
+	// load balancers
+	updateLoadBalancer(lb types.LoadBalancer) error
+	deleteLoadBalancer(ID string) error
+	getLoadBalancers() ([]types.LoadBalancer, error)
+
 	// quotas
 	updateQuotas(tenantID string, qds []types.QuotaDetails) error
 	getQuotas(tenantID string) ([]types.QuotaDetails, error)
@@ -143,6 +159,26 @@ type persistentStore interface {
 	updateImage(i types.Image) error
 	deleteImage(ID string) error
 	getImages() ([]types.Image, error)
+
+	// backups
+	updateBackup(b types.Backup) error
+	deleteBackup(ID string) error
+	getBackups() ([]types.Backup, error)
+
+	// network peerings
+	updateNetworkPeering(p types.NetworkPeering) error
+	deleteNetworkPeering(ID string) error
+	getNetworkPeerings() ([]types.NetworkPeering, error)
+
+	// tenant networks
+	addTenantNetwork(n types.TenantNetwork) error
+	deleteTenantNetwork(ID string) error
+	claimNetworkIP(networkID string, ip string) error
+	releaseNetworkIP(networkID string, ip string) error
+
+	// reserved addresses
+	addReservedIP(r types.ReservedIP) error
+	deleteReservedIP(tenantID string, ip string) error
 }
 
 // Datastore provides context for the datastore package.
@@ -182,15 +218,22 @@ type Datastore struct {
 	externalSubnets map[string]bool
 	externalIPs     map[string]bool
 	mappedIPs       map[string]types.MappedIP
+	loadBalancers   map[string]types.LoadBalancer
 	poolsLock       *sync.RWMutex
 
 	imageLock      *sync.RWMutex
 	images         map[string]types.Image
 	publicImages   []string
 	internalImages []string
+
+	backupsLock *sync.RWMutex
+	backups     map[string]types.Backup
+
+	peeringsLock *sync.RWMutex
+	peerings     map[string]types.NetworkPeering
 }
 
-func (ds *Datastore) initExternalIPs() {
+func (ds *Datastore) initExternalIPs() error {
 	ds.poolsLock = &sync.RWMutex{}
 	ds.externalSubnets = make(map[string]bool)
 	ds.externalIPs = make(map[string]bool)
@@ -208,6 +251,19 @@ func (ds *Datastore) initExternalIPs() {
 	}
 
 	ds.mappedIPs = ds.db.getMappedIPs()
+
+	ds.loadBalancers = make(map[string]types.LoadBalancer)
+
+	lbs, err := ds.db.getLoadBalancers()
+	if err != nil {
+		return errors.Wrap(err, "error getting load balancers from database")
+	}
+
+	for _, lb := range lbs {
+		ds.loadBalancers[lb.ID] = lb
+	}
+
+	return nil
 }
 
 func (ds *Datastore) initImages() error {
@@ -353,9 +409,31 @@ func (ds *Datastore) Init(config Config) error {
 
 	ds.attachLock = &sync.RWMutex{}
 
-	ds.initExternalIPs()
+	ds.backupsLock = &sync.RWMutex{}
+	ds.backups = make(map[string]types.Backup)
 
-	return nil
+	backups, err := ds.db.getBackups()
+	if err != nil {
+		return errors.Wrap(err, "error getting backups from database")
+	}
+
+	for _, b := range backups {
+		ds.backups[b.ID] = b
+	}
+
+	ds.peeringsLock = &sync.RWMutex{}
+	ds.peerings = make(map[string]types.NetworkPeering)
+
+	peerings, err := ds.db.getNetworkPeerings()
+	if err != nil {
+		return errors.Wrap(err, "error getting network peerings from database")
+	}
+
+	for _, p := range peerings {
+		ds.peerings[p.ID] = p
+	}
+
+	return ds.initExternalIPs()
 }
 
 // Exit will disconnect the backing database.
@@ -443,8 +521,10 @@ func (ds *Datastore) JSONPatchTenant(ID string, patch []byte) error {
 	}
 
 	oldconfig := types.TenantConfig{
-		Name:       tenant.Name,
-		SubnetBits: tenant.SubnetBits,
+		Name:          tenant.Name,
+		SubnetBits:    tenant.SubnetBits,
+		DNSDomain:     tenant.DNSDomain,
+		DNSForwarders: tenant.DNSForwarders,
 	}
 
 	orig, err := json.Marshal(oldconfig)
@@ -464,6 +544,8 @@ func (ds *Datastore) JSONPatchTenant(ID string, patch []byte) error {
 
 	tenant.Name = config.Name
 	tenant.SubnetBits = config.SubnetBits
+	tenant.DNSDomain = config.DNSDomain
+	tenant.DNSForwarders = config.DNSForwarders
 
 	return ds.db.updateTenant(&tenant.Tenant)
 }
@@ -656,10 +738,11 @@ func (ds *Datastore) ReleaseTenantIP(tenantID string, ip string) error {
 			// delete the network map and the subnet
 			delete(ds.tenants[tenantID].network, i)
 
-			if len(subnets) > 1 {
-				ds.tenants[tenantID].subnets = append(subnets[:i], subnets[i+1:]...)
-			} else {
-				ds.tenants[tenantID].subnets = nil
+			for k, subnet := range subnets {
+				if subnet == i {
+					ds.tenants[tenantID].subnets = append(subnets[:k], subnets[k+1:]...)
+					break
+				}
 			}
 
 			removeSubnet = true
@@ -678,6 +761,41 @@ func (ds *Datastore) ReleaseTenantIP(tenantID string, ip string) error {
 	return ds.db.releaseTenantIP(tenantID, int(subnetInt), int(ipBytes[3]))
 }
 
+// ReleaseInstanceIP returns the IP address of an instance to the tenant
+// network or to the default tenant address space it was allocated from.
+// The addresses of the additional NICs of the instance are returned to
+// their tenant networks.  If the instance retains its IP address, the
+// address is reserved for the tenant rather than returned.
+func (ds *Datastore) ReleaseInstanceIP(i *types.Instance) error {
+	for _, nic := range i.AdditionalNICs {
+		err := ds.ReleaseNetworkIP(i.TenantID, nic.NetworkID, nic.IPAddress)
+		if err != nil {
+			glog.Warningf("Unable to release IP %s of instance %s: %v",
+				nic.IPAddress, i.ID, err)
+		}
+	}
+
+	if i.RetainIP {
+		return ds.reserveInstanceIP(i)
+	}
+
+	if i.NetworkID != "" {
+		return ds.ReleaseNetworkIP(i.TenantID, i.NetworkID, i.IPAddress)
+	}
+
+	return ds.ReleaseTenantIP(i.TenantID, i.IPAddress)
+}
+
+func subnetIntToIPNet(subnetInt uint16) *net.IPNet {
+	subnetBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(subnetBytes, subnetInt)
+
+	return &net.IPNet{
+		IP:   net.IPv4(172, subnetBytes[0], subnetBytes[1], 0).To4(),
+		Mask: net.IPv4Mask(255, 255, 255, 0),
+	}
+}
+
 func getMaxHost(bits int) (int, error) {
 	_, ipNet, err := net.ParseCIDR(fmt.Sprintf("172.16.0.0/%d", bits))
 	if err != nil {
@@ -726,9 +844,10 @@ func (ds *Datastore) AllocateTenantIP(tenantID string) (net.IP, error) {
 		i := binary.BigEndian.Uint16(subnetBytes)
 
 		for {
-			// check for new subnet.
+			// check for new subnet that does not clash with
+			// any of the tenant's own networks.
 			_, ok := network[int(i)]
-			if !ok {
+			if !ok && !ds.tenants[tenantID].overlapsNetwork(subnetIntToIPNet(i)) {
 				sub := make(map[int]bool)
 				network[int(i)] = sub
 
@@ -805,6 +924,128 @@ func (ds *Datastore) AllocateTenantIP(tenantID string) (net.IP, error) {
 	return next, nil
 }
 
+// takeReservedIP removes the reservation of ip if it was reserved from the
+// tenant network networkID, or from the default tenant address space if
+// networkID is empty.  The tenants lock must be held.
+func (t *tenant) takeReservedIP(ip string, networkID string) (types.ReservedIP, bool) {
+	r, ok := t.reservedIPs[ip]
+	if !ok || r.NetworkID != networkID {
+		return r, false
+	}
+
+	delete(t.reservedIPs, ip)
+
+	return r, true
+}
+
+// undoIPClaim returns an address claimed from the tenant network networkID,
+// or from the default tenant address space if networkID is empty, when the
+// claim cannot be completed.  If the address was claimed from the
+// reservation r, the reservation is reinstated instead.  stored indicates
+// whether the claim had been written to the database.
+func (ds *Datastore) undoIPClaim(tenantID string, networkID string, ip string,
+	r *types.ReservedIP, stored bool) {
+	var err error
+
+	if r == nil {
+		if networkID != "" {
+			err = ds.ReleaseNetworkIP(tenantID, networkID, ip)
+		} else {
+			err = ds.ReleaseTenantIP(tenantID, ip)
+		}
+	} else {
+		ds.tenantsLock.Lock()
+		if t, ok := ds.tenants[tenantID]; ok {
+			t.reservedIPs[ip] = *r
+		}
+		ds.tenantsLock.Unlock()
+
+		if stored {
+			err = ds.db.addReservedIP(*r)
+		}
+	}
+
+	if err != nil {
+		glog.Warningf("Unable to return claimed IP %s: %v", ip, err)
+	}
+}
+
+// ClaimTenantIP will assign a specific IP address within the default
+// tenant address space.  The address must not be assigned to another
+// instance, but may have been reserved by the tenant, in which case the
+// reservation is removed and reserved is true.
+func (ds *Datastore) ClaimTenantIP(tenantID string, ip string) (addr net.IP, reserved bool, err error) {
+	addr = net.ParseIP(ip).To4()
+	if addr == nil || addr[0] != 172 || addr[1] < 16 || addr[1] > 31 {
+		return nil, false, types.ErrInvalidIP
+	}
+
+	// .0, .1 and .255 are never assigned to instances.
+	rest := int(addr[3])
+	if rest < 2 || rest > 254 {
+		return nil, false, types.ErrInvalidIP
+	}
+
+	subnetInt := int(binary.BigEndian.Uint16(addr[1:3]))
+
+	ds.tenantsLock.Lock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return nil, false, ErrNoTenant
+	}
+
+	if t.overlapsNetwork(subnetIntToIPNet(uint16(subnetInt))) {
+		ds.tenantsLock.Unlock()
+		return nil, false, types.ErrInvalidIP
+	}
+
+	hosts, ok := t.network[subnetInt]
+	if !ok {
+		hosts = make(map[int]bool)
+		t.network[subnetInt] = hosts
+		t.subnets = append(t.subnets, subnetInt)
+	}
+
+	var r *types.ReservedIP
+	if hosts[rest] {
+		var res types.ReservedIP
+		res, reserved = t.takeReservedIP(addr.String(), "")
+		if !reserved {
+			ds.tenantsLock.Unlock()
+			return nil, false, types.ErrAddressInUse
+		}
+		r = &res
+	} else {
+		hosts[rest] = true
+	}
+
+	mgr := t.CNCIctrl
+
+	ds.tenantsLock.Unlock()
+
+	if reserved {
+		err = ds.db.deleteReservedIP(tenantID, addr.String())
+	} else {
+		err = ds.db.claimTenantIP(tenantID, subnetInt, rest)
+	}
+	if err != nil {
+		ds.undoIPClaim(tenantID, "", addr.String(), r, false)
+		return nil, false, errors.Wrap(err, "Error claiming tenant IP in database")
+	}
+
+	if mgr != nil {
+		err = mgr.WaitForActive(subnetInt)
+		if err != nil {
+			ds.undoIPClaim(tenantID, "", addr.String(), r, true)
+			return nil, false, err
+		}
+	}
+
+	return addr, reserved, nil
+}
+
 func (ds *Datastore) getInstances(cncis bool) ([]*types.Instance, error) {
 	var instances []*types.Instance
 
@@ -1041,9 +1282,18 @@ func (ds *Datastore) StartFailure(instanceID string, reason payloads.StartFailur
 }
 
 // AttachVolumeFailure will clean up after a failure to attach a volume.
-// The volume state will be changed back to available, and an error message
-// will be logged.
+// The attachment will be removed, the volume state will be changed back to
+// available, or in use if the volume is still attached to other instances,
+// and an error message will be logged.
 func (ds *Datastore) AttachVolumeFailure(instanceID string, volumeID string, reason payloads.AttachVolumeFailureReason) error {
+	a, err := ds.getStorageAttachment(instanceID, volumeID)
+	if err == nil {
+		err = ds.DeleteStorageAttachment(a.ID)
+		if err != nil {
+			return errors.Wrapf(err, "error deleting storage attachment (%v)", a.ID)
+		}
+	}
+
 	// update the block data to reflect correct state
 	data, err := ds.GetBlockDevice(volumeID)
 	if err != nil {
@@ -1051,7 +1301,7 @@ func (ds *Datastore) AttachVolumeFailure(instanceID string, volumeID string, rea
 	}
 
 	oldState := data.State
-	data.State = types.Available
+	data.State = ds.detachedVolumeState(volumeID)
 	err = ds.UpdateBlockDevice(data)
 	if err != nil {
 		data.State = oldState
@@ -1084,6 +1334,130 @@ func (ds *Datastore) AttachVolumeFailure(instanceID string, volumeID string, rea
 	return errors.Wrap(ds.db.logEvent(e), "Error logging event")
 }
 
+// DetachVolumeFailure will clean up after a failure to detach a volume.
+// The attachment will no longer be marked as detaching, and a user error
+// will be logged.
+func (ds *Datastore) DetachVolumeFailure(instanceID string, volumeID string, reason payloads.DetachVolumeFailureReason) error {
+	a, err := ds.getStorageAttachment(instanceID, volumeID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting storage attachment for volume (%v)", volumeID)
+	}
+
+	err = ds.SetAttachmentDetaching(a.ID, false)
+	if err != nil {
+		return errors.Wrapf(err, "error updating storage attachment (%v)", a.ID)
+	}
+
+	// get owner of this instance
+	i, err := ds.GetInstance(instanceID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting instance (%v)", instanceID)
+	}
+
+	ds.nodesLock.Lock()
+	defer ds.nodesLock.Unlock()
+
+	n, ok := ds.nodes[i.NodeID]
+	if ok {
+		n.TotalFailures++
+	}
+
+	msg := fmt.Sprintf("Detach Volume Failure %s from %s: %s", volumeID, instanceID, reason.String())
+	e := types.LogEntry{
+		TenantID:  i.TenantID,
+		EventType: string(userError),
+		Message:   msg,
+		NodeID:    i.NodeID,
+	}
+
+	return errors.Wrap(ds.db.logEvent(e), "Error logging event")
+}
+
+// VolumeDetached will remove the storage attachment between an instance
+// and a volume that has been successfully detached, and mark the volume
+// as Available if it is no longer attached to any instances.
+func (ds *Datastore) VolumeDetached(instanceID string, volumeID string) error {
+	a, err := ds.getStorageAttachment(instanceID, volumeID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting storage attachment for volume (%v)", volumeID)
+	}
+
+	err = ds.DeleteStorageAttachment(a.ID)
+	if err != nil {
+		return errors.Wrapf(err, "error deleting storage attachment (%v)", a.ID)
+	}
+
+	data, err := ds.GetBlockDevice(volumeID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting block device for volume (%v)", volumeID)
+	}
+
+	data.State = ds.detachedVolumeState(volumeID)
+	err = ds.UpdateBlockDevice(data)
+	if err != nil {
+		return errors.Wrapf(err, "error updating block device for volume (%v)", volumeID)
+	}
+
+	return nil
+}
+
+// detachedVolumeState returns the state a volume should be in once one of
+// its attachments has been removed.
+func (ds *Datastore) detachedVolumeState(volumeID string) types.BlockState {
+	ds.attachLock.RLock()
+	defer ds.attachLock.RUnlock()
+
+	return ds.volumeStateLocked(volumeID)
+}
+
+// SetAttachmentDetaching records whether a detach of the storage
+// attachment with the associated ID is in progress.  The volume is marked
+// as Detaching once all of its attachments are being detached, and stays
+// InUse otherwise, so that the other attachments of a shared volume can
+// still be detached.
+func (ds *Datastore) SetAttachmentDetaching(ID string, detaching bool) error {
+	ds.attachLock.Lock()
+	a, ok := ds.attachments[ID]
+	if ok {
+		a.Detaching = detaching
+		ds.attachments[ID] = a
+	}
+	state := ds.volumeStateLocked(a.BlockID)
+	ds.attachLock.Unlock()
+
+	if !ok {
+		return ErrNoStorageAttachment
+	}
+
+	data, err := ds.GetBlockDevice(a.BlockID)
+	if err != nil {
+		return errors.Wrapf(err, "error getting block device for volume (%v)", a.BlockID)
+	}
+
+	data.State = state
+	return errors.Wrapf(ds.UpdateBlockDevice(data), "error updating block device for volume (%v)", a.BlockID)
+}
+
+// volumeStateLocked returns the state a volume should be in given its
+// current attachments.  The caller must hold the attachLock.
+func (ds *Datastore) volumeStateLocked(volumeID string) types.BlockState {
+	state := types.Available
+
+	for _, a := range ds.attachments {
+		if a.BlockID != volumeID {
+			continue
+		}
+
+		if !a.Detaching {
+			return types.InUse
+		}
+
+		state = types.Detaching
+	}
+
+	return state
+}
+
 func (ds *Datastore) deleteInstance(instanceID string) (string, error) {
 	if err := ds.db.deleteInstance(instanceID); err != nil {
 		glog.Warningf("error deleting instance (%v): %v", instanceID, err)
@@ -1120,7 +1494,7 @@ func (ds *Datastore) deleteInstance(instanceID string) (string, error) {
 	}
 
 	if i.CNCI == false {
-		if tmpErr := ds.ReleaseTenantIP(i.TenantID, i.IPAddress); tmpErr != nil {
+		if tmpErr := ds.ReleaseInstanceIP(i); tmpErr != nil {
 			glog.Warningf("error releasing IP for instance (%v): %v", i.ID, tmpErr)
 			if err == nil {
 				err = errors.Wrapf(err, "error releasing IP for instance (%v)", i.ID)
@@ -1197,6 +1571,37 @@ func (ds *Datastore) InstanceRestarting(instanceID string) error {
 	return nil
 }
 
+// SetInstanceSuspended records whether the VCPUs and memory of an instance
+// have been given back to its tenant because the instance is suspended or
+// being suspended.  It returns an InstanceStateError if the instance is
+// already marked as such, so that concurrent requests to suspend or resume
+// an instance cannot both update its tenant's quota.
+func (ds *Datastore) SetInstanceSuspended(instanceID string, suspended bool) error {
+	ds.instancesLock.Lock()
+	defer ds.instancesLock.Unlock()
+
+	i, ok := ds.instances[instanceID]
+	if !ok {
+		return types.ErrInstanceNotFound
+	}
+
+	if i.Suspended == suspended {
+		if suspended {
+			return &types.InstanceStateError{Expected: payloads.Running}
+		}
+		return &types.InstanceStateError{Expected: payloads.Suspended}
+	}
+
+	i.Suspended = suspended
+	err := ds.db.updateInstance(i)
+	if err != nil {
+		i.Suspended = !suspended
+		return errors.Wrap(err, "Error updating instance")
+	}
+
+	return nil
+}
+
 // InstanceStopped removes the link between an instance and its node
 func (ds *Datastore) InstanceStopped(instanceID string) error {
 	err := ds.updateInstanceStatus(payloads.Exited, instanceID)
@@ -1367,7 +1772,12 @@ func (ds *Datastore) addNodeStat(stat payloads.Stat) error {
 		MemAvailable:         stat.MemAvailableMB,
 		DiskTotal:            stat.DiskTotalMB,
 		DiskAvailable:        stat.DiskAvailableMB,
+		DiskReserved:         stat.DiskReservedMB,
+		DiskUsed:             stat.DiskUsedMB,
 		OnlineCPUs:           stat.CpusOnline,
+		DedicatedCPUs:        stat.DedicatedCpusAvailable,
+		HugePagesTotal:       stat.HugePagesTotalMB,
+		HugePagesAvailable:   stat.HugePagesAvailableMB,
 		TotalFailures:        n.TotalFailures,
 		StartFailures:        n.StartFailures,
 		AttachVolumeFailures: n.AttachVolumeFailures,
@@ -1515,6 +1925,9 @@ func (ds *Datastore) addInstanceStats(stats []payloads.InstanceStat, nodeID stri
 			instance.NodeID = nodeID
 			instance.SSHIP = stat.SSHIP
 			instance.SSHPort = stat.SSHPort
+			instance.Guest = stat.Guest
+			instance.BlockIO = stat.BlockIO
+			instance.NetworkIO = stat.NetworkIO
 			ds.nodesLock.Lock()
 			ds.nodes[nodeID].instances[instance.ID] = instance
 			ds.nodesLock.Unlock()
@@ -1593,7 +2006,7 @@ func (ds *Datastore) GetNodeSummary() ([]*types.NodeSummary, error) {
 				summary.TotalPendingInstances++
 			case payloads.Running:
 				summary.TotalRunningInstances++
-			case payloads.Exited:
+			case payloads.Exited, payloads.Paused, payloads.Suspended:
 				summary.TotalPausedInstances++
 			}
 		}
@@ -1779,6 +2192,11 @@ func (ds *Datastore) CreateStorageAttachment(instanceID string, volume payloads.
 		BlockID:    volume.ID,
 		Ephemeral:  volume.Ephemeral,
 		Boot:       volume.Bootable,
+		Mode:       types.ReadWrite,
+	}
+
+	if volume.ReadOnly {
+		a.Mode = types.ReadOnly
 	}
 
 	err := ds.db.addStorageAttachment(a)
@@ -1839,13 +2257,6 @@ func (ds *Datastore) updateStorageAttachments(instanceID string) {
 				continue
 			}
 
-			// update the state of the volume.
-			bd.State = types.Available
-			err = ds.UpdateBlockDevice(bd)
-			if err != nil {
-				glog.Warningf("error updating block device (%v): %v", a.BlockID, err)
-			}
-
 			// delete the attachment.
 			key := attachment{
 				instanceID: a.InstanceID,
@@ -1855,6 +2266,14 @@ func (ds *Datastore) updateStorageAttachments(instanceID string) {
 			delete(ds.attachments, ID)
 			delete(ds.instanceVolumes, key)
 
+			// update the state of the volume.  Shared volumes
+			// remain in use while attached to other instances.
+			bd.State = ds.volumeStateLocked(a.BlockID)
+			err = ds.UpdateBlockDevice(bd)
+			if err != nil {
+				glog.Warningf("error updating block device (%v): %v", a.BlockID, err)
+			}
+
 			// update persistent store asynch.
 			// ok for lock to be held here, but
 			// not needed as the db keeps it's
@@ -2094,16 +2513,16 @@ func (ds *Datastore) DeletePool(ID string) error {
 
 // AddExternalSubnet will add a new subnet to an existing pool.
 func (ds *Datastore) AddExternalSubnet(poolID string, subnet string) error {
-	sub := types.ExternalSubnet{
-		ID:   uuid.Generate().String(),
-		CIDR: subnet,
-	}
-
 	_, ipNet, err := net.ParseCIDR(subnet)
 	if err != nil {
 		return errors.Wrapf(err, "unable to parse subnet CIDR (%v)", subnet)
 	}
 
+	sub := types.ExternalSubnet{
+		ID:   uuid.Generate().String(),
+		CIDR: ipNet.String(),
+	}
+
 	ds.poolsLock.Lock()
 	defer ds.poolsLock.Unlock()
 
@@ -2116,13 +2535,9 @@ func (ds *Datastore) AddExternalSubnet(poolID string, subnet string) error {
 		return types.ErrDuplicateSubnet
 	}
 
-	ones, bits := ipNet.Mask.Size()
-
-	// intentionally do not support /32 here, user should add by IP address instead
-	// deduct gateway and broadcast
-	newIPs := (1 << uint32(bits-ones)) - 2
-	if newIPs <= 0 {
-		return types.ErrSubnetTooSmall
+	newIPs, err := externalSubnetSize(ipNet)
+	if err != nil {
+		return err
 	}
 	p.TotalIPs += newIPs
 	p.Free += newIPs
@@ -2140,6 +2555,37 @@ func (ds *Datastore) AddExternalSubnet(poolID string, subnet string) error {
 	return nil
 }
 
+// maxIPv6SubnetBits limits the size of IPv6 external subnets, as each
+// address of a subnet is checked when allocating from or deleting it.
+const maxIPv6SubnetBits = 16
+
+// externalSubnetSize returns the number of addresses of an external subnet
+// which can be mapped to instances.
+func externalSubnetSize(ipNet *net.IPNet) (int, error) {
+	ones, bits := ipNet.Mask.Size()
+
+	// intentionally do not support /32 and /128 here, user should add by
+	// IP address instead.
+	var size int
+	if ipNet.IP.To4() == nil {
+		if bits-ones > maxIPv6SubnetBits {
+			return 0, types.ErrSubnetTooLarge
+		}
+
+		// deduct gateway, IPv6 has no broadcast address
+		size = (1 << uint32(bits-ones)) - 1
+	} else {
+		// deduct gateway and broadcast
+		size = (1 << uint32(bits-ones)) - 2
+	}
+
+	if size <= 0 {
+		return 0, types.ErrSubnetTooSmall
+	}
+
+	return size, nil
+}
+
 // AddExternalIPs will add a list of individual IPs to an existing pool.
 func (ds *Datastore) AddExternalIPs(poolID string, IPs []string) error {
 	ds.poolsLock.Lock()
@@ -2218,14 +2664,15 @@ func (ds *Datastore) DeleteSubnet(poolID string, subnetID string) error {
 
 		// check each address in this subnet is not mapped.
 		for IP := IP.Mask(ipNet.Mask); ipNet.Contains(IP); incrementIP(IP) {
-			_, ok := ds.mappedIPs[IP.String()]
-			if ok {
+			if ds.externalIPInUse(IP.String()) {
 				return types.ErrPoolNotEmpty
 			}
 		}
 
-		ones, bits := ipNet.Mask.Size()
-		numIPs := (1 << uint32(bits-ones)) - 2
+		numIPs, err := externalSubnetSize(ipNet)
+		if err != nil {
+			return err
+		}
 		p.TotalIPs -= numIPs
 		p.Free -= numIPs
 		p.Subnets = append(p.Subnets[:i], p.Subnets[i+1:]...)
@@ -2261,8 +2708,7 @@ func (ds *Datastore) DeleteExternalIP(poolID string, addrID string) error {
 
 		// this path will be taken only once.
 		// check address is not mapped.
-		_, ok := ds.mappedIPs[extIP.Address]
-		if ok {
+		if ds.externalIPInUse(extIP.Address) {
 			return types.ErrPoolNotEmpty
 		}
 
@@ -2284,18 +2730,73 @@ func (ds *Datastore) DeleteExternalIP(poolID string, addrID string) error {
 	return types.ErrInvalidPoolAddress
 }
 
-func incrementIP(IP net.IP) {
-	for i := len(IP) - 1; i >= 0; i-- {
-		IP[i]++
-		if IP[i] > 0 {
-			break
+// externalIPInUse returns true if an external address is mapped to an
+// instance or is the virtual IP of a load balancer.  The pools lock must
+// be held.
+func (ds *Datastore) externalIPInUse(address string) bool {
+	if _, ok := ds.mappedIPs[address]; ok {
+		return true
+	}
+
+	for _, lb := range ds.loadBalancers {
+		if lb.VIP == address {
+			return true
 		}
 	}
-}
 
-// GetMappedIPs will return a list of mapped external IPs by tenant.
-func (ds *Datastore) GetMappedIPs(tenant *string) []types.MappedIP {
-	var mappedIPs []types.MappedIP
+	return false
+}
+
+// findFreeExternalIP returns the first address of a pool which is not in
+// use and for which usable returns true, or an empty string if there is
+// none.  The pools lock must be held.
+func (ds *Datastore) findFreeExternalIP(pool types.Pool, usable func(net.IP) bool) (string, error) {
+	// find a free IP address in any subnet.
+	for _, sub := range pool.Subnets {
+		IP, ipNet, err := net.ParseCIDR(sub.CIDR)
+		if err != nil {
+			return "", errors.Wrapf(err, "error parsing subnet CIDR (%v)", sub.CIDR)
+		}
+
+		if !usable(IP) {
+			continue
+		}
+
+		initIP := IP.Mask(ipNet.Mask)
+
+		// skip gateway
+		incrementIP(initIP)
+
+		// check each address in this subnet
+		for IP := initIP; ipNet.Contains(IP); incrementIP(IP) {
+			if !ds.externalIPInUse(IP.String()) {
+				return IP.String(), nil
+			}
+		}
+	}
+
+	// we are still looking. Check our individual IPs
+	for _, IP := range pool.IPs {
+		if !ds.externalIPInUse(IP.Address) && usable(net.ParseIP(IP.Address)) {
+			return IP.Address, nil
+		}
+	}
+
+	return "", nil
+}
+
+func incrementIP(IP net.IP) {
+	for i := len(IP) - 1; i >= 0; i-- {
+		IP[i]++
+		if IP[i] > 0 {
+			break
+		}
+	}
+}
+
+// GetMappedIPs will return a list of mapped external IPs by tenant.
+func (ds *Datastore) GetMappedIPs(tenant *string) []types.MappedIP {
+	var mappedIPs []types.MappedIP
 
 	ds.poolsLock.RLock()
 	defer ds.poolsLock.RUnlock()
@@ -2334,6 +2835,16 @@ func (ds *Datastore) MapExternalIP(poolID string, instanceID string) (types.Mapp
 		return m, errors.Wrapf(err, "error getting instance (%v)", instanceID)
 	}
 
+	// IPv6 external addresses can only be mapped to instances on dual
+	// stack networks.
+	internalIPv6 := ds.GetNICIPv6(instance.TenantID, instance.NetworkID, instance.MACAddress)
+	internalIP := func(IP net.IP) string {
+		if IP.To4() != nil {
+			return instance.IPAddress
+		}
+		return internalIPv6
+	}
+
 	ds.poolsLock.Lock()
 	defer ds.poolsLock.Unlock()
 
@@ -2346,84 +2857,46 @@ func (ds *Datastore) MapExternalIP(poolID string, instanceID string) (types.Mapp
 		return m, types.ErrPoolEmpty
 	}
 
-	// find a free IP address in any subnet.
-	for _, sub := range pool.Subnets {
-		IP, ipNet, err := net.ParseCIDR(sub.CIDR)
-		if err != nil {
-			return m, errors.Wrapf(err, "error parsing subnet CIDR (%v)", sub.CIDR)
-		}
-
-		initIP := IP.Mask(ipNet.Mask)
-
-		// skip gateway
-		incrementIP(initIP)
-
-		// check each address in this subnet
-		for IP := initIP; ipNet.Contains(IP); incrementIP(IP) {
-			_, ok := ds.mappedIPs[IP.String()]
-			if !ok {
-				m.ID = uuid.Generate().String()
-				m.ExternalIP = IP.String()
-				m.InternalIP = instance.IPAddress
-				m.InstanceID = instanceID
-				m.TenantID = instance.TenantID
-				m.PoolID = pool.ID
-				m.PoolName = pool.Name
-
-				pool.Free--
-
-				err = ds.db.addMappedIP(m)
-				if err != nil {
-					return types.MappedIP{}, errors.Wrap(err, "error adding IP mapping to database")
-				}
-				ds.mappedIPs[IP.String()] = m
-
-				err = ds.db.updatePool(pool)
-				if err != nil {
-					return types.MappedIP{}, errors.Wrap(err, "error updating pool in database")
-				}
-
-				ds.pools[poolID] = pool
+	address, err := ds.findFreeExternalIP(pool, func(IP net.IP) bool {
+		return internalIP(IP) != ""
+	})
+	if err != nil {
+		return m, err
+	}
 
-				return m, nil
-			}
+	// if you got here you are out of luck. But you never should, unless
+	// only IPv6 addresses are free and the instance has no IPv6 address.
+	if address == "" {
+		if internalIPv6 != "" {
+			glog.Warningf("Pool reports %d free addresses but none found", pool.Free)
 		}
+		return m, types.ErrPoolEmpty
 	}
 
-	// we are still looking. Check our individual IPs
-	for _, IP := range pool.IPs {
-		_, ok := ds.mappedIPs[IP.Address]
-		if !ok {
-			m.ID = uuid.Generate().String()
-			m.ExternalIP = IP.Address
-			m.InternalIP = instance.IPAddress
-			m.InstanceID = instanceID
-			m.TenantID = instance.TenantID
-			m.PoolID = pool.ID
-			m.PoolName = pool.Name
-
-			pool.Free--
-
-			err = ds.db.addMappedIP(m)
-			if err != nil {
-				return types.MappedIP{}, errors.Wrap(err, "error adding IP mapping to database")
-			}
-			ds.mappedIPs[IP.Address] = m
+	m.ID = uuid.Generate().String()
+	m.ExternalIP = address
+	m.InternalIP = internalIP(net.ParseIP(address))
+	m.InstanceID = instanceID
+	m.TenantID = instance.TenantID
+	m.PoolID = pool.ID
+	m.PoolName = pool.Name
 
-			err = ds.db.updatePool(pool)
-			if err != nil {
-				return types.MappedIP{}, errors.Wrap(err, "error updating pool in database")
-			}
+	pool.Free--
 
-			ds.pools[poolID] = pool
+	err = ds.db.addMappedIP(m)
+	if err != nil {
+		return types.MappedIP{}, errors.Wrap(err, "error adding IP mapping to database")
+	}
+	ds.mappedIPs[address] = m
 
-			return m, nil
-		}
+	err = ds.db.updatePool(pool)
+	if err != nil {
+		return types.MappedIP{}, errors.Wrap(err, "error updating pool in database")
 	}
 
-	// if you got here you are out of luck. But you never should.
-	glog.Warningf("Pool reports %d free addresses but none found", pool.Free)
-	return m, types.ErrPoolEmpty
+	ds.pools[poolID] = pool
+
+	return m, nil
 }
 
 // UnMapExternalIP will stop associating a given address with an instance.
@@ -2460,6 +2933,147 @@ func (ds *Datastore) UnMapExternalIP(address string) error {
 	return nil
 }
 
+// AddLoadBalancer allocates the virtual IP of a load balancer from a given
+// pool and adds the load balancer to the datastore and the database.
+func (ds *Datastore) AddLoadBalancer(poolID string, lb types.LoadBalancer) (types.LoadBalancer, error) {
+	ds.poolsLock.Lock()
+	defer ds.poolsLock.Unlock()
+
+	if _, ok := ds.loadBalancers[lb.ID]; ok {
+		return types.LoadBalancer{}, api.ErrAlreadyExists
+	}
+
+	pool, ok := ds.pools[poolID]
+	if !ok {
+		return types.LoadBalancer{}, types.ErrPoolNotFound
+	}
+
+	if pool.Free == 0 {
+		return types.LoadBalancer{}, types.ErrPoolEmpty
+	}
+
+	// the members are only reachable over IPv4.
+	address, err := ds.findFreeExternalIP(pool, func(IP net.IP) bool {
+		return IP.To4() != nil
+	})
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	if address == "" {
+		return types.LoadBalancer{}, types.ErrPoolEmpty
+	}
+
+	lb.VIP = address
+	lb.PoolID = pool.ID
+	lb.PoolName = pool.Name
+
+	pool.Free--
+
+	err = ds.db.updateLoadBalancer(lb)
+	if err != nil {
+		return types.LoadBalancer{}, errors.Wrap(err, "error adding load balancer to database")
+	}
+	ds.loadBalancers[lb.ID] = lb
+
+	err = ds.db.updatePool(pool)
+	if err != nil {
+		return types.LoadBalancer{}, errors.Wrap(err, "error updating pool in database")
+	}
+
+	ds.pools[poolID] = pool
+
+	return lb, nil
+}
+
+// UpdateLoadBalancer updates the configuration of an existing load balancer
+// in the datastore and the database.  The virtual IP cannot be changed.
+func (ds *Datastore) UpdateLoadBalancer(lb types.LoadBalancer) error {
+	ds.poolsLock.Lock()
+	defer ds.poolsLock.Unlock()
+
+	cur, ok := ds.loadBalancers[lb.ID]
+	if !ok || cur.TenantID != lb.TenantID {
+		return types.ErrLoadBalancerNotFound
+	}
+
+	if cur.VIP != lb.VIP || cur.PoolID != lb.PoolID {
+		return types.ErrBadRequest
+	}
+
+	err := ds.db.updateLoadBalancer(lb)
+	if err != nil {
+		return errors.Wrap(err, "error updating load balancer in database")
+	}
+
+	ds.loadBalancers[lb.ID] = lb
+
+	return nil
+}
+
+// GetLoadBalancer retrieves a load balancer of a tenant.
+func (ds *Datastore) GetLoadBalancer(tenantID string, ID string) (types.LoadBalancer, error) {
+	ds.poolsLock.RLock()
+	defer ds.poolsLock.RUnlock()
+
+	lb, ok := ds.loadBalancers[ID]
+	if !ok || lb.TenantID != tenantID {
+		return types.LoadBalancer{}, types.ErrLoadBalancerNotFound
+	}
+
+	return lb, nil
+}
+
+// GetLoadBalancers returns all the load balancers of a tenant.
+func (ds *Datastore) GetLoadBalancers(tenantID string) []types.LoadBalancer {
+	ds.poolsLock.RLock()
+	defer ds.poolsLock.RUnlock()
+
+	lbs := []types.LoadBalancer{}
+
+	for _, lb := range ds.loadBalancers {
+		if lb.TenantID == tenantID {
+			lbs = append(lbs, lb)
+		}
+	}
+
+	return lbs
+}
+
+// DeleteLoadBalancer removes a load balancer of a tenant from the datastore
+// and the database and returns its virtual IP to its pool.
+func (ds *Datastore) DeleteLoadBalancer(tenantID string, ID string) error {
+	ds.poolsLock.Lock()
+	defer ds.poolsLock.Unlock()
+
+	lb, ok := ds.loadBalancers[ID]
+	if !ok || lb.TenantID != tenantID {
+		return types.ErrLoadBalancerNotFound
+	}
+
+	err := ds.db.deleteLoadBalancer(ID)
+	if err != nil {
+		return errors.Wrap(err, "error deleting load balancer from database")
+	}
+	delete(ds.loadBalancers, ID)
+
+	pool, ok := ds.pools[lb.PoolID]
+	if !ok {
+		return types.ErrPoolNotFound
+	}
+
+	pool.Free++
+
+	err = ds.db.updatePool(pool)
+	if err != nil {
+		return errors.Wrap(err, "error updating pool in database")
+	}
+
+	ds.pools[pool.ID] = pool
+
+	return nil
+}
+
 // GenerateCNCIWorkload is used to create a workload definition for the CNCI.
 // This function should be called prior to any workload launch.
 func (ds *Datastore) GenerateCNCIWorkload(vcpus int, memMB int, diskMB int, key string, password string) {
@@ -2707,3 +3321,685 @@ func (ds *Datastore) DeleteImage(ID string) error {
 
 	return nil
 }
+
+// AddBackup adds a volume backup to the datastore and database
+func (ds *Datastore) AddBackup(b types.Backup) error {
+	ds.backupsLock.Lock()
+	defer ds.backupsLock.Unlock()
+
+	if _, ok := ds.backups[b.ID]; ok {
+		return api.ErrAlreadyExists
+	}
+
+	err := ds.db.updateBackup(b)
+	if err != nil {
+		return errors.Wrap(err, "Unable to add backup to database")
+	}
+
+	ds.backups[b.ID] = b
+
+	return nil
+}
+
+// UpdateBackup updates the backup metadata in the datastore and database
+func (ds *Datastore) UpdateBackup(b types.Backup) error {
+	ds.backupsLock.Lock()
+	defer ds.backupsLock.Unlock()
+
+	if _, ok := ds.backups[b.ID]; !ok {
+		return types.ErrBackupNotFound
+	}
+
+	err := ds.db.updateBackup(b)
+	if err != nil {
+		return errors.Wrap(err, "Error updating backup in database")
+	}
+
+	ds.backups[b.ID] = b
+
+	return nil
+}
+
+// GetBackup retrieves a backup by ID
+func (ds *Datastore) GetBackup(ID string) (types.Backup, error) {
+	ds.backupsLock.RLock()
+	defer ds.backupsLock.RUnlock()
+
+	b, ok := ds.backups[ID]
+	if !ok {
+		return types.Backup{}, types.ErrBackupNotFound
+	}
+
+	return b, nil
+}
+
+// GetBackups returns all the backups owned by a tenant
+func (ds *Datastore) GetBackups(tenantID string) ([]types.Backup, error) {
+	ds.backupsLock.RLock()
+	defer ds.backupsLock.RUnlock()
+
+	backups := []types.Backup{}
+
+	for _, b := range ds.backups {
+		if b.TenantID == tenantID {
+			backups = append(backups, b)
+		}
+	}
+
+	return backups, nil
+}
+
+// GetAllBackups returns the backups of all the tenants
+func (ds *Datastore) GetAllBackups() []types.Backup {
+	ds.backupsLock.RLock()
+	defer ds.backupsLock.RUnlock()
+
+	backups := []types.Backup{}
+
+	for _, b := range ds.backups {
+		backups = append(backups, b)
+	}
+
+	return backups
+}
+
+// DeleteBackup deletes a backup from the datastore and the database
+func (ds *Datastore) DeleteBackup(ID string) error {
+	ds.backupsLock.Lock()
+	defer ds.backupsLock.Unlock()
+
+	if _, ok := ds.backups[ID]; !ok {
+		return types.ErrBackupNotFound
+	}
+
+	err := ds.db.deleteBackup(ID)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting backup from database")
+	}
+
+	delete(ds.backups, ID)
+
+	return nil
+}
+
+// peeredCIDRs returns the subnets of the networks peered with a network.
+// The peerings lock must be held.
+func (ds *Datastore) peeredCIDRs(networkID string) []string {
+	var cidrs []string
+
+	for _, p := range ds.peerings {
+		if p.NetworkID == networkID {
+			cidrs = append(cidrs, p.PeerCIDR)
+		} else if p.PeerNetworkID == networkID {
+			cidrs = append(cidrs, p.CIDR)
+		}
+	}
+
+	return cidrs
+}
+
+// cidrsOverlap returns true if subnet overlaps any of cidrs.
+func cidrsOverlap(subnet *net.IPNet, cidrs []string) bool {
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			continue
+		}
+
+		if ipNetsOverlap(subnet, ipNet) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AddNetworkPeering adds a network peering to the datastore and database.
+// A pair of networks can only be peered once and, as traffic is routed
+// between peered networks, the subnet of each network may neither overlap
+// the subnet of the other network nor the subnet of any network already
+// peered with the other network.
+func (ds *Datastore) AddNetworkPeering(p types.NetworkPeering) error {
+	_, local, err := net.ParseCIDR(p.CIDR)
+	if err != nil {
+		return types.ErrBadRequest
+	}
+
+	_, peer, err := net.ParseCIDR(p.PeerCIDR)
+	if err != nil {
+		return types.ErrBadRequest
+	}
+
+	ds.peeringsLock.Lock()
+	defer ds.peeringsLock.Unlock()
+
+	if _, ok := ds.peerings[p.ID]; ok {
+		return api.ErrAlreadyExists
+	}
+
+	for _, cur := range ds.peerings {
+		if (cur.NetworkID == p.NetworkID && cur.PeerNetworkID == p.PeerNetworkID) ||
+			(cur.NetworkID == p.PeerNetworkID && cur.PeerNetworkID == p.NetworkID) {
+			return api.ErrAlreadyExists
+		}
+	}
+
+	if ipNetsOverlap(local, peer) ||
+		cidrsOverlap(peer, ds.peeredCIDRs(p.NetworkID)) ||
+		cidrsOverlap(local, ds.peeredCIDRs(p.PeerNetworkID)) {
+		return types.ErrPeeringOverlap
+	}
+
+	err = ds.db.updateNetworkPeering(p)
+	if err != nil {
+		return errors.Wrap(err, "Unable to add network peering to database")
+	}
+
+	ds.peerings[p.ID] = p
+
+	return nil
+}
+
+// UpdateNetworkPeering updates the status of a network peering in the
+// datastore and database.  The peered networks cannot be changed.
+func (ds *Datastore) UpdateNetworkPeering(p types.NetworkPeering) error {
+	ds.peeringsLock.Lock()
+	defer ds.peeringsLock.Unlock()
+
+	cur, ok := ds.peerings[p.ID]
+	if !ok {
+		return types.ErrNetworkPeeringNotFound
+	}
+
+	if cur.NetworkID != p.NetworkID || cur.PeerNetworkID != p.PeerNetworkID {
+		return types.ErrBadRequest
+	}
+
+	err := ds.db.updateNetworkPeering(p)
+	if err != nil {
+		return errors.Wrap(err, "Error updating network peering in database")
+	}
+
+	ds.peerings[p.ID] = p
+
+	return nil
+}
+
+// GetNetworkPeering retrieves a network peering by ID
+func (ds *Datastore) GetNetworkPeering(ID string) (types.NetworkPeering, error) {
+	ds.peeringsLock.RLock()
+	defer ds.peeringsLock.RUnlock()
+
+	p, ok := ds.peerings[ID]
+	if !ok {
+		return types.NetworkPeering{}, types.ErrNetworkPeeringNotFound
+	}
+
+	return p, nil
+}
+
+// GetNetworkPeerings returns all the network peerings, both pending and
+// active.
+func (ds *Datastore) GetNetworkPeerings() []types.NetworkPeering {
+	ds.peeringsLock.RLock()
+	defer ds.peeringsLock.RUnlock()
+
+	peerings := []types.NetworkPeering{}
+
+	for _, p := range ds.peerings {
+		peerings = append(peerings, p)
+	}
+
+	return peerings
+}
+
+// DeleteNetworkPeering deletes a network peering from the datastore and
+// the database
+func (ds *Datastore) DeleteNetworkPeering(ID string) error {
+	ds.peeringsLock.Lock()
+	defer ds.peeringsLock.Unlock()
+
+	if _, ok := ds.peerings[ID]; !ok {
+		return types.ErrNetworkPeeringNotFound
+	}
+
+	err := ds.db.deleteNetworkPeering(ID)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting network peering from database")
+	}
+
+	delete(ds.peerings, ID)
+
+	return nil
+}
+
+func ipNetsOverlap(a *net.IPNet, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// overlapsNetwork returns true if subnet overlaps any of the networks
+// defined by the tenant.  The tenants lock must be held.
+func (t *tenant) overlapsNetwork(subnet *net.IPNet) bool {
+	for _, n := range t.networks {
+		for _, cidr := range []string{n.CIDR, n.IPv6CIDR} {
+			_, ipNet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+
+			if ipNetsOverlap(subnet, ipNet) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// AddTenantNetwork adds a network defined by a tenant to the datastore and
+// database.  The subnet of the network may not overlap the subnet of any
+// other network of the tenant, or any subnet of the default tenant address
+// space currently used by the tenant.
+func (ds *Datastore) AddTenantNetwork(n types.TenantNetwork) error {
+	_, subnet, err := net.ParseCIDR(n.CIDR)
+	if err != nil {
+		return types.ErrBadRequest
+	}
+
+	ds.tenantsLock.Lock()
+	defer ds.tenantsLock.Unlock()
+
+	t, ok := ds.tenants[n.TenantID]
+	if !ok {
+		return ErrNoTenant
+	}
+
+	if _, ok := t.networks[n.ID]; ok {
+		return api.ErrAlreadyExists
+	}
+
+	if t.overlapsNetwork(subnet) {
+		return types.ErrNetworkOverlap
+	}
+
+	if n.IPv6CIDR != "" {
+		_, prefix, err := net.ParseCIDR(n.IPv6CIDR)
+		if err != nil {
+			return types.ErrBadRequest
+		}
+
+		if t.overlapsNetwork(prefix) {
+			return types.ErrNetworkOverlap
+		}
+	}
+
+	for k, hosts := range t.network {
+		if len(hosts) > 0 && ipNetsOverlap(subnet, subnetIntToIPNet(uint16(k))) {
+			return types.ErrNetworkOverlap
+		}
+	}
+
+	err = ds.db.addTenantNetwork(n)
+	if err != nil {
+		return errors.Wrap(err, "Unable to add network to database")
+	}
+
+	t.networks[n.ID] = &tenantNetwork{
+		TenantNetwork: n,
+		addresses:     make(map[string]bool),
+	}
+
+	return nil
+}
+
+// GetTenantNetwork retrieves a network defined by a tenant
+func (ds *Datastore) GetTenantNetwork(tenantID string, ID string) (types.TenantNetwork, error) {
+	ds.tenantsLock.RLock()
+	defer ds.tenantsLock.RUnlock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		return types.TenantNetwork{}, ErrNoTenant
+	}
+
+	n, ok := t.networks[ID]
+	if !ok {
+		return types.TenantNetwork{}, types.ErrNetworkNotFound
+	}
+
+	return n.TenantNetwork, nil
+}
+
+// GetNICIPv6 returns the IPv6 address of the NIC with the given MAC
+// address on a tenant network, or an empty string if the network is not
+// dual stack.
+func (ds *Datastore) GetNICIPv6(tenantID string, networkID string, mac string) string {
+	if networkID == "" {
+		return ""
+	}
+
+	n, err := ds.GetTenantNetwork(tenantID, networkID)
+	if err != nil || n.IPv6CIDR == "" {
+		return ""
+	}
+
+	_, prefix, err := net.ParseCIDR(n.IPv6CIDR)
+	if err != nil {
+		return ""
+	}
+
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return ""
+	}
+
+	return utils.NewTenantIPv6Addr(prefix, hw).String()
+}
+
+// GetTenantNetworks returns all the networks defined by a tenant
+func (ds *Datastore) GetTenantNetworks(tenantID string) ([]types.TenantNetwork, error) {
+	ds.tenantsLock.RLock()
+	defer ds.tenantsLock.RUnlock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	networks := []types.TenantNetwork{}
+	for _, n := range t.networks {
+		networks = append(networks, n.TenantNetwork)
+	}
+
+	return networks, nil
+}
+
+// DeleteTenantNetwork deletes a network defined by a tenant from the
+// datastore and the database.  Networks from which addresses are still
+// allocated cannot be deleted.
+func (ds *Datastore) DeleteTenantNetwork(tenantID string, ID string) error {
+	ds.tenantsLock.Lock()
+	defer ds.tenantsLock.Unlock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		return ErrNoTenant
+	}
+
+	n, ok := t.networks[ID]
+	if !ok {
+		return types.ErrNetworkNotFound
+	}
+
+	if len(n.addresses) > 0 {
+		return types.ErrNetworkInUse
+	}
+
+	err := ds.db.deleteTenantNetwork(ID)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting network from database")
+	}
+
+	delete(t.networks, ID)
+
+	return nil
+}
+
+// AllocateNetworkIP will find a free IP address within the allocation range
+// of a network defined by a tenant.  The gateway of the network is never
+// allocated.
+func (ds *Datastore) AllocateNetworkIP(tenantID string, networkID string) (net.IP, error) {
+	ds.tenantsLock.Lock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return nil, ErrNoTenant
+	}
+
+	n, ok := t.networks[networkID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return nil, types.ErrNetworkNotFound
+	}
+
+	start := net.ParseIP(n.AllocationStart).To4()
+	end := net.ParseIP(n.AllocationEnd).To4()
+	gateway := net.ParseIP(n.Gateway).To4()
+	if start == nil || end == nil || gateway == nil {
+		ds.tenantsLock.Unlock()
+		return nil, errors.Errorf("Invalid network definition %s", networkID)
+	}
+
+	var ip net.IP
+	gwU32 := binary.BigEndian.Uint32(gateway)
+	endU32 := binary.BigEndian.Uint32(end)
+	for u32 := binary.BigEndian.Uint32(start); u32 <= endU32 && u32 != 0; u32++ {
+		if u32 == gwU32 {
+			continue
+		}
+
+		candidate := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(candidate, u32)
+		if !n.addresses[candidate.String()] {
+			ip = candidate
+			break
+		}
+	}
+
+	if ip == nil {
+		ds.tenantsLock.Unlock()
+		return nil, types.ErrNetworkFull
+	}
+
+	n.addresses[ip.String()] = true
+	mgr := t.CNCIctrl
+	cidr := n.CIDR
+
+	ds.tenantsLock.Unlock()
+
+	err := ds.db.claimNetworkIP(networkID, ip.String())
+	if err != nil {
+		ds.undoIPClaim(tenantID, networkID, ip.String(), nil, false)
+		return nil, errors.Wrap(err, "Error claiming network IP in database")
+	}
+
+	// if the subnet has already been added, this function
+	// will just confirm the subnet is active. If a new one
+	// is needed, it will wait for the new cnci to become active.
+	if mgr != nil {
+		err = mgr.WaitForActiveSubnetString(cidr)
+		if err != nil {
+			ds.undoIPClaim(tenantID, networkID, ip.String(), nil, true)
+			return nil, err
+		}
+	}
+
+	return ip, nil
+}
+
+// ClaimNetworkIP will assign a specific IP address within the allocation
+// range of a network defined by a tenant.  The address must not be
+// assigned to another instance, but may have been reserved by the tenant,
+// in which case the reservation is removed and reserved is true.
+func (ds *Datastore) ClaimNetworkIP(tenantID string, networkID string, ip string) (addr net.IP, reserved bool, err error) {
+	addr = net.ParseIP(ip).To4()
+	if addr == nil {
+		return nil, false, types.ErrInvalidIP
+	}
+
+	ds.tenantsLock.Lock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return nil, false, ErrNoTenant
+	}
+
+	n, ok := t.networks[networkID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return nil, false, types.ErrNetworkNotFound
+	}
+
+	start := net.ParseIP(n.AllocationStart).To4()
+	end := net.ParseIP(n.AllocationEnd).To4()
+	if bytes.Compare(addr, start) < 0 || bytes.Compare(addr, end) > 0 ||
+		addr.Equal(net.ParseIP(n.Gateway)) {
+		ds.tenantsLock.Unlock()
+		return nil, false, types.ErrInvalidIP
+	}
+
+	var r *types.ReservedIP
+	if n.addresses[addr.String()] {
+		var res types.ReservedIP
+		res, reserved = t.takeReservedIP(addr.String(), networkID)
+		if !reserved {
+			ds.tenantsLock.Unlock()
+			return nil, false, types.ErrAddressInUse
+		}
+		r = &res
+	} else {
+		n.addresses[addr.String()] = true
+	}
+
+	mgr := t.CNCIctrl
+	cidr := n.CIDR
+
+	ds.tenantsLock.Unlock()
+
+	if reserved {
+		err = ds.db.deleteReservedIP(tenantID, addr.String())
+	} else {
+		err = ds.db.claimNetworkIP(networkID, addr.String())
+	}
+	if err != nil {
+		ds.undoIPClaim(tenantID, networkID, addr.String(), r, false)
+		return nil, false, errors.Wrap(err, "Error claiming network IP in database")
+	}
+
+	if mgr != nil {
+		err = mgr.WaitForActiveSubnetString(cidr)
+		if err != nil {
+			ds.undoIPClaim(tenantID, networkID, addr.String(), r, true)
+			return nil, false, err
+		}
+	}
+
+	return addr, reserved, nil
+}
+
+// ReleaseNetworkIP will return an IP address previously allocated from a
+// network defined by a tenant.  The CNCI serving the network is scheduled
+// for removal once the last address has been released.
+func (ds *Datastore) ReleaseNetworkIP(tenantID string, networkID string, ip string) error {
+	if net.ParseIP(ip) == nil {
+		return errors.New("Invalid IPv4 Address")
+	}
+
+	ds.tenantsLock.Lock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return ErrNoTenant
+	}
+
+	n, ok := t.networks[networkID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return types.ErrNetworkNotFound
+	}
+
+	delete(n.addresses, ip)
+
+	if len(n.addresses) == 0 && t.CNCIctrl != nil {
+		err := t.CNCIctrl.ScheduleRemoveSubnetString(n.CIDR)
+		if err != nil {
+			glog.Warningf("Unable to remove subnet (%v)", err)
+		}
+	}
+
+	ds.tenantsLock.Unlock()
+
+	return ds.db.releaseNetworkIP(networkID, ip)
+}
+
+// reserveInstanceIP keeps the IP address of a deleted instance reserved for
+// its tenant.  The address remains allocated until the reservation is
+// claimed by a new instance or released.
+func (ds *Datastore) reserveInstanceIP(i *types.Instance) error {
+	r := types.ReservedIP{
+		TenantID:   i.TenantID,
+		IPAddress:  i.IPAddress,
+		NetworkID:  i.NetworkID,
+		InstanceID: i.ID,
+		CreateTime: time.Now(),
+	}
+
+	ds.tenantsLock.Lock()
+
+	t, ok := ds.tenants[i.TenantID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return ErrNoTenant
+	}
+
+	t.reservedIPs[r.IPAddress] = r
+
+	ds.tenantsLock.Unlock()
+
+	return ds.db.addReservedIP(r)
+}
+
+// GetReservedIPs returns the addresses reserved by a tenant.
+func (ds *Datastore) GetReservedIPs(tenantID string) ([]types.ReservedIP, error) {
+	ds.tenantsLock.RLock()
+	defer ds.tenantsLock.RUnlock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		return nil, ErrNoTenant
+	}
+
+	reserved := []types.ReservedIP{}
+	for _, r := range t.reservedIPs {
+		reserved = append(reserved, r)
+	}
+
+	return reserved, nil
+}
+
+// ReleaseReservedIP removes the reservation of an address and returns the
+// address to the tenant network or default tenant address space it was
+// allocated from.
+func (ds *Datastore) ReleaseReservedIP(tenantID string, ip string) error {
+	ds.tenantsLock.Lock()
+
+	t, ok := ds.tenants[tenantID]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return ErrNoTenant
+	}
+
+	r, ok := t.reservedIPs[ip]
+	if !ok {
+		ds.tenantsLock.Unlock()
+		return types.ErrAddressNotFound
+	}
+
+	delete(t.reservedIPs, ip)
+
+	ds.tenantsLock.Unlock()
+
+	err := ds.db.deleteReservedIP(tenantID, ip)
+	if err != nil {
+		return err
+	}
+
+	if r.NetworkID != "" {
+		return ds.ReleaseNetworkIP(tenantID, r.NetworkID, ip)
+	}
+
+	return ds.ReleaseTenantIP(tenantID, ip)
+}
@@ -1328,6 +1328,171 @@ func TestAllocate1024IPs(t *testing.T) {
 	testAllocateTenantIPs(t, 1024)
 }
 
+func TestDetachVolumeFailure(t *testing.T) {
+	newTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ds.GetWorkloads(newTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := addTestInstance(newTenant, wls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := types.Volume{
+		BlockDevice: storage.BlockDevice{ID: uuid.Generate().String()},
+		State:       types.Available,
+		TenantID:    newTenant.ID,
+		CreateTime:  time.Now(),
+	}
+
+	err = ds.AddBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := ds.CreateStorageAttachment(instance.ID, payloads.StorageResource{ID: data.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.SetAttachmentDetaching(a.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.DetachVolumeFailure(instance.ID, data.ID, payloads.DetachVolumeDetachFailure)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bd, err := ds.GetBlockDevice(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bd.State != types.InUse {
+		t.Fatalf("expected state: %s, got %s\n", types.InUse, bd.State)
+	}
+}
+
+func TestVolumeDetachedMultiAttach(t *testing.T) {
+	newTenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ds.GetWorkloads(newTenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var instances []*types.Instance
+	for i := 0; i < 2; i++ {
+		instance, err := addTestInstance(newTenant, wls[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		instances = append(instances, instance)
+	}
+
+	data := types.Volume{
+		BlockDevice: storage.BlockDevice{ID: uuid.Generate().String()},
+		State:       types.Available,
+		TenantID:    newTenant.ID,
+		CreateTime:  time.Now(),
+		MultiAttach: true,
+	}
+
+	err = ds.AddBlockDevice(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, instance := range instances {
+		volume := payloads.StorageResource{
+			ID:       data.ID,
+			ReadOnly: i == 1,
+			Shared:   true,
+		}
+		a, err := ds.CreateStorageAttachment(instance.ID, volume)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := types.ReadWrite
+		if volume.ReadOnly {
+			expected = types.ReadOnly
+		}
+		if a.Mode != expected {
+			t.Fatalf("expected mode: %s, got %s\n", expected, a.Mode)
+		}
+	}
+
+	// detaching one attachment leaves the volume in use so that the
+	// other attachment can still be detached.
+	attachments, err := ds.GetVolumeAttachments(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, a := range attachments {
+		err = ds.SetAttachmentDetaching(a.ID, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := types.InUse
+		if i == len(attachments)-1 {
+			expected = types.Detaching
+		}
+
+		bd, err := ds.GetBlockDevice(data.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bd.State != expected {
+			t.Fatalf("expected state: %s, got %s\n", expected, bd.State)
+		}
+	}
+
+	for i, instance := range instances {
+		err = ds.VolumeDetached(instance.ID, data.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := types.Detaching
+		if i == len(instances)-1 {
+			expected = types.Available
+		}
+
+		bd, err := ds.GetBlockDevice(data.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bd.State != expected {
+			t.Fatalf("expected state: %s, got %s\n", expected, bd.State)
+		}
+	}
+
+	attachments, err = ds.GetVolumeAttachments(data.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(attachments) != 0 {
+		t.Fatalf("expected no attachments, got %d\n", len(attachments))
+	}
+}
+
 func TestAddBlockDevice(t *testing.T) {
 	newTenant, err := addTestTenant()
 	if err != nil {
@@ -2093,6 +2258,53 @@ func TestAddExternalSubnet(t *testing.T) {
 	}
 }
 
+func TestAddExternalIPv6Subnet(t *testing.T) {
+	orig := types.Pool{
+		ID:   uuid.Generate().String(),
+		Name: "test",
+	}
+
+	err := ds.AddPool(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.AddExternalSubnet(orig.ID, "2001:DB8:0:1::0/120")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := ds.GetPool(orig.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pool.Subnets) != 1 || pool.Subnets[0].CIDR != "2001:db8:0:1::/120" {
+		t.Fatal("subnet not added correctly")
+	}
+
+	// IPv6 subnets have no broadcast address
+	if pool.TotalIPs != 255 || pool.Free != 255 {
+		t.Fatalf("expected 255 addresses, got %d", pool.TotalIPs)
+	}
+
+	err = ds.AddExternalSubnet(orig.ID, "2001:db8:0:2::/64")
+	if err != types.ErrSubnetTooLarge {
+		t.Fatal("too large subnet allowed")
+	}
+
+	err = ds.AddExternalSubnet(orig.ID, "2001:db8:0:3::/128")
+	if err != types.ErrSubnetTooSmall {
+		t.Fatal("too small subnet allowed")
+	}
+
+	// cleanup.
+	err = ds.DeletePool(orig.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestAddExternalIPs(t *testing.T) {
 	orig := types.Pool{
 		ID:   uuid.Generate().String(),
@@ -2482,6 +2694,107 @@ func TestGetMappedIPs(t *testing.T) {
 	}
 }
 
+func TestLoadBalancers(t *testing.T) {
+	orig := types.Pool{
+		ID:   uuid.Generate().String(),
+		Name: "test",
+	}
+
+	err := ds.AddPool(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	IPs := []string{"192.168.0.1"}
+	err = ds.AddExternalIPs(orig.ID, IPs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wls, err := ds.GetWorkloads(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := addTestInstance(tenant, wls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lb := types.LoadBalancer{
+		ID:       uuid.Generate().String(),
+		TenantID: tenant.ID,
+		Port:     80,
+		Protocol: "tcp",
+		Members: []types.LoadBalancerMember{
+			{InstanceID: instance.ID, IPAddress: instance.IPAddress, Port: 80},
+		},
+	}
+
+	lb, err = ds.AddLoadBalancer(orig.ID, lb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lb.VIP != IPs[0] || lb.PoolID != orig.ID {
+		t.Fatalf("Wrong virtual IP %s from pool %s", lb.VIP, lb.PoolID)
+	}
+
+	// the virtual IP cannot be mapped to an instance.
+	_, err = ds.MapExternalIP(orig.ID, instance.ID)
+	if err != types.ErrPoolEmpty {
+		t.Fatal("virtual IP mapped to an instance")
+	}
+
+	// nor removed from the pool.
+	pool, err := ds.GetPool(orig.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.DeleteExternalIP(pool.ID, pool.IPs[0].ID)
+	if err != types.ErrPoolNotEmpty {
+		t.Fatal("virtual IP removed from pool")
+	}
+
+	lb.Members = []types.LoadBalancerMember{}
+	err = ds.UpdateLoadBalancer(lb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lbs := ds.GetLoadBalancers(tenant.ID)
+	if len(lbs) != 1 || len(lbs[0].Members) != 0 {
+		t.Fatalf("GetLoadBalancers failed %v", lbs)
+	}
+
+	_, err = ds.GetLoadBalancer(uuid.Generate().String(), lb.ID)
+	if err != types.ErrLoadBalancerNotFound {
+		t.Fatal("found load balancer of another tenant")
+	}
+
+	err = ds.DeleteLoadBalancer(tenant.ID, lb.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.GetLoadBalancer(tenant.ID, lb.ID)
+	if err != types.ErrLoadBalancerNotFound {
+		t.Fatal("found deleted load balancer")
+	}
+
+	// cleanup.
+	err = ds.DeletePool(orig.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDeleteWorkload(t *testing.T) {
 	tenant, err := addTestTenant()
 	if err != nil {
@@ -2769,3 +3082,592 @@ func TestMain(m *testing.M) {
 
 	os.Exit(code)
 }
+
+func TestAddRemoveBackup(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := types.Backup{
+		ID:         uuid.Generate().String(),
+		TenantID:   tenant.ID,
+		VolumeID:   uuid.Generate().String(),
+		VolumeSize: 10,
+		State:      types.BackupCreating,
+		Name:       "test-backup-1",
+	}
+
+	err = ds.AddBackup(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.AddBackup(b)
+	if err != api.ErrAlreadyExists {
+		t.Fatal("Expected error when adding duplicate backup")
+	}
+
+	b.State = types.BackupAvailable
+	b.Size = 1024
+	b.Checksum = "abcdef"
+
+	err = ds.UpdateBackup(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := ds.GetBackup(b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(backup, b) {
+		t.Fatal("Backup retrieval by ID expected to match")
+	}
+
+	backups, err := ds.GetBackups(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 1 || backups[0].ID != b.ID {
+		t.Fatalf("Expected 1 backup for tenant, got %d", len(backups))
+	}
+
+	err = ds.DeleteBackup(b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.GetBackup(b.ID)
+	if err != types.ErrBackupNotFound {
+		t.Fatal("Expected error on retrieval of deleted backup")
+	}
+
+	err = ds.UpdateBackup(b)
+	if err != types.ErrBackupNotFound {
+		t.Fatal("Expected error on update of deleted backup")
+	}
+}
+
+func TestAddRemoveTenantNetwork(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := types.TenantNetwork{
+		ID:              uuid.Generate().String(),
+		TenantID:        tenant.ID,
+		Name:            "test-network",
+		CIDR:            "10.10.0.0/29",
+		Gateway:         "10.10.0.1",
+		AllocationStart: "10.10.0.1",
+		AllocationEnd:   "10.10.0.3",
+	}
+
+	err = ds.AddTenantNetwork(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.AddTenantNetwork(n)
+	if err != api.ErrAlreadyExists {
+		t.Fatal("Expected error when adding duplicate network")
+	}
+
+	overlap := n
+	overlap.ID = uuid.Generate().String()
+	overlap.CIDR = "10.10.0.0/24"
+	err = ds.AddTenantNetwork(overlap)
+	if err != types.ErrNetworkOverlap {
+		t.Fatal("Expected error when adding overlapping network")
+	}
+
+	network, err := ds.GetTenantNetwork(tenant.ID, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(network, n) {
+		t.Fatal("Network retrieval by ID expected to match")
+	}
+
+	networks, err := ds.GetTenantNetworks(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(networks) != 1 || networks[0].ID != n.ID {
+		t.Fatalf("Expected 1 network for tenant, got %d", len(networks))
+	}
+
+	// the gateway is in the allocation range but must not be assigned.
+	expected := []string{"10.10.0.2", "10.10.0.3"}
+	for _, e := range expected {
+		ip, err := ds.AllocateNetworkIP(tenant.ID, n.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ip.String() != e {
+			t.Fatalf("Expected %s to be allocated, got %s", e, ip)
+		}
+	}
+
+	_, err = ds.AllocateNetworkIP(tenant.ID, n.ID)
+	if err != types.ErrNetworkFull {
+		t.Fatal("Expected error when allocating from a full network")
+	}
+
+	err = ds.DeleteTenantNetwork(tenant.ID, n.ID)
+	if err != types.ErrNetworkInUse {
+		t.Fatal("Expected error when deleting a network in use")
+	}
+
+	for _, e := range expected {
+		err = ds.ReleaseNetworkIP(tenant.ID, n.ID, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = ds.DeleteTenantNetwork(tenant.ID, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.GetTenantNetwork(tenant.ID, n.ID)
+	if err != types.ErrNetworkNotFound {
+		t.Fatal("Expected error on retrieval of deleted network")
+	}
+}
+
+func TestAddRemoveNetworkPeering(t *testing.T) {
+	p := types.NetworkPeering{
+		ID:            uuid.Generate().String(),
+		TenantID:      uuid.Generate().String(),
+		NetworkID:     uuid.Generate().String(),
+		CIDR:          "10.20.0.0/24",
+		PeerTenantID:  uuid.Generate().String(),
+		PeerNetworkID: uuid.Generate().String(),
+		PeerCIDR:      "10.30.0.0/24",
+		Status:        types.PeeringPending,
+	}
+
+	err := ds.AddNetworkPeering(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same networks cannot be peered twice, in either direction.
+	reverse := types.NetworkPeering{
+		ID:            uuid.Generate().String(),
+		TenantID:      p.PeerTenantID,
+		NetworkID:     p.PeerNetworkID,
+		CIDR:          p.PeerCIDR,
+		PeerTenantID:  p.TenantID,
+		PeerNetworkID: p.NetworkID,
+		PeerCIDR:      p.CIDR,
+		Status:        types.PeeringPending,
+	}
+
+	err = ds.AddNetworkPeering(reverse)
+	if err != api.ErrAlreadyExists {
+		t.Fatal("Expected error when adding duplicate peering")
+	}
+
+	overlap := reverse
+	overlap.PeerNetworkID = uuid.Generate().String()
+	overlap.PeerCIDR = "10.30.0.128/25"
+	err = ds.AddNetworkPeering(overlap)
+	if err != types.ErrPeeringOverlap {
+		t.Fatal("Expected error when peering overlapping networks")
+	}
+
+	// a third network may not overlap a network already peered with
+	// the network it is peered with.
+	overlap.PeerCIDR = "10.20.0.0/16"
+	err = ds.AddNetworkPeering(overlap)
+	if err != types.ErrPeeringOverlap {
+		t.Fatal("Expected error when peering with overlapping peers")
+	}
+
+	p.Status = types.PeeringActive
+	err = ds.UpdateNetworkPeering(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peering, err := ds.GetNetworkPeering(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(peering, p) {
+		t.Fatal("Peering retrieval by ID expected to match")
+	}
+
+	found := false
+	for _, peering := range ds.GetNetworkPeerings() {
+		if peering.ID == p.ID {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatal("Peering not returned in list of peerings")
+	}
+
+	err = ds.DeleteNetworkPeering(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ds.GetNetworkPeering(p.ID)
+	if err != types.ErrNetworkPeeringNotFound {
+		t.Fatal("Expected error on retrieval of deleted peering")
+	}
+}
+
+func TestDualStackTenantNetwork(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := types.TenantNetwork{
+		ID:              uuid.Generate().String(),
+		TenantID:        tenant.ID,
+		Name:            "test-network",
+		CIDR:            "10.10.0.0/24",
+		Gateway:         "10.10.0.1",
+		AllocationStart: "10.10.0.2",
+		AllocationEnd:   "10.10.0.254",
+		IPv6CIDR:        "fd12:3456:789a:1::/64",
+		IPv6Gateway:     "fd12:3456:789a:1::1",
+		IPv6Mode:        types.IPv6SLAAC,
+	}
+
+	err = ds.AddTenantNetwork(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	overlap := n
+	overlap.ID = uuid.Generate().String()
+	overlap.CIDR = "10.20.0.0/24"
+	err = ds.AddTenantNetwork(overlap)
+	if err != types.ErrNetworkOverlap {
+		t.Fatal("Expected error when adding overlapping IPv6 prefix")
+	}
+
+	ip := ds.GetNICIPv6(tenant.ID, n.ID, "02:00:0a:0a:00:02")
+	if ip != "fd12:3456:789a:1:0:aff:fe0a:2" {
+		t.Fatalf("Unexpected IPv6 address %s", ip)
+	}
+
+	if ds.GetNICIPv6(tenant.ID, "", "02:00:0a:0a:00:02") != "" {
+		t.Fatal("Expected no IPv6 address for instance without network")
+	}
+
+	err = ds.DeleteTenantNetwork(tenant.ID, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimReserveTenantIP(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []string{"10.0.0.5", "172.16.5.1", "172.16.5.255", "172.32.0.2"}
+	for _, ip := range invalid {
+		_, _, err = ds.ClaimTenantIP(tenant.ID, ip)
+		if err != types.ErrInvalidIP {
+			t.Fatalf("Expected error when claiming %s", ip)
+		}
+	}
+
+	ip, reserved, err := ds.ClaimTenantIP(tenant.ID, "172.16.5.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ip.String() != "172.16.5.10" || reserved {
+		t.Fatalf("Unexpected claimed address %s (reserved %v)", ip, reserved)
+	}
+
+	_, _, err = ds.ClaimTenantIP(tenant.ID, "172.16.5.10")
+	if err != types.ErrAddressInUse {
+		t.Fatal("Expected error when claiming an address in use")
+	}
+
+	i := types.Instance{
+		ID:        uuid.Generate().String(),
+		TenantID:  tenant.ID,
+		IPAddress: "172.16.5.10",
+		RetainIP:  true,
+	}
+
+	err = ds.ReleaseInstanceIP(&i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reservedIPs, err := ds.GetReservedIPs(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reservedIPs) != 1 || reservedIPs[0].IPAddress != i.IPAddress ||
+		reservedIPs[0].InstanceID != i.ID {
+		t.Fatalf("Unexpected reserved addresses %v", reservedIPs)
+	}
+
+	// the reserved address must not be handed out to other instances.
+	ip, err = ds.AllocateTenantIP(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ip.String() == i.IPAddress {
+		t.Fatal("Reserved address allocated")
+	}
+
+	err = ds.ReleaseTenantIP(tenant.ID, ip.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, reserved, err = ds.ClaimTenantIP(tenant.ID, i.IPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reserved {
+		t.Fatal("Expected reserved address to be claimed")
+	}
+
+	reservedIPs, err = ds.GetReservedIPs(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reservedIPs) != 0 {
+		t.Fatalf("Unexpected reserved addresses %v", reservedIPs)
+	}
+
+	err = ds.ReleaseInstanceIP(&i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.ReleaseReservedIP(tenant.ID, i.IPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.ReleaseReservedIP(tenant.ID, i.IPAddress)
+	if err != types.ErrAddressNotFound {
+		t.Fatal("Expected error when releasing an unreserved address")
+	}
+
+	_, reserved, err = ds.ClaimTenantIP(tenant.ID, i.IPAddress)
+	if err != nil || reserved {
+		t.Fatalf("Expected released address to be claimed: %v", err)
+	}
+
+	err = ds.ReleaseTenantIP(tenant.ID, i.IPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Checks that releasing the last claimed address of a subnet removes that
+// subnet, and only that subnet, from the tenant.
+func TestReleaseClaimedTenantIP(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimed := []string{"172.16.5.10", "172.16.6.10"}
+	for _, ip := range claimed {
+		_, _, err = ds.ClaimTenantIP(tenant.ID, ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = ds.ReleaseTenantIP(tenant.ID, claimed[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTenant, err := ds.getTenant(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := int(binary.BigEndian.Uint16(net.ParseIP(claimed[1]).To4()[1:3]))
+	if len(newTenant.subnets) != 1 || newTenant.subnets[0] != expected {
+		t.Fatalf("Unexpected subnets %v, expected [%d]", newTenant.subnets, expected)
+	}
+
+	err = ds.ReleaseTenantIP(tenant.ID, claimed[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClaimNetworkIP(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := types.TenantNetwork{
+		ID:              uuid.Generate().String(),
+		TenantID:        tenant.ID,
+		Name:            "test-network",
+		CIDR:            "10.20.0.0/24",
+		Gateway:         "10.20.0.1",
+		AllocationStart: "10.20.0.1",
+		AllocationEnd:   "10.20.0.100",
+	}
+
+	err = ds.AddTenantNetwork(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []string{"10.20.0.1", "10.20.0.101", "172.16.0.2"}
+	for _, ip := range invalid {
+		_, _, err = ds.ClaimNetworkIP(tenant.ID, n.ID, ip)
+		if err != types.ErrInvalidIP {
+			t.Fatalf("Expected error when claiming %s", ip)
+		}
+	}
+
+	_, _, err = ds.ClaimNetworkIP(tenant.ID, n.ID, "10.20.0.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = ds.ClaimNetworkIP(tenant.ID, n.ID, "10.20.0.50")
+	if err != types.ErrAddressInUse {
+		t.Fatal("Expected error when claiming an address in use")
+	}
+
+	i := types.Instance{
+		ID:        uuid.Generate().String(),
+		TenantID:  tenant.ID,
+		NetworkID: n.ID,
+		IPAddress: "10.20.0.50",
+		RetainIP:  true,
+	}
+
+	err = ds.ReleaseInstanceIP(&i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.DeleteTenantNetwork(tenant.ID, n.ID)
+	if err != types.ErrNetworkInUse {
+		t.Fatal("Expected error when deleting a network with reserved addresses")
+	}
+
+	_, reserved, err := ds.ClaimNetworkIP(tenant.ID, n.ID, "10.20.0.50")
+	if err != nil || !reserved {
+		t.Fatalf("Expected reserved address to be claimed: %v", err)
+	}
+
+	err = ds.ReleaseNetworkIP(tenant.ID, n.ID, "10.20.0.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.DeleteTenantNetwork(tenant.ID, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingCNCIController never brings up the subnets it is asked for.
+type failingCNCIController struct {
+	types.CNCIController
+}
+
+func (c failingCNCIController) WaitForActive(subnet int) error {
+	return fmt.Errorf("CNCI for subnet %d failed", subnet)
+}
+
+func (c failingCNCIController) ScheduleRemoveSubnet(subnet int) error {
+	return nil
+}
+
+func TestClaimTenantIPCNCIFailure(t *testing.T) {
+	tenant, err := addTestTenant()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := types.Instance{
+		ID:        uuid.Generate().String(),
+		TenantID:  tenant.ID,
+		IPAddress: "172.16.6.20",
+		RetainIP:  true,
+	}
+
+	_, _, err = ds.ClaimTenantIP(tenant.ID, i.IPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ds.ReleaseInstanceIP(&i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds.tenants[tenant.ID].CNCIctrl = failingCNCIController{}
+
+	for _, ip := range []string{"172.16.6.10", i.IPAddress} {
+		_, _, err = ds.ClaimTenantIP(tenant.ID, ip)
+		if err == nil {
+			t.Fatalf("Expected error when claiming %s without a CNCI", ip)
+		}
+	}
+
+	reserved, err := ds.GetReservedIPs(tenant.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reserved) != 1 || reserved[0].IPAddress != i.IPAddress {
+		t.Fatalf("Expected reservation of %s to be reinstated", i.IPAddress)
+	}
+
+	ds.tenants[tenant.ID].CNCIctrl = nil
+
+	ip, reused, err := ds.ClaimTenantIP(tenant.ID, "172.16.6.10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ip.String() != "172.16.6.10" || reused {
+		t.Fatalf("Unexpected claimed address %s (reserved %v)", ip, reused)
+	}
+
+	_, reused, err = ds.ClaimTenantIP(tenant.ID, i.IPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reused {
+		t.Fatalf("Expected reservation of %s to be claimed", i.IPAddress)
+	}
+}
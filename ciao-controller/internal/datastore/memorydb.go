@@ -81,13 +81,17 @@ func (db *MemoryDB) getEventLog() ([]*types.LogEntry, error) {
 func (db *MemoryDB) addTenant(id string, config types.TenantConfig) error {
 	t := &tenant{
 		Tenant: types.Tenant{
-			ID:         id,
-			Name:       config.Name,
-			SubnetBits: config.SubnetBits,
+			ID:            id,
+			Name:          config.Name,
+			SubnetBits:    config.SubnetBits,
+			DNSDomain:     config.DNSDomain,
+			DNSForwarders: config.DNSForwarders,
 		},
-		network:   make(map[int]map[int]bool),
-		instances: make(map[string]*types.Instance),
-		devices:   make(map[string]types.Volume),
+		network:     make(map[int]map[int]bool),
+		networks:    make(map[string]*tenantNetwork),
+		reservedIPs: make(map[string]types.ReservedIP),
+		instances:   make(map[string]*types.Instance),
+		devices:     make(map[string]types.Volume),
 	}
 	db.tenants[id] = t
 	return nil
@@ -217,6 +221,18 @@ func (db *MemoryDB) getMappedIPs() map[string]types.MappedIP {
 	return make(map[string]types.MappedIP)
 }
 
+func (db *MemoryDB) updateLoadBalancer(lb types.LoadBalancer) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteLoadBalancer(ID string) error {
+	return nil
+}
+
+func (db *MemoryDB) getLoadBalancers() ([]types.LoadBalancer, error) {
+	return []types.LoadBalancer{}, nil
+}
+
 func (db *MemoryDB) updateWorkload(wl types.Workload) error {
 	return nil
 }
@@ -257,3 +273,51 @@ func (db *MemoryDB) updateImage(i types.Image) error {
 func (db *MemoryDB) deleteImage(ID string) error {
 	return nil
 }
+
+func (db *MemoryDB) getBackups() ([]types.Backup, error) {
+	return []types.Backup{}, nil
+}
+
+func (db *MemoryDB) updateBackup(b types.Backup) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteBackup(ID string) error {
+	return nil
+}
+
+func (db *MemoryDB) getNetworkPeerings() ([]types.NetworkPeering, error) {
+	return []types.NetworkPeering{}, nil
+}
+
+func (db *MemoryDB) updateNetworkPeering(p types.NetworkPeering) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteNetworkPeering(ID string) error {
+	return nil
+}
+
+func (db *MemoryDB) addTenantNetwork(n types.TenantNetwork) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteTenantNetwork(ID string) error {
+	return nil
+}
+
+func (db *MemoryDB) claimNetworkIP(networkID string, ip string) error {
+	return nil
+}
+
+func (db *MemoryDB) releaseNetworkIP(networkID string, ip string) error {
+	return nil
+}
+
+func (db *MemoryDB) addReservedIP(r types.ReservedIP) error {
+	return nil
+}
+
+func (db *MemoryDB) deleteReservedIP(tenantID string, ip string) error {
+	return nil
+}
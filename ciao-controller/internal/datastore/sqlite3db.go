@@ -17,6 +17,7 @@ package datastore
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/url"
@@ -69,6 +70,59 @@ func (d namedData) DB() *sql.DB {
 	return d.db
 }
 
+// tableColumn describes a column added to a table after the table was
+// first released.  def is the type of the column and, unless NULL is
+// acceptable, the default value of the column in the rows written by
+// older versions of the controller.
+type tableColumn struct {
+	name string
+	def  string
+}
+
+// AddColumns upgrades a table created by an older version of the
+// controller by adding the columns it is missing.  Columns are added in
+// order, so they must be listed in the order in which they appear in the
+// CREATE TABLE statement.
+func (d namedData) AddColumns(columns []tableColumn) error {
+	rows, err := d.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", d.name))
+	if err != nil {
+		return errors.Wrapf(err, "error getting columns of %s", d.name)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defValue sql.NullString
+
+		err = rows.Scan(&cid, &name, &colType, &notNull, &defValue, &pk)
+		if err != nil {
+			return errors.Wrapf(err, "error getting columns of %s", d.name)
+		}
+
+		existing[name] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return errors.Wrapf(err, "error getting columns of %s", d.name)
+	}
+
+	for _, c := range columns {
+		if existing[c.name] {
+			continue
+		}
+
+		cmd := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", d.name, c.name, c.def)
+		err = d.ds.exec(d.db, cmd)
+		if err != nil {
+			return errors.Wrapf(err, "error adding column %s to %s", c.name, d.name)
+		}
+	}
+
+	return nil
+}
+
 type logData struct {
 	namedData
 }
@@ -121,12 +175,30 @@ func (d instanceData) Init() error {
 		create_time DATETIME,
 		name string,
 		cnci int,
+		network_id string,
+		retain_ip int,
+		cnci_standby int,
+		metadata text,
+		user_data text,
+		suspended int,
 		foreign key(tenant_id) references tenants(id),
 		foreign key(workload_id) references workload_template(id),
 		unique(tenant_id, ip, mac_address)
 		);`
 
-	return d.ds.exec(d.db, cmd)
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"network_id", "string DEFAULT ''"},
+		{"retain_ip", "int DEFAULT 0"},
+		{"cnci_standby", "int DEFAULT 0"},
+		{"metadata", "text DEFAULT ''"},
+		{"user_data", "text DEFAULT ''"},
+		{"suspended", "int DEFAULT 0"},
+	})
 }
 
 // Volume Data
@@ -145,10 +217,18 @@ func (d blockData) Init() error {
 		name string,
 		description string,
 		internal int,
+		multiattach int,
 		foreign key(tenant_id) references tenants(id)
 		);`
 
-	return d.ds.exec(d.db, cmd)
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"multiattach", "int DEFAULT 0"},
+	})
 }
 
 type attachments struct {
@@ -163,11 +243,19 @@ func (d attachments) Init() error {
 		block_id string,
 		ephemeral int,
 		boot int,
+		mode string,
 		foreign key(instance_id) references instances(id),
 		foreign key(block_id) references block_data(id)
 		);`
 
-	return d.ds.exec(d.db, cmd)
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"mode", "string DEFAULT 'rw'"},
+	})
 }
 
 // workload storage resources
@@ -204,10 +292,20 @@ func (d tenantData) Init() error {
 		(
 		id varchar(32) primary key,
 		name text,
-		subnet_bits int
+		subnet_bits int,
+		dns_domain text,
+		dns_forwarders text
 		);`
 
-	return d.ds.exec(d.db, cmd)
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"dns_domain", "text DEFAULT ''"},
+		{"dns_forwarders", "text DEFAULT ''"},
+	})
 }
 
 // workload resources
@@ -247,10 +345,18 @@ func (d workloadTemplateData) Init() error {
 		vm_type text,
 		image_name text,
 		internal integer,
+		template integer DEFAULT 0,
 		foreign key(tenant_id) references tenants(id)
 		);`
 
-	return d.ds.exec(d.db, cmd)
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"template", "integer DEFAULT 0"},
+	})
 }
 
 // statistics
@@ -291,10 +397,36 @@ func (d instanceStatisticsData) Init() error {
 			node_id varchar(32),
 			ssh_ip string,
 			ssh_port int,
+			block_read_bytes int,
+			block_write_bytes int,
+			block_read_ops int,
+			block_write_ops int,
+			net_rx_bytes int,
+			net_rx_packets int,
+			net_rx_dropped int,
+			net_tx_bytes int,
+			net_tx_packets int,
+			net_tx_dropped int,
 			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
 		);`
 
-	return d.ds.exec(d.db, cmd)
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"block_read_bytes", "int"},
+		{"block_write_bytes", "int"},
+		{"block_read_ops", "int"},
+		{"block_write_ops", "int"},
+		{"net_rx_bytes", "int"},
+		{"net_rx_packets", "int"},
+		{"net_rx_dropped", "int"},
+		{"net_tx_bytes", "int"},
+		{"net_tx_packets", "int"},
+		{"net_tx_dropped", "int"},
+	})
 }
 
 type frameStatisticsData struct {
@@ -396,6 +528,49 @@ func (d mappedIPData) Init() error {
 	return d.ds.exec(d.db, cmd)
 }
 
+type loadBalancerData struct {
+	namedData
+}
+
+func (d loadBalancerData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS load_balancers
+		(
+			id string primary key,
+			tenant_id string,
+			name string,
+			vip string,
+			pool_id varchar(32),
+			subnet string,
+			port int,
+			protocol string,
+			check_interval int,
+			check_timeout int,
+			check_retries int,
+			check_path string,
+			createtime DATETIME,
+			foreign key(tenant_id) references tenants(id)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type loadBalancerMemberData struct {
+	namedData
+}
+
+func (d loadBalancerMemberData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS load_balancer_members
+		(
+			load_balancer_id string,
+			instance_id string,
+			ip string,
+			port int,
+			foreign key(load_balancer_id) references load_balancers(id)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
 type quotaData struct {
 	namedData
 }
@@ -431,6 +606,139 @@ func (d imageData) Init() error {
 	return d.ds.exec(d.db, cmd)
 }
 
+type backupData struct {
+	namedData
+}
+
+func (d backupData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS backups
+		(
+			id string primary key,
+			tenant_id string,
+			volume_id string,
+			volume_size integer,
+			state string,
+			createtime DATETIME,
+			name string,
+			description string,
+			size integer,
+			checksum string,
+			foreign key(tenant_id) references tenants(id)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type networkData struct {
+	namedData
+}
+
+func (d networkData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS networks
+		(
+			id string primary key,
+			tenant_id string,
+			name string,
+			cidr string,
+			gateway string,
+			allocation_start string,
+			allocation_end string,
+			ipv6_cidr string,
+			ipv6_gateway string,
+			ipv6_mode string,
+			createtime DATETIME,
+			foreign key(tenant_id) references tenants(id)
+		);`
+
+	err := d.ds.exec(d.db, cmd)
+	if err != nil {
+		return err
+	}
+
+	return d.AddColumns([]tableColumn{
+		{"ipv6_cidr", "string DEFAULT ''"},
+		{"ipv6_gateway", "string DEFAULT ''"},
+		{"ipv6_mode", "string DEFAULT ''"},
+	})
+}
+
+type networkPeeringData struct {
+	namedData
+}
+
+func (d networkPeeringData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS network_peerings
+		(
+			id string primary key,
+			name string,
+			tenant_id string,
+			network_id string,
+			cidr string,
+			peer_tenant_id string,
+			peer_network_id string,
+			peer_cidr string,
+			status string,
+			createtime DATETIME
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type networkAddressData struct {
+	namedData
+}
+
+func (d networkAddressData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS network_addresses
+		(
+			network_id string,
+			ip string,
+			foreign key(network_id) references networks(id),
+			unique(network_id, ip)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type reservedIPData struct {
+	namedData
+}
+
+func (d reservedIPData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS reserved_ips
+		(
+			tenant_id string,
+			ip string,
+			network_id string,
+			instance_id string,
+			createtime DATETIME,
+			foreign key(tenant_id) references tenants(id),
+			unique(tenant_id, ip)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
+type instanceNICData struct {
+	namedData
+}
+
+func (d instanceNICData) Init() error {
+	cmd := `CREATE TABLE IF NOT EXISTS instance_nics
+		(
+			instance_id string,
+			nic_index integer,
+			network_id string,
+			mac_address string,
+			vnic_uuid string,
+			ip string,
+			foreign key(instance_id) references instances(id),
+			unique(instance_id, nic_index)
+		);`
+
+	return d.ds.exec(d.db, cmd)
+}
+
 func (ds *sqliteDB) exec(db *sql.DB, cmd string) error {
 	glog.V(2).Info("exec: ", cmd)
 
@@ -528,8 +836,16 @@ func (ds *sqliteDB) init(config Config) error {
 		subnetPoolData{namedData{ds: ds, name: "subnet_pool", db: ds.db}},
 		addressData{namedData{ds: ds, name: "address_pool", db: ds.db}},
 		mappedIPData{namedData{ds: ds, name: "mapped_ips", db: ds.db}},
+		loadBalancerData{namedData{ds: ds, name: "load_balancers", db: ds.db}},
+		loadBalancerMemberData{namedData{ds: ds, name: "load_balancer_members", db: ds.db}},
 		quotaData{namedData{ds: ds, name: "quotas", db: ds.db}},
 		imageData{namedData{ds: ds, name: "images", db: ds.db}},
+		backupData{namedData{ds: ds, name: "backups", db: ds.db}},
+		networkData{namedData{ds: ds, name: "networks", db: ds.db}},
+		networkAddressData{namedData{ds: ds, name: "network_addresses", db: ds.db}},
+		networkPeeringData{namedData{ds: ds, name: "network_peerings", db: ds.db}},
+		instanceNICData{namedData{ds: ds, name: "instance_nics", db: ds.db}},
+		reservedIPData{namedData{ds: ds, name: "reserved_ips", db: ds.db}},
 	}
 
 	ds.workloadsPath = config.InitWorkloadsPath
@@ -735,15 +1051,29 @@ func (ds *sqliteDB) addTenant(ID string, config types.TenantConfig) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	err := ds.create("tenants", ID, config.Name, config.SubnetBits)
+	db := ds.getTableDB("tenants")
+
+	_, err := db.Exec("INSERT INTO tenants (id, name, subnet_bits, dns_domain, dns_forwarders) VALUES (?, ?, ?, ?, ?)",
+		ID, config.Name, config.SubnetBits, config.DNSDomain, strings.Join(config.DNSForwarders, ","))
 
 	return err
 }
 
+// splitDNSForwarders converts the comma separated list of upstream DNS
+// servers stored in the tenants table back into a slice.
+func splitDNSForwarders(forwarders string) []string {
+	if forwarders == "" {
+		return nil
+	}
+	return strings.Split(forwarders, ",")
+}
+
 func (ds *sqliteDB) getTenant(ID string) (*tenant, error) {
 	query := `SELECT	tenants.id,
 				tenants.name,
-				tenants.subnet_bits
+				tenants.subnet_bits,
+				IFNULL(tenants.dns_domain, ''),
+				IFNULL(tenants.dns_forwarders, '')
 		  FROM tenants
 		  WHERE tenants.id = ?`
 
@@ -753,7 +1083,8 @@ func (ds *sqliteDB) getTenant(ID string) (*tenant, error) {
 
 	t := &tenant{}
 
-	err := row.Scan(&t.ID, &t.Name, &t.SubnetBits)
+	var forwarders string
+	err := row.Scan(&t.ID, &t.Name, &t.SubnetBits, &t.DNSDomain, &forwarders)
 	if err != nil {
 		glog.Warning("unable to retrieve tenant from tenants")
 
@@ -765,6 +1096,8 @@ func (ds *sqliteDB) getTenant(ID string) (*tenant, error) {
 		return nil, err
 	}
 
+	t.DNSForwarders = splitDNSForwarders(forwarders)
+
 	// for these items below, its ok to get err returned
 	// because a tenant could simply not have used any
 	// resources or networks yet.
@@ -773,6 +1106,16 @@ func (ds *sqliteDB) getTenant(ID string) (*tenant, error) {
 		glog.V(2).Info(err)
 	}
 
+	err = ds.getNetworks(t)
+	if err != nil {
+		glog.V(2).Info(err)
+	}
+
+	err = ds.getReservedIPs(t)
+	if err != nil {
+		glog.V(2).Info(err)
+	}
+
 	t.instances, err = ds.getTenantInstances(t.ID)
 	if err != nil {
 		glog.V(2).Info(err)
@@ -798,7 +1141,8 @@ func (ds *sqliteDB) getTenantWorkloads(tenantID string) ([]types.Workload, error
 			 description,
 			 fw_type,
 			 vm_type,
-			 image_name
+			 image_name,
+			 template
 		  FROM workload_template
 		  WHERE internal = 0 AND tenant_id = ?`
 
@@ -814,7 +1158,7 @@ func (ds *sqliteDB) getTenantWorkloads(tenantID string) ([]types.Workload, error
 
 		var VMType string
 
-		err = rows.Scan(&wl.ID, &wl.TenantID, &wl.Description, &wl.FWType, &VMType, &wl.ImageName)
+		err = rows.Scan(&wl.ID, &wl.TenantID, &wl.Description, &wl.FWType, &VMType, &wl.ImageName, &wl.Template)
 		if err != nil {
 			return nil, err
 		}
@@ -897,7 +1241,7 @@ func (ds *sqliteDB) updateWorkload(w types.Workload) error {
 			return err
 		}
 
-		_, err = tx.Exec("INSERT INTO workload_template (id, tenant_id, description, filename, fw_type, vm_type, image_name, internal) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", w.ID, w.TenantID, w.Description, filename, w.FWType, string(w.VMType), w.ImageName, false)
+		_, err = tx.Exec("INSERT INTO workload_template (id, tenant_id, description, filename, fw_type, vm_type, image_name, internal, template) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", w.ID, w.TenantID, w.Description, filename, w.FWType, string(w.VMType), w.ImageName, false, w.Template)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -960,7 +1304,9 @@ func (ds *sqliteDB) getTenants() ([]*tenant, error) {
 
 	query := `SELECT	tenants.id,
 				tenants.name,
-				tenants.subnet_bits
+				tenants.subnet_bits,
+				IFNULL(tenants.dns_domain, ''),
+				IFNULL(tenants.dns_forwarders, '')
 		  FROM tenants `
 
 	rows, err := db.Query(query)
@@ -972,13 +1318,16 @@ func (ds *sqliteDB) getTenants() ([]*tenant, error) {
 	for rows.Next() {
 		var id sql.NullString
 		var name sql.NullString
+		var forwarders string
 
 		t := new(tenant)
-		err = rows.Scan(&id, &name, &t.SubnetBits)
+		err = rows.Scan(&id, &name, &t.SubnetBits, &t.DNSDomain, &forwarders)
 		if err != nil {
 			return nil, err
 		}
 
+		t.DNSForwarders = splitDNSForwarders(forwarders)
+
 		if id.Valid {
 			t.ID = id.String
 		}
@@ -992,6 +1341,16 @@ func (ds *sqliteDB) getTenants() ([]*tenant, error) {
 			return nil, err
 		}
 
+		err = ds.getNetworks(t)
+		if err != nil {
+			return nil, err
+		}
+
+		err = ds.getReservedIPs(t)
+		if err != nil {
+			return nil, err
+		}
+
 		t.instances, err = ds.getTenantInstances(t.ID)
 		if err != nil {
 			return nil, err
@@ -1038,6 +1397,174 @@ func (ds *sqliteDB) releaseTenantIP(tenantID string, subnetInt int, rest int) er
 	return err
 }
 
+// getNetworks retrieves the networks defined by a tenant along with the
+// addresses allocated from them.
+func (ds *sqliteDB) getNetworks(tenant *tenant) error {
+	tenant.networks = make(map[string]*tenantNetwork)
+
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	db := ds.getTableDB("networks")
+
+	query := `SELECT id, tenant_id, name, cidr, gateway, allocation_start, allocation_end,
+		  IFNULL(ipv6_cidr, ''), IFNULL(ipv6_gateway, ''), IFNULL(ipv6_mode, ''), createtime
+		  FROM networks
+		  WHERE tenant_id = ?`
+
+	rows, err := db.Query(query, tenant.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		n := &tenantNetwork{
+			addresses: make(map[string]bool),
+		}
+
+		err = rows.Scan(&n.ID, &n.TenantID, &n.Name, &n.CIDR, &n.Gateway, &n.AllocationStart, &n.AllocationEnd, &n.IPv6CIDR, &n.IPv6Gateway, &n.IPv6Mode, &n.CreateTime)
+		if err != nil {
+			return err
+		}
+
+		tenant.networks[n.ID] = n
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	query = `SELECT network_addresses.network_id, network_addresses.ip
+		 FROM network_addresses
+		 JOIN networks
+		 ON network_addresses.network_id = networks.id
+		 WHERE networks.tenant_id = ?`
+
+	addrRows, err := db.Query(query, tenant.ID)
+	if err != nil {
+		return err
+	}
+	defer addrRows.Close()
+
+	for addrRows.Next() {
+		var networkID, ip string
+
+		err = addrRows.Scan(&networkID, &ip)
+		if err != nil {
+			return err
+		}
+
+		if n, ok := tenant.networks[networkID]; ok {
+			n.addresses[ip] = true
+		}
+	}
+
+	return addrRows.Err()
+}
+
+func (ds *sqliteDB) addTenantNetwork(n types.TenantNetwork) error {
+	query := `INSERT INTO networks (id, tenant_id, name, cidr, gateway, allocation_start, allocation_end, ipv6_cidr, ipv6_gateway, ipv6_mode, createtime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	db := ds.getTableDB("networks")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, n.ID, n.TenantID, n.Name, n.CIDR, n.Gateway, n.AllocationStart, n.AllocationEnd, n.IPv6CIDR, n.IPv6Gateway, n.IPv6Mode, n.CreateTime)
+
+	return errors.Wrap(err, "Error adding network to database")
+}
+
+func (ds *sqliteDB) deleteTenantNetwork(ID string) error {
+	query := `DELETE FROM networks WHERE id = ?`
+
+	db := ds.getTableDB("networks")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, ID)
+
+	return errors.Wrap(err, "Error deleting network from database")
+}
+
+func (ds *sqliteDB) claimNetworkIP(networkID string, ip string) error {
+	db := ds.getTableDB("network_addresses")
+
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec("INSERT INTO network_addresses VALUES(?, ?)", networkID, ip)
+
+	return err
+}
+
+func (ds *sqliteDB) releaseNetworkIP(networkID string, ip string) error {
+	db := ds.getTableDB("network_addresses")
+
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec("DELETE FROM network_addresses WHERE network_id = ? AND ip = ?", networkID, ip)
+
+	return err
+}
+
+func (ds *sqliteDB) getReservedIPs(tenant *tenant) error {
+	tenant.reservedIPs = make(map[string]types.ReservedIP)
+
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	db := ds.getTableDB("reserved_ips")
+
+	query := `SELECT tenant_id, ip, network_id, instance_id, createtime
+		  FROM reserved_ips
+		  WHERE tenant_id = ?`
+
+	rows, err := db.Query(query, tenant.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.ReservedIP
+
+		err = rows.Scan(&r.TenantID, &r.IPAddress, &r.NetworkID, &r.InstanceID, &r.CreateTime)
+		if err != nil {
+			return err
+		}
+
+		tenant.reservedIPs[r.IPAddress] = r
+	}
+
+	return rows.Err()
+}
+
+func (ds *sqliteDB) addReservedIP(r types.ReservedIP) error {
+	query := `INSERT INTO reserved_ips (tenant_id, ip, network_id, instance_id, createtime) VALUES (?, ?, ?, ?, ?)`
+
+	db := ds.getTableDB("reserved_ips")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, r.TenantID, r.IPAddress, r.NetworkID, r.InstanceID, r.CreateTime)
+
+	return errors.Wrap(err, "Error adding reserved IP to database")
+}
+
+func (ds *sqliteDB) deleteReservedIP(tenantID string, ip string) error {
+	query := `DELETE FROM reserved_ips WHERE tenant_id = ? AND ip = ?`
+
+	db := ds.getTableDB("reserved_ips")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, tenantID, ip)
+
+	return errors.Wrap(err, "Error deleting reserved IP from database")
+}
+
 func (ds *sqliteDB) getTenantNetwork(tenant *tenant) error {
 	tenant.network = make(map[int]map[int]bool)
 
@@ -1089,7 +1616,8 @@ func (ds *sqliteDB) updateTenant(tenant *types.Tenant) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("UPDATE tenants SET name = ?, subnet_bits = ? WHERE id = ?", tenant.Name, tenant.SubnetBits, tenant.ID)
+	_, err := db.Exec("UPDATE tenants SET name = ?, subnet_bits = ?, dns_domain = ?, dns_forwarders = ? WHERE id = ?",
+		tenant.Name, tenant.SubnetBits, tenant.DNSDomain, strings.Join(tenant.DNSForwarders, ","), tenant.ID)
 
 	return err
 }
@@ -1112,6 +1640,26 @@ func (ds *sqliteDB) deleteTenant(tenantID string) error {
 		return err
 	}
 
+	// and any addresses reserved by the tenant
+	_, err = tx.Exec("DELETE FROM reserved_ips WHERE tenant_id = ?", tenantID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// and any networks defined by the tenant
+	_, err = tx.Exec("DELETE FROM network_addresses WHERE network_id IN (SELECT id FROM networks WHERE tenant_id = ?)", tenantID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM networks WHERE tenant_id = ?", tenantID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	_, err = tx.Exec("DELETE FROM tenants WHERE id = ?", tenantID)
 	if err != nil {
 		tx.Rollback()
@@ -1139,7 +1687,17 @@ func (ds *sqliteDB) getInstances() ([]*types.Instance, error) {
 			instance_statistics.state,
 			instance_statistics.ssh_ip,
 			instance_statistics.ssh_port,
-			instance_statistics.node_id
+			instance_statistics.node_id,
+			instance_statistics.block_read_bytes,
+			instance_statistics.block_write_bytes,
+			instance_statistics.block_read_ops,
+			instance_statistics.block_write_ops,
+			instance_statistics.net_rx_bytes,
+			instance_statistics.net_rx_packets,
+			instance_statistics.net_rx_dropped,
+			instance_statistics.net_tx_bytes,
+			instance_statistics.net_tx_packets,
+			instance_statistics.net_tx_dropped
 		FROM instance_statistics
 		GROUP BY instance_statistics.instance_id
 	)
@@ -1155,7 +1713,23 @@ func (ds *sqliteDB) getInstances() ([]*types.Instance, error) {
 		subnet,
 		ip,
 		name,
-		cnci
+		cnci,
+		IFNULL(network_id, "") AS network_id,
+		IFNULL(retain_ip, 0) AS retain_ip,
+		IFNULL(cnci_standby, 0) AS cnci_standby,
+		IFNULL(metadata, "") AS metadata,
+		IFNULL(user_data, "") AS user_data,
+		IFNULL(suspended, 0) AS suspended,
+		latest.block_read_bytes,
+		latest.block_write_bytes,
+		latest.block_read_ops,
+		latest.block_write_ops,
+		latest.net_rx_bytes,
+		latest.net_rx_packets,
+		latest.net_rx_dropped,
+		latest.net_tx_bytes,
+		latest.net_tx_packets,
+		latest.net_tx_dropped
 	FROM instances
 	LEFT JOIN latest
 	ON instances.id = latest.instance_id
@@ -1171,15 +1745,25 @@ func (ds *sqliteDB) getInstances() ([]*types.Instance, error) {
 		var i types.Instance
 
 		var sshPort sql.NullInt64
+		var metadata string
+		ioStats := make([]sql.NullInt64, 10)
+
+		err = rows.Scan(&i.ID, &i.TenantID, &i.State, &i.WorkloadID, &i.SSHIP, &sshPort, &i.NodeID, &i.MACAddress, &i.VnicUUID, &i.Subnet, &i.IPAddress, &i.Name, &i.CNCI, &i.NetworkID, &i.RetainIP, &i.CNCIStandby, &metadata, &i.UserData, &i.Suspended,
+			&ioStats[0], &ioStats[1], &ioStats[2], &ioStats[3], &ioStats[4], &ioStats[5], &ioStats[6], &ioStats[7], &ioStats[8], &ioStats[9])
+		if err != nil {
+			return nil, err
+		}
+
+		if sshPort.Valid {
+			i.SSHPort = int(sshPort.Int64)
+		}
 
-		err = rows.Scan(&i.ID, &i.TenantID, &i.State, &i.WorkloadID, &i.SSHIP, &sshPort, &i.NodeID, &i.MACAddress, &i.VnicUUID, &i.Subnet, &i.IPAddress, &i.Name, &i.CNCI)
+		i.Metadata, err = decodeInstanceMetadata(metadata)
 		if err != nil {
 			return nil, err
 		}
 
-		if sshPort.Valid {
-			i.SSHPort = int(sshPort.Int64)
-		}
+		i.BlockIO, i.NetworkIO = ioStatsFromColumns(ioStats)
 
 		instances = append(instances, &i)
 	}
@@ -1188,9 +1772,56 @@ func (ds *sqliteDB) getInstances() ([]*types.Instance, error) {
 		return nil, err
 	}
 
+	nics, err := ds.getInstanceNICs("", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range instances {
+		i.AdditionalNICs = nics[i.ID]
+	}
+
 	return instances, nil
 }
 
+// getInstanceNICs returns the additional NICs of the instances matching
+// the optional where clause, indexed by instance ID.  The caller must hold
+// the database lock.
+func (ds *sqliteDB) getInstanceNICs(where string, args []interface{}) (map[string][]types.InstanceNIC, error) {
+	db := ds.getTableDB("instance_nics")
+
+	query := `SELECT instance_nics.instance_id,
+			 instance_nics.network_id,
+			 instance_nics.mac_address,
+			 instance_nics.vnic_uuid,
+			 instance_nics.ip
+		  FROM instance_nics
+		  JOIN instances
+		  ON instance_nics.instance_id = instances.id ` + where + `
+		  ORDER BY instance_nics.instance_id, instance_nics.nic_index`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nics := make(map[string][]types.InstanceNIC)
+	for rows.Next() {
+		var instanceID string
+		var nic types.InstanceNIC
+
+		err = rows.Scan(&instanceID, &nic.NetworkID, &nic.MACAddress, &nic.VnicUUID, &nic.IPAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		nics[instanceID] = append(nics[instanceID], nic)
+	}
+
+	return nics, rows.Err()
+}
+
 func (ds *sqliteDB) getTenantInstances(tenantID string) (map[string]*types.Instance, error) {
 	db := ds.getTableDB("instances")
 
@@ -1221,7 +1852,13 @@ func (ds *sqliteDB) getTenantInstances(tenantID string) (map[string]*types.Insta
 		subnet,
 		ip,
 		name,
-		cnci
+		cnci,
+		IFNULL(network_id, "") AS network_id,
+		IFNULL(retain_ip, 0) AS retain_ip,
+		IFNULL(cnci_standby, 0) AS cnci_standby,
+		IFNULL(metadata, "") AS metadata,
+		IFNULL(user_data, "") AS user_data,
+		IFNULL(suspended, 0) AS suspended
 	FROM instances
 	LEFT JOIN latest
 	ON instances.id = latest.instance_id
@@ -1239,10 +1876,16 @@ func (ds *sqliteDB) getTenantInstances(tenantID string) (map[string]*types.Insta
 		var nodeID sql.NullString
 		var sshIP sql.NullString
 		var sshPort sql.NullInt64
+		var metadata string
 
 		i := &types.Instance{}
 
-		err = rows.Scan(&i.ID, &i.TenantID, &i.State, &sshIP, &sshPort, &i.WorkloadID, &nodeID, &i.MACAddress, &i.VnicUUID, &i.Subnet, &i.IPAddress, &i.Name, &i.CNCI)
+		err = rows.Scan(&i.ID, &i.TenantID, &i.State, &sshIP, &sshPort, &i.WorkloadID, &nodeID, &i.MACAddress, &i.VnicUUID, &i.Subnet, &i.IPAddress, &i.Name, &i.CNCI, &i.NetworkID, &i.RetainIP, &i.CNCIStandby, &metadata, &i.UserData, &i.Suspended)
+		if err != nil {
+			return nil, err
+		}
+
+		i.Metadata, err = decodeInstanceMetadata(metadata)
 		if err != nil {
 			return nil, err
 		}
@@ -1266,6 +1909,17 @@ func (ds *sqliteDB) getTenantInstances(tenantID string) (map[string]*types.Insta
 		return nil, err
 	}
 
+	nics, err := ds.getInstanceNICs("WHERE instances.tenant_id = ?", []interface{}{tenantID})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, n := range nics {
+		if i, ok := instances[id]; ok {
+			i.AdditionalNICs = n
+		}
+	}
+
 	return instances, nil
 }
 
@@ -1275,9 +1929,31 @@ func (ds *sqliteDB) addInstance(instance *types.Instance) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("INSERT INTO instances VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", instance.ID, instance.TenantID, instance.WorkloadID, instance.MACAddress, instance.VnicUUID, instance.Subnet, instance.IPAddress, instance.CreateTime.Format(time.RFC3339Nano), instance.Name, instance.CNCI)
+	metadata, err := encodeInstanceMetadata(instance.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
 
-	return err
+	_, err = tx.Exec("INSERT INTO instances (id, tenant_id, workload_id, mac_address, vnic_uuid, subnet, ip, create_time, name, cnci, network_id, retain_ip, cnci_standby, metadata, user_data) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", instance.ID, instance.TenantID, instance.WorkloadID, instance.MACAddress, instance.VnicUUID, instance.Subnet, instance.IPAddress, instance.CreateTime.Format(time.RFC3339Nano), instance.Name, instance.CNCI, instance.NetworkID, instance.RetainIP, instance.CNCIStandby, metadata, instance.UserData)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for index, nic := range instance.AdditionalNICs {
+		_, err = tx.Exec("INSERT INTO instance_nics VALUES(?, ?, ?, ?, ?, ?)", instance.ID, index, nic.NetworkID, nic.MACAddress, nic.VnicUUID, nic.IPAddress)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func (ds *sqliteDB) deleteInstance(instanceID string) error {
@@ -1286,7 +1962,12 @@ func (ds *sqliteDB) deleteInstance(instanceID string) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("DELETE FROM instances WHERE id = ?", instanceID)
+	_, err := db.Exec("DELETE FROM instance_nics WHERE instance_id = ?", instanceID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM instances WHERE id = ?", instanceID)
 
 	return err
 }
@@ -1297,11 +1978,46 @@ func (ds *sqliteDB) updateInstance(instance *types.Instance) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("UPDATE instances SET mac_address = ?, ip = ? WHERE id = ?", instance.MACAddress, instance.IPAddress, instance.ID)
+	metadata, err := encodeInstanceMetadata(instance.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE instances SET mac_address = ?, ip = ?, cnci_standby = ?, metadata = ?, suspended = ? WHERE id = ?", instance.MACAddress, instance.IPAddress, instance.CNCIStandby, metadata, instance.Suspended, instance.ID)
 
 	return err
 }
 
+// encodeInstanceMetadata converts the key/value metadata of an instance
+// into the JSON object stored in the instances table.
+func encodeInstanceMetadata(metadata map[string]string) (string, error) {
+	if len(metadata) == 0 {
+		return "", nil
+	}
+
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.Wrap(err, "error encoding instance metadata")
+	}
+
+	return string(b), nil
+}
+
+// decodeInstanceMetadata is the reverse of encodeInstanceMetadata.
+func decodeInstanceMetadata(metadata string) (map[string]string, error) {
+	if metadata == "" {
+		return nil, nil
+	}
+
+	var m map[string]string
+	err := json.Unmarshal([]byte(metadata), &m)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding instance metadata")
+	}
+
+	return m, nil
+}
+
 func (ds *sqliteDB) addNodeStat(stat payloads.Stat) error {
 	db := ds.getTableDB("node_statistics")
 
@@ -1324,8 +2040,10 @@ func (ds *sqliteDB) addInstanceStats(stats []payloads.InstanceStat, nodeID strin
 		return err
 	}
 
-	cmd := `INSERT INTO instance_statistics (instance_id, memory_usage_mb, disk_usage_mb, cpu_usage, state, node_id, ssh_ip, ssh_port)
-		VALUES(?, ?, ?, ?, ?, ?, ?, ?)`
+	cmd := `INSERT INTO instance_statistics (instance_id, memory_usage_mb, disk_usage_mb, cpu_usage, state, node_id, ssh_ip, ssh_port,
+			block_read_bytes, block_write_bytes, block_read_ops, block_write_ops,
+			net_rx_bytes, net_rx_packets, net_rx_dropped, net_tx_bytes, net_tx_packets, net_tx_dropped)
+		VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	stmt, err := tx.Prepare(cmd)
 	if err != nil {
@@ -1338,7 +2056,10 @@ func (ds *sqliteDB) addInstanceStats(stats []payloads.InstanceStat, nodeID strin
 	for index := range stats {
 		stat := stats[index]
 
-		_, err = stmt.Exec(stat.InstanceUUID, stat.MemoryUsageMB, stat.DiskUsageMB, stat.CPUUsage, stat.State, nodeID, stat.SSHIP, stat.SSHPort)
+		args := []interface{}{stat.InstanceUUID, stat.MemoryUsageMB, stat.DiskUsageMB, stat.CPUUsage, stat.State, nodeID, stat.SSHIP, stat.SSHPort}
+		args = append(args, ioStatsColumns(stat.BlockIO, stat.NetworkIO)...)
+
+		_, err = stmt.Exec(args...)
 		if err != nil {
 			glog.Warning(err)
 			// but keep going
@@ -1350,6 +2071,58 @@ func (ds *sqliteDB) addInstanceStats(stats []payloads.InstanceStat, nodeID strin
 	return err
 }
 
+// ioStatsColumns returns the values of the I/O counter columns of the
+// instance_statistics table.  The columns of missing counters are NULL.
+func ioStatsColumns(blockIO *payloads.BlockIOStat, networkIO *payloads.VnicIOStat) []interface{} {
+	columns := make([]interface{}, 10)
+
+	if blockIO != nil {
+		columns[0] = blockIO.ReadBytes
+		columns[1] = blockIO.WriteBytes
+		columns[2] = blockIO.ReadOps
+		columns[3] = blockIO.WriteOps
+	}
+
+	if networkIO != nil {
+		columns[4] = networkIO.RxBytes
+		columns[5] = networkIO.RxPackets
+		columns[6] = networkIO.RxDropped
+		columns[7] = networkIO.TxBytes
+		columns[8] = networkIO.TxPackets
+		columns[9] = networkIO.TxDropped
+	}
+
+	return columns
+}
+
+// ioStatsFromColumns is the inverse of ioStatsColumns.
+func ioStatsFromColumns(columns []sql.NullInt64) (*payloads.BlockIOStat, *payloads.VnicIOStat) {
+	var blockIO *payloads.BlockIOStat
+	var networkIO *payloads.VnicIOStat
+
+	if columns[0].Valid {
+		blockIO = &payloads.BlockIOStat{
+			ReadBytes:  columns[0].Int64,
+			WriteBytes: columns[1].Int64,
+			ReadOps:    columns[2].Int64,
+			WriteOps:   columns[3].Int64,
+		}
+	}
+
+	if columns[4].Valid {
+		networkIO = &payloads.VnicIOStat{
+			RxBytes:   columns[4].Int64,
+			RxPackets: columns[5].Int64,
+			RxDropped: columns[6].Int64,
+			TxBytes:   columns[7].Int64,
+			TxPackets: columns[8].Int64,
+			TxDropped: columns[9].Int64,
+		}
+	}
+
+	return blockIO, networkIO
+}
+
 func (ds *sqliteDB) addFrameStat(stat payloads.FrameTrace) error {
 	db := ds.getTableDB("frame_statistics")
 
@@ -1634,7 +2407,8 @@ func (ds *sqliteDB) getTenantDevices(tenantID string) (map[string]types.Volume,
 				block_data.create_time,
 				block_data.name,
 				block_data.description,
-				block_data.internal
+				block_data.internal,
+				block_data.multiattach
 		  FROM	block_data
 		  WHERE block_data.tenant_id = ?`
 
@@ -1648,7 +2422,7 @@ func (ds *sqliteDB) getTenantDevices(tenantID string) (map[string]types.Volume,
 		var state string
 		var data types.Volume
 
-		err = rows.Scan(&data.ID, &data.TenantID, &data.Size, &state, &data.CreateTime, &data.Name, &data.Description, &data.Internal)
+		err = rows.Scan(&data.ID, &data.TenantID, &data.Size, &state, &data.CreateTime, &data.Name, &data.Description, &data.Internal, &data.MultiAttach)
 		if err != nil {
 			continue
 		}
@@ -1676,7 +2450,8 @@ func (ds *sqliteDB) getAllBlockData() (map[string]types.Volume, error) {
 				block_data.create_time,
 				block_data.name,
 				block_data.description,
-				block_data.internal
+				block_data.internal,
+				block_data.multiattach
 		  FROM	block_data `
 
 	rows, err := db.Query(query)
@@ -1689,7 +2464,7 @@ func (ds *sqliteDB) getAllBlockData() (map[string]types.Volume, error) {
 		var data types.Volume
 		var state string
 
-		err = rows.Scan(&data.ID, &data.TenantID, &data.Size, &state, &data.CreateTime, &data.Name, &data.Description, &data.Internal)
+		err = rows.Scan(&data.ID, &data.TenantID, &data.Size, &state, &data.CreateTime, &data.Name, &data.Description, &data.Internal, &data.MultiAttach)
 		if err != nil {
 			continue
 		}
@@ -1705,10 +2480,12 @@ func (ds *sqliteDB) getAllBlockData() (map[string]types.Volume, error) {
 }
 
 func (ds *sqliteDB) addBlockData(data types.Volume) error {
+	db := ds.getTableDB("block_data")
+
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	err := ds.create("block_data", data.ID, data.TenantID, data.Size, string(data.State), data.CreateTime.Format(time.RFC3339Nano), data.Name, data.Description, data.Internal)
+	_, err := db.Exec("INSERT INTO block_data (id, tenant_id, size, state, create_time, name, description, internal, multiattach) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", data.ID, data.TenantID, data.Size, string(data.State), data.CreateTime.Format(time.RFC3339Nano), data.Name, data.Description, data.Internal, data.MultiAttach)
 
 	return err
 }
@@ -1742,7 +2519,7 @@ func (ds *sqliteDB) addStorageAttachment(a types.StorageAttachment) error {
 	ds.dbLock.Lock()
 	defer ds.dbLock.Unlock()
 
-	_, err := db.Exec("INSERT INTO attachments (id, instance_id, block_id, ephemeral, boot) VALUES (?, ?, ?, ?, ?)", a.ID, a.InstanceID, a.BlockID, a.Ephemeral, a.Boot)
+	_, err := db.Exec("INSERT INTO attachments (id, instance_id, block_id, ephemeral, boot, mode) VALUES (?, ?, ?, ?, ?, ?)", a.ID, a.InstanceID, a.BlockID, a.Ephemeral, a.Boot, string(a.Mode))
 
 	return err
 }
@@ -1756,7 +2533,8 @@ func (ds *sqliteDB) getAllStorageAttachments() (map[string]types.StorageAttachme
 				attachments.instance_id,
 				attachments.block_id,
 				attachments.ephemeral,
-				attachments.boot
+				attachments.boot,
+				attachments.mode
 		  FROM	attachments `
 
 	rows, err := db.Query(query)
@@ -1767,11 +2545,13 @@ func (ds *sqliteDB) getAllStorageAttachments() (map[string]types.StorageAttachme
 
 	for rows.Next() {
 		var a types.StorageAttachment
+		var mode string
 
-		err = rows.Scan(&a.ID, &a.InstanceID, &a.BlockID, &a.Ephemeral, &a.Boot)
+		err = rows.Scan(&a.ID, &a.InstanceID, &a.BlockID, &a.Ephemeral, &a.Boot, &mode)
 		if err != nil {
 			continue
 		}
+		a.Mode = types.AttachmentMode(mode)
 		attachments[a.ID] = a
 	}
 
@@ -2156,6 +2936,141 @@ func (ds *sqliteDB) getMappedIPs() map[string]types.MappedIP {
 	return IPs
 }
 
+func (ds *sqliteDB) updateLoadBalancer(lb types.LoadBalancer) error {
+	db := ds.getTableDB("load_balancers")
+
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	// do the below as a single transaction.
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("REPLACE INTO load_balancers (id, tenant_id, name, vip, pool_id, subnet, port, protocol, check_interval, check_timeout, check_retries, check_path, createtime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		lb.ID, lb.TenantID, lb.Name, lb.VIP, lb.PoolID, lb.Subnet, lb.Port, lb.Protocol,
+		lb.HealthCheck.Interval, lb.HealthCheck.Timeout, lb.HealthCheck.Retries, lb.HealthCheck.Path, lb.CreateTime)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM load_balancer_members WHERE load_balancer_id = ?", lb.ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, m := range lb.Members {
+		_, err = tx.Exec("INSERT INTO load_balancer_members (load_balancer_id, instance_id, ip, port) VALUES (?, ?, ?, ?)", lb.ID, m.InstanceID, m.IPAddress, m.Port)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (ds *sqliteDB) deleteLoadBalancer(ID string) error {
+	db := ds.getTableDB("load_balancers")
+
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM load_balancer_members WHERE load_balancer_id = ?", ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM load_balancers WHERE id = ?", ID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (ds *sqliteDB) getLoadBalancers() ([]types.LoadBalancer, error) {
+	var lbs []types.LoadBalancer
+
+	db := ds.getTableDB("load_balancers")
+
+	query := `SELECT	load_balancers.id,
+				load_balancers.tenant_id,
+				load_balancers.name,
+				load_balancers.vip,
+				load_balancers.pool_id,
+				pools.name,
+				load_balancers.subnet,
+				load_balancers.port,
+				load_balancers.protocol,
+				load_balancers.check_interval,
+				load_balancers.check_timeout,
+				load_balancers.check_retries,
+				load_balancers.check_path,
+				load_balancers.createtime
+		  FROM	load_balancers
+		  JOIN pools
+		  ON pools.id = load_balancers.pool_id`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[string]int)
+
+	for rows.Next() {
+		var lb types.LoadBalancer
+
+		err = rows.Scan(&lb.ID, &lb.TenantID, &lb.Name, &lb.VIP, &lb.PoolID, &lb.PoolName, &lb.Subnet, &lb.Port, &lb.Protocol,
+			&lb.HealthCheck.Interval, &lb.HealthCheck.Timeout, &lb.HealthCheck.Retries, &lb.HealthCheck.Path, &lb.CreateTime)
+		if err != nil {
+			return nil, err
+		}
+
+		lb.Members = []types.LoadBalancerMember{}
+		index[lb.ID] = len(lbs)
+		lbs = append(lbs, lb)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	memberRows, err := db.Query("SELECT load_balancer_id, instance_id, ip, port FROM load_balancer_members")
+	if err != nil {
+		return nil, err
+	}
+	defer memberRows.Close()
+
+	for memberRows.Next() {
+		var ID string
+		var m types.LoadBalancerMember
+
+		err = memberRows.Scan(&ID, &m.InstanceID, &m.IPAddress, &m.Port)
+		if err != nil {
+			return nil, err
+		}
+
+		if i, ok := index[ID]; ok {
+			lbs[i].Members = append(lbs[i].Members, m)
+		}
+	}
+
+	return lbs, memberRows.Err()
+}
+
 func (ds *sqliteDB) updateQuotas(tenantID string, qds []types.QuotaDetails) error {
 	db := ds.getTableDB("quotas")
 
@@ -2264,3 +3179,115 @@ func (ds *sqliteDB) deleteImage(ID string) error {
 
 	return errors.Wrap(err, "Error deleting image from database")
 }
+
+func (ds *sqliteDB) getBackups() ([]types.Backup, error) {
+	backups := []types.Backup{}
+
+	query := `SELECT id, tenant_id, volume_id, volume_size, state, createtime, name, description, size, checksum FROM backups`
+
+	db := ds.getTableDB("backups")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return backups, errors.Wrap(err, "error getting backups from database")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b := types.Backup{}
+		var state string
+
+		err = rows.Scan(&b.ID, &b.TenantID, &b.VolumeID, &b.VolumeSize, &state, &b.CreateTime, &b.Name, &b.Description, &b.Size, &b.Checksum)
+		if err != nil {
+			return []types.Backup{}, errors.Wrap(err, "error reading backup row from database")
+		}
+
+		b.State = types.BackupState(state)
+
+		backups = append(backups, b)
+	}
+
+	return backups, rows.Err()
+}
+
+func (ds *sqliteDB) updateBackup(b types.Backup) error {
+	query := `REPLACE INTO backups (id, tenant_id, volume_id, volume_size, state, createtime, name, description, size, checksum) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	db := ds.getTableDB("backups")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, b.ID, b.TenantID, b.VolumeID, b.VolumeSize, string(b.State), b.CreateTime, b.Name, b.Description, b.Size, b.Checksum)
+
+	return errors.Wrap(err, "Error updating backup in database")
+}
+
+func (ds *sqliteDB) deleteBackup(ID string) error {
+	query := `DELETE FROM backups WHERE id = ?`
+
+	db := ds.getTableDB("backups")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, ID)
+
+	return errors.Wrap(err, "Error deleting backup from database")
+}
+
+func (ds *sqliteDB) getNetworkPeerings() ([]types.NetworkPeering, error) {
+	peerings := []types.NetworkPeering{}
+
+	query := `SELECT id, name, tenant_id, network_id, cidr, peer_tenant_id, peer_network_id, peer_cidr, status, createtime FROM network_peerings`
+
+	db := ds.getTableDB("network_peerings")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return peerings, errors.Wrap(err, "error getting network peerings from database")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := types.NetworkPeering{}
+		var status string
+
+		err = rows.Scan(&p.ID, &p.Name, &p.TenantID, &p.NetworkID, &p.CIDR, &p.PeerTenantID, &p.PeerNetworkID, &p.PeerCIDR, &status, &p.CreateTime)
+		if err != nil {
+			return []types.NetworkPeering{}, errors.Wrap(err, "error reading network peering row from database")
+		}
+
+		p.Status = types.NetworkPeeringStatus(status)
+
+		peerings = append(peerings, p)
+	}
+
+	return peerings, rows.Err()
+}
+
+func (ds *sqliteDB) updateNetworkPeering(p types.NetworkPeering) error {
+	query := `REPLACE INTO network_peerings (id, name, tenant_id, network_id, cidr, peer_tenant_id, peer_network_id, peer_cidr, status, createtime) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	db := ds.getTableDB("network_peerings")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, p.ID, p.Name, p.TenantID, p.NetworkID, p.CIDR, p.PeerTenantID, p.PeerNetworkID, p.PeerCIDR, string(p.Status), p.CreateTime)
+
+	return errors.Wrap(err, "Error updating network peering in database")
+}
+
+func (ds *sqliteDB) deleteNetworkPeering(ID string) error {
+	query := `DELETE FROM network_peerings WHERE id = ?`
+
+	db := ds.getTableDB("network_peerings")
+	ds.dbLock.Lock()
+	defer ds.dbLock.Unlock()
+
+	_, err := db.Exec(query, ID)
+
+	return errors.Wrap(err, "Error deleting network peering from database")
+}
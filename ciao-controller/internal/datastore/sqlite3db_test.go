@@ -15,6 +15,7 @@
 package datastore
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"reflect"
@@ -40,6 +41,232 @@ func getPersistentStore() (persistentStore, error) {
 	return ps, err
 }
 
+// baselineSchema creates the tables whose columns have since been extended
+// as they were created by older versions of the controller.
+var baselineSchema = []string{
+	`CREATE TABLE tenants
+		(
+		id varchar(32) primary key,
+		name text,
+		subnet_bits int
+		);`,
+	`CREATE TABLE instances
+		(
+		id string primary key,
+		tenant_id string,
+		workload_id string,
+		mac_address string,
+		vnic_uuid string,
+		subnet string,
+		ip string,
+		create_time DATETIME,
+		name string,
+		cnci int,
+		foreign key(tenant_id) references tenants(id),
+		foreign key(workload_id) references workload_template(id),
+		unique(tenant_id, ip, mac_address)
+		);`,
+	`CREATE TABLE block_data
+		(
+		id string primary_key,
+		tenant_id string,
+		size integer,
+		state string,
+		create_time DATETIME,
+		name string,
+		description string,
+		internal int,
+		foreign key(tenant_id) references tenants(id)
+		);`,
+	`CREATE TABLE attachments
+		(
+		id string primary key,
+		instance_id string,
+		block_id string,
+		ephemeral int,
+		boot int,
+		foreign key(instance_id) references instances(id),
+		foreign key(block_id) references block_data(id)
+		);`,
+	`CREATE TABLE instance_statistics
+		(
+			id integer primary key autoincrement not null,
+			instance_id varchar(32),
+			memory_usage_mb int,
+			disk_usage_mb int,
+			cpu_usage int,
+			state string,
+			node_id varchar(32),
+			ssh_ip string,
+			ssh_port int,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP NOT NULL
+		);`,
+	`CREATE TABLE networks
+		(
+			id string primary key,
+			tenant_id string,
+			name string,
+			cidr string,
+			gateway string,
+			allocation_start string,
+			allocation_end string,
+			createtime DATETIME,
+			foreign key(tenant_id) references tenants(id)
+		);`,
+	`CREATE TABLE workload_template
+		(
+		id varchar(32) primary key,
+		tenant_id varchar(32),
+		description text,
+		filename text,
+		fw_type text,
+		vm_type text,
+		image_name text,
+		internal integer,
+		foreign key(tenant_id) references tenants(id)
+		);`,
+	`INSERT INTO tenants VALUES ('old-tenant', 'old', 24);`,
+	`INSERT INTO workload_template VALUES ('old-workload', 'old-tenant', 'old', 'old_config.yaml', 'legacy', 'qemu', '', 1);`,
+	`INSERT INTO networks VALUES ('old-network', 'old-tenant', 'old', '10.1.0.0/24', '10.1.0.1', '10.1.0.2', '10.1.0.254', '2017-01-01T00:00:00Z');`,
+	`INSERT INTO instances VALUES ('old-instance', 'old-tenant', 'old-workload', '02:00:ac:10:00:02', 'old-vnic', '172.16.0.0/24', '172.16.0.2', '2017-01-01T00:00:00Z', 'old', 0);`,
+	`INSERT INTO block_data VALUES ('old-volume', 'old-tenant', 10, 'in-use', '2017-01-01T00:00:00Z', 'old', '', 0);`,
+	`INSERT INTO attachments VALUES ('old-attachment', 'old-instance', 'old-volume', 0, 0);`,
+	`INSERT INTO instance_statistics (instance_id, memory_usage_mb, disk_usage_mb, cpu_usage, state, node_id, ssh_ip, ssh_port) VALUES ('old-instance', 10, 10, 1, 'running', 'old-node', '', 0);`,
+}
+
+func TestSQLiteDBUpgradeSchema(t *testing.T) {
+	URI := fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", dbCount)
+	dbCount = dbCount + 2
+
+	// the shared in memory database lives as long as this connection.
+	old, err := sql.Open("sqlite3", URI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+
+	for _, cmd := range baselineSchema {
+		_, err = old.Exec(cmd)
+		if err != nil {
+			t.Fatalf("%s: %v", cmd, err)
+		}
+	}
+
+	db := &sqliteDB{}
+	err = db.init(Config{
+		PersistentURI:     URI,
+		InitWorkloadsPath: *workloadsPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.disconnect()
+
+	// initializing an upgraded database again must not fail.
+	for _, table := range db.tables {
+		err = table.Init()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tn, err := db.getTenant("old-tenant")
+	if err != nil || tn == nil || tn.DNSDomain != "" {
+		t.Fatalf("Unable to read old tenant %v: %v", tn, err)
+	}
+
+	n := tn.networks["old-network"]
+	if n == nil || n.CIDR != "10.1.0.0/24" || n.IPv6CIDR != "" {
+		t.Fatalf("Unable to read old network %v", n)
+	}
+
+	var template bool
+	err = old.QueryRow("SELECT template FROM workload_template WHERE id = 'old-workload'").Scan(&template)
+	if err != nil || template {
+		t.Fatalf("Unexpected template setting for old workload: %v", err)
+	}
+
+	_ = createTestTenant(db, t)
+
+	devices, err := db.getTenantDevices("old-tenant")
+	if err != nil || len(devices) != 1 || devices["old-volume"].MultiAttach {
+		t.Fatalf("Unable to read old volume %v: %v", devices, err)
+	}
+
+	attachments, err := db.getAllStorageAttachments()
+	if err != nil || attachments["old-attachment"].Mode != types.ReadWrite {
+		t.Fatalf("Unable to read old attachment %v: %v", attachments, err)
+	}
+
+	instances, err := db.getInstances()
+	if err != nil || len(instances) != 1 {
+		t.Fatalf("Unable to read old instance: %v", err)
+	}
+
+	if instances[0].NetworkID != "" || instances[0].RetainIP || instances[0].CNCIStandby ||
+		instances[0].Metadata != nil || instances[0].UserData != "" {
+		t.Fatalf("Unexpected defaults for old instance %+v", instances[0])
+	}
+
+	v := types.Volume{
+		ID:          uuid.Generate().String(),
+		TenantID:    "old-tenant",
+		State:       types.Available,
+		CreateTime:  time.Now(),
+		MultiAttach: true,
+	}
+
+	err = db.addBlockData(v)
+	if err != nil {
+		t.Fatalf("Unable to add volume to upgraded database: %v", err)
+	}
+
+	devices, err = db.getTenantDevices("old-tenant")
+	if err != nil || len(devices) != 2 || !devices[v.ID].MultiAttach {
+		t.Fatalf("Unable to read volumes from upgraded database %v: %v", devices, err)
+	}
+
+	i := types.Instance{
+		ID:         uuid.Generate().String(),
+		TenantID:   "old-tenant",
+		WorkloadID: uuid.Generate().String(),
+		IPAddress:  "172.16.0.3",
+		Name:       "new",
+	}
+
+	err = db.addInstance(&i)
+	if err != nil {
+		t.Fatalf("Unable to add instance to upgraded database: %v", err)
+	}
+
+	stats := []payloads.InstanceStat{
+		{
+			InstanceUUID: i.ID,
+			State:        payloads.Running,
+			BlockIO:      &payloads.BlockIOStat{ReadBytes: 1},
+		},
+	}
+
+	err = db.addInstanceStats(stats, "old-node")
+	if err != nil {
+		t.Fatalf("Unable to add instance stats to upgraded database: %v", err)
+	}
+
+	instances, err = db.getInstances()
+	if err != nil || len(instances) != 2 {
+		t.Fatalf("Unable to read instances from upgraded database: %v", err)
+	}
+
+	for _, instance := range instances {
+		if instance.ID == i.ID && (instance.BlockIO == nil || instance.BlockIO.ReadBytes != 1) {
+			t.Fatalf("Block I/O statistics not stored for %s", instance.ID)
+		}
+		if instance.ID == "old-instance" && instance.BlockIO != nil {
+			t.Fatalf("Unexpected block I/O statistics for old instance")
+		}
+	}
+}
+
 func TestSQLiteDBGetWorkloadStorage(t *testing.T) {
 	db, err := getPersistentStore()
 	if err != nil {
@@ -921,6 +1148,67 @@ func TestSQLiteDBInstanceStats(t *testing.T) {
 	}
 }
 
+func TestSQLiteDBInstanceIOStats(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := types.Instance{
+		ID:         uuid.Generate().String(),
+		TenantID:   uuid.Generate().String(),
+		WorkloadID: uuid.Generate().String(),
+	}
+
+	err = db.addInstance(&i)
+	if err != nil {
+		t.Fatalf("unable to store instance: %v\n", err)
+	}
+
+	blockIO := payloads.BlockIOStat{
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		ReadOps:    1,
+		WriteOps:   2,
+	}
+	networkIO := payloads.VnicIOStat{
+		RxBytes:   1024,
+		RxPackets: 8,
+		RxDropped: 1,
+		TxBytes:   2048,
+		TxPackets: 16,
+		TxDropped: 2,
+	}
+	stats := []payloads.InstanceStat{
+		{
+			InstanceUUID: i.ID,
+			State:        payloads.ComputeStatusRunning,
+			BlockIO:      &blockIO,
+			NetworkIO:    &networkIO,
+		},
+	}
+
+	err = db.addInstanceStats(stats, uuid.Generate().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err := db.getInstances()
+	if err != nil || len(instances) != 1 {
+		t.Fatal(err)
+	}
+
+	if instances[0].BlockIO == nil || *instances[0].BlockIO != blockIO {
+		t.Errorf("Expected block stats %+v, got %+v", blockIO, instances[0].BlockIO)
+	}
+
+	if instances[0].NetworkIO == nil || *instances[0].NetworkIO != networkIO {
+		t.Errorf("Expected network stats %+v, got %+v", networkIO, instances[0].NetworkIO)
+	}
+
+	db.disconnect()
+}
+
 func TestSQLiteDBUpdateDeleteWorkload(t *testing.T) {
 	db, err := getPersistentStore()
 	if err != nil {
@@ -968,6 +1256,7 @@ users:
 		VMType:      payloads.QEMU,
 		ImageName:   "",
 		Config:      testConfig,
+		Template:    true,
 		Defaults:    []payloads.RequestedResource{mem, cpus},
 		Storage:     []types.StorageResource{storage},
 	}
@@ -1234,6 +1523,67 @@ func TestSQLiteDBUpdateTenant(t *testing.T) {
 	db.disconnect()
 }
 
+func TestSQLiteDBTenantDNS(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantID := uuid.Generate().String()
+	config := types.TenantConfig{
+		Name:          "name1",
+		SubnetBits:    24,
+		DNSDomain:     "tenant.ciao",
+		DNSForwarders: []string{"8.8.8.8", "8.8.4.4"},
+	}
+
+	err = db.addTenant(tenantID, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := db.getTenant(tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tenant.DNSDomain != config.DNSDomain ||
+		!reflect.DeepEqual(tenant.DNSForwarders, config.DNSForwarders) {
+		t.Fatalf("DNS configuration not stored: %s %v", tenant.DNSDomain, tenant.DNSForwarders)
+	}
+
+	// clear the forwarders and change the domain
+	tenant.DNSDomain = "other.ciao"
+	tenant.DNSForwarders = nil
+
+	err = db.updateTenant(&tenant.Tenant)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenants, err := db.getTenants()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, tn := range tenants {
+		if tn.ID != tenantID {
+			continue
+		}
+		found = true
+		if tn.DNSDomain != "other.ciao" || len(tn.DNSForwarders) != 0 {
+			t.Fatalf("DNS update not successful: %s %v", tn.DNSDomain, tn.DNSForwarders)
+		}
+	}
+
+	if !found {
+		t.Fatal("tenant not found")
+	}
+
+	db.disconnect()
+}
+
 func TestSQLiteDBDeleteTenant(t *testing.T) {
 	db, err := getPersistentStore()
 	if err != nil {
@@ -1433,3 +1783,410 @@ func TestSQLiteDBUpdateImage(t *testing.T) {
 		t.Fatalf("Returned image not as expected %v vs %v", images[0], i)
 	}
 }
+
+func TestSQLiteDBAddRemoveBackups(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantID := uuid.Generate().String()
+	config := types.TenantConfig{
+		Name: "name1",
+	}
+
+	err = db.addTenant(tenantID, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := db.getBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 0 {
+		t.Fatalf("Unexpected backup count: %d vs 0", len(backups))
+	}
+
+	b := types.Backup{
+		ID:          uuid.Generate().String(),
+		TenantID:    tenantID,
+		VolumeID:    uuid.Generate().String(),
+		VolumeSize:  20,
+		State:       types.BackupCreating,
+		Name:        "test-backup",
+		Description: "a test backup",
+	}
+
+	err = db.updateBackup(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.State = types.BackupAvailable
+	b.Size = 21474836480
+	b.Checksum = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	err = db.updateBackup(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err = db.getBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 1 {
+		t.Fatalf("Unexpected backup count: %d vs 1", len(backups))
+	}
+
+	if !reflect.DeepEqual(backups[0], b) {
+		t.Fatalf("Returned backup not as expected %v vs %v", backups[0], b)
+	}
+
+	err = db.deleteBackup(b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err = db.getBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 0 {
+		t.Fatalf("Unexpected backup count: %d vs 0", len(backups))
+	}
+}
+
+func TestSQLiteDBAddRemoveNetworkPeerings(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerings, err := db.getNetworkPeerings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peerings) != 0 {
+		t.Fatalf("Unexpected peering count: %d vs 0", len(peerings))
+	}
+
+	p := types.NetworkPeering{
+		ID:            uuid.Generate().String(),
+		Name:          "test-peering",
+		TenantID:      uuid.Generate().String(),
+		NetworkID:     uuid.Generate().String(),
+		CIDR:          "10.20.0.0/24",
+		PeerTenantID:  uuid.Generate().String(),
+		PeerNetworkID: uuid.Generate().String(),
+		PeerCIDR:      "10.30.0.0/24",
+		Status:        types.PeeringPending,
+	}
+
+	err = db.updateNetworkPeering(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.Status = types.PeeringActive
+
+	err = db.updateNetworkPeering(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerings, err = db.getNetworkPeerings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peerings) != 1 {
+		t.Fatalf("Unexpected peering count: %d vs 1", len(peerings))
+	}
+
+	if !reflect.DeepEqual(peerings[0], p) {
+		t.Fatalf("Returned peering not as expected %v vs %v", peerings[0], p)
+	}
+
+	err = db.deleteNetworkPeering(p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerings, err = db.getNetworkPeerings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(peerings) != 0 {
+		t.Fatalf("Unexpected peering count: %d vs 0", len(peerings))
+	}
+}
+
+func TestSQLiteDBAddRemoveTenantNetworks(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantID := uuid.Generate().String()
+	config := types.TenantConfig{
+		Name: "name1",
+	}
+
+	err = db.addTenant(tenantID, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := types.TenantNetwork{
+		ID:              uuid.Generate().String(),
+		TenantID:        tenantID,
+		Name:            "test-network",
+		CIDR:            "10.10.0.0/24",
+		Gateway:         "10.10.0.1",
+		AllocationStart: "10.10.0.2",
+		AllocationEnd:   "10.10.0.254",
+	}
+
+	err = db.addTenantNetwork(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.claimNetworkIP(n.ID, "10.10.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err := db.getTenant(tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tn, ok := tenant.networks[n.ID]
+	if !ok {
+		t.Fatal("Network not returned with tenant")
+	}
+
+	if !reflect.DeepEqual(tn.TenantNetwork, n) {
+		t.Fatalf("Returned network not as expected %v vs %v", tn.TenantNetwork, n)
+	}
+
+	if len(tn.addresses) != 1 || !tn.addresses["10.10.0.2"] {
+		t.Fatalf("Unexpected network addresses %v", tn.addresses)
+	}
+
+	err = db.releaseNetworkIP(n.ID, "10.10.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.deleteTenantNetwork(n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err = db.getTenant(tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tenant.networks) != 0 {
+		t.Fatalf("Unexpected network count: %d vs 0", len(tenant.networks))
+	}
+}
+
+func TestSQLiteDBInstanceNICs(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantID := uuid.Generate().String()
+	i := types.Instance{
+		ID:         uuid.Generate().String(),
+		TenantID:   tenantID,
+		WorkloadID: uuid.Generate().String(),
+		IPAddress:  "172.16.0.2",
+		AdditionalNICs: []types.InstanceNIC{
+			{
+				NetworkID:  uuid.Generate().String(),
+				MACAddress: "02:00:0a:0a:00:02",
+				VnicUUID:   uuid.Generate().String(),
+				IPAddress:  "10.10.0.2",
+			},
+			{
+				NetworkID:  uuid.Generate().String(),
+				MACAddress: "02:00:0a:0b:00:02",
+				VnicUUID:   uuid.Generate().String(),
+				IPAddress:  "10.11.0.2",
+			},
+		},
+	}
+
+	err = db.addInstance(&i)
+	if err != nil {
+		t.Fatalf("unable to store instance: %v\n", err)
+	}
+
+	instances, err := db.getInstances()
+	if err != nil || len(instances) != 1 {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(instances[0].AdditionalNICs, i.AdditionalNICs) {
+		t.Fatalf("Returned NICs not as expected %v vs %v",
+			instances[0].AdditionalNICs, i.AdditionalNICs)
+	}
+
+	err = db.deleteInstance(i.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the NICs must have been deleted along with the instance, otherwise
+	// storing them again would fail.
+	err = db.addInstance(&i)
+	if err != nil {
+		t.Fatalf("unable to store instance again: %v\n", err)
+	}
+}
+
+func TestSQLiteDBReservedIPs(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantID := uuid.Generate().String()
+	config := types.TenantConfig{
+		Name: "name1",
+	}
+
+	err = db.addTenant(tenantID, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := types.Instance{
+		ID:         uuid.Generate().String(),
+		TenantID:   tenantID,
+		WorkloadID: uuid.Generate().String(),
+		IPAddress:  "172.16.0.10",
+		RetainIP:   true,
+	}
+
+	err = db.addInstance(&i)
+	if err != nil {
+		t.Fatalf("unable to store instance: %v\n", err)
+	}
+
+	instances, err := db.getInstances()
+	if err != nil || len(instances) != 1 {
+		t.Fatal(err)
+	}
+
+	if !instances[0].RetainIP {
+		t.Fatal("Instance does not retain its IP address")
+	}
+
+	r := types.ReservedIP{
+		TenantID:   tenantID,
+		IPAddress:  i.IPAddress,
+		InstanceID: i.ID,
+		CreateTime: time.Now(),
+	}
+
+	err = db.addReservedIP(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.addReservedIP(r)
+	if err == nil {
+		t.Fatal("Expected error when reserving an address twice")
+	}
+
+	tenant, err := db.getTenant(tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr, ok := tenant.reservedIPs[r.IPAddress]
+	if !ok {
+		t.Fatal("Reserved address not returned with tenant")
+	}
+
+	if rr.TenantID != r.TenantID || rr.InstanceID != r.InstanceID ||
+		rr.NetworkID != r.NetworkID {
+		t.Fatalf("Returned reserved address not as expected %v vs %v", rr, r)
+	}
+
+	err = db.deleteReservedIP(tenantID, r.IPAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenant, err = db.getTenant(tenantID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tenant.reservedIPs) != 0 {
+		t.Fatalf("Unexpected reserved address count: %d vs 0", len(tenant.reservedIPs))
+	}
+}
+
+func TestSQLiteDBInstanceMetadata(t *testing.T) {
+	db, err := getPersistentStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tenantID := uuid.Generate().String()
+	config := types.TenantConfig{
+		Name: "name1",
+	}
+
+	err = db.addTenant(tenantID, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := types.Instance{
+		ID:         uuid.Generate().String(),
+		TenantID:   tenantID,
+		WorkloadID: uuid.Generate().String(),
+		IPAddress:  "172.16.0.11",
+		Metadata:   map[string]string{"role": "db"},
+		UserData:   "#cloud-config\n",
+	}
+
+	err = db.addInstance(&i)
+	if err != nil {
+		t.Fatalf("unable to store instance: %v\n", err)
+	}
+
+	i.Metadata = map[string]string{"role": "web", "tier": "front"}
+	err = db.updateInstance(&i)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instances, err := db.getInstances()
+	if err != nil || len(instances) != 1 {
+		t.Fatal(err)
+	}
+
+	ii := instances[0]
+	if !reflect.DeepEqual(ii.Metadata, i.Metadata) || ii.UserData != i.UserData {
+		t.Fatalf("Returned metadata not as expected %v %q vs %v %q",
+			ii.Metadata, ii.UserData, i.Metadata, i.UserData)
+	}
+}
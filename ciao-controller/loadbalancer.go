@@ -0,0 +1,393 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+	"github.com/golang/glog"
+)
+
+// health check settings used when a load balancer is created without them.
+const (
+	defaultCheckInterval = 5
+	defaultCheckTimeout  = 2
+	defaultCheckRetries  = 3
+)
+
+// newLoadBalancerHealthCheck fills in the defaults of a requested health
+// check and makes sure the resulting check is usable.
+func newLoadBalancerHealthCheck(protocol string, req *types.LoadBalancerHealthCheck) (types.LoadBalancerHealthCheck, error) {
+	check := types.LoadBalancerHealthCheck{
+		Interval: defaultCheckInterval,
+		Timeout:  defaultCheckTimeout,
+		Retries:  defaultCheckRetries,
+	}
+
+	if req != nil {
+		if req.Interval < 0 || req.Timeout < 0 || req.Retries < 0 {
+			return types.LoadBalancerHealthCheck{}, types.ErrBadRequest
+		}
+
+		if req.Interval != 0 {
+			check.Interval = req.Interval
+		}
+		if req.Timeout != 0 {
+			check.Timeout = req.Timeout
+		}
+		if req.Retries != 0 {
+			check.Retries = req.Retries
+		}
+		check.Path = req.Path
+	}
+
+	if check.Timeout > check.Interval {
+		return types.LoadBalancerHealthCheck{}, types.ErrBadRequest
+	}
+
+	if protocol == payloads.LoadBalancerHTTP {
+		if check.Path == "" {
+			check.Path = "/"
+		}
+	} else if check.Path != "" {
+		return types.LoadBalancerHealthCheck{}, types.ErrBadRequest
+	}
+
+	return check, nil
+}
+
+// loadBalancerMembers resolves the requested members of a load balancer to
+// their addresses on a tenant subnet.  If subnet is empty the subnet of the
+// first member is used.  The subnet of the members is returned along with
+// the members.
+func (c *controller) loadBalancerMembers(tenant string, subnet string, port int, reqs []api.RequestedLoadBalancerMember) ([]types.LoadBalancerMember, string, error) {
+	networks, err := c.tenantNetworkSubnets(tenant)
+	if err != nil {
+		return nil, "", err
+	}
+
+	members := []types.LoadBalancerMember{}
+	seen := make(map[string]bool)
+
+	for _, req := range reqs {
+		i, err := c.ds.GetTenantInstance(tenant, req.InstanceID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if i.CNCI {
+			return nil, "", types.ErrBadRequest
+		}
+
+		if subnet == "" {
+			subnet, err = canonicalSubnet(i.Subnet)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		addr, ok := instanceSubnets(i, networks)[subnet]
+		if !ok || addr == "" {
+			return nil, "", types.ErrBadRequest
+		}
+
+		m := types.LoadBalancerMember{
+			InstanceID: i.ID,
+			IPAddress:  addr,
+			Port:       req.Port,
+		}
+
+		if m.Port == 0 {
+			m.Port = port
+		}
+
+		if m.Port < 0 || m.Port > 65535 || seen[i.ID] {
+			return nil, "", types.ErrBadRequest
+		}
+		seen[i.ID] = true
+
+		members = append(members, m)
+	}
+
+	return members, subnet, nil
+}
+
+// CreateLoadBalancer defines a new load balancer for a tenant.  Its virtual
+// IP is allocated from an external IP pool and counts against the external
+// IP quota of the tenant.
+func (c *controller) CreateLoadBalancer(tenant string, req api.RequestedLoadBalancer) (lb types.LoadBalancer, err error) {
+	err = c.confirmTenant(tenant)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	if req.Protocol == "" {
+		req.Protocol = payloads.LoadBalancerTCP
+	}
+
+	if req.Protocol != payloads.LoadBalancerTCP && req.Protocol != payloads.LoadBalancerHTTP {
+		return types.LoadBalancer{}, types.ErrBadRequest
+	}
+
+	if req.Port <= 0 || req.Port > 65535 || len(req.Members) == 0 {
+		return types.LoadBalancer{}, types.ErrBadRequest
+	}
+
+	check, err := newLoadBalancerHealthCheck(req.Protocol, req.HealthCheck)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	members, subnet, err := c.loadBalancerMembers(tenant, "", req.Port, req.Members)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	res := <-c.qs.Consume(tenant, payloads.RequestedResource{Type: payloads.ExternalIP, Value: 1})
+	defer func() {
+		if err != nil {
+			c.qs.Release(tenant, payloads.RequestedResource{Type: payloads.ExternalIP, Value: 1})
+		}
+	}()
+
+	if !res.Allowed() {
+		return types.LoadBalancer{}, types.ErrQuota
+	}
+
+	lb = types.LoadBalancer{
+		ID:          uuid.Generate().String(),
+		TenantID:    tenant,
+		Name:        req.Name,
+		Subnet:      subnet,
+		Port:        req.Port,
+		Protocol:    req.Protocol,
+		Members:     members,
+		HealthCheck: check,
+		CreateTime:  time.Now(),
+	}
+
+	pools, err := c.ds.GetPools()
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	err = types.ErrPoolEmpty
+
+	var added types.LoadBalancer
+	for _, pool := range pools {
+		if req.PoolName != nil {
+			if pool.Name == *req.PoolName {
+				added, err = c.ds.AddLoadBalancer(pool.ID, lb)
+				break
+			}
+		} else if pool.Free > 0 {
+			// the free addresses of a pool may all be IPv6 ones,
+			// try the next pool.
+			added, err = c.ds.AddLoadBalancer(pool.ID, lb)
+			if err != types.ErrPoolEmpty {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	t, err := c.ds.GetTenant(tenant)
+	if err == nil {
+		err = c.client.configureLoadBalancer(*t, added)
+	}
+	if err != nil {
+		_ = c.ds.DeleteLoadBalancer(tenant, added.ID)
+		return types.LoadBalancer{}, err
+	}
+
+	return added, nil
+}
+
+// ListLoadBalancers returns all the load balancers of a tenant.
+func (c *controller) ListLoadBalancers(tenant string) ([]types.LoadBalancer, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ds.GetLoadBalancers(tenant), nil
+}
+
+// ShowLoadBalancer returns a single load balancer of a tenant.
+func (c *controller) ShowLoadBalancer(tenant string, ID string) (types.LoadBalancer, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	return c.ds.GetLoadBalancer(tenant, ID)
+}
+
+// UpdateLoadBalancerMembers replaces the members of a load balancer.  The
+// new members must be attached to the subnet of the load balancer.
+func (c *controller) UpdateLoadBalancerMembers(tenant string, ID string, reqs []api.RequestedLoadBalancerMember) (types.LoadBalancer, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	lb, err := c.ds.GetLoadBalancer(tenant, ID)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	members, _, err := c.loadBalancerMembers(tenant, lb.Subnet, lb.Port, reqs)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	lb.Members = members
+
+	err = c.ds.UpdateLoadBalancer(lb)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	t, err := c.ds.GetTenant(tenant)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	err = c.client.configureLoadBalancer(*t, lb)
+	if err != nil {
+		return types.LoadBalancer{}, err
+	}
+
+	return lb, nil
+}
+
+// DeleteLoadBalancer removes a load balancer of a tenant and returns its
+// virtual IP to its pool.
+func (c *controller) DeleteLoadBalancer(tenant string, ID string) error {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	lb, err := c.ds.GetLoadBalancer(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	t, err := c.ds.GetTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	// the CNCI of the subnet may already be gone, in which case there
+	// is nothing left to remove from it.
+	err = c.client.removeLoadBalancer(*t, lb)
+	if err != nil {
+		glog.Warningf("Unable to remove load balancer %s from CNCI: %v", lb.ID, err)
+	}
+
+	err = c.ds.DeleteLoadBalancer(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	c.qs.Release(tenant, payloads.RequestedResource{Type: payloads.ExternalIP, Value: 1})
+
+	return nil
+}
+
+// removeLoadBalancerMember drops a deleted instance from the load balancers
+// of its tenant.
+func (c *controller) removeLoadBalancerMember(i *types.Instance) {
+	if i.CNCI {
+		return
+	}
+
+	var t *types.Tenant
+
+	for _, lb := range c.ds.GetLoadBalancers(i.TenantID) {
+		members := []types.LoadBalancerMember{}
+		for _, m := range lb.Members {
+			if m.InstanceID != i.ID {
+				members = append(members, m)
+			}
+		}
+
+		if len(members) == len(lb.Members) {
+			continue
+		}
+
+		lb.Members = members
+
+		err := c.ds.UpdateLoadBalancer(lb)
+		if err != nil {
+			glog.Warningf("Unable to remove %s from load balancer %s: %v", i.ID, lb.ID, err)
+			continue
+		}
+
+		if t == nil {
+			t, err = c.ds.GetTenant(i.TenantID)
+			if err != nil || t == nil {
+				glog.Warningf("Unable to get tenant %s: %v", i.TenantID, err)
+				return
+			}
+		}
+
+		err = c.client.configureLoadBalancer(*t, lb)
+		if err != nil {
+			glog.Warningf("Unable to configure load balancer %s: %v", lb.ID, err)
+		}
+	}
+}
+
+// restoreLoadBalancers configures the load balancers of a tenant subnet on
+// the CNCI serving the subnet.  It is called when a new CNCI takes over a
+// subnet.
+func (c *controller) restoreLoadBalancers(t *types.Tenant, subnet string) {
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return
+	}
+
+	for _, lb := range c.ds.GetLoadBalancers(t.ID) {
+		if lb.Subnet != subnet {
+			continue
+		}
+
+		err = c.client.configureLoadBalancer(*t, lb)
+		if err != nil {
+			glog.Warningf("Unable to configure load balancer %s: %v", lb.ID, err)
+		}
+	}
+}
+
+// deleteLoadBalancers removes all the load balancers of a tenant.
+func (c *controller) deleteLoadBalancers(tenant string) error {
+	for _, lb := range c.ds.GetLoadBalancers(tenant) {
+		err := c.DeleteLoadBalancer(tenant, lb.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
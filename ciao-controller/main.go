@@ -47,6 +47,7 @@ type tenantConfirmMemo struct {
 
 type controller struct {
 	storage.BlockDriver
+	backupTarget        storage.BackupTarget
 	client              controllerClient
 	ds                  *datastore.Datastore
 	apiURL              string
@@ -54,6 +55,11 @@ type controller struct {
 	tenantReadinessLock sync.Mutex
 	qs                  *quotas.Quotas
 	httpServers         []*http.Server
+	consoleLogs         map[string]chan consoleLogResult
+	consoleLogsLock     sync.Mutex
+	consoles            map[string]*consoleSession
+	consolesLock        sync.Mutex
+	consoleTLS          *tls.Config
 }
 
 var cert = flag.String("cert", "", "Client certificate")
@@ -70,6 +76,12 @@ var clientCertCAPath = "/etc/pki/ciao/auth-CA.pem"
 
 var cephID = flag.String("ceph_id", "", "ceph client id")
 
+var backupTarget = flag.String("backup_target", "", "volume backup target, e.g., file:///path or s3://host/bucket")
+
+var gracefulTimeout = flag.Int("graceful_timeout", 0, "seconds given to instances to shut down cleanly before they are terminated, 0 for the compute node's default")
+
+var cnciStandby = flag.Bool("cnci_standby", false, "launch a standby CNCI for each tenant subnet and fail over to it when the active CNCI is lost")
+
 var adminSSHKey = ""
 
 // default password set to "ciao"
@@ -151,6 +163,12 @@ func main() {
 		return
 	}
 
+	ctl.consoleTLS, err = ssntp.PeerTLSConfig(config, false, ssntp.AGENT|ssntp.NETAGENT)
+	if err != nil {
+		glog.Fatalf("Unable to load certificates for console sessions: %v", err)
+		return
+	}
+
 	ssntpClient := ctl.client.ssntpClient()
 	clusterConfig, err := ssntpClient.ClusterConfiguration()
 	if err != nil {
@@ -194,6 +212,16 @@ func main() {
 		return driver
 	}()
 
+	if *backupTarget != "" {
+		ctl.backupTarget, err = storage.NewBackupTarget(*backupTarget)
+		if err != nil {
+			glog.Fatalf("Unable to initialize backup target: %v", err)
+			return
+		}
+	}
+
+	ctl.recoverBackups()
+
 	err = initializeCNCICtrls(ctl)
 	if err != nil {
 		glog.Fatal("Unable to initialize CNCI controllers: ", err)
@@ -0,0 +1,288 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net"
+	"sort"
+
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// metadata keys and values are limited to the same length as in the
+// OpenStack compute API.
+const maxMetadataLength = 255
+
+// validateInstanceMetadata checks the key/value metadata of an instance.
+func validateInstanceMetadata(metadata map[string]string) error {
+	for k, v := range metadata {
+		if k == "" || len(k) > maxMetadataLength || len(v) > maxMetadataLength {
+			return types.ErrBadRequest
+		}
+	}
+
+	return nil
+}
+
+// configPublicKeys returns the SSH public keys authorized by the cloud-init
+// config of an instance, both for the default user and for the users the
+// config creates.
+func configPublicKeys(userData string) []string {
+	var config struct {
+		SSHAuthorizedKeys []string      `yaml:"ssh_authorized_keys"`
+		Users             []interface{} `yaml:"users"`
+	}
+
+	err := yaml.Unmarshal([]byte(userData), &config)
+	if err != nil {
+		return nil
+	}
+
+	keys := config.SSHAuthorizedKeys
+
+	for _, u := range config.Users {
+		// users may also be given by name only, e.g., default.
+		user, ok := u.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, field := range []string{"ssh-authorized-keys", "ssh_authorized_keys"} {
+			userKeys, ok := user[field].([]interface{})
+			if !ok {
+				continue
+			}
+
+			for _, k := range userKeys {
+				if key, ok := k.(string); ok {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	return keys
+}
+
+// subnetGateway returns the default gateway of a subnet: the gateway of
+// the tenant network if the subnet belongs to one, otherwise the first
+// host address of the subnet.
+func subnetGateway(subnet string, network *types.TenantNetwork) string {
+	if network != nil && network.Gateway != "" {
+		return network.Gateway
+	}
+
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil || ipNet.IP.To4() == nil {
+		return ""
+	}
+
+	return uint32ToIP(ipToUint32(ipNet.IP) + 1).String()
+}
+
+// metadataNICs describes the NICs of instance i, primary NIC first.
+func metadataNICs(i *types.Instance, networks map[string]*types.TenantNetwork) []payloads.MetadataNIC {
+	subnet, err := canonicalSubnet(i.Subnet)
+	if err != nil {
+		return nil
+	}
+
+	nics := []payloads.MetadataNIC{
+		{
+			MAC:     i.MACAddress,
+			IP:      i.IPAddress,
+			Subnet:  subnet,
+			Gateway: subnetGateway(subnet, networks[i.NetworkID]),
+		},
+	}
+
+	for _, nic := range i.AdditionalNICs {
+		n, ok := networks[nic.NetworkID]
+		if !ok {
+			continue
+		}
+
+		subnet, err := canonicalSubnet(n.CIDR)
+		if err != nil {
+			continue
+		}
+
+		nics = append(nics, payloads.MetadataNIC{
+			MAC:     nic.MACAddress,
+			IP:      nic.IPAddress,
+			Subnet:  subnet,
+			Gateway: subnetGateway(subnet, n),
+		})
+	}
+
+	return nics
+}
+
+// instanceMetadata returns the metadata served to instance i.
+func instanceMetadata(i *types.Instance, networks map[string]*types.TenantNetwork) payloads.InstanceMetadata {
+	md := payloads.InstanceMetadata{
+		InstanceUUID: i.ID,
+		Name:         i.Name,
+		Hostname:     i.ID,
+		NICs:         metadataNICs(i, networks),
+		PublicKeys:   configPublicKeys(i.UserData),
+		Metadata:     i.Metadata,
+		UserData:     i.UserData,
+	}
+
+	if i.Name != "" {
+		md.Hostname = i.Name
+	}
+
+	return md
+}
+
+// metadataRecords returns the metadata of all the instances of a tenant
+// that are attached to subnet.
+func (c *controller) metadataRecords(tenantID string, subnet string) ([]payloads.InstanceMetadata, error) {
+	instances, err := c.ds.GetAllInstancesFromTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantNetworks, err := c.ds.GetTenantNetworks(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	networks := make(map[string]*types.TenantNetwork)
+	for i := range tenantNetworks {
+		networks[tenantNetworks[i].ID] = &tenantNetworks[i]
+	}
+
+	var records []payloads.InstanceMetadata
+	for _, i := range instances {
+		if i.CNCI {
+			continue
+		}
+
+		md := instanceMetadata(i, networks)
+		for _, nic := range md.NICs {
+			if nic.Subnet == subnet && nic.IP != "" {
+				records = append(records, md)
+				break
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].InstanceUUID < records[j].InstanceUUID
+	})
+
+	return records, nil
+}
+
+// updateSubnetMetadata sends the metadata of all the instances attached to
+// a tenant subnet to the CNCI serving that subnet.
+func (c *controller) updateSubnetMetadata(t *types.Tenant, subnet string) error {
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
+	cnci, err := t.CNCIctrl.GetSubnetCNCI(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "unable to find CNCI for subnet %s", subnet)
+	}
+
+	records, err := c.metadataRecords(t.ID, subnet)
+	if err != nil {
+		return errors.Wrapf(err, "unable to retrieve metadata for subnet %s", subnet)
+	}
+
+	return c.client.updateMetadata(*t, cnci.ID, subnet, records)
+}
+
+// updateInstanceMetadata refreshes the metadata of each of the subnets
+// instance i is attached to.  It is called once the instance has been
+// added to or removed from the datastore and when its metadata changes.
+func (c *controller) updateInstanceMetadata(i *types.Instance) {
+	if i.CNCI {
+		return
+	}
+
+	t, err := c.ds.GetTenant(i.TenantID)
+	if err != nil || t == nil {
+		glog.Warningf("Unable to update metadata for instance %s: %v", i.ID, err)
+		return
+	}
+
+	networks, err := c.tenantNetworkSubnets(i.TenantID)
+	if err != nil {
+		glog.Warningf("Unable to update metadata for instance %s: %v", i.ID, err)
+		return
+	}
+
+	for subnet := range instanceSubnets(i, networks) {
+		err = c.updateSubnetMetadata(t, subnet)
+		if err != nil {
+			glog.Warningf("Unable to update metadata for instance %s: %v", i.ID, err)
+		}
+	}
+}
+
+// ShowServerMetadata returns the key/value metadata of an instance.
+func (c *controller) ShowServerMetadata(tenant string, server string) (map[string]string, error) {
+	i, err := c.ds.GetTenantInstance(tenant, server)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]string)
+	for k, v := range i.Metadata {
+		metadata[k] = v
+	}
+
+	return metadata, nil
+}
+
+// UpdateServerMetadata replaces the key/value metadata of an instance and
+// pushes it to the metadata service of the CNCIs serving the instance.
+func (c *controller) UpdateServerMetadata(tenant string, server string, metadata map[string]string) (map[string]string, error) {
+	err := validateInstanceMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := c.ds.GetTenantInstance(tenant, server)
+	if err != nil {
+		return nil, err
+	}
+
+	md := make(map[string]string)
+	for k, v := range metadata {
+		md[k] = v
+	}
+
+	i.Metadata = md
+
+	err = c.ds.UpdateInstance(i)
+	if err != nil {
+		return nil, err
+	}
+
+	c.updateInstanceMetadata(i)
+
+	return c.ShowServerMetadata(tenant, server)
+}
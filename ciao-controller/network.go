@@ -0,0 +1,292 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+)
+
+// tenant networks must leave room for a gateway and at least one instance
+// and must not be larger than the concentrator is able to serve.
+const (
+	minNetworkPrefix = 16
+	maxNetworkPrefix = 30
+)
+
+// the IPv6 prefix of a dual stack network must be a /64 as instances derive
+// their addresses from their MAC addresses.
+const ipv6NetworkPrefix = 64
+
+// setTenantNetwork fills in the subnet configuration of an instance attached
+// to a tenant defined network.
+func setTenantNetwork(networking *payloads.NetworkResources, n types.TenantNetwork) {
+	networking.Subnet = n.CIDR
+	networking.Gateway = n.Gateway
+	networking.DHCPStart = n.AllocationStart
+	networking.DHCPEnd = n.AllocationEnd
+	networking.IPv6Subnet = n.IPv6CIDR
+	networking.IPv6Gateway = n.IPv6Gateway
+	networking.IPv6Mode = n.IPv6Mode
+}
+
+func ipToUint32(ip net.IP) uint32 {
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+func uint32ToIP(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
+
+// parseHostIP parses an address which must be a host address of ipNet, i.e.,
+// neither its network nor its broadcast address.
+func parseHostIP(s string, ipNet *net.IPNet) (net.IP, error) {
+	ip := net.ParseIP(s).To4()
+	if ip == nil || !ipNet.Contains(ip) {
+		return nil, types.ErrBadRequest
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	hostMask := uint32(1)<<uint(bits-ones) - 1
+	host := ipToUint32(ip) & hostMask
+	if host == 0 || host == hostMask {
+		return nil, types.ErrBadRequest
+	}
+
+	return ip, nil
+}
+
+// newIPv6Prefix generates a random /64 unique local address prefix, as
+// described in RFC 4193.
+func newIPv6Prefix() (*net.IPNet, error) {
+	ip := make(net.IP, net.IPv6len)
+	ip[0] = 0xfd
+	_, err := rand.Read(ip[1:8])
+	if err != nil {
+		return nil, err
+	}
+
+	return &net.IPNet{
+		IP:   ip,
+		Mask: net.CIDRMask(ipv6NetworkPrefix, 8*net.IPv6len),
+	}, nil
+}
+
+// setIPv6Network validates the IPv6 part of a network request and makes
+// the network dual stack if it is requested.  A unique local prefix is
+// generated if no prefix is provided, the gateway is always the first
+// address of the prefix and the mode defaults to SLAAC.
+func setIPv6Network(n *types.TenantNetwork, req api.RequestedNetwork) error {
+	if req.IPv6CIDR == "" && req.IPv6Mode == "" {
+		return nil
+	}
+
+	mode := req.IPv6Mode
+	if mode == "" {
+		mode = types.IPv6SLAAC
+	}
+	if mode != types.IPv6SLAAC && mode != types.IPv6DHCP {
+		return types.ErrBadRequest
+	}
+
+	var prefix *net.IPNet
+	if req.IPv6CIDR != "" {
+		ip, ipNet, err := net.ParseCIDR(req.IPv6CIDR)
+		if err != nil || ip.To4() != nil {
+			return types.ErrBadRequest
+		}
+
+		ones, _ := ipNet.Mask.Size()
+		if ones != ipv6NetworkPrefix {
+			return types.ErrBadRequest
+		}
+		prefix = ipNet
+	} else {
+		var err error
+		prefix, err = newIPv6Prefix()
+		if err != nil {
+			return err
+		}
+	}
+
+	gateway := make(net.IP, net.IPv6len)
+	copy(gateway, prefix.IP)
+	gateway[net.IPv6len-1] = 1
+
+	n.IPv6CIDR = prefix.String()
+	n.IPv6Gateway = gateway.String()
+	n.IPv6Mode = mode
+
+	return nil
+}
+
+// newTenantNetwork validates a network request and fills in the defaults for
+// the optional fields.  The gateway defaults to the first host address of the
+// subnet and the allocation range to all the remaining host addresses.
+func newTenantNetwork(tenant string, req api.RequestedNetwork) (types.TenantNetwork, error) {
+	ip, ipNet, err := net.ParseCIDR(req.CIDR)
+	if err != nil || ip.To4() == nil {
+		return types.TenantNetwork{}, types.ErrBadRequest
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	if ones < minNetworkPrefix || ones > maxNetworkPrefix {
+		return types.TenantNetwork{}, types.ErrBadRequest
+	}
+
+	network := ipToUint32(ipNet.IP)
+	broadcast := network | ^ipToUint32(net.IP(ipNet.Mask))
+
+	gateway := uint32ToIP(network + 1)
+	if req.Gateway != "" {
+		gateway, err = parseHostIP(req.Gateway, ipNet)
+		if err != nil {
+			return types.TenantNetwork{}, err
+		}
+	}
+
+	start := uint32ToIP(network + 2)
+	if req.AllocationStart != "" {
+		start, err = parseHostIP(req.AllocationStart, ipNet)
+		if err != nil {
+			return types.TenantNetwork{}, err
+		}
+	}
+
+	end := uint32ToIP(broadcast - 1)
+	if req.AllocationEnd != "" {
+		end, err = parseHostIP(req.AllocationEnd, ipNet)
+		if err != nil {
+			return types.TenantNetwork{}, err
+		}
+	}
+
+	if bytes.Compare(start, end) > 0 {
+		return types.TenantNetwork{}, types.ErrBadRequest
+	}
+
+	// the range must contain at least one address for an instance.
+	if start.Equal(end) && start.Equal(gateway) {
+		return types.TenantNetwork{}, types.ErrBadRequest
+	}
+
+	n := types.TenantNetwork{
+		ID:              uuid.Generate().String(),
+		TenantID:        tenant,
+		Name:            req.Name,
+		CIDR:            ipNet.String(),
+		Gateway:         gateway.String(),
+		AllocationStart: start.String(),
+		AllocationEnd:   end.String(),
+		CreateTime:      time.Now(),
+	}
+
+	err = setIPv6Network(&n, req)
+	if err != nil {
+		return types.TenantNetwork{}, err
+	}
+
+	return n, nil
+}
+
+// CreateNetwork defines a new network for a tenant.  Instances attached to
+// the network are assigned addresses from its allocation range.
+func (c *controller) CreateNetwork(tenant string, req api.RequestedNetwork) (types.TenantNetwork, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.TenantNetwork{}, err
+	}
+
+	n, err := newTenantNetwork(tenant, req)
+	if err != nil {
+		return types.TenantNetwork{}, err
+	}
+
+	err = c.ds.AddTenantNetwork(n)
+	if err != nil {
+		return types.TenantNetwork{}, err
+	}
+
+	return n, nil
+}
+
+// ListNetworks returns all the networks defined by a tenant.
+func (c *controller) ListNetworks(tenant string) ([]types.TenantNetwork, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ds.GetTenantNetworks(tenant)
+}
+
+// ShowNetwork returns a single tenant network.
+func (c *controller) ShowNetwork(tenant string, network string) (types.TenantNetwork, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.TenantNetwork{}, err
+	}
+
+	return c.ds.GetTenantNetwork(tenant, network)
+}
+
+// DeleteNetwork removes a tenant network.  Networks with instances still
+// attached cannot be deleted.
+func (c *controller) DeleteNetwork(tenant string, network string) error {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	err = c.ds.DeleteTenantNetwork(tenant, network)
+	if err != nil {
+		return err
+	}
+
+	c.deleteNetworkPeerings(network)
+
+	return nil
+}
+
+// ListReservedIPs returns the addresses reserved by a tenant.
+func (c *controller) ListReservedIPs(tenant string) ([]types.ReservedIP, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ds.GetReservedIPs(tenant)
+}
+
+// ReleaseReservedIP removes the reservation of an address retained after
+// the deletion of an instance, allowing it to be assigned to any instance.
+func (c *controller) ReleaseReservedIP(tenant string, ip string) error {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.ds.ReleaseReservedIP(tenant, ip)
+}
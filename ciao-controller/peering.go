@@ -0,0 +1,318 @@
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ciao-project/ciao/ciao-controller/api"
+	"github.com/ciao-project/ciao/ciao-controller/types"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/uuid"
+	"github.com/golang/glog"
+)
+
+// peeringParty returns true if tenant owns either network of a peering.
+func peeringParty(p types.NetworkPeering, tenant string) bool {
+	return p.TenantID == tenant || p.PeerTenantID == tenant
+}
+
+// peerOf returns the tenant and subnet on the other side of a peering from
+// the given tenant subnet.  ok is false if the subnet is not peered by p.
+func peerOf(p types.NetworkPeering, tenantID string, subnet string) (peerTenant string, peerSubnet string, ok bool) {
+	local, err := canonicalSubnet(p.CIDR)
+	if err == nil && p.TenantID == tenantID && local == subnet {
+		return p.PeerTenantID, p.PeerCIDR, true
+	}
+
+	peer, err := canonicalSubnet(p.PeerCIDR)
+	if err == nil && p.PeerTenantID == tenantID && peer == subnet {
+		return p.TenantID, p.CIDR, true
+	}
+
+	return "", "", false
+}
+
+// subnetCNCI returns the tenant and the CNCI serving one of its subnets.
+// The CNCI is nil if the subnet has no CNCI, e.g., when no instance is
+// attached to it.
+func (c *controller) subnetCNCI(tenantID string, subnet string) (*types.Tenant, *types.Instance) {
+	t, err := c.ds.GetTenant(tenantID)
+	if err != nil || t == nil || t.CNCIctrl == nil {
+		return nil, nil
+	}
+
+	cnci, err := t.CNCIctrl.GetSubnetCNCI(subnet)
+	if err != nil || cnci.IPAddress == "" {
+		return t, nil
+	}
+
+	return t, cnci
+}
+
+// subnetPeers returns the peer subnets of the active peerings of a tenant
+// subnet which are served by a CNCI.
+func (c *controller) subnetPeers(tenantID string, subnet string) []payloads.PeerSubnet {
+	peers := []payloads.PeerSubnet{}
+
+	for _, p := range c.ds.GetNetworkPeerings() {
+		if p.Status != types.PeeringActive {
+			continue
+		}
+
+		peerTenant, peerSubnet, ok := peerOf(p, tenantID, subnet)
+		if !ok {
+			continue
+		}
+
+		_, cnci := c.subnetCNCI(peerTenant, peerSubnet)
+		if cnci == nil {
+			continue
+		}
+
+		peers = append(peers, payloads.PeerSubnet{
+			PeeringUUID:    p.ID,
+			Subnet:         peerSubnet,
+			ConcentratorIP: cnci.IPAddress,
+		})
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].PeeringUUID < peers[j].PeeringUUID
+	})
+
+	return peers
+}
+
+// updateSubnetPeering sends the peer subnets of a tenant subnet to the CNCI
+// serving the subnet, if any.
+func (c *controller) updateSubnetPeering(tenantID string, subnet string) error {
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return err
+	}
+
+	t, cnci := c.subnetCNCI(tenantID, subnet)
+	if cnci == nil {
+		return nil
+	}
+
+	return c.client.updatePeering(*t, cnci.ID, subnet, c.subnetPeers(tenantID, subnet))
+}
+
+// refreshNetworkPeering updates the CNCIs of both networks of a peering.
+func (c *controller) refreshNetworkPeering(p types.NetworkPeering) {
+	sides := []struct {
+		tenant string
+		subnet string
+	}{
+		{p.TenantID, p.CIDR},
+		{p.PeerTenantID, p.PeerCIDR},
+	}
+
+	for _, s := range sides {
+		err := c.updateSubnetPeering(s.tenant, s.subnet)
+		if err != nil {
+			glog.Warningf("Unable to update peering %s of %s: %v", p.ID, s.subnet, err)
+		}
+	}
+}
+
+// restoreNetworkPeerings configures the peerings of a tenant subnet on the
+// CNCI serving the subnet and points the CNCIs of the peer subnets to it.
+// It is called when a new CNCI takes over a subnet.
+func (c *controller) restoreNetworkPeerings(t *types.Tenant, subnet string) {
+	subnet, err := canonicalSubnet(subnet)
+	if err != nil {
+		return
+	}
+
+	for _, p := range c.ds.GetNetworkPeerings() {
+		if p.Status != types.PeeringActive {
+			continue
+		}
+
+		if _, _, ok := peerOf(p, t.ID, subnet); ok {
+			c.refreshNetworkPeering(p)
+		}
+	}
+}
+
+// removeNetworkPeering deletes a peering and, if it was active, withdraws
+// its routes from the CNCIs of both networks.
+func (c *controller) removeNetworkPeering(p types.NetworkPeering) error {
+	err := c.ds.DeleteNetworkPeering(p.ID)
+	if err != nil {
+		return err
+	}
+
+	if p.Status == types.PeeringActive {
+		c.refreshNetworkPeering(p)
+	}
+
+	return nil
+}
+
+// deleteNetworkPeerings removes the peerings of a deleted tenant network.
+func (c *controller) deleteNetworkPeerings(networkID string) {
+	for _, p := range c.ds.GetNetworkPeerings() {
+		if p.NetworkID != networkID && p.PeerNetworkID != networkID {
+			continue
+		}
+
+		err := c.removeNetworkPeering(p)
+		if err != nil {
+			glog.Warningf("Unable to remove peering %s: %v", p.ID, err)
+		}
+	}
+}
+
+// deleteTenantPeerings removes all the peerings a tenant is party to.
+func (c *controller) deleteTenantPeerings(tenant string) error {
+	for _, p := range c.ds.GetNetworkPeerings() {
+		if !peeringParty(p, tenant) {
+			continue
+		}
+
+		err := c.removeNetworkPeering(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateNetworkPeering requests the peering of a network of a tenant with
+// a network of another tenant.  The peering remains pending until it is
+// approved by an administrator.
+func (c *controller) CreateNetworkPeering(tenant string, req api.RequestedNetworkPeering) (types.NetworkPeering, error) {
+	err := c.confirmTenant(tenant)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	if req.PeerTenantID == "" || req.PeerTenantID == tenant {
+		return types.NetworkPeering{}, types.ErrBadRequest
+	}
+
+	n, err := c.ds.GetTenantNetwork(tenant, req.NetworkID)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	peerTenant, err := c.ds.GetTenant(req.PeerTenantID)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	if peerTenant == nil {
+		return types.NetworkPeering{}, types.ErrTenantNotFound
+	}
+
+	peer, err := c.ds.GetTenantNetwork(req.PeerTenantID, req.PeerNetworkID)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	p := types.NetworkPeering{
+		ID:            uuid.Generate().String(),
+		Name:          req.Name,
+		TenantID:      tenant,
+		NetworkID:     n.ID,
+		CIDR:          n.CIDR,
+		PeerTenantID:  peer.TenantID,
+		PeerNetworkID: peer.ID,
+		PeerCIDR:      peer.CIDR,
+		Status:        types.PeeringPending,
+		CreateTime:    time.Now(),
+	}
+
+	err = c.ds.AddNetworkPeering(p)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	return p, nil
+}
+
+// ListNetworkPeerings returns the network peerings a tenant is party to, or
+// all the network peerings if tenant is empty.
+func (c *controller) ListNetworkPeerings(tenant string) ([]types.NetworkPeering, error) {
+	peerings := []types.NetworkPeering{}
+
+	for _, p := range c.ds.GetNetworkPeerings() {
+		if tenant == "" || peeringParty(p, tenant) {
+			peerings = append(peerings, p)
+		}
+	}
+
+	sort.Slice(peerings, func(i, j int) bool {
+		return peerings[i].CreateTime.Before(peerings[j].CreateTime)
+	})
+
+	return peerings, nil
+}
+
+// ShowNetworkPeering returns a single network peering.  Tenants may only
+// retrieve the peerings they are party to.
+func (c *controller) ShowNetworkPeering(tenant string, ID string) (types.NetworkPeering, error) {
+	p, err := c.ds.GetNetworkPeering(ID)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	if tenant != "" && !peeringParty(p, tenant) {
+		return types.NetworkPeering{}, types.ErrNetworkPeeringNotFound
+	}
+
+	return p, nil
+}
+
+// ApproveNetworkPeering activates a pending network peering.  The CNCIs of
+// the two networks start routing traffic between them.
+func (c *controller) ApproveNetworkPeering(ID string) (types.NetworkPeering, error) {
+	p, err := c.ds.GetNetworkPeering(ID)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	if p.Status == types.PeeringActive {
+		return p, nil
+	}
+
+	p.Status = types.PeeringActive
+
+	err = c.ds.UpdateNetworkPeering(p)
+	if err != nil {
+		return types.NetworkPeering{}, err
+	}
+
+	c.refreshNetworkPeering(p)
+
+	return p, nil
+}
+
+// DeleteNetworkPeering removes a network peering.  Either tenant party to
+// the peering, or an administrator when tenant is empty, may remove it.
+func (c *controller) DeleteNetworkPeering(tenant string, ID string) error {
+	p, err := c.ShowNetworkPeering(tenant, ID)
+	if err != nil {
+		return err
+	}
+
+	return c.removeNetworkPeering(p)
+}
@@ -79,11 +79,40 @@ func populateQuotasFromDatastore(qs *quotas.Quotas, ds *datastore.Datastore) err
 			if err != nil {
 				return errors.Wrapf(err, "error getting workload")
 			}
-			resources := []payloads.RequestedResource{{Type: payloads.Instance, Value: 1}}
-			resources = append(resources, wl.Defaults...)
-			<-qs.Consume(t.ID, resources...)
+			<-qs.Consume(t.ID, instanceResources(instance, &wl)...)
 		}
 	}
 
 	return nil
 }
+
+// suspendedResources returns the resources of an instance of workload wl
+// that are given back to the tenant while the instance is suspended.
+func suspendedResources(wl *types.Workload) []payloads.RequestedResource {
+	var resources []payloads.RequestedResource
+	for _, r := range wl.Defaults {
+		if r.Type == payloads.VCPUs || r.Type == payloads.MemMB {
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
+
+// instanceResources returns the resources currently accounted to instance
+// i of workload wl.  Paused instances retain all their resources, but
+// suspended instances do not use their VCPUs or memory.  The Suspended flag
+// of the instance, rather than its state, is used as it is updated along
+// with the tenant's quota when the suspend or resume is requested.
+func instanceResources(i *types.Instance, wl *types.Workload) []payloads.RequestedResource {
+	resources := []payloads.RequestedResource{{Type: payloads.Instance, Value: 1}}
+	if !i.Suspended {
+		return append(resources, wl.Defaults...)
+	}
+
+	for _, r := range wl.Defaults {
+		if r.Type != payloads.VCPUs && r.Type != payloads.MemMB {
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
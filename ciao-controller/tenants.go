@@ -15,11 +15,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/ciao-project/ciao/ciao-controller/types"
 	"github.com/ciao-project/ciao/uuid"
+	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/golang/glog"
 	"github.com/pkg/errors"
 )
@@ -65,13 +68,52 @@ func (c *controller) ShowTenant(tenantID string) (types.TenantConfig, error) {
 
 	config.Name = tenant.Name
 	config.SubnetBits = tenant.SubnetBits
+	config.DNSDomain = tenant.DNSDomain
+	config.DNSForwarders = tenant.DNSForwarders
 
 	return config, err
 }
 
 func (c *controller) PatchTenant(tenantID string, patch []byte) error {
+	old, err := c.ShowTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	// validate the DNS settings before they are stored.
+	orig, err := json.Marshal(old)
+	if err != nil {
+		return errors.Wrap(err, "error updating tenant")
+	}
+
+	patched, err := jsonpatch.MergePatch(orig, patch)
+	if err != nil {
+		return errors.Wrap(err, "error updating tenant")
+	}
+
+	var config types.TenantConfig
+	err = json.Unmarshal(patched, &config)
+	if err != nil {
+		return errors.Wrap(err, "error updating tenant")
+	}
+
+	err = validateDNSConfig(config)
+	if err != nil {
+		return err
+	}
+
 	// we need to update through datastore.
-	return c.ds.JSONPatchTenant(tenantID, patch)
+	err = c.ds.JSONPatchTenant(tenantID, patch)
+	if err != nil {
+		return err
+	}
+
+	if config.DNSDomain != old.DNSDomain ||
+		strings.Join(config.DNSForwarders, ",") != strings.Join(old.DNSForwarders, ",") {
+		go c.updateTenantDNS(tenantID)
+	}
+
+	return nil
 }
 
 func (c *controller) CreateTenant(tenantID string, config types.TenantConfig) (types.TenantSummary, error) {
@@ -90,6 +132,11 @@ func (c *controller) CreateTenant(tenantID string, config types.TenantConfig) (t
 		}
 	}
 
+	err = validateDNSConfig(config)
+	if err != nil {
+		return types.TenantSummary{}, err
+	}
+
 	tenant, err := c.ds.AddTenant(tuuid.String(), config)
 	if err != nil {
 		return types.TenantSummary{}, err
@@ -135,14 +182,7 @@ func (c *controller) deleteCNCIInstances(tenantID string) error {
 		go func(ID string, CIDR string) {
 			defer wg.Done()
 
-			subnet, err := subnetStringToInt(CIDR)
-			if err != nil {
-				c.client.RemoveInstance(ID)
-				glog.Warningf("Unable to remove tenant cnci: %v", err)
-				return
-			}
-
-			err = tenant.CNCIctrl.RemoveSubnet(subnet)
+			err := tenant.CNCIctrl.RemoveSubnetString(CIDR)
 			if err != nil {
 				// remove directly.
 				c.client.RemoveInstance(ID)
@@ -169,6 +209,18 @@ func (c *controller) deleteInstances(tenantID string) error {
 		}
 	}
 
+	// remove any load balancers
+	err := c.deleteLoadBalancers(tenantID)
+	if err != nil {
+		return errors.Wrap(err, "Unable to remove tenant")
+	}
+
+	// remove any peerings with the networks of other tenants
+	err = c.deleteTenantPeerings(tenantID)
+	if err != nil {
+		return errors.Wrap(err, "Unable to remove tenant")
+	}
+
 	// delete all this tenant's instances.
 	instances, err := c.ds.GetAllInstancesFromTenant(tenantID)
 	if err != nil {
@@ -86,6 +86,7 @@ type Workload struct {
 	VMType      payloads.Hypervisor          `json:"vm_type"`
 	ImageName   string                       `json:"image_name"`
 	Config      string                       `json:"config"`
+	Template    bool                         `json:"template,omitempty"`
 	Defaults    []payloads.RequestedResource `json:"defaults"`
 	Storage     []StorageResource            `json:"storage"`
 }
@@ -107,26 +108,63 @@ type WorkloadRequest struct {
 	Volumes    []storage.BlockDevice
 	Name       string
 	Subnet     string
+	NetworkID  string
+	Metadata   map[string]string
+
+	// AdditionalNetworks contains the IDs of the tenant networks to
+	// which the NICs of the instances other than the first are attached.
+	AdditionalNetworks []string
+
+	// IPAddress is the fixed address requested for the instance.  If
+	// empty the next free address is allocated.
+	IPAddress string
+
+	// RetainIP requests that the address of the instance stays reserved
+	// for the tenant once the instance is deleted.
+	RetainIP bool
+
+	// ExcludedNodes contains the IDs of the nodes on which the instances
+	// must not be started.
+	ExcludedNodes []string
+}
+
+// InstanceNIC describes a NIC of an instance, other than the first,
+// attached to a tenant network.
+type InstanceNIC struct {
+	NetworkID  string `json:"network_id"`
+	MACAddress string `json:"mac_address"`
+	VnicUUID   string `json:"vnic_uuid"`
+	IPAddress  string `json:"ip_address"`
 }
 
 // Instance contains information about an instance of a workload.
 type Instance struct {
-	ID          string       `json:"instance_id"`
-	TenantID    string       `json:"tenant_id"`
-	State       string       `json:"instance_state"`
-	WorkloadID  string       `json:"workload_id"`
-	NodeID      string       `json:"node_id"`
-	MACAddress  string       `json:"mac_address"`
-	VnicUUID    string       `json:"vnic_uuid"`
-	Subnet      string       `json:"subnet"`
-	IPAddress   string       `json:"ip_address"`
-	SSHIP       string       `json:"ssh_ip"`
-	SSHPort     int          `json:"ssh_port"`
-	CNCI        bool         `json:"-"`
-	CreateTime  time.Time    `json:"-"`
-	Name        string       `json:"name"`
-	StateLock   sync.RWMutex `json:"-"`
-	StateChange *sync.Cond   `json:"-"`
+	ID             string                `json:"instance_id"`
+	TenantID       string                `json:"tenant_id"`
+	State          string                `json:"instance_state"`
+	WorkloadID     string                `json:"workload_id"`
+	NodeID         string                `json:"node_id"`
+	MACAddress     string                `json:"mac_address"`
+	VnicUUID       string                `json:"vnic_uuid"`
+	Subnet         string                `json:"subnet"`
+	NetworkID      string                `json:"network_id"`
+	IPAddress      string                `json:"ip_address"`
+	AdditionalNICs []InstanceNIC         `json:"additional_nics,omitempty"`
+	RetainIP       bool                  `json:"retain_ip"`
+	SSHIP          string                `json:"ssh_ip"`
+	SSHPort        int                   `json:"ssh_port"`
+	CNCI           bool                  `json:"-"`
+	CNCIStandby    bool                  `json:"-"`
+	Suspended      bool                  `json:"-"`
+	CreateTime     time.Time             `json:"-"`
+	Name           string                `json:"name"`
+	Metadata       map[string]string     `json:"metadata,omitempty"`
+	UserData       string                `json:"-"`
+	Guest          *payloads.GuestInfo   `json:"-"`
+	BlockIO        *payloads.BlockIOStat `json:"-"`
+	NetworkIO      *payloads.VnicIOStat  `json:"-"`
+	StateLock      sync.RWMutex          `json:"-"`
+	StateChange    *sync.Cond            `json:"-"`
 }
 
 // SortedInstancesByID implements sort.Interface for Instance by ID string
@@ -145,16 +183,20 @@ func (s SortedNodesByID) Less(i, j int) bool { return s[i].ID < s[j].ID }
 
 // TenantConfig stores the configurable attributes of a tenant.
 type TenantConfig struct {
-	Name       string `json:"name"`
-	SubnetBits int    `json:"subnet_bits"`
+	Name          string   `json:"name"`
+	SubnetBits    int      `json:"subnet_bits"`
+	DNSDomain     string   `json:"dns_domain,omitempty"`
+	DNSForwarders []string `json:"dns_forwarders,omitempty"`
 }
 
 // Tenant contains information about a tenant or project.
 type Tenant struct {
-	ID         string
-	Name       string
-	CNCIctrl   CNCIController
-	SubnetBits int
+	ID            string
+	Name          string
+	CNCIctrl      CNCIController
+	SubnetBits    int
+	DNSDomain     string
+	DNSForwarders []string
 }
 
 // TenantSummary is a short form of Tenant
@@ -261,6 +303,10 @@ type Node struct {
 type BlockState string
 
 const (
+	// Creating means that the volume data is still being written,
+	// e.g., when a volume is restored from a backup.
+	Creating BlockState = "creating"
+
 	// Available means that the volume is ok for attaching.
 	Available BlockState = "available"
 
@@ -277,6 +323,19 @@ const (
 	Detaching BlockState = "detaching"
 )
 
+// AttachmentMode represents the access mode of a storage attachment.
+type AttachmentMode string
+
+const (
+	// ReadWrite means that the instance can read from and write to
+	// the attached volume.
+	ReadWrite AttachmentMode = "rw"
+
+	// ReadOnly means that the instance can only read from the
+	// attached volume.
+	ReadOnly AttachmentMode = "ro"
+)
+
 // Volume respresents the attributes of this block device.
 // TBD - do we really need to store this as actual data,
 // or can we use a set of interfaces to get the info?
@@ -288,16 +347,157 @@ type Volume struct {
 	Name        string     `json:"name"`        // a human readable name for this volume
 	Description string     `json:"description"` // some text to describe this volume.
 	Internal    bool       `json:"internal"`    // whether this storage should be shown to the user
+	MultiAttach bool       `json:"multiattach"` // whether this volume can be attached to more than one instance
+
+	// Attachments lists the instances this volume is attached to.
+	// It is only filled in when volumes are returned by the API.
+	Attachments []StorageAttachment `json:"attachments,omitempty"`
 }
 
 // StorageAttachment represents a link between a block device and
 // an instance.
 type StorageAttachment struct {
-	ID         string // a uuid
-	InstanceID string // the instance this volume is attached to
-	BlockID    string // the ID of the block device
-	Ephemeral  bool   // whether the storage should be deleted on Cleanup
-	Boot       bool   // whether this is a boot device
+	ID         string         `json:"id"`          // a uuid
+	InstanceID string         `json:"instance_id"` // the instance this volume is attached to
+	BlockID    string         `json:"volume_id"`   // the ID of the block device
+	Ephemeral  bool           `json:"ephemeral"`   // whether the storage should be deleted on Cleanup
+	Boot       bool           `json:"boot"`        // whether this is a boot device
+	Mode       AttachmentMode `json:"mode"`        // whether the volume is attached read-write or read-only
+	Detaching  bool           `json:"detaching"`   // whether a detach of this attachment is in progress
+}
+
+// BackupState represents the state of a volume backup.
+type BackupState string
+
+const (
+	// BackupCreating means that the volume data is being exported to
+	// the backup target.
+	BackupCreating BackupState = "creating"
+
+	// BackupAvailable means that the backup is complete and can be
+	// restored.
+	BackupAvailable BackupState = "available"
+
+	// BackupError means that the backup could not be created.
+	BackupError BackupState = "error"
+)
+
+// Backup represents a copy of a volume stored on a backup target outside
+// of the cluster.
+type Backup struct {
+	ID          string      `json:"id"`          // a uuid
+	TenantID    string      `json:"tenant_id"`   // the tenant who owns this backup
+	VolumeID    string      `json:"volume_id"`   // the volume that was backed up
+	VolumeSize  int         `json:"volume_size"` // the size in GiB of the volume that was backed up
+	State       BackupState `json:"state"`       // status of the backup
+	CreateTime  time.Time   `json:"created"`     // when we created the backup
+	Name        string      `json:"name"`        // a human readable name for this backup
+	Description string      `json:"description"` // some text to describe this backup
+	Size        int64       `json:"size"`        // the number of bytes stored on the backup target
+	Checksum    string      `json:"checksum"`    // hex encoded SHA-256 of the backup data
+}
+
+// TenantNetwork represents a network defined by a tenant.  Instances
+// attached to the network are assigned addresses from its allocation range
+// and use its gateway as their default route.
+type TenantNetwork struct {
+	ID              string    `json:"id"`                     // a uuid
+	TenantID        string    `json:"tenant_id"`              // the tenant who owns this network
+	Name            string    `json:"name"`                   // a human readable name for this network
+	CIDR            string    `json:"cidr"`                   // the subnet of the network
+	Gateway         string    `json:"gateway"`                // the default gateway of the network
+	AllocationStart string    `json:"allocation_start"`       // the first address assigned to instances
+	AllocationEnd   string    `json:"allocation_end"`         // the last address assigned to instances
+	IPv6CIDR        string    `json:"ipv6_cidr,omitempty"`    // the IPv6 /64 prefix of a dual stack network
+	IPv6Gateway     string    `json:"ipv6_gateway,omitempty"` // the IPv6 default gateway of the network
+	IPv6Mode        string    `json:"ipv6_mode,omitempty"`    // how instances are assigned IPv6 addresses
+	CreateTime      time.Time `json:"created"`                // when we created the network
+}
+
+// IPv6 address assignment modes of dual stack tenant networks.
+const (
+	// IPv6SLAAC means instances configure their IPv6 addresses from the
+	// router advertisements of the network.
+	IPv6SLAAC = "slaac"
+
+	// IPv6DHCP means instances are assigned their IPv6 addresses by the
+	// DHCPv6 server of the network.
+	IPv6DHCP = "dhcpv6"
+)
+
+// ReservedIP represents an address retained by a tenant after the deletion
+// of the instance to which it was assigned.  The address can only be
+// assigned to an instance which explicitly requests it.
+type ReservedIP struct {
+	TenantID   string    `json:"tenant_id"`            // the tenant who owns this address
+	IPAddress  string    `json:"ip_address"`           // the reserved address
+	NetworkID  string    `json:"network_id,omitempty"` // the tenant network of the address, if any
+	InstanceID string    `json:"instance_id"`          // the instance the address was retained from
+	CreateTime time.Time `json:"created"`              // when the address was reserved
+}
+
+// LoadBalancer represents a load balancer defined by a tenant.  Traffic
+// sent to the listener port of its virtual IP is spread by the CNCI of the
+// subnet of its members across the members passing the health check.
+type LoadBalancer struct {
+	ID          string                  `json:"id"`           // a uuid
+	TenantID    string                  `json:"tenant_id"`    // the tenant who owns this load balancer
+	Name        string                  `json:"name"`         // a human readable name for this load balancer
+	VIP         string                  `json:"vip"`          // the external virtual IP of the load balancer
+	PoolID      string                  `json:"pool_id"`      // the external IP pool of the virtual IP
+	PoolName    string                  `json:"pool_name"`    // the name of the external IP pool
+	Subnet      string                  `json:"subnet"`       // the tenant subnet of the members
+	Port        int                     `json:"port"`         // the listener port
+	Protocol    string                  `json:"protocol"`     // tcp or http
+	Members     []LoadBalancerMember    `json:"members"`      // the instances receiving the traffic
+	HealthCheck LoadBalancerHealthCheck `json:"health_check"` // how the members are checked
+	CreateTime  time.Time               `json:"created"`      // when we created the load balancer
+}
+
+// LoadBalancerMember is an instance receiving the traffic of a load balancer.
+type LoadBalancerMember struct {
+	InstanceID string `json:"instance_id"` // the member instance
+	IPAddress  string `json:"ip_address"`  // the address of the instance on the load balancer subnet
+	Port       int    `json:"port"`        // the port of the instance the traffic is sent to
+}
+
+// LoadBalancerHealthCheck describes how the members of a load balancer are
+// checked.  Members failing the check stop receiving traffic.
+type LoadBalancerHealthCheck struct {
+	Interval int    `json:"interval"`       // seconds between two checks of a member
+	Timeout  int    `json:"timeout"`        // seconds a member has to answer a check
+	Retries  int    `json:"retries"`        // consecutive checks to fail or pass to change state
+	Path     string `json:"path,omitempty"` // the URI requested by the checks of http load balancers
+}
+
+// NetworkPeeringStatus is the state of a network peering.
+type NetworkPeeringStatus string
+
+const (
+	// PeeringPending means the peering has been requested by a tenant
+	// and awaits the approval of an administrator.
+	PeeringPending NetworkPeeringStatus = "pending"
+
+	// PeeringActive means the peering has been approved and traffic is
+	// routed between the two networks.
+	PeeringActive NetworkPeeringStatus = "active"
+)
+
+// NetworkPeering connects two networks of different tenants.  Once approved
+// by an administrator, the CNCIs of the two networks route traffic between
+// them without address translation, so the subnets of the networks may not
+// overlap.
+type NetworkPeering struct {
+	ID            string               `json:"id"`              // a uuid
+	Name          string               `json:"name"`            // a human readable name for this peering
+	TenantID      string               `json:"tenant_id"`       // the tenant who requested the peering
+	NetworkID     string               `json:"network_id"`      // the network of the requesting tenant
+	CIDR          string               `json:"cidr"`            // the subnet of the network
+	PeerTenantID  string               `json:"peer_tenant_id"`  // the tenant owning the peer network
+	PeerNetworkID string               `json:"peer_network_id"` // the peer network
+	PeerCIDR      string               `json:"peer_cidr"`       // the subnet of the peer network
+	Status        NetworkPeeringStatus `json:"status"`          // pending or active
+	CreateTime    time.Time            `json:"created"`         // when the peering was requested
 }
 
 // CiaoNode contains status and statistic information for an individual
@@ -311,8 +511,13 @@ type CiaoNode struct {
 	MemAvailable          int       `json:"ram_available"`
 	DiskTotal             int       `json:"disk_total"`
 	DiskAvailable         int       `json:"disk_available"`
+	DiskReserved          int       `json:"disk_reserved"`
+	DiskUsed              int       `json:"disk_used"`
 	Load                  int       `json:"load"`
 	OnlineCPUs            int       `json:"online_cpus"`
+	DedicatedCPUs         int       `json:"dedicated_cpus_available"`
+	HugePagesTotal        int       `json:"hugepages_total"`
+	HugePagesAvailable    int       `json:"hugepages_available"`
 	TotalInstances        int       `json:"total_instances"`
 	TotalRunningInstances int       `json:"total_running_instances"`
 	TotalPendingInstances int       `json:"total_pending_instances"`
@@ -576,6 +781,10 @@ var (
 	// ErrSubnetTooSmall is returned when an invalid subnet is used
 	ErrSubnetTooSmall = errors.New("Requested subnet is too small to be usable")
 
+	// ErrSubnetTooLarge is returned when a subnet has too many addresses
+	// to be managed as an external IP pool
+	ErrSubnetTooLarge = errors.New("Requested subnet is too large to be usable")
+
 	// ErrPoolNotFound is returned when an external IP pool is not found
 	ErrPoolNotFound = errors.New("Pool not found")
 
@@ -606,8 +815,95 @@ var (
 
 	// ErrWorkloadInUse is returned by DeleteWorkload when an instance of a workload is still active.
 	ErrWorkloadInUse = errors.New("Workload definition still in use")
+
+	// ErrBackupNotFound is returned when a backup ID cannot be found
+	ErrBackupNotFound = errors.New("Backup not found")
+
+	// ErrBackupNotAvailable is returned when a backup cannot be restored
+	// or deleted because it is still being created or has failed.
+	ErrBackupNotAvailable = errors.New("Backup not available")
+
+	// ErrBackupsNotConfigured is returned when no backup target has been
+	// configured.
+	ErrBackupsNotConfigured = errors.New("No backup target configured")
+
+	// ErrBackupChecksum is returned when the data read back from a backup
+	// target does not match the recorded checksum.
+	ErrBackupChecksum = errors.New("Backup checksum mismatch")
+
+	// ErrConsoleLogTimeout is returned when the node running an instance
+	// does not return its console log in time.
+	ErrConsoleLogTimeout = errors.New("Timed out waiting for console log")
+
+	// ErrConsoleLogNotSupported is returned when the console log of a
+	// container is requested.
+	ErrConsoleLogNotSupported = errors.New("Console logs are not available for containers")
+
+	// ErrConsoleTimeout is returned when the node running an instance
+	// does not open a console session in time.
+	ErrConsoleTimeout = errors.New("Timed out waiting for console")
+
+	// ErrConsoleTokenInvalid is returned when a console token is unknown,
+	// has expired or has already been used.
+	ErrConsoleTokenInvalid = errors.New("Invalid console token")
+
+	// ErrInstanceNotRunning is returned when an operation requires a
+	// running instance.
+	ErrInstanceNotRunning = errors.New("Instance is not running")
+
+	// ErrNetworkNotFound is returned when a tenant network cannot be found
+	ErrNetworkNotFound = errors.New("Network not found")
+
+	// ErrNetworkInUse is returned when a tenant network which still has
+	// instances attached to it is deleted.
+	ErrNetworkInUse = errors.New("Network still in use")
+
+	// ErrNetworkOverlap is returned when the subnet of a new tenant network
+	// overlaps the subnet of another network of the same tenant.
+	ErrNetworkOverlap = errors.New("Network overlaps an existing network")
+
+	// ErrNetworkFull is returned when all the addresses in the allocation
+	// range of a tenant network have been assigned.
+	ErrNetworkFull = errors.New("No free addresses in network")
+
+	// ErrAddressInUse is returned when a fixed address requested for an
+	// instance is already assigned or reserved.
+	ErrAddressInUse = errors.New("Address already in use")
+
+	// ErrLoadBalancerNotFound is returned when a load balancer cannot be
+	// found
+	ErrLoadBalancerNotFound = errors.New("Load balancer not found")
+
+	// ErrNetworkPeeringNotFound is returned when a network peering cannot
+	// be found
+	ErrNetworkPeeringNotFound = errors.New("Network peering not found")
+
+	// ErrPeeringOverlap is returned when the subnets of two networks to be
+	// peered overlap each other or the subnet of a network already peered
+	// with one of them.
+	ErrPeeringOverlap = errors.New("Peered networks overlap")
 )
 
+// ConfigTemplateError is returned when the cloud-init config of a workload
+// cannot be parsed or rendered as a template.
+type ConfigTemplateError struct {
+	Err error
+}
+
+func (e *ConfigTemplateError) Error() string {
+	return "Invalid workload config template: " + e.Err.Error()
+}
+
+// InstanceStateError is returned when an action is requested on an instance
+// that is not in the state the action requires.
+type InstanceStateError struct {
+	Expected string
+}
+
+func (e *InstanceStateError) Error() string {
+	return "Instance must be " + e.Expected
+}
+
 // Link provides a url and relationship for a resource.
 type Link struct {
 	Rel  string `json:"rel"`
@@ -784,10 +1080,14 @@ type CNCIController interface {
 	CNCIAdded(ID string) error
 	CNCIRemoved(ID string) error
 	CNCIStopped(id string) error
+	CNCIConnected(ID string) error
+	CNCIDisconnected(ID string) error
 	StartFailure(ID string) error
 	Active(ID string) bool
 	ScheduleRemoveSubnet(subnet int) error
 	RemoveSubnet(subnet int) error
+	RemoveSubnetString(subnet string) error
+	ScheduleRemoveSubnetString(subnet string) error
 	WaitForActive(subnet int) error
 	WaitForActiveSubnetString(subnet string) error
 	GetInstanceCNCI(InstanceID string) (*Instance, error)
@@ -29,6 +29,25 @@ func NewTenantHardwareAddr(ip net.IP) net.HardwareAddr {
 	return net.HardwareAddr(buf)
 }
 
+// NewTenantIPv6Addr will generate the IPv6 address of a tenant instance
+// NIC with the given MAC address on a /64 prefix.  The address is derived
+// from the MAC address using the modified EUI-64 format, as used by SLAAC.
+func NewTenantIPv6Addr(prefix *net.IPNet, hw net.HardwareAddr) net.IP {
+	if prefix == nil || len(hw) != 6 {
+		return nil
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, prefix.IP.To16())
+	ip[8] = hw[0] ^ 2
+	ip[9] = hw[1]
+	ip[10] = hw[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	copy(ip[13:], hw[3:6])
+	return ip
+}
+
 // NewHardwareAddr will generate a MAC address for a CNCI.
 func NewHardwareAddr() (net.HardwareAddr, error) {
 	buf := make([]byte, 6)
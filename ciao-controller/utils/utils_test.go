@@ -31,6 +31,19 @@ func TestNewTenantHardwareAddr(t *testing.T) {
 	}
 }
 
+// TestNewTenantIPv6Addr
+// Confirm that the IPv6 address generated from a given
+// prefix and mac address is as expected.
+func TestNewTenantIPv6Addr(t *testing.T) {
+	_, prefix, _ := net.ParseCIDR("fd12:3456:789a:1::/64")
+	hw := NewTenantHardwareAddr(net.ParseIP("172.16.0.2"))
+	expectedIP := "fd12:3456:789a:1:0:acff:fe10:2"
+	ip := NewTenantIPv6Addr(prefix, hw)
+	if ip.String() != expectedIP {
+		t.Error("Expected: ", expectedIP, " Received: ", ip.String())
+	}
+}
+
 // TestHardwareAddr
 // Confirm that the mac addresses generated from a given
 // IP address is as expected.
@@ -66,6 +66,7 @@ func (c *controller) CreateVolume(tenant string, req api.RequestedVolume) (types
 		State:       types.Available,
 		Name:        req.Name,
 		Description: req.Description,
+		MultiAttach: req.MultiAttach,
 	}
 
 	// It's best to make the quota request here as we don't know the volume
@@ -133,7 +134,7 @@ func (c *controller) DeleteVolume(tenant string, volume string) error {
 	return nil
 }
 
-func (c *controller) AttachVolume(tenant string, volume string, instance string, mountpoint string) error {
+func (c *controller) AttachVolume(tenant string, volume string, instance string, mountpoint string, mode types.AttachmentMode) error {
 	err := c.confirmTenant(tenant)
 	if err != nil {
 		return err
@@ -145,8 +146,11 @@ func (c *controller) AttachVolume(tenant string, volume string, instance string,
 		return err
 	}
 
-	// check that the block device is available.
-	if info.State != types.Available {
+	// check that the block device is available.  Volumes that
+	// support multiple attachments can also be attached while
+	// they are in use.
+	if info.State != types.Available &&
+		!(info.MultiAttach && info.State == types.InUse) {
 		return api.ErrVolumeNotAvailable
 	}
 
@@ -161,6 +165,20 @@ func (c *controller) AttachVolume(tenant string, volume string, instance string,
 		return api.ErrInstanceNotFound
 	}
 
+	// a volume can only be attached to an instance once.
+	attachments, err := c.ds.GetVolumeAttachments(volume)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range attachments {
+		if a.InstanceID == i.ID {
+			return api.ErrVolumeNotAvailable
+		}
+	}
+
+	oldState := info.State
+
 	// update volume state to attaching
 	info.State = types.Attaching
 
@@ -174,10 +192,12 @@ func (c *controller) AttachVolume(tenant string, volume string, instance string,
 		ID:        info.ID,
 		Ephemeral: false,
 		Bootable:  false,
+		ReadOnly:  mode == types.ReadOnly,
+		Shared:    info.MultiAttach,
 	}
-	_, err = c.ds.CreateStorageAttachment(i.ID, a)
+	attachment, err := c.ds.CreateStorageAttachment(i.ID, a)
 	if err != nil {
-		info.State = types.Available
+		info.State = oldState
 		dsErr := c.ds.UpdateBlockDevice(info)
 		if dsErr != nil {
 			glog.Error(dsErr)
@@ -186,10 +206,14 @@ func (c *controller) AttachVolume(tenant string, volume string, instance string,
 	}
 
 	// send command to attach volume.
-	err = c.client.attachVolume(volume, instance, i.NodeID)
+	err = c.client.attachVolume(volume, instance, i.NodeID, a.ReadOnly, a.Shared)
 	if err != nil {
-		info.State = types.Available
-		dsErr := c.ds.UpdateBlockDevice(info)
+		dsErr := c.ds.DeleteStorageAttachment(attachment.ID)
+		if dsErr != nil {
+			glog.Error(dsErr)
+		}
+		info.State = oldState
+		dsErr = c.ds.UpdateBlockDevice(info)
 		if dsErr != nil {
 			glog.Error(dsErr)
 		}
@@ -205,17 +229,23 @@ func (c *controller) DetachVolume(tenant string, volume string, attachment strin
 		return err
 	}
 
-	// we don't support detaching by attachment ID yet.
-	if attachment != "" {
-		return errors.New("Detaching by attachment ID not implemented")
-	}
-
 	// get attachment info
 	attachments, err := c.ds.GetVolumeAttachments(volume)
 	if err != nil {
 		return err
 	}
 
+	// only detach the requested attachment if one was given.
+	// Attachments that are already being detached are skipped.
+	var found []types.StorageAttachment
+	for _, a := range attachments {
+		if a.Detaching || (attachment != "" && a.ID != attachment) {
+			continue
+		}
+		found = append(found, a)
+	}
+	attachments = found
+
 	if len(attachments) == 0 {
 		return api.ErrVolumeNotAttached
 	}
@@ -262,17 +292,41 @@ func (c *controller) DetachVolume(tenant string, volume string, attachment strin
 		state := i.State
 		i.StateLock.RUnlock()
 
-		if state != payloads.Exited {
-			retval = errors.New("Can only detach from exited instances")
+		if state == payloads.Exited {
+			// instance is not running, so we can simply remove
+			// the attachment.
+			err = c.ds.VolumeDetached(a.InstanceID, volume)
+			if err != nil {
+				glog.Error(err)
+				retval = err
+			}
 			continue
 		}
 
-		// update volume state to detaching
-		info.State = types.Available
+		if state != payloads.Running {
+			retval = errors.New("Can only detach from running or exited instances")
+			continue
+		}
 
-		err = c.ds.UpdateBlockDevice(info)
+		// mark the attachment as detaching; the attachment will be
+		// removed, or marked as attached again, once the launcher
+		// reports the result of the detach.
+		err = c.ds.SetAttachmentDetaching(a.ID, true)
 		if err != nil {
-			return err
+			glog.Error(err)
+			retval = err
+			continue
+		}
+
+		// send command to detach volume.
+		err = c.client.detachVolume(volume, a.InstanceID, i.NodeID)
+		if err != nil {
+			dsErr := c.ds.SetAttachmentDetaching(a.ID, false)
+			if dsErr != nil {
+				glog.Error(dsErr)
+			}
+			retval = err
+			continue
 		}
 	}
 
@@ -297,6 +351,11 @@ func (c *controller) ListVolumesDetail(tenant string) ([]types.Volume, error) {
 			continue
 		}
 
+		vol.Attachments, err = c.ds.GetVolumeAttachments(vol.ID)
+		if err != nil {
+			return vols, err
+		}
+
 		vols = append(vols, vol)
 	}
 
@@ -318,5 +377,10 @@ func (c *controller) ShowVolumeDetails(tenant string, volume string) (types.Volu
 		return types.Volume{}, api.ErrVolumeOwner
 	}
 
+	vol.Attachments, err = c.ds.GetVolumeAttachments(vol.ID)
+	if err != nil {
+		return types.Volume{}, err
+	}
+
 	return vol, nil
 }
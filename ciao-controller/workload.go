@@ -149,6 +149,14 @@ func (c *controller) validateWorkloadRequest(req types.Workload) error {
 		return types.ErrBadRequest
 	}
 
+	if req.Template {
+		err := validateConfigTemplate(req.Config)
+		if err != nil {
+			glog.V(2).Infof("Invalid workload request: %v", err)
+			return err
+		}
+	}
+
 	if len(req.Storage) > 0 {
 		err := c.validateWorkloadStorage(req)
 		if err != nil {
@@ -0,0 +1,115 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ciao-project/ciao/qemu"
+	"github.com/golang/glog"
+)
+
+func cpuList(cpus []int) string {
+	list := make([]string, len(cpus))
+	for i, cpu := range cpus {
+		list[i] = strconv.Itoa(cpu)
+	}
+	return strings.Join(list, ",")
+}
+
+var setThreadAffinity = func(tid int, cpus []int) error {
+	out, err := exec.Command("taskset", "-pc", cpuList(cpus),
+		strconv.Itoa(tid)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Unable to set affinity of thread %d: %v: %s",
+			tid, err, string(out))
+	}
+
+	return nil
+}
+
+var setProcessAffinity = func(pid int, cpus []int) error {
+	out, err := exec.Command("taskset", "-a", "-pc", cpuList(cpus),
+		strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Unable to set affinity of process %d: %v: %s",
+			pid, err, string(out))
+	}
+
+	return nil
+}
+
+// vcpuAffinity returns the host CPUs the given VCPU of an instance is
+// allowed to run on.
+func vcpuAffinity(cfg *vmConfig, vcpu int) ([]int, error) {
+	if !cfg.DedicatedCPUs {
+		return cfg.PinnedCPUs, nil
+	}
+
+	if vcpu < 0 || vcpu >= len(cfg.PinnedCPUs) {
+		return nil, fmt.Errorf("No host CPU dedicated to VCPU %d", vcpu)
+	}
+
+	return cfg.PinnedCPUs[vcpu : vcpu+1], nil
+}
+
+// pinVCPUs restricts the threads of each of the VCPUs of an instance to the
+// host CPUs chosen for them by the overseer.  All the threads of an
+// instance without dedicated CPUs, pid, are confined to the shared CPUs.
+func pinVCPUs(q *qemu.QMP, cfg *vmConfig, pid int) error {
+	if len(cfg.PinnedCPUs) == 0 {
+		return nil
+	}
+
+	if !cfg.DedicatedCPUs {
+		if pid == 0 {
+			return fmt.Errorf("Unable to determine pid of %s", cfg.Instance)
+		}
+		if err := setProcessAffinity(pid, cfg.PinnedCPUs); err != nil {
+			return err
+		}
+		glog.Infof("%s confined to %v", cfg.Instance, cfg.PinnedCPUs)
+		return nil
+	}
+
+	ctx, cancelFN := context.WithTimeout(context.Background(), time.Second*10)
+	cpus, err := q.ExecuteQueryCpus(ctx)
+	cancelFN()
+	if err != nil {
+		return err
+	}
+
+	for _, cpu := range cpus {
+		hostCPUs, err := vcpuAffinity(cfg, cpu.CPU)
+		if err != nil {
+			return err
+		}
+
+		if err = setThreadAffinity(cpu.ThreadID, hostCPUs); err != nil {
+			return err
+		}
+
+		glog.Infof("VCPU %d of %s pinned to %v", cpu.CPU, cfg.Instance, hostCPUs)
+	}
+
+	return nil
+}
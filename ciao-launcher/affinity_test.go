@@ -0,0 +1,51 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Checks the host CPUs VCPUs are pinned to.
+//
+// Compute the affinity of the VCPUs of an instance with dedicated CPUs and
+// of an instance that is only NUMA local.
+//
+// Each VCPU of the first instance should be pinned to its own host CPU and
+// an error should be returned for a VCPU with no dedicated CPU.  All the
+// VCPUs of the second instance should share the CPUs of the NUMA node.
+func TestVCPUAffinity(t *testing.T) {
+	cfg := &vmConfig{Cpus: 2, DedicatedCPUs: true, PinnedCPUs: []int{4, 6}}
+	for i, expected := range [][]int{{4}, {6}} {
+		cpus, err := vcpuAffinity(cfg, i)
+		if err != nil || !reflect.DeepEqual(cpus, expected) {
+			t.Errorf("VCPU %d: expected %v got %v %v", i, expected, cpus, err)
+		}
+	}
+	if _, err := vcpuAffinity(cfg, 2); err == nil {
+		t.Errorf("Expected an error for VCPU 2")
+	}
+
+	cfg = &vmConfig{Cpus: 2, NUMALocal: true, PinnedCPUs: []int{0, 1}}
+	for i := 0; i < 2; i++ {
+		cpus, err := vcpuAffinity(cfg, i)
+		if err != nil || !reflect.DeepEqual(cpus, cfg.PinnedCPUs) {
+			t.Errorf("VCPU %d: expected %v got %v %v", i, cfg.PinnedCPUs, cpus, err)
+		}
+	}
+}
@@ -23,7 +23,8 @@ import (
 )
 
 func processAttachVolume(storageDriver storage.BlockDriver, monitorCh chan interface{}, cfg *vmConfig,
-	instance, instanceDir, volumeUUID string, conn serverConn) *attachVolumeError {
+	instance, instanceDir string, volume volumeConfig, conn serverConn) *attachVolumeError {
+	volumeUUID := volume.UUID
 
 	if cfg.Container {
 		attachErr := &attachVolumeError{nil, payloads.AttachVolumeNotSupported}
@@ -69,6 +70,10 @@ func processAttachVolume(storageDriver storage.BlockDriver, monitorCh chan inter
 			responseCh: responseCh,
 			volumeUUID: volumeUUID,
 			device:     devName,
+			readOnly:   volume.ReadOnly,
+			shared:     volume.Shared,
+			iops:       int64(cfg.DiskIOPS),
+			bps:        cfg.diskBPS(),
 		}
 
 		err = <-responseCh
@@ -84,7 +89,7 @@ func processAttachVolume(storageDriver storage.BlockDriver, monitorCh chan inter
 		}
 	}
 
-	cfg.Volumes = append(cfg.Volumes, volumeConfig{UUID: volumeUUID})
+	cfg.Volumes = append(cfg.Volumes, volume)
 
 	err := cfg.save(instanceDir)
 	if err != nil {
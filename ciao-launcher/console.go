@@ -0,0 +1,410 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/qemu"
+	"github.com/golang/glog"
+)
+
+const (
+	consoleSocket       = "console.sock"
+	consoleAttachSocket = "console-attach.sock"
+	consoleLogFile      = "console.log"
+
+	// The console log is rotated when it reaches this size.  Only one
+	// rotated log is kept, so the serial output of an instance never
+	// consumes more than twice this amount of space.
+	consoleLogMaxSize = 256 * 1024
+
+	// Time given to the console logger to copy any remaining output
+	// of a VM that is shutting down before its connection is closed.
+	consoleDrainTimeout = 500 * time.Millisecond
+
+	// Time after which a client attached to the console of a VM is
+	// disconnected if it does not read the output of the VM.
+	consoleClientWriteTimeout = 5 * time.Second
+)
+
+// consoleDevice returns the qemu device that connects the first serial port
+// of a VM to a domain socket in its instance directory.  The launcher
+// listens on this socket before the VM is launched, so that no output is
+// lost, reads the guest's serial output from it and stores it in the
+// instance's console log.  qemu reconnects to the socket if the launcher
+// is restarted.
+func consoleDevice(instanceDir string) qemu.CharDevice {
+	return qemu.CharDevice{
+		Backend:   qemu.Socket,
+		Driver:    qemu.ISASerial,
+		ID:        "console0",
+		DeviceID:  "serial0",
+		Path:      path.Join(instanceDir, consoleSocket),
+		Client:    true,
+		Reconnect: 1,
+	}
+}
+
+// consoleLog is an io.Writer that appends to a log file, rotating it when
+// it grows beyond maxSize.
+type consoleLog struct {
+	path    string
+	maxSize int64
+	f       *os.File
+	size    int64
+}
+
+func openConsoleLog(logPath string, maxSize int64) (*consoleLog, error) {
+	f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &consoleLog{
+		path:    logPath,
+		maxSize: maxSize,
+		f:       f,
+		size:    fi.Size(),
+	}, nil
+}
+
+func (l *consoleLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		glog.Warningf("Unable to close console log %s: %v", l.path, err)
+	}
+
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.size = 0
+
+	return nil
+}
+
+func (l *consoleLog) Write(p []byte) (int, error) {
+	if l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+func (l *consoleLog) Close() error {
+	return l.f.Close()
+}
+
+// consoleLogger copies the serial output of a VM to its console log.  It
+// listens on a domain socket in the instance directory to which qemu
+// connects.  It also listens on a second domain socket, through which a
+// single client at a time can interact with the serial console.  The output
+// of the VM is copied to the attached client, if any, and the input of the
+// client is sent to the VM.
+type consoleLogger struct {
+	vmListener net.Listener
+	listener   net.Listener
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	wg         sync.WaitGroup
+
+	vmLock sync.Mutex
+	vm     net.Conn
+
+	clientLock sync.Mutex
+	client     net.Conn
+	closed     bool
+}
+
+// listenUnix listens on the domain socket socketPath, removing any socket
+// left behind by a previous instance of launcher.
+func listenUnix(socketPath string) (net.Listener, error) {
+	_ = os.Remove(socketPath)
+	return net.Listen("unix", socketPath)
+}
+
+func startConsoleLogger(instance, instanceDir string) (*consoleLogger, error) {
+	vmListener, err := listenUnix(path.Join(instanceDir, consoleSocket))
+	if err != nil {
+		return nil, err
+	}
+
+	log, err := openConsoleLog(path.Join(instanceDir, consoleLogFile), consoleLogMaxSize)
+	if err != nil {
+		_ = vmListener.Close()
+		return nil, err
+	}
+
+	cl := &consoleLogger{
+		vmListener: vmListener,
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+	}
+
+	cl.listener, err = listenUnix(path.Join(instanceDir, consoleAttachSocket))
+	if err != nil {
+		glog.Warningf("Unable to listen for console clients of %s: %v", instance, err)
+	} else {
+		cl.wg.Add(1)
+		go cl.acceptClients(instance)
+	}
+
+	go cl.acceptVM(instance, log)
+
+	return cl, nil
+}
+
+// acceptVM copies the output of each connection qemu makes to the console
+// socket to the console log, until the logger is stopped.
+func (cl *consoleLogger) acceptVM(instance string, log io.WriteCloser) {
+	for {
+		conn, err := cl.vmListener.Accept()
+		if err != nil {
+			break
+		}
+
+		if !cl.setVM(conn) {
+			_ = conn.Close()
+			break
+		}
+
+		cl.copyOutput(instance, conn, log)
+
+		_ = cl.setVM(nil)
+		_ = conn.Close()
+	}
+
+	_ = log.Close()
+	close(cl.doneCh)
+}
+
+// setVM records the connection qemu has made to the console socket.  It
+// returns false if the logger is being stopped.
+func (cl *consoleLogger) setVM(conn net.Conn) bool {
+	cl.vmLock.Lock()
+	defer cl.vmLock.Unlock()
+
+	select {
+	case <-cl.stopCh:
+		if conn != nil {
+			return false
+		}
+	default:
+	}
+
+	cl.vm = conn
+	return true
+}
+
+func (cl *consoleLogger) copyOutput(instance string, conn net.Conn, log io.Writer) {
+	var logErr error
+	buf := make([]byte, 4096)
+
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if logErr == nil {
+				if _, logErr = log.Write(buf[:n]); logErr != nil {
+					glog.Warningf("Console logging for %s failed: %v", instance, logErr)
+				}
+			}
+			cl.writeClient(buf[:n])
+		}
+
+		if err != nil {
+			select {
+			case <-cl.stopCh:
+			default:
+				if err != io.EOF {
+					glog.Warningf("Console logging for %s failed: %v", instance, err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// Write sends the input of the attached client to the VM.  The input is
+// discarded if qemu is not connected to the console socket.
+func (cl *consoleLogger) Write(p []byte) (int, error) {
+	cl.vmLock.Lock()
+	defer cl.vmLock.Unlock()
+
+	if cl.vm == nil {
+		return len(p), nil
+	}
+
+	return cl.vm.Write(p)
+}
+
+// writeClient copies the output of the VM to the attached client,
+// disconnecting the client if it fails to keep up.
+func (cl *consoleLogger) writeClient(p []byte) {
+	cl.clientLock.Lock()
+	defer cl.clientLock.Unlock()
+
+	if cl.client == nil {
+		return
+	}
+
+	_ = cl.client.SetWriteDeadline(time.Now().Add(consoleClientWriteTimeout))
+	if _, err := cl.client.Write(p); err != nil {
+		_ = cl.client.Close()
+		cl.client = nil
+	}
+}
+
+func (cl *consoleLogger) acceptClients(instance string) {
+	defer cl.wg.Done()
+
+	for {
+		c, err := cl.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		cl.clientLock.Lock()
+		if cl.closed {
+			cl.clientLock.Unlock()
+			_ = c.Close()
+			return
+		}
+		if cl.client != nil {
+			cl.clientLock.Unlock()
+			_, _ = c.Write([]byte("Console is already in use\r\n"))
+			_ = c.Close()
+			continue
+		}
+		cl.client = c
+		cl.wg.Add(1)
+		cl.clientLock.Unlock()
+
+		glog.Infof("Client attached to console of %s", instance)
+		go cl.copyInput(instance, c)
+	}
+}
+
+func (cl *consoleLogger) copyInput(instance string, c net.Conn) {
+	defer cl.wg.Done()
+
+	_, _ = io.Copy(cl, c)
+
+	cl.clientLock.Lock()
+	if cl.client == c {
+		cl.client = nil
+	}
+	cl.clientLock.Unlock()
+	_ = c.Close()
+
+	glog.Infof("Client detached from console of %s", instance)
+}
+
+// stop waits for the VM to close its serial port, closing the connection
+// itself if this does not happen within consoleDrainTimeout, and returns
+// once all the output received has been written to the console log and
+// any attached client has been disconnected.
+func (cl *consoleLogger) stop() {
+	_ = cl.vmListener.Close()
+	select {
+	case <-cl.doneCh:
+	case <-time.After(consoleDrainTimeout):
+		close(cl.stopCh)
+		cl.vmLock.Lock()
+		if cl.vm != nil {
+			_ = cl.vm.Close()
+		}
+		cl.vmLock.Unlock()
+		<-cl.doneCh
+	}
+
+	if cl.listener != nil {
+		_ = cl.listener.Close()
+	}
+
+	cl.clientLock.Lock()
+	cl.closed = true
+	if cl.client != nil {
+		_ = cl.client.Close()
+		cl.client = nil
+	}
+	cl.clientLock.Unlock()
+
+	cl.wg.Wait()
+}
+
+// tailLines returns the last lines lines of data, or all of data if lines
+// is 0.
+func tailLines(data []byte, lines int) []byte {
+	if lines == 0 {
+		return data
+	}
+
+	i := len(data)
+	if i > 0 && data[i-1] == '\n' {
+		i--
+	}
+
+	for ; lines > 0; lines-- {
+		i = bytes.LastIndexByte(data[:i], '\n')
+		if i == -1 {
+			return data
+		}
+	}
+
+	return data[i+1:]
+}
+
+// readConsoleLog returns the last lines lines of an instance's console log,
+// including those in the rotated log, or the entire log if lines is 0.
+func readConsoleLog(instanceDir string, lines int) (string, error) {
+	var data []byte
+
+	logPath := path.Join(instanceDir, consoleLogFile)
+	for _, p := range []string{logPath + ".1", logPath} {
+		buf, err := ioutil.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		data = append(data, buf...)
+	}
+
+	return string(tailLines(data, lines)), nil
+}
@@ -0,0 +1,111 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package main
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"io"
+	"net"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Time for which a console session opened by an OpenConsole command waits
+// for a client to connect and present its token.
+const consoleProxyTimeout = 60 * time.Second
+
+// startConsoleProxy listens on a TCP port of the node for a client that
+// presents token, followed by a newline, and then connects this client to
+// console.  Connections are protected by TLS, configured by config, so
+// that only ciao-controller can connect to the port and the console
+// traffic is encrypted.  Only one client is accepted, after which the port
+// is closed.  console is closed when the session terminates or if no client
+// presents the token within consoleProxyTimeout.  The address on which the
+// proxy is listening is returned.
+func startConsoleProxy(instance, token string, console io.ReadWriteCloser, config *tls.Config) (string, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort(getNodeIPAddress(), "0"))
+	if err != nil {
+		return "", err
+	}
+
+	go serveConsoleProxy(instance, token, l.(*net.TCPListener), config, console)
+
+	return l.Addr().String(), nil
+}
+
+func serveConsoleProxy(instance, token string, l *net.TCPListener, config *tls.Config,
+	console io.ReadWriteCloser) {
+	var conn net.Conn
+
+	deadline := time.Now().Add(consoleProxyTimeout)
+	_ = l.SetDeadline(deadline)
+	for conn == nil {
+		tc, err := l.Accept()
+		if err != nil {
+			glog.Warningf("No client connected to console of %s: %v", instance, err)
+			_ = l.Close()
+			_ = console.Close()
+			return
+		}
+
+		c := tls.Server(tc, config)
+		_ = c.SetDeadline(deadline)
+		if checkConsoleToken(c, token) {
+			conn = c
+		} else {
+			glog.Warningf("Invalid token presented for console of %s by %s",
+				instance, c.RemoteAddr())
+			_ = c.Close()
+		}
+	}
+	_ = l.Close()
+	_ = conn.SetDeadline(time.Time{})
+
+	glog.Infof("Console session for %s started by %s", instance, conn.RemoteAddr())
+	spliceConsole(conn, console)
+	glog.Infof("Console session for %s terminated", instance)
+}
+
+func checkConsoleToken(r io.Reader, token string) bool {
+	buf := make([]byte, len(token)+1)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(buf, []byte(token+"\n")) == 1
+}
+
+// spliceConsole copies data in both directions between a and b until one
+// of them is closed, after which both are closed.
+func spliceConsole(a, b io.ReadWriteCloser) {
+	doneCh := make(chan struct{}, 2)
+
+	go func() {
+		_, _ = io.Copy(a, b)
+		doneCh <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		doneCh <- struct{}{}
+	}()
+
+	<-doneCh
+	_ = a.Close()
+	_ = b.Close()
+	<-doneCh
+}
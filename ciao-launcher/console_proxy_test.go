@@ -0,0 +1,143 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package main
+
+import (
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/ciao-project/ciao/testutil"
+)
+
+// consoleProxyTLS returns the TLS configurations used by a launcher with an
+// agent certificate and by a controller.
+func consoleProxyTLS(t *testing.T, dir string) (*tls.Config, *tls.Config) {
+	caPath := path.Join(dir, "CAcert")
+	if err := ioutil.WriteFile(caPath, []byte(testutil.TestCACert), 0600); err != nil {
+		t.Fatalf("Unable to write CA certificate: %v", err)
+	}
+
+	roles := []struct {
+		role   ssntp.Role
+		peer   ssntp.Role
+		server bool
+	}{
+		{ssntp.AGENT, ssntp.Controller, true},
+		{ssntp.Controller, ssntp.AGENT, false},
+	}
+
+	var configs [2]*tls.Config
+	for i, r := range roles {
+		certPath := path.Join(dir, r.role.String())
+		if err := ioutil.WriteFile(certPath, []byte(testutil.RoleToTestCert(r.role)), 0600); err != nil {
+			t.Fatalf("Unable to write certificate: %v", err)
+		}
+
+		config, err := ssntp.PeerTLSConfig(&ssntp.Config{CAcert: caPath, Cert: certPath},
+			r.server, r.peer)
+		if err != nil {
+			t.Fatalf("Unable to create TLS config: %v", err)
+		}
+		configs[i] = config
+	}
+
+	return configs[0], configs[1]
+}
+
+// Checks that a console proxy only connects clients that present the
+// correct certificate and token to the console.
+//
+// A console proxy is started for a console that echoes its input.  A client
+// without a certificate connects to the proxy, followed by a controller
+// presenting an invalid token, followed by a controller presenting the
+// correct token.
+//
+// The first two clients should be disconnected.  The third client should be
+// connected to the console and should receive its echoed input.  The
+// console should be closed when the third client disconnects.
+func TestConsoleProxy(t *testing.T) {
+	const token = "0123456789abcdef"
+
+	dir, err := ioutil.TempDir("", "console-proxy-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	serverTLS, clientTLS := consoleProxyTLS(t, dir)
+
+	console, vm := net.Pipe()
+	closedCh := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(vm, vm)
+		close(closedCh)
+	}()
+
+	address, err := startConsoleProxy("test-instance", token, console, serverTLS)
+	if err != nil {
+		t.Fatalf("Unable to start console proxy: %v", err)
+	}
+
+	anon, err := tls.Dial("tcp", address, &tls.Config{InsecureSkipVerify: true})
+	if err == nil {
+		_ = anon.SetDeadline(time.Now().Add(time.Second))
+		_, _ = anon.Write([]byte(token + "\n"))
+		if _, err := anon.Read(make([]byte, 1)); err == nil {
+			t.Errorf("Expected client without certificate to be disconnected")
+		}
+		_ = anon.Close()
+	}
+
+	bad, err := tls.Dial("tcp", address, clientTLS)
+	if err != nil {
+		t.Fatalf("Unable to connect to console proxy: %v", err)
+	}
+	_, _ = bad.Write([]byte("fedcba9876543210\n"))
+	_ = bad.SetReadDeadline(time.Now().Add(time.Second))
+	if data, err := ioutil.ReadAll(bad); err != nil || len(data) != 0 {
+		t.Errorf("Expected client with invalid token to be disconnected: %v", err)
+	}
+	_ = bad.Close()
+
+	conn, err := tls.Dial("tcp", address, clientTLS)
+	if err != nil {
+		t.Fatalf("Unable to connect to console proxy: %v", err)
+	}
+
+	const input = "hello\n"
+	if _, err := conn.Write([]byte(token + "\n" + input)); err != nil {
+		t.Fatalf("Unable to write to console proxy: %v", err)
+	}
+	buf := make([]byte, len(input))
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != input {
+		t.Errorf("Expected %q from console, got %q: %v", input, string(buf), err)
+	}
+	_ = conn.Close()
+
+	select {
+	case <-closedCh:
+	case <-time.After(time.Second):
+		t.Errorf("Console not closed when client disconnected")
+	}
+}
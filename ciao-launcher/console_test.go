@@ -0,0 +1,232 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Checks that the console log is rotated once it reaches its maximum size.
+//
+// A consoleLog with a maximum size of 16 bytes is created and 3 lines of 10
+// bytes are written to it.
+//
+// The first two lines should be written to the first log, the rotated log
+// should contain the second line and the current log the third.  All three
+// writes should succeed.
+func TestConsoleLogRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	logPath := path.Join(dir, consoleLogFile)
+	log, err := openConsoleLog(logPath, 16)
+	if err != nil {
+		t.Fatalf("Unable to open console log: %v", err)
+	}
+
+	for _, l := range []string{"line 0001\n", "line 0002\n", "line 0003\n"} {
+		if _, err := log.Write([]byte(l)); err != nil {
+			t.Fatalf("Unable to write to console log: %v", err)
+		}
+	}
+	_ = log.Close()
+
+	rotated, err := ioutil.ReadFile(logPath + ".1")
+	if err != nil || string(rotated) != "line 0002\n" {
+		t.Errorf("Unexpected rotated log %q: %v", string(rotated), err)
+	}
+
+	current, err := ioutil.ReadFile(logPath)
+	if err != nil || string(current) != "line 0003\n" {
+		t.Errorf("Unexpected console log %q: %v", string(current), err)
+	}
+
+	logs, err := readConsoleLog(dir, 0)
+	if err != nil || logs != "line 0002\nline 0003\n" {
+		t.Errorf("Unexpected log contents %q: %v", logs, err)
+	}
+}
+
+// Checks the tailLines function.
+//
+// tailLines is called with logs with and without a trailing newline
+// requesting various numbers of lines.
+//
+// The expected lines should be returned in each case and the entire log
+// should be returned if 0 or more lines than the log contains are requested.
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		data     string
+		lines    int
+		expected string
+	}{
+		{"", 10, ""},
+		{"a\nb\nc\n", 0, "a\nb\nc\n"},
+		{"a\nb\nc\n", 1, "c\n"},
+		{"a\nb\nc\n", 2, "b\nc\n"},
+		{"a\nb\nc\n", 3, "a\nb\nc\n"},
+		{"a\nb\nc\n", 4, "a\nb\nc\n"},
+		{"a\nb\nc", 1, "c"},
+		{"a\nb\nc", 2, "b\nc"},
+	}
+
+	for _, test := range tests {
+		tail := string(tailLines([]byte(test.data), test.lines))
+		if tail != test.expected {
+			t.Errorf("tailLines(%q, %d) returned %q, expected %q", test.data,
+				test.lines, tail, test.expected)
+		}
+	}
+}
+
+// Checks that the serial output of an instance is copied to its console log.
+//
+// A console logger is started in a temporary instance directory before
+// a connection standing in for qemu's serial port is made to its socket.
+// Some data is written to the connection, which is then closed and
+// reopened, as qemu does when launcher is restarted.  More data is written
+// before the console logger is stopped.
+//
+// The data written to both connections should be present in the console log
+// once the logger has stopped.  readConsoleLog should return an empty log for
+// an instance that has no console log.
+func TestConsoleLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	logs, err := readConsoleLog(dir, 0)
+	if err != nil || logs != "" {
+		t.Errorf("Expected empty log, got %q: %v", logs, err)
+	}
+
+	cl, err := startConsoleLogger("test-instance", dir)
+	if err != nil {
+		t.Fatalf("Unable to start console logger: %v", err)
+	}
+
+	for _, output := range []string{"Booting from Hard Disk...\n", "login: "} {
+		conn, err := net.Dial("unix", path.Join(dir, consoleSocket))
+		if err != nil {
+			t.Fatalf("Unable to connect to console socket: %v", err)
+		}
+
+		if _, err := conn.Write([]byte(output)); err != nil {
+			t.Fatalf("Unable to write to console socket: %v", err)
+		}
+		_ = conn.Close()
+	}
+
+	// wait for the output of the second connection to be logged
+	// before the logger is stopped.
+	for i := 0; i < 100; i++ {
+		logs, _ = readConsoleLog(dir, 0)
+		if strings.HasSuffix(logs, "login: ") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cl.stop()
+
+	logs, err = readConsoleLog(dir, 0)
+	if err != nil || logs != "Booting from Hard Disk...\nlogin: " {
+		t.Errorf("Unexpected console log %q: %v", logs, err)
+	}
+}
+
+// Checks that a client can interact with the serial console of an instance.
+//
+// A console logger is started in a temporary instance directory and a
+// connection standing in for qemu's serial port is made to its socket.  Two
+// clients connect to the console logger's attach socket.
+//
+// The first client should receive the output of the VM and its input should
+// be received by the VM.  The second client should be told that the console
+// is in use and disconnected.  The output of the VM should also be present
+// in the console log.
+func TestConsoleAttach(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	cl, err := startConsoleLogger("test-instance", dir)
+	if err != nil {
+		t.Fatalf("Unable to start console logger: %v", err)
+	}
+	defer cl.stop()
+
+	vm, err := net.Dial("unix", path.Join(dir, consoleSocket))
+	if err != nil {
+		t.Fatalf("Unable to connect to console socket: %v", err)
+	}
+	defer func() { _ = vm.Close() }()
+
+	client, err := net.Dial("unix", path.Join(dir, consoleAttachSocket))
+	if err != nil {
+		t.Fatalf("Unable to attach to console: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	busy, err := net.Dial("unix", path.Join(dir, consoleAttachSocket))
+	if err != nil {
+		t.Fatalf("Unable to connect to console attach socket: %v", err)
+	}
+	_ = busy.SetReadDeadline(time.Now().Add(time.Second))
+	msg, _ := ioutil.ReadAll(busy)
+	if !strings.Contains(string(msg), "in use") {
+		t.Errorf("Expected console in use message, got %q", string(msg))
+	}
+	_ = busy.Close()
+
+	const output = "login: "
+	if _, err := vm.Write([]byte(output)); err != nil {
+		t.Fatalf("Unable to write to console socket: %v", err)
+	}
+	buf := make([]byte, len(output))
+	_ = client.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(client, buf); err != nil || string(buf) != output {
+		t.Errorf("Expected %q from console, got %q: %v", output, string(buf), err)
+	}
+
+	const input = "root\n"
+	if _, err := client.Write([]byte(input)); err != nil {
+		t.Fatalf("Unable to write to console: %v", err)
+	}
+	buf = make([]byte, len(input))
+	_ = vm.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := io.ReadFull(vm, buf); err != nil || string(buf) != input {
+		t.Errorf("Expected %q from client, got %q: %v", input, string(buf), err)
+	}
+
+	logs, err := readConsoleLog(dir, 0)
+	if err != nil || logs != output {
+		t.Errorf("Unexpected console log %q: %v", logs, err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 )
 
 type containerManager interface {
+	Info(context.Context) (types.Info, error)
 	ImageList(context.Context, types.ImageListOptions) ([]types.Image, error)
 	ImagePull(context.Context, types.ImagePullOptions, client.RequestPrivilegeFunc) (io.ReadCloser, error)
 	ContainerCreate(context.Context, *container.Config, *container.HostConfig,
@@ -37,5 +38,9 @@ type containerManager interface {
 	ContainerInspectWithRaw(context.Context, string, bool) (types.ContainerJSON, []byte, error)
 	ContainerStats(context.Context, string, bool) (io.ReadCloser, error)
 	ContainerKill(context.Context, string, string) error
+	ContainerStop(context.Context, string, int) error
+	ContainerPause(context.Context, string) error
+	ContainerUnpause(context.Context, string) error
 	ContainerWait(context.Context, string) (int, error)
+	ContainerAttach(context.Context, types.ContainerAttachOptions) (types.HijackedResponse, error)
 }
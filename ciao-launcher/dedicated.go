@@ -0,0 +1,310 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"sort"
+
+	"github.com/ciao-project/ciao/deviceinfo"
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+type numaNodeResources struct {
+	id int
+
+	// All the CPUs of the node that are not dedicated to instances
+	sharedCPUs []int
+
+	// The CPUs of the node that can be dedicated to instances.  The
+	// value indicates whether the CPU is currently dedicated.
+	pinnableCPUs map[int]bool
+
+	hugePagesMB          int
+	hugePagesAllocatedMB int
+}
+
+type dedicatedAllocation struct {
+	cpus        []int
+	node        int
+	hugePagesMB int
+}
+
+// dedicatedResources keeps track of the host resources, i.e., pinnable CPUs
+// and huge pages, that can be dedicated to instances.  It is owned by the
+// overseer and so does not need to be protected by a mutex.
+type dedicatedResources struct {
+	nodes       []*numaNodeResources
+	allocations map[string]*dedicatedAllocation
+}
+
+func newDedicatedResources(topology []deviceinfo.NUMANode, hugePageSizeKB int,
+	pinnable []int) *dedicatedResources {
+	isPinnable := make(map[int]bool)
+	for _, cpu := range pinnable {
+		isPinnable[cpu] = true
+	}
+
+	d := &dedicatedResources{
+		nodes:       make([]*numaNodeResources, 0, len(topology)),
+		allocations: make(map[string]*dedicatedAllocation),
+	}
+
+	for _, n := range topology {
+		node := &numaNodeResources{
+			id:           n.ID,
+			pinnableCPUs: make(map[int]bool),
+		}
+		for _, cpu := range n.CPUs {
+			if isPinnable[cpu] {
+				node.pinnableCPUs[cpu] = false
+			} else {
+				node.sharedCPUs = append(node.sharedCPUs, cpu)
+			}
+		}
+		if hugePageSizeKB > 0 && n.HugePagesTotal > 0 {
+			node.hugePagesMB = n.HugePagesTotal * hugePageSizeKB / 1024
+		}
+		d.nodes = append(d.nodes, node)
+	}
+
+	return d
+}
+
+func (n *numaNodeResources) freeCPUs() []int {
+	cpus := make([]int, 0, len(n.pinnableCPUs))
+	for cpu, used := range n.pinnableCPUs {
+		if !used {
+			cpus = append(cpus, cpu)
+		}
+	}
+	sort.Ints(cpus)
+	return cpus
+}
+
+func (n *numaNodeResources) fits(cfg *vmConfig) bool {
+	if cfg.HugePages && n.hugePagesMB-n.hugePagesAllocatedMB < cfg.Mem {
+		return false
+	}
+
+	if cfg.NUMALocal {
+		if cfg.DedicatedCPUs {
+			return len(n.freeCPUs()) >= cfg.Cpus
+		}
+		return len(n.sharedCPUs) > 0
+	}
+
+	return true
+}
+
+// sharedCPUs returns the host CPUs that instances without dedicated CPUs
+// are allowed to run on and whether any CPUs are pinnable.  If no CPUs are
+// pinnable these instances are not confined.
+func (d *dedicatedResources) sharedCPUs() (cpus []int, pinnable bool) {
+	for _, n := range d.nodes {
+		cpus = append(cpus, n.sharedCPUs...)
+		pinnable = pinnable || len(n.pinnableCPUs) > 0
+	}
+
+	if !pinnable {
+		return nil, false
+	}
+
+	sort.Ints(cpus)
+	return cpus, true
+}
+
+func (d *dedicatedResources) findNode(id int) *numaNodeResources {
+	for _, n := range d.nodes {
+		if n.id == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func (d *dedicatedResources) pickCPUs(preferred *numaNodeResources, strict bool,
+	count int) []int {
+	var cpus []int
+	if preferred != nil {
+		cpus = preferred.freeCPUs()
+	}
+
+	if !strict {
+		for _, n := range d.nodes {
+			if n != preferred {
+				cpus = append(cpus, n.freeCPUs()...)
+			}
+		}
+	}
+
+	if len(cpus) < count {
+		return nil
+	}
+
+	return cpus[:count]
+}
+
+func (d *dedicatedResources) commit(instance string, alloc *dedicatedAllocation) {
+	for _, cpu := range alloc.cpus {
+		for _, n := range d.nodes {
+			if _, ok := n.pinnableCPUs[cpu]; ok {
+				n.pinnableCPUs[cpu] = true
+			}
+		}
+	}
+
+	if alloc.hugePagesMB > 0 {
+		if n := d.findNode(alloc.node); n != nil {
+			n.hugePagesAllocatedMB += alloc.hugePagesMB
+		}
+	}
+
+	d.allocations[instance] = alloc
+}
+
+// allocate reserves the host resources requested by an instance, updating
+// cfg with the CPUs and the NUMA node chosen for it.  Instances without
+// dedicated CPUs are confined to the CPUs that are not pinnable, so that
+// they never run on a CPU dedicated to another instance.  It returns false
+// if the resources are not available.
+func (d *dedicatedResources) allocate(instance string, cfg *vmConfig) bool {
+	var shared []int
+	if !cfg.DedicatedCPUs && !cfg.NUMALocal {
+		var pinnable bool
+		shared, pinnable = d.sharedCPUs()
+		if pinnable && len(shared) == 0 {
+			glog.Warningf("No shared CPUs available for %s", instance)
+			return false
+		}
+	}
+
+	if !cfg.DedicatedCPUs && !cfg.numaBound() {
+		cfg.PinnedCPUs = shared
+		return true
+	}
+
+	alloc := &dedicatedAllocation{node: -1}
+
+	var node *numaNodeResources
+	if cfg.numaBound() {
+		for _, n := range d.nodes {
+			if n.fits(cfg) {
+				node = n
+				break
+			}
+		}
+		if node == nil {
+			glog.Warningf("No NUMA node can accommodate %s", instance)
+			return false
+		}
+		alloc.node = node.id
+	}
+
+	if cfg.DedicatedCPUs {
+		alloc.cpus = d.pickCPUs(node, cfg.NUMALocal, cfg.Cpus)
+		if alloc.cpus == nil {
+			glog.Warningf("Not enough dedicated CPUs for %s", instance)
+			return false
+		}
+		cfg.PinnedCPUs = alloc.cpus
+	} else if cfg.NUMALocal {
+		cfg.PinnedCPUs = node.sharedCPUs
+	} else {
+		cfg.PinnedCPUs = shared
+	}
+
+	if cfg.HugePages {
+		alloc.hugePagesMB = cfg.Mem
+	}
+
+	if node != nil {
+		cfg.HostNUMANode = node.id
+	}
+
+	d.commit(instance, alloc)
+
+	return true
+}
+
+// restore re-registers the resources allocated to an instance that was
+// running before the launcher was restarted.
+func (d *dedicatedResources) restore(instance string, cfg *vmConfig) {
+	if !cfg.DedicatedCPUs && !cfg.numaBound() {
+		return
+	}
+
+	alloc := &dedicatedAllocation{node: -1}
+	if cfg.DedicatedCPUs {
+		alloc.cpus = cfg.PinnedCPUs
+	}
+	if cfg.numaBound() {
+		alloc.node = cfg.HostNUMANode
+	}
+	if cfg.HugePages {
+		alloc.hugePagesMB = cfg.Mem
+	}
+
+	d.commit(instance, alloc)
+}
+
+// release returns the resources dedicated to an instance to the pool.
+func (d *dedicatedResources) release(instance string) {
+	alloc := d.allocations[instance]
+	if alloc == nil {
+		return
+	}
+
+	for _, cpu := range alloc.cpus {
+		for _, n := range d.nodes {
+			if _, ok := n.pinnableCPUs[cpu]; ok {
+				n.pinnableCPUs[cpu] = false
+			}
+		}
+	}
+
+	if alloc.hugePagesMB > 0 {
+		if n := d.findNode(alloc.node); n != nil {
+			n.hugePagesAllocatedMB -= alloc.hugePagesMB
+			if n.hugePagesAllocatedMB < 0 {
+				n.hugePagesAllocatedMB = 0
+			}
+		}
+	}
+
+	delete(d.allocations, instance)
+}
+
+// stats returns the total number of dedicated CPUs available, the total and
+// available MBs of huge pages, and a breakdown of these per NUMA node.
+func (d *dedicatedResources) stats() (cpusAvailable, hugePagesTotalMB,
+	hugePagesAvailableMB int, nodes []payloads.NUMANodeStat) {
+	nodes = make([]payloads.NUMANodeStat, 0, len(d.nodes))
+	for _, n := range d.nodes {
+		stat := payloads.NUMANodeStat{
+			NodeID:                 n.id,
+			DedicatedCpusAvailable: len(n.freeCPUs()),
+			HugePagesAvailableMB:   n.hugePagesMB - n.hugePagesAllocatedMB,
+		}
+		cpusAvailable += stat.DedicatedCpusAvailable
+		hugePagesTotalMB += n.hugePagesMB
+		hugePagesAvailableMB += stat.HugePagesAvailableMB
+		nodes = append(nodes, stat)
+	}
+
+	return
+}
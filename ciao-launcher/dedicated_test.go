@@ -0,0 +1,169 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ciao-project/ciao/deviceinfo"
+)
+
+func newTestDedicatedResources() *dedicatedResources {
+	topology := []deviceinfo.NUMANode{
+		{ID: 0, CPUs: []int{0, 1, 2, 3}, HugePagesTotal: 512},
+		{ID: 1, CPUs: []int{4, 5, 6, 7}, HugePagesTotal: 1024},
+	}
+	return newDedicatedResources(topology, 2048, []int{2, 3, 5, 6, 7})
+}
+
+func checkDedicatedStats(t *testing.T, d *dedicatedResources, cpus, hugePagesMB int) {
+	cpusAvail, hugePagesTotal, hugePagesAvail, nodes := d.stats()
+	if cpusAvail != cpus {
+		t.Errorf("Expected %d dedicated CPUs available, found %d", cpus, cpusAvail)
+	}
+	if hugePagesTotal != 3072 {
+		t.Errorf("Expected 3072 MB of huge pages, found %d", hugePagesTotal)
+	}
+	if hugePagesAvail != hugePagesMB {
+		t.Errorf("Expected %d MB of huge pages available, found %d",
+			hugePagesMB, hugePagesAvail)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("Expected stats for 2 NUMA nodes, found %d", len(nodes))
+	}
+}
+
+// Checks that dedicated CPUs and huge pages are correctly allocated.
+//
+// Allocate resources for a NUMA local instance with dedicated CPUs and huge
+// pages, then for an instance with dedicated CPUs only, then for an instance
+// that cannot be accommodated.  Finally release the first instance.
+//
+// The first instance should be placed on node 1, the only node with enough
+// pinnable CPUs and huge pages.  The second instance should be given the
+// remaining pinnable CPUs from both nodes.  The third allocation
+// should fail and the released resources should become available again.
+func TestDedicatedAllocate(t *testing.T) {
+	d := newTestDedicatedResources()
+	checkDedicatedStats(t, d, 5, 3072)
+
+	cfg := &vmConfig{Cpus: 2, Mem: 1536, DedicatedCPUs: true, HugePages: true,
+		NUMALocal: true}
+	if !d.allocate("instance1", cfg) {
+		t.Fatal("Unable to allocate resources for instance1")
+	}
+	if cfg.HostNUMANode != 1 || !reflect.DeepEqual(cfg.PinnedCPUs, []int{5, 6}) {
+		t.Errorf("Unexpected placement of instance1: node %d cpus %v",
+			cfg.HostNUMANode, cfg.PinnedCPUs)
+	}
+	checkDedicatedStats(t, d, 3, 1536)
+
+	cfg2 := &vmConfig{Cpus: 3, Mem: 512, DedicatedCPUs: true}
+	if !d.allocate("instance2", cfg2) {
+		t.Fatal("Unable to allocate resources for instance2")
+	}
+	if !reflect.DeepEqual(cfg2.PinnedCPUs, []int{2, 3, 7}) {
+		t.Errorf("Unexpected cpus for instance2: %v", cfg2.PinnedCPUs)
+	}
+	checkDedicatedStats(t, d, 0, 1536)
+
+	cfg3 := &vmConfig{Cpus: 1, Mem: 512, DedicatedCPUs: true}
+	if d.allocate("instance3", cfg3) {
+		t.Error("Allocation for instance3 expected to fail")
+	}
+
+	d.release("instance1")
+	checkDedicatedStats(t, d, 2, 3072)
+}
+
+// Checks the NUMA local placement of instances without dedicated CPUs.
+//
+// Allocate resources for a NUMA local instance with huge pages, but
+// without dedicated CPUs.
+//
+// The instance should be placed on the first node and allowed to run on
+// the node's CPUs that are not pinnable.
+func TestDedicatedNUMALocal(t *testing.T) {
+	d := newTestDedicatedResources()
+
+	cfg := &vmConfig{Cpus: 4, Mem: 512, HugePages: true, NUMALocal: true}
+	if !d.allocate("instance", cfg) {
+		t.Fatal("Unable to allocate resources for instance")
+	}
+	if cfg.HostNUMANode != 0 || !reflect.DeepEqual(cfg.PinnedCPUs, []int{0, 1}) {
+		t.Errorf("Unexpected placement of instance: node %d cpus %v",
+			cfg.HostNUMANode, cfg.PinnedCPUs)
+	}
+	checkDedicatedStats(t, d, 5, 2560)
+}
+
+// Checks that instances without dedicated CPUs are confined to the shared
+// CPUs.
+//
+// Allocate resources for an instance without dedicated CPUs on a host with
+// pinnable CPUs, on a host without pinnable CPUs and on a host whose CPUs
+// are all pinnable.
+//
+// The first instance should be confined to the CPUs that are not pinnable,
+// the second should not be confined and the third allocation should fail.
+func TestDedicatedShared(t *testing.T) {
+	d := newTestDedicatedResources()
+	cfg := &vmConfig{Cpus: 2, Mem: 512}
+	if !d.allocate("instance1", cfg) {
+		t.Fatal("Unable to allocate resources for instance1")
+	}
+	if !reflect.DeepEqual(cfg.PinnedCPUs, []int{0, 1, 4}) {
+		t.Errorf("Unexpected cpus for instance1: %v", cfg.PinnedCPUs)
+	}
+	checkDedicatedStats(t, d, 5, 3072)
+
+	topology := []deviceinfo.NUMANode{{ID: 0, CPUs: []int{0, 1}}}
+	d = newDedicatedResources(topology, 0, nil)
+	cfg = &vmConfig{Cpus: 2, Mem: 512}
+	if !d.allocate("instance2", cfg) {
+		t.Fatal("Unable to allocate resources for instance2")
+	}
+	if cfg.PinnedCPUs != nil {
+		t.Errorf("Unexpected cpus for instance2: %v", cfg.PinnedCPUs)
+	}
+
+	d = newDedicatedResources(topology, 0, []int{0, 1})
+	cfg = &vmConfig{Cpus: 2, Mem: 512}
+	if d.allocate("instance3", cfg) {
+		t.Error("Allocation for instance3 expected to fail")
+	}
+}
+
+// Checks that resources allocated to existing instances are restored.
+//
+// Restore the resources of an instance with dedicated CPUs and huge pages
+// and then release them.
+//
+// The resources of the instance should be marked as in use and then become
+// available again.
+func TestDedicatedRestore(t *testing.T) {
+	d := newTestDedicatedResources()
+
+	cfg := &vmConfig{Cpus: 2, Mem: 1024, DedicatedCPUs: true, HugePages: true,
+		PinnedCPUs: []int{2, 5}, HostNUMANode: 1}
+	d.restore("instance", cfg)
+	checkDedicatedStats(t, d, 3, 2048)
+
+	d.release("instance")
+	checkDedicatedStats(t, d, 5, 3072)
+}
@@ -29,13 +29,13 @@ func deleteVnic(instanceDir string, conn serverConn) {
 		return
 	}
 
-	vnicCfg, err := createVnicCfg(cfg)
+	vnicCfgs, err := createVnicCfgs(cfg)
 	if err != nil {
 		glog.Warningf("Unable to create vnicCfg: %s", err)
 		return
 	}
 
-	err = destroyVnic(conn, vnicCfg)
+	err = destroyVnics(conn, vnicCfgs)
 	if err != nil {
 		glog.Warningf("Unable to destroy vnic: %s", err)
 	}
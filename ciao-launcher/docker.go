@@ -24,6 +24,8 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -31,10 +33,12 @@ import (
 	"context"
 
 	storage "github.com/ciao-project/ciao/ciao-storage"
+	"github.com/ciao-project/ciao/payloads"
 
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/engine-api/client"
 	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/blkiodev"
 	"github.com/docker/engine-api/types/container"
 	"github.com/docker/engine-api/types/filters"
 	"github.com/docker/engine-api/types/network"
@@ -60,6 +64,7 @@ type docker struct {
 	dockerID       string
 	prevCPUTime    int64
 	prevSampleTime time.Time
+	blockIO        *payloads.BlockIOStat
 	storageDriver  storage.BlockDriver
 	mount          mounter
 	cli            containerManager
@@ -172,8 +177,42 @@ func (d *docker) ensureBackingImage() error {
 	return nil
 }
 
+// blkioDevice returns the path of the disk that holds the docker data root
+// and consequently the root filesystems of the containers.  Docker can only
+// throttle I/O to whole disks, so if the data root lives on a partition the
+// disk containing that partition is returned.
+func (d *docker) blkioDevice() (string, error) {
+	info, err := d.cli.Info(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("Unable to retrieve docker info: %v", err)
+	}
+
+	var st syscall.Stat_t
+	if err = syscall.Stat(info.DockerRootDir, &st); err != nil {
+		return "", fmt.Errorf("Unable to stat %s: %v", info.DockerRootDir, err)
+	}
+
+	major := (st.Dev >> 8) & 0xfff
+	minor := (st.Dev & 0xff) | ((st.Dev >> 12) & 0xfff00)
+	if major == 0 {
+		return "", fmt.Errorf("%s is not backed by a block device",
+			info.DockerRootDir)
+	}
+
+	sysDev := fmt.Sprintf("/sys/dev/block/%d:%d", major, minor)
+	if _, err = os.Stat(path.Join(sysDev, "partition")); err == nil {
+		dev, err := ioutil.ReadFile(path.Join(sysDev, "..", "dev"))
+		if err != nil {
+			return "", fmt.Errorf("Unable to find disk of %s: %v", sysDev, err)
+		}
+		return path.Join("/dev/block", strings.TrimSpace(string(dev))), nil
+	}
+
+	return fmt.Sprintf("/dev/block/%d:%d", major, minor), nil
+}
+
 func (d *docker) createConfigs(bridge, gatewayIP string, userData,
-	metaData []byte, volumes []string) (config *container.Config,
+	metaData []byte, volumes []string, blkioDev string) (config *container.Config,
 	hostConfig *container.HostConfig, networkConfig *network.NetworkingConfig) {
 
 	var hostname string
@@ -205,10 +244,15 @@ func (d *docker) createConfigs(bridge, gatewayIP string, userData,
 		}
 	}
 
+	// Containers whose workload requests a console are given a terminal
+	// and an open stdin so that users can attach to them via the
+	// instance console.
 	config = &container.Config{
-		Hostname: hostname,
-		Image:    d.cfg.DockerImage,
-		Cmd:      cmd,
+		Hostname:  hostname,
+		Image:     d.cfg.DockerImage,
+		Cmd:       cmd,
+		Tty:       d.cfg.Console,
+		OpenStdin: d.cfg.Console,
 	}
 
 	hostConfig = &container.HostConfig{
@@ -230,6 +274,34 @@ func (d *docker) createConfigs(bridge, gatewayIP string, userData,
 		hostConfig.CPUQuota = hostConfig.CPUPeriod * int64(d.cfg.Cpus)
 	}
 
+	if len(d.cfg.PinnedCPUs) > 0 {
+		cpus := make([]string, len(d.cfg.PinnedCPUs))
+		for i, cpu := range d.cfg.PinnedCPUs {
+			cpus[i] = strconv.Itoa(cpu)
+		}
+		hostConfig.CpusetCpus = strings.Join(cpus, ",")
+	}
+
+	if d.cfg.NUMALocal {
+		hostConfig.CpusetMems = strconv.Itoa(d.cfg.HostNUMANode)
+	}
+
+	if blkioDev != "" && d.cfg.DiskIOPS > 0 {
+		iops := []*blkiodev.ThrottleDevice{
+			{Path: blkioDev, Rate: uint64(d.cfg.DiskIOPS)},
+		}
+		hostConfig.BlkioDeviceReadIOps = iops
+		hostConfig.BlkioDeviceWriteIOps = iops
+	}
+
+	if blkioDev != "" && d.cfg.DiskMBps > 0 {
+		bps := []*blkiodev.ThrottleDevice{
+			{Path: blkioDev, Rate: uint64(d.cfg.diskBPS())},
+		}
+		hostConfig.BlkioDeviceReadBps = bps
+		hostConfig.BlkioDeviceWriteBps = bps
+	}
+
 	networkConfig = &network.NetworkingConfig{}
 	if bridge != "" {
 		config.MacAddress = d.cfg.VnicMAC
@@ -303,6 +375,9 @@ func (d *docker) prepareVolumes() ([]string, error) {
 		}
 
 		volumes[i] = fmt.Sprintf("%s:/volumes/%s", vd, vol.UUID)
+		if vol.ReadOnly {
+			volumes[i] += ":ro"
+		}
 	}
 
 	return volumes, nil
@@ -320,8 +395,17 @@ func (d *docker) createImage(bridge, gatewayIP string, userData, metaData []byte
 		return err
 	}
 
+	var blkioDev string
+	if d.cfg.DiskIOPS > 0 || d.cfg.DiskMBps > 0 {
+		blkioDev, err = d.blkioDevice()
+		if err != nil {
+			glog.Warningf("Unable to throttle disk I/O of %s: %v",
+				d.cfg.Instance, err)
+		}
+	}
+
 	config, hostConfig, networkConfig := d.createConfigs(bridge, gatewayIP,
-		userData, metaData, volumes)
+		userData, metaData, volumes, blkioDev)
 
 	resp, err := d.cli.ContainerCreate(context.Background(), config, hostConfig, networkConfig,
 		d.cfg.Instance)
@@ -373,7 +457,7 @@ func (d *docker) deleteImage() error {
 	return dockerDeleteContainer(d.cli, d.dockerID, d.cfg.Instance)
 }
 
-func (d *docker) startVM(vnicName, ipAddress, cephID string) error {
+func (d *docker) startVM(vnicNames []string, ipAddress, cephID string) error {
 	err := d.initDockerClient()
 	if err != nil {
 		return err
@@ -395,14 +479,45 @@ func (d *docker) startVM(vnicName, ipAddress, cephID string) error {
 	return nil
 }
 
+func (d *docker) resumeVM(vnicNames []string, ipAddress, cephID, stateFile string) error {
+	return fmt.Errorf("Resume not supported for containers")
+}
+
+// stopContainer asks docker to stop a container, giving it timeout to exit
+// after receiving SIGTERM before it is killed.  Docker does not tell us
+// whether it had to kill the container, so we infer this from the exit code
+// of the container, which is only valid once lostContainerCh is closed.
+func stopContainer(cli containerManager, instance, dockerID string, timeout time.Duration,
+	lostContainerCh chan struct{}, exitCode *int) payloads.StopMethod {
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	err := cli.ContainerStop(context.Background(), dockerID, seconds)
+	if err != nil {
+		glog.Warningf("Unable to stop instance %s:%s cleanly: %v", instance, dockerID, err)
+		err = cli.ContainerKill(context.Background(), dockerID, "KILL")
+		if err != nil {
+			glog.Errorf("Unable to stop instance %s:%s: %v", instance, dockerID, err)
+		}
+		return payloads.StopForced
+	}
+
+	<-lostContainerCh
+	if *exitCode == 128+int(syscall.SIGKILL) {
+		return payloads.StopForced
+	}
+
+	return payloads.StopGraceful
+}
+
 func dockerCommandLoop(cli containerManager, dockerChannel chan interface{}, instance, dockerID string) {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	lostContainerCh := make(chan struct{})
+	var exitCode int
 	go func() {
 		defer close(lostContainerCh)
 		ret, err := cli.ContainerWait(ctx, dockerID)
 		glog.Infof("Instance %s:%s exitted with code %d err %v",
 			instance, dockerID, ret, err)
+		exitCode = ret
 	}()
 
 DONE:
@@ -419,13 +534,18 @@ DONE:
 			}
 			switch cmd := cmd.(type) {
 			case virtualizerStopCmd:
-				err := cli.ContainerKill(context.Background(), dockerID, "KILL")
-				if err != nil {
-					glog.Errorf("Unable to stop instance %s:%s: %v", instance, dockerID, err)
-				}
+				cmd.stopped(stopContainer(cli, instance, dockerID, cmd.timeout,
+					lostContainerCh, &exitCode))
 			case virtualizerAttachCmd:
 				err := fmt.Errorf("Live Attach of volumes not supported for containers")
 				cmd.responseCh <- err
+			case virtualizerPauseCmd:
+				cmd.responseCh <- cli.ContainerPause(context.Background(), dockerID)
+			case virtualizerUnpauseCmd:
+				cmd.responseCh <- cli.ContainerUnpause(context.Background(), dockerID)
+			case virtualizerSuspendCmd:
+				err := fmt.Errorf("Suspend not supported for containers")
+				cmd.responseCh <- err
 			}
 		}
 	}
@@ -512,6 +632,7 @@ func (d *docker) stats() (disk, memory, cpu int) {
 	disk = d.computeInstanceDiskspace()
 	memory = -1
 	cpu = -1
+	d.blockIO = nil
 
 	if d.cfg == nil {
 		return
@@ -542,6 +663,24 @@ func (d *docker) stats() (disk, memory, cpu int) {
 	// The value from docker comes in bytes
 	memory = int(stats.MemoryStats.Usage / 1024 / 1024)
 
+	d.blockIO = &payloads.BlockIOStat{}
+	for _, e := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch e.Op {
+		case "Read":
+			d.blockIO.ReadBytes += int64(e.Value)
+		case "Write":
+			d.blockIO.WriteBytes += int64(e.Value)
+		}
+	}
+	for _, e := range stats.BlkioStats.IoServicedRecursive {
+		switch e.Op {
+		case "Read":
+			d.blockIO.ReadOps += int64(e.Value)
+		case "Write":
+			d.blockIO.WriteOps += int64(e.Value)
+		}
+	}
+
 	cpuTime := int64(stats.CPUStats.CPUUsage.TotalUsage)
 	now := time.Now()
 	if d.prevCPUTime != -1 {
@@ -560,6 +699,14 @@ func (d *docker) stats() (disk, memory, cpu int) {
 	return
 }
 
+func (d *docker) guestInfo() *payloads.GuestInfo {
+	return nil
+}
+
+func (d *docker) blockStats() *payloads.BlockIOStat {
+	return d.blockIO
+}
+
 func (d *docker) connected() {
 	d.prevCPUTime = -1
 }
@@ -569,3 +716,45 @@ func (d *docker) lostVM() {
 
 	d.umountVolumes(d.cfg.Volumes)
 }
+
+// dockerConsole adapts the hijacked connection returned by ContainerAttach
+// to an io.ReadWriteCloser.
+type dockerConsole struct {
+	types.HijackedResponse
+}
+
+func (c dockerConsole) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+func (c dockerConsole) Write(p []byte) (int, error) {
+	return c.Conn.Write(p)
+}
+
+func (c dockerConsole) Close() error {
+	return c.Conn.Close()
+}
+
+func (d *docker) attachConsole() (io.ReadWriteCloser, error) {
+	if d.cli == nil {
+		return nil, fmt.Errorf("Docker client not initialised")
+	}
+
+	if !d.cfg.Console {
+		return nil, fmt.Errorf("Container was not created with a console")
+	}
+
+	resp, err := d.cli.ContainerAttach(context.Background(),
+		types.ContainerAttachOptions{
+			ContainerID: d.dockerID,
+			Stream:      true,
+			Stdin:       true,
+			Stdout:      true,
+			Stderr:      true,
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerConsole{resp}, nil
+}
@@ -33,11 +33,13 @@ import (
 	"golang.org/x/net/context"
 
 	storage "github.com/ciao-project/ciao/ciao-storage"
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/testutil"
 
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/engine-api/client"
 	"github.com/docker/engine-api/types"
+	"github.com/docker/engine-api/types/blkiodev"
 	"github.com/docker/engine-api/types/container"
 	"github.com/docker/engine-api/types/network"
 )
@@ -115,6 +117,14 @@ func (s dockerTestStorage) Resize(string, int) (int, error) {
 	return 0, nil
 }
 
+func (s dockerTestStorage) ExportBlockDeviceSnapshot(volumeUUID string, snapshotID string, w io.Writer) error {
+	return nil
+}
+
+func (s dockerTestStorage) ImportBlockDevice(volumeUUID string, r io.Reader, sizeGB int) (storage.BlockDevice, error) {
+	return storage.BlockDevice{}, nil
+}
+
 type dockerTestClient struct {
 	err               error
 	images            []types.Image
@@ -125,6 +135,10 @@ type dockerTestClient struct {
 	containerWaitCh   chan struct{}
 }
 
+func (d *dockerTestClient) Info(context.Context) (types.Info, error) {
+	return types.Info{}, fmt.Errorf("Not implemented")
+}
+
 func (d *dockerTestClient) ImageList(context.Context, types.ImageListOptions) ([]types.Image, error) {
 	if d.err != nil {
 		return nil, d.err
@@ -196,6 +210,18 @@ func (d *dockerTestClient) ContainerStats(context.Context, string, bool) (io.Rea
   },
   "memory_stats" : {
      "usage" : 104857600
+  },
+  "blkio_stats" : {
+    "io_service_bytes_recursive" : [
+      { "major" : 8, "minor" : 0, "op" : "Read", "value" : 4096 },
+      { "major" : 8, "minor" : 0, "op" : "Write", "value" : 8192 },
+      { "major" : 8, "minor" : 0, "op" : "Total", "value" : 12288 }
+    ],
+    "io_serviced_recursive" : [
+      { "major" : 8, "minor" : 0, "op" : "Read", "value" : 1 },
+      { "major" : 8, "minor" : 0, "op" : "Write", "value" : 2 },
+      { "major" : 8, "minor" : 0, "op" : "Total", "value" : 3 }
+    ]
   }
 }`)
 
@@ -207,6 +233,23 @@ func (d *dockerTestClient) ContainerKill(context.Context, string, string) error
 	return nil
 }
 
+func (d *dockerTestClient) ContainerStop(context.Context, string, int) error {
+	close(d.containerWaitCh)
+	return nil
+}
+
+func (d *dockerTestClient) ContainerPause(context.Context, string) error {
+	return d.err
+}
+
+func (d *dockerTestClient) ContainerUnpause(context.Context, string) error {
+	return d.err
+}
+
+func (d *dockerTestClient) ContainerAttach(context.Context, types.ContainerAttachOptions) (types.HijackedResponse, error) {
+	return types.HijackedResponse{}, fmt.Errorf("Not implemented")
+}
+
 func (d *dockerTestClient) ContainerWait(ctx context.Context, id string) (int, error) {
 	select {
 	case <-d.containerWaitCh:
@@ -567,8 +610,9 @@ func TestDockerCreateImageFail(t *testing.T) {
 // then tries to create an image with a bootable volume.
 //
 // The first call to createImage should succeed and directories should be created
-// for each volume.  The second call to createImage should fail as it's not
-// possible to create an image with a bootable volume.
+// for each volume and read only volumes should be bound read only.  The second
+// call to createImage should fail as it's not possible to create an image with
+// a bootable volume.
 func TestDockerCreateImageWithVolumes(t *testing.T) {
 	tmpDir, err := ioutil.TempDir("", "ciao-docker-tests")
 	if err != nil {
@@ -584,7 +628,7 @@ func TestDockerCreateImageWithVolumes(t *testing.T) {
 		cfg: &vmConfig{
 			Volumes: []volumeConfig{
 				{UUID: "92a1e4fa-8448-4260-adb1-4d2dd816cc7c"},
-				{UUID: "5ce2c5bf-58d9-4573-b433-05550b945866"},
+				{UUID: "5ce2c5bf-58d9-4573-b433-05550b945866", ReadOnly: true},
 			},
 		}}
 
@@ -592,7 +636,7 @@ func TestDockerCreateImageWithVolumes(t *testing.T) {
 		t.Fatalf("Unable to create image : %v", err)
 	}
 
-	for _, vol := range tc.hostConfig.Binds {
+	for i, vol := range tc.hostConfig.Binds {
 		volInfo := strings.Split(vol, ":")
 		fi, err := os.Stat(volInfo[0])
 		if err != nil {
@@ -602,6 +646,10 @@ func TestDockerCreateImageWithVolumes(t *testing.T) {
 		if !fi.IsDir() {
 			t.Errorf("%s is not a directory", vol)
 		}
+		readOnly := len(volInfo) == 3 && volInfo[2] == "ro"
+		if readOnly != d.cfg.Volumes[i].ReadOnly {
+			t.Errorf("Unexpected access mode for %s", vol)
+		}
 	}
 
 	err = d.deleteImage()
@@ -662,6 +710,38 @@ func TestDockerCreateImageWithResources(t *testing.T) {
 	}
 }
 
+// Check createConfigs throttles disk I/O correctly
+//
+// Create the configs of a container with disk I/O limits.
+//
+// The read and write IOPS and bandwidth of the supplied device are limited
+// to the values specified in the instance's configuration.
+func TestDockerCreateConfigsWithIOLimits(t *testing.T) {
+	d := &docker{
+		cfg: &vmConfig{
+			DiskIOPS: 500,
+			DiskMBps: 20,
+		}}
+
+	_, hostConfig, _ := d.createConfigs("", "", nil, nil, nil, "/dev/block/8:0")
+
+	check := func(name string, devs []*blkiodev.ThrottleDevice, rate uint64) {
+		if len(devs) != 1 || devs[0].Path != "/dev/block/8:0" ||
+			devs[0].Rate != rate {
+			t.Errorf("Wrong %s throttling %v", name, devs)
+		}
+	}
+	check("read IOPS", hostConfig.BlkioDeviceReadIOps, 500)
+	check("write IOPS", hostConfig.BlkioDeviceWriteIOps, 500)
+	check("read bps", hostConfig.BlkioDeviceReadBps, 20*1000*1000)
+	check("write bps", hostConfig.BlkioDeviceWriteBps, 20*1000*1000)
+
+	_, hostConfig, _ = d.createConfigs("", "", nil, nil, nil, "")
+	if hostConfig.BlkioDeviceReadIOps != nil || hostConfig.BlkioDeviceReadBps != nil {
+		t.Errorf("Disk I/O throttled without a device")
+	}
+}
+
 // Checks the monitorVM function works correctly.
 //
 // This test creates a new instance, calls monitor VM, waits for the connected
@@ -711,7 +791,8 @@ func TestDockerMonitorVM(t *testing.T) {
 		t.Fatalf("Timed out waiting to connect to container")
 	}
 
-	dockerCh <- virtualizerStopCmd{}
+	methodCh := make(chan payloads.StopMethod, 1)
+	dockerCh <- virtualizerStopCmd{time.Second, methodCh}
 
 	select {
 	case <-closedCh:
@@ -719,6 +800,10 @@ func TestDockerMonitorVM(t *testing.T) {
 		t.Fatalf("Timed out waiting to connect to container")
 	}
 
+	if method := <-methodCh; method != payloads.StopGraceful {
+		t.Errorf("Expected graceful stop, got %s", method)
+	}
+
 	wg.Wait()
 }
 
@@ -764,8 +849,8 @@ func TestDockerMonitorVMClose(t *testing.T) {
 //
 // Call the stats method twice.  The second call is required to retrieve cpu stats.
 //
-// The stats method should return the statistics provisioned in the dockerTestClient
-// ContainerInspectWithRaw and ContainerStats methods.
+// The stats and blockStats methods should return the statistics provisioned in
+// the dockerTestClient ContainerInspectWithRaw and ContainerStats methods.
 func TestDockerStats(t *testing.T) {
 	tc := &dockerTestClient{}
 	d := &docker{dockerID: testutil.InstanceUUID, cfg: &vmConfig{}, cli: tc, prevCPUTime: -1}
@@ -783,6 +868,16 @@ func TestDockerStats(t *testing.T) {
 		t.Errorf("Expected cpu usage of -1.  Got %d", cpu)
 	}
 
+	expected := payloads.BlockIOStat{
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		ReadOps:    1,
+		WriteOps:   2,
+	}
+	if blockIO := d.blockStats(); blockIO == nil || *blockIO != expected {
+		t.Errorf("Expected block stats %+v.  Got %+v", expected, blockIO)
+	}
+
 	_, _, cpu = d.stats()
 	if cpu != 0 {
 		t.Errorf("Expected cpu usage of 0.  Got %d", cpu)
@@ -0,0 +1,276 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/ciao-project/ciao/deviceinfo"
+	"github.com/golang/glog"
+)
+
+// ephemeralPool manages the storage from which the launcher carves the
+// local disks of the instances it hosts.  Each disk is created with a fixed
+// size, which is the limit on the amount of pool storage the disk can
+// consume.
+type ephemeralPool interface {
+	// createDisk creates a new, empty, disk of sizeMB for an instance and
+	// returns the path of the block device or image that backs it.
+	createDisk(instance string, index, sizeMB int) (string, error)
+
+	// deleteDisk destroys a disk previously returned by createDisk.
+	deleteDisk(path string) error
+
+	// diskUsageMB returns the amount of pool storage consumed by a disk
+	// or -1 if this cannot be determined.
+	diskUsageMB(path string) int
+
+	// usage returns the size of the pool and the amount of storage that
+	// is not yet consumed by any disk.  Both values are -1 if the pool
+	// cannot be queried.
+	usage() (totalMB, availableMB int)
+}
+
+// newEphemeralPool creates an ephemeralPool from a specification string of
+// the form dir:<path> or lvm:<volume group>/<thin pool>.
+func newEphemeralPool(spec string) (ephemeralPool, error) {
+	i := strings.Index(spec, ":")
+	if i == -1 {
+		return nil, fmt.Errorf("Invalid ephemeral pool %s", spec)
+	}
+
+	kind, location := spec[:i], spec[i+1:]
+	switch kind {
+	case "dir":
+		if !filepath.IsAbs(location) {
+			return nil, fmt.Errorf("Ephemeral pool directory %s is not absolute",
+				location)
+		}
+		if err := os.MkdirAll(location, 0755); err != nil {
+			return nil, fmt.Errorf("Unable to create ephemeral pool directory %s: %v",
+				location, err)
+		}
+		return &dirPool{path: location}, nil
+	case "lvm":
+		parts := strings.Split(location, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("Invalid LVM thin pool %s", location)
+		}
+		pool := &lvmPool{vg: parts[0], thinPool: parts[1]}
+		if _, _, err := pool.lvs(pool.vg + "/" + pool.thinPool); err != nil {
+			return nil, fmt.Errorf("Unable to find LVM thin pool %s: %v",
+				location, err)
+		}
+		return pool, nil
+	}
+
+	return nil, fmt.Errorf("Unknown ephemeral pool type %s", kind)
+}
+
+// dirPool stores local disks as sparse raw images in a directory.
+type dirPool struct {
+	path string
+}
+
+func (p *dirPool) createDisk(instance string, index, sizeMB int) (string, error) {
+	dir := filepath.Join(p.path, instance)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	diskPath := filepath.Join(dir, fmt.Sprintf("disk-%d.raw", index))
+	f, err := os.OpenFile(diskPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	err = f.Truncate(int64(sizeMB) * 1024 * 1024)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		_ = os.Remove(diskPath)
+		return "", err
+	}
+
+	return diskPath, nil
+}
+
+func (p *dirPool) deleteDisk(path string) error {
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Only succeeds once the last disk of the instance has been removed.
+	_ = os.Remove(filepath.Dir(path))
+
+	return nil
+}
+
+func (p *dirPool) diskUsageMB(path string) int {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return -1
+	}
+
+	return int((st.Blocks * 512) / (1024 * 1024))
+}
+
+func (p *dirPool) usage() (totalMB, availableMB int) {
+	return deviceinfo.GetFSInfo(p.path)
+}
+
+// lvmPool stores local disks as thin volumes in an LVM thin pool.
+type lvmPool struct {
+	vg       string
+	thinPool string
+}
+
+func lvmCommand(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %v: %s", name, err,
+			strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// lvs returns the size and the amount of data written, both in MB, of the
+// given logical volume.
+func (p *lvmPool) lvs(lv string) (sizeMB, usedMB int, err error) {
+	out, err := lvmCommand("lvs", "--noheadings", "--nosuffix", "--units", "m",
+		"-o", "lv_size,data_percent", lv)
+	if err != nil {
+		return -1, -1, err
+	}
+
+	return parseLVS(string(out))
+}
+
+func parseLVS(out string) (sizeMB, usedMB int, err error) {
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return -1, -1, fmt.Errorf("Unexpected lvs output: %s", out)
+	}
+
+	size, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return -1, -1, fmt.Errorf("Invalid lvs size %s", fields[0])
+	}
+
+	percent, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return -1, -1, fmt.Errorf("Invalid lvs data percentage %s", fields[1])
+	}
+
+	return int(size), int(size * percent / 100), nil
+}
+
+func (p *lvmPool) createDisk(instance string, index, sizeMB int) (string, error) {
+	name := fmt.Sprintf("ciao-%s-%d", instance, index)
+	_, err := lvmCommand("lvcreate", "-q", "-V", fmt.Sprintf("%dm", sizeMB),
+		"-T", p.vg+"/"+p.thinPool, "-n", name)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join("/dev", p.vg, name), nil
+}
+
+func (p *lvmPool) deleteDisk(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	_, err := lvmCommand("lvremove", "-q", "-f", path)
+	return err
+}
+
+func (p *lvmPool) diskUsageMB(path string) int {
+	_, usedMB, err := p.lvs(path)
+	if err != nil {
+		glog.Warningf("Unable to determine usage of %s: %v", path, err)
+		return -1
+	}
+
+	return usedMB
+}
+
+func (p *lvmPool) usage() (totalMB, availableMB int) {
+	sizeMB, usedMB, err := p.lvs(p.vg + "/" + p.thinPool)
+	if err != nil {
+		glog.Warningf("Unable to determine usage of thin pool %s/%s: %v",
+			p.vg, p.thinPool, err)
+		return -1, -1
+	}
+
+	return sizeMB, sizeMB - usedMB
+}
+
+func createEphemeralDisks(cfg *vmConfig) error {
+	for i := range cfg.EphemeralDisks {
+		disk := &cfg.EphemeralDisks[i]
+		path, err := ephemeral.createDisk(cfg.Instance, i, disk.SizeMB)
+		if err != nil {
+			deleteEphemeralDisks(cfg)
+			return fmt.Errorf("Unable to create local disk %d: %v", i, err)
+		}
+		disk.Path = path
+		glog.Infof("Created local disk %s of %d MB", path, disk.SizeMB)
+	}
+
+	return nil
+}
+
+func deleteEphemeralDisks(cfg *vmConfig) {
+	for i := range cfg.EphemeralDisks {
+		disk := &cfg.EphemeralDisks[i]
+		if disk.Path == "" {
+			continue
+		}
+		if err := ephemeral.deleteDisk(disk.Path); err != nil {
+			glog.Warningf("Unable to delete local disk %s: %v", disk.Path, err)
+			continue
+		}
+		disk.Path = ""
+	}
+}
+
+func ephemeralDisksUsageMB(cfg *vmConfig) int {
+	usage := 0
+	for _, disk := range cfg.EphemeralDisks {
+		if disk.Path == "" {
+			continue
+		}
+		if used := ephemeral.diskUsageMB(disk.Path); used > 0 {
+			usage += used
+		}
+	}
+
+	return usage
+}
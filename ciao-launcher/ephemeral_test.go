@@ -0,0 +1,145 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Checks that newEphemeralPool rejects malformed pool specifications.
+//
+// newEphemeralPool is called with a number of invalid specifications and
+// once with a valid directory specification.
+//
+// An error should be returned for each invalid specification and a dirPool
+// should be returned, and its directory created, for the valid one.
+func TestNewEphemeralPool(t *testing.T) {
+	for _, spec := range []string{"", "/var/lib/ciao", "dir:relative",
+		"lvm:novg", "lvm:/pool", "zfs:tank"} {
+		if _, err := newEphemeralPool(spec); err == nil {
+			t.Errorf("Expected error for ephemeral pool %q", spec)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "ephemeral-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	poolDir := filepath.Join(dir, "pool")
+	pool, err := newEphemeralPool("dir:" + poolDir)
+	if err != nil {
+		t.Fatalf("Unable to create ephemeral pool: %v", err)
+	}
+	if dp, ok := pool.(*dirPool); !ok || dp.path != poolDir {
+		t.Fatalf("Unexpected ephemeral pool %v", pool)
+	}
+	if _, err := os.Stat(poolDir); err != nil {
+		t.Fatalf("Ephemeral pool directory not created: %v", err)
+	}
+}
+
+// Checks the life cycle of local disks in a directory pool.
+//
+// Two local disks are created for an instance in a dirPool, their usage
+// queried and then deleted.
+//
+// The disks should be created with the requested sizes but should be sparse,
+// their paths should be recorded in the vmConfig and all of the instance's
+// files should be removed from the pool once the disks are deleted.
+func TestDirPoolDisks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ephemeral-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	savedPool := ephemeral
+	ephemeral = &dirPool{path: dir}
+	defer func() { ephemeral = savedPool }()
+
+	cfg := &vmConfig{
+		Instance: "test-instance",
+		EphemeralDisks: []ephemeralDiskConfig{
+			{SizeMB: 16},
+			{SizeMB: 8, Swap: true},
+		},
+	}
+	if err := createEphemeralDisks(cfg); err != nil {
+		t.Fatalf("Unable to create local disks: %v", err)
+	}
+
+	for _, d := range cfg.EphemeralDisks {
+		fi, err := os.Stat(d.Path)
+		if err != nil {
+			t.Fatalf("Unable to stat local disk %s: %v", d.Path, err)
+		}
+		if fi.Size() != int64(d.SizeMB)*1024*1024 {
+			t.Errorf("Local disk %s has size %d, expected %d MB", d.Path,
+				fi.Size(), d.SizeMB)
+		}
+		if used := ephemeral.diskUsageMB(d.Path); used != 0 {
+			t.Errorf("Local disk %s is not sparse, %d MB used", d.Path, used)
+		}
+	}
+
+	if err := createEphemeralDisks(cfg); err == nil {
+		t.Errorf("Expected error when recreating local disks")
+	}
+
+	deleteEphemeralDisks(cfg)
+	for _, d := range cfg.EphemeralDisks {
+		if d.Path != "" {
+			t.Errorf("Path of deleted local disk %s not cleared", d.Path)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, cfg.Instance)); !os.IsNotExist(err) {
+		t.Errorf("Instance directory not removed from pool")
+	}
+
+	if total, available := ephemeral.usage(); total <= 0 || available < 0 {
+		t.Errorf("Unexpected pool usage %d/%d", available, total)
+	}
+}
+
+// Checks the parsing of lvs output.
+//
+// parseLVS is called with the output of lvs for a thin pool and with some
+// malformed output.
+//
+// The size and used MB of the thin pool should be correctly computed and
+// errors returned for the malformed output.
+func TestParseLVS(t *testing.T) {
+	size, used, err := parseLVS("  10240.00 25.00\n")
+	if err != nil {
+		t.Fatalf("Unable to parse lvs output: %v", err)
+	}
+	if size != 10240 || used != 2560 {
+		t.Errorf("Unexpected lvs results %d/%d", used, size)
+	}
+
+	for _, out := range []string{"", "10240.00", "big 25.00", "10240.00 lots"} {
+		if _, _, err := parseLVS(out); err == nil {
+			t.Errorf("Expected error parsing %q", out)
+		}
+	}
+}
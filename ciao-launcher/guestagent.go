@@ -0,0 +1,199 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/qemu"
+	"github.com/golang/glog"
+)
+
+const (
+	guestAgentSocket = "qga.sock"
+
+	// Interval at which the guest agent of an instance is queried for
+	// information about the guest.
+	guestAgentPollInterval = 30 * time.Second
+
+	// Time given to the guest agent to answer all the queries of a
+	// single poll.
+	guestAgentTimeout = 5 * time.Second
+)
+
+// guestAgentDevices returns the qemu devices that connect a virtio-serial
+// port of a VM, on which the qemu guest agent listens, to a domain socket in
+// its instance directory.
+func guestAgentDevices(instanceDir string) []qemu.Device {
+	return []qemu.Device{
+		qemu.SerialDevice{
+			Driver: qemu.VirtioSerial,
+			ID:     "virtio-serial0",
+		},
+		qemu.CharDevice{
+			Backend:  qemu.Socket,
+			Driver:   qemu.VirtioSerialPort,
+			ID:       "qga0",
+			DeviceID: "channel0",
+			Name:     qemu.GuestAgentChannel,
+			Path:     path.Join(instanceDir, guestAgentSocket),
+		},
+	}
+}
+
+// guestAgent periodically queries the guest agent of a running VM for
+// information about the guest.  The polling go routine is started and
+// stopped by the qmp go routine, whereas the latest information retrieved
+// is read by the instance go routine, hence the mutex.
+type guestAgent struct {
+	instance string
+	socket   string
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+
+	infoLock sync.Mutex
+	info     *payloads.GuestInfo
+}
+
+func newGuestAgent(instance, instanceDir string) *guestAgent {
+	return &guestAgent{
+		instance: instance,
+		socket:   path.Join(instanceDir, guestAgentSocket),
+	}
+}
+
+func (g *guestAgent) start() {
+	g.stopCh = make(chan struct{})
+	g.doneCh = make(chan struct{})
+	go g.poll()
+}
+
+func (g *guestAgent) stop() {
+	close(g.stopCh)
+	<-g.doneCh
+	g.setInfo(nil)
+}
+
+// guestInfo returns the information last reported by the guest agent, or nil
+// if the agent is not responding.
+func (g *guestAgent) guestInfo() *payloads.GuestInfo {
+	g.infoLock.Lock()
+	defer g.infoLock.Unlock()
+	return g.info
+}
+
+func (g *guestAgent) setInfo(info *payloads.GuestInfo) {
+	g.infoLock.Lock()
+	g.info = info
+	g.infoLock.Unlock()
+}
+
+func (g *guestAgent) poll() {
+	var q *qemu.QGA
+
+	defer func() {
+		if q != nil {
+			q.Shutdown()
+		}
+		close(g.doneCh)
+	}()
+
+	ticker := time.NewTicker(guestAgentPollInterval)
+	defer ticker.Stop()
+
+	for {
+		q = g.refresh(q)
+
+		select {
+		case <-g.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *guestAgent) refresh(q *qemu.QGA) *qemu.QGA {
+	ctx, cancelFN := context.WithTimeout(context.Background(), guestAgentTimeout)
+	defer cancelFN()
+
+	if q == nil {
+		var err error
+		q, err = qemu.QGAStart(ctx, g.socket, qemu.QGAConfig{Logger: qmpGlogLogger{}})
+		if err != nil {
+			return nil
+		}
+	}
+
+	info, err := queryGuestInfo(ctx, q)
+	if err != nil {
+		if glog.V(1) {
+			glog.Infof("Guest agent of %s is not responding: %v", g.instance, err)
+		}
+		g.setInfo(nil)
+		return q
+	}
+
+	g.setInfo(info)
+	return q
+}
+
+func queryGuestInfo(ctx context.Context, q *qemu.QGA) (*payloads.GuestInfo, error) {
+	if err := q.ExecuteGuestPing(ctx); err != nil {
+		return nil, err
+	}
+
+	osInfo, err := q.ExecuteGuestGetOSInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ifaces, err := q.ExecuteGuestNetworkGetInterfaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeGuestInfo(osInfo, ifaces), nil
+}
+
+func makeGuestInfo(osInfo *qemu.GuestOSInfo, ifaces []qemu.GuestNetworkInterface) *payloads.GuestInfo {
+	info := &payloads.GuestInfo{
+		OSName:        osInfo.PrettyName,
+		OSVersion:     osInfo.Version,
+		KernelRelease: osInfo.KernelRelease,
+	}
+
+	if info.OSName == "" {
+		info.OSName = osInfo.Name
+	}
+
+	for _, iface := range ifaces {
+		for _, addr := range iface.IPAddresses {
+			ip := net.ParseIP(addr.Address)
+			if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			info.IPAddresses = append(info.IPAddresses, addr.Address)
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,71 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/qemu"
+)
+
+// Checks the conversion of guest agent responses into a GuestInfo.
+//
+// Convert the OS information and the network interfaces of a guest with a
+// loopback interface and an interface with IPv4 and link local IPv6
+// addresses.
+//
+// The pretty name of the OS should be used and only the IPv4 address of the
+// second interface should be reported.
+func TestMakeGuestInfo(t *testing.T) {
+	osInfo := &qemu.GuestOSInfo{
+		Name:          "Ubuntu",
+		PrettyName:    "Ubuntu 16.04.2 LTS",
+		Version:       "16.04.2 LTS (Xenial Xerus)",
+		KernelRelease: "4.4.0-62-generic",
+	}
+	ifaces := []qemu.GuestNetworkInterface{
+		{
+			Name: "lo",
+			IPAddresses: []qemu.GuestIPAddress{
+				{Type: "ipv4", Address: "127.0.0.1", Prefix: 8},
+				{Type: "ipv6", Address: "::1", Prefix: 128},
+			},
+		},
+		{
+			Name:            "eth0",
+			HardwareAddress: "02:00:e6:f5:af:f9",
+			IPAddresses: []qemu.GuestIPAddress{
+				{Type: "ipv4", Address: "192.168.8.2", Prefix: 21},
+				{Type: "ipv6", Address: "fe80::e6ff:fef5:aff9", Prefix: 64},
+			},
+		},
+	}
+
+	expected := &payloads.GuestInfo{
+		OSName:        "Ubuntu 16.04.2 LTS",
+		OSVersion:     "16.04.2 LTS (Xenial Xerus)",
+		KernelRelease: "4.4.0-62-generic",
+		IPAddresses:   []string{"192.168.8.2"},
+	}
+
+	info := makeGuestInfo(osInfo, ifaces)
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("Unexpected guest info %+v", *info)
+	}
+}
@@ -134,6 +134,7 @@ func purgeLauncherState() {
 				dockerKillInstance(path)
 			} else {
 				qemuKillInstance(path)
+				deleteEphemeralDisks(cfg)
 			}
 		}
 		toRemove = append(toRemove, path)
@@ -24,6 +24,7 @@ import (
 	yaml "gopkg.in/yaml.v2"
 
 	storage "github.com/ciao-project/ciao/ciao-storage"
+	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
 	"github.com/golang/glog"
@@ -48,6 +49,9 @@ type instanceData struct {
 	rcvStamp       time.Time
 	st             *startTimes
 	storageDriver  storage.BlockDriver
+	paused         bool
+	suspended      bool
+	vnicCfg        *libsnnet.VnicConfig
 }
 
 type insStartCmd struct {
@@ -77,11 +81,42 @@ type insDeleteCmd struct {
 	// two operations are almost identical for launcher.  The only difference
 	// is in the events that get sent back to controller.
 	stop bool
+
+	// Time given to a running instance to shut down cleanly before it is
+	// terminated.  If 0, the value of the -graceful_timeout option is
+	// used.
+	timeout time.Duration
 }
 type insMonitorCmd struct{}
 
 type insAttachVolumeCmd struct {
 	volumeUUID string
+	readOnly   bool
+	shared     bool
+}
+
+type insDetachVolumeCmd struct {
+	volumeUUID string
+}
+
+type insConsoleLogCmd struct {
+	lines     int
+	requestID string
+}
+
+type insOpenConsoleCmd struct {
+	token string
+}
+
+// insPauseCmd is used for the PauseInstance, UnpauseInstance,
+// SuspendInstance and ResumeInstance commands, which all change the power
+// state of an existing instance.
+type insPauseCmd struct {
+	command ssntp.Command
+}
+
+type insUpdateConcentratorCmd struct {
+	update *updateConcentratorCmd
 }
 
 /*
@@ -158,6 +193,14 @@ func (id *instanceData) startCommand(cmd *insStartCmd) {
 }
 
 func (id *instanceData) monitorCommand(cmd *insMonitorCmd) {
+	if instanceSuspended(id.instanceDir) {
+		glog.Infof("Instance %s is suspended", id.instance)
+		id.suspended = true
+		id.ovsCh <- &ovsStateChange{id.instance, ovsSuspended}
+		return
+	}
+
+	id.paused = instancePaused(id.instanceDir)
 	id.connectedCh = make(chan struct{})
 	id.monitorCloseCh = make(chan struct{})
 	id.monitorCh = id.vm.monitorVM(id.monitorCloseCh, id.connectedCh, &id.instanceWg, true)
@@ -180,10 +223,11 @@ func (id *instanceData) sendInstanceDeletedEvent() {
 	}
 }
 
-func (id *instanceData) sendInstanceStoppedEvent() {
+func (id *instanceData) sendInstanceStoppedEvent(method payloads.StopMethod) {
 	var event payloads.EventInstanceStopped
 
 	event.InstanceStopped.InstanceUUID = id.instance
+	event.InstanceStopped.StopMethod = method
 
 	payload, err := yaml.Marshal(&event)
 	if err != nil {
@@ -205,10 +249,18 @@ func (id *instanceData) deleteCommand(cmd *insDeleteCmd) bool {
 		return false
 	}
 
+	var method payloads.StopMethod
 	if id.monitorCh != nil {
-		glog.Infof("Powerdown %s before deleting", id.instance)
-		id.monitorCh <- virtualizerStopCmd{}
+		timeout := cmd.timeout
+		if timeout == 0 {
+			timeout = gracefulTimeout
+		}
+		glog.Infof("Powerdown %s before deleting, timeout %v", id.instance, timeout)
+		methodCh := make(chan payloads.StopMethod, 1)
+		id.monitorCh <- virtualizerStopCmd{timeout, methodCh}
 		<-id.monitorCloseCh
+		method = <-methodCh
+		glog.Infof("%s shut down, %s", id.instance, method)
 		id.vm.lostVM()
 	}
 
@@ -218,7 +270,7 @@ func (id *instanceData) deleteCommand(cmd *insDeleteCmd) bool {
 
 	if !cmd.skipDeleteEvent {
 		if cmd.stop {
-			id.sendInstanceStoppedEvent()
+			id.sendInstanceStoppedEvent(method)
 		} else {
 			id.sendInstanceDeletedEvent()
 		}
@@ -235,18 +287,270 @@ func (id *instanceData) attachVolumeCommand(cmd *insAttachVolumeCmd) {
 		return
 	}
 
+	volume := volumeConfig{
+		UUID:     cmd.volumeUUID,
+		ReadOnly: cmd.readOnly,
+		Shared:   cmd.shared,
+	}
 	attachErr := processAttachVolume(id.storageDriver, id.monitorCh, id.cfg, id.instance, id.instanceDir,
-		cmd.volumeUUID, id.ac.conn)
+		volume, id.ac.conn)
 	if attachErr != nil {
 		attachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
 		return
 	}
-	d, m, c := id.vm.stats()
-	id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+	id.sendStats()
 
 	glog.Infof("Volume %s attached to instance %s", cmd.volumeUUID, id.instance)
 }
 
+func (id *instanceData) sendVolumeDetachedEvent(volumeUUID string) {
+	var event payloads.EventVolumeDetached
+
+	event.VolumeDetached.InstanceUUID = id.instance
+	event.VolumeDetached.VolumeUUID = volumeUUID
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall VolumeDetached %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEvent(ssntp.VolumeDetached, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+func (id *instanceData) detachVolumeCommand(cmd *insDetachVolumeCmd) {
+	if id.shuttingDown {
+		detachErr := &detachVolumeError{nil, payloads.DetachVolumeInstanceFailure}
+		glog.Errorf("Unable to detach instance[%s]", string(detachErr.code))
+		detachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
+		return
+	}
+
+	detachErr := processDetachVolume(id.storageDriver, id.monitorCh, id.cfg, id.instance, id.instanceDir,
+		cmd.volumeUUID, id.ac.conn)
+	if detachErr != nil {
+		detachErr.send(id.ac.conn, id.instance, cmd.volumeUUID)
+		return
+	}
+	id.sendStats()
+
+	id.sendVolumeDetachedEvent(cmd.volumeUUID)
+
+	glog.Infof("Volume %s detached from instance %s", cmd.volumeUUID, id.instance)
+}
+
+func (id *instanceData) sendConsoleLogEvent(log, requestID string) {
+	var event payloads.EventConsoleLog
+
+	event.ConsoleLog.InstanceUUID = id.instance
+	event.ConsoleLog.Log = log
+	event.ConsoleLog.RequestID = requestID
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall ConsoleLog %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEvent(ssntp.ConsoleLog, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+func (id *instanceData) consoleLogCommand(cmd *insConsoleLogCmd) {
+	if id.cfg.Container {
+		cle := &consoleLogError{nil, payloads.ConsoleLogNotSupported}
+		cle.send(id.ac.conn, id.instance, cmd.requestID)
+		return
+	}
+
+	log, err := readConsoleLog(id.instanceDir, cmd.lines)
+	if err != nil {
+		glog.Errorf("Unable to read console log of %s: %v", id.instance, err)
+		cle := &consoleLogError{err, payloads.ConsoleLogReadFailure}
+		cle.send(id.ac.conn, id.instance, cmd.requestID)
+		return
+	}
+
+	id.sendConsoleLogEvent(log, cmd.requestID)
+}
+
+func (id *instanceData) sendConsoleOpenedEvent(token, address string) {
+	var event payloads.EventConsoleOpened
+
+	event.Console.InstanceUUID = id.instance
+	event.Console.Token = token
+	event.Console.Address = address
+
+	payload, err := yaml.Marshal(&event)
+	if err != nil {
+		glog.Errorf("Unable to Marshall ConsoleOpened %v", err)
+		return
+	}
+	_, err = id.ac.conn.SendEvent(ssntp.ConsoleOpened, payload)
+	if err != nil {
+		glog.Errorf("Failed to send event command %v", err)
+		return
+	}
+}
+
+func (id *instanceData) openConsoleCommand(cmd *insOpenConsoleCmd) {
+	if id.monitorCh == nil || id.connectedCh != nil {
+		oce := &openConsoleError{nil, payloads.OpenConsoleNotRunning}
+		oce.send(id.ac.conn, id.instance, cmd.token)
+		return
+	}
+
+	console, err := id.vm.attachConsole()
+	if err != nil {
+		glog.Errorf("Unable to attach to console of %s: %v", id.instance, err)
+		oce := &openConsoleError{err, payloads.OpenConsoleAttachFailure}
+		oce.send(id.ac.conn, id.instance, cmd.token)
+		return
+	}
+
+	address, err := startConsoleProxy(id.instance, cmd.token, console, id.ac.consoleTLS)
+	if err != nil {
+		_ = console.Close()
+		glog.Errorf("Unable to open console session for %s: %v", id.instance, err)
+		oce := &openConsoleError{err, payloads.OpenConsoleAttachFailure}
+		oce.send(id.ac.conn, id.instance, cmd.token)
+		return
+	}
+
+	glog.Infof("Console session for %s waiting on %s", id.instance, address)
+	id.sendConsoleOpenedEvent(cmd.token, address)
+}
+
+func (id *instanceData) pauseInstance(pause bool) *pauseError {
+	if id.shuttingDown || id.monitorCh == nil || id.connectedCh != nil ||
+		id.paused == pause {
+		return &pauseError{nil, payloads.PauseInvalidState}
+	}
+
+	responseCh := make(chan error)
+	if pause {
+		id.monitorCh <- virtualizerPauseCmd{responseCh}
+	} else {
+		id.monitorCh <- virtualizerUnpauseCmd{responseCh}
+	}
+	if err := <-responseCh; err != nil {
+		return &pauseError{err, payloads.PauseActionFailure}
+	}
+
+	id.paused = pause
+	setInstancePaused(id.instanceDir, pause)
+	state := ovsRunning
+	if pause {
+		state = ovsPaused
+	}
+	id.ovsCh <- &ovsStateChange{id.instance, state}
+	return nil
+}
+
+func (id *instanceData) suspendInstance() *pauseError {
+	if id.cfg.Container {
+		return &pauseError{nil, payloads.PauseNotSupported}
+	}
+
+	if id.shuttingDown || id.monitorCh == nil || id.connectedCh != nil ||
+		id.paused {
+		return &pauseError{nil, payloads.PauseInvalidState}
+	}
+
+	responseCh := make(chan error)
+	stateFile := path.Join(id.instanceDir, suspendStateFile)
+	id.monitorCh <- virtualizerSuspendCmd{responseCh, stateFile}
+	if err := <-responseCh; err != nil {
+		return &pauseError{err, payloads.PauseActionFailure}
+	}
+
+	// The VM is going away but, unlike a VM that has stopped, we don't
+	// want the instance to be deleted.
+
+	<-id.monitorCloseCh
+	id.vm.lostVM()
+	id.monitorCloseCh = nil
+	close(id.monitorCh)
+	id.monitorCh = nil
+	id.statsTimer = nil
+	id.st = nil
+	id.suspended = true
+	setInstanceRunning(id.instanceDir, false)
+	id.ovsCh <- &ovsStateChange{id.instance, ovsSuspended}
+	id.sendStats()
+	return nil
+}
+
+func (id *instanceData) resumeInstance() *pauseError {
+	if id.shuttingDown || !id.suspended {
+		return &pauseError{nil, payloads.PauseInvalidState}
+	}
+
+	pauseErr := processResume(id.vm, id.cfg, id.instanceDir, id.ac.conn)
+	if pauseErr != nil {
+		return pauseErr
+	}
+
+	id.suspended = false
+	id.connectedCh = make(chan struct{})
+	id.monitorCloseCh = make(chan struct{})
+	id.monitorCh = id.vm.monitorVM(id.monitorCloseCh, id.connectedCh, &id.instanceWg, false)
+	id.ovsCh <- &ovsStateChange{id.instance, ovsPending}
+	return nil
+}
+
+func (id *instanceData) pauseCommand(cmd *insPauseCmd) {
+	var pauseErr *pauseError
+
+	switch cmd.command {
+	case ssntp.PauseInstance:
+		pauseErr = id.pauseInstance(true)
+	case ssntp.UnpauseInstance:
+		pauseErr = id.pauseInstance(false)
+	case ssntp.SuspendInstance:
+		pauseErr = id.suspendInstance()
+	case ssntp.ResumeInstance:
+		pauseErr = id.resumeInstance()
+	}
+
+	if pauseErr != nil {
+		glog.Errorf("%s failed for %s [%s]: %v", cmd.command, id.instance,
+			string(pauseErr.code), pauseErr.err)
+		pauseErr.send(id.ac.conn, cmd.command, id.instance)
+		return
+	}
+
+	glog.Infof("%s succeeded for %s", cmd.command, id.instance)
+	id.ovsCh <- &ovsStatusCmd{}
+}
+
+func (id *instanceData) updateConcentratorCommand(cmd *insUpdateConcentratorCmd) {
+	if cnNet == nil || id.shuttingDown {
+		return
+	}
+
+	moved, err := moveSubnet(id.ac.conn, id.cfg, cmd.update)
+	if moved {
+		id.vnicCfg = nil
+		if err := id.cfg.save(id.instanceDir); err != nil {
+			glog.Errorf("Unable to save state of instance %s: %v", id.instance, err)
+		}
+		id.sendStats()
+		glog.Infof("Instance %s moved to concentrator %s", id.instance,
+			cmd.update.concUUID)
+	}
+
+	if err != nil {
+		glog.Errorf("Unable to move instance %s to concentrator %s: %v",
+			id.instance, cmd.update.concUUID, err)
+	}
+}
+
 func (id *instanceData) logStartTrace() {
 	if id.st == nil {
 		return
@@ -284,6 +588,16 @@ func (id *instanceData) instanceCommand(cmd interface{}) bool {
 		id.monitorCommand(cmd)
 	case *insAttachVolumeCmd:
 		id.attachVolumeCommand(cmd)
+	case *insDetachVolumeCmd:
+		id.detachVolumeCommand(cmd)
+	case *insConsoleLogCmd:
+		id.consoleLogCommand(cmd)
+	case *insOpenConsoleCmd:
+		id.openConsoleCommand(cmd)
+	case *insPauseCmd:
+		id.pauseCommand(cmd)
+	case *insUpdateConcentratorCmd:
+		id.updateConcentratorCommand(cmd)
 	case *insDeleteCmd:
 		if id.deleteCommand(cmd) {
 			return false
@@ -318,12 +632,45 @@ func (id *instanceData) unmapVolumes() {
 	}
 }
 
+// sendStats sends the current statistics of the instance to the overseer.
+func (id *instanceData) sendStats() {
+	d, m, c := id.vm.stats()
+	id.ovsCh <- &ovsStatsUpdateCmd{
+		instance:      id.instance,
+		memoryUsageMB: m,
+		diskUsageMB:   d,
+		CPUUsage:      c,
+		volumes:       id.getVolumes(),
+		guest:         id.vm.guestInfo(),
+		blockIO:       id.vm.blockStats(),
+		networkIO:     id.vnicStats(),
+		sshIP:         id.cfg.ConcIP,
+	}
+}
+
+// vnicStats returns the traffic counters of the first tenant vnic of the
+// instance, or nil if the instance does not have one.
+func (id *instanceData) vnicStats() *payloads.VnicIOStat {
+	if cnNet == nil || id.cfg.NetworkNode {
+		return nil
+	}
+
+	if id.vnicCfg == nil {
+		vnicCfg, err := createCNVnicCfg(id.cfg)
+		if err != nil {
+			return nil
+		}
+		id.vnicCfg = vnicCfg
+	}
+
+	return vnicStats(id.vnicCfg)
+}
+
 func (id *instanceData) instanceLoop() {
 
 	id.vm.init(id.cfg, id.instanceDir)
 
-	d, m, c := id.vm.stats()
-	id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+	id.sendStats()
 
 DONE:
 	for {
@@ -331,8 +678,7 @@ DONE:
 		case <-id.doneCh:
 			break DONE
 		case <-id.statsTimer:
-			d, m, c := id.vm.stats()
-			id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+			id.sendStats()
 			id.statsTimer = time.After(time.Second * resourcePeriod)
 		case cmd := <-id.cmdCh:
 			if !id.instanceCommand(cmd) {
@@ -341,8 +687,7 @@ DONE:
 		case <-id.monitorCloseCh:
 			// Means we've lost VM for now
 			id.vm.lostVM()
-			d, m, c := id.vm.stats()
-			id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+			id.sendStats()
 
 			glog.Infof("Lost VM instance: %s", id.instance)
 			id.monitorCloseCh = nil
@@ -350,17 +695,33 @@ DONE:
 			close(id.monitorCh)
 			id.monitorCh = nil
 			id.statsTimer = nil
-			id.ovsCh <- &ovsStateChange{id.instance, ovsStopped}
 			id.st = nil
-			killMe(id.instance, false, true, id.doneCh, id.ac, &id.instanceWg)
-			id.shuttingDown = true
+
+			// A VM that was running when the node went down is kept
+			// so that the controller can decide to restart it when
+			// it receives our inventory.
+
+			lost := instanceLost(id.instanceDir)
+			setInstanceRunning(id.instanceDir, false)
+			if lost {
+				glog.Infof("Instance %s was lost when the node went down", id.instance)
+				id.ovsCh <- &ovsInstanceLostCmd{id.instance}
+			} else {
+				id.ovsCh <- &ovsStateChange{id.instance, ovsStopped}
+				killMe(id.instance, false, true, id.doneCh, id.ac, &id.instanceWg)
+				id.shuttingDown = true
+			}
 		case <-id.connectedCh:
 			id.logStartTrace()
 			id.connectedCh = nil
 			id.vm.connected()
-			id.ovsCh <- &ovsStateChange{id.instance, ovsRunning}
-			d, m, c := id.vm.stats()
-			id.ovsCh <- &ovsStatsUpdateCmd{id.instance, m, d, c, id.getVolumes()}
+			setInstanceRunning(id.instanceDir, true)
+			if id.paused {
+				id.ovsCh <- &ovsStateChange{id.instance, ovsPaused}
+			} else {
+				id.ovsCh <- &ovsStateChange{id.instance, ovsRunning}
+			}
+			id.sendStats()
 			id.statsTimer = time.After(time.Second * resourcePeriod)
 		}
 	}
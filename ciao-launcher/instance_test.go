@@ -19,7 +19,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path"
 	"sync"
@@ -59,9 +61,13 @@ type instanceTestState struct {
 	stf             payloads.ErrorStartFailure
 	df              payloads.ErrorDeleteFailure
 	avf             payloads.ErrorAttachVolumeFailure
+	dvf             payloads.ErrorDetachVolumeFailure
+	pf              payloads.ErrorPauseFailure
 	deMigration     bool
 	de              payloads.EventInstanceDeleted
 	se              payloads.EventInstanceStopped
+	stopMethod      payloads.StopMethod
+	vde             payloads.EventVolumeDetached
 	connect         bool
 	monitorCh       chan interface{}
 	errorCh         chan struct{}
@@ -91,13 +97,17 @@ func (v *instanceTestState) deleteImage() error {
 	return nil
 }
 
-func (v *instanceTestState) startVM(vnicName, ipAddress, cephID string) error {
+func (v *instanceTestState) startVM(vnicNames []string, ipAddress, cephID string) error {
 	if v.failStartVM {
 		return fmt.Errorf("Failed to start VM")
 	}
 	return nil
 }
 
+func (v *instanceTestState) resumeVM(vnicNames []string, ipAddress, cephID, stateFile string) error {
+	return v.startVM(vnicNames, ipAddress, cephID)
+}
+
 func (v *instanceTestState) monitorVM(closedCh chan struct{}, connectedCh chan struct{},
 	wg *sync.WaitGroup, boot bool) chan interface{} {
 
@@ -118,6 +128,14 @@ func (v *instanceTestState) stats() (disk, memory, cpu int) {
 	return v.statsArray[0], v.statsArray[1], v.statsArray[2]
 }
 
+func (v *instanceTestState) guestInfo() *payloads.GuestInfo {
+	return nil
+}
+
+func (v *instanceTestState) blockStats() *payloads.BlockIOStat {
+	return nil
+}
+
 func (v *instanceTestState) connected() {
 
 }
@@ -125,6 +143,15 @@ func (v *instanceTestState) connected() {
 func (v *instanceTestState) lostVM() {
 }
 
+func (v *instanceTestState) attachConsole() (io.ReadWriteCloser, error) {
+	console, vm := net.Pipe()
+	go func() {
+		_, _ = io.Copy(vm, vm)
+		_ = vm.Close()
+	}()
+	return console, nil
+}
+
 func (v *instanceTestState) SendError(error ssntp.Error, payload []byte) (int, error) {
 	switch error {
 	case ssntp.StartFailure:
@@ -142,6 +169,17 @@ func (v *instanceTestState) SendError(error ssntp.Error, payload []byte) (int, e
 		if err != nil {
 			v.t.Fatalf("Failed to unmarshall attach volume error %v", err)
 		}
+	case ssntp.DetachVolumeFailure:
+		err := yaml.Unmarshal(payload, &v.dvf)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall detach volume error %v", err)
+		}
+	case ssntp.PauseFailure, ssntp.UnpauseFailure, ssntp.SuspendFailure,
+		ssntp.ResumeFailure:
+		err := yaml.Unmarshal(payload, &v.pf)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall pause error %v", err)
+		}
 	}
 
 	if v.errorCh != nil {
@@ -165,6 +203,11 @@ func (v *instanceTestState) SendEvent(event ssntp.Event, payload []byte) (int, e
 		if err != nil {
 			v.t.Fatalf("Failed to unmarshall instanceStopped event %v", err)
 		}
+	case ssntp.VolumeDetached:
+		err := yaml.Unmarshal(payload, &v.vde)
+		if err != nil {
+			v.t.Fatalf("Failed to unmarshall volumeDetached event %v", err)
+		}
 	}
 
 	if v.eventCh != nil {
@@ -284,6 +327,10 @@ func (v *instanceTestState) checkDeleteEvent(t *testing.T, cmd *insDeleteCmd) {
 		t.Errorf("Event recevied for wrong instance.  Expected %s got %s",
 			v.instance, instance)
 	}
+	if cmd.stop && v.se.InstanceStopped.StopMethod != v.stopMethod {
+		t.Errorf("Wrong stop method.  Expected %s got %s", v.stopMethod,
+			v.se.InstanceStopped.StopMethod)
+	}
 }
 
 func (v *instanceTestState) handleInstanceShutdown(t *testing.T, ovsCh chan interface{},
@@ -315,10 +362,16 @@ func (v *instanceTestState) handleInstanceShutdown(t *testing.T, ovsCh chan inte
 				return true
 			}
 		case monCmd := <-v.monitorCh:
-			if _, stopCmd := monCmd.(virtualizerStopCmd); !stopCmd {
+			stopCmd, ok := monCmd.(virtualizerStopCmd)
+			if !ok {
 				t.Errorf("Invalid monitor command found %t, expected virtualizerStopCmd", monCmd)
 				return false
 			}
+			if stopCmd.timeout != gracefulTimeout {
+				t.Errorf("Expected timeout of %v, got %v", gracefulTimeout, stopCmd.timeout)
+			}
+			stopCmd.stopped(payloads.StopForced)
+			v.stopMethod = payloads.StopForced
 			close(v.monitorClosedCh)
 			v.monitorCh = nil
 		case <-time.After(time.Second):
@@ -427,6 +480,59 @@ DONE:
 	return v.expectStatsUpdate(t, ovsCh)
 }
 
+func (v *instanceTestState) pauseInstance(t *testing.T, ovsCh chan interface{},
+	cmdCh chan<- interface{}, command ssntp.Command, expected ovsRunningState) bool {
+
+	select {
+	case cmdCh <- &insPauseCmd{command}:
+	case <-time.After(time.Second):
+		t.Errorf("Timed out sending %s command", command)
+		return false
+	}
+
+	stateChanged := false
+	for {
+		select {
+		case monCmd := <-v.monitorCh:
+			switch monCmd := monCmd.(type) {
+			case virtualizerPauseCmd:
+				monCmd.responseCh <- nil
+			case virtualizerUnpauseCmd:
+				monCmd.responseCh <- nil
+			case virtualizerSuspendCmd:
+				monCmd.responseCh <- nil
+				close(v.monitorClosedCh)
+				v.monitorCh = nil
+			default:
+				t.Errorf("Unexpected monitor command %T", monCmd)
+				return false
+			}
+		case ovsCmd := <-ovsCh:
+			switch ovsCmd := ovsCmd.(type) {
+			case *ovsStateChange:
+				if ovsCmd.state != expected {
+					t.Errorf("ovs state %d expected.  Found state %d",
+						expected, ovsCmd.state)
+					return false
+				}
+				stateChanged = true
+			case *ovsStatusCmd:
+				if !stateChanged {
+					t.Errorf("State of instance not updated by %s", command)
+				}
+				return stateChanged
+			case *ovsStatsUpdateCmd:
+			default:
+				t.Error("Unexpected commands received on ovsCh")
+				return false
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Timed out waiting for %s to complete", command)
+			return false
+		}
+	}
+}
+
 func shutdownInstanceLoop(doneCh chan struct{}, ovsCh chan interface{}, wg *sync.WaitGroup,
 	t *testing.T) {
 	close(doneCh)
@@ -829,7 +935,7 @@ func TestAttachVolumeToInstance(t *testing.T) {
 	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
 
 	select {
-	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID}:
+	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID, false, false}:
 	case <-time.After(time.Second):
 		t.Error("Timed out sending attach volume command")
 	}
@@ -864,7 +970,7 @@ func TestAttachExistingVolumeToInstance(t *testing.T) {
 	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
 
 	select {
-	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID}:
+	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID, false, false}:
 	case <-time.After(time.Second):
 		t.Error("Timed out sending attach volume command")
 	}
@@ -881,7 +987,7 @@ func TestAttachExistingVolumeToInstance(t *testing.T) {
 	select {
 	case <-state.errorCh:
 		t.Error("Initial Volume attach failed")
-	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID}:
+	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID, false, false}:
 	case <-time.After(time.Second):
 		t.Error("Timed out sending attach volume command")
 	}
@@ -903,6 +1009,201 @@ func TestAttachExistingVolumeToInstance(t *testing.T) {
 	wg.Wait()
 }
 
+// Check that we can detach a volume from a running instance
+//
+// We start the instance loop, add a volume, detach the volume and then
+// delete the instance.
+//
+// The instanceLoop and then instance should start correctly.  The volume should
+// be correctly attached and then detached.  The stats command should verify this
+// and a VolumeDetached event should be sent.  The instance should be correctly
+// deleted.
+func TestDetachVolumeFromInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	select {
+	case cmdCh <- &insAttachVolumeCmd{testutil.VolumeUUID, false, false}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending attach volume command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerAttachCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for attach volume command result")
+	}
+
+	_ = state.expectStatsUpdateWithVolumes(t, ovsCh, []string{testutil.VolumeUUID})
+
+	state.eventCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insDetachVolumeCmd{testutil.VolumeUUID}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending detach volume command")
+	}
+
+	select {
+	case monCmd := <-state.monitorCh:
+		monCmd.(virtualizerDetachCmd).responseCh <- nil
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for detach volume command result")
+	}
+
+	_ = state.expectStatsUpdateWithVolumes(t, ovsCh, []string{})
+
+	select {
+	case <-state.eventCh:
+		if state.vde.VolumeDetached.VolumeUUID != testutil.VolumeUUID {
+			t.Errorf("Unexpected volume detached.  Expected %s got %s",
+				testutil.VolumeUUID, state.vde.VolumeDetached.VolumeUUID)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for VolumeDetached event")
+	}
+	state.eventCh = nil
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that detaching a volume that is not attached fails
+//
+// We start the instance loop, detach a volume that was never attached and then
+// delete the instance.
+//
+// The instanceLoop and then instance should start correctly.  The detach
+// should fail with a DetachVolumeNotAttached error.  The instance should be
+// correctly deleted.
+func TestDetachUnattachedVolume(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	state.errorCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insDetachVolumeCmd{testutil.VolumeUUID}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending detach volume command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.dvf.Reason != payloads.DetachVolumeNotAttached {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.DetachVolumeNotAttached, state.dvf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for detach to fail")
+	}
+	state.errorCh = nil
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that we can pause and unpause an instance
+//
+// We start the instance loop, pause the instance, unpause it and then
+// delete the instance.
+//
+// The instance should be started correctly.  The pause and unpause commands
+// should be passed to the virtualizer and the state of the instance should
+// change to paused and then back to running.  The instance should be
+// correctly deleted.
+func TestPauseInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	if !state.pauseInstance(t, ovsCh, cmdCh, ssntp.PauseInstance, ovsPaused) ||
+		!state.pauseInstance(t, ovsCh, cmdCh, ssntp.UnpauseInstance, ovsRunning) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that we can suspend and resume an instance
+//
+// We start the instance loop, suspend the instance, resume it and then
+// delete the instance.
+//
+// The instance should be started correctly.  The instance should be
+// reported as suspended once the virtualizer has shut it down.  It should
+// then be relaunched and monitored when it is resumed, at which point it
+// should be reported as running again.  The instance should be correctly
+// deleted.
+func TestSuspendInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	if !state.pauseInstance(t, ovsCh, cmdCh, ssntp.SuspendInstance, ovsSuspended) ||
+		!state.pauseInstance(t, ovsCh, cmdCh, ssntp.ResumeInstance, ovsPending) ||
+		!waitForStateChange(t, ovsRunning, ovsCh) ||
+		!state.expectStatsUpdate(t, ovsCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
+// Check that resuming a running instance fails
+//
+// We start the instance loop, resume the instance and then delete it.
+//
+// The instance should be started correctly.  The resume should fail with
+// a PauseInvalidState error.  The instance should be correctly deleted.
+func TestResumeRunningInstance(t *testing.T) {
+	var wg sync.WaitGroup
+	cfg := standardCfg
+	state, ovsCh, cmdCh, doneCh := startVMWithCFG(t, &wg, &cfg, true, false)
+
+	state.errorCh = make(chan struct{})
+
+	select {
+	case cmdCh <- &insPauseCmd{ssntp.ResumeInstance}:
+	case <-time.After(time.Second):
+		t.Error("Timed out sending resume command")
+	}
+
+	select {
+	case <-state.errorCh:
+		if state.pf.Reason != payloads.PauseInvalidState {
+			t.Errorf("Unexpected error.  Expected %s got %s",
+				payloads.PauseInvalidState, state.pf.Reason)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timed out waiting for resume to fail")
+	}
+	state.errorCh = nil
+
+	if !state.deleteInstance(t, ovsCh, cmdCh) {
+		cleanupShutdownFail(t, cfg.Instance, doneCh, ovsCh, &wg)
+	}
+
+	wg.Wait()
+}
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	var err error
@@ -0,0 +1,75 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// bootMarkerFile is created in the instance directory while the
+	// instance is running.  It holds the boot ID of the host so that
+	// launcher can tell whether a VM it finds missing on startup was
+	// lost because the node went down.
+	bootMarkerFile = "boot_id"
+)
+
+var hostBootID = func() []byte {
+	id, err := ioutil.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		glog.Warningf("Unable to read boot ID: %v", err)
+		return nil
+	}
+	return bytes.TrimSpace(id)
+}
+
+func setInstanceRunning(instanceDir string, running bool) {
+	markerPath := path.Join(instanceDir, bootMarkerFile)
+	if !running {
+		if err := os.Remove(markerPath); err != nil && !os.IsNotExist(err) {
+			glog.Warningf("Unable to remove %s: %v", markerPath, err)
+		}
+		return
+	}
+
+	id := hostBootID()
+	if id == nil {
+		return
+	}
+
+	if err := ioutil.WriteFile(markerPath, id, 0600); err != nil {
+		glog.Warningf("Unable to create %s: %v", markerPath, err)
+	}
+}
+
+// instanceLost returns true if the instance was running when the host was
+// last shut down, i.e., if its boot marker was written during an earlier
+// boot of the host.
+func instanceLost(instanceDir string) bool {
+	id, err := ioutil.ReadFile(path.Join(instanceDir, bootMarkerFile))
+	if err != nil {
+		return false
+	}
+
+	current := hostBootID()
+	return current != nil && !bytes.Equal(bytes.TrimSpace(id), current)
+}
@@ -0,0 +1,63 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Checks that instances are only considered lost if they were running
+// during an earlier boot of the host.
+//
+// The boot marker of an instance is checked before the instance is run,
+// while it is running, after the host has been rebooted and after the
+// marker has been cleared.
+//
+// The instance should only be reported as lost after the host reboot.
+func TestInstanceLost(t *testing.T) {
+	instanceDir, err := ioutil.TempDir("", "lost-instance-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory")
+	}
+	defer func() { _ = os.RemoveAll(instanceDir) }()
+
+	bootID := []byte("boot-1")
+	savedBootID := hostBootID
+	hostBootID = func() []byte { return bootID }
+	defer func() { hostBootID = savedBootID }()
+
+	if instanceLost(instanceDir) {
+		t.Errorf("Instance that never ran reported as lost")
+	}
+
+	setInstanceRunning(instanceDir, true)
+	if instanceLost(instanceDir) {
+		t.Errorf("Running instance reported as lost")
+	}
+
+	bootID = []byte("boot-2")
+	if !instanceLost(instanceDir) {
+		t.Errorf("Instance running before reboot not reported as lost")
+	}
+
+	setInstanceRunning(instanceDir, false)
+	if instanceLost(instanceDir) {
+		t.Errorf("Stopped instance reported as lost")
+	}
+}
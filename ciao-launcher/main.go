@@ -31,6 +31,7 @@ import (
 	"time"
 
 	"github.com/ciao-project/ciao/clogger/gloginterface"
+	"github.com/ciao-project/ciao/deviceinfo"
 	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/osprepare"
 	"github.com/ciao-project/ciao/payloads"
@@ -84,6 +85,11 @@ var diskLimit bool
 var memLimit bool
 var cephID string
 var simulate bool
+var ephemeralPoolSpec string
+var ephemeral ephemeralPool = &dirPool{path: ephemeralDir}
+var pinnableCPUsSpec string
+var pinnableCPUs []int
+var gracefulTimeout time.Duration
 var maxInstances = int(math.MaxInt32)
 
 func init() {
@@ -93,12 +99,19 @@ func init() {
 	flag.BoolVar(&hardReset, "hard-reset", false, "Kill and delete all instances, reset networking and exit")
 	flag.BoolVar(&simulate, "simulation", false, "Launcher simulation")
 	flag.StringVar(&cephID, "ceph_id", "", "ceph client id")
+	flag.StringVar(&ephemeralPoolSpec, "ephemeral_pool", "dir:"+ephemeralDir,
+		"Storage for local disks, dir:<path> or lvm:<vg>/<thin pool>")
+	flag.StringVar(&pinnableCPUsSpec, "pinnable_cpus", "",
+		"Host CPUs that can be dedicated to instances, e.g., 2-7,10")
+	flag.DurationVar(&gracefulTimeout, "graceful_timeout", 30*time.Second,
+		"Time given to instances to shut down cleanly if STOP or DELETE does not specify one")
 }
 
 const (
 	lockDir         = "/tmp/lock/ciao"
 	ciaoDir         = "/var/lib/ciao"
 	instancesDir    = ciaoDir + "/instances"
+	ephemeralDir    = ciaoDir + "/ephemeral"
 	dataDir         = ciaoDir + "/data/launcher/"
 	logDir          = ciaoDir + "/logs/launcher"
 	maintenanceFile = dataDir + "/maintenance"
@@ -171,7 +184,7 @@ func processCommand(conn serverConn, cmd *cmdWrapper, ovsCh chan<- interface{})
 		return
 	}
 
-	switch cmd.cmd.(type) {
+	switch cmd := cmd.cmd.(type) {
 	case *statusCmd:
 		ovsCh <- &ovsStatsStatusCmd{}
 		return
@@ -200,6 +213,12 @@ func processCommand(conn serverConn, cmd *cmdWrapper, ovsCh chan<- interface{})
 		ovsCh <- &ovsRestoreCmd{doneCh}
 		<-doneCh
 		glog.Info("Node restored")
+	case *updateConcentratorCmd:
+		for _, i := range getAllInstances(ovsCh) {
+			i.cmdCh <- &insUpdateConcentratorCmd{cmd}
+		}
+		glog.Infof("Subnet %s of tenant %s moved to concentrator %s",
+			cmd.subnet, cmd.tenant, cmd.concUUID)
 	}
 }
 
@@ -237,6 +256,38 @@ func processInstanceCommand(conn serverConn, cmd *cmdWrapper, ovsCh chan<- inter
 		}
 		delCmd = insCmd
 		delCmd.running = insState.running
+	case *insDetachVolumeCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			dve := detachVolumeError{nil, payloads.DetachVolumeNoInstance}
+			dve.send(conn, cmd.instance, insCmd.volumeUUID)
+			return
+		}
+	case *insConsoleLogCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			cle := consoleLogError{nil, payloads.ConsoleLogNoInstance}
+			cle.send(conn, cmd.instance, insCmd.requestID)
+			return
+		}
+	case *insOpenConsoleCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			oce := openConsoleError{nil, payloads.OpenConsoleNoInstance}
+			oce.send(conn, cmd.instance, insCmd.token)
+			return
+		}
+	case *insPauseCmd:
+		target = insCmdChannel(cmd.instance, ovsCh)
+		if target == nil {
+			glog.Errorf("Instance %s does not exist", cmd.instance)
+			pe := pauseError{nil, payloads.PauseNoInstance}
+			pe.send(conn, insCmd.command, cmd.instance)
+			return
+		}
 	default:
 		target = insCmdChannel(cmd.instance, ovsCh)
 	}
@@ -348,6 +399,13 @@ func connectToServer(doneCh chan struct{}, statusCh chan struct{}) {
 			return
 		}
 
+		client.consoleTLS, err = ssntp.PeerTLSConfig(cfg, true, ssntp.Controller)
+		if err != nil {
+			glog.Errorf("Unable to load certificates for console sessions: %v", err)
+			client.conn.Close()
+			return
+		}
+
 		err = loadClusterConfig(client.conn)
 		if err != nil {
 			glog.Errorf("Unable to get Cluster Configuration %v", err)
@@ -537,6 +595,17 @@ func main() {
 
 	glog.Info("Starting Launcher")
 
+	pool, err := newEphemeralPool(ephemeralPoolSpec)
+	if err != nil {
+		glog.Fatalf("Unable to initialise ephemeral pool: %v", err)
+	}
+	ephemeral = pool
+
+	pinnableCPUs, err = deviceinfo.ParseCPUList(pinnableCPUsSpec)
+	if err != nil {
+		glog.Fatalf("Invalid pinnable_cpus: %v", err)
+	}
+
 	exitCode := 0
 	var stopProfile func()
 	if profileFN != nil {
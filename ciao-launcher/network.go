@@ -193,27 +193,67 @@ func initNetworking(ctx context.Context) chan error {
 }
 
 func createCNVnicCfg(cfg *vmConfig) (*libsnnet.VnicConfig, error) {
+	return createNICVnicCfg(cfg, cfg.nics()[0])
+}
+
+func createNICVnicCfg(cfg *vmConfig, nic nicConfig) (*libsnnet.VnicConfig, error) {
 
 	glog.Info("Creating CN Vnic CFG")
 
-	mac, err := net.ParseMAC(cfg.VnicMAC)
+	mac, err := net.ParseMAC(nic.VnicMAC)
 	if err != nil {
 		return nil, fmt.Errorf("Invalid mac address %v", err)
 	}
 
-	_, vnet, err := net.ParseCIDR(cfg.SubnetIP)
+	_, vnet, err := net.ParseCIDR(nic.SubnetIP)
 	if err != nil {
 		return nil, fmt.Errorf("Invalid vnic subnet %v", err)
 	}
 
-	concIP := net.ParseIP(cfg.ConcIP)
+	concIP := net.ParseIP(nic.ConcIP)
 	if concIP == nil {
-		return nil, fmt.Errorf("Invalid concentrator ip %s", cfg.ConcIP)
+		return nil, fmt.Errorf("Invalid concentrator ip %s", nic.ConcIP)
 	}
 
-	vnicIP := net.ParseIP(cfg.VnicIP)
+	vnicIP := net.ParseIP(nic.VnicIP)
 	if vnicIP == nil {
-		return nil, fmt.Errorf("Invalid vnicIP ip %s", cfg.VnicIP)
+		return nil, fmt.Errorf("Invalid vnicIP ip %s", nic.VnicIP)
+	}
+
+	var dhcp libsnnet.DhcpConfig
+	dhcpParams := []struct {
+		value string
+		ip    *net.IP
+	}{
+		{nic.Gateway, &dhcp.Gateway},
+		{nic.DHCPStart, &dhcp.Start},
+		{nic.DHCPEnd, &dhcp.End},
+	}
+	for _, p := range dhcpParams {
+		if p.value == "" {
+			continue
+		}
+		*p.ip = net.ParseIP(p.value)
+		if *p.ip == nil || !vnet.Contains(*p.ip) {
+			return nil, fmt.Errorf("Invalid vnic subnet address %s", p.value)
+		}
+	}
+
+	if nic.IPv6Subnet != "" {
+		if _, dhcp.IPv6Prefix, err = net.ParseCIDR(nic.IPv6Subnet); err != nil {
+			return nil, fmt.Errorf("Invalid vnic IPv6 subnet %v", err)
+		}
+
+		if nic.IPv6Gateway != "" {
+			dhcp.IPv6Gateway = net.ParseIP(nic.IPv6Gateway)
+			if dhcp.IPv6Gateway == nil || !dhcp.IPv6Prefix.Contains(dhcp.IPv6Gateway) {
+				return nil, fmt.Errorf("Invalid vnic IPv6 gateway %s", nic.IPv6Gateway)
+			}
+		}
+
+		if dhcp.IPv6Mode, err = libsnnet.ParseIPv6Mode(nic.IPv6Mode); err != nil {
+			return nil, err
+		}
 	}
 
 	subnetKey := binary.LittleEndian.Uint32(vnet.IP)
@@ -230,12 +270,15 @@ func createCNVnicCfg(cfg *vmConfig) (*libsnnet.VnicConfig, error) {
 		ConcIP:     concIP,
 		VnicMAC:    mac,
 		Subnet:     *vnet,
+		Dhcp:       dhcp,
 		SubnetKey:  int(subnetKey),
-		VnicID:     cfg.VnicUUID,
+		VnicID:     nic.VnicUUID,
 		InstanceID: cfg.Instance,
 		TenantID:   cfg.TenantUUID,
-		SubnetID:   cfg.SubnetIP,
-		ConcID:     cfg.ConcUUID}, nil
+		SubnetID:   nic.SubnetIP,
+		ConcID:     nic.ConcUUID,
+		RxMbit:     cfg.NetRxMbps,
+		TxMbit:     cfg.NetTxMbps}, nil
 }
 
 func createCNCIVnicCfg(cfg *vmConfig) (*libsnnet.VnicConfig, error) {
@@ -255,12 +298,48 @@ func createCNCIVnicCfg(cfg *vmConfig) (*libsnnet.VnicConfig, error) {
 		TenantID:   cfg.TenantUUID}, nil
 }
 
-func createVnicCfg(cfg *vmConfig) (*libsnnet.VnicConfig, error) {
+// createVnicCfgs returns the configurations of the vnics of all the NICs
+// of an instance, starting with the first one.
+func createVnicCfgs(cfg *vmConfig) ([]*libsnnet.VnicConfig, error) {
 	if cfg.NetworkNode {
-		return createCNCIVnicCfg(cfg)
+		vnicCfg, err := createCNCIVnicCfg(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []*libsnnet.VnicConfig{vnicCfg}, nil
+	}
+
+	nics := cfg.nics()
+	vnicCfgs := make([]*libsnnet.VnicConfig, 0, len(nics))
+	for _, nic := range nics {
+		vnicCfg, err := createNICVnicCfg(cfg, nic)
+		if err != nil {
+			return nil, err
+		}
+		vnicCfgs = append(vnicCfgs, vnicCfg)
+	}
+
+	return vnicCfgs, nil
+}
+
+func vnicStats(vnicCfg *libsnnet.VnicConfig) *payloads.VnicIOStat {
+	stats, err := cnNet.VnicStats(vnicCfg)
+	if err != nil {
+		if glog.V(1) {
+			glog.Infof("Unable to retrieve stats of vnic %s: %v",
+				vnicCfg.VnicID, err)
+		}
+		return nil
 	}
 
-	return createCNVnicCfg(cfg)
+	return &payloads.VnicIOStat{
+		RxBytes:   int64(stats.RxBytes),
+		RxPackets: int64(stats.RxPackets),
+		RxDropped: int64(stats.RxDropped),
+		TxBytes:   int64(stats.TxBytes),
+		TxPackets: int64(stats.TxPackets),
+		TxDropped: int64(stats.TxDropped),
+	}
 }
 
 func sendNetworkEvent(conn serverConn, eventType ssntp.Event,
@@ -325,6 +404,47 @@ func createVnic(conn serverConn, vnicCfg *libsnnet.VnicConfig) (string, string,
 	return name, bridge, gatewayIP, nil
 }
 
+// createVnics creates the vnics of all the NICs of an instance and returns
+// their names.  The bridge and the gateway returned are those of the first
+// vnic.  If any of the vnics cannot be created, those already created are
+// destroyed.
+func createVnics(conn serverConn, vnicCfgs []*libsnnet.VnicConfig) ([]string, string, string, error) {
+	var bridge string
+	var gatewayIP string
+
+	names := make([]string, 0, len(vnicCfgs))
+	for i, vnicCfg := range vnicCfgs {
+		name, vnicBridge, vnicGatewayIP, err := createVnic(conn, vnicCfg)
+		if err != nil {
+			_ = destroyVnics(conn, vnicCfgs[:i])
+			return nil, "", "", err
+		}
+
+		if i == 0 {
+			bridge = vnicBridge
+			gatewayIP = vnicGatewayIP
+		}
+		names = append(names, name)
+	}
+
+	return names, bridge, gatewayIP, nil
+}
+
+// destroyVnics destroys the vnics of all the NICs of an instance.  It
+// carries on if a vnic cannot be destroyed and returns the first error
+// encountered.
+func destroyVnics(conn serverConn, vnicCfgs []*libsnnet.VnicConfig) error {
+	var err error
+
+	for _, vnicCfg := range vnicCfgs {
+		if vnicErr := destroyVnic(conn, vnicCfg); vnicErr != nil && err == nil {
+			err = vnicErr
+		}
+	}
+
+	return err
+}
+
 func destroyVnic(conn serverConn, vnicCfg *libsnnet.VnicConfig) error {
 	if vnicCfg.VnicRole != libsnnet.DataCenter {
 		event, info, err := cnNet.DestroyVnic(vnicCfg)
@@ -365,3 +485,41 @@ func getNodeIPAddress() string {
 
 	return nicInfo[0].NodeIP
 }
+
+// moveSubnet re-points the tunnels of the vnics of the NICs of an instance
+// attached to a tenant subnet to the CNCI that now serves the subnet.  The
+// tunnel is shared by all the instances attached to the subnet, so only the
+// first instance to be moved generates a TenantAdded event.  It returns true
+// if the configuration of the instance has been updated.
+func moveSubnet(conn serverConn, cfg *vmConfig, cmd *updateConcentratorCmd) (bool, error) {
+	if cfg.NetworkNode || cfg.TenantUUID != cmd.tenant {
+		return false, nil
+	}
+
+	concIP := net.ParseIP(cmd.concIP)
+	moved := false
+	for i, nic := range cfg.nics() {
+		if nic.SubnetIP != cmd.subnet || nic.ConcUUID == cmd.concUUID {
+			continue
+		}
+
+		vnicCfg, err := createNICVnicCfg(cfg, nic)
+		if err != nil {
+			return moved, err
+		}
+
+		event, err := cnNet.MoveSubnet(vnicCfg, cmd.concUUID, concIP)
+		if err != nil {
+			glog.Errorf("cn.MoveSubnet failed %v", err)
+			return moved, err
+		}
+
+		sendNetworkEvent(conn, ssntp.TenantAdded, event)
+		cfg.setConcentrator(i, cmd.concUUID, cmd.concIP)
+		moved = true
+
+		glog.Infoln("CN VNIC moved =", vnicCfg.VnicIP, event)
+	}
+
+	return moved, nil
+}
@@ -77,12 +77,20 @@ type ovsStateChange struct {
 	state    ovsRunningState
 }
 
+type ovsInstanceLostCmd struct {
+	instance string
+}
+
 type ovsStatsUpdateCmd struct {
 	instance      string
 	memoryUsageMB int
 	diskUsageMB   int
 	CPUUsage      int
 	volumes       []string
+	guest         *payloads.GuestInfo
+	blockIO       *payloads.BlockIOStat
+	networkIO     *payloads.VnicIOStat
+	sshIP         string
 }
 
 type ovsMaintenanceCmd struct {
@@ -107,6 +115,8 @@ type deviceInfo interface {
 	GetFSInfo(path string) (total, available int)
 	GetOnlineCPUs() int
 	GetMemoryInfo() (total, available int)
+	GetNUMATopology() ([]deviceinfo.NUMANode, error)
+	GetHugePageSizeKB() int
 }
 
 type realDeviceInfo struct{}
@@ -127,12 +137,36 @@ func (realDeviceInfo) GetMemoryInfo() (total, available int) {
 	return deviceinfo.GetMemoryInfo()
 }
 
+func (realDeviceInfo) GetNUMATopology() ([]deviceinfo.NUMANode, error) {
+	return deviceinfo.GetNUMATopology()
+}
+
+func (realDeviceInfo) GetHugePageSizeKB() int {
+	return deviceinfo.GetHugePageSizeKB()
+}
+
 const (
 	ovsPending ovsRunningState = iota
 	ovsRunning
 	ovsStopped
+	ovsPaused
+	ovsSuspended
 )
 
+func (s ovsRunningState) String() string {
+	switch s {
+	case ovsRunning:
+		return payloads.Running
+	case ovsStopped:
+		return payloads.Exited
+	case ovsPaused:
+		return payloads.Paused
+	case ovsSuspended:
+		return payloads.Suspended
+	}
+	return payloads.Pending
+}
+
 const (
 	diskSpaceHWM = 80 * 1000
 	memHWM       = 1 * 1000
@@ -151,7 +185,12 @@ type ovsInstanceState struct {
 	maxMemoryMB    int
 	sshIP          string
 	sshPort        int
+	tenant         string
 	volumes        []string
+	guest          *payloads.GuestInfo
+	blockIO        *payloads.BlockIOStat
+	networkIO      *payloads.VnicIOStat
+	lost           bool
 }
 
 type overseer struct {
@@ -167,11 +206,14 @@ type overseer struct {
 	diskSpaceAllocated int
 	memoryAllocated    int
 	diskSpaceAvailable int
+	diskSpaceUsed      int
 	memoryAvailable    int
 	traceFrames        *list.List
 	statsInterval      time.Duration
 	di                 deviceInfo
+	dedicated          *dedicatedResources
 	maintenance        bool
+	inventoryPending   bool
 }
 
 type cnStats struct {
@@ -183,7 +225,7 @@ type cnStats struct {
 	cpusOnline      int
 }
 
-func (ovs *overseer) roomAvailable(cfg *vmConfig) payloads.StartFailureReason {
+func (ovs *overseer) roomAvailable(instance string, cfg *vmConfig) payloads.StartFailureReason {
 	if ovs.maintenance {
 		return payloads.NodeInMaintenance
 	}
@@ -198,6 +240,13 @@ func (ovs *overseer) roomAvailable(cfg *vmConfig) payloads.StartFailureReason {
 
 	glog.Infof("disk Avail %d MemAvail %d", diskSpaceAvailable, memoryAvailable)
 
+	// Local disks are always carved out of the ephemeral pool so we
+	// never allow their reservations to exceed its capacity.
+	if len(cfg.EphemeralDisks) > 0 && diskSpaceAvailable < 0 {
+		glog.Warningf("Not enough space in ephemeral pool for %d MB", cfg.Disk)
+		return payloads.FullComputeNode
+	}
+
 	if diskSpaceAvailable < diskSpaceLWM {
 		if diskLimit == true {
 			return payloads.FullComputeNode
@@ -210,6 +259,12 @@ func (ovs *overseer) roomAvailable(cfg *vmConfig) payloads.StartFailureReason {
 		}
 	}
 
+	// Dedicated resources are reserved last so that there is nothing to
+	// undo if one of the checks above fails.
+	if !ovs.dedicated.allocate(instance, cfg) {
+		return payloads.FullComputeNode
+	}
+
 	return ""
 }
 
@@ -230,6 +285,7 @@ func (ovs *overseer) updateAvailableResources(cns *cnStats) {
 		}
 	}
 
+	ovs.diskSpaceUsed = diskSpaceConsumed
 	ovs.diskSpaceAvailable = (cns.availableDiskMB + diskSpaceConsumed) -
 		ovs.diskSpaceAllocated
 
@@ -275,7 +331,10 @@ func (ovs *overseer) sendReadyStatusCommand(cns *cnStats) {
 	s.MemTotalMB, s.MemAvailableMB = cns.totalMemMB, cns.availableMemMB
 	s.Load = cns.load
 	s.CpusOnline = cns.cpusOnline
-	s.DiskTotalMB, s.DiskAvailableMB = cns.totalDiskMB, cns.availableDiskMB
+	s.DedicatedCpusAvailable, s.HugePagesTotalMB, s.HugePagesAvailableMB,
+		s.NUMANodes = ovs.dedicated.stats()
+	s.DiskTotalMB, s.DiskAvailableMB = cns.totalDiskMB, ovs.diskSpaceAvailable
+	s.DiskReservedMB, s.DiskUsedMB = ovs.diskSpaceAllocated, ovs.diskSpaceUsed
 	s.Networks = make([]payloads.NetworkStat, len(nicInfo))
 	for i, nic := range nicInfo {
 		s.Networks[i] = *nic
@@ -321,7 +380,10 @@ func (ovs *overseer) sendStats(cns *cnStats, status ssntp.Status) {
 	s.MemTotalMB, s.MemAvailableMB = cns.totalMemMB, cns.availableMemMB
 	s.Load = cns.load
 	s.CpusOnline = cns.cpusOnline
-	s.DiskTotalMB, s.DiskAvailableMB = cns.totalDiskMB, cns.availableDiskMB
+	s.DedicatedCpusAvailable, s.HugePagesTotalMB, s.HugePagesAvailableMB,
+		s.NUMANodes = ovs.dedicated.stats()
+	s.DiskTotalMB, s.DiskAvailableMB = cns.totalDiskMB, ovs.diskSpaceAvailable
+	s.DiskReservedMB, s.DiskUsedMB = ovs.diskSpaceAllocated, ovs.diskSpaceUsed
 	s.NodeHostName = hostname // global from network.go
 	s.Networks = make([]payloads.NetworkStat, len(nicInfo))
 	for i, nic := range nicInfo {
@@ -331,19 +393,16 @@ func (ovs *overseer) sendStats(cns *cnStats, status ssntp.Status) {
 	i := 0
 	for uuid, state := range ovs.instances {
 		s.Instances[i].InstanceUUID = uuid
-		if state.running == ovsRunning {
-			s.Instances[i].State = payloads.Running
-		} else if state.running == ovsStopped {
-			s.Instances[i].State = payloads.Exited
-		} else {
-			s.Instances[i].State = payloads.Pending
-		}
+		s.Instances[i].State = state.running.String()
 		s.Instances[i].MemoryUsageMB = state.memoryUsageMB
 		s.Instances[i].DiskUsageMB = state.diskUsageMB
 		s.Instances[i].CPUUsage = state.CPUUsage
 		s.Instances[i].SSHIP = state.sshIP
 		s.Instances[i].SSHPort = state.sshPort
 		s.Instances[i].Volumes = state.volumes
+		s.Instances[i].Guest = state.guest
+		s.Instances[i].BlockIO = state.blockIO
+		s.Instances[i].NetworkIO = state.networkIO
 		i++
 	}
 
@@ -360,6 +419,52 @@ func (ovs *overseer) sendStats(cns *cnStats, status ssntp.Status) {
 	}
 }
 
+// sendInventoryIfSettled sends the node's inventory once the state of all
+// instances is known, i.e., once the instances restored at startup have
+// been monitored.  An inventory that cannot be sent immediately is sent
+// when the last pending instance changes state or, at the latest, when
+// the stats timer next fires.
+func (ovs *overseer) sendInventoryIfSettled() {
+	for _, state := range ovs.instances {
+		if state.running == ovsPending {
+			return
+		}
+	}
+	ovs.sendInventory()
+}
+
+func (ovs *overseer) sendInventory() {
+	var e payloads.EventNodeInventory
+
+	ovs.inventoryPending = false
+
+	e.Inventory.NodeUUID = ovs.ac.conn.UUID()
+	e.Inventory.Instances = make([]payloads.InstanceInventory, 0, len(ovs.instances))
+	for uuid, state := range ovs.instances {
+		e.Inventory.Instances = append(e.Inventory.Instances,
+			payloads.InstanceInventory{
+				InstanceUUID: uuid,
+				TenantUUID:   state.tenant,
+				State:        state.running.String(),
+				Lost:         state.lost,
+			})
+	}
+
+	payload, err := yaml.Marshal(&e)
+	if err != nil {
+		glog.Errorf("Unable to Marshall NodeInventory %v", err)
+		return
+	}
+
+	_, err = ovs.ac.conn.SendEvent(ssntp.NodeInventory, payload)
+	if err != nil {
+		glog.Errorf("Failed to send NodeInventory event %v", err)
+		return
+	}
+
+	glog.Infof("Sent inventory of %d instances", len(e.Inventory.Instances))
+}
+
 func (ovs *overseer) sendTraceReport() {
 	var s payloads.Trace
 
@@ -393,13 +498,13 @@ func (ovs *overseer) sendTraceReport() {
 	}
 }
 
-func getStats(instancesDir string) *cnStats {
+func getStats() *cnStats {
 	var s cnStats
 
 	s.totalMemMB, s.availableMemMB = deviceinfo.GetMemoryInfo()
 	s.load = deviceinfo.GetLoadAvg()
 	s.cpusOnline = deviceinfo.GetOnlineCPUs()
-	s.totalDiskMB, s.availableDiskMB = deviceinfo.GetFSInfo(instancesDir)
+	s.totalDiskMB, s.availableDiskMB = ephemeral.usage()
 
 	return &s
 }
@@ -440,7 +545,7 @@ func (ovs *overseer) processAddCommand(cmd *ovsAddCmd) {
 	cfg := cmd.cfg
 	if target != nil {
 		targetCh = target.cmdCh
-	} else if errCode = ovs.roomAvailable(cfg); errCode == "" {
+	} else if errCode = ovs.roomAvailable(cmd.instance, cfg); errCode == "" {
 		ovs.vcpusAllocated += cfg.Cpus
 		ovs.diskSpaceAllocated += cfg.Disk
 		ovs.memoryAllocated += cfg.Mem
@@ -457,6 +562,7 @@ func (ovs *overseer) processAddCommand(cmd *ovsAddCmd) {
 			maxMemoryMB:    cfg.Mem,
 			sshIP:          cfg.ConcIP,
 			sshPort:        cfg.SSHPort,
+			tenant:         cfg.TenantUUID,
 		}
 	}
 	cmd.targetCh <- ovsAddResult{targetCh, errCode}
@@ -485,6 +591,8 @@ func (ovs *overseer) processRemoveCommand(cmd *ovsRemoveCmd) {
 		ovs.memoryAllocated = 0
 	}
 
+	ovs.dedicated.release(cmd.instance)
+
 	delete(ovs.instances, cmd.instance)
 	cmd.errCh <- nil
 }
@@ -494,7 +602,7 @@ func (ovs *overseer) processStatusCommand(cmd *ovsStatusCmd) {
 	if !ovs.ac.conn.isConnected() {
 		return
 	}
-	cns := getStats(ovs.instancesDir)
+	cns := getStats()
 	ovs.updateAvailableResources(cns)
 	ovs.sendStatusCommand(cns, ovs.computeStatus())
 }
@@ -504,10 +612,12 @@ func (ovs *overseer) processStatsStatusCommand(cmd *ovsStatsStatusCmd) {
 	if !ovs.ac.conn.isConnected() {
 		return
 	}
-	cns := getStats(ovs.instancesDir)
+	cns := getStats()
 	ovs.updateAvailableResources(cns)
 	status := ovs.computeStatus()
 	ovs.sendStatusCommand(cns, status)
+	ovs.inventoryPending = true
+	ovs.sendInventoryIfSettled()
 	ovs.sendStats(cns, status)
 }
 
@@ -517,6 +627,21 @@ func (ovs *overseer) processStateChangeCommand(cmd *ovsStateChange) {
 	if target != nil {
 		target.running = cmd.state
 	}
+	if ovs.inventoryPending && ovs.ac.conn.isConnected() {
+		ovs.sendInventoryIfSettled()
+	}
+}
+
+func (ovs *overseer) processInstanceLostCommand(cmd *ovsInstanceLostCmd) {
+	glog.Infof("Overseer: Instance %s lost", cmd.instance)
+	target := ovs.instances[cmd.instance]
+	if target != nil {
+		target.running = ovsStopped
+		target.lost = true
+	}
+	if ovs.inventoryPending && ovs.ac.conn.isConnected() {
+		ovs.sendInventoryIfSettled()
+	}
 }
 
 func (ovs *overseer) processStatusUpdateCommand(cmd *ovsStatsUpdateCmd) {
@@ -531,6 +656,10 @@ func (ovs *overseer) processStatusUpdateCommand(cmd *ovsStatsUpdateCmd) {
 		target.diskUsageMB = cmd.diskUsageMB
 		target.CPUUsage = cmd.CPUUsage
 		target.volumes = cmd.volumes
+		target.guest = cmd.guest
+		target.blockIO = cmd.blockIO
+		target.networkIO = cmd.networkIO
+		target.sshIP = cmd.sshIP
 	}
 }
 
@@ -587,6 +716,8 @@ func (ovs *overseer) processCommand(cmd interface{}) {
 		ovs.processStatsStatusCommand(cmd)
 	case *ovsStateChange:
 		ovs.processStateChangeCommand(cmd)
+	case *ovsInstanceLostCmd:
+		ovs.processInstanceLostCommand(cmd)
 	case *ovsStatsUpdateCmd:
 		ovs.processStatusUpdateCommand(cmd)
 	case *ovsTraceFrame:
@@ -619,10 +750,13 @@ DONE:
 				continue
 			}
 
-			cns := getStats(ovs.instancesDir)
+			cns := getStats()
 			ovs.updateAvailableResources(cns)
 			status := ovs.computeStatus()
 			ovs.sendStatusCommand(cns, status)
+			if ovs.inventoryPending {
+				ovs.sendInventory()
+			}
 			ovs.sendStats(cns, status)
 			ovs.sendTraceReport()
 			statsTimer = time.After(ovs.statsInterval)
@@ -679,6 +813,13 @@ func startOverseerFull(instancesDir string, wg *sync.WaitGroup, ac *agentClient,
 	diskSpaceAllocated := 0
 	memoryAllocated := 0
 
+	topology, err := di.GetNUMATopology()
+	if err != nil {
+		glog.Warningf("Unable to determine NUMA topology: %v", err)
+	}
+	dedicated := newDedicatedResources(topology, di.GetHugePageSizeKB(),
+		pinnableCPUs)
+
 	_ = filepath.Walk(instancesDir, func(path string, info os.FileInfo, err error) error {
 		if path == instancesDir {
 			return nil
@@ -702,6 +843,7 @@ func startOverseerFull(instancesDir string, wg *sync.WaitGroup, ac *agentClient,
 		vcpusAllocated += cfg.Cpus
 		diskSpaceAllocated += cfg.Disk
 		memoryAllocated += cfg.Mem
+		dedicated.restore(instance, cfg)
 
 		target := startInstance(instance, cfg, childWg, childDoneCh, ac, ovsInstanceCh)
 		instances[instance] = &ovsInstanceState{
@@ -715,13 +857,14 @@ func startOverseerFull(instancesDir string, wg *sync.WaitGroup, ac *agentClient,
 			maxMemoryMB:    cfg.Mem,
 			sshIP:          cfg.ConcIP,
 			sshPort:        cfg.SSHPort,
+			tenant:         cfg.TenantUUID,
 		}
 		toMonitor = append(toMonitor, target)
 
 		return filepath.SkipDir
 	})
 
-	_, err := os.Stat(maintenanceFile)
+	_, err = os.Stat(maintenanceFile)
 	maintenance := err == nil
 
 	if maintenance {
@@ -743,6 +886,7 @@ func startOverseerFull(instancesDir string, wg *sync.WaitGroup, ac *agentClient,
 		traceFrames:        list.New(),
 		statsInterval:      statsInterval,
 		di:                 di,
+		dedicated:          dedicated,
 		maintenance:        maintenance,
 	}
 	ovs.parentWg.Add(1)
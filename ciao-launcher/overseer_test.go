@@ -27,6 +27,7 @@ import (
 
 	"gopkg.in/yaml.v2"
 
+	"github.com/ciao-project/ciao/deviceinfo"
 	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/ssntp"
 )
@@ -54,11 +55,23 @@ func (fakeDeviceInfo) GetMemoryInfo() (total, available int) {
 	return 16000, 8000
 }
 
+func (fakeDeviceInfo) GetNUMATopology() ([]deviceinfo.NUMANode, error) {
+	return []deviceinfo.NUMANode{
+		{ID: 0, CPUs: []int{0, 1}, HugePagesTotal: 512, HugePagesFree: 512},
+		{ID: 1, CPUs: []int{2, 3}, HugePagesTotal: 512, HugePagesFree: 512},
+	}, nil
+}
+
+func (fakeDeviceInfo) GetHugePageSizeKB() int {
+	return 2048
+}
+
 type overseerTestState struct {
-	t        *testing.T
-	ac       *agentClient
-	statusCh chan *fakeStatus
-	statsCh  chan *payloads.Stat
+	t           *testing.T
+	ac          *agentClient
+	statusCh    chan *fakeStatus
+	statsCh     chan *payloads.Stat
+	inventoryCh chan *payloads.EventNodeInventory
 }
 
 func (v *overseerTestState) SendError(error ssntp.Error, payload []byte) (int, error) {
@@ -66,6 +79,17 @@ func (v *overseerTestState) SendError(error ssntp.Error, payload []byte) (int, e
 }
 
 func (v *overseerTestState) SendEvent(event ssntp.Event, payload []byte) (int, error) {
+	if event != ssntp.NodeInventory || v.inventoryCh == nil {
+		return 0, nil
+	}
+
+	inventory := &payloads.EventNodeInventory{}
+	err := yaml.Unmarshal(payload, inventory)
+	if err != nil {
+		v.t.Errorf("Failed to unmarshall NodeInventory %v", err)
+	}
+	v.inventoryCh <- inventory
+
 	return 0, nil
 }
 
@@ -442,6 +466,12 @@ DONE:
 			state.UUID(), status.ready.NodeUUID)
 	}
 
+	if status.ready.HugePagesTotalMB != 2048 ||
+		status.ready.HugePagesAvailableMB != 2048 ||
+		len(status.ready.NUMANodes) != 2 {
+		t.Errorf("Unexpected huge pages reported in READY event")
+	}
+
 	shutdownOverseer(ovsCh, state)
 	wg.Wait()
 }
@@ -814,3 +844,73 @@ func TestStateChange(t *testing.T) {
 	shutdownOverseer(ovsCh, state)
 	wg.Wait()
 }
+
+// Check that the node inventory is sent once restored instances have settled.
+//
+// Start the overseer, add an instance and issue a statsStatusCommand, as
+// happens when the launcher connects to the scheduler.  Then set the
+// instance's state to running.
+//
+// No inventory should be sent while the instance is pending.  An inventory
+// containing the running instance should be sent once its state changes.
+func TestNodeInventory(t *testing.T) {
+	diskLimit = false
+	memLimit = false
+
+	instancesDir, err := ioutil.TempDir("", "overseer-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory")
+	}
+	defer func() { _ = os.RemoveAll(instancesDir) }()
+
+	var wg sync.WaitGroup
+	state := &overseerTestState{
+		t:           t,
+		statsCh:     make(chan *payloads.Stat),
+		inventoryCh: make(chan *payloads.EventNodeInventory, 1),
+	}
+	state.ac = &agentClient{conn: state, cmdCh: make(chan *cmdWrapper)}
+
+	ovsCh := startOverseerFull(instancesDir, &wg, state.ac, time.Second*1000,
+		fakeDeviceInfo{})
+
+	_ = addInstance(t, ovsCh, state, false)
+	_, _ = getStatusStats(t, ovsCh, state)
+
+	select {
+	case <-state.inventoryCh:
+		t.Fatal("Inventory sent while instance pending")
+	default:
+	}
+
+	select {
+	case ovsCh <- &ovsStateChange{
+		instance: "test-instance",
+		state:    ovsRunning,
+	}:
+	case <-time.After(time.Second):
+		t.Fatal("Unable to send ovsStateChange")
+	}
+
+	select {
+	case inventory := <-state.inventoryCh:
+		inv := inventory.Inventory
+		if inv.NodeUUID != state.UUID() {
+			t.Errorf("Unexpected node UUID %s", inv.NodeUUID)
+		}
+		if len(inv.Instances) != 1 {
+			t.Fatalf("Expected one instance, found %d", len(inv.Instances))
+		}
+		ins := inv.Instances[0]
+		if ins.InstanceUUID != "test-instance" ||
+			ins.TenantUUID != "67d86208-000-4465-9018-fe14087d415f" ||
+			ins.State != payloads.Running {
+			t.Errorf("Unexpected inventory entry %+v", ins)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for NodeInventory")
+	}
+
+	shutdownOverseer(ovsCh, state)
+	wg.Wait()
+}
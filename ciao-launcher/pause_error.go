@@ -0,0 +1,54 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package main
+
+import (
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
+	"github.com/golang/glog"
+)
+
+// pauseFailures maps the commands that change the power state of an
+// instance to the errors used to report their failure.
+var pauseFailures = map[ssntp.Command]ssntp.Error{
+	ssntp.PauseInstance:   ssntp.PauseFailure,
+	ssntp.UnpauseInstance: ssntp.UnpauseFailure,
+	ssntp.SuspendInstance: ssntp.SuspendFailure,
+	ssntp.ResumeInstance:  ssntp.ResumeFailure,
+}
+
+type pauseError struct {
+	err  error
+	code payloads.PauseFailureReason
+}
+
+func (pe *pauseError) send(conn serverConn, command ssntp.Command, instance string) {
+	if !conn.isConnected() {
+		return
+	}
+
+	failure := pauseFailures[command]
+	payload, err := generatePauseError(conn.UUID(), instance, pe)
+	if err != nil {
+		glog.Errorf("Unable to generate payload for %s: %v", failure, err)
+		return
+	}
+
+	_, err = conn.SendError(failure, payload)
+	if err != nil {
+		glog.Errorf("Unable to send %s: %v", failure, err)
+	}
+}
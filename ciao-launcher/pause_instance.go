@@ -0,0 +1,95 @@
+/*
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/golang/glog"
+)
+
+const (
+	// suspendStateFile is the name of the file in the instance directory
+	// that holds the saved state of a suspended VM.
+	suspendStateFile = "state.save"
+
+	// pausedMarkerFile is created in the instance directory when an
+	// instance is paused so that launcher can report the correct state
+	// of the instance if it is restarted.
+	pausedMarkerFile = "paused"
+)
+
+func instanceSuspended(instanceDir string) bool {
+	_, err := os.Stat(path.Join(instanceDir, suspendStateFile))
+	return err == nil
+}
+
+func instancePaused(instanceDir string) bool {
+	_, err := os.Stat(path.Join(instanceDir, pausedMarkerFile))
+	return err == nil
+}
+
+func setInstancePaused(instanceDir string, paused bool) {
+	markerPath := path.Join(instanceDir, pausedMarkerFile)
+	if !paused {
+		if err := os.Remove(markerPath); err != nil {
+			glog.Warningf("Unable to remove %s: %v", markerPath, err)
+		}
+		return
+	}
+
+	f, err := os.Create(markerPath)
+	if err != nil {
+		glog.Warningf("Unable to create %s: %v", markerPath, err)
+		return
+	}
+	_ = f.Close()
+}
+
+func processResume(vm virtualizer, cfg *vmConfig, instanceDir string, conn serverConn) *pauseError {
+	var vnicNames []string
+
+	if networking {
+		vnicCfgs, err := createVnicCfgs(cfg)
+		if err != nil {
+			glog.Errorf("Could not create VnicCFG: %s", err)
+			return &pauseError{err, payloads.PauseActionFailure}
+		}
+
+		vnicNames, _, _, err = createVnics(conn, vnicCfgs)
+		if err != nil {
+			return &pauseError{err, payloads.PauseActionFailure}
+		}
+	}
+
+	stateFile := path.Join(instanceDir, suspendStateFile)
+	err := vm.resumeVM(vnicNames, getNodeIPAddress(), cephID, stateFile)
+	if err != nil {
+		return &pauseError{err, payloads.PauseActionFailure}
+	}
+
+	// The virtualizer holds its own descriptor for the state file so it
+	// can be removed while the state is still being restored.
+
+	if err = os.Remove(stateFile); err != nil {
+		glog.Warningf("Unable to remove %s: %v", stateFile, err)
+	}
+
+	return nil
+}
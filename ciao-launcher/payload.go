@@ -23,9 +23,11 @@ import (
 	"net"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
 	"github.com/golang/glog"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -65,10 +67,22 @@ func printCloudinit(data *payloads.Start) {
 	glog.Infof("VnicIP:               %v", net.PrivateIP)
 	glog.Infof("ConcIP:               %v", net.ConcentratorIP)
 	glog.Infof("SubnetIP:             %v", net.Subnet)
+	glog.Infof("Gateway:              %v", net.Gateway)
 	glog.Infof("ConcUUID:             %v", net.ConcentratorUUID)
 	glog.Infof("VnicUUID:             %v", net.VnicUUID)
 	glog.Infof("Restart:              %t", start.Restart)
 
+	for i, net := range start.AdditionalNetworking {
+		glog.Infof("Additional NIC %d:", i+1)
+		glog.Infof("  VnicMAC:            %v", net.VnicMAC)
+		glog.Infof("  VnicIP:             %v", net.PrivateIP)
+		glog.Infof("  ConcIP:             %v", net.ConcentratorIP)
+		glog.Infof("  SubnetIP:           %v", net.Subnet)
+		glog.Infof("  Gateway:            %v", net.Gateway)
+		glog.Infof("  ConcUUID:           %v", net.ConcentratorUUID)
+		glog.Infof("  VnicUUID:           %v", net.VnicUUID)
+	}
+
 	glog.Info("Requested resources:")
 	for i := range start.RequestedResources {
 		glog.Infof("%8s:     %v", start.RequestedResources[i].Type,
@@ -79,6 +93,9 @@ func printCloudinit(data *payloads.Start) {
 		if storage.ID != "" {
 			glog.Info("Volumes:")
 			glog.Infof("  %s Bootable=%t", storage.ID, storage.Bootable)
+		} else if storage.Local {
+			glog.Info("Local disks:")
+			glog.Infof("  %d GB Swap=%t", storage.Size, storage.Swap)
 		}
 	}
 }
@@ -140,7 +157,8 @@ func parseStartPayload(data []byte) (*vmConfig, *payloadError) {
 	legacy := fwType == payloads.Legacy
 
 	var cpus, mem int
-	var networkNode bool
+	var networkNode, dedicatedCPUs, hugePages, numaLocal, guestAgent, console bool
+	var diskIOPS, diskMBps, netRxMbps, netTxMbps int
 	container, err := parseVMTtype(start)
 	if err != nil {
 		return nil, &payloadError{err, payloads.InvalidData}
@@ -154,44 +172,135 @@ func parseStartPayload(data []byte) (*vmConfig, *payloadError) {
 			mem = start.RequestedResources[i].Value
 		case payloads.NetworkNode:
 			networkNode = start.RequestedResources[i].Value != 0
+		case payloads.DedicatedCPUs:
+			dedicatedCPUs = start.RequestedResources[i].Value != 0
+		case payloads.HugePages:
+			hugePages = start.RequestedResources[i].Value != 0
+		case payloads.NUMALocal:
+			numaLocal = start.RequestedResources[i].Value != 0
+		case payloads.GuestAgent:
+			guestAgent = start.RequestedResources[i].Value != 0
+		case payloads.Console:
+			console = start.RequestedResources[i].Value != 0
+		case payloads.DiskIOPS:
+			diskIOPS = start.RequestedResources[i].Value
+		case payloads.DiskMBps:
+			diskMBps = start.RequestedResources[i].Value
+		case payloads.NetRxMbps:
+			netRxMbps = start.RequestedResources[i].Value
+		case payloads.NetTxMbps:
+			netTxMbps = start.RequestedResources[i].Value
 		}
 	}
 
+	if diskIOPS < 0 || diskMBps < 0 || netRxMbps < 0 || netTxMbps < 0 {
+		err = fmt.Errorf("I/O limits must not be negative")
+		return nil, &payloadError{err, payloads.InvalidData}
+	}
+
+	if dedicatedCPUs && cpus <= 0 {
+		err = fmt.Errorf("Dedicated CPUs requested without specifying the number of VCPUs")
+		return nil, &payloadError{err, payloads.InvalidData}
+	}
+
+	if hugePages && (mem <= 0 || container) {
+		err = fmt.Errorf("Huge pages require the memory size to be specified and are not supported by containers")
+		return nil, &payloadError{err, payloads.InvalidData}
+	}
+
+	if len(start.AdditionalNetworking) > 0 && (container || networkNode) {
+		err = fmt.Errorf("Additional NICs are only supported by VMs running on CNs")
+		return nil, &payloadError{err, payloads.InvalidData}
+	}
+
+	var additionalNICs []nicConfig
+	for _, net := range start.AdditionalNetworking {
+		additionalNICs = append(additionalNICs, nicConfig{
+			VnicMAC:     strings.TrimSpace(net.VnicMAC),
+			VnicIP:      strings.TrimSpace(net.PrivateIP),
+			VnicUUID:    strings.TrimSpace(net.VnicUUID),
+			ConcIP:      strings.TrimSpace(net.ConcentratorIP),
+			ConcUUID:    strings.TrimSpace(net.ConcentratorUUID),
+			SubnetIP:    strings.TrimSpace(net.Subnet),
+			Gateway:     strings.TrimSpace(net.Gateway),
+			DHCPStart:   strings.TrimSpace(net.DHCPStart),
+			DHCPEnd:     strings.TrimSpace(net.DHCPEnd),
+			IPv6Subnet:  strings.TrimSpace(net.IPv6Subnet),
+			IPv6Gateway: strings.TrimSpace(net.IPv6Gateway),
+			IPv6Mode:    strings.TrimSpace(net.IPv6Mode),
+		})
+	}
+
 	net := &start.Networking
 	vnicIP := strings.TrimSpace(net.PrivateIP)
 	sshPort := computeSSHPort(networkNode, vnicIP)
 	var volumes []volumeConfig
+	var ephemeralDisks []ephemeralDiskConfig
+	var disk int
 	for _, storage := range start.Storage {
 		if storage.ID != "" {
 			volumes = append(volumes, volumeConfig{
 				UUID:     storage.ID,
 				Bootable: storage.Bootable,
+				ReadOnly: storage.ReadOnly,
+				Shared:   storage.Shared,
 			})
-		} else {
-			/* See github issue #972:
-			   A storage.ID == "" implies an auto-created-by-launcher
-			   local disk.  This is not yet supported. */
+			continue
+		}
+
+		// A storage.ID == "" implies an auto-created-by-launcher
+		// local disk, carved out of the ephemeral pool.
+		if !storage.Local || storage.Size <= 0 {
+			err = fmt.Errorf("Invalid local disk: size %d GB", storage.Size)
 			return nil, &payloadError{err, payloads.InvalidData}
 		}
+		if container || storage.Bootable {
+			err = fmt.Errorf("Local disks must be non-bootable and are not supported by containers")
+			return nil, &payloadError{err, payloads.InvalidData}
+		}
+		ephemeralDisks = append(ephemeralDisks, ephemeralDiskConfig{
+			SizeMB: storage.Size * 1024,
+			Swap:   storage.Swap,
+			Tag:    storage.Tag,
+		})
+		disk += storage.Size * 1024
 	}
 
 	return &vmConfig{Cpus: cpus,
-		Mem:         mem,
-		Instance:    instance,
-		DockerImage: start.DockerImage,
-		Legacy:      legacy,
-		Container:   container,
-		NetworkNode: networkNode,
-		VnicMAC:     strings.TrimSpace(net.VnicMAC),
-		VnicIP:      vnicIP,
-		ConcIP:      strings.TrimSpace(net.ConcentratorIP),
-		SubnetIP:    strings.TrimSpace(net.Subnet),
-		TenantUUID:  strings.TrimSpace(start.TenantUUID),
-		ConcUUID:    strings.TrimSpace(net.ConcentratorUUID),
-		VnicUUID:    strings.TrimSpace(net.VnicUUID),
-		SSHPort:     sshPort,
-		Volumes:     volumes,
-		Restart:     clouddata.Start.Restart,
+		Mem:            mem,
+		Disk:           disk,
+		Instance:       instance,
+		DockerImage:    start.DockerImage,
+		Legacy:         legacy,
+		Container:      container,
+		NetworkNode:    networkNode,
+		VnicMAC:        strings.TrimSpace(net.VnicMAC),
+		VnicIP:         vnicIP,
+		ConcIP:         strings.TrimSpace(net.ConcentratorIP),
+		SubnetIP:       strings.TrimSpace(net.Subnet),
+		Gateway:        strings.TrimSpace(net.Gateway),
+		DHCPStart:      strings.TrimSpace(net.DHCPStart),
+		DHCPEnd:        strings.TrimSpace(net.DHCPEnd),
+		IPv6Subnet:     strings.TrimSpace(net.IPv6Subnet),
+		IPv6Gateway:    strings.TrimSpace(net.IPv6Gateway),
+		IPv6Mode:       strings.TrimSpace(net.IPv6Mode),
+		TenantUUID:     strings.TrimSpace(start.TenantUUID),
+		ConcUUID:       strings.TrimSpace(net.ConcentratorUUID),
+		VnicUUID:       strings.TrimSpace(net.VnicUUID),
+		SSHPort:        sshPort,
+		Volumes:        volumes,
+		EphemeralDisks: ephemeralDisks,
+		Restart:        clouddata.Start.Restart,
+		DedicatedCPUs:  dedicatedCPUs,
+		HugePages:      hugePages,
+		NUMALocal:      numaLocal,
+		GuestAgent:     guestAgent && !container,
+		Console:        console && container,
+		AdditionalNICs: additionalNICs,
+		DiskIOPS:       diskIOPS,
+		DiskMBps:       diskMBps,
+		NetRxMbps:      netRxMbps,
+		NetTxMbps:      netTxMbps,
 	}, nil
 }
 
@@ -224,6 +333,45 @@ func generateAttachVolumeError(node, instance, volume string, ave *attachVolumeE
 	return yaml.Marshal(avf)
 }
 
+func generateDetachVolumeError(node, instance, volume string, dve *detachVolumeError) (out []byte, err error) {
+	dvf := &payloads.ErrorDetachVolumeFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		VolumeUUID:   volume,
+		Reason:       dve.code,
+	}
+	return yaml.Marshal(dvf)
+}
+
+func generateConsoleLogError(node, instance, requestID string, cle *consoleLogError) (out []byte, err error) {
+	clf := &payloads.ErrorConsoleLogFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		Reason:       cle.code,
+		RequestID:    requestID,
+	}
+	return yaml.Marshal(clf)
+}
+
+func generateOpenConsoleError(node, instance, token string, oce *openConsoleError) (out []byte, err error) {
+	ocf := &payloads.ErrorOpenConsoleFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		Token:        token,
+		Reason:       oce.code,
+	}
+	return yaml.Marshal(ocf)
+}
+
+func generatePauseError(node, instance string, pe *pauseError) (out []byte, err error) {
+	pf := &payloads.ErrorPauseFailure{
+		NodeUUID:     node,
+		InstanceUUID: instance,
+		Reason:       pe.code,
+	}
+	return yaml.Marshal(pf)
+}
+
 func generateNetEventPayload(ssntpEvent *libsnnet.SsntpEventInfo, agentUUID string) ([]byte, error) {
 	var event interface{}
 	var eventData *payloads.TenantAddedEvent
@@ -246,6 +394,12 @@ func generateNetEventPayload(ssntpEvent *libsnnet.SsntpEventInfo, agentUUID stri
 	eventData.AgentIP = ssntpEvent.CnIP
 	eventData.TenantUUID = ssntpEvent.TenantID
 	eventData.TenantSubnet = ssntpEvent.SubnetID
+	eventData.TenantGateway = ssntpEvent.Gateway
+	eventData.DHCPStart = ssntpEvent.DhcpStart
+	eventData.DHCPEnd = ssntpEvent.DhcpEnd
+	eventData.IPv6Subnet = ssntpEvent.IPv6Subnet
+	eventData.IPv6Gateway = ssntpEvent.IPv6Gateway
+	eventData.IPv6Mode = ssntpEvent.IPv6Mode
 	eventData.ConcentratorUUID = ssntpEvent.ConcID
 	eventData.ConcentratorIP = ssntpEvent.CnciIP
 	eventData.SubnetKey = ssntpEvent.SubnetKey
@@ -253,20 +407,67 @@ func generateNetEventPayload(ssntpEvent *libsnnet.SsntpEventInfo, agentUUID stri
 	return yaml.Marshal(event)
 }
 
-func parseDeletePayload(data []byte) (string, bool, *payloadError) {
+func parseDeletePayload(data []byte) (string, *insDeleteCmd, *payloadError) {
 	var clouddata payloads.Delete
 
 	err := yaml.Unmarshal(data, &clouddata)
 	if err != nil {
-		return "", false, &payloadError{err, payloads.DeleteInvalidPayload}
+		return "", nil, &payloadError{err, payloads.DeleteInvalidPayload}
 	}
 
 	instance := strings.TrimSpace(clouddata.Delete.InstanceUUID)
 	if !uuidRegexp.MatchString(instance) {
 		err = fmt.Errorf("Invalid instance id received: %s", instance)
-		return "", false, &payloadError{err, payloads.DeleteInvalidData}
+		return "", nil, &payloadError{err, payloads.DeleteInvalidData}
+	}
+
+	if clouddata.Delete.GracefulTimeout < 0 {
+		err = fmt.Errorf("Invalid graceful timeout received: %d",
+			clouddata.Delete.GracefulTimeout)
+		return "", nil, &payloadError{err, payloads.DeleteInvalidData}
+	}
+
+	return instance, &insDeleteCmd{
+		stop:    clouddata.Delete.Stop,
+		timeout: time.Duration(clouddata.Delete.GracefulTimeout) * time.Second,
+	}, nil
+}
+
+func parseUpdateConcentratorPayload(data []byte) (*updateConcentratorCmd, error) {
+	var clouddata payloads.CommandUpdateConcentrator
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &clouddata.UpdateConcentrator
+	tenant := strings.TrimSpace(update.TenantUUID)
+	if !uuidRegexp.MatchString(tenant) {
+		return nil, fmt.Errorf("Invalid tenant id received: %s", tenant)
+	}
+
+	concUUID := strings.TrimSpace(update.ConcentratorUUID)
+	if !uuidRegexp.MatchString(concUUID) {
+		return nil, fmt.Errorf("Invalid concentrator id received: %s", concUUID)
+	}
+
+	subnet := strings.TrimSpace(update.TenantSubnet)
+	if _, _, err := net.ParseCIDR(subnet); err != nil {
+		return nil, fmt.Errorf("Invalid tenant subnet received: %s", subnet)
+	}
+
+	concIP := strings.TrimSpace(update.ConcentratorIP)
+	if net.ParseIP(concIP) == nil {
+		return nil, fmt.Errorf("Invalid concentrator ip received: %s", concIP)
 	}
-	return instance, clouddata.Delete.Stop, nil
+
+	return &updateConcentratorCmd{
+		tenant:   tenant,
+		subnet:   subnet,
+		concUUID: concUUID,
+		concIP:   concIP,
+	}, nil
 }
 
 func extractVolumeInfo(cmd *payloads.VolumeCmd, errString string) (string, string, *payloadError) {
@@ -284,16 +485,125 @@ func extractVolumeInfo(cmd *payloads.VolumeCmd, errString string) (string, strin
 	return instance, volume, nil
 }
 
-func parseAttachVolumePayload(data []byte) (string, string, *payloadError) {
+func parseAttachVolumePayload(data []byte) (string, volumeConfig, *payloadError) {
 	var clouddata payloads.AttachVolume
 
 	err := yaml.Unmarshal(data, &clouddata)
 	if err != nil {
 		glog.Errorf("YAML error: %v", err)
-		return "", "", &payloadError{err, payloads.AttachVolumeInvalidPayload}
+		return "", volumeConfig{}, &payloadError{err, payloads.AttachVolumeInvalidPayload}
+	}
+
+	instance, volume, payloadErr := extractVolumeInfo(&clouddata.Attach,
+		payloads.AttachVolumeInvalidData)
+	if payloadErr != nil {
+		return "", volumeConfig{}, payloadErr
+	}
+
+	return instance, volumeConfig{
+		UUID:     volume,
+		ReadOnly: clouddata.Attach.ReadOnly,
+		Shared:   clouddata.Attach.Shared,
+	}, nil
+}
+
+func parseDetachVolumePayload(data []byte) (string, string, *payloadError) {
+	var clouddata payloads.DetachVolume
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", "", &payloadError{err, payloads.DetachVolumeInvalidPayload}
+	}
+
+	return extractVolumeInfo(&clouddata.Detach, payloads.DetachVolumeInvalidData)
+}
+
+func parseGetConsoleLogPayload(data []byte) (string, string, int, *payloadError) {
+	var clouddata payloads.GetConsoleLog
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", "", 0, &payloadError{err, payloads.ConsoleLogInvalidPayload}
+	}
+
+	requestID := clouddata.ConsoleLog.RequestID
+	instance := strings.TrimSpace(clouddata.ConsoleLog.InstanceUUID)
+	if !uuidRegexp.MatchString(instance) {
+		err := fmt.Errorf("Invalid instance id received: %s", instance)
+		return "", requestID, 0, &payloadError{err, payloads.ConsoleLogInvalidData}
+	}
+
+	lines := clouddata.ConsoleLog.Lines
+	if lines < 0 {
+		err := fmt.Errorf("Invalid number of lines requested: %d", lines)
+		return instance, requestID, 0, &payloadError{err, payloads.ConsoleLogInvalidData}
+	}
+
+	return instance, requestID, lines, nil
+}
+
+func parseOpenConsolePayload(data []byte) (string, string, *payloadError) {
+	var clouddata payloads.OpenConsole
+
+	err := yaml.Unmarshal(data, &clouddata)
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", "", &payloadError{err, payloads.OpenConsoleInvalidPayload}
+	}
+
+	instance := strings.TrimSpace(clouddata.Console.InstanceUUID)
+	if !uuidRegexp.MatchString(instance) {
+		err := fmt.Errorf("Invalid instance id received: %s", instance)
+		return "", "", &payloadError{err, payloads.OpenConsoleInvalidData}
+	}
+
+	token := clouddata.Console.Token
+	if token == "" || strings.ContainsAny(token, " \t\r\n") {
+		err := fmt.Errorf("Invalid console token received")
+		return instance, "", &payloadError{err, payloads.OpenConsoleInvalidData}
+	}
+
+	return instance, token, nil
+}
+
+func parsePausePayload(command ssntp.Command, data []byte) (string, *payloadError) {
+	var cmd payloads.PauseCmd
+	var err error
+
+	switch command {
+	case ssntp.PauseInstance:
+		var clouddata payloads.Pause
+		err = yaml.Unmarshal(data, &clouddata)
+		cmd = clouddata.Pause
+	case ssntp.UnpauseInstance:
+		var clouddata payloads.Unpause
+		err = yaml.Unmarshal(data, &clouddata)
+		cmd = clouddata.Unpause
+	case ssntp.SuspendInstance:
+		var clouddata payloads.Suspend
+		err = yaml.Unmarshal(data, &clouddata)
+		cmd = clouddata.Suspend
+	case ssntp.ResumeInstance:
+		var clouddata payloads.Resume
+		err = yaml.Unmarshal(data, &clouddata)
+		cmd = clouddata.Resume
+	default:
+		err = fmt.Errorf("Unexpected command %s", command)
+	}
+	if err != nil {
+		glog.Errorf("YAML error: %v", err)
+		return "", &payloadError{err, string(payloads.PauseInvalidPayload)}
+	}
+
+	instance := strings.TrimSpace(cmd.InstanceUUID)
+	if !uuidRegexp.MatchString(instance) {
+		err := fmt.Errorf("Invalid instance id received: %s", instance)
+		return "", &payloadError{err, string(payloads.PauseInvalidData)}
 	}
 
-	return extractVolumeInfo(&clouddata.Attach, payloads.AttachVolumeInvalidData)
+	return instance, nil
 }
 
 func linesToBytes(doc []string, buf *bytes.Buffer) {
@@ -18,12 +18,15 @@ package main
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/ciao-project/ciao/networking/libsnnet"
 	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/ssntp"
 	"github.com/ciao-project/ciao/testutil"
 )
 
@@ -69,8 +72,8 @@ start:
 			SSHPort:    35050,
 			Volumes: []volumeConfig{
 				{
-					"69e84267-ed01-4738-b15f-b47de06b62e7",
-					true,
+					UUID:     "69e84267-ed01-4738-b15f-b47de06b62e7",
+					Bootable: true,
 				},
 			},
 		},
@@ -149,6 +152,296 @@ start:
   storage:
      - id: 69e84267-ed01-4738-b15f-b47de06b62e7
        boot: true
+`,
+		nil,
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: mem_mb
+       value: 370
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+  networking:
+    vnic_mac: 02:00:e6:f5:af:f9
+    vnic_uuid: 67d86208-b46c-0000-9018-fe14087d415f
+    concentrator_ip: 192.168.42.21
+    concentrator_uuid: 67d86208-b46c-4465-0000-fe14087d415f
+    subnet: 192.168.8.0/21
+    private_ip: 192.168.8.2
+  storage:
+     - id: 69e84267-ed01-4738-b15f-b47de06b62e7
+       boot: true
+     - local: true
+       size: 2
+     - local: true
+       swap: true
+       tag: swap
+       size: 1
+`,
+		&vmConfig{
+			Cpus:       2,
+			Mem:        370,
+			Disk:       3 * 1024,
+			Instance:   "d7d86208-b46c-4465-9018-ee14087d415f",
+			Legacy:     true,
+			VnicMAC:    "02:00:e6:f5:af:f9",
+			VnicIP:     "192.168.8.2",
+			ConcIP:     "192.168.42.21",
+			SubnetIP:   "192.168.8.0/21",
+			TenantUUID: "67d86208-000-4465-9018-fe14087d415f",
+			ConcUUID:   "67d86208-b46c-4465-0000-fe14087d415f",
+			VnicUUID:   "67d86208-b46c-0000-9018-fe14087d415f",
+			SSHPort:    35050,
+			Volumes: []volumeConfig{
+				{
+					UUID:     "69e84267-ed01-4738-b15f-b47de06b62e7",
+					Bootable: true,
+				},
+			},
+			EphemeralDisks: []ephemeralDiskConfig{
+				{
+					SizeMB: 2 * 1024,
+				},
+				{
+					SizeMB: 1024,
+					Swap:   true,
+					Tag:    "swap",
+				},
+			},
+		},
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: mem_mb
+       value: 370
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+  networking:
+    vnic_mac: 02:00:e6:f5:af:f9
+    vnic_uuid: 67d86208-b46c-0000-9018-fe14087d415f
+    concentrator_ip: 192.168.42.21
+    concentrator_uuid: 67d86208-b46c-4465-0000-fe14087d415f
+    subnet: 192.168.8.0/21
+    private_ip: 192.168.8.2
+  storage:
+     - local: true
+       boot: true
+       size: 2
+`,
+		nil,
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: mem_mb
+       value: 1024
+     - type: dedicated_cpus
+       value: 1
+     - type: hugepages
+       value: 1
+     - type: numa_local
+       value: 1
+     - type: guest_agent
+       value: 1
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+`,
+		&vmConfig{
+			Cpus:          2,
+			Mem:           1024,
+			Instance:      "d7d86208-b46c-4465-9018-ee14087d415f",
+			Legacy:        true,
+			TenantUUID:    "67d86208-000-4465-9018-fe14087d415f",
+			DedicatedCPUs: true,
+			HugePages:     true,
+			NUMALocal:     true,
+			GuestAgent:    true,
+		},
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: mem_mb
+       value: 1024
+     - type: dedicated_cpus
+       value: 1
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+`,
+		nil,
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: mem_mb
+       value: 1024
+     - type: hugepages
+       value: 1
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  docker_image: ubuntu:latest
+  vm_type: docker
+`,
+		nil,
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: mem_mb
+       value: 1024
+     - type: console
+       value: 1
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  docker_image: ubuntu:latest
+  vm_type: docker
+`,
+		&vmConfig{
+			Cpus:        2,
+			Mem:         1024,
+			Instance:    "d7d86208-b46c-4465-9018-ee14087d415f",
+			DockerImage: "ubuntu:latest",
+			Container:   true,
+			TenantUUID:  "67d86208-000-4465-9018-fe14087d415f",
+			Console:     true,
+		},
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: mem_mb
+       value: 1024
+     - type: disk_iops
+       value: 500
+     - type: disk_mbps
+       value: 20
+     - type: net_rx_mbps
+       value: 100
+     - type: net_tx_mbps
+       value: 50
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+`,
+		&vmConfig{
+			Cpus:       2,
+			Mem:        1024,
+			Instance:   "d7d86208-b46c-4465-9018-ee14087d415f",
+			Legacy:     true,
+			TenantUUID: "67d86208-000-4465-9018-fe14087d415f",
+			DiskIOPS:   500,
+			DiskMBps:   20,
+			NetRxMbps:  100,
+			NetTxMbps:  50,
+		},
+	},
+	{
+		`
+start:
+  requested_resources:
+     - type: vcpus
+       value: 2
+     - type: disk_iops
+       value: -1
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+`,
+		nil,
+	},
+	{
+		`
+start:
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  fw_type: legacy
+  vm_type: qemu
+  networking:
+    vnic_mac: 02:00:e6:f5:af:f9
+    vnic_uuid: 67d86208-b46c-0000-9018-fe14087d415f
+    concentrator_ip: 192.168.42.21
+    concentrator_uuid: 67d86208-b46c-4465-0000-fe14087d415f
+    subnet: 192.168.8.0/21
+    private_ip: 192.168.8.2
+  additional_networking:
+    - vnic_mac: 02:00:0a:0a:00:02
+      vnic_uuid: 67d86208-b46c-1111-9018-fe14087d415f
+      concentrator_ip: 192.168.42.22
+      concentrator_uuid: 67d86208-b46c-4465-1111-fe14087d415f
+      subnet: 10.10.0.0/24
+      gateway: 10.10.0.254
+      dhcp_start: 10.10.0.2
+      dhcp_end: 10.10.0.100
+      private_ip: 10.10.0.2
+`,
+		&vmConfig{
+			Instance:   "d7d86208-b46c-4465-9018-ee14087d415f",
+			Legacy:     true,
+			TenantUUID: "67d86208-000-4465-9018-fe14087d415f",
+			VnicMAC:    "02:00:e6:f5:af:f9",
+			VnicIP:     "192.168.8.2",
+			ConcIP:     "192.168.42.21",
+			SubnetIP:   "192.168.8.0/21",
+			ConcUUID:   "67d86208-b46c-4465-0000-fe14087d415f",
+			VnicUUID:   "67d86208-b46c-0000-9018-fe14087d415f",
+			SSHPort:    35050,
+			AdditionalNICs: []nicConfig{
+				{
+					VnicMAC:   "02:00:0a:0a:00:02",
+					VnicIP:    "10.10.0.2",
+					VnicUUID:  "67d86208-b46c-1111-9018-fe14087d415f",
+					ConcIP:    "192.168.42.22",
+					ConcUUID:  "67d86208-b46c-4465-1111-fe14087d415f",
+					SubnetIP:  "10.10.0.0/24",
+					Gateway:   "10.10.0.254",
+					DHCPStart: "10.10.0.2",
+					DHCPEnd:   "10.10.0.100",
+				},
+			},
+		},
+	},
+	{
+		`
+start:
+  instance_uuid: d7d86208-b46c-4465-9018-ee14087d415f
+  tenant_uuid: 67d86208-000-4465-9018-fe14087d415f
+  docker_image: ubuntu:latest
+  vm_type: docker
+  additional_networking:
+    - vnic_mac: 02:00:0a:0a:00:02
+      subnet: 10.10.0.0/24
+      private_ip: 10.10.0.2
 `,
 		nil,
 	},
@@ -166,9 +459,20 @@ func TestParseAttachVolumePayload(t *testing.T) {
 	if err != nil {
 		t.Fatalf("parseAttachVolumePayload failed: %v", err)
 	}
-	if instance != testutil.InstanceUUID || volume != testutil.VolumeUUID {
+	if instance != testutil.InstanceUUID || volume.UUID != testutil.VolumeUUID {
 		t.Fatalf("VolumeUUID or InstanceUUID is invalid")
 	}
+	if volume.ReadOnly || volume.Shared {
+		t.Fatalf("Volume should not be read only or shared")
+	}
+
+	_, volume, err = parseAttachVolumePayload([]byte(testutil.AttachSharedReadOnlyVolumeYaml))
+	if err != nil {
+		t.Fatalf("parseAttachVolumePayload failed: %v", err)
+	}
+	if !volume.ReadOnly || !volume.Shared {
+		t.Fatalf("Volume should be read only and shared")
+	}
 
 	_, _, err = parseAttachVolumePayload([]byte("  -"))
 	if err == nil || err.code != payloads.AttachVolumeInvalidPayload {
@@ -181,6 +485,164 @@ func TestParseAttachVolumePayload(t *testing.T) {
 	}
 }
 
+// Verify the parseDetachVolumePayload function.
+//
+// The function is passed one valid payload and two invalid payloads.
+//
+// No error should be returned for the valid payload and the returned instance
+// and volume UUIDs should match what is in the payload.  Errors should be
+// returned for the invalid payloads.
+func TestParseDetachVolumePayload(t *testing.T) {
+	instance, volume, err := parseDetachVolumePayload([]byte(testutil.DetachVolumeYaml))
+	if err != nil {
+		t.Fatalf("parseDetachVolumePayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || volume != testutil.VolumeUUID {
+		t.Fatalf("VolumeUUID or InstanceUUID is invalid")
+	}
+
+	_, _, err = parseDetachVolumePayload([]byte("  -"))
+	if err == nil || err.code != payloads.DetachVolumeInvalidPayload {
+		t.Fatalf("DetachVolumeInvalidPayload error expected")
+	}
+
+	_, _, err = parseDetachVolumePayload([]byte(testutil.BadDetachVolumeYaml))
+	if err == nil || err.code != payloads.DetachVolumeInvalidData {
+		t.Fatalf("DetachVolumeInvalidData error expected")
+	}
+}
+
+// Verify the parseGetConsoleLogPayload function.
+//
+// The function is passed one valid payload and two invalid payloads.
+//
+// No error should be returned for the valid payload and the returned instance
+// UUID, request ID and number of lines should match what is in the payload.
+// Errors should be returned for the invalid payloads, along with the request
+// ID of the second invalid payload.
+func TestParseGetConsoleLogPayload(t *testing.T) {
+	instance, requestID, lines, err := parseGetConsoleLogPayload([]byte(testutil.GetConsoleLogYaml))
+	if err != nil {
+		t.Fatalf("parseGetConsoleLogPayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || lines != 100 ||
+		requestID != testutil.ConsoleLogRequestID {
+		t.Fatalf("InstanceUUID, request ID or lines is invalid")
+	}
+
+	_, _, _, err = parseGetConsoleLogPayload([]byte("  -"))
+	if err == nil || err.code != payloads.ConsoleLogInvalidPayload {
+		t.Fatalf("ConsoleLogInvalidPayload error expected")
+	}
+
+	badPayload := strings.Replace(testutil.GetConsoleLogYaml, "lines: 100", "lines: -1", 1)
+	_, requestID, _, err = parseGetConsoleLogPayload([]byte(badPayload))
+	if err == nil || err.code != payloads.ConsoleLogInvalidData {
+		t.Fatalf("ConsoleLogInvalidData error expected")
+	}
+	if requestID != testutil.ConsoleLogRequestID {
+		t.Fatalf("Request ID of invalid payload not returned")
+	}
+}
+
+func TestParseOpenConsolePayload(t *testing.T) {
+	instance, token, err := parseOpenConsolePayload([]byte(testutil.OpenConsoleYaml))
+	if err != nil {
+		t.Fatalf("parseOpenConsolePayload failed: %v", err)
+	}
+	if instance != testutil.InstanceUUID || token != testutil.ConsoleToken {
+		t.Fatalf("InstanceUUID or token is invalid")
+	}
+
+	_, _, err = parseOpenConsolePayload([]byte("  -"))
+	if err == nil || err.code != payloads.OpenConsoleInvalidPayload {
+		t.Fatalf("OpenConsoleInvalidPayload error expected")
+	}
+
+	badPayload := strings.Replace(testutil.OpenConsoleYaml, testutil.ConsoleToken, `""`, 1)
+	_, _, err = parseOpenConsolePayload([]byte(badPayload))
+	if err == nil || err.code != payloads.OpenConsoleInvalidData {
+		t.Fatalf("OpenConsoleInvalidData error expected")
+	}
+}
+
+// Verify the parsePausePayload function.
+//
+// The function is passed a valid payload for each of the commands it
+// supports, a corrupt payload and a payload with an invalid instance UUID.
+//
+// No error should be returned for the valid payloads and the returned
+// instance UUID should match what is in the payload.  Errors should be
+// returned for the invalid payloads.
+func TestParsePausePayload(t *testing.T) {
+	pauseTests := []struct {
+		command ssntp.Command
+		payload string
+	}{
+		{ssntp.PauseInstance, testutil.PauseYaml},
+		{ssntp.UnpauseInstance, testutil.UnpauseYaml},
+		{ssntp.SuspendInstance, testutil.SuspendYaml},
+		{ssntp.ResumeInstance, testutil.ResumeYaml},
+	}
+
+	for _, pt := range pauseTests {
+		instance, err := parsePausePayload(pt.command, []byte(pt.payload))
+		if err != nil {
+			t.Fatalf("parsePausePayload failed for %s: %v", pt.command, err)
+		}
+		if instance != testutil.InstanceUUID {
+			t.Fatalf("InstanceUUID is invalid for %s", pt.command)
+		}
+	}
+
+	_, err := parsePausePayload(ssntp.PauseInstance, []byte("  -"))
+	if err == nil || err.code != string(payloads.PauseInvalidPayload) {
+		t.Fatalf("PauseInvalidPayload error expected")
+	}
+
+	badPayload := strings.Replace(testutil.PauseYaml, testutil.InstanceUUID, "foo", 1)
+	_, err = parsePausePayload(ssntp.PauseInstance, []byte(badPayload))
+	if err == nil || err.code != string(payloads.PauseInvalidData) {
+		t.Fatalf("PauseInvalidData error expected")
+	}
+}
+
+// Verify the parseUpdateConcentratorPayload function.
+//
+// The function is passed a valid payload, a corrupt payload and payloads
+// with an invalid concentrator UUID and an invalid concentrator IP address.
+//
+// No error should be returned for the valid payload and the returned
+// command should match what is in the payload.  Errors should be returned
+// for the invalid payloads.
+func TestParseUpdateConcentratorPayload(t *testing.T) {
+	cmd, err := parseUpdateConcentratorPayload([]byte(testutil.UpdateConcentratorYaml))
+	if err != nil {
+		t.Fatalf("parseUpdateConcentratorPayload failed: %v", err)
+	}
+	if cmd.tenant != testutil.TenantUUID || cmd.subnet != testutil.TenantSubnet ||
+		cmd.concUUID != testutil.CNCIUUID || cmd.concIP != testutil.CNCIIP {
+		t.Fatalf("Unexpected command %+v", cmd)
+	}
+
+	_, err = parseUpdateConcentratorPayload([]byte("  -"))
+	if err == nil {
+		t.Fatalf("Error expected for corrupt payload")
+	}
+
+	badPayload := strings.Replace(testutil.UpdateConcentratorYaml, testutil.CNCIUUID, "foo", 1)
+	_, err = parseUpdateConcentratorPayload([]byte(badPayload))
+	if err == nil {
+		t.Fatalf("Error expected for invalid concentrator UUID")
+	}
+
+	badPayload = strings.Replace(testutil.UpdateConcentratorYaml, testutil.CNCIIP, "foo", 1)
+	_, err = parseUpdateConcentratorPayload([]byte(badPayload))
+	if err == nil {
+		t.Fatalf("Error expected for invalid concentrator IP")
+	}
+}
+
 // Verify the parseStartPayload function.
 //
 // The function is passed one valid payload and a number of invalid payloads.
@@ -212,6 +674,9 @@ func compareNetEvents(t *testing.T, ev *libsnnet.SsntpEventInfo, eventData *payl
 		eventData.AgentIP != ev.CnIP ||
 		eventData.TenantUUID != ev.TenantID ||
 		eventData.TenantSubnet != ev.SubnetID ||
+		eventData.TenantGateway != ev.Gateway ||
+		eventData.DHCPStart != ev.DhcpStart ||
+		eventData.DHCPEnd != ev.DhcpEnd ||
 		eventData.ConcentratorUUID != ev.ConcID ||
 		eventData.ConcentratorIP != ev.CnciIP ||
 		eventData.SubnetKey != ev.SubnetKey {
@@ -241,6 +706,9 @@ func TestGenerateNetEventPayload(t *testing.T) {
 		ConcID:    testutil.CNCIUUID,
 		CnID:      testutil.AgentUUID,
 		SubnetKey: 1,
+		Gateway:   "10.2.0.254",
+		DhcpStart: "10.2.0.10",
+		DhcpEnd:   "10.2.0.100",
 	}
 
 	pl, err := generateNetEventPayload(ev, testutil.AgentUUID)
@@ -282,7 +750,7 @@ func TestGenerateNetEventPayload(t *testing.T) {
 // The payload should parse without any error and the instance UUID in the
 // resulting payloads data structure should be as expected.
 func TestParseDeletePayload(t *testing.T) {
-	instance, stop, err := parseDeletePayload([]byte(testutil.DeleteYaml))
+	instance, cmd, err := parseDeletePayload([]byte(testutil.DeleteYaml))
 	if err != nil {
 		t.Fatalf("Failed to parse delete payload : %v", err.err)
 	}
@@ -290,7 +758,18 @@ func TestParseDeletePayload(t *testing.T) {
 		t.Errorf("Wrong instance UUID.  Expected %s found %s", instance,
 			testutil.InstanceUUID)
 	}
-	if stop {
+	if cmd.stop {
 		t.Errorf("Expected stop to be false")
 	}
+	if cmd.timeout != 0 {
+		t.Errorf("Expected timeout to be 0, found %v", cmd.timeout)
+	}
+
+	_, cmd, err = parseDeletePayload([]byte(testutil.GracefulDeleteYaml))
+	if err != nil {
+		t.Fatalf("Failed to parse delete payload : %v", err.err)
+	}
+	if cmd.timeout != 60*time.Second {
+		t.Errorf("Expected timeout to be 60s, found %v", cmd.timeout)
+	}
 }
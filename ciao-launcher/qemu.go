@@ -20,6 +20,8 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path"
@@ -27,10 +29,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"context"
 
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/ciao-project/ciao/qemu"
 	"github.com/golang/glog"
 )
@@ -39,6 +43,12 @@ const (
 	qemuEfiFw = "/usr/share/qemu/OVMF.fd"
 	seedImage = "seed.iso"
 	vcTries   = 10
+
+	// suspendTimeout is the time we give qemu to write the state of a
+	// VM to disk.
+	suspendTimeout = 5 * time.Minute
+
+	hugePagesMount = "/dev/hugepages"
 )
 
 type qmpGlogLogger struct{}
@@ -75,12 +85,38 @@ type qemuV struct {
 	prevCPUTime    int64
 	prevSampleTime time.Time
 	isoPath        string
+	guest          *guestAgent
+	blockIO        qmpBlockStats
+	console        *consoleLogger
+}
+
+// qmpBlockStats holds the block statistics of a VM last retrieved by the
+// qmp go routine.  They are read by the instance go routine, hence the
+// mutex.
+type qmpBlockStats struct {
+	sync.Mutex
+	stats *payloads.BlockIOStat
+}
+
+func (b *qmpBlockStats) get() *payloads.BlockIOStat {
+	b.Lock()
+	defer b.Unlock()
+	return b.stats
+}
+
+func (b *qmpBlockStats) set(stats *payloads.BlockIOStat) {
+	b.Lock()
+	b.stats = stats
+	b.Unlock()
 }
 
 func (q *qemuV) init(cfg *vmConfig, instanceDir string) {
 	q.cfg = cfg
 	q.instanceDir = instanceDir
 	q.isoPath = path.Join(instanceDir, seedImage)
+	if cfg.GuestAgent {
+		q.guest = newGuestAgent(cfg.Instance, instanceDir)
+	}
 }
 
 func createCloudInitISO(instanceDir, isoPath string, cfg *vmConfig, userData, metaData []byte) error {
@@ -115,10 +151,11 @@ func (q *qemuV) createImage(bridge, gatewayIP string, userData, metaData []byte)
 		return err
 	}
 
-	return nil
+	return createEphemeralDisks(q.cfg)
 }
 
 func (q *qemuV) deleteImage() error {
+	deleteEphemeralDisks(q.cfg)
 	return nil
 }
 
@@ -135,34 +172,31 @@ func cleanupFds(fds []*os.File, numFds int) {
 	}
 }
 
-func computeMacvtapParam(vnicName string, mac string, queues int) ([]string, []*os.File, error) {
+func computeMacvtapDevice(vnicName string, mac string, queues int) (qemu.NetDevice, error) {
 
 	fds := make([]*os.File, queues)
-	params := make([]string, 0, 8)
 
 	ifIndexPath := path.Join("/sys/class/net", vnicName, "ifindex")
 	fip, err := os.Open(ifIndexPath)
 	if err != nil {
 		glog.Errorf("Failed to determine tap ifname: %s", err)
-		return nil, nil, err
+		return qemu.NetDevice{}, err
 	}
 	defer func() { _ = fip.Close() }()
 
 	scan := bufio.NewScanner(fip)
 	if !scan.Scan() {
 		glog.Error("Unable to read tap index")
-		return nil, nil, fmt.Errorf("Unable to read tap index")
+		return qemu.NetDevice{}, fmt.Errorf("Unable to read tap index")
 	}
 
 	i, err := strconv.Atoi(scan.Text())
 	if err != nil {
 		glog.Errorf("Failed to determine tap ifname: %s", err)
-		return nil, nil, err
+		return qemu.NetDevice{}, err
 	}
 
 	//mq support
-	var fdParam bytes.Buffer
-	fdSeperator := ""
 	for q := 0; q < queues; q++ {
 
 		tapDev := fmt.Sprintf("/dev/tap%d", i)
@@ -171,39 +205,34 @@ func computeMacvtapParam(vnicName string, mac string, queues int) ([]string, []*
 		if err != nil {
 			glog.Errorf("Failed to open tap device %s: %s", tapDev, err)
 			cleanupFds(fds, q)
-			return nil, nil, err
+			return qemu.NetDevice{}, err
 		}
 		fds[q] = f
-		/*
-		   3, what do you mean 3.  Well, it turns out that files passed to child
-		   processes via cmd.ExtraFiles have different fds in the child and the
-		   parent.  In the child the fds are determined by the file's position
-		   in the ExtraFiles array + 3.
-		*/
-
-		// bytes.WriteString does not return an error
-		_, _ = fdParam.WriteString(fmt.Sprintf("%s%d", fdSeperator, q+3))
-		fdSeperator = ":"
 	}
 
-	netdev := fmt.Sprintf("type=tap,fds=%s,id=%s,vhost=on", fdParam.String(), vnicName)
-	device := fmt.Sprintf("virtio-net-pci,netdev=%s,mq=on,vectors=%d,mac=%s", vnicName, 32, mac)
-	params = append(params, "-netdev", netdev)
-	params = append(params, "-device", device)
-	return params, fds, nil
+	return qemu.NetDevice{
+		Type:       qemu.MACVTAP,
+		ID:         vnicName,
+		IFName:     vnicName,
+		FDs:        fds,
+		VHost:      true,
+		MACAddress: mac,
+	}, nil
 }
 
-func computeTapParam(vnicName string, mac string) ([]string, error) {
-	params := make([]string, 0, 8)
-	netdev := fmt.Sprintf("type=tap,ifname=%s,script=no,downscript=no,id=%s,vhost=on", vnicName, vnicName)
-	device := fmt.Sprintf("driver=virtio-net-pci,netdev=%s,mac=%s", vnicName, mac)
-
-	params = append(params, "-netdev", netdev)
-	params = append(params, "-device", device)
-	return params, nil
+func computeTapDevice(vnicName string, mac string) qemu.NetDevice {
+	return qemu.NetDevice{
+		Type:       qemu.TAP,
+		ID:         vnicName,
+		IFName:     vnicName,
+		Script:     "no",
+		DownScript: "no",
+		VHost:      true,
+		MACAddress: mac,
+	}
 }
 
-func launchQemuWithNC(params []string, fds []*os.File, ipAddress string) (int, error) {
+func launchQemuWithNC(params []string, fds []*os.File, ipAddress, instanceDir string) (int, error) {
 	var err error
 
 	tries := 0
@@ -215,8 +244,9 @@ func launchQemuWithNC(params []string, fds []*os.File, ipAddress string) (int, e
 		if port == 0 {
 			break
 		}
-		ncString := "socket,port=%d,host=%s,server,id=gnc0,server,nowait"
-		params[len(params)-1] = fmt.Sprintf(ncString, port, ipAddress)
+		ncString := "socket,port=%d,host=%s,server,id=gnc0,server,nowait,logfile=%s,logappend=on"
+		params[len(params)-1] = fmt.Sprintf(ncString, port, ipAddress,
+			path.Join(instanceDir, consoleLogFile))
 		var errStr string
 
 		errStr, err = qemu.LaunchCustomQemu(context.Background(), "", params, fds, qmpGlogLogger{})
@@ -236,7 +266,8 @@ func launchQemuWithNC(params []string, fds []*os.File, ipAddress string) (int, e
 
 	if port == 0 || (err != nil && tries == vcTries) {
 		glog.Warning("Failed to launch qemu due to chardev error.  Relaunching without virtual console")
-		_, err = qemu.LaunchCustomQemu(context.Background(), "", params[:len(params)-4], fds, qmpGlogLogger{})
+		params = append(params[:len(params)-4], consoleDevice(instanceDir).QemuParams(nil)...)
+		_, err = qemu.LaunchCustomQemu(context.Background(), "", params, fds, qmpGlogLogger{})
 	}
 
 	return port, err
@@ -280,38 +311,111 @@ func launchQemuWithSpice(params []string, fds []*os.File, ipAddress string) (int
 	return port, err
 }
 
-func generateQEMULaunchParams(cfg *vmConfig, isoPath, instanceDir string,
-	networkParams []string, cephID string) []string {
-	params := make([]string, 0, 32)
+func generateQEMUConfig(cfg *vmConfig, isoPath, instanceDir string,
+	netdevs []qemu.NetDevice, cephID string) (qemu.Config, error) {
+	devices := make([]qemu.Device, 0,
+		len(cfg.Volumes)+len(cfg.EphemeralDisks)+len(netdevs)+2)
+
+	// Drives specified in the START payload need to be assigned fixed PCI
+	// addresses otherwise qemu hangs on startup.  qemu does pre-allocate
+	// addresses when using the legacy method of adding volumes but we
+	// can't use this method if we want to be able to live detach these
+	// volumes.  The first slot available is 3 without spice and 4 with.
 
-	addr := 3
+	firstSlot := 3
 	if launchWithUI.String() == "spice" {
-		addr = 4
+		firstSlot = 4
 	}
-
-	// I know this is nasty but we have to specify a bus and address otherwise qemu
-	// hangs on startup.  I can't find a way to get qemu to pre-allocate the address.
-	// It will do this when using the legacy method of adding volumes but we can't do
-	// this if we want to be able to live detach these volumes.  The first drive qemu
-	// adds, i.e., the rootfs  is assigned a slot of 3 without spice and 4 with.
+	pciAddrs := qemu.NewPCIAddressAllocator("pci.0", firstSlot)
 
 	for _, v := range cfg.Volumes {
-		blockdevID := fmt.Sprintf("drive_%s", v.UUID)
-		volDriveStr := fmt.Sprintf("file=rbd:rbd/%s:id=%s,if=none,id=%s,format=raw",
-			v.UUID, cephID, blockdevID)
-		params = append(params, "-drive", volDriveStr)
-		volDeviceStr :=
-			fmt.Sprintf("virtio-blk-pci,scsi=off,bus=pci.0,addr=0x%x,id=device_%s,drive=%s",
-				addr, v.UUID, blockdevID)
-		params = append(params, "-device", volDeviceStr)
-		addr++
+		addr, err := pciAddrs.Next()
+		if err != nil {
+			return qemu.Config{}, err
+		}
+		devices = append(devices, qemu.BlockDevice{
+			Driver:    qemu.VirtioBlockPCI,
+			ID:        fmt.Sprintf("drive_%s", v.UUID),
+			DeviceID:  fmt.Sprintf("device_%s", v.UUID),
+			File:      v.UUID,
+			Backend:   qemu.RBDBackend,
+			RBDUser:   cephID,
+			Format:    qemu.RAW,
+			Interface: qemu.NoInterface,
+			Bus:       pciAddrs.Bus,
+			Addr:      addr,
+			WCE:       true,
+			ReadOnly:  v.ReadOnly,
+			ShareRW:   v.Shared,
+			IOPS:      int64(cfg.DiskIOPS),
+			BPS:       cfg.diskBPS(),
+		})
+	}
+
+	for i, d := range cfg.EphemeralDisks {
+		addr, err := pciAddrs.Next()
+		if err != nil {
+			return qemu.Config{}, err
+		}
+		devices = append(devices, qemu.BlockDevice{
+			Driver:    qemu.VirtioBlockPCI,
+			ID:        fmt.Sprintf("drive_local%d", i),
+			DeviceID:  fmt.Sprintf("device_local%d", i),
+			File:      d.Path,
+			Format:    qemu.RAW,
+			Interface: qemu.NoInterface,
+			Cache:     qemu.NoCache,
+			Bus:       pciAddrs.Bus,
+			Addr:      addr,
+			WCE:       true,
+			IOPS:      int64(cfg.DiskIOPS),
+			BPS:       cfg.diskBPS(),
+		})
+	}
+
+	devices = append(devices, qemu.CDROMDevice{
+		File:      isoPath,
+		Interface: qemu.VirtioInterface,
+	})
+
+	for _, netdev := range netdevs {
+		devices = append(devices, netdev)
+	}
+
+	// The netcat virtual console of debug builds is attached to the
+	// first serial port and logs its output itself.
+	if launchWithUI.String() != "nc" {
+		devices = append(devices, consoleDevice(instanceDir))
+	}
+
+	if cfg.GuestAgent {
+		devices = append(devices, guestAgentDevices(instanceDir)...)
+	}
+
+	config := qemu.Config{
+		Devices: devices,
+		QMPSockets: []qemu.QMPSocket{
+			{
+				Type:   qemu.Unix,
+				Name:   path.Join(instanceDir, "socket"),
+				Server: true,
+				NoWait: true,
+			},
+		},
+		// qemu.Config always passes -rtc, so spell out qemu's
+		// default base.
+		RTC: qemu.RTC{
+			Base: qemu.UTC,
+		},
+		Knobs: qemu.Knobs{
+			Daemonize: true,
+
+			// Leave memory locking to qemu's defaults.  Without
+			// this the qemu package passes -realtime mlock=off.
+			Mlock: true,
+		},
 	}
 
-	isoParam := fmt.Sprintf("file=%s,if=virtio,media=cdrom", isoPath)
-	params = append(params, "-drive", isoParam)
-
-	params = append(params, networkParams...)
-
 	useKvm := true
 
 	switch qemuVirtualisation {
@@ -325,68 +429,103 @@ func generateQEMULaunchParams(cfg *vmConfig, isoPath, instanceDir string,
 	}
 
 	if useKvm {
-		params = append(params, "-enable-kvm")
-		params = append(params, "-cpu", "host")
+		config.Machine = qemu.Machine{
+			Type:         "pc",
+			Acceleration: "kvm",
+		}
+		config.CPUModel = "host"
 	} else {
 		glog.Warning("Running qemu without kvm support")
 	}
 
-	params = append(params, "-daemonize")
-
-	qmpSocket := path.Join(instanceDir, "socket")
-	qmpParam := fmt.Sprintf("unix:%s,server,nowait", qmpSocket)
-	params = append(params, "-qmp", qmpParam)
-
 	if cfg.Mem > 0 {
-		memoryParam := fmt.Sprintf("%d", cfg.Mem)
-		params = append(params, "-m", memoryParam)
+		config.Memory.Size = fmt.Sprintf("%dM", cfg.Mem)
 	}
 	if cfg.Cpus > 0 {
-		cpusParam := fmt.Sprintf("cpus=%d", cfg.Cpus)
-		params = append(params, "-smp", cpusParam)
+		config.SMP.CPUs = uint32(cfg.Cpus)
+	}
+
+	// Instances with huge pages or NUMA local placement get a single
+	// guest NUMA node whose memory is bound to the host node chosen by
+	// the overseer.
+	if cfg.numaBound() && cfg.Mem > 0 {
+		node := qemu.NUMANode{
+			ID:        0,
+			Size:      fmt.Sprintf("%dM", cfg.Mem),
+			HostNodes: strconv.Itoa(cfg.HostNUMANode),
+		}
+		if cfg.HugePages {
+			node.MemPath = hugePagesMount
+			node.Prealloc = true
+		}
+		config.NUMANodes = []qemu.NUMANode{node}
 	}
 
 	if !cfg.Legacy {
-		params = append(params, "-bios", qemuEfiFw)
+		config.Bios = qemuEfiFw
 	}
-	return params
+	return config, nil
 }
 
-func (q *qemuV) startVM(vnicName, ipAddress, cephID string) error {
+func (q *qemuV) startVM(vnicNames []string, ipAddress, cephID string) error {
+	return q.launchVM(vnicNames, ipAddress, cephID, nil)
+}
 
-	var fds []*os.File
+func (q *qemuV) resumeVM(vnicNames []string, ipAddress, cephID, stateFile string) error {
+	f, err := os.Open(stateFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
 
-	glog.Info("Launching qemu")
+	return q.launchVM(vnicNames, ipAddress, cephID, f)
+}
+
+// launchVM boots a new qemu process for the instance.  If incoming is not
+// nil the state of the VM is restored from it rather than being booted.
+func (q *qemuV) launchVM(vnicNames []string, ipAddress, cephID string, incoming *os.File) error {
 
-	networkParams := make([]string, 0, 32)
+	var netdevs []qemu.NetDevice
+
+	glog.Info("Launching qemu")
 
-	if vnicName != "" {
+	if len(vnicNames) > 0 {
 		if q.cfg.NetworkNode {
-			var err error
-			var macvtapParam []string
 			//TODO: @mcastelino get from scheduler/controller
 			numQueues := 4
-			macvtapParam, fds, err = computeMacvtapParam(vnicName, q.cfg.VnicMAC, numQueues)
+			netdev, err := computeMacvtapDevice(vnicNames[0], q.cfg.VnicMAC, numQueues)
 			if err != nil {
 				return err
 			}
-			defer cleanupFds(fds, len(fds))
-			networkParams = append(networkParams, macvtapParam...)
+			defer cleanupFds(netdev.FDs, len(netdev.FDs))
+			netdevs = append(netdevs, netdev)
 		} else {
-			tapParam, err := computeTapParam(vnicName, q.cfg.VnicMAC)
-			if err != nil {
-				return err
+			nics := q.cfg.nics()
+			if len(nics) != len(vnicNames) {
+				return fmt.Errorf("Expected %d vnics, found %d",
+					len(nics), len(vnicNames))
+			}
+			for i, vnicName := range vnicNames {
+				netdevs = append(netdevs,
+					computeTapDevice(vnicName, nics[i].VnicMAC))
 			}
-			networkParams = append(networkParams, tapParam...)
 		}
 	} else {
-		networkParams = append(networkParams, "-net", "nic,model=virtio")
-		networkParams = append(networkParams, "-net", "user")
+		netdevs = append(netdevs, qemu.NetDevice{
+			Type: qemu.USER,
+			ID:   "net0",
+		})
+	}
+
+	config, err := generateQEMUConfig(q.cfg, q.isoPath, q.instanceDir, netdevs, cephID)
+	if err != nil {
+		return err
 	}
+	config.Incoming = incoming
 
-	params := generateQEMULaunchParams(q.cfg, q.isoPath, q.instanceDir, networkParams, cephID)
+	params, fds := config.QemuParams()
 
-	var err error
+	q.startConsole()
 
 	if !launchWithUI.Enabled() {
 		params = append(params, "-display", "none", "-vga", "none")
@@ -399,13 +538,14 @@ func (q *qemuV) startVM(vnicName, ipAddress, cephID string) error {
 		}
 	} else {
 		var port int
-		port, err = launchQemuWithNC(params, fds, ipAddress)
+		port, err = launchQemuWithNC(params, fds, ipAddress, q.instanceDir)
 		if err == nil {
 			q.vcPort = port
 		}
 	}
 
 	if err != nil {
+		q.stopConsole()
 		return err
 	}
 
@@ -422,40 +562,264 @@ func (q *qemuV) lostVM() {
 	}
 	q.pid = 0
 	q.prevCPUTime = -1
+	q.blockIO.set(nil)
+	q.stopConsole()
 }
 
-func qmpAttach(cmd virtualizerAttachCmd, q *qemu.QMP) {
-	glog.Info("Attach command received")
+// startConsole starts logging the serial console of the VM, unless it is
+// already being logged or is served by netcat.  It is called before qemu
+// is launched so that the console logger is listening when qemu connects
+// to it.
+func (q *qemuV) startConsole() {
+	if q.console != nil || launchWithUI.String() == "nc" {
+		return
+	}
+
+	cl, err := startConsoleLogger(q.cfg.Instance, q.instanceDir)
+	if err != nil {
+		glog.Warningf("Unable to log console of %s: %v", q.cfg.Instance, err)
+		return
+	}
+	q.console = cl
+}
+
+func (q *qemuV) stopConsole() {
+	if q.console != nil {
+		q.console.stop()
+		q.console = nil
+	}
+}
 
-	// Versions of qemu 2.9 and greater have a 31 byte limit on the size of
-	// IDs used to identify block devices.  We form our ID by appending the
-	// the volumeUUID with the '-'s and the final 3 characters removed, to the
-	// constant string "d_".  Drive names are not allowed to start with numbers.
+func (q *qemuV) attachConsole() (io.ReadWriteCloser, error) {
+	if launchWithUI.String() == "nc" {
+		return nil, fmt.Errorf("console is served by netcat")
+	}
 
-	blockdevID := fmt.Sprintf("d_%s", strings.Replace(cmd.volumeUUID, "-", "", -1))
+	return net.Dial("unix", path.Join(q.instanceDir, consoleAttachSocket))
+}
+
+// Versions of qemu 2.9 and greater have a 31 byte limit on the size of
+// IDs used to identify block devices.  We form our ID by appending the
+// the volumeUUID with the '-'s and the final 3 characters removed, to the
+// constant string "d_".  Drive names are not allowed to start with numbers.
+func hotplugBlockdevID(volumeUUID string) string {
+	blockdevID := fmt.Sprintf("d_%s", strings.Replace(volumeUUID, "-", "", -1))
 	if len(blockdevID) > 31 {
 		blockdevID = blockdevID[:31]
 	}
-	err := q.ExecuteBlockdevAdd(context.Background(), cmd.device, blockdevID)
+	return blockdevID
+}
+
+func qmpAttach(cmd virtualizerAttachCmd, q *qemu.QMP) {
+	glog.Info("Attach command received")
+
+	blockdevID := hotplugBlockdevID(cmd.volumeUUID)
+	err := q.ExecuteBlockdevAddWithOptions(context.Background(), cmd.device, blockdevID,
+		cmd.readOnly)
 	if err != nil {
 		glog.Errorf("Failed to execute blockdev-add: %v", err)
 	} else {
 		devID := fmt.Sprintf("device_%s", cmd.volumeUUID)
-		err = q.ExecuteDeviceAdd(context.Background(), blockdevID,
-			devID, "virtio-blk-pci", "")
+		err = q.ExecuteDeviceAddWithOptions(context.Background(), blockdevID,
+			devID, "virtio-blk-pci", "", cmd.shared)
 		if err != nil {
 			glog.Errorf("Failed to execute device_add: %v", err)
 			if err := q.ExecuteBlockdevDel(context.Background(), blockdevID); err != nil {
 				glog.Warningf("Failed to remove block device : %v", err)
 			}
+		} else if cmd.iops > 0 || cmd.bps > 0 {
+			err = q.ExecuteBlockSetIOThrottle(context.Background(),
+				devID, cmd.bps, cmd.iops)
+			if err != nil {
+				// Rather than leave the volume attached without the
+				// limits of the workload, the attach fails.
+				glog.Errorf("Failed to throttle %s: %v", cmd.volumeUUID, err)
+				qmpDetachHotplugged(q, devID, blockdevID)
+			}
 		}
 	}
 	cmd.responseCh <- err
 }
 
-func qmpConnect(qmpChannel chan interface{}, instance, instanceDir string, closedCh chan struct{},
-	connectedCh chan struct{}, wg *sync.WaitGroup, boot bool) {
+func qmpDetachHotplugged(q *qemu.QMP, devID, blockdevID string) {
+	ctx, cancelFN := context.WithTimeout(context.Background(), time.Second*30)
+	err := q.ExecuteDeviceDel(ctx, devID)
+	cancelFN()
+	if err != nil {
+		glog.Warningf("Failed to remove device %s: %v", devID, err)
+		return
+	}
 
+	if err := q.ExecuteBlockdevDel(context.Background(), blockdevID); err != nil {
+		glog.Warningf("Unable to remove block device %s: %v", blockdevID, err)
+	}
+}
+
+func qmpDetach(cmd virtualizerDetachCmd, q *qemu.QMP) {
+	glog.Info("Detach command received")
+
+	devID := fmt.Sprintf("device_%s", cmd.volumeUUID)
+	ctx, cancelFN := context.WithTimeout(context.Background(), time.Second*30)
+	err := q.ExecuteDeviceDel(ctx, devID)
+	cancelFN()
+	if err != nil {
+		glog.Errorf("Failed to execute device_del: %v", err)
+		cmd.responseCh <- err
+		return
+	}
+
+	// Volumes specified in the START payload are added with -drive and
+	// their backends are released by QEMU along with the device.  Only
+	// the backends of hot plugged volumes need to be deleted explicitly.
+
+	blockdevID := hotplugBlockdevID(cmd.volumeUUID)
+	if err := q.ExecuteBlockdevDel(context.Background(), blockdevID); err != nil {
+		glog.Warningf("Unable to remove block device %s: %v", blockdevID, err)
+	}
+	cmd.responseCh <- nil
+}
+
+// qmpStop sends an ACPI power down request to the VM and waits for the
+// guest to shut down.  If the guest does not shut down within the timeout
+// given in cmd, qemu is asked to quit and, should that fail, killed.
+func qmpStop(cmd virtualizerStopCmd, q *qemu.QMP, instanceDir string,
+	closedCh chan struct{}) payloads.StopMethod {
+	ctx, cancelFN := context.WithTimeout(context.Background(), cmd.timeout)
+	err := q.ExecuteSystemPowerdown(ctx)
+	cancelFN()
+	if err == nil {
+		return payloads.StopGraceful
+	}
+
+	glog.Warningf("Failed to power down cleanly: %v", err)
+	ctx, cancelFN = context.WithTimeout(context.Background(), time.Second*10)
+	err = q.ExecuteQuit(ctx)
+	cancelFN()
+	if err == nil {
+		return payloads.StopForced
+	}
+
+	glog.Warningf("Failed to execute quit instance: %v", err)
+	killQemu(instanceDir, closedCh)
+	return payloads.StopForced
+}
+
+// killQemu kills the qemu process of the instance stored in instanceDir.
+func killQemu(instanceDir string, closedCh chan struct{}) {
+
+	// There's no need to kill qemu if we've lost the connection to
+	// QMP because it has already exited.
+
+	select {
+	case <-closedCh:
+		return
+	default:
+	}
+
+	pid := qemuPID(instanceDir)
+	if pid == 0 {
+		glog.Errorf("Unable to determine pid of qemu for %s", instanceDir)
+		return
+	}
+
+	glog.Warningf("Killing qemu process %d", pid)
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil {
+		glog.Errorf("Unable to kill qemu process %d: %v", pid, err)
+	}
+}
+
+// qmpSuspend saves the state of the VM to cmd.stateFile using a migration
+// and then asks qemu to quit.  The state is written to a temporary file
+// first so that a partially saved VM is never mistaken for a suspended one.
+// The VM keeps running until the migration completes and so it will be
+// running when it is restored.  qemu is killed if it refuses to quit as
+// the instance go routine waits for it to exit.
+func qmpSuspend(cmd virtualizerSuspendCmd, q *qemu.QMP, instanceDir string,
+	closedCh chan struct{}) {
+	glog.Info("Suspend command received")
+
+	tmpFile := cmd.stateFile + ".tmp"
+	err := q.ExecuteMigrate(context.Background(), fmt.Sprintf("exec:cat > %s", tmpFile))
+	if err != nil {
+		glog.Errorf("Failed to execute migrate: %v", err)
+		cmd.responseCh <- err
+		return
+	}
+
+	err = qmpWaitForMigration(q, suspendTimeout)
+	if err == nil {
+		err = os.Rename(tmpFile, cmd.stateFile)
+	}
+	if err != nil {
+		glog.Errorf("Unable to save state of VM: %v", err)
+		_ = os.Remove(tmpFile)
+		if err := q.ExecuteCont(context.Background()); err != nil {
+			glog.Warningf("Unable to restart VM: %v", err)
+		}
+		cmd.responseCh <- err
+		return
+	}
+
+	ctx, cancelFN := context.WithTimeout(context.Background(), time.Second*10)
+	err = q.ExecuteQuit(ctx)
+	cancelFN()
+	if err != nil {
+		glog.Warningf("Failed to execute quit instance: %v", err)
+		killQemu(instanceDir, closedCh)
+	}
+	cmd.responseCh <- nil
+}
+
+// qmpWaitForMigration polls qemu until the current migration has finished
+// or timeout has expired.
+func qmpWaitForMigration(q *qemu.QMP, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := q.ExecuteQueryMigrate(context.Background())
+		if err != nil {
+			return err
+		}
+
+		switch info.Status {
+		case "completed":
+			return nil
+		case "failed", "cancelled":
+			return fmt.Errorf("migration %s: %s", info.Status, info.ErrorDesc)
+		}
+
+		if time.Now().After(deadline) {
+			_ = q.ExecuteMigrateCancel(context.Background())
+			return fmt.Errorf("migration timed out")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// qmpQueryBlockStats retrieves the I/O counters of all the drives of a VM
+// and stores their sum in blockIO.
+func qmpQueryBlockStats(q *qemu.QMP, blockIO *qmpBlockStats) {
+	drives, err := q.ExecuteQueryBlockstats(context.Background())
+	if err != nil {
+		glog.Warningf("Failed to execute query-blockstats: %v", err)
+		blockIO.set(nil)
+		return
+	}
+
+	stats := &payloads.BlockIOStat{}
+	for _, d := range drives {
+		stats.ReadBytes += d.Stats.RdBytes
+		stats.WriteBytes += d.Stats.WrBytes
+		stats.ReadOps += d.Stats.RdOperations
+		stats.WriteOps += d.Stats.WrOperations
+	}
+	blockIO.set(stats)
+}
+
+func qmpConnect(qmpChannel chan interface{}, vmCfg *vmConfig, instanceDir string, guest *guestAgent,
+	blockIO *qmpBlockStats, closedCh chan struct{}, connectedCh chan struct{},
+	wg *sync.WaitGroup, boot bool) {
+
+	instance := vmCfg.Instance
 	var q *qemu.QMP
 	defer func() {
 		if q != nil {
@@ -483,28 +847,47 @@ func qmpConnect(qmpChannel chan interface{}, instance, instanceDir string, close
 		return
 	}
 
+	if err = pinVCPUs(q, vmCfg, qemuPID(instanceDir)); err != nil {
+		glog.Warningf("Unable to pin VCPUs of %s: %v", instance, err)
+	}
+
+	if guest != nil {
+		guest.start()
+		defer guest.stop()
+	}
+
 	close(connectedCh)
 
+	qmpQueryBlockStats(q, blockIO)
+	ticker := time.NewTicker(time.Second * resourcePeriod)
+	defer ticker.Stop()
+
 DONE:
 	for {
-		cmd, ok := <-qmpChannel
-		if !ok {
-			break DONE
+		var cmd interface{}
+		var ok bool
+		select {
+		case cmd, ok = <-qmpChannel:
+			if !ok {
+				break DONE
+			}
+		case <-ticker.C:
+			qmpQueryBlockStats(q, blockIO)
+			continue
 		}
 		switch cmd := cmd.(type) {
 		case virtualizerStopCmd:
-			ctx, cancelFN := context.WithTimeout(context.Background(), time.Second*10)
-			err = q.ExecuteSystemPowerdown(ctx)
-			cancelFN()
-			if err != nil {
-				glog.Warningf("Failed to power down cleanly: %v", err)
-				err = q.ExecuteQuit(context.Background())
-				if err != nil {
-					glog.Warningf("Failed to execute quit instance: %v", err)
-				}
-			}
+			cmd.stopped(qmpStop(cmd, q, instanceDir, closedCh))
 		case virtualizerAttachCmd:
 			qmpAttach(cmd, q)
+		case virtualizerDetachCmd:
+			qmpDetach(cmd, q)
+		case virtualizerPauseCmd:
+			cmd.responseCh <- q.ExecuteStop(context.Background())
+		case virtualizerUnpauseCmd:
+			cmd.responseCh <- q.ExecuteCont(context.Background())
+		case virtualizerSuspendCmd:
+			qmpSuspend(cmd, q, instanceDir, closedCh)
 		}
 	}
 }
@@ -519,9 +902,14 @@ DONE:
 
 func (q *qemuV) monitorVM(closedCh chan struct{}, connectedCh chan struct{},
 	wg *sync.WaitGroup, boot bool) chan interface{} {
+	// qemu reconnects to the console logger of a VM that was running
+	// before the launcher was restarted.
+	q.startConsole()
+
 	qmpChannel := make(chan interface{})
 	wg.Add(1)
-	go qmpConnect(qmpChannel, q.cfg.Instance, q.instanceDir, closedCh, connectedCh, wg, boot)
+	go qmpConnect(qmpChannel, q.cfg, q.instanceDir, q.guest, &q.blockIO, closedCh,
+		connectedCh, wg, boot)
 	return qmpChannel
 }
 
@@ -539,6 +927,8 @@ func (q *qemuV) stats() (disk, memory, cpu int) {
 		return
 	}
 
+	disk = ephemeralDisksUsageMB(q.cfg)
+
 	cpuTime := computeProcessCPUTime(q.pid)
 	now := time.Now()
 	if q.prevCPUTime != -1 {
@@ -557,15 +947,28 @@ func (q *qemuV) stats() (disk, memory, cpu int) {
 	return
 }
 
-func (q *qemuV) connected() {
-	qmpSocket := path.Join(q.instanceDir, "socket")
+func (q *qemuV) guestInfo() *payloads.GuestInfo {
+	if q.guest == nil {
+		return nil
+	}
+	return q.guest.guestInfo()
+}
+
+func (q *qemuV) blockStats() *payloads.BlockIOStat {
+	return q.blockIO.get()
+}
+
+// qemuPID returns the pid of the qemu process that has the QMP socket of an
+// instance open, or 0 if it cannot be determined.
+func qemuPID(instanceDir string) int {
+	qmpSocket := path.Join(instanceDir, "socket")
 	var buf bytes.Buffer
 	cmd := exec.Command("fuser", qmpSocket)
 	cmd.Stdout = &buf
 	err := cmd.Run()
 	if err != nil {
 		glog.Errorf("Failed to run fuser: %v", err)
-		return
+		return 0
 	}
 
 	scanner := bufio.NewScanner(&buf)
@@ -577,14 +980,19 @@ func (q *qemuV) connected() {
 		}
 
 		if pid != 0 && pid != os.Getpid() {
-			glog.Infof("PID of qemu for instance %s is %d", q.instanceDir, pid)
-			q.pid = pid
-			break
+			return pid
 		}
 	}
 
+	return 0
+}
+
+func (q *qemuV) connected() {
+	q.pid = qemuPID(q.instanceDir)
 	if q.pid == 0 {
 		glog.Errorf("Unable to determine pid for %s", q.instanceDir)
+	} else {
+		glog.Infof("PID of qemu for instance %s is %d", q.instanceDir, q.pid)
 	}
 	q.prevCPUTime = -1
 }
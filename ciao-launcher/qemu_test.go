@@ -23,68 +23,230 @@ import (
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/qemu"
 )
 
-func genQEMUParams(networkParams []string) []string {
+var userNetdev = qemu.NetDevice{
+	Type: qemu.USER,
+	ID:   "net0",
+}
+
+func genQEMUParams(resourceParams, blockParams, networkParams []string) []string {
 	baseParams := []string{
-		"-drive",
-		"file=/var/lib/ciao/instance/1/seed.iso,if=virtio,media=cdrom",
+		"-machine", "pc,accel=kvm", "-cpu", "host",
+		"-qmp", "unix:/var/lib/ciao/instance/1/socket,server,nowait",
+	}
+	baseParams = append(baseParams, resourceParams...)
+	baseParams = append(baseParams, blockParams...)
+	baseParams = append(baseParams, "-drive",
+		"file=/var/lib/ciao/instance/1/seed.iso,if=virtio,media=cdrom")
+	if networkParams == nil {
+		networkParams = []string{"-netdev", "user,id=net0",
+			"-device", "driver=virtio-net-pci,netdev=net0"}
 	}
 	baseParams = append(baseParams, networkParams...)
-	baseParams = append(baseParams, "-enable-kvm", "-cpu", "host", "-daemonize",
-		"-qmp", "unix:/var/lib/ciao/instance/1/socket,server,nowait")
+	baseParams = append(baseParams,
+		"-device", "isa-serial,chardev=console0,id=serial0",
+		"-chardev", "socket,id=console0,path=/var/lib/ciao/instance/1/console.sock,reconnect=1",
+		"-rtc", "base=utc",
+		"-daemonize")
 
 	return baseParams
 }
 
-func TestGenerateQEMULaunchParams(t *testing.T) {
+func checkQEMUConfig(t *testing.T, cfg *vmConfig, netdev qemu.NetDevice, params []string) {
+	checkQEMUConfigNetdevs(t, cfg, []qemu.NetDevice{netdev}, params)
+}
+
+func checkQEMUConfigNetdevs(t *testing.T, cfg *vmConfig, netdevs []qemu.NetDevice, params []string) {
+	config, err := generateQEMUConfig(cfg, "/var/lib/ciao/instance/1/seed.iso",
+		"/var/lib/ciao/instance/1", netdevs, "ciao")
+	if err != nil {
+		t.Fatalf("Unable to generate qemu config: %v", err)
+	}
+
+	genParams, _ := config.QemuParams()
+	if !reflect.DeepEqual(params, genParams) {
+		t.Fatalf("%s and %s do not match", params, genParams)
+	}
+}
+
+func TestGenerateQEMUConfig(t *testing.T) {
 	var cfg vmConfig
 
-	params := genQEMUParams(nil)
+	params := genQEMUParams(nil, nil, nil)
 	cfg.Legacy = false
 	cfg.Mem = 0
 	cfg.Cpus = 0
 	params = append(params, "-bios", qemuEfiFw)
-	genParams := generateQEMULaunchParams(&cfg, "/var/lib/ciao/instance/1/seed.iso",
-		"/var/lib/ciao/instance/1", nil, "ciao")
-	if !reflect.DeepEqual(params, genParams) {
-		t.Fatalf("%s and %s do not match", params, genParams)
-	}
+	checkQEMUConfig(t, &cfg, userNetdev, params)
 
-	params = genQEMUParams(nil)
+	params = genQEMUParams([]string{"-m", "100M"}, nil, nil)
 	cfg.Mem = 100
 	cfg.Cpus = 0
 	cfg.Legacy = true
-	params = append(params, "-m", "100")
-	genParams = generateQEMULaunchParams(&cfg, "/var/lib/ciao/instance/1/seed.iso",
-		"/var/lib/ciao/instance/1", nil, "ciao")
-	if !reflect.DeepEqual(params, genParams) {
-		t.Fatalf("%s and %s do not match", params, genParams)
-	}
+	checkQEMUConfig(t, &cfg, userNetdev, params)
 
-	params = genQEMUParams(nil)
+	params = genQEMUParams([]string{"-smp", "4"}, nil, nil)
 	cfg.Mem = 0
 	cfg.Cpus = 4
 	cfg.Legacy = true
-	params = append(params, "-smp", "cpus=4")
-	genParams = generateQEMULaunchParams(&cfg, "/var/lib/ciao/instance/1/seed.iso",
-		"/var/lib/ciao/instance/1", nil, "ciao")
-	if !reflect.DeepEqual(params, genParams) {
-		t.Fatalf("%s and %s do not match", params, genParams)
-	}
+	checkQEMUConfig(t, &cfg, userNetdev, params)
 
-	netParams := []string{"-net", "nic,model=virtio", "-net", "user"}
-	params = genQEMUParams(netParams)
+	netParams := []string{
+		"-netdev", "tap,id=ciao_vnic0,vhost=on,ifname=ciao_vnic0,downscript=no,script=no",
+		"-device", "driver=virtio-net-pci,netdev=ciao_vnic0,mac=02:00:e6:f5:af:f9",
+	}
+	params = genQEMUParams(nil, nil, netParams)
 	cfg.Mem = 0
 	cfg.Cpus = 0
 	cfg.Legacy = true
-	genParams = generateQEMULaunchParams(&cfg, "/var/lib/ciao/instance/1/seed.iso",
-		"/var/lib/ciao/instance/1", netParams, "ciao")
-	if !reflect.DeepEqual(params, genParams) {
-		t.Fatalf("%s and %s do not match", params, genParams)
+	checkQEMUConfig(t, &cfg, computeTapDevice("ciao_vnic0", "02:00:e6:f5:af:f9"), params)
+
+	volume := "e8ce2c59-7a4f-4e9f-8ac7-6b6c6b2f9b3a"
+	blockParams := []string{
+		"-device",
+		fmt.Sprintf("virtio-blk-pci,drive=drive_%s,scsi=off,share-rw=on,id=device_%s,bus=pci.0,addr=3",
+			volume, volume),
+		"-drive",
+		fmt.Sprintf("id=drive_%s,file=rbd:rbd/%s:id=ciao,format=raw,if=none,readonly=on",
+			volume, volume),
+	}
+	params = genQEMUParams(nil, blockParams, nil)
+	cfg.Volumes = []volumeConfig{{UUID: volume, ReadOnly: true, Shared: true}}
+	checkQEMUConfig(t, &cfg, userNetdev, params)
+
+	disk := "/var/lib/ciao/ephemeral/1/disk-0.raw"
+	blockParams = append(blockParams,
+		"-device",
+		"virtio-blk-pci,drive=drive_local0,scsi=off,id=device_local0,bus=pci.0,addr=4",
+		"-drive",
+		fmt.Sprintf("id=drive_local0,file=%s,format=raw,if=none,cache=none", disk),
+	)
+	params = genQEMUParams(nil, blockParams, nil)
+	cfg.EphemeralDisks = []ephemeralDiskConfig{{SizeMB: 1024, Path: disk}}
+	checkQEMUConfig(t, &cfg, userNetdev, params)
+}
+
+// Checks the network devices of instances with more than one NIC.
+//
+// Generate the configuration of an instance with two tap vnics.
+//
+// A virtio-net device with the MAC address of its NIC should be created
+// for each vnic, in order.
+func TestGenerateQEMUConfigMultipleNICs(t *testing.T) {
+	cfg := vmConfig{
+		Legacy: true,
+	}
+	netParams := []string{
+		"-netdev", "tap,id=ciao_vnic0,vhost=on,ifname=ciao_vnic0,downscript=no,script=no",
+		"-device", "driver=virtio-net-pci,netdev=ciao_vnic0,mac=02:00:e6:f5:af:f9",
+		"-netdev", "tap,id=ciao_vnic1,vhost=on,ifname=ciao_vnic1,downscript=no,script=no",
+		"-device", "driver=virtio-net-pci,netdev=ciao_vnic1,mac=02:00:0a:0a:00:02",
+	}
+	params := genQEMUParams(nil, nil, netParams)
+	netdevs := []qemu.NetDevice{
+		computeTapDevice("ciao_vnic0", "02:00:e6:f5:af:f9"),
+		computeTapDevice("ciao_vnic1", "02:00:0a:0a:00:02"),
+	}
+	checkQEMUConfigNetdevs(t, &cfg, netdevs, params)
+}
+
+// Checks the NUMA configuration of instances backed by huge pages.
+//
+// Generate the configuration of an instance with huge pages bound to
+// host NUMA node 1.
+//
+// A single guest NUMA node whose memory is preallocated from the huge pages
+// of host node 1 should be created.
+func TestGenerateQEMUConfigHugePages(t *testing.T) {
+	cfg := vmConfig{
+		Mem:          512,
+		Cpus:         2,
+		Legacy:       true,
+		HugePages:    true,
+		HostNUMANode: 1,
+	}
+	params := genQEMUParams([]string{
+		"-m", "512M",
+		"-smp", "2",
+		"-object", "memory-backend-file,id=ram-node0,size=512M,mem-path=/dev/hugepages,share=on,prealloc=on,host-nodes=1,policy=bind",
+		"-numa", "node,nodeid=0,memdev=ram-node0",
+	}, nil, nil)
+	checkQEMUConfig(t, &cfg, userNetdev, params)
+}
+
+// Checks the devices of instances with a guest agent channel.
+//
+// Generate the configuration of an instance that requests a guest agent.
+//
+// A virtio-serial controller and a port named after the guest agent channel
+// connected to a domain socket in the instance directory should be added.
+func TestGenerateQEMUConfigGuestAgent(t *testing.T) {
+	cfg := vmConfig{
+		Legacy:     true,
+		GuestAgent: true,
+	}
+	params := genQEMUParams(nil, nil, nil)
+	params = append(params[:len(params)-3],
+		"-device", "virtio-serial-pci,id=virtio-serial0",
+		"-device", "virtserialport,chardev=qga0,id=channel0,name=org.qemu.guest_agent.0",
+		"-chardev", "socket,id=qga0,path=/var/lib/ciao/instance/1/qga.sock,server,nowait",
+		"-rtc", "base=utc",
+		"-daemonize")
+	checkQEMUConfig(t, &cfg, userNetdev, params)
+}
+
+// Checks the drives of instances with disk I/O limits.
+//
+// Generate the configuration of an instance with a volume and a local disk
+// whose IOPS and throughput are limited.
+//
+// The throttling options of both drives should be set.
+func TestGenerateQEMUConfigDiskLimits(t *testing.T) {
+	volume := "e8ce2c59-7a4f-4e9f-8ac7-6b6c6b2f9b3a"
+	disk := "/var/lib/ciao/ephemeral/1/disk-0.raw"
+	cfg := vmConfig{
+		Legacy:         true,
+		DiskIOPS:       500,
+		DiskMBps:       20,
+		Volumes:        []volumeConfig{{UUID: volume}},
+		EphemeralDisks: []ephemeralDiskConfig{{SizeMB: 1024, Path: disk}},
+	}
+	blockParams := []string{
+		"-device",
+		fmt.Sprintf("virtio-blk-pci,drive=drive_%s,scsi=off,id=device_%s,bus=pci.0,addr=3",
+			volume, volume),
+		"-drive",
+		fmt.Sprintf("id=drive_%s,file=rbd:rbd/%s:id=ciao,format=raw,if=none,throttling.iops-total=500,throttling.bps-total=20000000",
+			volume, volume),
+		"-device",
+		"virtio-blk-pci,drive=drive_local0,scsi=off,id=device_local0,bus=pci.0,addr=4",
+		"-drive",
+		fmt.Sprintf("id=drive_local0,file=%s,format=raw,if=none,cache=none,throttling.iops-total=500,throttling.bps-total=20000000", disk),
+	}
+	params := genQEMUParams(nil, blockParams, nil)
+	checkQEMUConfig(t, &cfg, userNetdev, params)
+}
+
+func TestGenerateQEMUConfigTooManyDrives(t *testing.T) {
+	var cfg vmConfig
+
+	for i := 0; i < 32; i++ {
+		cfg.EphemeralDisks = append(cfg.EphemeralDisks,
+			ephemeralDiskConfig{SizeMB: 1, Path: fmt.Sprintf("/tmp/disk-%d.raw", i)})
+	}
+
+	_, err := generateQEMUConfig(&cfg, "/var/lib/ciao/instance/1/seed.iso",
+		"/var/lib/ciao/instance/1", []qemu.NetDevice{userNetdev}, "ciao")
+	if err == nil {
+		t.Fatalf("Expected generateQEMUConfig to fail when PCI bus is full")
 	}
 }
 
@@ -97,7 +259,8 @@ func TestQmpConnectBadSocket(t *testing.T) {
 	instanceDir := path.Join("/tmp", instance)
 
 	wg.Add(1)
-	go qmpConnect(qmpChannel, instance, instanceDir, closedCh, connectedCh, &wg, false)
+	go qmpConnect(qmpChannel, &vmConfig{Instance: instance}, instanceDir, nil,
+		&qmpBlockStats{}, closedCh, connectedCh, &wg, false)
 	wg.Wait()
 	select {
 	case <-closedCh:
@@ -129,7 +292,9 @@ func setupQmpSocket(t *testing.T, runTest func(net.Conn, *bufio.Scanner, chan in
 	}
 	defer ln.Close()
 	wg.Add(1)
-	go qmpConnect(qmpChannel, instance, instanceDir, closedCh, connectedCh, &wg, false)
+	var blockIO qmpBlockStats
+	go qmpConnect(qmpChannel, &vmConfig{Instance: instance}, instanceDir, nil,
+		&blockIO, closedCh, connectedCh, &wg, false)
 	fd, err := ln.Accept()
 	if err != nil {
 		t.Fatalf("Unable to accept client %v", err)
@@ -162,6 +327,17 @@ func setupQmpSocket(t *testing.T, runTest func(net.Conn, *bufio.Scanner, chan in
 		t.Fatalf("Timed out waiting for connectedCh to close")
 	}
 
+	if !sc.Scan() || !strings.Contains(sc.Text(), "query-blockstats") {
+		fd.Close()
+		t.Fatalf("query-blockstats command expected")
+	}
+
+	_, err = fmt.Fprintln(fd, `{ "return": [{ "device": "drive_0", "stats": { "rd_bytes": 4096, "wr_bytes": 8192, "rd_operations": 1, "wr_operations": 2}}]}`)
+	if err != nil {
+		fd.Close()
+		t.Fatalf("Unable to write to qmpChannel %v", err)
+	}
+
 	if runTest(fd, sc, qmpChannel, t) {
 		defer fd.Close()
 	}
@@ -172,6 +348,16 @@ func setupQmpSocket(t *testing.T, runTest func(net.Conn, *bufio.Scanner, chan in
 	case <-time.After(time.Second):
 		t.Fatalf("Timed out waiting for closedCh to close")
 	}
+
+	expected := payloads.BlockIOStat{
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		ReadOps:    1,
+		WriteOps:   2,
+	}
+	if stats := blockIO.get(); stats == nil || *stats != expected {
+		t.Errorf("Expected block stats %+v, got %+v", expected, stats)
+	}
 }
 
 func TestQmpConnect(t *testing.T) {
@@ -181,8 +367,9 @@ func TestQmpConnect(t *testing.T) {
 }
 
 func TestQmpShutdown(t *testing.T) {
+	methodCh := make(chan payloads.StopMethod, 1)
 	setupQmpSocket(t, func(fd net.Conn, sc *bufio.Scanner, qmpChannel chan interface{}, t *testing.T) bool {
-		qmpChannel <- virtualizerStopCmd{}
+		qmpChannel <- virtualizerStopCmd{time.Second, methodCh}
 		if !sc.Scan() {
 			t.Fatalf("power down command expected")
 		}
@@ -195,11 +382,16 @@ func TestQmpShutdown(t *testing.T) {
 
 		return true
 	})
+
+	if method := <-methodCh; method != payloads.StopGraceful {
+		t.Errorf("Expected graceful stop, got %s", method)
+	}
 }
 
 func TestQmpLost(t *testing.T) {
+	methodCh := make(chan payloads.StopMethod, 1)
 	setupQmpSocket(t, func(fd net.Conn, sc *bufio.Scanner, qmpChannel chan interface{}, t *testing.T) bool {
-		qmpChannel <- virtualizerStopCmd{}
+		qmpChannel <- virtualizerStopCmd{time.Second, methodCh}
 		if !sc.Scan() {
 			t.Fatalf("power down command expected")
 		}
@@ -207,4 +399,42 @@ func TestQmpLost(t *testing.T) {
 
 		return false
 	})
+
+	if method := <-methodCh; method != payloads.StopForced {
+		t.Errorf("Expected forced stop, got %s", method)
+	}
+}
+
+// Checks that instances that ignore ACPI power down requests are terminated.
+//
+// Send a stop command with a short timeout and acknowledge the
+// system_powerdown command without ever sending the SHUTDOWN event.
+//
+// A quit command should be received once the timeout expires and the stop
+// should be reported as forced.
+func TestQmpShutdownTimeout(t *testing.T) {
+	methodCh := make(chan payloads.StopMethod, 1)
+	setupQmpSocket(t, func(fd net.Conn, sc *bufio.Scanner, qmpChannel chan interface{}, t *testing.T) bool {
+		qmpChannel <- virtualizerStopCmd{100 * time.Millisecond, methodCh}
+		if !sc.Scan() {
+			t.Fatalf("power down command expected")
+		}
+		_, err := fmt.Fprintln(fd, `{ "return": {}}`)
+		if err != nil {
+			t.Fatalf("Unable to write to domain socket: %v", err)
+		}
+		if !sc.Scan() {
+			t.Fatalf("quit command expected")
+		}
+		_, err = fmt.Fprintln(fd, `{ "return": {}}`)
+		if err != nil {
+			t.Fatalf("Unable to write to domain socket: %v", err)
+		}
+
+		return true
+	})
+
+	if method := <-methodCh; method != payloads.StopForced {
+		t.Errorf("Expected forced stop, got %s", method)
+	}
 }
@@ -17,10 +17,13 @@
 package main
 
 import (
+	"io"
 	"math/rand"
+	"net"
 	"sync"
 	"time"
 
+	"github.com/ciao-project/ciao/payloads"
 	"github.com/golang/glog"
 )
 
@@ -75,7 +78,17 @@ VM:
 				s.monitorCh = nil
 				break VM
 			}
-			if _, stopCmd := cmd.(virtualizerStopCmd); stopCmd {
+			switch cmd := cmd.(type) {
+			case virtualizerStopCmd:
+				cmd.stopped(payloads.StopGraceful)
+				break VM
+			case virtualizerPauseCmd:
+				cmd.responseCh <- nil
+			case virtualizerUnpauseCmd:
+				cmd.responseCh <- nil
+			case virtualizerSuspendCmd:
+				cmd.responseCh <- nil
+				close(s.closedCh)
 				break VM
 			}
 		case <-s.killCh:
@@ -93,7 +106,7 @@ VM:
 
 }
 
-func (s *simulation) startVM(vnicName, ipAddress, cephID string) error {
+func (s *simulation) startVM(vnicNames []string, ipAddress, cephID string) error {
 	glog.Infof("startVM\n")
 
 	s.killCh = make(chan struct{})
@@ -101,6 +114,14 @@ func (s *simulation) startVM(vnicName, ipAddress, cephID string) error {
 	return nil
 }
 
+func (s *simulation) resumeVM(vnicNames []string, ipAddress, cephID, stateFile string) error {
+	glog.Infof("resumeVM\n")
+
+	s.killCh = make(chan struct{})
+
+	return nil
+}
+
 func (s *simulation) monitorVM(closedCh chan struct{}, connectedCh chan struct{}, wg *sync.WaitGroup, boot bool) chan interface{} {
 	glog.Infof("monitorVM\n")
 	s.closedCh = closedCh
@@ -118,6 +139,14 @@ func (s *simulation) stats() (disk, memory, cpu int) {
 	return s.disk / 10, s.mem / 10, s.cpus / 10
 }
 
+func (s *simulation) guestInfo() *payloads.GuestInfo {
+	return nil
+}
+
+func (s *simulation) blockStats() *payloads.BlockIOStat {
+	return nil
+}
+
 func (s *simulation) connected() {
 	glog.Infof("connected\n")
 }
@@ -125,3 +154,13 @@ func (s *simulation) connected() {
 func (s *simulation) lostVM() {
 	glog.Infof("simulation: lostVM\n")
 }
+
+// attachConsole returns a console that echoes its input.
+func (s *simulation) attachConsole() (io.ReadWriteCloser, error) {
+	console, vm := net.Pipe()
+	go func() {
+		_, _ = io.Copy(vm, vm)
+		_ = vm.Close()
+	}()
+	return console, nil
+}
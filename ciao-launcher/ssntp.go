@@ -17,6 +17,7 @@
 package main
 
 import (
+	"crypto/tls"
 	"sync"
 	"time"
 
@@ -33,6 +34,15 @@ type statusCmd struct{}
 type evacuateCmd struct{}
 type restoreCmd struct{}
 
+// updateConcentratorCmd is used to re-point the tunnels of a tenant subnet
+// to the CNCI that now serves it.  It is passed on to all the instances.
+type updateConcentratorCmd struct {
+	tenant   string
+	subnet   string
+	concUUID string
+	concIP   string
+}
+
 // serverConn is an abstract interface representing a connection to
 // a server.  It contains methods to connect to the server and to
 // send information to the server, such as commands or events.
@@ -77,6 +87,10 @@ func (s *ssntpConn) setStatus(status bool) {
 type agentClient struct {
 	conn  serverConn
 	cmdCh chan *cmdWrapper
+
+	// consoleTLS protects the console sessions opened for the
+	// controller.
+	consoleTLS *tls.Config
 }
 
 func (client *agentClient) DisconnectNotify() {
@@ -113,7 +127,7 @@ func (client *agentClient) CommandNotify(cmd ssntp.Command, frame *ssntp.Frame)
 		}
 		client.cmdCh <- &cmdWrapper{cfg.Instance, &insStartCmd{cn, md, frame, cfg, time.Now()}}
 	case ssntp.DELETE:
-		instance, stop, payloadErr := parseDeletePayload(payload)
+		instance, delCmd, payloadErr := parseDeletePayload(payload)
 		if payloadErr != nil {
 			deleteError := &deleteError{
 				payloadErr.err,
@@ -123,7 +137,7 @@ func (client *agentClient) CommandNotify(cmd ssntp.Command, frame *ssntp.Frame)
 			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
 			return
 		}
-		client.cmdCh <- &cmdWrapper{instance, &insDeleteCmd{stop: stop}}
+		client.cmdCh <- &cmdWrapper{instance, delCmd}
 	case ssntp.AttachVolume:
 		instance, volume, payloadErr := parseAttachVolumePayload(payload)
 		if payloadErr != nil {
@@ -135,11 +149,68 @@ func (client *agentClient) CommandNotify(cmd ssntp.Command, frame *ssntp.Frame)
 			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
 			return
 		}
-		client.cmdCh <- &cmdWrapper{instance, &insAttachVolumeCmd{volume}}
+		client.cmdCh <- &cmdWrapper{instance, &insAttachVolumeCmd{volume.UUID,
+			volume.ReadOnly, volume.Shared}}
+	case ssntp.DetachVolume:
+		instance, volume, payloadErr := parseDetachVolumePayload(payload)
+		if payloadErr != nil {
+			detachVolumeError := &detachVolumeError{
+				payloadErr.err,
+				payloads.DetachVolumeFailureReason(payloadErr.code),
+			}
+			detachVolumeError.send(client.conn, "", "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insDetachVolumeCmd{volume}}
+	case ssntp.GetConsoleLog:
+		instance, requestID, lines, payloadErr := parseGetConsoleLogPayload(payload)
+		if payloadErr != nil {
+			consoleLogError := &consoleLogError{
+				payloadErr.err,
+				payloads.ConsoleLogFailureReason(payloadErr.code),
+			}
+			consoleLogError.send(client.conn, instance, requestID)
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insConsoleLogCmd{lines, requestID}}
+	case ssntp.OpenConsole:
+		instance, token, payloadErr := parseOpenConsolePayload(payload)
+		if payloadErr != nil {
+			openConsoleError := &openConsoleError{
+				payloadErr.err,
+				payloads.OpenConsoleFailureReason(payloadErr.code),
+			}
+			openConsoleError.send(client.conn, instance, "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insOpenConsoleCmd{token}}
+	case ssntp.PauseInstance, ssntp.UnpauseInstance, ssntp.SuspendInstance,
+		ssntp.ResumeInstance:
+		instance, payloadErr := parsePausePayload(cmd, payload)
+		if payloadErr != nil {
+			pauseError := &pauseError{
+				payloadErr.err,
+				payloads.PauseFailureReason(payloadErr.code),
+			}
+			pauseError.send(client.conn, cmd, "")
+			glog.Errorf("Unable to parse YAML: %s", payloadErr.err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{instance, &insPauseCmd{cmd}}
 	case ssntp.EVACUATE:
 		client.cmdCh <- &cmdWrapper{"", &evacuateCmd{}}
 	case ssntp.Restore:
 		client.cmdCh <- &cmdWrapper{"", &restoreCmd{}}
+	case ssntp.UpdateConcentrator:
+		update, err := parseUpdateConcentratorPayload(payload)
+		if err != nil {
+			glog.Errorf("Unable to parse YAML: %s", err)
+			return
+		}
+		client.cmdCh <- &cmdWrapper{"", update}
 	}
 }
 
@@ -280,3 +280,132 @@ func TestAgentAttachVolume(t *testing.T) {
 
 	checkErrorPayload(t, &ac, state, ssntp.AttachVolume, ssntp.AttachVolumeFailure)
 }
+
+// Verify that the agentClient correctly processes ssntp.DetachVolume
+//
+// Send the ssntp.DetachVolume command to the agent client with a valid payload,
+// then send another ssntp.DetachVolume command with an invalid payload.
+//
+// The command with the valid payload should be processed correctly and a
+// insDetachVolumeCmd should be received on the agent's cmdCh.  The second
+// command with the invalid payload should result in a call to state.SendError.
+func TestAgentDetachVolume(t *testing.T) {
+	state := &ssntpTestState{}
+	cmdCh := make(chan *cmdWrapper)
+	ac := agentClient{conn: state, cmdCh: cmdCh}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		select {
+		case cmd := <-cmdCh:
+			if _, ok := cmd.cmd.(*insDetachVolumeCmd); !ok {
+				t.Errorf("Unexpected command received.  Expected detachVolumeCmd")
+			}
+			if cmd.instance != testutil.InstanceUUID {
+				t.Errorf("Unexpected instanced.  Expected %s found %s",
+					testutil.InstanceUUID, cmd.instance)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Timedout waiting for cmdCh")
+		}
+		wg.Done()
+	}()
+
+	frame := &ssntp.Frame{Payload: []byte(testutil.DetachVolumeYaml)}
+	ac.CommandNotify(ssntp.DetachVolume, frame)
+	wg.Wait()
+
+	checkErrorPayload(t, &ac, state, ssntp.DetachVolume, ssntp.DetachVolumeFailure)
+}
+
+// Verify that the agentClient correctly processes ssntp.GetConsoleLog
+//
+// Send the ssntp.GetConsoleLog command to the agent client with a valid payload,
+// then send another ssntp.GetConsoleLog command with an invalid payload.
+//
+// The command with the valid payload should be processed correctly and a
+// insConsoleLogCmd with the requested number of lines and the request ID
+// should be received on the agent's cmdCh.  The second command with the invalid payload should
+// result in a call to state.SendError.
+func TestAgentGetConsoleLog(t *testing.T) {
+	state := &ssntpTestState{}
+	cmdCh := make(chan *cmdWrapper)
+	ac := agentClient{conn: state, cmdCh: cmdCh}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		select {
+		case cmd := <-cmdCh:
+			clCmd, ok := cmd.cmd.(*insConsoleLogCmd)
+			if !ok {
+				t.Errorf("Unexpected command received.  Expected insConsoleLogCmd")
+			} else if clCmd.lines != 100 {
+				t.Errorf("Unexpected lines.  Expected 100 found %d", clCmd.lines)
+			} else if clCmd.requestID != testutil.ConsoleLogRequestID {
+				t.Errorf("Unexpected request ID.  Expected %s found %s",
+					testutil.ConsoleLogRequestID, clCmd.requestID)
+			}
+			if cmd.instance != testutil.InstanceUUID {
+				t.Errorf("Unexpected instanced.  Expected %s found %s",
+					testutil.InstanceUUID, cmd.instance)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Timedout waiting for cmdCh")
+		}
+		wg.Done()
+	}()
+
+	frame := &ssntp.Frame{Payload: []byte(testutil.GetConsoleLogYaml)}
+	ac.CommandNotify(ssntp.GetConsoleLog, frame)
+	wg.Wait()
+
+	checkErrorPayload(t, &ac, state, ssntp.GetConsoleLog, ssntp.ConsoleLogFailure)
+}
+
+// Verify that the agentClient correctly processes ssntp.OpenConsole
+//
+// Send the ssntp.OpenConsole command to the agent client with a valid payload,
+// then send another ssntp.OpenConsole command with an invalid payload.
+//
+// The command with the valid payload should be processed correctly and a
+// insOpenConsoleCmd with the controller's token should be received on the
+// agent's cmdCh.  The second command with the invalid payload should
+// result in a call to state.SendError.
+func TestAgentOpenConsole(t *testing.T) {
+	state := &ssntpTestState{}
+	cmdCh := make(chan *cmdWrapper)
+	ac := agentClient{conn: state, cmdCh: cmdCh}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		select {
+		case cmd := <-cmdCh:
+			ocCmd, ok := cmd.cmd.(*insOpenConsoleCmd)
+			if !ok {
+				t.Errorf("Unexpected command received.  Expected insOpenConsoleCmd")
+			} else if ocCmd.token != testutil.ConsoleToken {
+				t.Errorf("Unexpected token.  Expected %s found %s",
+					testutil.ConsoleToken, ocCmd.token)
+			}
+			if cmd.instance != testutil.InstanceUUID {
+				t.Errorf("Unexpected instanced.  Expected %s found %s",
+					testutil.InstanceUUID, cmd.instance)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("Timedout waiting for cmdCh")
+		}
+		wg.Done()
+	}()
+
+	frame := &ssntp.Frame{Payload: []byte(testutil.OpenConsoleYaml)}
+	ac.CommandNotify(ssntp.OpenConsole, frame)
+	wg.Wait()
+
+	checkErrorPayload(t, &ac, state, ssntp.OpenConsole, ssntp.OpenConsoleFailure)
+}
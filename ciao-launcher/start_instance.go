@@ -66,10 +66,10 @@ func createInstance(vm virtualizer, instanceDir string, cfg *vmConfig,
 
 func processStart(cmd *insStartCmd, instanceDir string, vm virtualizer, conn serverConn) (*startTimes, *startError) {
 	var err error
-	var vnicName string
+	var vnicNames []string
 	var bridge string
 	var gatewayIP string
-	var vnicCfg *libsnnet.VnicConfig
+	var vnicCfgs []*libsnnet.VnicConfig
 	var st startTimes
 
 	st.startStamp = time.Now()
@@ -96,15 +96,15 @@ func processStart(cmd *insStartCmd, instanceDir string, vm virtualizer, conn ser
 	st.backingImageCheck = time.Now()
 
 	if networking {
-		vnicCfg, err = createVnicCfg(cfg)
+		vnicCfgs, err = createVnicCfgs(cfg)
 		if err != nil {
 			glog.Errorf("Could not create VnicCFG: %s", err)
 			return nil, &startError{err, payloads.InvalidData, cmd.cfg.Restart}
 		}
 	}
 
-	if vnicCfg != nil {
-		vnicName, bridge, gatewayIP, err = createVnic(conn, vnicCfg)
+	if vnicCfgs != nil {
+		vnicNames, bridge, gatewayIP, err = createVnics(conn, vnicCfgs)
 		if err != nil {
 			return nil, &startError{err, payloads.NetworkFailure, cmd.cfg.Restart}
 		}
@@ -120,7 +120,7 @@ func processStart(cmd *insStartCmd, instanceDir string, vm virtualizer, conn ser
 
 	st.creationStamp = time.Now()
 
-	err = vm.startVM(vnicName, getNodeIPAddress(), cephID)
+	err = vm.startVM(vnicNames, getNodeIPAddress(), cephID)
 	if err != nil {
 		return nil, &startError{err, payloads.LaunchFailure, cmd.cfg.Restart}
 	}
@@ -265,6 +265,8 @@ func stats(host string) error {
 	fmt.Fprintf(w, "MemAvailable:\t %d MB\n", stats.MemAvailableMB)
 	fmt.Fprintf(w, "DiskTotal:\t %d MB\n", stats.DiskTotalMB)
 	fmt.Fprintf(w, "DiskAvailable:\t %d MB\n", stats.DiskAvailableMB)
+	fmt.Fprintf(w, "DiskReserved:\t %d MB\n", stats.DiskReservedMB)
+	fmt.Fprintf(w, "DiskUsed:\t %d MB\n", stats.DiskUsedMB)
 	fmt.Fprintf(w, "Load:\t %d\n", stats.Load)
 	fmt.Fprintf(w, "CpusOnline:\t %d\n", stats.CpusOnline)
 	fmt.Fprintf(w, "NodeHostName:\t %s\n", stats.NodeHostName)
@@ -317,6 +319,8 @@ func status(host string) error {
 	fmt.Fprintf(w, "MemAvailable:\t %d MB\n", status.MemAvailableMB)
 	fmt.Fprintf(w, "DiskTotal:\t %d MB\n", status.DiskTotalMB)
 	fmt.Fprintf(w, "DiskAvailable:\t %d MB\n", status.DiskAvailableMB)
+	fmt.Fprintf(w, "DiskReserved:\t %d MB\n", status.DiskReservedMB)
+	fmt.Fprintf(w, "DiskUsed:\t %d MB\n", status.DiskUsedMB)
 	fmt.Fprintf(w, "Load:\t %d\n", status.Load)
 	fmt.Fprintf(w, "CpusOnline:\t %d\n", status.CpusOnline)
 	w.Flush()
@@ -18,14 +18,62 @@ package main
 
 import (
 	"errors"
+	"io"
 	"sync"
+	"time"
+
+	"github.com/ciao-project/ciao/payloads"
 )
 
-type virtualizerStopCmd struct{}
+// virtualizerStopCmd asks the go routine monitoring an instance to shut it
+// down.  The instance is given timeout to shut down cleanly before it is
+// terminated.  The monitor reports how the instance was brought down on
+// methodCh, which must be buffered, if methodCh is not nil.
+type virtualizerStopCmd struct {
+	timeout  time.Duration
+	methodCh chan payloads.StopMethod
+}
 type virtualizerAttachCmd struct {
 	responseCh chan error
 	volumeUUID string
 	device     string
+	readOnly   bool
+	shared     bool
+
+	// iops and bps are the I/O limits to apply to the volume once it
+	// has been attached.  0 means no limit.
+	iops int64
+	bps  int64
+}
+type virtualizerDetachCmd struct {
+	responseCh chan error
+	volumeUUID string
+}
+
+// virtualizerPauseCmd asks the go routine monitoring an instance to freeze
+// its execution.  The result is reported on responseCh.
+type virtualizerPauseCmd struct {
+	responseCh chan error
+}
+
+// virtualizerUnpauseCmd asks the go routine monitoring an instance to
+// resume the execution of an instance frozen by a virtualizerPauseCmd.
+type virtualizerUnpauseCmd struct {
+	responseCh chan error
+}
+
+// virtualizerSuspendCmd asks the go routine monitoring a VM to save the
+// state of the VM to stateFile and then to terminate it.  The result is
+// reported on responseCh before the monitor's closedCh is closed.
+type virtualizerSuspendCmd struct {
+	responseCh chan error
+	stateFile  string
+}
+
+func (cmd virtualizerStopCmd) stopped(method payloads.StopMethod) {
+	if cmd.methodCh != nil {
+		cmd.methodCh <- method
+	}
 }
 
 var errImageNotFound = errors.New("Image Not Found")
@@ -61,8 +109,14 @@ type virtualizer interface {
 	// deleted by the instance go routine.
 	deleteImage() error
 
-	// Boots a VM.  This method is called by START
-	startVM(vnicName, ipAddress, cephID string) error
+	// Boots a VM.  This method is called by START.  vnicNames contains
+	// the names of the vnics of the NICs of the instance, starting with
+	// the first one.  It is empty if networking is disabled.
+	startVM(vnicNames []string, ipAddress, cephID string) error
+
+	// Restores a VM from the state saved in stateFile by a previous
+	// suspend.  This method is called by RESUME.
+	resumeVM(vnicNames []string, ipAddress, cephID, stateFile string) error
 
 	//BUG(markus): Need to use context rather than the monitor channel to
 	//detect when we need to quit.
@@ -99,6 +153,15 @@ type virtualizer interface {
 	// cpu: Normalized CPU time of VM or container process
 	stats() (disk, memory, cpu int)
 
+	// Returns the information reported by the guest agent running inside the
+	// instance, or nil if the instance has no guest agent or if the agent has
+	// not yet responded.
+	guestInfo() *payloads.GuestInfo
+
+	// Returns the I/O counters of the disks of the instance, or nil if they
+	// are not known.
+	blockStats() *payloads.BlockIOStat
+
 	// connected is called by the instance go routine to inform the virtualizer that
 	// the VM is running.  The virtualizer can used this notification to perform some
 	// bookkeeping, for example determine the pid of the underlying process.  It may
@@ -118,4 +181,10 @@ type virtualizer interface {
 	// The instance go routine then calls lostVM so that the virtualizer can update
 	// its internal state.
 	lostVM()
+
+	// Attaches to the console of a running instance.  The returned
+	// io.ReadWriteCloser carries the output of the console and accepts
+	// input destined for it.  It may be used from any go routine and is
+	// closed by the caller when the console session terminates.
+	attachConsole() (io.ReadWriteCloser, error)
 }
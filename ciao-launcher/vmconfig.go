@@ -27,27 +27,92 @@ import (
 type volumeConfig struct {
 	UUID     string
 	Bootable bool
+	ReadOnly bool
+	Shared   bool
 }
 
-type vmConfig struct {
-	Cpus        int
-	Mem         int
-	Disk        int
-	Instance    string
-	DockerImage string
-	Legacy      bool
-	Container   bool
-	NetworkNode bool
+type ephemeralDiskConfig struct {
+	SizeMB int
+	Swap   bool
+	Tag    string
+	Path   string
+}
+
+// nicConfig describes a NIC of an instance attached to a tenant subnet.
+type nicConfig struct {
 	VnicMAC     string
 	VnicIP      string
+	VnicUUID    string
 	ConcIP      string
-	SubnetIP    string
-	TenantUUID  string
 	ConcUUID    string
-	VnicUUID    string
-	SSHPort     int
-	Volumes     []volumeConfig
-	Restart     bool
+	SubnetIP    string
+	Gateway     string
+	DHCPStart   string
+	DHCPEnd     string
+	IPv6Subnet  string
+	IPv6Gateway string
+	IPv6Mode    string
+}
+
+type vmConfig struct {
+	Cpus           int
+	Mem            int
+	Disk           int
+	Instance       string
+	DockerImage    string
+	Legacy         bool
+	Container      bool
+	NetworkNode    bool
+	VnicMAC        string
+	VnicIP         string
+	ConcIP         string
+	SubnetIP       string
+	Gateway        string
+	DHCPStart      string
+	DHCPEnd        string
+	IPv6Subnet     string
+	IPv6Gateway    string
+	IPv6Mode       string
+	TenantUUID     string
+	ConcUUID       string
+	VnicUUID       string
+	SSHPort        int
+	Volumes        []volumeConfig
+	EphemeralDisks []ephemeralDiskConfig
+	Restart        bool
+	DedicatedCPUs  bool
+	HugePages      bool
+	NUMALocal      bool
+	GuestAgent     bool
+
+	// Console is set for containers that are created with a terminal
+	// and an open stdin, to which a console session can be attached.
+	Console bool
+
+	// AdditionalNICs describes the NICs of the instance other than the
+	// first, which is described by the Vnic, Conc, SubnetIP, Gateway
+	// and DHCP fields above.
+	AdditionalNICs []nicConfig
+
+	// DiskIOPS and DiskMBps limit the I/O operations per second and the
+	// throughput of each of the disks of the instance.  NetRxMbps and
+	// NetTxMbps limit the rate of the traffic received and transmitted
+	// by its vnic.  0 means no limit.
+	DiskIOPS  int
+	DiskMBps  int
+	NetRxMbps int
+	NetTxMbps int
+
+	// PinnedCPUs contains the host CPUs dedicated to each of the VCPUs
+	// of the instance when DedicatedCPUs is set.  Otherwise it contains
+	// the shared host CPUs the instance is allowed to run on, i.e., those
+	// of its NUMA node if NUMALocal is set.  It is empty if the host has
+	// no pinnable CPUs and the instance is not NUMA local.
+	PinnedCPUs []int
+
+	// HostNUMANode is the host NUMA node that backs the memory of the
+	// instance.  It is only meaningful if HugePages or NUMALocal are set.
+	HostNUMANode int
 }
 
 func loadVMConfig(instanceDir string) (*vmConfig, error) {
@@ -89,6 +154,50 @@ func (cfg *vmConfig) save(instanceDir string) error {
 	return cfgFile.Close()
 }
 
+// nics returns the configuration of all the NICs of the instance, starting
+// with the first one.
+func (cfg *vmConfig) nics() []nicConfig {
+	primary := nicConfig{
+		VnicMAC:     cfg.VnicMAC,
+		VnicIP:      cfg.VnicIP,
+		VnicUUID:    cfg.VnicUUID,
+		ConcIP:      cfg.ConcIP,
+		ConcUUID:    cfg.ConcUUID,
+		SubnetIP:    cfg.SubnetIP,
+		Gateway:     cfg.Gateway,
+		DHCPStart:   cfg.DHCPStart,
+		DHCPEnd:     cfg.DHCPEnd,
+		IPv6Subnet:  cfg.IPv6Subnet,
+		IPv6Gateway: cfg.IPv6Gateway,
+		IPv6Mode:    cfg.IPv6Mode,
+	}
+
+	return append([]nicConfig{primary}, cfg.AdditionalNICs...)
+}
+
+// setConcentrator sets the CNCI serving the tenant subnet of the i'th NIC of
+// the instance, where the first NIC has index 0.
+func (cfg *vmConfig) setConcentrator(i int, concUUID, concIP string) {
+	if i == 0 {
+		cfg.ConcUUID = concUUID
+		cfg.ConcIP = concIP
+		return
+	}
+
+	cfg.AdditionalNICs[i-1].ConcUUID = concUUID
+	cfg.AdditionalNICs[i-1].ConcIP = concIP
+}
+
+func (cfg *vmConfig) numaBound() bool {
+	return cfg.HugePages || cfg.NUMALocal
+}
+
+// diskBPS returns the disk throughput limit of the instance in bytes per
+// second.
+func (cfg *vmConfig) diskBPS() int64 {
+	return int64(cfg.DiskMBps) * 1000 * 1000
+}
+
 func (cfg *vmConfig) findVolume(UUID string) *volumeConfig {
 	for i := range cfg.Volumes {
 		if cfg.Volumes[i].UUID == UUID {
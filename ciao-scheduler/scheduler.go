@@ -98,6 +98,34 @@ type nodeStat struct {
 	cpus        int
 	isNetNode   bool
 	networks    []payloads.NetworkStat
+
+	dedicatedCPUsAvail int
+	hugePagesAvailMB   int
+	numaNodes          []payloads.NUMANodeStat
+
+	// local disk demands of instances dispatched to the node which the
+	// node has yet to account for, indexed by instance uuid
+	diskReservations map[string]int
+}
+
+// Sum of the local disk reservations of the referenced locked nodeStat
+func (node *nodeStat) pendingDiskMB() int {
+	pending := 0
+	for _, diskMB := range node.diskReservations {
+		pending += diskMB
+	}
+	return pending
+}
+
+// Give back the local disk reservation of an instance on the referenced
+// locked nodeStat, if it still holds one
+func (node *nodeStat) releaseDiskReservation(instanceUUID string) {
+	diskMB, ok := node.diskReservations[instanceUUID]
+	if !ok {
+		return
+	}
+	node.diskAvailMB += diskMB
+	delete(node.diskReservations, instanceUUID)
 }
 
 type controllerStatus uint8
@@ -312,6 +340,11 @@ func disconnectComputeNode(sched *ssntpSchedulerServer, uuid string) {
 	//TODO: consider moving to cnInactiveMap?
 	delete(sched.cnMap, uuid)
 
+	// the node reports its own disk usage again once it reconnects.
+	node.mutex.Lock()
+	node.diskReservations = nil
+	node.mutex.Unlock()
+
 	for i, n := range sched.cnList {
 		if n != node {
 			continue
@@ -390,6 +423,11 @@ func (sched *ssntpSchedulerServer) ConnectNotify(uuid string, role ssntp.Role) {
 	if role.IsNetAgent() {
 		connectNetworkNode(sched, uuid)
 	}
+	if role.IsCNCIAgent() {
+		// CNCIs are not scheduled on, the controller only needs to
+		// know whether they are alive
+		go sched.sendNodeConnectionEvents(uuid, payloads.CNCINode, true)
+	}
 
 	glog.V(2).Infof("Connect (role 0x%x, uuid=%s)\n", role, uuid)
 }
@@ -404,6 +442,9 @@ func (sched *ssntpSchedulerServer) DisconnectNotify(uuid string, role ssntp.Role
 	if role.IsNetAgent() {
 		disconnectNetworkNode(sched, uuid)
 	}
+	if role.IsCNCIAgent() {
+		go sched.sendNodeConnectionEvents(uuid, payloads.CNCINode, false)
+	}
 
 	glog.V(2).Infof("Connect (role 0x%x, uuid=%s)\n", role, uuid)
 }
@@ -427,10 +468,13 @@ func (sched *ssntpSchedulerServer) updateNodeStat(node *nodeStat, status ssntp.S
 		node.memTotalMB = stats.MemTotalMB
 		node.memAvailMB = stats.MemAvailableMB
 		node.diskTotalMB = stats.DiskTotalMB
-		node.diskAvailMB = stats.DiskAvailableMB
+		node.diskAvailMB = stats.DiskAvailableMB - node.pendingDiskMB()
 		node.load = stats.Load
 		node.cpus = stats.CpusOnline
 		node.networks = stats.Networks
+		node.dedicatedCPUsAvail = stats.DedicatedCpusAvailable
+		node.hugePagesAvailMB = stats.HugePagesAvailableMB
+		node.numaNodes = stats.NUMANodes
 
 		//any changes to the payloads.Ready struct should be
 		//accompanied by a change here
@@ -475,9 +519,18 @@ type workResources struct {
 	diskReqMB    int
 	networkNode  bool
 	physNets     []string
+
+	dedicatedCPUs int
+	hugePagesMB   int
+	numaLocal     bool
+
+	excludedNodes []string
 }
 
 func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (workload workResources, err error) {
+	var vcpus int
+	var dedicatedCPUs, hugePages bool
+
 	// loop the array to find resources
 	for idx := range work.Start.RequestedResources {
 		reqType := work.Start.RequestedResources[idx].Type
@@ -489,6 +542,18 @@ func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (w
 			workload.memReqMB = reqValue
 		}
 
+		// cpus, possibly dedicated, huge pages and NUMA placement
+		switch reqType {
+		case payloads.VCPUs:
+			vcpus = reqValue
+		case payloads.DedicatedCPUs:
+			dedicatedCPUs = reqValue != 0
+		case payloads.HugePages:
+			hugePages = reqValue != 0
+		case payloads.NUMALocal:
+			workload.numaLocal = reqValue != 0
+		}
+
 		// network node
 		if reqType == payloads.NetworkNode {
 			wantsNetworkNode := reqValue
@@ -531,9 +596,19 @@ func (sched *ssntpSchedulerServer) getWorkloadResources(work *payloads.Start) (w
 	if workload.diskReqMB < 0 {
 		return workload, fmt.Errorf("invalid start payload local disk demand: disk MB (%d) < 0, must be >= 0", workload.diskReqMB)
 	}
+	if dedicatedCPUs {
+		if vcpus <= 0 {
+			return workload, fmt.Errorf("invalid start payload resource demand: dedicated_cpus with vcpus (%d) <= 0, must be > 0", vcpus)
+		}
+		workload.dedicatedCPUs = vcpus
+	}
+	if hugePages {
+		workload.hugePagesMB = workload.memReqMB
+	}
 
-	// note the uuid
+	// note the uuid and the nodes to avoid
 	workload.instanceUUID = work.Start.InstanceUUID
+	workload.excludedNodes = work.Start.ExcludedNodes
 
 	return workload, nil
 }
@@ -562,13 +637,54 @@ func networkDemandsSatisfied(node *nodeStat, workload *workResources) bool {
 	return true
 }
 
+// Index of the first NUMA node of the referenced, locked nodeStat object
+// which can host an instance whose memory is backed by huge pages or which
+// is to be confined to a single NUMA node, mirroring the launcher's own
+// placement.  Returns -1 if there is no such node.
+func numaNodeFit(node *nodeStat, workload *workResources) int {
+	for i, n := range node.numaNodes {
+		if n.HugePagesAvailableMB < workload.hugePagesMB {
+			continue
+		}
+		if workload.numaLocal && n.DedicatedCpusAvailable < workload.dedicatedCPUs {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func dedicatedDemandsSatisfied(node *nodeStat, workload *workResources) bool {
+	if node.dedicatedCPUsAvail < workload.dedicatedCPUs ||
+		node.hugePagesAvailMB < workload.hugePagesMB {
+		return false
+	}
+
+	if workload.hugePagesMB == 0 && !workload.numaLocal {
+		return true
+	}
+
+	return numaNodeFit(node, workload) != -1
+}
+
+func nodeExcluded(node *nodeStat, workload *workResources) bool {
+	for _, uuid := range workload.excludedNodes {
+		if node.uuid == uuid {
+			return true
+		}
+	}
+	return false
+}
+
 // Check resource demands are satisfiable by the referenced, locked nodeStat object
 func (sched *ssntpSchedulerServer) workloadFits(node *nodeStat, workload *workResources) bool {
 	// simple scheduling policy == first fit
 	if node.memAvailMB >= workload.memReqMB &&
 		node.diskAvailMB >= workload.diskReqMB &&
 		node.status == ssntp.READY &&
-		networkDemandsSatisfied(node, workload) {
+		!nodeExcluded(node, workload) &&
+		networkDemandsSatisfied(node, workload) &&
+		dedicatedDemandsSatisfied(node, workload) {
 
 		return true
 	}
@@ -604,6 +720,26 @@ func (sched *ssntpSchedulerServer) getCommandConcentratorUUID(command ssntp.Comm
 		var cmd payloads.CommandReleasePublicIP
 		err := yaml.Unmarshal(payload, &cmd)
 		return cmd.ReleaseIP.ConcentratorUUID, err
+	case ssntp.UpdateDNS:
+		var cmd payloads.CommandUpdateDNS
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.UpdateDNS.ConcentratorUUID, err
+	case ssntp.ConfigureLoadBalancer:
+		var cmd payloads.CommandConfigureLoadBalancer
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.ConfigureLoadBalancer.ConcentratorUUID, err
+	case ssntp.RemoveLoadBalancer:
+		var cmd payloads.CommandRemoveLoadBalancer
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.RemoveLoadBalancer.ConcentratorUUID, err
+	case ssntp.UpdateMetadata:
+		var cmd payloads.CommandUpdateMetadata
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.UpdateMetadata.ConcentratorUUID, err
+	case ssntp.UpdatePeering:
+		var cmd payloads.CommandUpdatePeering
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.UpdatePeering.ConcentratorUUID, err
 	}
 }
 
@@ -676,6 +812,38 @@ func getWorkloadAgentUUID(sched *ssntpSchedulerServer, command ssntp.Command, pa
 		var cmd payloads.AttachVolume
 		err := yaml.Unmarshal(payload, &cmd)
 		return cmd.Attach.InstanceUUID, cmd.Attach.WorkloadAgentUUID, err
+	case ssntp.DetachVolume:
+		var cmd payloads.DetachVolume
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Detach.InstanceUUID, cmd.Detach.WorkloadAgentUUID, err
+	case ssntp.GetConsoleLog:
+		var cmd payloads.GetConsoleLog
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.ConsoleLog.InstanceUUID, cmd.ConsoleLog.WorkloadAgentUUID, err
+	case ssntp.OpenConsole:
+		var cmd payloads.OpenConsole
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Console.InstanceUUID, cmd.Console.WorkloadAgentUUID, err
+	case ssntp.PauseInstance:
+		var cmd payloads.Pause
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Pause.InstanceUUID, cmd.Pause.WorkloadAgentUUID, err
+	case ssntp.UnpauseInstance:
+		var cmd payloads.Unpause
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Unpause.InstanceUUID, cmd.Unpause.WorkloadAgentUUID, err
+	case ssntp.SuspendInstance:
+		var cmd payloads.Suspend
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Suspend.InstanceUUID, cmd.Suspend.WorkloadAgentUUID, err
+	case ssntp.ResumeInstance:
+		var cmd payloads.Resume
+		err := yaml.Unmarshal(payload, &cmd)
+		return cmd.Resume.InstanceUUID, cmd.Resume.WorkloadAgentUUID, err
+	case ssntp.UpdateConcentrator:
+		var cmd payloads.CommandUpdateConcentrator
+		err := yaml.Unmarshal(payload, &cmd)
+		return "", cmd.UpdateConcentrator.AgentUUID, err
 	}
 }
 
@@ -692,12 +860,97 @@ func (sched *ssntpSchedulerServer) fwdCmdToComputeNode(command ssntp.Command, pa
 	glog.V(2).Infof("Forwarding controller %s command to %s\n", command.String(), cnDestUUID)
 	dest.AddRecipient(cnDestUUID)
 
+	// an instance deleted before the node reported it in its STATS
+	// would otherwise hold on to its local disk reservation.
+	if command == ssntp.DELETE {
+		sched.withNodeStat(cnDestUUID, func(node *nodeStat) {
+			node.releaseDiskReservation(instanceUUID)
+		})
+	}
+
 	return
 }
 
 // Decrement resource claims for the referenced locked nodeStat object
 func (sched *ssntpSchedulerServer) decrementResourceUsage(node *nodeStat, workload *workResources) {
 	node.memAvailMB -= workload.memReqMB
+
+	// Local disk demands are held as reservations until the node reports
+	// the instance in its STATS, so that READY frames sent before the node
+	// has seen the START cannot free up the space again.
+	if workload.diskReqMB > 0 {
+		node.diskAvailMB -= workload.diskReqMB
+		if node.diskReservations == nil {
+			node.diskReservations = make(map[string]int)
+		}
+		node.diskReservations[workload.instanceUUID] = workload.diskReqMB
+	}
+
+	node.dedicatedCPUsAvail -= workload.dedicatedCPUs
+	node.hugePagesAvailMB -= workload.hugePagesMB
+	if workload.hugePagesMB > 0 || workload.numaLocal {
+		if i := numaNodeFit(node, workload); i != -1 {
+			node.numaNodes[i].HugePagesAvailableMB -= workload.hugePagesMB
+			if workload.numaLocal {
+				node.numaNodes[i].DedicatedCpusAvailable -= workload.dedicatedCPUs
+			}
+		}
+	}
+}
+
+// Call fn with the locked nodeStat of the compute or network node uuid,
+// if such a node is connected
+func (sched *ssntpSchedulerServer) withNodeStat(uuid string, fn func(node *nodeStat)) {
+	lockedFn := func(mutex *sync.RWMutex, nodeMap map[string]*nodeStat) bool {
+		mutex.RLock()
+		defer mutex.RUnlock()
+
+		node := nodeMap[uuid]
+		if node == nil {
+			return false
+		}
+
+		node.mutex.Lock()
+		fn(node)
+		node.mutex.Unlock()
+		return true
+	}
+
+	if !lockedFn(&sched.cnMutex, sched.cnMap) {
+		lockedFn(&sched.nnMutex, sched.nnMap)
+	}
+}
+
+// Drop the local disk reservations of the instances a node reports in its
+// STATS, their disk demands being accounted for by the node itself from
+// now on.
+func (sched *ssntpSchedulerServer) updateDiskReservations(uuid string, payload []byte) {
+	var stats payloads.Stat
+	err := yaml.Unmarshal(payload, &stats)
+	if err != nil {
+		glog.Errorf("Bad STATS yaml for node %s\n", uuid)
+		return
+	}
+
+	sched.withNodeStat(uuid, func(node *nodeStat) {
+		for _, instance := range stats.Instances {
+			delete(node.diskReservations, instance.InstanceUUID)
+		}
+	})
+}
+
+// Give back the local disk reservation of an instance a node failed to start.
+func (sched *ssntpSchedulerServer) releaseDiskReservation(uuid string, payload []byte) {
+	var failure payloads.ErrorStartFailure
+	err := yaml.Unmarshal(payload, &failure)
+	if err != nil {
+		glog.Errorf("Bad StartFailure yaml for node %s\n", uuid)
+		return
+	}
+
+	sched.withNodeStat(uuid, func(node *nodeStat) {
+		node.releaseDiskReservation(failure.InstanceUUID)
+	})
 }
 
 // Find suitable compute node, returning referenced to a locked nodeStat if found
@@ -817,6 +1070,7 @@ func startWorkload(sched *ssntpSchedulerServer, controllerUUID string, payload [
 	if targetNode != nil {
 		//TODO: mark the targetNode as unavailable until next stats / READY checkin?
 		//	or is subtracting mem demand sufficiently speculative enough?
+		//	Local disk demands are reserved until the node accounts for them.
 		//	Goal is to have spread, not schedule "too many" workloads back
 		//	to back on the same targetNode, but also not add latency to dispatch and
 		//	hopefully not queue when all nodes have just started a workload.
@@ -832,6 +1086,62 @@ func startWorkload(sched *ssntpSchedulerServer, controllerUUID string, payload [
 	return dest, instanceUUID
 }
 
+func (sched *ssntpSchedulerServer) sendResumeFailureError(clientUUID string, nodeUUID string, instanceUUID string, reason payloads.PauseFailureReason) {
+	error := payloads.ErrorPauseFailure{
+		NodeUUID:     nodeUUID,
+		InstanceUUID: instanceUUID,
+		Reason:       reason,
+	}
+
+	payload, err := yaml.Marshal(&error)
+	if err != nil {
+		glog.Errorf("Unable to Marshall Status %v", err)
+		return
+	}
+
+	glog.Warningf("Unable to resume %s: %v\n", instanceUUID, reason)
+	sched.ssntp.SendError(clientUUID, ssntp.ResumeFailure, payload)
+}
+
+// A suspended instance can only be resumed on the node which holds its
+// saved state, so rather than picking a node, check that the instance's
+// node has enough memory left to restore it.  The dedicated CPUs and huge
+// pages of suspended instances remain reserved by their node.
+func resumeWorkload(sched *ssntpSchedulerServer, controllerUUID string, payload []byte) (dest ssntp.ForwardDestination, instanceUUID string) {
+	var cmd payloads.Resume
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil || cmd.Resume.WorkloadAgentUUID == "" {
+		glog.Errorf("Bad ResumeInstance command yaml from Controller %s\n", controllerUUID)
+		dest.SetDecision(ssntp.Discard)
+		return dest, ""
+	}
+
+	instanceUUID = cmd.Resume.InstanceUUID
+	nodeUUID := cmd.Resume.WorkloadAgentUUID
+	workload := workResources{
+		instanceUUID: instanceUUID,
+		memReqMB:     cmd.Resume.MemMB,
+	}
+
+	// A command for a node that is not connected is forwarded as usual.
+	fits := true
+	sched.withNodeStat(nodeUUID, func(node *nodeStat) {
+		fits = node.memAvailMB >= workload.memReqMB
+		if fits {
+			sched.decrementResourceUsage(node, &workload)
+		}
+	})
+
+	if !fits {
+		sched.sendResumeFailureError(controllerUUID, nodeUUID, instanceUUID, payloads.PauseFullComputeNode)
+		dest.SetDecision(ssntp.Discard)
+		return dest, instanceUUID
+	}
+
+	dest.AddRecipient(nodeUUID)
+	return dest, instanceUUID
+}
+
 func (sched *ssntpSchedulerServer) CommandForward(controllerUUID string, command ssntp.Command, frame *ssntp.Frame) (dest ssntp.ForwardDestination) {
 	payload := frame.Payload
 	instanceUUID := ""
@@ -861,17 +1171,43 @@ func (sched *ssntpSchedulerServer) CommandForward(controllerUUID string, command
 	// the main command with scheduler processing
 	case ssntp.START:
 		dest, instanceUUID = startWorkload(sched, controllerUUID, payload)
+	case ssntp.ResumeInstance:
+		dest, instanceUUID = resumeWorkload(sched, controllerUUID, payload)
 	case ssntp.DELETE:
 		fallthrough
 	case ssntp.AttachVolume:
 		fallthrough
+	case ssntp.DetachVolume:
+		fallthrough
+	case ssntp.GetConsoleLog:
+		fallthrough
+	case ssntp.OpenConsole:
+		fallthrough
+	case ssntp.PauseInstance:
+		fallthrough
+	case ssntp.UnpauseInstance:
+		fallthrough
+	case ssntp.SuspendInstance:
+		fallthrough
 	case ssntp.EVACUATE:
 		fallthrough
 	case ssntp.Restore:
+		fallthrough
+	case ssntp.UpdateConcentrator:
 		dest, instanceUUID = sched.fwdCmdToComputeNode(command, payload)
 	case ssntp.AssignPublicIP:
 		fallthrough
 	case ssntp.ReleasePublicIP:
+		fallthrough
+	case ssntp.UpdateDNS:
+		fallthrough
+	case ssntp.ConfigureLoadBalancer:
+		fallthrough
+	case ssntp.RemoveLoadBalancer:
+		fallthrough
+	case ssntp.UpdateMetadata:
+		fallthrough
+	case ssntp.UpdatePeering:
 		dest = sched.fwdCmdToCNCI(command, payload)
 	default:
 		dest.SetDecision(ssntp.Discard)
@@ -885,8 +1221,13 @@ func (sched *ssntpSchedulerServer) CommandForward(controllerUUID string, command
 
 func (sched *ssntpSchedulerServer) CommandNotify(uuid string, command ssntp.Command, frame *ssntp.Frame) {
 	// Currently all commands are handled by CommandForward, the SSNTP command forwader,
-	// or directly by role defined forwarding rules.
+	// or directly by role defined forwarding rules.  STATS are also used to
+	// settle local disk reservations.
 	glog.V(2).Infof("COMMAND %v from %s\n", command, uuid)
+
+	if command == ssntp.STATS {
+		sched.updateDiskReservations(uuid, frame.Payload)
+	}
 }
 
 func (sched *ssntpSchedulerServer) EventForward(uuid string, event ssntp.Event, frame *ssntp.Frame) (dest ssntp.ForwardDestination) {
@@ -915,6 +1256,10 @@ func (sched *ssntpSchedulerServer) EventNotify(uuid string, event ssntp.Event, f
 
 func (sched *ssntpSchedulerServer) ErrorNotify(uuid string, error ssntp.Error, frame *ssntp.Frame) {
 	glog.V(2).Infof("ERROR %v from %s\n", error, uuid)
+
+	if error == ssntp.StartFailure {
+		sched.releaseDiskReservation(uuid, frame.Payload)
+	}
 }
 
 func setLimits() {
@@ -1115,6 +1460,22 @@ func setSSNTPForwardRules(sched *ssntpSchedulerServer) {
 			Operand: ssntp.InstanceStopped,
 			Dest:    ssntp.Controller,
 		},
+		{ // all VolumeDetached events go to all Controllers
+			Operand: ssntp.VolumeDetached,
+			Dest:    ssntp.Controller,
+		},
+		{ // all ConsoleLog events go to all Controllers
+			Operand: ssntp.ConsoleLog,
+			Dest:    ssntp.Controller,
+		},
+		{ // all ConsoleOpened events go to all Controllers
+			Operand: ssntp.ConsoleOpened,
+			Dest:    ssntp.Controller,
+		},
+		{ // all NodeInventory events go to all Controllers
+			Operand: ssntp.NodeInventory,
+			Dest:    ssntp.Controller,
+		},
 		{ // all ConcentratorInstanceAdded events go to all Controllers
 			Operand: ssntp.ConcentratorInstanceAdded,
 			Dest:    ssntp.Controller,
@@ -1183,6 +1544,62 @@ func setSSNTPForwardRules(sched *ssntpSchedulerServer) {
 			Operand: ssntp.AttachVolumeFailure,
 			Dest:    ssntp.Controller,
 		},
+		{ // all DetachVolume command are processed by the Command forwarder
+			Operand:        ssntp.DetachVolume,
+			CommandForward: sched,
+		},
+		{ // all DetachVolumeFailure errors go to all Controllers
+			Operand: ssntp.DetachVolumeFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all GetConsoleLog command are processed by the Command forwarder
+			Operand:        ssntp.GetConsoleLog,
+			CommandForward: sched,
+		},
+		{ // all ConsoleLogFailure errors go to all Controllers
+			Operand: ssntp.ConsoleLogFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all OpenConsole command are processed by the Command forwarder
+			Operand:        ssntp.OpenConsole,
+			CommandForward: sched,
+		},
+		{ // all OpenConsoleFailure errors go to all Controllers
+			Operand: ssntp.OpenConsoleFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all PauseInstance command are processed by the Command forwarder
+			Operand:        ssntp.PauseInstance,
+			CommandForward: sched,
+		},
+		{ // all PauseFailure errors go to all Controllers
+			Operand: ssntp.PauseFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all UnpauseInstance command are processed by the Command forwarder
+			Operand:        ssntp.UnpauseInstance,
+			CommandForward: sched,
+		},
+		{ // all UnpauseFailure errors go to all Controllers
+			Operand: ssntp.UnpauseFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all SuspendInstance command are processed by the Command forwarder
+			Operand:        ssntp.SuspendInstance,
+			CommandForward: sched,
+		},
+		{ // all SuspendFailure errors go to all Controllers
+			Operand: ssntp.SuspendFailure,
+			Dest:    ssntp.Controller,
+		},
+		{ // all ResumeInstance command are processed by the Command forwarder
+			Operand:        ssntp.ResumeInstance,
+			CommandForward: sched,
+		},
+		{ // all ResumeFailure errors go to all Controllers
+			Operand: ssntp.ResumeFailure,
+			Dest:    ssntp.Controller,
+		},
 		{ // all AssignPublicIP commands are processed by the Command forwarder
 			Operand:        ssntp.AssignPublicIP,
 			CommandForward: sched,
@@ -1191,6 +1608,30 @@ func setSSNTPForwardRules(sched *ssntpSchedulerServer) {
 			Operand:        ssntp.ReleasePublicIP,
 			CommandForward: sched,
 		},
+		{ // all UpdateDNS commands are processed by the Command forwarder
+			Operand:        ssntp.UpdateDNS,
+			CommandForward: sched,
+		},
+		{ // all UpdateConcentrator commands are processed by the Command forwarder
+			Operand:        ssntp.UpdateConcentrator,
+			CommandForward: sched,
+		},
+		{ // all ConfigureLoadBalancer commands are processed by the Command forwarder
+			Operand:        ssntp.ConfigureLoadBalancer,
+			CommandForward: sched,
+		},
+		{ // all RemoveLoadBalancer commands are processed by the Command forwarder
+			Operand:        ssntp.RemoveLoadBalancer,
+			CommandForward: sched,
+		},
+		{ // all UpdateMetadata commands are processed by the Command forwarder
+			Operand:        ssntp.UpdateMetadata,
+			CommandForward: sched,
+		},
+		{ // all UpdatePeering commands are processed by the Command forwarder
+			Operand:        ssntp.UpdatePeering,
+			CommandForward: sched,
+		},
 	}
 }
 
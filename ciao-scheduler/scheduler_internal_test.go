@@ -761,6 +761,13 @@ func TestGetWorkloadAgentUUID(t *testing.T) {
 		{ssntp.EVACUATE, []byte(testutil.EvacuateYaml), "", testutil.AgentUUID},
 		{ssntp.Restore, []byte(testutil.RestoreYaml), "", testutil.AgentUUID},
 		{ssntp.AttachVolume, []byte(testutil.AttachVolumeYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.DetachVolume, []byte(testutil.DetachVolumeYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.GetConsoleLog, []byte(testutil.GetConsoleLogYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.OpenConsole, []byte(testutil.OpenConsoleYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.PauseInstance, []byte(testutil.PauseYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.UnpauseInstance, []byte(testutil.UnpauseYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.SuspendInstance, []byte(testutil.SuspendYaml), testutil.InstanceUUID, testutil.AgentUUID},
+		{ssntp.ResumeInstance, []byte(testutil.ResumeYaml), testutil.InstanceUUID, testutil.AgentUUID},
 	}
 	for _, test := range stringTests {
 		instanceUUID, agentUUID, _ := GetWorkloadAgentUUID(sched, test.cmd, test.yaml)
@@ -772,3 +779,252 @@ func TestGetWorkloadAgentUUID(t *testing.T) {
 		}
 	}
 }
+
+func TestDiskReservations(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+	spinUpController(sched, 1, controllerMaster)
+	var controllerUUID = fmt.Sprintf("%08d", 1)
+
+	spinUpComputeNode(sched, 1, 16138)
+	var nodeUUID = fmt.Sprintf("%08d", 1)
+	node := sched.cnMap[nodeUUID]
+	node.diskAvailMB = 5 * 1024
+
+	work := createStartWorkload(2, 256, 0)
+	work.Start.Storage = []payloads.StorageResource{{Local: true, Size: 4}}
+	payload, err := yaml.Marshal(work)
+	if err != nil {
+		t.Fatalf("unable to marshal START payload: %v", err)
+	}
+
+	fwd, _ := startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Forward {
+		t.Fatalf("unable to start workload with local disk")
+	}
+	if node.diskAvailMB != 1024 || node.pendingDiskMB() != 4*1024 {
+		t.Fatalf("local disk not reserved, available %d, pending %d",
+			node.diskAvailMB, node.pendingDiskMB())
+	}
+
+	// a second workload must not oversubscribe the node
+	fwd, _ = startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Discard {
+		t.Fatalf("node oversubscribed by concurrent start")
+	}
+
+	// a READY sent before the node has seen the START keeps the reservation
+	ready := testutil.ReadyPayload(nodeUUID, 16138, 16138, nil)
+	ready.DiskAvailableMB = 5 * 1024
+	readyYaml, _ := yaml.Marshal(&ready)
+	sched.updateNodeStat(node, ssntp.READY, &ssntp.Frame{Payload: readyYaml})
+	if node.diskAvailMB != 1024 {
+		t.Fatalf("reservation lost on READY, available %d", node.diskAvailMB)
+	}
+
+	// once the node reports the instance the reservation is dropped
+	stats := testutil.StatsPayload(nodeUUID, "test",
+		[]payloads.InstanceStat{{InstanceUUID: work.Start.InstanceUUID}}, nil)
+	statsYaml, _ := yaml.Marshal(&stats)
+	sched.CommandNotify(nodeUUID, ssntp.STATS, &ssntp.Frame{Payload: statsYaml})
+	if node.pendingDiskMB() != 0 {
+		t.Fatalf("reservation not dropped after STATS")
+	}
+	ready.DiskAvailableMB = 1024
+	readyYaml, _ = yaml.Marshal(&ready)
+	sched.updateNodeStat(node, ssntp.READY, &ssntp.Frame{Payload: readyYaml})
+	if node.diskAvailMB != 1024 {
+		t.Fatalf("unexpected available disk %d after READY", node.diskAvailMB)
+	}
+
+	// a failed start gives the reservation back
+	node.diskAvailMB = 5 * 1024
+	fwd, _ = startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Forward {
+		t.Fatalf("unable to start workload with local disk")
+	}
+	failure := payloads.ErrorStartFailure{
+		NodeUUID:     nodeUUID,
+		InstanceUUID: work.Start.InstanceUUID,
+		Reason:       payloads.FullComputeNode,
+	}
+	failureYaml, _ := yaml.Marshal(&failure)
+	sched.ErrorNotify(nodeUUID, ssntp.StartFailure, &ssntp.Frame{Payload: failureYaml})
+	if node.diskAvailMB != 5*1024 || node.pendingDiskMB() != 0 {
+		t.Fatalf("reservation not released after StartFailure, available %d, pending %d",
+			node.diskAvailMB, node.pendingDiskMB())
+	}
+
+	// deleting an instance the node has yet to report gives the
+	// reservation back
+	fwd, _ = startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Forward {
+		t.Fatalf("unable to start workload with local disk")
+	}
+	del := payloads.Delete{
+		Delete: payloads.StopCmd{
+			InstanceUUID:      work.Start.InstanceUUID,
+			WorkloadAgentUUID: nodeUUID,
+		},
+	}
+	delYaml, _ := yaml.Marshal(&del)
+	sched.fwdCmdToComputeNode(ssntp.DELETE, delYaml)
+	if node.diskAvailMB != 5*1024 || node.pendingDiskMB() != 0 {
+		t.Fatalf("reservation not released after DELETE, available %d, pending %d",
+			node.diskAvailMB, node.pendingDiskMB())
+	}
+
+	// as does the node disconnecting
+	fwd, _ = startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Forward {
+		t.Fatalf("unable to start workload with local disk")
+	}
+	disconnectComputeNode(sched, nodeUUID)
+	if node.pendingDiskMB() != 0 {
+		t.Fatalf("reservation not released after disconnect")
+	}
+}
+
+func TestResumeWorkload(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+	spinUpController(sched, 1, controllerMaster)
+	var controllerUUID = fmt.Sprintf("%08d", 1)
+
+	spinUpComputeNode(sched, 1, 1024)
+	var nodeUUID = fmt.Sprintf("%08d", 1)
+	node := sched.cnMap[nodeUUID]
+
+	resume := payloads.Resume{
+		Resume: payloads.PauseCmd{
+			InstanceUUID:      testutil.InstanceUUID,
+			WorkloadAgentUUID: nodeUUID,
+			MemMB:             768,
+		},
+	}
+	payload, err := yaml.Marshal(&resume)
+	if err != nil {
+		t.Fatalf("unable to marshal ResumeInstance payload: %v", err)
+	}
+
+	fwd, uuid := resumeWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Forward || uuid != testutil.InstanceUUID {
+		t.Fatalf("unable to resume workload, got decision=0x%x, workload uuid=%s",
+			fwd.Decision(), uuid)
+	}
+	recipients := fwd.Recipients()
+	if len(recipients) != 1 || recipients[0] != nodeUUID {
+		t.Fatalf("resume not sent to the instance's node: %v", recipients)
+	}
+	if node.memAvailMB != 256 {
+		t.Fatalf("memory not claimed by resume, available %d", node.memAvailMB)
+	}
+
+	// a second resume must not overcommit the node
+	fwd, _ = resumeWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Discard {
+		t.Fatalf("node overcommitted by resume")
+	}
+}
+
+func TestDedicatedResources(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+	spinUpController(sched, 1, controllerMaster)
+	var controllerUUID = fmt.Sprintf("%08d", 1)
+
+	// only the second node has dedicated cpus and huge pages
+	spinUpComputeNode(sched, 1, 16138)
+	spinUpComputeNode(sched, 2, 16138)
+	var nodeUUID = fmt.Sprintf("%08d", 2)
+	node := sched.cnMap[nodeUUID]
+	ready := testutil.ReadyPayload(nodeUUID, 16138, 16138, nil)
+	readyYaml, _ := yaml.Marshal(&ready)
+	sched.updateNodeStat(node, ssntp.READY, &ssntp.Frame{Payload: readyYaml})
+
+	work := createStartWorkload(2, 512, 0)
+	for _, r := range []payloads.Resource{payloads.DedicatedCPUs, payloads.HugePages, payloads.NUMALocal} {
+		work.Start.RequestedResources = append(work.Start.RequestedResources,
+			payloads.RequestedResource{Type: r, Value: 1})
+	}
+	payload, err := yaml.Marshal(work)
+	if err != nil {
+		t.Fatalf("unable to marshal START payload: %v", err)
+	}
+
+	fwd, _ := startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Forward {
+		t.Fatalf("unable to start workload with dedicated resources")
+	}
+	recipients := fwd.Recipients()
+	if len(recipients) != 1 || recipients[0] != nodeUUID {
+		t.Fatalf("workload sent to %v, expected %s", recipients, nodeUUID)
+	}
+	if node.dedicatedCPUsAvail != 0 || node.hugePagesAvailMB != 512 ||
+		node.numaNodes[0].DedicatedCpusAvailable != 0 ||
+		node.numaNodes[0].HugePagesAvailableMB != 512 {
+		t.Fatalf("dedicated resources not claimed, cpus %d, huge pages %d",
+			node.dedicatedCPUsAvail, node.hugePagesAvailMB)
+	}
+
+	// no node has any dedicated cpus left
+	fwd, _ = startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Discard {
+		t.Fatalf("node oversubscribed by dedicated cpu demand")
+	}
+}
+
+func TestExcludedNodes(t *testing.T) {
+	sched = configSchedulerServer()
+	if sched == nil {
+		t.Fatal("unable to configure test scheduler")
+	}
+	spinUpController(sched, 1, controllerMaster)
+	var controllerUUID = fmt.Sprintf("%08d", 1)
+
+	spinUpNetworkNodeLarge(sched, 1, testutil.MultipleComputeNetworks)
+	spinUpNetworkNodeLarge(sched, 2, testutil.MultipleComputeNetworks)
+	var activeUUID = fmt.Sprintf("%08d", 1)
+	var standbyUUID = fmt.Sprintf("%08d", 2)
+
+	var work payloads.Start
+	err := yaml.Unmarshal([]byte(testutil.CNCIStartYaml), &work)
+	if err != nil {
+		t.Fatalf("bad CNCI workload yaml: %s", err)
+	}
+	work.Start.ExcludedNodes = []string{activeUUID}
+
+	payload, err := yaml.Marshal(work)
+	if err != nil {
+		t.Fatalf("unable to marshal START payload: %v", err)
+	}
+
+	// the standby must never land on the active CNCI's node
+	for i := 0; i < 3; i++ {
+		fwd, _ := startWorkload(sched, controllerUUID, payload)
+		if fwd.Decision() != ssntp.Forward {
+			t.Fatalf("unable to start workload avoiding %s", activeUUID)
+		}
+		recipients := fwd.Recipients()
+		if len(recipients) != 1 || recipients[0] != standbyUUID {
+			t.Fatalf("workload sent to %v, expected %s", recipients, standbyUUID)
+		}
+	}
+
+	work.Start.ExcludedNodes = []string{activeUUID, standbyUUID}
+	payload, err = yaml.Marshal(work)
+	if err != nil {
+		t.Fatalf("unable to marshal START payload: %v", err)
+	}
+
+	fwd, _ := startWorkload(sched, controllerUUID, payload)
+	if fwd.Decision() != ssntp.Discard {
+		t.Fatalf("workload started on an excluded node")
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrNoBackup is returned from a backup target when the requested
+	// backup does not exist.
+	ErrNoBackup = errors.New("Backup not found")
+)
+
+// BackupTarget is the interface that all backup targets must implement.
+// A backup target stores named, opaque objects outside of the cluster.
+type BackupTarget interface {
+	Put(name string, r io.Reader, size int64) error
+	Get(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+// NewBackupTarget returns the backup target described by uri.  The
+// following forms are supported:
+//
+//	file:///path/to/dir
+//	s3://host[:port]/bucket
+//	s3+http://host[:port]/bucket
+//
+// The s3+http form talks plain HTTP and is intended for local S3
+// compatible stores.  The credentials for S3 targets are taken from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_REGION environment
+// variables.
+func NewBackupTarget(uri string) (BackupTarget, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid backup target %s: %v", uri, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("Missing path in backup target %s", uri)
+		}
+		return &DirTarget{Path: u.Path}, nil
+	case "s3", "s3+http":
+		bucket := strings.Trim(u.Path, "/")
+		if u.Host == "" || bucket == "" {
+			return nil, fmt.Errorf("Missing host or bucket in backup target %s", uri)
+		}
+		scheme := "https"
+		if u.Scheme == "s3+http" {
+			scheme = "http"
+		}
+		return &S3Target{
+			Endpoint:  scheme + "://" + u.Host,
+			Bucket:    bucket,
+			Region:    os.Getenv("AWS_REGION"),
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unsupported backup target %s", uri)
+}
+
+func checkBackupName(name string) error {
+	if name == "" || name == "." || name == ".." ||
+		strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("Invalid backup name %q", name)
+	}
+	return nil
+}
+
+// DirTarget stores backups as files in a local directory.  The directory
+// will typically be a mount point for some form of off-cluster storage.
+type DirTarget struct {
+	// Path is the directory in which backups are stored
+	Path string
+}
+
+// Put stores size bytes read from r in the file name.  The file is
+// written under a temporary name and only renamed once all the data has
+// been successfully written.
+func (t *DirTarget) Put(name string, r io.Reader, size int64) error {
+	if err := checkBackupName(name); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(t.Path, 0700); err != nil {
+		return fmt.Errorf("Unable to create backup directory: %v", err)
+	}
+
+	f, err := ioutil.TempFile(t.Path, "."+name)
+	if err != nil {
+		return fmt.Errorf("Unable to create backup file: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		return fmt.Errorf("Unable to write backup %s: %v", name, err)
+	}
+
+	if n != size {
+		return fmt.Errorf("Short write for backup %s: expected %d bytes, wrote %d", name, size, n)
+	}
+
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("Unable to sync backup %s: %v", name, err)
+	}
+
+	return os.Rename(f.Name(), filepath.Join(t.Path, name))
+}
+
+// Get opens the backup called name.
+func (t *DirTarget) Get(name string) (io.ReadCloser, error) {
+	if err := checkBackupName(name); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(t.Path, name))
+	if os.IsNotExist(err) {
+		return nil, ErrNoBackup
+	}
+	return f, err
+}
+
+// Delete removes the backup called name.
+func (t *DirTarget) Delete(name string) error {
+	if err := checkBackupName(name); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(t.Path, name))
+	if os.IsNotExist(err) {
+		return ErrNoBackup
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testBackupTarget(t *testing.T, target BackupTarget) {
+	data := []byte("some backup data")
+
+	err := target.Put("backup", bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Unable to put backup: %v", err)
+	}
+
+	r, err := target.Get("backup")
+	if err != nil {
+		t.Fatalf("Unable to get backup: %v", err)
+	}
+	read, err := ioutil.ReadAll(r)
+	_ = r.Close()
+	if err != nil {
+		t.Fatalf("Unable to read backup: %v", err)
+	}
+	if !bytes.Equal(read, data) {
+		t.Fatalf("Backup data does not match: expected %q got %q", data, read)
+	}
+
+	err = target.Put("empty", bytes.NewReader(nil), 0)
+	if err != nil {
+		t.Fatalf("Unable to put empty backup: %v", err)
+	}
+
+	err = target.Delete("backup")
+	if err != nil {
+		t.Fatalf("Unable to delete backup: %v", err)
+	}
+
+	_, err = target.Get("backup")
+	if err != ErrNoBackup {
+		t.Fatalf("Expected ErrNoBackup, got %v", err)
+	}
+
+	err = target.Put("../escape", bytes.NewReader(data), int64(len(data)))
+	if err == nil {
+		t.Fatalf("Expected invalid name to be rejected")
+	}
+}
+
+// Check that the directory backup target works
+//
+// Store, retrieve and delete a backup using a DirTarget.
+//
+// The data retrieved should match the data stored, a deleted backup should
+// not be found and names containing path separators should be rejected.
+func TestDirTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ciao-backup-tests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	testBackupTarget(t, &DirTarget{Path: filepath.Join(dir, "backups")})
+
+	err = (&DirTarget{Path: dir}).Put("short", bytes.NewReader([]byte("x")), 2)
+	if err == nil {
+		t.Fatalf("Expected short write to fail")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "short")); !os.IsNotExist(err) {
+		t.Fatalf("Partial backup should not have been stored")
+	}
+}
+
+// Check that backup targets are created correctly from URIs
+//
+// Call NewBackupTarget with a number of valid and invalid URIs.
+//
+// The valid URIs should result in correctly configured targets and the
+// invalid URIs should be rejected.
+func TestNewBackupTarget(t *testing.T) {
+	target, err := NewBackupTarget("file:///var/lib/ciao/backups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dt, ok := target.(*DirTarget); !ok || dt.Path != "/var/lib/ciao/backups" {
+		t.Fatalf("Unexpected target %+v", target)
+	}
+
+	target, err = NewBackupTarget("s3://s3.example.com/ciao")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := target.(*S3Target); !ok || st.Endpoint != "https://s3.example.com" || st.Bucket != "ciao" {
+		t.Fatalf("Unexpected target %+v", target)
+	}
+
+	target, err = NewBackupTarget("s3+http://127.0.0.1:9000/ciao/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := target.(*S3Target); !ok || st.Endpoint != "http://127.0.0.1:9000" || st.Bucket != "ciao" {
+		t.Fatalf("Unexpected target %+v", target)
+	}
+
+	for _, uri := range []string{"file://", "s3://host", "ftp://host/dir", "%%"} {
+		if _, err := NewBackupTarget(uri); err == nil {
+			t.Errorf("Expected %s to be rejected", uri)
+		}
+	}
+}
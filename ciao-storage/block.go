@@ -16,6 +16,7 @@ package storage
 
 import (
 	"errors"
+	"io"
 )
 
 var (
@@ -37,6 +38,8 @@ type BlockDriver interface {
 	GetBlockDeviceSize(volumeUUID string) (uint64, error)
 	IsValidSnapshotUUID(string) error
 	Resize(volumeUUID string, sizeGiB int) (int, error)
+	ExportBlockDeviceSnapshot(volumeUUID string, snapshotID string, w io.Writer) error
+	ImportBlockDevice(volumeUUID string, r io.Reader, sizeGB int) (BlockDevice, error)
 }
 
 // BlockDevice contains information about a block device
@@ -19,6 +19,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os/exec"
 	"strconv"
 	"strings"
@@ -290,3 +291,53 @@ func (d CephDriver) Resize(volumeUUID string, sizeGiB int) (int, error) {
 	size, _ := d.getBlockDeviceSizeGiB(volumeUUID)
 	return size, err
 }
+
+// ExportBlockDeviceSnapshot writes the raw contents of the snapshot
+// volumeUUID@snapshotID to w.
+func (d CephDriver) ExportBlockDeviceSnapshot(volumeUUID string, snapshotID string, w io.Writer) error {
+	var stderr bytes.Buffer
+
+	args := append(d.getCredentials(), "export", "--no-progress", volumeUUID+"@"+snapshotID, "-")
+	cmd := exec.Command("rbd", args...)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, stderr.Bytes())
+	}
+	return nil
+}
+
+// ImportBlockDevice creates a new rbd image named volumeUUID from the raw
+// data read from r.  The image is grown to sizeGB if the imported data is
+// smaller.
+func (d CephDriver) ImportBlockDevice(volumeUUID string, r io.Reader, sizeGB int) (BlockDevice, error) {
+	var stderr bytes.Buffer
+
+	args := append(d.getCredentials(), "import", "--no-progress", "--image-feature", "layering", "-", volumeUUID)
+	cmd := exec.Command("rbd", args...)
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return BlockDevice{}, fmt.Errorf("Error when running: %v: %v: %s", cmd.Args, err, stderr.Bytes())
+	}
+
+	size, err := d.getBlockDeviceSizeGiB(volumeUUID)
+	if err != nil {
+		d.DeleteBlockDevice(volumeUUID)
+		return BlockDevice{}, fmt.Errorf("Error when querying block device size: %v", err)
+	}
+
+	if size < sizeGB {
+		size, err = d.Resize(volumeUUID, sizeGB)
+		if err != nil {
+			d.DeleteBlockDevice(volumeUUID)
+			return BlockDevice{}, err
+		}
+	}
+
+	return BlockDevice{ID: volumeUUID, Size: size}, nil
+}
@@ -15,6 +15,7 @@
 package storage
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
@@ -121,3 +122,17 @@ func TestNoopSnapshots(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestNoopExportImport(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := noopDriver.ExportBlockDeviceSnapshot("", "", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bd, err := noopDriver.ImportBlockDevice("volume", &buf, 10)
+	if err != nil || bd.ID != "volume" || bd.Size != 10 {
+		t.Fatalf("Unexpected import result %v: %v", bd, err)
+	}
+}
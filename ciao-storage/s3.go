@@ -0,0 +1,216 @@
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3DefaultRegion  = "us-east-1"
+	s3DateTimeFormat = "20060102T150405Z"
+)
+
+// S3Target stores backups as objects in a bucket of an S3 compatible
+// object store.  Requests are made using path style addressing and are
+// signed with AWS signature version 4.
+type S3Target struct {
+	// Endpoint is the base URL of the object store, e.g., https://host:9000
+	Endpoint string
+
+	// Bucket is the name of the bucket in which backups are stored
+	Bucket string
+
+	// Region is the region used to sign requests.  us-east-1 is used
+	// if Region is empty.
+	Region string
+
+	// AccessKey and SecretKey are the credentials used to sign requests
+	AccessKey string
+	SecretKey string
+
+	// Client is the HTTP client used to talk to the object store.
+	// http.DefaultClient is used if Client is nil.
+	Client *http.Client
+}
+
+func (t *S3Target) objectURL(name string) string {
+	u := url.URL{Path: "/" + t.Bucket + "/" + name}
+	return strings.TrimRight(t.Endpoint, "/") + u.EscapedPath()
+}
+
+func (t *S3Target) region() string {
+	if t.Region == "" {
+		return s3DefaultRegion
+	}
+	return t.Region
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	_, _ = h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// s3Signature computes the version 4 signature of req.  The host header
+// and all the headers already set in req are signed.
+func s3Signature(req *http.Request, secretKey, region string, now time.Time) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders string
+	for _, k := range names {
+		canonicalHeaders += k + ":" + headers[k] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	date := now.UTC().Format("20060102")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.UTC().Format(s3DateTimeFormat),
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign)), signedHeaders
+}
+
+func (t *S3Target) do(method, name string, body io.Reader, size int64) (*http.Response, error) {
+	if err := checkBackupName(name); err != nil {
+		return nil, err
+	}
+
+	if body != nil && size == 0 {
+		body = http.NoBody
+	}
+
+	req, err := http.NewRequest(method, t.objectURL(name), body)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.ContentLength = size
+	}
+
+	now := time.Now()
+	req.Header.Set("X-Amz-Date", now.UTC().Format(s3DateTimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+
+	region := t.region()
+	signature, signedHeaders := s3Signature(req, t.SecretKey, region, now)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s/%s/s3/aws4_request, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, t.AccessKey, now.UTC().Format("20060102"), region, signedHeaders, signature))
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(req)
+}
+
+func s3Error(method, name string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNoBackup
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s of backup %s failed: %s: %s", method, name, resp.Status, msg)
+}
+
+// Put uploads size bytes read from r to the object name.
+func (t *S3Target) Put(name string, r io.Reader, size int64) error {
+	resp, err := t.do(http.MethodPut, name, r, size)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return s3Error(http.MethodPut, name, resp)
+	}
+	return nil
+}
+
+// Get downloads the object name.  The caller must close the returned
+// reader.
+func (t *S3Target) Get(name string) (io.ReadCloser, error) {
+	resp, err := t.do(http.MethodGet, name, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, s3Error(http.MethodGet, name, resp)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object name.
+func (t *S3Target) Delete(name string) error {
+	resp, err := t.do(http.MethodDelete, name, nil, 0)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent &&
+		resp.StatusCode != http.StatusOK {
+		return s3Error(http.MethodDelete, name, resp)
+	}
+	return nil
+}
@@ -21,10 +21,15 @@ package deviceinfo
 
 import (
 	"bufio"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -33,6 +38,8 @@ var memFreeRegexp *regexp.Regexp
 var memActiveFileRegexp *regexp.Regexp
 var memInactiveFileRegexp *regexp.Regexp
 var cpuStatsRegexp *regexp.Regexp
+var hugePageSizeRegexp *regexp.Regexp
+var numaNodeRegexp *regexp.Regexp
 
 func init() {
 	memTotalRegexp = regexp.MustCompile(`MemTotal:\s+(\d+)`)
@@ -40,6 +47,8 @@ func init() {
 	memActiveFileRegexp = regexp.MustCompile(`Active\(file\):\s+(\d+)`)
 	memInactiveFileRegexp = regexp.MustCompile(`Inactive\(file\):\s+(\d+)`)
 	cpuStatsRegexp = regexp.MustCompile(`^cpu[0-9]+.*$`)
+	hugePageSizeRegexp = regexp.MustCompile(`Hugepagesize:\s+(\d+)`)
+	numaNodeRegexp = regexp.MustCompile(`^node([0-9]+)$`)
 }
 
 func grabInt(re *regexp.Regexp, line string, val *int) bool {
@@ -190,3 +199,186 @@ func GetLoadAvg() int {
 
 	return load
 }
+
+// NUMANode contains information about a NUMA node of the device.
+type NUMANode struct {
+	// ID is the number of the node.
+	ID int
+
+	// CPUs is the list of the online CPUs of the node.
+	CPUs []int
+
+	// HugePagesTotal is the number of default sized huge pages reserved
+	// on the node.
+	HugePagesTotal int
+
+	// HugePagesFree is the number of default sized huge pages of the
+	// node that are not in use.
+	HugePagesFree int
+}
+
+// ParseCPUList parses a list of CPUs in the format used by the kernel,
+// e.g., 0-3,8,10-11, and returns the CPUs it contains in ascending order.
+func ParseCPUList(list string) ([]int, error) {
+	cpus := make(map[int]struct{})
+
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return []int{}, nil
+	}
+
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("Invalid CPU list %s", list)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("Invalid CPU list %s", list)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus[cpu] = struct{}{}
+		}
+	}
+
+	sorted := make([]int, 0, len(cpus))
+	for cpu := range cpus {
+		sorted = append(sorted, cpu)
+	}
+	sort.Ints(sorted)
+
+	return sorted, nil
+}
+
+func getHugePageSizeKB(file io.Reader) int {
+	size := -1
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if grabInt(hugePageSizeRegexp, scanner.Text(), &size) {
+			break
+		}
+	}
+
+	return size
+}
+
+// GetHugePageSizeKB returns the size of the default huge pages of the
+// device in KiB.  A return value of -1 indicates that an error has occurred
+// or that the device does not support huge pages.
+func GetHugePageSizeKB() int {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return -1
+	}
+
+	size := getHugePageSizeKB(file)
+
+	_ = file.Close()
+
+	return size
+}
+
+func readIntFile(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return -1, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func getHugePages(hugePagesDir string, hugePageSizeKB int) (total, free int) {
+	if hugePageSizeKB <= 0 {
+		return 0, 0
+	}
+
+	dir := path.Join(hugePagesDir, fmt.Sprintf("hugepages-%dkB", hugePageSizeKB))
+	total, err := readIntFile(path.Join(dir, "nr_hugepages"))
+	if err != nil {
+		return 0, 0
+	}
+
+	free, err = readIntFile(path.Join(dir, "free_hugepages"))
+	if err != nil {
+		return total, 0
+	}
+
+	return total, free
+}
+
+func getNUMATopology(nodeDir string, hugePageSizeKB int) ([]NUMANode, error) {
+	entries, err := ioutil.ReadDir(nodeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]NUMANode, 0, len(entries))
+	for _, e := range entries {
+		matches := numaNodeRegexp.FindStringSubmatch(e.Name())
+		if matches == nil {
+			continue
+		}
+
+		id, _ := strconv.Atoi(matches[1])
+		dir := path.Join(nodeDir, e.Name())
+		cpuList, err := ioutil.ReadFile(path.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+
+		cpus, err := ParseCPUList(string(cpuList))
+		if err != nil {
+			return nil, err
+		}
+
+		node := NUMANode{
+			ID:   id,
+			CPUs: cpus,
+		}
+		node.HugePagesTotal, node.HugePagesFree =
+			getHugePages(path.Join(dir, "hugepages"), hugePageSizeKB)
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("No NUMA nodes found in %s", nodeDir)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	return nodes, nil
+}
+
+// GetNUMATopology returns the NUMA nodes of the device, ordered by ID,
+// along with their online CPUs and the number of default sized huge pages
+// they provide.  Devices whose kernel does not support NUMA are reported as
+// having a single node containing all their CPUs.
+func GetNUMATopology() ([]NUMANode, error) {
+	hugePageSizeKB := GetHugePageSizeKB()
+
+	nodes, err := getNUMATopology("/sys/devices/system/node", hugePageSizeKB)
+	if err == nil {
+		return nodes, nil
+	}
+
+	cpuList, err := ioutil.ReadFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		return nil, err
+	}
+
+	cpus, err := ParseCPUList(string(cpuList))
+	if err != nil {
+		return nil, err
+	}
+
+	node := NUMANode{CPUs: cpus}
+	node.HugePagesTotal, node.HugePagesFree =
+		getHugePages("/sys/kernel/mm/hugepages", hugePageSizeKB)
+
+	return []NUMANode{node}, nil
+}
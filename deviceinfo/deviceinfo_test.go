@@ -21,7 +21,10 @@ package deviceinfo
 
 import (
 	"bytes"
+	"io/ioutil"
 	"os"
+	"path"
+	"reflect"
 	"testing"
 )
 
@@ -159,3 +162,109 @@ func TestGetLoadAvg(t *testing.T) {
 		t.Errorf("Expected Load %d , found %d", expectedLoad, load)
 	}
 }
+
+// TestGetHugePageSizeKB tests the code that parses the huge page size from
+// /proc/meminfo.
+//
+// We call getHugePageSizeKB to parse a buffer that contains the contents of
+// an example /proc/meminfo file.
+//
+// A huge page size of 2048 KiB should be returned.
+func TestGetHugePageSizeKB(t *testing.T) {
+	buf := bytes.NewBufferString(memInfoContents)
+	const expectedSize = 2048
+	size := getHugePageSizeKB(buf)
+	if size != expectedSize {
+		t.Errorf("Expected huge page size %d, found %d", expectedSize, size)
+	}
+}
+
+// TestParseCPUList tests the parsing of kernel formatted CPU lists.
+//
+// We parse a number of valid and invalid CPU lists.
+//
+// The valid lists should be expanded into sorted lists of CPUs and
+// errors should be returned for the invalid lists.
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list string
+		cpus []int
+	}{
+		{"", []int{}},
+		{"0\n", []int{0}},
+		{"0-3", []int{0, 1, 2, 3}},
+		{"8,0-1,10-11", []int{0, 1, 8, 10, 11}},
+		{"2,2-3", []int{2, 3}},
+	}
+
+	for _, test := range tests {
+		cpus, err := ParseCPUList(test.list)
+		if err != nil {
+			t.Errorf("Unable to parse %q: %v", test.list, err)
+			continue
+		}
+		if !reflect.DeepEqual(cpus, test.cpus) {
+			t.Errorf("Expected %v for %q, found %v", test.cpus, test.list, cpus)
+		}
+	}
+
+	for _, list := range []string{"a", "3-1", "-1", "1,,2", "0-"} {
+		if _, err := ParseCPUList(list); err == nil {
+			t.Errorf("Expected %q to be rejected", list)
+		}
+	}
+}
+
+func writeSysFile(t *testing.T, filePath, contents string) {
+	err := os.MkdirAll(path.Dir(filePath), 0755)
+	if err != nil {
+		t.Fatalf("Unable to create %s: %v", path.Dir(filePath), err)
+	}
+
+	err = ioutil.WriteFile(filePath, []byte(contents), 0644)
+	if err != nil {
+		t.Fatalf("Unable to write %s: %v", filePath, err)
+	}
+}
+
+// TestGetNUMATopology tests the code that discovers the NUMA nodes of a
+// device.
+//
+// We create a fake sysfs node directory describing two NUMA nodes, only
+// the second of which has huge pages, and call getNUMATopology.
+//
+// Both nodes should be returned, ordered by ID, with the correct CPUs and
+// huge page counts.
+func TestGetNUMATopology(t *testing.T) {
+	nodeDir, err := ioutil.TempDir("", "deviceinfo-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+	defer func() { _ = os.RemoveAll(nodeDir) }()
+
+	writeSysFile(t, path.Join(nodeDir, "node1", "cpulist"), "2-3\n")
+	writeSysFile(t, path.Join(nodeDir, "node1", "hugepages",
+		"hugepages-2048kB", "nr_hugepages"), "512\n")
+	writeSysFile(t, path.Join(nodeDir, "node1", "hugepages",
+		"hugepages-2048kB", "free_hugepages"), "256\n")
+	writeSysFile(t, path.Join(nodeDir, "node0", "cpulist"), "0-1\n")
+	writeSysFile(t, path.Join(nodeDir, "possible"), "0-1\n")
+
+	nodes, err := getNUMATopology(nodeDir, 2048)
+	if err != nil {
+		t.Fatalf("Unable to retrieve NUMA topology: %v", err)
+	}
+
+	expected := []NUMANode{
+		{ID: 0, CPUs: []int{0, 1}},
+		{ID: 1, CPUs: []int{2, 3}, HugePagesTotal: 512, HugePagesFree: 256},
+	}
+	if !reflect.DeepEqual(nodes, expected) {
+		t.Errorf("Expected %+v, found %+v", expected, nodes)
+	}
+
+	_, err = getNUMATopology(path.Join(nodeDir, "node0"), 2048)
+	if err == nil {
+		t.Errorf("Expected error for directory without NUMA nodes")
+	}
+}
@@ -232,6 +232,61 @@ func processCommand(client *ssntpConn, cmd *cmdWrapper) {
 			}
 		}(cmd)
 
+	case *payloads.CommandUpdateDNS:
+
+		go func(cmd *cmdWrapper) {
+			c := &netCmd.UpdateDNS
+			glog.Infof("Processing: CiaoCommandUpdateDNS %v", c)
+			err := updateDNS(c)
+			if err != nil {
+				glog.Errorf("Error Processing: CiaoCommandUpdateDNS %+v", err)
+			}
+		}(cmd)
+
+	case *payloads.CommandConfigureLoadBalancer:
+
+		go func(cmd *cmdWrapper) {
+			c := &netCmd.ConfigureLoadBalancer
+			glog.Infof("Processing: CiaoCommandConfigureLoadBalancer %v", c)
+			err := configureLoadBalancer(c)
+			if err != nil {
+				glog.Errorf("Error Processing: CiaoCommandConfigureLoadBalancer %+v", err)
+			}
+		}(cmd)
+
+	case *payloads.CommandRemoveLoadBalancer:
+
+		go func(cmd *cmdWrapper) {
+			c := &netCmd.RemoveLoadBalancer
+			glog.Infof("Processing: CiaoCommandRemoveLoadBalancer %v", c)
+			err := removeLoadBalancer(c)
+			if err != nil {
+				glog.Errorf("Error Processing: CiaoCommandRemoveLoadBalancer %+v", err)
+			}
+		}(cmd)
+
+	case *payloads.CommandUpdateMetadata:
+
+		go func(cmd *cmdWrapper) {
+			c := &netCmd.UpdateMetadata
+			glog.Infof("Processing: CiaoCommandUpdateMetadata %v %v", c.TenantSubnet, len(c.Instances))
+			err := updateMetadata(c)
+			if err != nil {
+				glog.Errorf("Error Processing: CiaoCommandUpdateMetadata %+v", err)
+			}
+		}(cmd)
+
+	case *payloads.CommandUpdatePeering:
+
+		go func(cmd *cmdWrapper) {
+			c := &netCmd.UpdatePeering
+			glog.Infof("Processing: CiaoCommandUpdatePeering %v %v", c.TenantSubnet, len(c.Peers))
+			err := updatePeering(c)
+			if err != nil {
+				glog.Errorf("Error Processing: CiaoCommandUpdatePeering %+v", err)
+			}
+		}(cmd)
+
 	case *statusConnected:
 		//Block and send this as it does not make sense to send other events
 		//or process commands when we have not yet registered
@@ -291,6 +346,106 @@ func (client *agentClient) CommandNotify(cmd ssntp.Command, frame *ssntp.Frame)
 			client.cmdCh <- &cmdWrapper{&releaseIP}
 		}(payload)
 
+	case ssntp.UpdateDNS:
+		glog.Infof("CMD: ssntp.UpdateDNS %v", len(payload))
+
+		go func(payload []byte) {
+			var updateDNS payloads.CommandUpdateDNS
+			err := yaml.Unmarshal(payload, &updateDNS)
+			if err != nil {
+				glog.Warning("Error unmarshalling UpdateDNS")
+				return
+			}
+			glog.Infof("EVENT: ssntp.UpdateDNS %v", updateDNS)
+
+			err = dbProcessCommand(client.db, &updateDNS)
+			if err != nil {
+				glog.Errorf("unable to save state %+v", err)
+			}
+
+			client.cmdCh <- &cmdWrapper{&updateDNS}
+		}(payload)
+
+	case ssntp.ConfigureLoadBalancer:
+		glog.Infof("CMD: ssntp.ConfigureLoadBalancer %v", len(payload))
+
+		go func(payload []byte) {
+			var configureLB payloads.CommandConfigureLoadBalancer
+			err := yaml.Unmarshal(payload, &configureLB)
+			if err != nil {
+				glog.Warning("Error unmarshalling ConfigureLoadBalancer")
+				return
+			}
+			glog.Infof("EVENT: ssntp.ConfigureLoadBalancer %v", configureLB)
+
+			err = dbProcessCommand(client.db, &configureLB)
+			if err != nil {
+				glog.Errorf("unable to save state %+v", err)
+			}
+
+			client.cmdCh <- &cmdWrapper{&configureLB}
+		}(payload)
+
+	case ssntp.RemoveLoadBalancer:
+		glog.Infof("CMD: ssntp.RemoveLoadBalancer %v", len(payload))
+
+		go func(payload []byte) {
+			var removeLB payloads.CommandRemoveLoadBalancer
+			err := yaml.Unmarshal(payload, &removeLB)
+			if err != nil {
+				glog.Warning("Error unmarshalling RemoveLoadBalancer")
+				return
+			}
+			glog.Infof("EVENT: ssntp.RemoveLoadBalancer %v", removeLB)
+
+			err = dbProcessCommand(client.db, &removeLB)
+			if err != nil {
+				glog.Errorf("unable to save state %+v", err)
+			}
+
+			client.cmdCh <- &cmdWrapper{&removeLB}
+		}(payload)
+
+	case ssntp.UpdateMetadata:
+		glog.Infof("CMD: ssntp.UpdateMetadata %v", len(payload))
+
+		go func(payload []byte) {
+			var updateMetadata payloads.CommandUpdateMetadata
+			err := yaml.Unmarshal(payload, &updateMetadata)
+			if err != nil {
+				glog.Warning("Error unmarshalling UpdateMetadata")
+				return
+			}
+			glog.Infof("EVENT: ssntp.UpdateMetadata %v", updateMetadata.UpdateMetadata.TenantSubnet)
+
+			err = dbProcessCommand(client.db, &updateMetadata)
+			if err != nil {
+				glog.Errorf("unable to save state %+v", err)
+			}
+
+			client.cmdCh <- &cmdWrapper{&updateMetadata}
+		}(payload)
+
+	case ssntp.UpdatePeering:
+		glog.Infof("CMD: ssntp.UpdatePeering %v", len(payload))
+
+		go func(payload []byte) {
+			var updatePeering payloads.CommandUpdatePeering
+			err := yaml.Unmarshal(payload, &updatePeering)
+			if err != nil {
+				glog.Warning("Error unmarshalling UpdatePeering")
+				return
+			}
+			glog.Infof("EVENT: ssntp.UpdatePeering %v", updatePeering.UpdatePeering.TenantSubnet)
+
+			err = dbProcessCommand(client.db, &updatePeering)
+			if err != nil {
+				glog.Errorf("unable to save state %+v", err)
+			}
+
+			client.cmdCh <- &cmdWrapper{&updatePeering}
+		}(payload)
+
 	default:
 		glog.Infof("CMD: %s", cmd)
 	}
@@ -454,6 +609,14 @@ func rebuildNetworkState(db *cnciDatabase) error {
 	defer db.SubnetMap.Unlock()
 	db.PublicIPMap.Lock()
 	defer db.PublicIPMap.Unlock()
+	db.DNSMap.Lock()
+	defer db.DNSMap.Unlock()
+	db.LoadBalancerMap.Lock()
+	defer db.LoadBalancerMap.Unlock()
+	db.MetadataMap.Lock()
+	defer db.MetadataMap.Unlock()
+	db.PeeringMap.Lock()
+	defer db.PeeringMap.Unlock()
 
 	for key, subnet := range db.SubnetMap.m {
 		glog.Infof("Key: %v Subnet: %v", key, subnet)
@@ -473,6 +636,42 @@ func rebuildNetworkState(db *cnciDatabase) error {
 		}
 	}
 
+	for key, dns := range db.DNSMap.m {
+		glog.Infof("Key: %v DNS: %v", key, dns)
+		err := updateDNS(dns)
+		if err != nil {
+			lastError = err
+			glog.Errorf("rebuildNetworkState: %v", err)
+		}
+	}
+
+	for key, lb := range db.LoadBalancerMap.m {
+		glog.Infof("Key: %v LoadBalancer: %v", key, lb)
+		err := configureLoadBalancer(lb)
+		if err != nil {
+			lastError = err
+			glog.Errorf("rebuildNetworkState: %v", err)
+		}
+	}
+
+	for key, md := range db.MetadataMap.m {
+		glog.Infof("Key: %v Metadata: %v", key, len(md.Instances))
+		err := updateMetadata(md)
+		if err != nil {
+			lastError = err
+			glog.Errorf("rebuildNetworkState: %v", err)
+		}
+	}
+
+	for key, peering := range db.PeeringMap.m {
+		glog.Infof("Key: %v Peers: %v", key, len(peering.Peers))
+		err := updatePeering(peering)
+		if err != nil {
+			lastError = err
+			glog.Errorf("rebuildNetworkState: %v", err)
+		}
+	}
+
 	return errors.Wrapf(lastError, "rebuild network state")
 }
 
@@ -513,12 +712,6 @@ func main() {
 	wdogCh := make(chan struct{})
 	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
-	//TODO: Wait till the node gets an IP address before we kick this off
-	//TODO: Add a IP address change notifier to handle potential IP address change
-	if err := initNetwork(signalCh); err != nil {
-		glog.Fatalf("Unable to setup network. %+v", err)
-	}
-
 	//Recover the state from the database and then
 	//recreate the CNCI state by replaying the commands
 	//Has to be done prior to accepting commands over the network
@@ -527,6 +720,16 @@ func main() {
 		glog.Fatalf("Unable to setup database. %+v", err)
 	}
 
+	//TODO: Wait till the node gets an IP address before we kick this off
+	//TODO: Add a IP address change notifier to handle potential IP address change
+	if err := initNetwork(signalCh, db); err != nil {
+		glog.Fatalf("Unable to setup network. %+v", err)
+	}
+
+	if err := startMetadataServer(); err != nil {
+		glog.Errorf("Unable to start metadata service. %+v", err)
+	}
+
 	if err := rebuildNetworkState(db); err != nil {
 		glog.Errorf("Unable to rebuild network state. %+v", err)
 	}
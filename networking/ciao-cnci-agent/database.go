@@ -30,11 +30,19 @@ type cnciDatabase struct {
 	database.DbProvider //Database used to persist the CNCI state
 	SubnetMap
 	PublicIPMap
+	DNSMap
+	LoadBalancerMap
+	MetadataMap
+	PeeringMap
 }
 
 const (
 	tableSubnetMap   = "SubnetMap"
 	tablePublicIPMap = "PublicIPMap"
+	tableDNSMap      = "DNSMap"
+	tableLBMap       = "LoadBalancerMap"
+	tableMetadataMap = "MetadataMap"
+	tablePeeringMap  = "PeeringMap"
 )
 
 //dbCfg controls plugin data base attributes
@@ -114,11 +122,143 @@ func (d *PublicIPMap) Add(k string, v interface{}) error {
 	return nil
 }
 
+//DNSMap maintains the DNS configuration of each tenant subnet handled
+//by this CNCI
+type DNSMap struct {
+	sync.Mutex
+	m map[string]*payloads.DNSCommand //index: Tenant Subnet
+}
+
+//NewTable creates a new map
+func (d *DNSMap) NewTable() {
+	d.m = make(map[string]*payloads.DNSCommand)
+}
+
+//Name provides the name of the map
+func (d *DNSMap) Name() string {
+	return tableDNSMap
+}
+
+//NewElement allocates and returns a DNS configuration value
+func (d *DNSMap) NewElement() interface{} {
+	return &payloads.DNSCommand{}
+}
+
+//Add adds a value to the map with the specified key
+func (d *DNSMap) Add(k string, v interface{}) error {
+	val, ok := v.(*payloads.DNSCommand)
+	if !ok {
+		return errors.Errorf("Invalid value type %t", v)
+	}
+	d.m[k] = val
+	return nil
+}
+
+//LoadBalancerMap maintains the configuration of the load balancers
+//served by this CNCI
+type LoadBalancerMap struct {
+	sync.Mutex
+	m map[string]*payloads.LoadBalancerCommand //index: Load Balancer UUID
+}
+
+//NewTable creates a new map
+func (d *LoadBalancerMap) NewTable() {
+	d.m = make(map[string]*payloads.LoadBalancerCommand)
+}
+
+//Name provides the name of the map
+func (d *LoadBalancerMap) Name() string {
+	return tableLBMap
+}
+
+//NewElement allocates and returns a load balancer configuration value
+func (d *LoadBalancerMap) NewElement() interface{} {
+	return &payloads.LoadBalancerCommand{}
+}
+
+//Add adds a value to the map with the specified key
+func (d *LoadBalancerMap) Add(k string, v interface{}) error {
+	val, ok := v.(*payloads.LoadBalancerCommand)
+	if !ok {
+		return errors.Errorf("Invalid value type %t", v)
+	}
+	d.m[k] = val
+	return nil
+}
+
+//MetadataMap maintains the metadata of the instances attached to each
+//tenant subnet handled by this CNCI
+type MetadataMap struct {
+	sync.Mutex
+	m map[string]*payloads.MetadataCommand //index: Tenant Subnet
+}
+
+//NewTable creates a new map
+func (d *MetadataMap) NewTable() {
+	d.m = make(map[string]*payloads.MetadataCommand)
+}
+
+//Name provides the name of the map
+func (d *MetadataMap) Name() string {
+	return tableMetadataMap
+}
+
+//NewElement allocates and returns an instance metadata value
+func (d *MetadataMap) NewElement() interface{} {
+	return &payloads.MetadataCommand{}
+}
+
+//Add adds a value to the map with the specified key
+func (d *MetadataMap) Add(k string, v interface{}) error {
+	val, ok := v.(*payloads.MetadataCommand)
+	if !ok {
+		return errors.Errorf("Invalid value type %t", v)
+	}
+	d.m[k] = val
+	return nil
+}
+
+//PeeringMap maintains the peered subnets of each tenant subnet handled
+//by this CNCI
+type PeeringMap struct {
+	sync.Mutex
+	m map[string]*payloads.PeeringCommand //index: Tenant Subnet
+}
+
+//NewTable creates a new map
+func (d *PeeringMap) NewTable() {
+	d.m = make(map[string]*payloads.PeeringCommand)
+}
+
+//Name provides the name of the map
+func (d *PeeringMap) Name() string {
+	return tablePeeringMap
+}
+
+//NewElement allocates and returns a peering value
+func (d *PeeringMap) NewElement() interface{} {
+	return &payloads.PeeringCommand{}
+}
+
+//Add adds a value to the map with the specified key
+func (d *PeeringMap) Add(k string, v interface{}) error {
+	val, ok := v.(*payloads.PeeringCommand)
+	if !ok {
+		return errors.Errorf("Invalid value type %t", v)
+	}
+	d.m[k] = val
+	return nil
+}
+
 func dbInit() (*cnciDatabase, error) {
 	db := &cnciDatabase{}
 	db.DbProvider = database.NewBoltDBProvider()
 	db.SubnetMap.m = make(map[string]*payloads.TenantAddedEvent)
 	db.PublicIPMap.m = make(map[string]*payloads.PublicIPCommand)
+	db.DNSMap.m = make(map[string]*payloads.DNSCommand)
+	db.LoadBalancerMap.m = make(map[string]*payloads.LoadBalancerCommand)
+	db.MetadataMap.m = make(map[string]*payloads.MetadataCommand)
+	db.PeeringMap.m = make(map[string]*payloads.PeeringCommand)
 
 	if err := db.DbInit(dbCfg.DataDir, dbCfg.DbFile); err != nil {
 		return nil, errors.Wrapf(err, "db init: %v, %v", dbCfg.DataDir, dbCfg.DbFile)
@@ -129,6 +269,18 @@ func dbInit() (*cnciDatabase, error) {
 	if err := db.DbTableRebuild(&db.PublicIPMap); err != nil {
 		return nil, errors.Wrapf(err, "publicIPMap")
 	}
+	if err := db.DbTableRebuild(&db.DNSMap); err != nil {
+		return nil, errors.Wrapf(err, "dnsMap")
+	}
+	if err := db.DbTableRebuild(&db.LoadBalancerMap); err != nil {
+		return nil, errors.Wrapf(err, "loadBalancerMap")
+	}
+	if err := db.DbTableRebuild(&db.MetadataMap); err != nil {
+		return nil, errors.Wrapf(err, "metadataMap")
+	}
+	if err := db.DbTableRebuild(&db.PeeringMap); err != nil {
+		return nil, errors.Wrapf(err, "peeringMap")
+	}
 	return db, nil
 }
 
@@ -199,6 +351,85 @@ func dbProcessCommand(db *cnciDatabase, cmd interface{}) error {
 			return errors.Wrapf(err, "delete Public IP from db: %v", c)
 		}
 
+	case *payloads.CommandUpdateDNS:
+
+		c := &netCmd.UpdateDNS
+
+		db.DNSMap.Lock()
+		defer db.DNSMap.Unlock()
+
+		key := c.TenantSubnet
+		db.DNSMap.m[key] = c
+
+		if err := db.DbAdd(tableDNSMap, key, db.DNSMap.m[key]); err != nil {
+			return errors.Wrapf(err, "add DNS configuration to db: %v", c)
+		}
+
+	case *payloads.CommandConfigureLoadBalancer:
+
+		c := &netCmd.ConfigureLoadBalancer
+
+		db.LoadBalancerMap.Lock()
+		defer db.LoadBalancerMap.Unlock()
+
+		key := c.LoadBalancerUUID
+		db.LoadBalancerMap.m[key] = c
+
+		if err := db.DbAdd(tableLBMap, key, db.LoadBalancerMap.m[key]); err != nil {
+			return errors.Wrapf(err, "add load balancer to db: %v", c)
+		}
+
+	case *payloads.CommandRemoveLoadBalancer:
+
+		c := &netCmd.RemoveLoadBalancer
+
+		db.LoadBalancerMap.Lock()
+		defer db.LoadBalancerMap.Unlock()
+
+		key := c.LoadBalancerUUID
+		delete(db.LoadBalancerMap.m, key)
+
+		if err := db.DbDelete(tableLBMap, key); err != nil {
+			return errors.Wrapf(err, "delete load balancer from db: %v", c)
+		}
+
+	case *payloads.CommandUpdateMetadata:
+
+		c := &netCmd.UpdateMetadata
+
+		db.MetadataMap.Lock()
+		defer db.MetadataMap.Unlock()
+
+		key := c.TenantSubnet
+		db.MetadataMap.m[key] = c
+
+		if err := db.DbAdd(tableMetadataMap, key, db.MetadataMap.m[key]); err != nil {
+			return errors.Wrapf(err, "add instance metadata to db: %v", c)
+		}
+
+	case *payloads.CommandUpdatePeering:
+
+		c := &netCmd.UpdatePeering
+
+		db.PeeringMap.Lock()
+		defer db.PeeringMap.Unlock()
+
+		key := c.TenantSubnet
+
+		if len(c.Peers) == 0 {
+			delete(db.PeeringMap.m, key)
+			if err := db.DbDelete(tablePeeringMap, key); err != nil {
+				return errors.Wrapf(err, "delete peering from db: %v", c)
+			}
+			break
+		}
+
+		db.PeeringMap.m[key] = c
+
+		if err := db.DbAdd(tablePeeringMap, key, db.PeeringMap.m[key]); err != nil {
+			return errors.Wrapf(err, "add peering to db: %v", c)
+		}
+
 	default:
 		return errors.Errorf("unknown command: %v", netCmd)
 
@@ -0,0 +1,338 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+
+	"github.com/ciao-project/ciao/networking/libsnnet"
+	"github.com/ciao-project/ciao/payloads"
+)
+
+//metadataServer serves the metadata of the instances attached to the
+//tenant subnets of the CNCI. Instances are identified by the source
+//address of their requests
+type metadataServer struct {
+	sync.RWMutex
+	subnets map[string]map[string]*payloads.InstanceMetadata //Tenant subnet to instance IP to metadata
+}
+
+var gMetadata = &metadataServer{
+	subnets: make(map[string]map[string]*payloads.InstanceMetadata),
+}
+
+//update replaces the metadata served to the instances of a tenant subnet
+func (s *metadataServer) update(subnet string, instances []payloads.InstanceMetadata) {
+	ips := make(map[string]*payloads.InstanceMetadata)
+	for i := range instances {
+		for _, nic := range instances[i].NICs {
+			if nic.Subnet == subnet {
+				ips[net.ParseIP(nic.IP).String()] = &instances[i]
+			}
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if len(ips) == 0 {
+		delete(s.subnets, subnet)
+		return
+	}
+	s.subnets[subnet] = ips
+}
+
+//lookup returns the metadata of the instance with the given address
+func (s *metadataServer) lookup(ip string) *payloads.InstanceMetadata {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, ips := range s.subnets {
+		if md, ok := ips[ip]; ok {
+			return md
+		}
+	}
+	return nil
+}
+
+func (s *metadataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	md := s.lookup(net.ParseIP(host).String())
+	if md == nil {
+		glog.Warningf("Metadata request from unknown instance %s", host)
+		http.NotFound(w, r)
+		return
+	}
+
+	var path []string
+	if p := strings.Trim(r.URL.Path, "/"); p != "" {
+		path = strings.Split(p, "/")
+	}
+
+	if len(path) > 0 && path[0] == "openstack" {
+		serveOpenStackMetadata(w, r, md, path[1:])
+		return
+	}
+	serveEC2Metadata(w, r, md, path)
+}
+
+func writeText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/plain")
+	_, _ = fmt.Fprint(w, text)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func writeUserData(w http.ResponseWriter, r *http.Request, md *payloads.InstanceMetadata) {
+	if md.UserData == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = fmt.Fprint(w, md.UserData)
+}
+
+func openStackMetaData(md *payloads.InstanceMetadata) map[string]interface{} {
+	keys := make(map[string]string)
+	for i, k := range md.PublicKeys {
+		keys[fmt.Sprintf("key%d", i)] = k
+	}
+
+	meta := md.Metadata
+	if meta == nil {
+		meta = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		"uuid":              md.InstanceUUID,
+		"name":              md.Name,
+		"hostname":          md.Hostname,
+		"public_keys":       keys,
+		"meta":              meta,
+		"availability_zone": "nova",
+		"launch_index":      0,
+	}
+}
+
+func openStackNetworkData(md *payloads.InstanceMetadata) map[string]interface{} {
+	links := []map[string]interface{}{}
+	networks := []map[string]interface{}{}
+
+	for i, nic := range md.NICs {
+		link := fmt.Sprintf("interface%d", i)
+		links = append(links, map[string]interface{}{
+			"id":                   link,
+			"type":                 "phy",
+			"ethernet_mac_address": nic.MAC,
+		})
+
+		_, snet, err := net.ParseCIDR(nic.Subnet)
+		if err != nil {
+			continue
+		}
+
+		network := map[string]interface{}{
+			"id":         fmt.Sprintf("network%d", i),
+			"link":       link,
+			"type":       "ipv4",
+			"ip_address": nic.IP,
+			"netmask":    net.IP(snet.Mask).String(),
+			"routes":     []map[string]string{},
+		}
+		if snet.IP.To4() == nil {
+			network["type"] = "ipv6"
+		}
+
+		//Only the primary NIC carries the default route
+		if i == 0 && nic.Gateway != "" {
+			dst, mask := "0.0.0.0", "0.0.0.0"
+			if snet.IP.To4() == nil {
+				dst, mask = "::", "::"
+			}
+			network["routes"] = []map[string]string{
+				{
+					"network": dst,
+					"netmask": mask,
+					"gateway": nic.Gateway,
+				},
+			}
+		}
+		networks = append(networks, network)
+	}
+
+	return map[string]interface{}{
+		"links":    links,
+		"networks": networks,
+		"services": []interface{}{},
+	}
+}
+
+//serveOpenStackMetadata serves the /openstack tree of the OpenStack
+//metadata service
+func serveOpenStackMetadata(w http.ResponseWriter, r *http.Request, md *payloads.InstanceMetadata, path []string) {
+	switch len(path) {
+	case 0:
+		writeText(w, "latest\n")
+		return
+	case 1:
+		writeText(w, "meta_data.json\nnetwork_data.json\nuser_data\n")
+		return
+	case 2:
+		switch path[1] {
+		case "meta_data.json":
+			writeJSON(w, openStackMetaData(md))
+			return
+		case "network_data.json":
+			writeJSON(w, openStackNetworkData(md))
+			return
+		case "user_data":
+			writeUserData(w, r, md)
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+//serveEC2Metadata serves the subset of the EC2 metadata service used by
+//cloud-init
+func serveEC2Metadata(w http.ResponseWriter, r *http.Request, md *payloads.InstanceMetadata, path []string) {
+	switch len(path) {
+	case 0:
+		writeText(w, "latest\n")
+		return
+	case 1:
+		writeText(w, "meta-data/\nuser-data\n")
+		return
+	}
+
+	switch path[1] {
+	case "user-data":
+		if len(path) == 2 {
+			writeUserData(w, r, md)
+			return
+		}
+	case "meta-data":
+		serveEC2MetaData(w, r, md, path[2:])
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func serveEC2MetaData(w http.ResponseWriter, r *http.Request, md *payloads.InstanceMetadata, path []string) {
+	values := map[string]string{
+		"instance-id":    md.InstanceUUID,
+		"hostname":       md.Hostname,
+		"local-hostname": md.Hostname,
+	}
+	if len(md.NICs) > 0 {
+		values["local-ipv4"] = md.NICs[0].IP
+		values["mac"] = md.NICs[0].MAC
+	}
+
+	if len(path) == 0 {
+		writeText(w, "hostname\ninstance-id\nlocal-hostname\nlocal-ipv4\nmac\npublic-keys/\n")
+		return
+	}
+
+	if path[0] != "public-keys" {
+		if v, ok := values[path[0]]; ok && len(path) == 1 {
+			writeText(w, v)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	switch len(path) {
+	case 1:
+		var list []string
+		for i := range md.PublicKeys {
+			list = append(list, fmt.Sprintf("%d=key%d", i, i))
+		}
+		writeText(w, strings.Join(list, "\n"))
+		return
+	case 2, 3:
+		var i int
+		if _, err := fmt.Sscanf(path[1], "%d", &i); err != nil || i < 0 || i >= len(md.PublicKeys) {
+			break
+		}
+		if len(path) == 2 {
+			writeText(w, "openssh-key\n")
+			return
+		}
+		if path[2] == "openssh-key" {
+			writeText(w, md.PublicKeys[i])
+			return
+		}
+	}
+
+	http.NotFound(w, r)
+}
+
+//startMetadataServer assigns the metadata service address to the CNCI
+//and starts serving the metadata of the instances of the tenant
+func startMetadataServer() error {
+	if !enableNetwork {
+		return nil
+	}
+
+	if err := gCnci.EnableMetadataService(); err != nil {
+		return errors.Wrapf(err, "metadata service")
+	}
+
+	addr := net.JoinHostPort(libsnnet.MetadataIP.String(), "80")
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "metadata service listen %s", addr)
+	}
+
+	go func() {
+		err := http.Serve(l, gMetadata)
+		glog.Errorf("Metadata service stopped: %v", err)
+	}()
+
+	glog.Infof("Metadata service listening on %s", addr)
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -38,13 +39,36 @@ var gFw *libsnnet.Firewall
 
 //TODO: Subscribe to netlink event to monitor physical interface changes
 //TODO: Why does go not allow chan interface{}
-func initNetwork(cancelCh <-chan os.Signal) error {
+//subnetDhcp returns the DHCP configuration of the subnets recorded in the
+//database so that the DHCP servers of existing bridges are restarted with it
+func subnetDhcp(db *cnciDatabase) map[string]libsnnet.DhcpConfig {
+	configs := make(map[string]libsnnet.DhcpConfig)
+	if db == nil {
+		return configs
+	}
+
+	db.SubnetMap.Lock()
+	defer db.SubnetMap.Unlock()
+
+	for _, subnet := range db.SubnetMap.m {
+		dhcp, err := unmarshallDhcpParams(subnet)
+		if err != nil {
+			glog.Warningf("Invalid DHCP configuration for %s: %v", subnet.TenantSubnet, err)
+			continue
+		}
+		configs[subnet.TenantSubnet] = dhcp
+	}
+	return configs
+}
+
+func initNetwork(cancelCh <-chan os.Signal, db *cnciDatabase) error {
 
 	cnci := &libsnnet.Cnci{}
 
 	cnci.NetworkConfig = &libsnnet.NetworkConfig{
 		Mode: libsnnet.GreTunnel,
 	}
+	cnci.SubnetDhcp = subnetDhcp(db)
 
 	if computeNet != "" {
 		_, cnet, _ := net.ParseCIDR(computeNet)
@@ -117,6 +141,50 @@ func unmarshallSubnetParams(cmd *payloads.TenantAddedEvent) (*net.IPNet, int, ne
 	return snet, subnetKey, cIP, nil
 }
 
+func unmarshallDhcpParams(cmd *payloads.TenantAddedEvent) (libsnnet.DhcpConfig, error) {
+	var dhcp libsnnet.DhcpConfig
+
+	params := []struct {
+		value string
+		ip    *net.IP
+	}{
+		{cmd.TenantGateway, &dhcp.Gateway},
+		{cmd.DHCPStart, &dhcp.Start},
+		{cmd.DHCPEnd, &dhcp.End},
+	}
+
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+
+		*p.ip = net.ParseIP(p.value)
+		if *p.ip == nil {
+			return dhcp, errors.Errorf("invalid DHCP parameter %s", p.value)
+		}
+	}
+
+	if cmd.IPv6Subnet == "" {
+		return dhcp, nil
+	}
+
+	_, prefix, err := net.ParseCIDR(cmd.IPv6Subnet)
+	if err != nil {
+		return dhcp, errors.Wrapf(err, "invalid IPv6 subnet %s", cmd.IPv6Subnet)
+	}
+	dhcp.IPv6Prefix = prefix
+
+	if cmd.IPv6Gateway != "" {
+		dhcp.IPv6Gateway = net.ParseIP(cmd.IPv6Gateway)
+		if dhcp.IPv6Gateway == nil {
+			return dhcp, errors.Errorf("invalid IPv6 gateway %s", cmd.IPv6Gateway)
+		}
+	}
+
+	dhcp.IPv6Mode, err = libsnnet.ParseIPv6Mode(cmd.IPv6Mode)
+	return dhcp, err
+}
+
 func genIPsInSubnet(subnet net.IPNet) []net.IP {
 
 	var allIPs []net.IP
@@ -174,10 +242,15 @@ func addRemoteSubnet(cmd *payloads.TenantAddedEvent) error {
 		return errors.Wrapf(err, "invalid params %s %x %s", rs, tk, rip)
 	}
 
+	dhcp, err := unmarshallDhcpParams(cmd)
+	if err != nil {
+		return errors.Wrapf(err, "invalid params %s %x %s", rs, tk, rip)
+	}
+
 	if !enableNetwork {
 		return nil
 	}
-	bridge, err := gCnci.AddRemoteSubnet(*rs, tk, rip)
+	bridge, err := gCnci.AddRemoteSubnetDhcp(*rs, dhcp, tk, rip)
 	if err != nil {
 		return errors.Wrapf(err, "add remote subnet %s %x %s", rs, tk, rip)
 	}
@@ -382,6 +455,8 @@ func unmarshallPubIP(cmd *payloads.PublicIPCommand) (net.IP, net.IP, error) {
 		return nil, nil, errors.Errorf("invalid private IP %v", cmd.PrivateIP)
 	case puIP == nil:
 		return nil, nil, errors.Errorf("invalid public IP %v", cmd.PublicIP)
+	case (prIP.To4() == nil) != (puIP.To4() == nil):
+		return nil, nil, errors.Errorf("address family mismatch %v %v", cmd.PrivateIP, cmd.PublicIP)
 	}
 
 	return prIP, puIP, nil
@@ -409,3 +484,210 @@ func releasePubIP(cmd *payloads.PublicIPCommand) error {
 	err = gFw.PublicIPAccess(libsnnet.FwDisable, prIP, puIP, gCnci.ComputeLink[0].Attrs().Name)
 	return errors.Wrapf(err, "release ip")
 }
+
+func unmarshallDNSParams(cmd *payloads.DNSCommand) (*net.IPNet, libsnnet.DNSConfig, error) {
+	dns := libsnnet.DNSConfig{
+		Domain:  cmd.Domain,
+		Records: make(map[string]net.IP),
+	}
+
+	_, snet, err := net.ParseCIDR(cmd.TenantSubnet)
+	if err != nil {
+		return nil, dns, errors.Wrapf(err, "invalid tenant subnet")
+	}
+
+	for _, f := range cmd.Forwarders {
+		ip := net.ParseIP(f)
+		if ip == nil {
+			return nil, dns, errors.Errorf("invalid forwarder %v", f)
+		}
+		dns.Forwarders = append(dns.Forwarders, ip)
+	}
+
+	for _, r := range cmd.Records {
+		ip := net.ParseIP(r.IP)
+		if ip == nil || !snet.Contains(ip) {
+			return nil, dns, errors.Errorf("invalid record %s %s", r.Name, r.IP)
+		}
+		dns.Records[r.Name] = ip
+	}
+
+	return snet, dns, nil
+}
+
+func updateDNS(cmd *payloads.DNSCommand) error {
+
+	snet, dns, err := unmarshallDNSParams(cmd)
+	if err != nil {
+		return errors.Wrapf(err, "invalid params %v", cmd)
+	}
+
+	if !enableNetwork {
+		return nil
+	}
+
+	err = gCnci.UpdateDNS(*snet, dns)
+	return errors.Wrapf(err, "update dns %s", snet)
+}
+
+func updateMetadata(cmd *payloads.MetadataCommand) error {
+	_, snet, err := net.ParseCIDR(cmd.TenantSubnet)
+	if err != nil {
+		return errors.Wrapf(err, "invalid params %v", cmd.TenantSubnet)
+	}
+
+	for _, i := range cmd.Instances {
+		for _, nic := range i.NICs {
+			if net.ParseIP(nic.IP) == nil {
+				return errors.Errorf("invalid IP %v for instance %v", nic.IP, i.InstanceUUID)
+			}
+		}
+	}
+
+	gMetadata.update(snet.String(), cmd.Instances)
+	return nil
+}
+
+//peerings tracks the peered subnets configured for each tenant subnet
+//so that the peers dropped by an update can be torn down
+var peerings = struct {
+	sync.Mutex
+	m map[string]map[string]payloads.PeerSubnet //Tenant subnet to peer subnet
+}{
+	m: make(map[string]map[string]payloads.PeerSubnet),
+}
+
+func peerSubnetAccess(action libsnnet.FwAction, local *net.IPNet, peer payloads.PeerSubnet) error {
+	_, psnet, err := net.ParseCIDR(peer.Subnet)
+	if err != nil {
+		return errors.Wrapf(err, "invalid peer subnet %v", peer.Subnet)
+	}
+
+	gw := net.ParseIP(peer.ConcentratorIP)
+	if gw == nil {
+		return errors.Errorf("invalid concentrator IP %v", peer.ConcentratorIP)
+	}
+
+	err = gCnci.PeerRoute(action, psnet, gw)
+	if err != nil {
+		return errors.Wrapf(err, "peer route %s", psnet)
+	}
+
+	err = gFw.PeerAccess(action, local, psnet)
+	return errors.Wrapf(err, "peer access %s", psnet)
+}
+
+//updatePeering routes the traffic of a tenant subnet to its peered subnets
+//through the CNCIs serving them. Peers absent from the update are removed
+func updatePeering(cmd *payloads.PeeringCommand) error {
+	_, snet, err := net.ParseCIDR(cmd.TenantSubnet)
+	if err != nil {
+		return errors.Wrapf(err, "invalid params %v", cmd.TenantSubnet)
+	}
+
+	if !enableNetwork {
+		return nil
+	}
+
+	peerings.Lock()
+	defer peerings.Unlock()
+
+	key := snet.String()
+	cur := peerings.m[key]
+	next := make(map[string]payloads.PeerSubnet)
+
+	var lastError error
+
+	for _, p := range cmd.Peers {
+		if old, ok := cur[p.Subnet]; ok && old == p {
+			next[p.Subnet] = p
+			continue
+		}
+
+		if err := peerSubnetAccess(libsnnet.FwEnable, snet, p); err != nil {
+			lastError = err
+			continue
+		}
+		next[p.Subnet] = p
+	}
+
+	for subnet, p := range cur {
+		if _, ok := next[subnet]; ok {
+			continue
+		}
+
+		if err := peerSubnetAccess(libsnnet.FwDisable, snet, p); err != nil {
+			lastError = err
+		}
+	}
+
+	if len(next) == 0 {
+		delete(peerings.m, key)
+	} else {
+		peerings.m[key] = next
+	}
+
+	return errors.Wrapf(lastError, "update peering %s", snet)
+}
+
+func unmarshallLoadBalancerParams(cmd *payloads.LoadBalancerCommand) (libsnnet.LoadBalancerConfig, error) {
+	lb := libsnnet.LoadBalancerConfig{
+		ID:       cmd.LoadBalancerUUID,
+		VIP:      net.ParseIP(cmd.VIP),
+		Port:     cmd.Port,
+		Protocol: cmd.Protocol,
+		HealthCheck: libsnnet.LoadBalancerHealthCheck{
+			Interval: time.Duration(cmd.HealthCheck.Interval) * time.Second,
+			Timeout:  time.Duration(cmd.HealthCheck.Timeout) * time.Second,
+			Retries:  cmd.HealthCheck.Retries,
+			Path:     cmd.HealthCheck.Path,
+		},
+	}
+
+	if lb.VIP == nil {
+		return lb, errors.Errorf("invalid VIP %v", cmd.VIP)
+	}
+
+	for _, m := range cmd.Members {
+		ip := net.ParseIP(m.IP)
+		if ip == nil {
+			return lb, errors.Errorf("invalid member %v", m.IP)
+		}
+		lb.Members = append(lb.Members, libsnnet.LoadBalancerMember{
+			IP:   ip,
+			Port: m.Port,
+		})
+	}
+
+	return lb, nil
+}
+
+func configureLoadBalancer(cmd *payloads.LoadBalancerCommand) error {
+
+	lb, err := unmarshallLoadBalancerParams(cmd)
+	if err != nil {
+		return errors.Wrapf(err, "invalid params %v", cmd)
+	}
+
+	if !enableNetwork {
+		return nil
+	}
+
+	err = gCnci.ConfigureLoadBalancer(lb)
+	return errors.Wrapf(err, "configure load balancer %s", lb.ID)
+}
+
+func removeLoadBalancer(cmd *payloads.LoadBalancerRemoveCommand) error {
+
+	vip := net.ParseIP(cmd.VIP)
+	if vip == nil {
+		return errors.Errorf("invalid params %v", cmd)
+	}
+
+	if !enableNetwork {
+		return nil
+	}
+
+	err := gCnci.RemoveLoadBalancer(cmd.LoadBalancerUUID, vip)
+	return errors.Wrapf(err, "remove load balancer %s", cmd.LoadBalancerUUID)
+}
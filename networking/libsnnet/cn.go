@@ -59,13 +59,16 @@ type VnicConfig struct {
 	ConcIP     net.IP
 	VnicMAC    net.HardwareAddr
 	MTU        int
+	RxMbit     int // optional: limit of the traffic received in Mbit/s
+	TxMbit     int // optional: limit of the traffic transmitted in Mbit/s
 	SubnetKey  int //optional: Currently set to SubnetIP
 	Subnet     net.IPNet
-	VnicID     string // UUID
-	InstanceID string // UUID
-	TenantID   string // UUID
-	SubnetID   string // UUID
-	ConcID     string // UUID
+	Dhcp       DhcpConfig // optional: Gateway and DHCP range of the subnet
+	VnicID     string     // UUID
+	InstanceID string     // UUID
+	TenantID   string     // UUID
+	SubnetID   string     // UUID
+	ConcID     string     // UUID
 }
 
 // CNSsntpEvent to be generated in response to a VNIC creation
@@ -94,6 +97,12 @@ type SsntpEventInfo struct {
 	ConcID            string       // CNCI UUID
 	CnID              string       // CN UUID
 	SubnetKey         int
+	Gateway           string // Tenant Subnet Gateway, optional
+	DhcpStart         string // First address of the DHCP range, optional
+	DhcpEnd           string // Last address of the DHCP range, optional
+	IPv6Subnet        string // IPv6 prefix of a dual stack subnet, optional
+	IPv6Gateway       string // IPv6 gateway of a dual stack subnet, optional
+	IPv6Mode          string // IPv6 address mode of a dual stack subnet, optional
 	containerSubnetID string // Logical name of the container network.
 	// Hack: Will be removed once we drop deprecated APIs
 }
@@ -289,7 +298,7 @@ func (cn *ComputeNode) Init() error {
 	}
 
 	//TODO: Support all modes
-	if cn.Mode != GreTunnel {
+	if cn.Mode != GreTunnel && cn.Mode != VxlanTunnel {
 		return NewAPIError(fmt.Sprintf("Unsupported network mode %v", cn.Mode))
 	}
 
@@ -308,15 +317,45 @@ type vnicAliases struct {
 	bridge string
 	vnic   string
 	gre    string
+	vxlan  string
 }
 
 const (
 	bridgePrefix   = "br_"
 	vnicPrefix     = "vnic_"
 	grePrefix      = "gre_"
+	vxlanPrefix    = "vxlan_"
 	cnciVnicPrefix = "cncivnic_"
 )
 
+//tunnelPrefix returns the alias prefix of the tunnels used in the
+//configured network mode
+func (cn *ComputeNode) tunnelPrefix() string {
+	if cn.Mode == VxlanTunnel {
+		return vxlanPrefix
+	}
+	return grePrefix
+}
+
+//tunnelAlias returns the alias of the tunnel used in the configured
+//network mode
+func (cn *ComputeNode) tunnelAlias(alias *vnicAliases) string {
+	if cn.Mode == VxlanTunnel {
+		return alias.vxlan
+	}
+	return alias.gre
+}
+
+//newTunnelEP initializes the tunnel to the CNCI based on the configured
+//network mode. With VXLAN the subnet key is used as the VNI and the CNCI
+//is added as the remote end point in the forwarding database
+func (cn *ComputeNode) newTunnelEP(alias *vnicAliases, local net.IP, remote net.IP, key uint32) (tunnelEP, error) {
+	if cn.Mode == VxlanTunnel {
+		return newVxlanEP(alias.vxlan, local, remote, key)
+	}
+	return newGreTunEP(alias.gre, local, remote, key)
+}
+
 func (cn *ComputeNode) genCnciVnicAlias(cfg *VnicConfig) string {
 	return fmt.Sprintf("%s%s_%s", cnciVnicPrefix,
 		cfg.TenantID,
@@ -352,6 +391,12 @@ func genCnVnicAliases(cfg *VnicConfig) *vnicAliases {
 		cfg.ConcID,
 		cfg.ConcIP)
 
+	vnic.vxlan = fmt.Sprintf("%s%s_%s_%s_%s", vxlanPrefix,
+		cfg.TenantID,
+		cfg.SubnetID,
+		cfg.ConcID,
+		cfg.ConcIP)
+
 	vnic.vnic = fmt.Sprintf("%s%s_%s_%s_%s##%s", vnicPrefix,
 		cfg.TenantID,
 		cfg.SubnetID,
@@ -427,12 +472,12 @@ func (cn *ComputeNode) rebuildVnicMap(links []netlink.Link) error {
 				id := strings.TrimPrefix(vnic, vnicPrefix)
 				id = strings.Split(id, "##")[0]
 				bridge := bridgePrefix + id
-				gre := grePrefix + id
+				tunnel := cn.tunnelPrefix() + id
 				if _, err := cn.dbUpdate(bridge, vnic, dbInsVnic); err != nil {
 					return NewFatalError("db rebuild: add vnic" + err.Error())
 				}
-				if _, ok := cn.linkMap[gre]; !ok {
-					return NewFatalError("db rebuild: missing gre tunnel " + gre)
+				if _, ok := cn.linkMap[tunnel]; !ok {
+					return NewFatalError("db rebuild: missing tunnel " + tunnel)
 				}
 				if link.Type() == "veth" {
 					cn.containerMap[bridge] = true
@@ -711,10 +756,36 @@ func newCNVnic(cfg *VnicConfig, id string) (*Vnic, error) {
 	}
 	vnic.MACAddr = &cfg.VnicMAC
 	vnic.MTU = cfg.MTU
+	vnic.RxMbit = cfg.RxMbit
+	vnic.TxMbit = cfg.TxMbit
 
 	return vnic, nil
 }
 
+// VnicStats returns the traffic counters of the tenant VNIC described by cfg.
+// The VNIC must have been created by an earlier call to CreateVnic.
+func (cn *ComputeNode) VnicStats(cfg *VnicConfig) (*VnicStats, error) {
+	if cfg == nil {
+		return nil, NewAPIError("invalid vnic or configuration")
+	}
+
+	if err := checkCnVnicCfg(cfg); err != nil {
+		return nil, NewAPIError("invalid vnic or configuration")
+	}
+
+	alias := genCnVnicAliases(cfg)
+	vnic, err := newCNVnic(cfg, alias.vnic)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := vnic.GetDevice(); err != nil {
+		return nil, NewAPIError(err.Error())
+	}
+
+	return vnic.Stats()
+}
+
 func (cn *ComputeNode) waitForExistingVnic(vnic *Vnic, bridge *Bridge, vLink *linkInfo, bLink *linkInfo, cfg *VnicConfig) (*Vnic, *SsntpEventInfo, *ContainerInfo, error) {
 	var err error
 
@@ -722,6 +793,14 @@ func (cn *ComputeNode) waitForExistingVnic(vnic *Vnic, bridge *Bridge, vLink *li
 	if err != nil {
 		return nil, nil, nil, NewFatalError(vnic.GlobalID + err.Error())
 	}
+
+	//The VNIC may have been created before the node was restarted or
+	//by an earlier incarnation of the instance, so its limits are
+	//applied again
+	if err := setVnicBandwidth(vnic); err != nil {
+		return nil, nil, nil, NewFatalError(err.Error())
+	}
+
 	if cfg.VnicRole == TenantVM {
 		return vnic, nil, nil, nil
 	}
@@ -739,7 +818,7 @@ func (cn *ComputeNode) waitForExistingVnic(vnic *Vnic, bridge *Bridge, vLink *li
 
 }
 
-func (cn *ComputeNode) createDevicesFromCfg(cfg *VnicConfig) (*Vnic, *Bridge, *GreTunEP, error) {
+func (cn *ComputeNode) createDevicesFromCfg(cfg *VnicConfig) (*Vnic, *Bridge, tunnelEP, error) {
 
 	alias := genCnVnicAliases(cfg)
 
@@ -754,12 +833,12 @@ func (cn *ComputeNode) createDevicesFromCfg(cfg *VnicConfig) (*Vnic, *Bridge, *G
 	}
 
 	local := cn.ComputeAddr[0].IPNet.IP
-	gre, err := newGreTunEP(alias.gre, local, cfg.ConcIP, uint32(cfg.SubnetKey))
+	tunnel, err := cn.newTunnelEP(alias, local, cfg.ConcIP, uint32(cfg.SubnetKey))
 	if err != nil {
 		return nil, nil, nil, NewAPIError(err.Error())
 	}
 
-	return vnic, bridge, gre, nil
+	return vnic, bridge, tunnel, nil
 
 }
 
@@ -797,9 +876,9 @@ func (cn *ComputeNode) addVnicToBridge(cfg *VnicConfig, vnic *Vnic, bridge *Brid
 }
 
 func (cn *ComputeNode) createVnicInternal(cfg *VnicConfig) (*Vnic, *SsntpEventInfo, *ContainerInfo, error) {
-	var gLink *linkInfo
+	var tLink *linkInfo
 
-	vnic, bridge, gre, err := cn.createDevicesFromCfg(cfg)
+	vnic, bridge, tunnel, err := cn.createDevicesFromCfg(cfg)
 
 	if err != nil {
 		return nil, nil, nil, err
@@ -831,13 +910,13 @@ func (cn *ComputeNode) createVnicInternal(cfg *VnicConfig) (*Vnic, *SsntpEventIn
 		return cn.addVnicToBridge(cfg, vnic, bridge, vLink, bLink)
 	}
 
-	if err := cn.logicallyCreateBridge(bridge, gre, vnic); err != nil {
+	if err := cn.logicallyCreateBridge(bridge, tunnel, vnic); err != nil {
 		cn.cnTopology.Unlock()
 		return nil, nil, nil, NewFatalError(err.Error())
 	}
 
-	gLink = cn.linkMap[gre.GlobalID]
-	defer close(gLink.ready)
+	tLink = cn.linkMap[tunnel.attrs().GlobalID]
+	defer close(tLink.ready)
 
 	bLink = cn.linkMap[bridge.GlobalID]
 	defer close(bLink.ready)
@@ -853,23 +932,13 @@ func (cn *ComputeNode) createVnicInternal(cfg *VnicConfig) (*Vnic, *SsntpEventIn
 	//but is outside the critical section
 	//The defer close(ready) ensures that
 	//the channel will close even on failure
-	brCreateMsg := &SsntpEventInfo{
-		Event:     SsntpTunAdd,
-		CnciIP:    cfg.ConcIP.String(),
-		ConcID:    cfg.ConcID,
-		TenantID:  cfg.TenantID,
-		SubnetID:  cfg.SubnetID,
-		SubnetKey: cfg.SubnetKey,
-		Subnet:    cfg.Subnet.String(),
-		CnIP:      gre.LocalIP.String(),
-		CnID:      cn.ID,
-	}
-
-	if err := createAndEnableBridge(bridge, gre); err != nil {
+	brCreateMsg := cn.tunnelEvent(SsntpTunAdd, cfg)
+
+	if err := createAndEnableBridge(bridge, tunnel); err != nil {
 		return nil, brCreateMsg, nil, NewFatalError(err.Error())
 	}
 	bLink.index = bridge.Link.Index
-	gLink.index = gre.Link.Index
+	tLink.index = tunnel.link().Attrs().Index
 
 	//iptables -A FORWARD -p all -i "$bridge" -j ACCEPT
 	err = cn.AppendUnique("filter", "FORWARD",
@@ -893,17 +962,35 @@ func (cn *ComputeNode) createVnicInternal(cfg *VnicConfig) (*Vnic, *SsntpEventIn
 	return vnic, brCreateMsg, cInfo, nil
 }
 
+//tunnelEvent generates the SSNTP event that notifies the CNCI of
+//cfg of a change to the local end of the tenant subnet tunnel
+func (cn *ComputeNode) tunnelEvent(event CNSsntpEvent, cfg *VnicConfig) *SsntpEventInfo {
+	return &SsntpEventInfo{
+		Event:       event,
+		CnciIP:      cfg.ConcIP.String(),
+		ConcID:      cfg.ConcID,
+		TenantID:    cfg.TenantID,
+		SubnetID:    cfg.SubnetID,
+		SubnetKey:   cfg.SubnetKey,
+		Subnet:      cfg.Subnet.String(),
+		Gateway:     ipString(cfg.Dhcp.Gateway),
+		DhcpStart:   ipString(cfg.Dhcp.Start),
+		DhcpEnd:     ipString(cfg.Dhcp.End),
+		IPv6Subnet:  ipNetString(cfg.Dhcp.IPv6Prefix),
+		IPv6Gateway: ipString(cfg.Dhcp.IPv6GatewayIP()),
+		IPv6Mode:    ipv6ModeString(cfg.Dhcp),
+		CnIP:        cn.ComputeAddr[0].IPNet.IP.String(),
+		CnID:        cn.ID,
+	}
+}
+
 func getContainerInfo(cfg *VnicConfig, vnic *Vnic, bridge *Bridge) *ContainerInfo {
-	//TODO. Create a ciao gateway function so that in the future
-	//if we ever change our gateway algorithm it will propagate everywhere
-	gateway := cfg.Subnet.IP.To4().Mask(cfg.Subnet.Mask)
-	gateway[3]++
 	return &ContainerInfo{
 		CNContainerEvent: ContainerNetworkInfo, //Default. Caller to override
 		SubnetID:         bridge.LinkName,
 		Bridge:           bridge.GlobalID,
 		Subnet:           cfg.Subnet,
-		Gateway:          gateway,
+		Gateway:          cfg.Dhcp.GatewayIP(cfg.Subnet),
 	}
 }
 
@@ -936,11 +1023,13 @@ func (cn *ComputeNode) logicallyCreateVnic(vnic *Vnic) (err error) {
 //The physical devices are not yet created but their names aliases
 //are added to the topology reserving them
 //TODO: Check for global topology issues. E.g. Two tenants with same CNCI
-func (cn *ComputeNode) logicallyCreateBridge(bridge *Bridge, gre *GreTunEP, vnic *Vnic) (err error) {
+func (cn *ComputeNode) logicallyCreateBridge(bridge *Bridge, tunnel tunnelEP, vnic *Vnic) (err error) {
+	tun := tunnel.attrs()
+
 	if bridge.LinkName, err = cn.genLinkName(bridge); err != nil {
 		return err
 	}
-	if gre.LinkName, err = cn.genLinkName(gre); err != nil {
+	if tun.LinkName, err = cn.genLinkName(tunnel); err != nil {
 		return err
 	}
 	if _, err = cn.dbUpdate(bridge.GlobalID, "", dbInsBr); err != nil {
@@ -950,8 +1039,8 @@ func (cn *ComputeNode) logicallyCreateBridge(bridge *Bridge, gre *GreTunEP, vnic
 		return err
 	}
 
-	cn.linkMap[gre.GlobalID] = &linkInfo{
-		name:  gre.LinkName,
+	cn.linkMap[tun.GlobalID] = &linkInfo{
+		name:  tun.LinkName,
 		ready: make(chan struct{}),
 	}
 
@@ -966,22 +1055,24 @@ func (cn *ComputeNode) logicallyCreateBridge(bridge *Bridge, gre *GreTunEP, vnic
 //Physically create the devices by calling into the kernel
 //TODO: Try to be more fault tolerant here. We may miss errors but try to
 // honor the request  e.g. If bridge exists use it and try and create tunnel
-func createAndEnableBridge(bridge *Bridge, gre *GreTunEP) error {
+func createAndEnableBridge(bridge *Bridge, tunnel tunnelEP) error {
+	tun := tunnel.attrs()
+
 	if err := bridge.Create(); err != nil {
 		return fmt.Errorf("Bridge creation failed %s %s", bridge.GlobalID, err.Error())
 	}
-	if err := gre.create(); err != nil {
-		return fmt.Errorf("GRE creation failed %s %s", gre.GlobalID, err.Error())
+	if err := tunnel.create(); err != nil {
+		return fmt.Errorf("Tunnel creation failed %s %s", tun.GlobalID, err.Error())
 	}
-	if err := gre.attach(bridge); err != nil {
-		return fmt.Errorf("GRE attach failed %s %s %s", gre.GlobalID, bridge.GlobalID, err.Error())
+	if err := tunnel.attach(bridge); err != nil {
+		return fmt.Errorf("Tunnel attach failed %s %s %s", tun.GlobalID, bridge.GlobalID, err.Error())
 	}
 
-	if err := gre.enable(); err != nil {
-		return fmt.Errorf("GRE enable failed %s %s %s", gre.GlobalID, bridge.GlobalID, err.Error())
+	if err := tunnel.enable(); err != nil {
+		return fmt.Errorf("Tunnel enable failed %s %s %s", tun.GlobalID, bridge.GlobalID, err.Error())
 	}
 	if err := bridge.Enable(); err != nil {
-		return fmt.Errorf("Bridge enable failed %s %s %s", gre.GlobalID, bridge.GlobalID, err.Error())
+		return fmt.Errorf("Bridge enable failed %s %s %s", tun.GlobalID, bridge.GlobalID, err.Error())
 	}
 	return nil
 }
@@ -1009,6 +1100,17 @@ func createAndEnableVnic(vnic *Vnic, bridge *Bridge) error {
 	if err := vnic.Enable(); err != nil {
 		return fmt.Errorf("VNIC enable failed %s %s %s", vnic.GlobalID, bridge.GlobalID, err.Error())
 	}
+	return setVnicBandwidth(vnic)
+}
+
+//Apply the traffic limits of the VNIC, if any, to its device
+func setVnicBandwidth(vnic *Vnic) error {
+	if vnic.RxMbit == 0 && vnic.TxMbit == 0 {
+		return nil
+	}
+	if err := vnic.SetBandwidth(vnic.RxMbit, vnic.TxMbit); err != nil {
+		return fmt.Errorf("VNIC set bandwidth failed %s %s", vnic.GlobalID, err.Error())
+	}
 	return nil
 }
 
@@ -1108,18 +1210,20 @@ func (cn *ComputeNode) deleteVnicInternal(vnic *Vnic, vLink *linkInfo) (err erro
 }
 
 //Note: Can only be called when holding the topology lock cn.cnTopology.Lock()
-func (cn *ComputeNode) deleteGreInternal(gre *GreTunEP, gLink *linkInfo) (err error) {
-	gre.LinkName, gre.Link.Index, err = waitForDeviceReady(gLink, cn.APITimeout)
+func (cn *ComputeNode) deleteTunnelInternal(tunnel tunnelEP, tLink *linkInfo) (err error) {
+	tun := tunnel.attrs()
+
+	tun.LinkName, tunnel.link().Attrs().Index, err = waitForDeviceReady(tLink, cn.APITimeout)
 	if err != nil {
-		return NewFatalError(gre.GlobalID + err.Error())
+		return NewFatalError(tun.GlobalID + err.Error())
 	}
 
-	err = gre.destroy()
+	err = tunnel.destroy()
 	if err != nil {
-		return NewFatalError("gre destroy " + gre.GlobalID + err.Error())
+		return NewFatalError("tunnel destroy " + tun.GlobalID + err.Error())
 	}
-	delete(cn.nameMap, gre.LinkName)
-	delete(cn.linkMap, gre.GlobalID)
+	delete(cn.nameMap, tun.LinkName)
+	delete(cn.linkMap, tun.GlobalID)
 	return nil
 }
 
@@ -1200,33 +1304,23 @@ func (cn *ComputeNode) destroyVnicInternal(cfg *VnicConfig) (*SsntpEventInfo, er
 		return nil, NewFatalError(err.Error())
 	}
 
-	gre, err := newGreTunEP(alias.gre, nil, nil, 0)
+	tunnel, err := cn.newTunnelEP(alias, nil, nil, 0)
 	if err != nil {
 		return nil, NewFatalError(err.Error())
 	}
 
-	brDeleteMsg = &SsntpEventInfo{
-		Event:     SsntpTunDel,
-		CnciIP:    cfg.ConcIP.String(),
-		ConcID:    cfg.ConcID,
-		TenantID:  cfg.TenantID,
-		SubnetID:  cfg.SubnetID,
-		SubnetKey: cfg.SubnetKey,
-		Subnet:    cfg.Subnet.String(),
-		CnIP:      cn.ComputeAddr[0].IPNet.IP.String(),
-		CnID:      cn.ID,
-	}
+	brDeleteMsg = cn.tunnelEvent(SsntpTunDel, cfg)
 
 	//TODO: Try and make forward progress even on error
-	gLink, present := cn.linkMap[alias.gre]
+	tLink, present := cn.linkMap[cn.tunnelAlias(alias)]
 	if present {
-		err := cn.deleteGreInternal(gre, gLink)
+		err := cn.deleteTunnelInternal(tunnel, tLink)
 		if err != nil {
 			return nil, err
 		}
 	} else {
 		//TODO: Consider logging this and continue to delete bridge
-		return nil, NewFatalError(fmt.Sprintf("gre tunnel not present %s", gre.GlobalID))
+		return nil, NewFatalError(fmt.Sprintf("tunnel not present %s", cn.tunnelAlias(alias)))
 	}
 
 	bLink, present := cn.linkMap[alias.bridge]
@@ -1248,6 +1342,206 @@ func (cn *ComputeNode) destroyVnicInternal(cfg *VnicConfig) (*SsntpEventInfo, er
 	return brDeleteMsg, nil
 }
 
+// MoveSubnet re-points the tenant subnet described by cfg from the CNCI
+// in cfg to the CNCI identified by concID and concIP. It is used when a
+// standby CNCI takes over a tenant subnet from a failed CNCI.
+//
+// The tunnel to the old CNCI is destroyed and a new tunnel to the new CNCI
+// is attached to the existing tenant bridge. The bridge and the VNICs
+// attached to it are preserved so that running instances are not disturbed,
+// however their aliases are updated to reference the new CNCI. Subsequent
+// calls to CreateVnic and DestroyVnic for the subnet must use the new CNCI.
+//
+// If the subnet has VNICs on this node a SsntpTunAdd event is returned.
+// This message needs to be sent to the new CNCI which will setup the far side
+// of the tunnel.
+// Note: The caller of this function is responsible to send the message to the
+// scheduler or CNCI
+func (cn *ComputeNode) MoveSubnet(cfg *VnicConfig, concID string, concIP net.IP) (*SsntpEventInfo, error) {
+	if cn.cnTopology == nil || cfg == nil {
+		return nil, NewAPIError("invalid vnic or configuration")
+	}
+
+	if err := checkCnVnicCfg(cfg); err != nil {
+		return nil, NewAPIError(err.Error())
+	}
+
+	if concID == "" || concIP == nil {
+		return nil, NewAPIError("invalid concentrator")
+	}
+
+	cn.apiThrottleSem <- 1
+	defer func() {
+		<-cn.apiThrottleSem
+	}()
+
+	if apiCancelled(cfg.CancelChan) {
+		return nil, NewAPIError("API Cancelled for " + cfg.VnicID)
+	}
+
+	newCfg := *cfg
+	newCfg.ConcID = concID
+	newCfg.ConcIP = concIP
+
+	return cn.moveSubnetInternal(cfg, &newCfg)
+}
+
+//setLinkAlias updates the alias of an existing link identified by its name
+func setLinkAlias(name string, alias string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("link by name %s %s", name, err.Error())
+	}
+
+	if err := netlink.LinkSetAlias(link, alias); err != nil {
+		return fmt.Errorf("link set alias %s %s %s", name, alias, err.Error())
+	}
+	return nil
+}
+
+//Physically create the tunnel and attach it to an existing bridge
+func createAndAttachTunnel(tunnel tunnelEP, bridge *Bridge) error {
+	tun := tunnel.attrs()
+
+	if err := tunnel.create(); err != nil {
+		return fmt.Errorf("Tunnel creation failed %s %s", tun.GlobalID, err.Error())
+	}
+	if err := tunnel.attach(bridge); err != nil {
+		return fmt.Errorf("Tunnel attach failed %s %s %s", tun.GlobalID, bridge.GlobalID, err.Error())
+	}
+	if err := tunnel.enable(); err != nil {
+		return fmt.Errorf("Tunnel enable failed %s %s %s", tun.GlobalID, bridge.GlobalID, err.Error())
+	}
+	return nil
+}
+
+//moveVnicAliases re-aliases the VNICs attached to the bridge oldBridge
+//so that they reference the bridge newBridge. The VNIC devices, and so
+//their traffic limits, are left untouched
+//Note: Can only be called when holding the topology lock cn.cnTopology.Lock()
+func (cn *ComputeNode) moveVnicAliases(oldBridge string, newBridge string) error {
+	oldPrefix := vnicPrefix + strings.TrimPrefix(oldBridge, bridgePrefix)
+	newPrefix := vnicPrefix + strings.TrimPrefix(newBridge, bridgePrefix)
+
+	vnicMap := make(map[string]bool)
+	for vnic := range cn.bridgeMap[oldBridge] {
+		newVnic := newPrefix + strings.TrimPrefix(vnic, oldPrefix)
+
+		vLink, present := cn.linkMap[vnic]
+		if !present {
+			return NewFatalError("vnic not present " + vnic)
+		}
+
+		name, _, err := waitForDeviceReady(vLink, cn.APITimeout)
+		if err != nil {
+			return NewFatalError(vnic + err.Error())
+		}
+
+		if err := setLinkAlias(name, newVnic); err != nil {
+			return NewFatalError(err.Error())
+		}
+
+		delete(cn.linkMap, vnic)
+		cn.linkMap[newVnic] = vLink
+		vnicMap[newVnic] = true
+	}
+
+	delete(cn.bridgeMap, oldBridge)
+	cn.bridgeMap[newBridge] = vnicMap
+
+	if cn.containerMap[oldBridge] {
+		delete(cn.containerMap, oldBridge)
+		cn.containerMap[newBridge] = true
+	}
+
+	return nil
+}
+
+//TODO: Try and make forward progress even on error. A failure part way
+//through leaves the subnet partially moved and the node has to be reset.
+func (cn *ComputeNode) moveSubnetInternal(oldCfg *VnicConfig, newCfg *VnicConfig) (*SsntpEventInfo, error) {
+	oldAlias := genCnVnicAliases(oldCfg)
+	newAlias := genCnVnicAliases(newCfg)
+
+	// The entire move has to be performed in a CS
+	// as VNICs cannot be added to or removed from the
+	// bridge while its tunnel is replaced
+	cn.cnTopology.Lock()
+	defer cn.cnTopology.Unlock()
+
+	bLink, present := cn.linkMap[oldAlias.bridge]
+	if !present {
+		//No instances on this subnet or the subnet has already moved
+		return nil, nil
+	}
+
+	if _, present := cn.linkMap[newAlias.bridge]; present {
+		return nil, NewFatalError("bridge already present " + newAlias.bridge)
+	}
+
+	bridge, err := NewBridge(oldAlias.bridge)
+	if err != nil {
+		return nil, NewFatalError(err.Error())
+	}
+
+	bridge.LinkName, bridge.Link.Index, err = waitForDeviceReady(bLink, cn.APITimeout)
+	if err != nil {
+		return nil, NewFatalError(bridge.GlobalID + err.Error())
+	}
+
+	//The old tunnel has to be removed first as a VXLAN VNI can
+	//only be used by a single device
+	tLink, present := cn.linkMap[cn.tunnelAlias(oldAlias)]
+	if !present {
+		return nil, NewFatalError(fmt.Sprintf("tunnel not present %s", cn.tunnelAlias(oldAlias)))
+	}
+
+	oldTunnel, err := cn.newTunnelEP(oldAlias, nil, nil, 0)
+	if err != nil {
+		return nil, NewFatalError(err.Error())
+	}
+
+	if err := cn.deleteTunnelInternal(oldTunnel, tLink); err != nil {
+		return nil, err
+	}
+
+	local := cn.ComputeAddr[0].IPNet.IP
+	tunnel, err := cn.newTunnelEP(newAlias, local, newCfg.ConcIP, uint32(newCfg.SubnetKey))
+	if err != nil {
+		return nil, NewFatalError(err.Error())
+	}
+
+	tun := tunnel.attrs()
+	if tun.LinkName, err = cn.genLinkName(tunnel); err != nil {
+		return nil, NewFatalError(err.Error())
+	}
+
+	if err := createAndAttachTunnel(tunnel, bridge); err != nil {
+		delete(cn.nameMap, tun.LinkName)
+		return nil, NewFatalError(err.Error())
+	}
+
+	tLink = &linkInfo{
+		index: tunnel.link().Attrs().Index,
+		name:  tun.LinkName,
+		ready: make(chan struct{}),
+	}
+	close(tLink.ready)
+	cn.linkMap[tun.GlobalID] = tLink
+
+	if err := bridge.setAlias(newAlias.bridge); err != nil {
+		return nil, NewFatalError(err.Error())
+	}
+	delete(cn.linkMap, oldAlias.bridge)
+	cn.linkMap[newAlias.bridge] = bLink
+
+	if err := cn.moveVnicAliases(oldAlias.bridge, newAlias.bridge); err != nil {
+		return nil, err
+	}
+
+	return cn.tunnelEvent(SsntpTunAdd, newCfg), nil
+}
+
 //ResetNetwork will attempt to clean up all network interfaces
 //created. It will not clean up any interfaces created manually
 func (cn *ComputeNode) ResetNetwork() error {
@@ -685,6 +685,96 @@ func TestCN_Base(t *testing.T) {
 	}
 }
 
+//Tests moving a tenant subnet to a new CNCI
+//
+//This tests creates two VNICs on a tenant subnet, moves the
+//subnet to a new CNCI and checks that the VNICs are preserved
+//and can be destroyed using the new CNCI
+//
+//Test is expected to pass
+func TestCN_MoveSubnet(t *testing.T) {
+	assert := assert.New(t)
+	cn, err := cnTestInit()
+	require.Nil(t, err)
+
+	_, tenantNet, _ := net.ParseCIDR("192.168.1.0/24")
+
+	mac, _ := net.ParseMAC("CA:FE:00:01:02:03")
+	vnicCfg := &VnicConfig{
+		VnicIP:     net.IPv4(192, 168, 1, 100),
+		ConcIP:     net.IPv4(192, 168, 1, 1),
+		VnicMAC:    mac,
+		Subnet:     *tenantNet,
+		SubnetKey:  0xF,
+		VnicID:     "vuuid",
+		InstanceID: "iuuid",
+		TenantID:   "tuuid",
+		SubnetID:   "suuid",
+		ConcID:     "cnciuuid",
+	}
+
+	mac2, _ := net.ParseMAC("CA:FE:00:01:02:22")
+	vnicCfg2 := &VnicConfig{
+		VnicIP:     net.IPv4(192, 168, 1, 2),
+		ConcIP:     net.IPv4(192, 168, 1, 1),
+		VnicMAC:    mac2,
+		Subnet:     *tenantNet,
+		SubnetKey:  0xF,
+		VnicID:     "vuuid2",
+		InstanceID: "iuuid2",
+		TenantID:   "tuuid",
+		SubnetID:   "suuid",
+		ConcID:     "cnciuuid",
+	}
+
+	vnic, _, _, err := cn.CreateVnic(vnicCfg)
+	require.Nil(t, err)
+	vnicName := vnic.LinkName
+
+	_, _, _, err = cn.CreateVnic(vnicCfg2)
+	require.Nil(t, err)
+
+	concIP := net.IPv4(192, 168, 1, 3)
+	ssntpEvent, err := cn.MoveSubnet(vnicCfg, "cnciuuid2", concIP)
+	if assert.Nil(err) && assert.NotNil(ssntpEvent) {
+		assert.Equal(ssntpEvent.Event, SsntpTunAdd)
+		assert.Equal(ssntpEvent.ConcID, "cnciuuid2")
+		assert.Equal(ssntpEvent.CnciIP, concIP.String())
+	}
+
+	//The subnet has already moved
+	ssntpEvent, err = cn.MoveSubnet(vnicCfg, "cnciuuid2", concIP)
+	assert.Nil(err)
+	assert.Nil(ssntpEvent)
+
+	vnicCfg.ConcID = "cnciuuid2"
+	vnicCfg.ConcIP = concIP
+	vnicCfg2.ConcID = "cnciuuid2"
+	vnicCfg2.ConcIP = concIP
+
+	//The existing VNIC should be returned
+	vnic, ssntpEvent, _, err = cn.CreateVnic(vnicCfg)
+	if assert.Nil(err) {
+		assert.Nil(ssntpEvent)
+		assert.Equal(vnicName, vnic.LinkName)
+	}
+
+	//The links should be rebuilt with the new aliases
+	assert.Nil(cn.DbRebuild(nil))
+
+	ssntpEvent, _, err = cn.DestroyVnic(vnicCfg2)
+	if assert.Nil(err) {
+		assert.Nil(ssntpEvent)
+	}
+
+	ssntpEvent, _, err = cn.DestroyVnic(vnicCfg)
+	if assert.Nil(err) && assert.NotNil(ssntpEvent) {
+		err := validSsntpEvent(ssntpEvent, vnicCfg)
+		assert.Nil(err)
+		assert.Equal(ssntpEvent.Event, SsntpTunDel)
+	}
+}
+
 //Whitebox test the CN API
 //
 //This tests exercises tests the primitive operations
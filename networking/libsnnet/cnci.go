@@ -26,6 +26,10 @@ import (
 	"github.com/vishvananda/netlink"
 )
 
+//MetadataIP is the link local address instances use to reach the
+//metadata service of their CNCI
+var MetadataIP = net.IPv4(169, 254, 169, 254)
+
 // Cnci represents a Concentrator for a single tenant
 // All subnets belonging to this tenant that are handled
 // by this concentrator. A separate bridge will be setup
@@ -55,6 +59,11 @@ type Cnci struct {
 	PublicIPs   []net.IP
 	PublicIPMap map[string]net.IP //Key is public IPNet
 
+	// Optional: Gateway and DHCP range of the subnets already served by
+	// the concentrator, keyed by subnet CIDR. They are used to restart
+	// the DHCP servers of the existing bridges when the topology is rebuilt
+	SubnetDhcp map[string]DhcpConfig
+
 	topology *cnciTopology
 }
 
@@ -64,6 +73,10 @@ type cnciTopology struct {
 	linkMap   map[string]*linkInfo //Alias to Link mapping
 	nameMap   map[string]bool      //Link name
 	bridgeMap map[string]*bridgeInfo
+	dnsMap    map[string]DNSConfig  //Bridge alias to DNS configuration
+	dhcpMap   map[string]DhcpConfig //Bridge alias to DHCP configuration
+	remoteMap map[string]bool       //VXLAN remote end points
+	lbMap     map[string]*Haproxy   //Load balancer UUID to haproxy service
 }
 
 func newCnciTopology() *cnciTopology {
@@ -71,13 +84,22 @@ func newCnciTopology() *cnciTopology {
 		linkMap:   make(map[string]*linkInfo),
 		nameMap:   make(map[string]bool),
 		bridgeMap: make(map[string]*bridgeInfo),
+		dnsMap:    make(map[string]DNSConfig),
+		dhcpMap:   make(map[string]DhcpConfig),
+		remoteMap: make(map[string]bool),
+		lbMap:     make(map[string]*Haproxy),
 	}
 }
 
+//reinitTopology clears the state discovered from the links. The DHCP
+//configuration cannot be discovered and is retained
 func reinitTopology(topology *cnciTopology) {
 	topology.linkMap = make(map[string]*linkInfo)
 	topology.nameMap = make(map[string]bool)
 	topology.bridgeMap = make(map[string]*bridgeInfo)
+	topology.dnsMap = make(map[string]DNSConfig)
+	topology.remoteMap = make(map[string]bool)
+	topology.lbMap = make(map[string]*Haproxy)
 }
 
 type bridgeInfo struct {
@@ -187,6 +209,14 @@ func (cnci *Cnci) Init() error {
 	}
 
 	cnci.topology = newCnciTopology()
+	for cidr, dhcp := range cnci.SubnetDhcp {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("Invalid subnet %s %v", cidr, err)
+		}
+		cnci.topology.dhcpMap[genBridgeAlias(*subnet)] = dhcp
+	}
+
 	if err = cnci.RebuildTopology(); err != nil {
 		return err
 	}
@@ -244,7 +274,8 @@ func (cnci *Cnci) rebuildBridgeMap(links []netlink.Link) error {
 			return (err)
 		}
 
-		dns, err := startDnsmasq(br, cnci.Tenant, *subnet)
+		dhcp := cnci.topology.dhcpMap[bridgeID]
+		dns, err := startDnsmasq(br, cnci.Tenant, *subnet, dhcp, nil)
 		if err != nil {
 			return (err)
 		}
@@ -280,6 +311,50 @@ func (cnci *Cnci) verifyTopology(links []netlink.Link) error {
 		}
 		brInfo.tunnels++
 	}
+	return cnci.verifyVxlanTopology(links)
+}
+
+//verifyVxlanTopology rebuilds the VXLAN remote end points from the
+//forwarding database of the VXLAN tunnels
+func (cnci *Cnci) verifyVxlanTopology(links []netlink.Link) error {
+	for _, link := range links {
+		if link.Type() != "vxlan" {
+			continue
+		}
+
+		alias := link.Attrs().Alias
+		if !strings.HasPrefix(alias, vxlanPrefix) {
+			continue
+		}
+
+		bridgeID := bridgePrefix + strings.TrimPrefix(alias, vxlanPrefix)
+
+		if _, ok := cnci.topology.linkMap[bridgeID]; !ok {
+			return fmt.Errorf("missing bridge for vxlan tunnel %s", alias)
+		}
+
+		brInfo, ok := cnci.topology.bridgeMap[bridgeID]
+		if !ok {
+			return fmt.Errorf("missing bridge map for vxlan tunnel %s", alias)
+		}
+
+		vxlan, err := newVxlanEP(alias, nil, nil, 0)
+		if err != nil {
+			return err
+		}
+		if err := vxlan.getDevice(); err != nil {
+			return err
+		}
+
+		remotes, err := vxlan.remotes()
+		if err != nil {
+			return err
+		}
+		for _, cnIP := range remotes {
+			cnci.topology.remoteMap[genVxlanRemoteID(alias, cnIP)] = true
+			brInfo.tunnels++
+		}
+	}
 	return nil
 }
 
@@ -337,6 +412,32 @@ func genGreAlias(subnet net.IPNet, cnIP net.IP) string {
 	return fmt.Sprintf("%s%s##%s", grePrefix, subnetToString(subnet), cnIP.String())
 }
 
+func genVxlanAlias(subnet net.IPNet) string {
+	return fmt.Sprintf("%s%s", vxlanPrefix, subnetToString(subnet))
+}
+
+func genVxlanRemoteID(vxlanAlias string, cnIP net.IP) string {
+	return fmt.Sprintf("%s##%s", vxlanAlias, cnIP.String())
+}
+
+//newTunnelEP initializes the tunnel to the compute node based on the
+//configured network mode. With GRE a tunnel is created per compute node.
+//With VXLAN a single tunnel is created per subnet using the subnet key
+//as the VNI and each compute node is a remote end point of that tunnel
+func (cnci *Cnci) newTunnelEP(subnet net.IPNet, subnetKey int, cnIP net.IP) (tunnel tunnelEP, remoteID string, err error) {
+	local := cnci.ComputeAddr[0].IPNet.IP
+
+	if cnci.Mode == VxlanTunnel {
+		alias := genVxlanAlias(subnet)
+		tunnel, err = newVxlanEP(alias, local, nil, uint32(subnetKey))
+		return tunnel, genVxlanRemoteID(alias, cnIP), err
+	}
+
+	alias := genGreAlias(subnet, cnIP)
+	tunnel, err = newGreTunEP(alias, local, cnIP, uint32(subnetKey))
+	return tunnel, alias, err
+}
+
 func genLinkName(device interface{}, nameMap map[string]bool) (string, error) {
 	for i := 0; i < ifaceRetryLimit; {
 		name, _ := genIface(device, false)
@@ -348,12 +449,16 @@ func genLinkName(device interface{}, nameMap map[string]bool) (string, error) {
 	return "", fmt.Errorf("Unable to generate unique device name")
 }
 
-func startDnsmasq(bridge *Bridge, tenant string, subnet net.IPNet) (*Dnsmasq, error) {
-	dns, err := newDnsmasq(bridge.GlobalID, tenant, subnet, 0, bridge)
+func startDnsmasq(bridge *Bridge, tenant string, subnet net.IPNet, dhcp DhcpConfig, dnsCfg *DNSConfig) (*Dnsmasq, error) {
+	dns, err := newDnsmasq(bridge.GlobalID, tenant, subnet, 0, dhcp, bridge)
 	if err != nil {
 		return nil, fmt.Errorf("NewDnsmasq failed %v", err)
 	}
 
+	if dnsCfg != nil {
+		_ = dns.setDNS(*dnsCfg)
+	}
+
 	if _, err = dns.attach(); err != nil {
 		err = dns.restart()
 		if err != nil {
@@ -363,7 +468,7 @@ func startDnsmasq(bridge *Bridge, tenant string, subnet net.IPNet) (*Dnsmasq, er
 	return dns, nil
 }
 
-func createCnciBridge(bridge *Bridge, brInfo *bridgeInfo, tenant string, subnet net.IPNet) (err error) {
+func createCnciBridge(bridge *Bridge, brInfo *bridgeInfo, tenant string, subnet net.IPNet, dhcp DhcpConfig, dnsCfg *DNSConfig) (err error) {
 	if bridge == nil || brInfo == nil {
 		return fmt.Errorf("nil pointer encountered bridge[%v] brInfo[%v]", bridge, brInfo)
 	}
@@ -373,15 +478,15 @@ func createCnciBridge(bridge *Bridge, brInfo *bridgeInfo, tenant string, subnet
 	if err = bridge.Enable(); err != nil {
 		return err
 	}
-	brInfo.Dnsmasq, err = startDnsmasq(bridge, tenant, subnet)
+	brInfo.Dnsmasq, err = startDnsmasq(bridge, tenant, subnet, dhcp, dnsCfg)
 	return err
 }
 
-func createCnciTunnel(gre *GreTunEP) (err error) {
-	if err = gre.create(); err != nil {
+func createCnciTunnel(tunnel tunnelEP) (err error) {
+	if err = tunnel.create(); err != nil {
 		return err
 	}
-	if err = gre.enable(); err != nil {
+	if err = tunnel.enable(); err != nil {
 		return err
 	}
 	return nil
@@ -405,16 +510,24 @@ func checkInputParams(subnet net.IPNet, subnetKey int, cnIP net.IP) error {
 //If the function returns error the bridgeName can be ignored
 //If the function does not return error and has a valid bridge name
 //then the subnet has been found and no further processing is needed
-func (cnci *Cnci) addSubnetToTopology(bridge *Bridge, gre *GreTunEP, brInfo **bridgeInfo) (brExists bool,
-	greExists bool, bLink *linkInfo, gLink *linkInfo, err error) {
+//With GRE the remote exists if the tunnel exists. With VXLAN the tunnel
+//is shared by all the remotes of the subnet
+func (cnci *Cnci) addSubnetToTopology(bridge *Bridge, tunnel tunnelEP, remoteID string, brInfo **bridgeInfo) (brExists bool,
+	tunExists bool, remoteExists bool, bLink *linkInfo, tLink *linkInfo, err error) {
 	err = nil
+	tun := tunnel.attrs()
 
 	// CS Start
 	cnci.topology.Lock()
 	bLink, brExists = cnci.topology.linkMap[bridge.GlobalID]
-	gLink, greExists = cnci.topology.linkMap[gre.GlobalID]
+	tLink, tunExists = cnci.topology.linkMap[tun.GlobalID]
+
+	remoteExists = tunExists
+	if cnci.Mode == VxlanTunnel {
+		remoteExists = cnci.topology.remoteMap[remoteID]
+	}
 
-	if brExists && greExists {
+	if brExists && remoteExists {
 		cnci.topology.Unlock()
 		return
 	}
@@ -443,18 +556,22 @@ func (cnci *Cnci) addSubnetToTopology(bridge *Bridge, gre *GreTunEP, brInfo **br
 		}
 	}
 
-	if !greExists {
-		gre.LinkName, err = genLinkName(gre, cnci.topology.nameMap)
+	if !tunExists {
+		tun.LinkName, err = genLinkName(tunnel, cnci.topology.nameMap)
 		if err != nil {
 			cnci.topology.Unlock()
 			return
 		}
 
-		gLink = &linkInfo{
-			name:  gre.LinkName,
+		tLink = &linkInfo{
+			name:  tun.LinkName,
 			ready: make(chan struct{}),
 		}
-		cnci.topology.linkMap[gre.GlobalID] = gLink
+		cnci.topology.linkMap[tun.GlobalID] = tLink
+	}
+
+	//VXLAN remotes are only recorded once they have been added to the tunnel
+	if !remoteExists && cnci.Mode != VxlanTunnel {
 		(*brInfo).tunnels++
 	}
 	cnci.topology.Unlock()
@@ -466,7 +583,23 @@ func (cnci *Cnci) addSubnetToTopology(bridge *Bridge, gre *GreTunEP, brInfo **br
 //If the bridge and DHCP server does not exist it will be created.
 //If the tunnel exists and the bridge does not exist the bridge is created
 //The bridge name interface name is returned if the bridge is newly created
+//The last gateway and DHCP range specified for the subnet are retained
 func (cnci *Cnci) AddRemoteSubnet(subnet net.IPNet, subnetKey int, cnIP net.IP) (string, error) {
+	if err := checkInputParams(subnet, subnetKey, cnIP); err != nil {
+		return "", err
+	}
+
+	cnci.topology.Lock()
+	dhcp := cnci.topology.dhcpMap[genBridgeAlias(subnet)]
+	cnci.topology.Unlock()
+
+	return cnci.AddRemoteSubnetDhcp(subnet, dhcp, subnetKey, cnIP)
+}
+
+//AddRemoteSubnetDhcp is identical to AddRemoteSubnet but allows the gateway
+//and the DHCP range of the subnet to be specified. If the bridge already
+//exists its DHCP server is restarted when they change
+func (cnci *Cnci) AddRemoteSubnetDhcp(subnet net.IPNet, dhcp DhcpConfig, subnetKey int, cnIP net.IP) (string, error) {
 
 	if err := checkInputParams(subnet, subnetKey, cnIP); err != nil {
 		return "", err
@@ -477,38 +610,57 @@ func (cnci *Cnci) AddRemoteSubnet(subnet net.IPNet, subnetKey int, cnIP net.IP)
 		return "", err
 	}
 
-	gre, err := newGreTunEP(genGreAlias(subnet, cnIP), cnci.ComputeAddr[0].IPNet.IP, cnIP, uint32(subnetKey))
+	tunnel, remoteID, err := cnci.newTunnelEP(subnet, subnetKey, cnIP)
 	if err != nil {
 		return "", err
 	}
+	tun := tunnel.attrs()
+
+	cnci.topology.Lock()
+	cnci.topology.dhcpMap[bridge.GlobalID] = dhcp
+	cnci.topology.Unlock()
 
-	//Logically add the bridge and gre tunnel to the topology
+	//Logically add the bridge and tunnel to the topology
 	var brInfo *bridgeInfo
-	brExists, greExists, bLink, gLink, err := cnci.addSubnetToTopology(bridge, gre, &brInfo)
+	brExists, tunExists, remoteExists, bLink, tLink, err := cnci.addSubnetToTopology(bridge, tunnel, remoteID, &brInfo)
 	if err != nil {
 		return "", err
 	}
-	if brExists && greExists {
+	if brExists {
+		if err := cnci.updateDhcp(bridge.GlobalID, bLink); err != nil {
+			return "", err
+		}
+	}
+	if brExists && remoteExists {
 		//The subnet already exists and is fully setup
 		return bLink.name, nil
 	}
 
 	//Now create them. This is time consuming
 	if !brExists {
-		err = createCnciBridge(bridge, brInfo, cnci.Tenant, subnet)
+		var dnsCfg *DNSConfig
+		cnci.topology.Lock()
+		if cfg, ok := cnci.topology.dnsMap[bridge.GlobalID]; ok {
+			dnsCfg = &cfg
+		}
+		cnci.topology.Unlock()
+
+		err = createCnciBridge(bridge, brInfo, cnci.Tenant, subnet, dhcp, dnsCfg)
 		bLink.index = bridge.Link.Index
 		close(bLink.ready)
 		if err != nil {
-			//Do not leave the GRE hanging
-			close(gLink.ready)
+			//Do not leave the tunnel hanging
+			if !tunExists {
+				close(tLink.ready)
+			}
 			return "", err
 		}
 	}
 
-	if !greExists {
-		err = createCnciTunnel(gre)
-		gLink.index = gre.Link.Index
-		close(gLink.ready)
+	if !tunExists {
+		err = createCnciTunnel(tunnel)
+		tLink.index = tunnel.link().Attrs().Index
+		close(tLink.ready)
 		if err != nil {
 			return "", err
 		}
@@ -518,12 +670,23 @@ func (cnci *Cnci) AddRemoteSubnet(subnet net.IPNet, subnetKey int, cnIP net.IP)
 	if err != nil {
 		return "", err
 	}
-	gre.LinkName, gre.Link.Index, err = waitForDeviceReady(gLink, cnci.APITimeout)
+	tun.LinkName, tunnel.link().Attrs().Index, err = waitForDeviceReady(tLink, cnci.APITimeout)
 	if err != nil {
 		return "", err
 	}
 
-	err = gre.attach(bridge)
+	err = tunnel.attach(bridge)
+	if err == nil && !remoteExists && cnci.Mode == VxlanTunnel {
+		err = tunnel.(*VxlanEP).addRemote(cnIP)
+		if err == nil {
+			cnci.topology.Lock()
+			if !cnci.topology.remoteMap[remoteID] {
+				cnci.topology.remoteMap[remoteID] = true
+				brInfo.tunnels++
+			}
+			cnci.topology.Unlock()
+		}
+	}
 	if brExists {
 		return "", err
 	}
@@ -531,6 +694,24 @@ func (cnci *Cnci) AddRemoteSubnet(subnet net.IPNet, subnetKey int, cnIP net.IP)
 
 }
 
+//updateDhcp applies the latest DHCP configuration of an existing bridge
+func (cnci *Cnci) updateDhcp(bridgeID string, bLink *linkInfo) error {
+	//Wait for the bridge and its DHCP server to be created
+	if _, _, err := waitForDeviceReady(bLink, cnci.APITimeout); err != nil {
+		return fmt.Errorf("AddRemoteSubnet %s %v", bridgeID, err)
+	}
+
+	cnci.topology.Lock()
+	defer cnci.topology.Unlock()
+
+	brInfo, present := cnci.topology.bridgeMap[bridgeID]
+	if !present || brInfo.Dnsmasq == nil {
+		return fmt.Errorf("AddRemoteSubnet %s invalid dnsmasq", bridgeID)
+	}
+
+	return brInfo.Dnsmasq.updateDhcp(cnci.topology.dhcpMap[bridgeID])
+}
+
 //DelRemoteSubnet detaches a remote subnet from the local bridge
 //The bridge and DHCP server is kept around as they impose minimal overhead
 //and helps in the case where instances keep getting added and deleted constantly
@@ -540,6 +721,10 @@ func (cnci *Cnci) DelRemoteSubnet(subnet net.IPNet, subnetKey int, cnIP net.IP)
 		return err
 	}
 
+	if cnci.Mode == VxlanTunnel {
+		return cnci.delRemoteVxlan(subnet, subnetKey, cnIP)
+	}
+
 	bridgeID := genBridgeAlias(subnet)
 
 	gre, err := newGreTunEP(genGreAlias(subnet, cnIP),
@@ -581,7 +766,204 @@ func (cnci *Cnci) DelRemoteSubnet(subnet net.IPNet, subnetKey int, cnIP net.IP)
 	return err
 }
 
-//Shutdown stops all DHCP Servers. Tears down all links and tunnels
+//delRemoteVxlan removes the compute node from the forwarding database of
+//the VXLAN tunnel of the subnet. The tunnel is kept around with the bridge
+func (cnci *Cnci) delRemoteVxlan(subnet net.IPNet, subnetKey int, cnIP net.IP) error {
+	bridgeID := genBridgeAlias(subnet)
+
+	vxlan, err := newVxlanEP(genVxlanAlias(subnet),
+		cnci.ComputeAddr[0].IPNet.IP,
+		nil, uint32(subnetKey))
+
+	if err != nil {
+		return err
+	}
+	remoteID := genVxlanRemoteID(vxlan.GlobalID, cnIP)
+
+	// CS Start
+	cnci.topology.Lock()
+	defer cnci.topology.Unlock()
+
+	if !cnci.topology.remoteMap[remoteID] {
+		//TODO: Log this and continue
+		return nil
+	}
+
+	brInfo, present := cnci.topology.bridgeMap[bridgeID]
+	if !present {
+		return fmt.Errorf("DelRemoteSubnet missing bridge %s for %s", bridgeID, remoteID)
+	}
+	delete(cnci.topology.remoteMap, remoteID)
+	brInfo.tunnels--
+
+	vLink, present := cnci.topology.linkMap[vxlan.GlobalID]
+	if !present {
+		return fmt.Errorf("DelRemoteSubnet missing vxlan tunnel %s", vxlan.GlobalID)
+	}
+
+	vxlan.LinkName, vxlan.Link.Index, err = waitForDeviceReady(vLink, cnci.APITimeout)
+	if err != nil {
+		return fmt.Errorf("DelRemoteSubnet %s %v", remoteID, err)
+	}
+
+	return vxlan.delRemote(cnIP)
+}
+
+//UpdateDNS sets the domain, the upstream DNS servers and the instance
+//names served by the DNS server of a subnet. The configuration is retained
+//and applied when the bridge and DNS server of the subnet are created if
+//they do not exist yet
+func (cnci *Cnci) UpdateDNS(subnet net.IPNet, dns DNSConfig) error {
+	if subnet.IP == nil || subnet.Mask == nil {
+		return fmt.Errorf("Invalid input parameters - Subnet")
+	}
+
+	bridgeID := genBridgeAlias(subnet)
+
+	// CS Start
+	cnci.topology.Lock()
+	cnci.topology.dnsMap[bridgeID] = dns
+	bLink, present := cnci.topology.linkMap[bridgeID]
+	cnci.topology.Unlock()
+	// CS End
+
+	if !present {
+		return nil
+	}
+
+	//Wait for the bridge and its DNS server to be created
+	if _, _, err := waitForDeviceReady(bLink, cnci.APITimeout); err != nil {
+		return fmt.Errorf("UpdateDNS %s %v", bridgeID, err)
+	}
+
+	cnci.topology.Lock()
+	defer cnci.topology.Unlock()
+
+	brInfo, present := cnci.topology.bridgeMap[bridgeID]
+	if !present || brInfo.Dnsmasq == nil {
+		return fmt.Errorf("UpdateDNS %s invalid dnsmasq", bridgeID)
+	}
+
+	//Always apply the latest configuration as updates may race
+	if brInfo.Dnsmasq.setDNS(cnci.topology.dnsMap[bridgeID]) {
+		return brInfo.Dnsmasq.restart()
+	}
+	return brInfo.Dnsmasq.reload()
+}
+
+//ConfigureLoadBalancer assigns the virtual IP of a load balancer to the
+//compute interface of the CNCI and starts the haproxy service spreading
+//its traffic across the members. If the load balancer already exists its
+//configuration is updated without dropping established connections
+func (cnci *Cnci) ConfigureLoadBalancer(lb LoadBalancerConfig) error {
+	h, err := newHaproxy(lb)
+	if err != nil {
+		return err
+	}
+
+	extIf := cnci.ComputeLink[0].Attrs().Name
+
+	cnci.topology.Lock()
+	defer cnci.topology.Unlock()
+
+	if cur, present := cnci.topology.lbMap[lb.ID]; present {
+		if cur.Config.VIP.Equal(lb.VIP) {
+			cur.Config = lb
+			return cur.reload()
+		}
+		if err := cnci.stopLoadBalancer(cur, extIf); err != nil {
+			return fmt.Errorf("ConfigureLoadBalancer %s %v", lb.ID, err)
+		}
+	}
+
+	if err := ipAssign(FwEnable, lb.VIP, extIf); err != nil {
+		return fmt.Errorf("ConfigureLoadBalancer %s %v", lb.ID, err)
+	}
+
+	if err := h.start(); err != nil {
+		_ = ipAssign(FwDisable, lb.VIP, extIf)
+		return fmt.Errorf("ConfigureLoadBalancer %s %v", lb.ID, err)
+	}
+
+	cnci.topology.lbMap[lb.ID] = h
+	return nil
+}
+
+//RemoveLoadBalancer stops the haproxy service of a load balancer and
+//releases its virtual IP
+func (cnci *Cnci) RemoveLoadBalancer(id string, vip net.IP) error {
+	extIf := cnci.ComputeLink[0].Attrs().Name
+
+	cnci.topology.Lock()
+	defer cnci.topology.Unlock()
+
+	h, present := cnci.topology.lbMap[id]
+	if !present {
+		//The service may have been launched before the agent restarted
+		h = &Haproxy{
+			Config: LoadBalancerConfig{ID: id, VIP: vip},
+		}
+		h.getFileConfiguration()
+	}
+
+	return cnci.stopLoadBalancer(h, extIf)
+}
+
+func (cnci *Cnci) stopLoadBalancer(h *Haproxy, extIf string) error {
+	var lasterr error
+
+	if err := h.stop(); err != nil {
+		lasterr = err
+	}
+
+	if h.Config.VIP != nil {
+		if err := ipAssign(FwDisable, h.Config.VIP, extIf); err != nil {
+			lasterr = err
+		}
+	}
+
+	delete(cnci.topology.lbMap, h.Config.ID)
+	return lasterr
+}
+
+//EnableMetadataService assigns the metadata service address to the
+//loopback interface of the CNCI so that requests routed to the CNCI by the
+//instances of its tenant subnets are delivered locally
+func (cnci *Cnci) EnableMetadataService() error {
+	if err := ipAssign(FwEnable, MetadataIP, "lo"); err != nil {
+		return fmt.Errorf("EnableMetadataService %v", err)
+	}
+	return nil
+}
+
+//PeerRoute adds or removes the route to a peered tenant subnet through
+//the CNCI serving it, reachable over the compute network
+func (cnci *Cnci) PeerRoute(action FwAction, peer *net.IPNet, gw net.IP) error {
+	route := &netlink.Route{
+		LinkIndex: cnci.ComputeLink[0].Attrs().Index,
+		Dst:       peer,
+		Gw:        gw,
+	}
+
+	switch action {
+	case FwEnable:
+		//The CNCI serving the peered subnet may have changed
+		_ = netlink.RouteDel(&netlink.Route{Dst: peer})
+		if err := netlink.RouteAdd(route); err != nil {
+			return fmt.Errorf("PeerRoute add %v via %v %v", peer, gw, err)
+		}
+	case FwDisable:
+		if err := netlink.RouteDel(route); err != nil {
+			return fmt.Errorf("PeerRoute del %v via %v %v", peer, gw, err)
+		}
+	default:
+		return fmt.Errorf("PeerRoute invalid action %v", action)
+	}
+
+	return nil
+}
+
+//Shutdown stops all DHCP Servers and load balancers. Tears down all links and tunnels
 //It will continue even on encountering an error and perform as much
 //cleanup as possible
 func (cnci *Cnci) Shutdown() error {
@@ -603,6 +985,15 @@ func (cnci *Cnci) Shutdown() error {
 		delete(cnci.topology.bridgeMap, id)
 	}
 
+	if len(cnci.topology.lbMap) > 0 {
+		extIf := cnci.ComputeLink[0].Attrs().Name
+		for _, h := range cnci.topology.lbMap {
+			if err := cnci.stopLoadBalancer(h, extIf); err != nil {
+				lasterr = err
+			}
+		}
+	}
+
 	for alias, linfo := range cnci.topology.linkMap {
 		if linfo != nil {
 			//HACKING: Better to create the right type
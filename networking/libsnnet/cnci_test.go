@@ -147,7 +147,7 @@ func TestCNCI_Internal(t *testing.T) {
 	assert.Nil(bridge.Enable())
 
 	// Attach the DNS masq against the CNCI bridge. This gives it an IP address
-	d, err := newDnsmasq(bridgeAlias, tenantUUID, subnet, reserved, bridge)
+	d, err := newDnsmasq(bridgeAlias, tenantUUID, subnet, reserved, DhcpConfig{}, bridge)
 	assert.Nil(err)
 
 	assert.Nil(d.start())
@@ -35,6 +35,7 @@ const (
 	leasePath  = "/tmp/"
 	configPath = "/tmp/"
 	hostsPath  = "/tmp/"
+	namesPath  = "/tmp/"
 	MACPrefix  = "02:00" //Prefix for all private MAC addresses
 //	CONFIG_PATH = "/etc/"
 //	PID_PATH = "/var/run/"
@@ -50,28 +51,33 @@ type Dnsmasq struct {
 	TenantID    string                // UUID of the Tenant to which the CNCI belongs to
 	TenantNet   net.IPNet             // The tenant subnet served by this dnsmasq, has to be /29 or larger
 	ReservedIPs int                   // Reserve IP at the start of subnet
+	Dhcp        DhcpConfig            // Gateway and DHCP range, overrides ReservedIPs
 	ConcIP      net.IP                // IP Address of the CNCI
 	IPMap       map[string]*DhcpEntry // Static mac to IP map, key is macaddress
 	Dev         *Bridge               // The bridge on which dnsmasq will attach
 	MTU         int                   // MTU that takes into account the tunnel overhead
 	DomainName  string                // Domain Name to be assigned to the subnet
+	Forwarders  []net.IP              // Upstream DNS servers, defaults to the host resolvers
+	Records     map[string]net.IP     // Instance names served under DomainName
 
 	// Private fields
 	dhcpSize  int
 	subnet    net.IP    // The DHCP addresses will be served from this subnet
 	gateway   net.IPNet // The address of the bridge. Will also be default gw to the instances
+	gateway6  net.IPNet // The IPv6 address of the bridge on dual stack subnets
 	startIP   net.IP    // First address in the DHCP range Skipping ReservedIPs
 	endIP     net.IP    // Last address in the DHCP range excluding broadcast
 	confFile  string
 	pidFile   string
 	leaseFile string
 	hostsFile string
+	namesFile string
 }
 
 // NewDnsmasq initializes a new dnsmasq instance and attaches it to the specified bridge
 // The dnsmasq object is initialized but no operations have been executed or files created
 // This is a pure in-memory operation
-func newDnsmasq(id string, tenant string, subnet net.IPNet, reserved int, b *Bridge) (*Dnsmasq, error) {
+func newDnsmasq(id string, tenant string, subnet net.IPNet, reserved int, dhcp DhcpConfig, b *Bridge) (*Dnsmasq, error) {
 	if b == nil {
 		return nil, fmt.Errorf("invalid bridge")
 	}
@@ -81,7 +87,9 @@ func newDnsmasq(id string, tenant string, subnet net.IPNet, reserved int, b *Bri
 		TenantID:    tenant,
 		TenantNet:   subnet,
 		ReservedIPs: reserved,
+		Dhcp:        dhcp,
 		IPMap:       make(map[string]*DhcpEntry),
+		Records:     make(map[string]net.IP),
 		Dev:         b,
 	}
 
@@ -111,6 +119,10 @@ func (d *Dnsmasq) start() error {
 		return fmt.Errorf("d.createHostsFile failed %v", err)
 	}
 
+	if err := d.createNamesFile(); err != nil {
+		return fmt.Errorf("d.createNamesFile failed %v", err)
+	}
+
 	if err := d.Dev.AddIP(&d.gateway); err != nil {
 		_ = d.Dev.DelIP(&d.gateway) //TODO: check it already has the IP
 		if err = d.Dev.AddIP(&d.gateway); err != nil {
@@ -118,6 +130,15 @@ func (d *Dnsmasq) start() error {
 		}
 	}
 
+	if d.gateway6.IP != nil {
+		if err := d.Dev.AddIP(&d.gateway6); err != nil {
+			_ = d.Dev.DelIP(&d.gateway6)
+			if err = d.Dev.AddIP(&d.gateway6); err != nil {
+				return fmt.Errorf("d.Dev.AddIP failed %v %v", err, d.gateway6.String())
+			}
+		}
+	}
+
 	if err := d.launch(); err != nil {
 		return fmt.Errorf("d.launch failed %v", err)
 	}
@@ -165,12 +186,21 @@ func (d *Dnsmasq) stop() error {
 		cumError = append(cumError, fmt.Errorf("Unable to delete bridge IP %v", err))
 	}
 
+	if d.gateway6.IP != nil {
+		if err = d.Dev.DelIP(&d.gateway6); err != nil {
+			cumError = append(cumError, fmt.Errorf("Unable to delete bridge IPv6 %v", err))
+		}
+	}
+
 	if err = os.Remove(d.confFile); err != nil {
 		cumError = append(cumError, fmt.Errorf("Unable to delete file %v %v", d.confFile, err))
 	}
 	if err = os.Remove(d.hostsFile); err != nil {
 		cumError = append(cumError, fmt.Errorf("Unable to delete file %v %v", d.hostsFile, err))
 	}
+	if err = os.Remove(d.namesFile); err != nil {
+		cumError = append(cumError, fmt.Errorf("Unable to delete file %v %v", d.namesFile, err))
+	}
 	_ = os.Remove(d.leaseFile)
 
 	if cumError != nil {
@@ -194,6 +224,34 @@ func (d *Dnsmasq) restart() error {
 	return nil
 }
 
+// updateDhcp changes the gateway and DHCP range served by this dnsmasq
+// service. The service is restarted if they differ from the current ones
+func (d *Dnsmasq) updateDhcp(dhcp DhcpConfig) error {
+	if d.Dhcp.equal(dhcp) {
+		return nil
+	}
+
+	//Stop with the old configuration so the old gateway is removed
+	_ = d.stop() //Ignore any errors
+
+	old := d.Dhcp
+	d.Dhcp = dhcp
+	cfgErr := d.getSubnetConfiguration()
+	if cfgErr != nil {
+		//Keep serving the old configuration
+		d.Dhcp = old
+		_ = d.getSubnetConfiguration()
+	}
+
+	if err := d.start(); err != nil {
+		return fmt.Errorf("d.Start failed %v", err)
+	}
+	if cfgErr != nil {
+		return fmt.Errorf("Unable to get subnet configuration %v", cfgErr)
+	}
+	return nil
+}
+
 // Reload is called to update the configuration of the dnsmasq
 // service. It is typically called when its configuration is updated
 func (d *Dnsmasq) reload() error {
@@ -214,6 +272,9 @@ func (d *Dnsmasq) reload() error {
 	if err = d.createHostsFile(); err != nil {
 		return fmt.Errorf("Unable to delete hosts file %v", err)
 	}
+	if err = d.createNamesFile(); err != nil {
+		return fmt.Errorf("Unable to delete names file %v", err)
+	}
 	if err = syscall.Kill(pid, syscall.SIGHUP); err != nil {
 		return fmt.Errorf("Unable to reload/SIGHUP dnsmasq %v", err)
 	}
@@ -229,6 +290,26 @@ func (d *Dnsmasq) addDhcpEntry(entry *DhcpEntry) error {
 	return nil
 }
 
+// setDNS updates the domain, forwarders and instance names served by
+// this dnsmasq service. It returns true if the change affects the
+// configuration file, in which case restart() rather than reload()
+// has to be invoked to activate it if the service is already running
+func (d *Dnsmasq) setDNS(dns DNSConfig) bool {
+	changed := d.DomainName != dns.Domain || len(d.Forwarders) != len(dns.Forwarders)
+	for i := 0; !changed && i < len(dns.Forwarders); i++ {
+		changed = !d.Forwarders[i].Equal(dns.Forwarders[i])
+	}
+
+	d.DomainName = dns.Domain
+	d.Forwarders = dns.Forwarders
+	d.Records = make(map[string]net.IP)
+	for name, ip := range dns.Records {
+		d.Records[name] = ip
+	}
+
+	return changed
+}
+
 // Populates the file specific private variables
 func (d *Dnsmasq) getFileConfiguration() error {
 
@@ -240,6 +321,7 @@ func (d *Dnsmasq) getFileConfiguration() error {
 	d.confFile = fmt.Sprintf("%sdnsmasq_%s.conf", configPath, d.SubnetID)
 	d.leaseFile = fmt.Sprintf("%sdnsmasq_%s.leases", leasePath, d.SubnetID)
 	d.hostsFile = fmt.Sprintf("%sdnsmasq_%s.hosts", hostsPath, d.SubnetID)
+	d.namesFile = fmt.Sprintf("%sdnsmasq_%s.names", namesPath, d.SubnetID)
 
 	return nil
 }
@@ -255,13 +337,6 @@ func (d *Dnsmasq) getSubnetConfiguration() error {
 	}
 	subnetSize := ^(^0 << uint32(32-ones)) + 1
 
-	// We need at least one IP for DHCP
-	// 3 are reserved for subnet, gateway, and broadcast (subnet i.e. .0 can be
-	// used but is currently is not due to legacy convention)
-	if d.dhcpSize = subnetSize - d.ReservedIPs - 3; d.dhcpSize <= 0 {
-		return fmt.Errorf("invalid reservation %s %v", d.TenantNet.String(), d.ReservedIPs)
-	}
-
 	//No deep copy implementation in net.IP
 	//Mask is the closest to a deep copy
 	//TODO Implement deep copy
@@ -270,28 +345,64 @@ func (d *Dnsmasq) getSubnetConfiguration() error {
 		return fmt.Errorf("invalid subnet")
 	}
 
-	d.gateway.IP = d.TenantNet.IP.To4().Mask(d.TenantNet.Mask)
-	d.gateway.Mask = d.TenantNet.Mask
-	d.startIP = d.TenantNet.IP.To4().Mask(d.TenantNet.Mask)
-	d.endIP = d.TenantNet.IP.To4().Mask(d.TenantNet.Mask)
-	//End Hack
+	netU32 := binary.BigEndian.Uint32(d.subnet)
+	bcastU32 := netU32 + uint32(subnetSize) - 1
 
-	//Skip the network address
-	d.gateway.IP[3]++
+	// By default the gateway is the first address after the network and
+	// the DHCP range covers the rest of the subnet, skipping ReservedIPs
+	// and the broadcast address (subnet i.e. .0 can be used but is
+	// currently not due to legacy convention)
+	gwU32 := netU32 + 1
+	startU32 := netU32 + uint32(2+d.ReservedIPs)
+	endU32 := bcastU32 - 1
 
-	//Designate the first IP after network, gateway and reserved range
-	startU32 := binary.BigEndian.Uint32(d.startIP)
-	startU32 += uint32(2 + d.ReservedIPs)
-	binary.BigEndian.PutUint32(d.startIP, startU32)
+	hostAddr := func(ip net.IP) (uint32, error) {
+		ip4 := ip.To4()
+		if ip4 == nil || !d.TenantNet.Contains(ip4) {
+			return 0, fmt.Errorf("invalid address %s for subnet %s", ip, d.TenantNet.String())
+		}
+		u32 := binary.BigEndian.Uint32(ip4)
+		if u32 == netU32 || u32 == bcastU32 {
+			return 0, fmt.Errorf("invalid address %s for subnet %s", ip, d.TenantNet.String())
+		}
+		return u32, nil
+	}
 
-	endU32 := binary.BigEndian.Uint32(d.endIP)
-	endU32 += startU32 + uint32(d.dhcpSize)
+	var err error
+	if d.Dhcp.Gateway != nil {
+		if gwU32, err = hostAddr(d.Dhcp.Gateway); err != nil {
+			return err
+		}
+	}
+	if d.Dhcp.Start != nil {
+		if startU32, err = hostAddr(d.Dhcp.Start); err != nil {
+			return err
+		}
+	}
+	if d.Dhcp.End != nil {
+		if endU32, err = hostAddr(d.Dhcp.End); err != nil {
+			return err
+		}
+	}
+
+	d.gateway.IP = make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(d.gateway.IP, gwU32)
+	d.gateway.Mask = d.TenantNet.Mask
+	d.startIP = make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(d.startIP, startU32)
+	d.endIP = make(net.IP, net.IPv4len)
 	binary.BigEndian.PutUint32(d.endIP, endU32)
 
-	//Generate all valid IPs in this subnet and pre-assign a MAC address
-	for i := 0; i < d.dhcpSize; i++ {
+	//Generate all valid IPs in the DHCP range, except for the gateway,
+	//and pre-assign a MAC address
+	d.dhcpSize = 0
+	for u32 := startU32; u32 <= endU32; u32++ {
+		if u32 == gwU32 {
+			continue
+		}
+
 		vIP := make(net.IP, net.IPv4len)
-		binary.BigEndian.PutUint32(vIP, startU32+uint32(i))
+		binary.BigEndian.PutUint32(vIP, u32)
 
 		//last 4 bytes will directly map to the desired IP address
 		macStr := fmt.Sprintf("%s:%02x:%02x:%02x:%02x", MACPrefix, vIP[0], vIP[1], vIP[2], vIP[3])
@@ -308,8 +419,40 @@ func (d *Dnsmasq) getSubnetConfiguration() error {
 		if err := d.addDhcpEntry(dhcpEntry); err != nil {
 			return err
 		}
+		d.dhcpSize++
+	}
+
+	// We need at least one IP for DHCP
+	if d.dhcpSize <= 0 {
+		return fmt.Errorf("invalid reservation %s %v", d.TenantNet.String(), d.ReservedIPs)
+	}
+
+	return d.getIPv6Configuration()
+}
+
+// Populates the IPv6 specific private variables of dual stack subnets
+func (d *Dnsmasq) getIPv6Configuration() error {
+	d.gateway6 = net.IPNet{}
+
+	prefix := d.Dhcp.IPv6Prefix
+	if prefix == nil {
+		return nil
+	}
+
+	// SLAAC and the EUI-64 derived addresses require a /64
+	ones, bits := prefix.Mask.Size()
+	if bits != 128 || ones != 64 || prefix.IP.To4() != nil {
+		return fmt.Errorf("invalid IPv6 subnet %s", prefix.String())
+	}
+
+	gw := d.Dhcp.IPv6GatewayIP()
+	if !prefix.Contains(gw) || gw.Equal(prefix.IP.Mask(prefix.Mask)) {
+		return fmt.Errorf("invalid IPv6 gateway %s for subnet %s", gw, prefix.String())
 	}
 
+	d.gateway6.IP = gw
+	d.gateway6.Mask = prefix.Mask
+
 	return nil
 }
 
@@ -322,6 +465,9 @@ func (d *Dnsmasq) createHostsFile() error {
 
 	for _, e := range d.IPMap {
 		s := fmt.Sprintf("%s,%s", e.MACAddr, e.IPAddr)
+		if d.gateway6.IP != nil && d.Dhcp.IPv6Mode == IPv6DHCP {
+			s = fmt.Sprintf("%s,[%s]", s, d.Dhcp.SlaacIP(e.MACAddr))
+		}
 		if e.Hostname != "" {
 			s = fmt.Sprintf("%s,%s", s, e.Hostname)
 		}
@@ -334,6 +480,26 @@ func (d *Dnsmasq) createHostsFile() error {
 	return file.Sync()
 }
 
+func (d *Dnsmasq) createNamesFile() error {
+	file, err := os.Create(d.namesFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	for name, ip := range d.Records {
+		s := fmt.Sprintf("%s %s\n", ip, name)
+		if d.DomainName != "" {
+			s = fmt.Sprintf("%s %s.%s %s\n", ip, name, d.DomainName, name)
+		}
+		if _, err := file.WriteString(s); err != nil {
+			return err
+		}
+	}
+
+	return file.Sync()
+}
+
 func (d *Dnsmasq) createConfigFile() error {
 	params := make([]string, 20)
 
@@ -350,8 +516,16 @@ func (d *Dnsmasq) createConfigFile() error {
 	params = append(params, fmt.Sprintf("dhcp-hostsfile=%s\n", d.hostsFile))
 	//params = append(params, "strict-order\n")
 	//params = append(params, "expand-hosts\n")
+	params = append(params, fmt.Sprintf("addn-hosts=%s\n", d.namesFile))
 	if d.DomainName != "" {
-		params = append(params, "domain=%s\n", d.DomainName)
+		params = append(params, fmt.Sprintf("domain=%s\n", d.DomainName))
+		params = append(params, fmt.Sprintf("local=/%s/\n", d.DomainName))
+	}
+	if len(d.Forwarders) > 0 {
+		params = append(params, "no-resolv\n")
+		for _, f := range d.Forwarders {
+			params = append(params, fmt.Sprintf("server=%s\n", f))
+		}
 	}
 	params = append(params, "domain-needed\n")
 	params = append(params, "bogus-priv\n")
@@ -364,6 +538,17 @@ func (d *Dnsmasq) createConfigFile() error {
 	params = append(params, fmt.Sprintf("dhcp-range=%s,static\n", d.subnet.String()))
 	params = append(params, fmt.Sprintf("dhcp-lease-max=%d\n", d.dhcpSize))
 	params = append(params, fmt.Sprintf("dhcp-option-force=26,%d\n", d.MTU))
+	if d.gateway6.IP != nil {
+		prefix := d.gateway6.IP.Mask(d.gateway6.Mask)
+		params = append(params, fmt.Sprintf("listen-address=%s\n", d.gateway6.IP.String()))
+		params = append(params, "enable-ra\n")
+		params = append(params, fmt.Sprintf("ra-param=%s,mtu:%d,60\n", d.Dev.LinkName, d.MTU))
+		if d.Dhcp.IPv6Mode == IPv6DHCP {
+			params = append(params, fmt.Sprintf("dhcp-range=%s,static,64\n", prefix.String()))
+		} else {
+			params = append(params, fmt.Sprintf("dhcp-range=%s,ra-stateless,64\n", prefix.String()))
+		}
+	}
 	//params = append(params, "log-dhcp\n")
 
 	file, err := os.Create(d.confFile)
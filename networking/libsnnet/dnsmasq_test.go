@@ -51,7 +51,7 @@ func TestDnsmasq_Basic(t *testing.T) {
 
 	defer func() { _ = bridge.Destroy() }()
 
-	d, err := newDnsmasq(id, tenant, subnet, reserved, bridge)
+	d, err := newDnsmasq(id, tenant, subnet, reserved, DhcpConfig{}, bridge)
 	assert.Nil(err)
 
 	if len(d.IPMap) != (256 - reserved - 3) {
@@ -107,13 +107,13 @@ func TestDnsmasq_Negative(t *testing.T) {
 
 	// Note: Re instantiate d each time as that
 	// is how it will be used
-	d, err := newDnsmasq(id, tenant, subnet, reserved, bridge)
+	d, err := newDnsmasq(id, tenant, subnet, reserved, DhcpConfig{}, bridge)
 	if assert.Nil(err) {
 		assert.Nil(d.start())
 
 	}
 	//Attach should work
-	d, err = newDnsmasq(id, tenant, subnet, reserved, bridge)
+	d, err = newDnsmasq(id, tenant, subnet, reserved, DhcpConfig{}, bridge)
 	if assert.Nil(err) {
 		pid, err := d.attach()
 		if assert.Nil(err) {
@@ -124,7 +124,7 @@ func TestDnsmasq_Negative(t *testing.T) {
 		}
 	}
 
-	d, err = newDnsmasq(id, tenant, subnet, reserved, bridge)
+	d, err = newDnsmasq(id, tenant, subnet, reserved, DhcpConfig{}, bridge)
 	if assert.Nil(err) {
 		//Restart should work
 		assert.Nil(d.restart())
@@ -133,7 +133,7 @@ func TestDnsmasq_Negative(t *testing.T) {
 	}
 
 	// Duplicate creation - should fail
-	d, err = newDnsmasq(id, tenant, subnet, reserved, bridge)
+	d, err = newDnsmasq(id, tenant, subnet, reserved, DhcpConfig{}, bridge)
 	if assert.Nil(err) {
 		assert.NotNil(d.start())
 		assert.Nil(d.stop())
@@ -144,9 +144,127 @@ func TestDnsmasq_Negative(t *testing.T) {
 	}
 
 	//Restart should not fail
-	d, err = newDnsmasq(id, tenant, subnet, reserved, bridge)
+	d, err = newDnsmasq(id, tenant, subnet, reserved, DhcpConfig{}, bridge)
 	if assert.Nil(err) {
 		assert.Nil(d.restart())
 		assert.Nil(d.stop())
 	}
 }
+
+//Dnsmasq subnet configuration with a user defined gateway and DHCP range
+//
+//Checks that only the addresses in the DHCP range, other than the gateway,
+//are served and that invalid ranges are rejected
+//
+//Test is expected to pass
+func TestDnsmasq_DhcpConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	subnet := net.IPNet{
+		IP:   net.IPv4(10, 1, 0, 0),
+		Mask: net.IPv4Mask(255, 255, 255, 0),
+	}
+	dhcp := DhcpConfig{
+		Gateway: net.IPv4(10, 1, 0, 254),
+		Start:   net.IPv4(10, 1, 0, 100),
+		End:     net.IPv4(10, 1, 0, 254),
+	}
+
+	bridge, _ := NewBridge("dns_testbr")
+
+	d, err := newDnsmasq("concuuid", "tenantuuid", subnet, 0, dhcp, bridge)
+	if !assert.Nil(err) {
+		return
+	}
+
+	assert.Equal(154, len(d.IPMap))
+	assert.Equal("10.1.0.254", d.gateway.IP.String())
+	assert.Equal("10.1.0.100", d.startIP.String())
+	for _, e := range d.IPMap {
+		assert.NotEqual("10.1.0.254", e.IPAddr.String())
+	}
+
+	dhcp.Gateway = net.IPv4(10, 1, 1, 1)
+	_, err = newDnsmasq("concuuid", "tenantuuid", subnet, 0, dhcp, bridge)
+	assert.NotNil(err)
+
+	dhcp.Gateway = nil
+	dhcp.Start = net.IPv4(10, 1, 0, 200)
+	dhcp.End = net.IPv4(10, 1, 0, 100)
+	_, err = newDnsmasq("concuuid", "tenantuuid", subnet, 0, dhcp, bridge)
+	assert.NotNil(err)
+}
+
+//Tests the IPv6 configuration of a dual stack subnet
+//
+//Checks that the IPv6 gateway is derived from the prefix, that instance
+//addresses are derived from their MAC addresses and that prefixes other
+//than /64 are rejected
+//
+//Test is expected to pass
+func TestDnsmasq_IPv6Config(t *testing.T) {
+	assert := assert.New(t)
+
+	subnet := net.IPNet{
+		IP:   net.IPv4(10, 1, 0, 0),
+		Mask: net.IPv4Mask(255, 255, 255, 0),
+	}
+	_, prefix, _ := net.ParseCIDR("fd12:3456:789a:1::/64")
+	dhcp := DhcpConfig{
+		IPv6Prefix: prefix,
+		IPv6Mode:   IPv6DHCP,
+	}
+
+	bridge, _ := NewBridge("dns_testbr")
+
+	d, err := newDnsmasq("concuuid", "tenantuuid", subnet, 0, dhcp, bridge)
+	if !assert.Nil(err) {
+		return
+	}
+
+	assert.Equal("fd12:3456:789a:1::1", d.gateway6.IP.String())
+
+	mac, _ := net.ParseMAC("02:00:0a:01:00:02")
+	assert.Equal("fd12:3456:789a:1:0:aff:fe01:2", dhcp.SlaacIP(mac).String())
+
+	_, dhcp.IPv6Prefix, _ = net.ParseCIDR("fd12:3456:789a::/48")
+	_, err = newDnsmasq("concuuid", "tenantuuid", subnet, 0, dhcp, bridge)
+	assert.NotNil(err)
+
+	dhcp.IPv6Prefix = prefix
+	dhcp.IPv6Gateway = net.ParseIP("fd12:3456:789a:2::1")
+	_, err = newDnsmasq("concuuid", "tenantuuid", subnet, 0, dhcp, bridge)
+	assert.NotNil(err)
+}
+
+//Tests the comparison of DHCP configurations
+//
+//Checks that configurations are only equal if all their fields match,
+//which determines whether the DHCP server of an existing bridge is
+//restarted
+//
+//Test is expected to pass
+func TestDnsmasq_DhcpConfigEqual(t *testing.T) {
+	assert := assert.New(t)
+
+	_, prefix, _ := net.ParseCIDR("fd12:3456:789a:1::/64")
+	dhcp := DhcpConfig{
+		Gateway:    net.IPv4(10, 1, 0, 254),
+		Start:      net.IPv4(10, 1, 0, 100),
+		IPv6Prefix: prefix,
+	}
+
+	other := dhcp
+	_, other.IPv6Prefix, _ = net.ParseCIDR("fd12:3456:789a:1::/64")
+	assert.True(dhcp.equal(other))
+	assert.False(dhcp.equal(DhcpConfig{}))
+
+	other.End = net.IPv4(10, 1, 0, 200)
+	assert.False(dhcp.equal(other))
+
+	other = dhcp
+	other.IPv6Mode = IPv6DHCP
+	assert.False(dhcp.equal(other))
+
+	assert.True(DhcpConfig{}.equal(DhcpConfig{}))
+}
@@ -67,7 +67,8 @@ Routing decision                                                  |
 */
 
 const (
-	procIPFwd = "/proc/sys/net/ipv4/ip_forward"
+	procIPFwd   = "/proc/sys/net/ipv4/ip_forward"
+	procIPv6Fwd = "/proc/sys/net/ipv6/conf/all/forwarding"
 )
 
 //FwAction defines firewall action to be performed
@@ -84,6 +85,7 @@ const (
 type Firewall struct {
 	ExtInterfaces []string
 	*iptables.IPTables
+	ipv6 bool // ip6tables NAT is available on the node
 }
 
 //InitFirewall Enables routing on the node and NAT on all
@@ -154,6 +156,9 @@ func InitFirewall(devices ...string) (*Firewall, error) {
 		f.ExtInterfaces = append(f.ExtInterfaces, device)
 	}
 
+	// IPv6 is optional, the node may not support ip6tables NAT
+	f.ipv6 = initIPv6Firewall(floatingIPsChains, devices) == nil
+
 	if err = Routing(FwEnable); err != nil {
 		return nil, fmt.Errorf("Error: InitFirewall routing enable %v", err)
 	}
@@ -178,18 +183,90 @@ func (f *Firewall) ShutdownFirewall() error {
 		if err != nil {
 			return fmt.Errorf("Error: Shutdown Firewall NAT disable %v", err)
 		}
+
+		if f.ipv6 {
+			err = ip6tables("-t", "nat", "-D", "POSTROUTING",
+				"-o", device, "-j", "MASQUERADE")
+			if err != nil {
+				return fmt.Errorf("Error: Shutdown Firewall IPv6 NAT disable %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+//initIPv6Firewall sets up the IPv6 floating IP chains and NAT on all
+//external facing interfaces
+func initIPv6Firewall(chains []string, devices []string) error {
+	for _, chain := range chains {
+		// verify it exists if not create it
+		_ = ip6tables("-t", "nat", "-N", chain)
+	}
+
+	if !ip6tablesExists("nat", "PREROUTING", "-j", chains[0]) {
+		if err := ip6tables("-t", "nat", "-I", "PREROUTING", "1", "-j", chains[0]); err != nil {
+			return err
+		}
+	}
+
+	if !ip6tablesExists("nat", "POSTROUTING", "-j", chains[1]) {
+		if err := ip6tables("-t", "nat", "-I", "POSTROUTING", "1", "-j", chains[1]); err != nil {
+			return err
+		}
+	}
+
+	for _, device := range devices {
+		//ip6tables -t nat -A POSTROUTING -o $device -j MASQUERADE
+		if ip6tablesExists("nat", "POSTROUTING", "-o", device, "-j", "MASQUERADE") {
+			continue
+		}
+		err := ip6tables("-t", "nat", "-A", "POSTROUTING",
+			"-o", device, "-j", "MASQUERADE")
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+//ip6tables runs an ip6tables command. The vendored iptables package
+//only manages IPv4 tables
+func ip6tables(args ...string) error {
+	out, err := exec.Command("ip6tables", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ip6tables %v failed %v %s", args, err, out)
+	}
+	return nil
+}
+
+//ip6tablesExists checks if the rule exists in the chain of the table
+func ip6tablesExists(table, chain string, rulespec ...string) bool {
+	args := append([]string{"-t", table, "-C", chain}, rulespec...)
+	return exec.Command("ip6tables", args...).Run() == nil
+}
+
 //Routing enable or disables routing
 //echo 0 > /proc/sys/net/ipv4/ip_forward
 //echo 1 > /proc/sys/net/ipv4/ip_forward
+//IPv6 routing is also enabled or disabled if the node supports IPv6
 func Routing(action FwAction) error {
-	file, err := os.OpenFile(procIPFwd, os.O_WRONLY|os.O_TRUNC, 0644)
+	if err := setForwarding(procIPFwd, action); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(procIPv6Fwd); err != nil {
+		return nil
+	}
+
+	return setForwarding(procIPv6Fwd, action)
+}
+
+func setForwarding(procFile string, action FwAction) error {
+	file, err := os.OpenFile(procFile, os.O_WRONLY|os.O_TRUNC, 0644)
 	if err != nil {
-		return fmt.Errorf("Routing: Unable to open %v %v", procIPFwd, err)
+		return fmt.Errorf("Routing: Unable to open %v %v", procFile, err)
 	}
 	defer func() { _ = file.Close() }()
 
@@ -315,11 +392,19 @@ func ipAssign(action FwAction, ip net.IP, iface string) error {
 		return fmt.Errorf("Unable to detect interface %v %v", iface, err)
 	}
 
+	family := netlink.FAMILY_V4
 	addr := &netlink.Addr{IPNet: &net.IPNet{
 		IP:   ip.To4(),
 		Mask: net.IPv4Mask(255, 255, 255, 255),
 	},
 	}
+	if ip.To4() == nil {
+		family = netlink.FAMILY_V6
+		addr.IPNet = &net.IPNet{
+			IP:   ip.To16(),
+			Mask: net.CIDRMask(128, 128),
+		}
+	}
 
 	switch action {
 	case FwEnable:
@@ -344,7 +429,7 @@ func ipAssign(action FwAction, ip net.IP, iface string) error {
 		}
 
 		//Check if someone deleted it
-		addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+		addrs, err := netlink.AddrList(link, family)
 		if err != nil || len(addrs) == 0 {
 			return fmt.Errorf("Unable to unassign IP from interface %s %v %v", ip, iface, err)
 		}
@@ -367,6 +452,14 @@ func (f *Firewall) PublicIPAccess(action FwAction,
 	intIP := internalIP.String()
 	pubIP := publicIP.String()
 
+	ipv6 := publicIP.To4() == nil
+	if ipv6 != (internalIP.To4() == nil) {
+		return fmt.Errorf("Address family mismatch %s %s", intIP, pubIP)
+	}
+	if ipv6 && !f.ipv6 {
+		return fmt.Errorf("IPv6 NAT not supported %s", pubIP)
+	}
+
 	switch action {
 	case FwEnable:
 		// assign the pubIP to the cnci agent
@@ -374,6 +467,9 @@ func (f *Firewall) PublicIPAccess(action FwAction,
 		if err != nil {
 			return fmt.Errorf("Public IP Assignment failure %v", err)
 		}
+		if ipv6 {
+			return enablePublicIPv6(intIP, pubIP)
+		}
 		return enablePublicIP(intIP, pubIP)
 	case FwDisable:
 		// remove the pubIP from the cnci agent
@@ -382,6 +478,9 @@ func (f *Firewall) PublicIPAccess(action FwAction,
 			return fmt.Errorf("Public IP Assignment failure %v", err)
 		}
 
+		if ipv6 {
+			return disablePublicIPv6(intIP, pubIP)
+		}
 		return disablePublicIP(intIP, pubIP)
 	default:
 		return fmt.Errorf("Invalid parameter %v", action)
@@ -462,6 +561,107 @@ func disablePublicIP(intIP, pubIP string) error {
 	return nil
 }
 
+func enablePublicIPv6(intIP, pubIP string) error {
+	// ip6tables -t nat -I ciao-floating-ip-pre -d <pubIP> -j DNAT --to-destination <intIP>
+	pre := []string{"-d", pubIP + "/128", "-j", "DNAT", "--to-destination", intIP}
+	if !ip6tablesExists("nat", "ciao-floating-ip-pre", pre...) {
+		err := ip6tables(append([]string{"-t", "nat", "-I", "ciao-floating-ip-pre", "1"}, pre...)...)
+		if err != nil {
+			return fmt.Errorf("Could not insert firewall PREROUTING rule %s to %s into chain ciao-floating-ip-pre %v", pubIP, intIP, err)
+		}
+	}
+
+	// ip6tables -t nat -I ciao-floating-ip-post -s <intIP> -j SNAT --to-source <pubIP>
+	post := []string{"-s", intIP + "/128", "-j", "SNAT", "--to-source", pubIP}
+	if !ip6tablesExists("nat", "ciao-floating-ip-post", post...) {
+		err := ip6tables(append([]string{"-t", "nat", "-I", "ciao-floating-ip-post", "1"}, post...)...)
+		if err != nil {
+			return fmt.Errorf("Could not insert firewall POSTROUTING rule %s to %s into chain ciao-floating-ip-post %v", intIP, pubIP, err)
+		}
+	}
+
+	return nil
+}
+
+func disablePublicIPv6(intIP, pubIP string) error {
+	// ip6tables -t nat -D ciao-floating-ip-pre -d <pubIP> -j DNAT --to-destination <intIP>
+	pre := []string{"-d", pubIP + "/128", "-j", "DNAT", "--to-destination", intIP}
+	if ip6tablesExists("nat", "ciao-floating-ip-pre", pre...) {
+		err := ip6tables(append([]string{"-t", "nat", "-D", "ciao-floating-ip-pre"}, pre...)...)
+		if err != nil {
+			return fmt.Errorf("Could not delete firewall PREROUTING rule %s to %s into chain ciao-floating-ip-pre %v", pubIP, intIP, err)
+		}
+	}
+
+	// ip6tables -t nat -D ciao-floating-ip-post -s <intIP> -j SNAT --to-source <pubIP>
+	post := []string{"-s", intIP + "/128", "-j", "SNAT", "--to-source", pubIP}
+	if ip6tablesExists("nat", "ciao-floating-ip-post", post...) {
+		err := ip6tables(append([]string{"-t", "nat", "-D", "ciao-floating-ip-post"}, post...)...)
+		if err != nil {
+			return fmt.Errorf("Could not delete firewall POSTROUTING rule %s to %s into chain ciao-floating-ip-post %v", intIP, pubIP, err)
+		}
+	}
+
+	return nil
+}
+
+//PeerAccess Enables/Disables the forwarding of traffic between a local
+//tenant subnet and a peered subnet served by another CNCI. Traffic to the
+//peered subnet is exempted from NAT so that the instances on both sides
+//see each other's private addresses
+func (f *Firewall) PeerAccess(action FwAction, local *net.IPNet, peer *net.IPNet) error {
+	if local.IP.To4() == nil || peer.IP.To4() == nil {
+		return fmt.Errorf("PeerAccess: IPv6 peering not supported %v %v", local, peer)
+	}
+
+	loc := local.String()
+	rem := peer.String()
+
+	rules := []struct {
+		table    string
+		chain    string
+		rulespec []string
+	}{
+		//iptables -I FORWARD -s $local -d $peer -j ACCEPT
+		{"filter", "FORWARD", []string{"-s", loc, "-d", rem, "-j", "ACCEPT"}},
+		//iptables -I FORWARD -s $peer -d $local -j ACCEPT
+		{"filter", "FORWARD", []string{"-s", rem, "-d", loc, "-j", "ACCEPT"}},
+		//iptables -t nat -I POSTROUTING -s $local -d $peer -j ACCEPT
+		{"nat", "POSTROUTING", []string{"-s", loc, "-d", rem, "-j", "ACCEPT"}},
+	}
+
+	for _, r := range rules {
+		ok, err := f.Exists(r.table, r.chain, r.rulespec...)
+		if err != nil {
+			return fmt.Errorf("PeerAccess: unable to verify %s %s rule %v %v",
+				r.table, r.chain, r.rulespec, err)
+		}
+
+		switch action {
+		case FwEnable:
+			if ok {
+				continue
+			}
+			//Ahead of the MASQUERADE rules
+			err = f.Insert(r.table, r.chain, 1, r.rulespec...)
+		case FwDisable:
+			if !ok {
+				continue
+			}
+			err = f.Delete(r.table, r.chain, r.rulespec...)
+		default:
+			return fmt.Errorf("Invalid parameter %v", action)
+		}
+
+		if err != nil {
+			return fmt.Errorf("PeerAccess: unable to %v %s %s rule %v %v",
+				action, r.table, r.chain, r.rulespec, err)
+		}
+	}
+
+	return nil
+}
+
 //DumpIPTables provides a utility routine that returns
 //the current state of the iptables
 func DumpIPTables() string {
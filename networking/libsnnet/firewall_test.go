@@ -136,6 +136,33 @@ func TestFw_PublicIP(t *testing.T) {
 	}
 }
 
+//Tests the forwarding of traffic between peered subnets
+//
+//Test if the forwarding and NAT exemption rules of a peered
+//subnet can be setup and torn down
+//
+//Test is expected to pass
+func TestFw_Peering(t *testing.T) {
+	assert := assert.New(t)
+
+	fwinit()
+	fw, err := InitFirewall(fwIf)
+	require.Nil(t, err)
+
+	_, local, _ := net.ParseCIDR("198.51.100.0/24")
+	_, peer, _ := net.ParseCIDR("203.0.113.0/24")
+
+	assert.Nil(fw.PeerAccess(FwEnable, local, peer))
+	assert.Nil(fw.PeerAccess(FwEnable, local, peer))
+	assert.Nil(fw.PeerAccess(FwDisable, local, peer))
+	assert.Nil(fw.PeerAccess(FwDisable, local, peer))
+
+	_, peer6, _ := net.ParseCIDR("2001:db8::/64")
+	assert.NotNil(fw.PeerAccess(FwEnable, local, peer6))
+
+	assert.Nil(fw.ShutdownFirewall())
+}
+
 //Exercises all valid CNCI Firewall APIs
 //
 //This tests performs the sequence of operations typically
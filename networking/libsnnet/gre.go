@@ -213,3 +213,11 @@ func (g *GreTunEP) detach(dev interface{}) error {
 
 	return nil
 }
+
+func (g *GreTunEP) attrs() *Attrs {
+	return &g.Attrs
+}
+
+func (g *GreTunEP) link() netlink.Link {
+	return g.Link
+}
@@ -0,0 +1,306 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package libsnnet
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+//Load balancer protocols
+const (
+	LoadBalancerTCP  = "tcp"  //Spreads TCP connections across the members
+	LoadBalancerHTTP = "http" //Spreads HTTP requests across the members
+)
+
+//LoadBalancerMember is an instance receiving the traffic of a load balancer
+type LoadBalancerMember struct {
+	IP   net.IP //Address of the instance on a tenant subnet
+	Port int    //Port of the instance the traffic is sent to
+}
+
+//LoadBalancerHealthCheck describes how the members of a load balancer
+//are checked. Members failing the check stop receiving traffic
+type LoadBalancerHealthCheck struct {
+	Interval time.Duration //Time between two checks of a member
+	Timeout  time.Duration //Time a member has to answer a check
+	Retries  int           //Consecutive checks to fail or pass to change state
+	Path     string        //URI requested by the checks of HTTP load balancers
+}
+
+//LoadBalancerConfig describes a tenant load balancer served by a CNCI
+type LoadBalancerConfig struct {
+	ID          string //UUID of the load balancer
+	VIP         net.IP //Virtual IP the load balancer listens on
+	Port        int    //Listener port
+	Protocol    string //LoadBalancerTCP or LoadBalancerHTTP
+	Members     []LoadBalancerMember
+	HealthCheck LoadBalancerHealthCheck
+}
+
+// Haproxy contains all the information required to spawn
+// a haproxy process serving a load balancer on a concentrator
+type Haproxy struct {
+	Config LoadBalancerConfig
+
+	// Private fields
+	confFile string
+	pidFile  string
+}
+
+// newHaproxy initializes a new haproxy instance for the load balancer
+// The haproxy object is initialized but no operations have been executed or files created
+// This is a pure in-memory operation
+func newHaproxy(lb LoadBalancerConfig) (*Haproxy, error) {
+	if err := lb.validate(); err != nil {
+		return nil, err
+	}
+
+	h := &Haproxy{
+		Config: lb,
+	}
+	h.getFileConfiguration()
+
+	return h, nil
+}
+
+// Populates the file specific private variables
+func (h *Haproxy) getFileConfiguration() {
+	h.pidFile = fmt.Sprintf("%shaproxy_%s.pid", pidPath, h.Config.ID)
+	h.confFile = fmt.Sprintf("%shaproxy_%s.cfg", configPath, h.Config.ID)
+}
+
+func (lb *LoadBalancerConfig) validate() error {
+	if lb.ID == "" || lb.VIP == nil {
+		return fmt.Errorf("invalid load balancer %v", lb)
+	}
+
+	if lb.Port <= 0 || lb.Port > 65535 {
+		return fmt.Errorf("invalid load balancer port %d", lb.Port)
+	}
+
+	if lb.Protocol != LoadBalancerTCP && lb.Protocol != LoadBalancerHTTP {
+		return fmt.Errorf("invalid load balancer protocol %s", lb.Protocol)
+	}
+
+	for _, m := range lb.Members {
+		if m.IP == nil || m.Port <= 0 || m.Port > 65535 {
+			return fmt.Errorf("invalid load balancer member %v", m)
+		}
+	}
+
+	return nil
+}
+
+// Start the haproxy service
+// This creates the configuration file and launches the service. An
+// instance already serving the load balancer, e.g. launched before the
+// agent restarted, is gracefully replaced
+func (h *Haproxy) start() error {
+	if err := h.createConfigFile(); err != nil {
+		return fmt.Errorf("h.createConfigFile failed %v", err)
+	}
+
+	pid, _ := h.attach()
+	if err := h.launch(pid); err != nil {
+		return fmt.Errorf("h.launch failed %v", err)
+	}
+
+	return nil
+}
+
+// Reload is called to update the configuration of the haproxy
+// service. The new process takes over the listener and the old one
+// finishes serving its established connections
+func (h *Haproxy) reload() error {
+	pid, err := h.attach()
+	if err != nil {
+		return err
+	}
+
+	if err = h.createConfigFile(); err != nil {
+		return fmt.Errorf("Unable to create config file %v", err)
+	}
+
+	if err = h.launch(pid); err != nil {
+		return fmt.Errorf("Unable to reload haproxy %v", err)
+	}
+	return nil
+}
+
+// Attach to an existing service
+// Returns -1 and error on failure
+// Returns pid of current process on success
+func (h *Haproxy) attach() (int, error) {
+	pid, err := h.getPid()
+
+	if err != nil {
+		return -1, fmt.Errorf("No pid file %v", err)
+	}
+
+	if err = syscall.Kill(pid, syscall.Signal(0)); err != nil {
+		return -1, fmt.Errorf("Process does not exist or unable to attach %v", err)
+	}
+	return pid, nil
+}
+
+// Stop the haproxy service
+func (h *Haproxy) stop() error {
+	var cumError []error
+
+	pid, err := h.attach()
+
+	if err != nil {
+		cumError = append(cumError, fmt.Errorf("Process does not exist %v", err))
+	}
+
+	if pid != -1 {
+		if err = syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			cumError = append(cumError, fmt.Errorf("Unable to kill haproxy %v", err))
+		} else {
+			if err := os.Remove(h.pidFile); err != nil {
+				cumError = append(cumError, fmt.Errorf("Unable to delete file %v %v", h.pidFile, err))
+			}
+		}
+	}
+
+	if err = os.Remove(h.confFile); err != nil {
+		cumError = append(cumError, fmt.Errorf("Unable to delete file %v %v", h.confFile, err))
+	}
+
+	if cumError != nil {
+		allErrors := ""
+		for _, e := range cumError {
+			allErrors = allErrors + e.Error()
+		}
+		return errors.New(allErrors)
+	}
+
+	return nil
+}
+
+//haproxy splits addresses and ports on the last colon and
+//needs to be told explicitly about IPv6 addresses
+func haproxyAddr(ip net.IP, port int) string {
+	if ip.To4() == nil {
+		return fmt.Sprintf("ipv6@%s:%d", ip, port)
+	}
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+func haproxyDuration(d time.Duration) string {
+	return fmt.Sprintf("%dms", d/time.Millisecond)
+}
+
+func (h *Haproxy) createConfigFile() error {
+	var params []string
+	lb := h.Config
+	hc := lb.HealthCheck
+
+	params = append(params, "global\n")
+	params = append(params, "\tdaemon\n")
+	params = append(params, fmt.Sprintf("\tpidfile %s\n", h.pidFile))
+	params = append(params, "defaults\n")
+	params = append(params, fmt.Sprintf("\tmode %s\n", lb.Protocol))
+	params = append(params, "\ttimeout connect 5s\n")
+	params = append(params, "\ttimeout client 60s\n")
+	params = append(params, "\ttimeout server 60s\n")
+	params = append(params, fmt.Sprintf("frontend lb_%s\n", lb.ID))
+	params = append(params, fmt.Sprintf("\tbind %s\n", haproxyAddr(lb.VIP, lb.Port)))
+	params = append(params, "\tdefault_backend members\n")
+	params = append(params, "backend members\n")
+	params = append(params, "\tbalance roundrobin\n")
+	if lb.Protocol == LoadBalancerHTTP {
+		path := hc.Path
+		if path == "" {
+			path = "/"
+		}
+		params = append(params, "\toption forwardfor\n")
+		params = append(params, fmt.Sprintf("\toption httpchk GET %s\n", path))
+	}
+	if hc.Timeout > 0 {
+		params = append(params, fmt.Sprintf("\ttimeout check %s\n", haproxyDuration(hc.Timeout)))
+	}
+
+	check := "check"
+	if hc.Interval > 0 {
+		check = fmt.Sprintf("%s inter %s", check, haproxyDuration(hc.Interval))
+	}
+	if hc.Retries > 0 {
+		check = fmt.Sprintf("%s fall %d rise %d", check, hc.Retries, hc.Retries)
+	}
+	for i, m := range lb.Members {
+		params = append(params, fmt.Sprintf("\tserver member%d %s %s\n", i, haproxyAddr(m.IP, m.Port), check))
+	}
+
+	file, err := os.Create(h.confFile)
+	if err != nil {
+		return fmt.Errorf("Unable to create file %v %v", h.confFile, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	for _, s := range params {
+		if _, err := file.WriteString(s); err != nil {
+			return err
+		}
+	}
+
+	return file.Sync()
+}
+
+//launch starts haproxy. If oldPid is a running haproxy it is
+//asked to hand over its listeners and to exit once idle
+func (h *Haproxy) launch(oldPid int) error {
+	prog := "haproxy"
+	args := []string{"-f", h.confFile, "-p", h.pidFile, "-D"}
+	if oldPid > 0 {
+		args = append(args, "-sf", strconv.Itoa(oldPid))
+	}
+
+	cmd := exec.Command(prog, args...)
+	_, err := cmd.Output()
+
+	return err
+}
+
+func (h *Haproxy) getPid() (int, error) {
+
+	pidbytes, err := ioutil.ReadFile(h.pidFile)
+	if err != nil {
+		return -1, err
+	}
+
+	//haproxy writes one pid per process
+	pidStr := strings.Fields(string(pidbytes))
+	if len(pidStr) == 0 {
+		return -1, fmt.Errorf("empty pid file %s", h.pidFile)
+	}
+	pid, err := strconv.ParseUint(pidStr[0], 10, 32)
+	if err != nil {
+		return -1, err
+	}
+
+	return int(pid), nil
+}
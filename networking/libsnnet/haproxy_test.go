@@ -0,0 +1,113 @@
+//
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package libsnnet
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//Test haproxy configuration generation
+//
+//This test generates the configuration of an HTTP load balancer
+//and checks the listener, health check and members
+//
+//Test is expected to pass
+func TestHaproxy_Config(t *testing.T) {
+	assert := assert.New(t)
+
+	lb := LoadBalancerConfig{
+		ID:       "lbuuid",
+		VIP:      net.ParseIP("10.10.0.5"),
+		Port:     80,
+		Protocol: LoadBalancerHTTP,
+		Members: []LoadBalancerMember{
+			{IP: net.ParseIP("192.168.1.2"), Port: 8080},
+			{IP: net.ParseIP("fd00::2"), Port: 8080},
+		},
+		HealthCheck: LoadBalancerHealthCheck{
+			Interval: 5 * time.Second,
+			Timeout:  2 * time.Second,
+			Retries:  3,
+			Path:     "/health",
+		},
+	}
+
+	h, err := newHaproxy(lb)
+	assert.Nil(err)
+
+	err = h.createConfigFile()
+	assert.Nil(err)
+	defer func() { _ = os.Remove(h.confFile) }()
+
+	cfg, err := ioutil.ReadFile(h.confFile)
+	assert.Nil(err)
+
+	for _, s := range []string{
+		"pidfile " + h.pidFile,
+		"mode http",
+		"bind 10.10.0.5:80",
+		"option httpchk GET /health",
+		"timeout check 2000ms",
+		"server member0 192.168.1.2:8080 check inter 5000ms fall 3 rise 3",
+		"server member1 ipv6@fd00::2:8080 check inter 5000ms fall 3 rise 3",
+	} {
+		assert.True(strings.Contains(string(cfg), s), s)
+	}
+}
+
+//Test haproxy configuration validation
+//
+//This test checks that invalid load balancers are rejected
+//
+//Test is expected to pass
+func TestHaproxy_Invalid(t *testing.T) {
+	valid := LoadBalancerConfig{
+		ID:       "lbuuid",
+		VIP:      net.ParseIP("10.10.0.5"),
+		Port:     443,
+		Protocol: LoadBalancerTCP,
+	}
+
+	if _, err := newHaproxy(valid); err != nil {
+		t.Errorf("Valid load balancer rejected %v", err)
+	}
+
+	noVIP := valid
+	noVIP.VIP = nil
+
+	badPort := valid
+	badPort.Port = 0
+
+	badProtocol := valid
+	badProtocol.Protocol = "udp"
+
+	badMember := valid
+	badMember.Members = []LoadBalancerMember{{IP: net.ParseIP("192.168.1.2")}}
+
+	for _, lb := range []LoadBalancerConfig{noVIP, badPort, badProtocol, badMember} {
+		if _, err := newHaproxy(lb); err == nil {
+			t.Errorf("Invalid load balancer accepted %v", lb)
+		}
+	}
+}
@@ -35,6 +35,8 @@ func netError(dev interface{}, format string, args ...interface{}) error {
 		return fmt.Errorf("cncivnic error: "+format, args...)
 	case GreTunEP, *GreTunEP:
 		return fmt.Errorf("gre error: "+format, args...)
+	case VxlanEP, *VxlanEP:
+		return fmt.Errorf("vxlan error: "+format, args...)
 	}
 	return fmt.Errorf("network error: "+format, args...)
 }
@@ -97,6 +99,8 @@ const (
 	Routed NetworkMode = iota
 	// GreTunnel means tenant instances interlinked using GRE tunnels. Full tenant isolation
 	GreTunnel
+	// VxlanTunnel means tenant instances interlinked using VXLAN tunnels. Full tenant isolation
+	VxlanTunnel
 )
 
 // VnicRole specifies the role of the VNIC
@@ -150,6 +154,146 @@ type DhcpEntry struct {
 	Hostname string // Optional
 }
 
+// IPv6Mode describes how instances on dual stack tenant subnets are
+// assigned their IPv6 addresses
+type IPv6Mode int
+
+const (
+	// IPv6SLAAC means instances configure their addresses from the router
+	// advertisements sent by the CNCI
+	IPv6SLAAC IPv6Mode = iota
+	// IPv6DHCP means instances are assigned their addresses by the DHCPv6
+	// server of the CNCI
+	IPv6DHCP
+)
+
+// ParseIPv6Mode converts the name of an IPv6 address assignment mode,
+// "slaac" or "dhcpv6", to an IPv6Mode. An empty name selects SLAAC
+func ParseIPv6Mode(mode string) (IPv6Mode, error) {
+	switch mode {
+	case "", "slaac":
+		return IPv6SLAAC, nil
+	case "dhcpv6":
+		return IPv6DHCP, nil
+	}
+	return IPv6SLAAC, fmt.Errorf("invalid IPv6 mode %s", mode)
+}
+
+func (m IPv6Mode) String() string {
+	if m == IPv6DHCP {
+		return "dhcpv6"
+	}
+	return "slaac"
+}
+
+// DhcpConfig describes the gateway and the range of addresses served by
+// the DHCP server of a tenant subnet. Fields left unset select the defaults,
+// i.e. the first host address of the subnet is the gateway and all the
+// other host addresses are served.
+// Dual stack subnets additionally have an IPv6 prefix. Instances are
+// assigned the address of the prefix derived from their MAC address
+type DhcpConfig struct {
+	Gateway     net.IP     // Optional: Default gateway of the subnet
+	Start       net.IP     // Optional: First address in the DHCP range
+	End         net.IP     // Optional: Last address in the DHCP range
+	IPv6Prefix  *net.IPNet // Optional: IPv6 /64 prefix of a dual stack subnet
+	IPv6Gateway net.IP     // Optional: IPv6 default gateway of the subnet
+	IPv6Mode    IPv6Mode   // IPv6 address assignment mode of the subnet
+}
+
+// GatewayIP returns the default gateway of the subnet
+func (d DhcpConfig) GatewayIP(subnet net.IPNet) net.IP {
+	if d.Gateway != nil {
+		return d.Gateway.To4()
+	}
+
+	gateway := subnet.IP.To4().Mask(subnet.Mask)
+	gateway[3]++
+	return gateway
+}
+
+func ipNetEqual(a *net.IPNet, b *net.IPNet) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.String() == b.String()
+}
+
+// equal reports whether d and o describe the same DHCP configuration
+func (d DhcpConfig) equal(o DhcpConfig) bool {
+	return d.Gateway.Equal(o.Gateway) && d.Start.Equal(o.Start) &&
+		d.End.Equal(o.End) && ipNetEqual(d.IPv6Prefix, o.IPv6Prefix) &&
+		d.IPv6Gateway.Equal(o.IPv6Gateway) && d.IPv6Mode == o.IPv6Mode
+}
+
+// IPv6GatewayIP returns the IPv6 default gateway of a dual stack subnet,
+// or nil if the subnet is IPv4 only
+func (d DhcpConfig) IPv6GatewayIP() net.IP {
+	if d.IPv6Prefix == nil {
+		return nil
+	}
+
+	if d.IPv6Gateway != nil {
+		return d.IPv6Gateway.To16()
+	}
+
+	gateway := make(net.IP, net.IPv6len)
+	copy(gateway, d.IPv6Prefix.IP.To16())
+	gateway[net.IPv6len-1]++
+	return gateway
+}
+
+// SlaacIP returns the address of the NIC with hardware address mac on a
+// dual stack subnet, using the modified EUI-64 interface identifier
+// defined for SLAAC. It returns nil if the subnet is IPv4 only
+func (d DhcpConfig) SlaacIP(mac net.HardwareAddr) net.IP {
+	if d.IPv6Prefix == nil || len(mac) != 6 {
+		return nil
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	copy(ip, d.IPv6Prefix.IP.To16())
+	ip[8] = mac[0] ^ 0x02
+	ip[9] = mac[1]
+	ip[10] = mac[2]
+	ip[11] = 0xff
+	ip[12] = 0xfe
+	ip[13] = mac[3]
+	ip[14] = mac[4]
+	ip[15] = mac[5]
+	return ip
+}
+
+// DNSConfig describes the internal DNS service of a tenant subnet.
+// Records map instance names, relative to Domain, to their addresses
+// and always hold the complete set of names served for the subnet
+type DNSConfig struct {
+	Domain     string            // Domain of the tenant
+	Forwarders []net.IP          // Optional: Upstream DNS servers
+	Records    map[string]net.IP // Optional: Instance name to IP mapping
+}
+
+func ipString(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+func ipNetString(ipNet *net.IPNet) string {
+	if ipNet == nil {
+		return ""
+	}
+	return ipNet.String()
+}
+
+func ipv6ModeString(dhcp DhcpConfig) string {
+	if dhcp.IPv6Prefix == nil {
+		return ""
+	}
+	return dhcp.IPv6Mode.String()
+}
+
 //VnicAttrs represent common Vnic attributes
 type VnicAttrs struct {
 	Attrs
@@ -158,6 +302,20 @@ type VnicAttrs struct {
 	BridgeID   string // ID of bridge it has attached to
 	IPAddr     *net.IP
 	MTU        int
+	RxMbit     int // Limit of the traffic received by the instance in Mbit/s
+	TxMbit     int // Limit of the traffic transmitted by the instance in Mbit/s
+}
+
+// VnicStats contains the traffic counters of a Vnic as seen by the
+// instance it is attached to, i.e., Rx counts the traffic received by
+// the instance.
+type VnicStats struct {
+	RxBytes   uint64
+	RxPackets uint64
+	RxDropped uint64
+	TxBytes   uint64
+	TxPackets uint64
+	TxDropped uint64
 }
 
 // Vnic represents a ciao VNIC (typically a tap or veth interface)
@@ -186,3 +344,25 @@ type GreTunEP struct {
 	CNCIId   string // UUID of the CNCI
 	CNId     string // UUID of the CN
 }
+
+// VxlanEP ciao VXLAN Tunnel representation
+// This represents the local end point of all the tunnels of a tenant
+// subnet. The remote end points are maintained in the forwarding database
+type VxlanEP struct {
+	Attrs
+	Link     *netlink.Vxlan
+	Key      uint32 // The VXLAN Network Identifier
+	LocalIP  net.IP
+	RemoteIP net.IP // Optional remote end point added on creation
+}
+
+// tunnelEP is the local end point of the overlay tunnel connecting
+// a tenant bridge to the rest of the tenant subnet
+type tunnelEP interface {
+	attrs() *Attrs
+	link() netlink.Link
+	create() error
+	destroy() error
+	enable() error
+	attach(dev interface{}) error
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package libsnnet
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// minBurstKB is the smallest burst, in kilobytes, used when shaping the
+// traffic of a vnic.  Smaller bursts prevent the configured rate from
+// being reached.
+const minBurstKB = 32
+
+// tcBurst returns the burst size for a rate of rateMbit, which corresponds
+// to about 10ms of traffic at that rate.
+func tcBurst(rateMbit int) string {
+	burst := rateMbit * 5 / 4
+	if burst < minBurstKB {
+		burst = minBurstKB
+	}
+	return fmt.Sprintf("%dkb", burst)
+}
+
+// tcShapingCmds returns the tc commands that limit the traffic of the
+// host side device of a vnic.  Traffic leaving device is received by the
+// instance and is shaped with a token bucket filter.  Traffic entering
+// device is transmitted by the instance and is policed on the ingress
+// qdisc.  A rate of 0 leaves the corresponding direction unlimited.
+func tcShapingCmds(device string, rxMbit, txMbit int) [][]string {
+	var cmds [][]string
+
+	if rxMbit > 0 {
+		cmds = append(cmds, []string{"qdisc", "replace", "dev", device,
+			"root", "tbf", "rate", fmt.Sprintf("%dmbit", rxMbit),
+			"burst", tcBurst(rxMbit), "latency", "50ms"})
+	}
+
+	if txMbit > 0 {
+		cmds = append(cmds, []string{"qdisc", "add", "dev", device,
+			"handle", "ffff:", "ingress"})
+		cmds = append(cmds, []string{"filter", "add", "dev", device,
+			"parent", "ffff:", "protocol", "all", "prio", "1", "u32",
+			"match", "u32", "0", "0", "police", "rate",
+			fmt.Sprintf("%dmbit", txMbit), "burst", tcBurst(txMbit),
+			"drop", "flowid", ":1"})
+	}
+
+	return cmds
+}
+
+// SetBandwidth limits the rate, in Mbit/s, at which the instance
+// attached to the vnic receives and transmits traffic.  Any previous
+// limits are removed.  A rate of 0 leaves the corresponding direction
+// unlimited.
+func (v *Vnic) SetBandwidth(rxMbit, txMbit int) error {
+	if v.LinkName == "" {
+		return netError(v, "set bandwidth unnitialized")
+	}
+
+	// Errors are expected here if no limits were previously set.
+	_ = exec.Command("tc", "qdisc", "del", "dev", v.LinkName, "root").Run()
+	_ = exec.Command("tc", "qdisc", "del", "dev", v.LinkName, "ingress").Run()
+
+	for _, args := range tcShapingCmds(v.LinkName, rxMbit, txMbit) {
+		out, err := exec.Command("tc", args...).CombinedOutput()
+		if err != nil {
+			return netError(v, "tc %s failed %v %s",
+				strings.Join(args, " "), err, string(out))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+//
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package libsnnet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTcShapingCmds(t *testing.T) {
+	if cmds := tcShapingCmds("tap0", 0, 0); len(cmds) != 0 {
+		t.Fatalf("Expected no commands, got %v", cmds)
+	}
+
+	expected := [][]string{
+		{"qdisc", "replace", "dev", "tap0", "root", "tbf", "rate", "10mbit",
+			"burst", "32kb", "latency", "50ms"},
+		{"qdisc", "add", "dev", "tap0", "handle", "ffff:", "ingress"},
+		{"filter", "add", "dev", "tap0", "parent", "ffff:", "protocol", "all",
+			"prio", "1", "u32", "match", "u32", "0", "0", "police", "rate",
+			"1000mbit", "burst", "1250kb", "drop", "flowid", ":1"},
+	}
+
+	cmds := tcShapingCmds("tap0", 10, 1000)
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Fatalf("Expected %v, got %v", expected, cmds)
+	}
+
+	cmds = tcShapingCmds("tap0", 0, 1000)
+	if !reflect.DeepEqual(cmds, expected[1:]) {
+		t.Fatalf("Expected %v, got %v", expected[1:], cmds)
+	}
+}
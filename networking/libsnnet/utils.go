@@ -35,6 +35,7 @@ const (
 	prefixVnicHost = "svn"
 	prefixCnciVnic = "svc"
 	prefixGretap   = "sgt"
+	prefixVxlan    = "svx"
 )
 
 const ifaceRetryLimit = 10
@@ -76,6 +77,7 @@ func validSnPrefix(s string) bool {
 	case strings.HasPrefix(s, prefixVnicHost):
 	case strings.HasPrefix(s, prefixCnciVnic):
 	case strings.HasPrefix(s, prefixGretap):
+	case strings.HasPrefix(s, prefixVxlan):
 	default:
 		return false
 	}
@@ -99,6 +101,8 @@ func getPrefix(device interface{}) (string, error) {
 		}
 	case *GreTunEP:
 		prefix = prefixGretap
+	case *VxlanEP:
+		prefix = prefixVxlan
 	case *CnciVnic:
 		prefix = prefixCnciVnic
 	}
@@ -18,7 +18,10 @@ package libsnnet
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -122,6 +125,40 @@ func (v *Vnic) GetDevice() error {
 	return nil
 }
 
+// Stats returns the traffic counters of the VNIC. The counters are read
+// from sysfs rather than netlink as the netlink statistics are only 32
+// bits wide. Traffic transmitted by the host side of the VNIC is received
+// by the instance, so the host side counters are swapped.
+func (v *Vnic) Stats() (*VnicStats, error) {
+	if v.LinkName == "" {
+		return nil, netError(v, "stats unnitialized")
+	}
+
+	stats := &VnicStats{}
+	counters := map[string]*uint64{
+		"tx_bytes":   &stats.RxBytes,
+		"tx_packets": &stats.RxPackets,
+		"tx_dropped": &stats.RxDropped,
+		"rx_bytes":   &stats.TxBytes,
+		"rx_packets": &stats.TxPackets,
+		"rx_dropped": &stats.TxDropped,
+	}
+
+	dir := filepath.Join("/sys/class/net", v.LinkName, "statistics")
+	for name, value := range counters {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, netError(v, "stats %v", err)
+		}
+		*value, err = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			return nil, netError(v, "stats invalid %s %v", name, err)
+		}
+	}
+
+	return stats, nil
+}
+
 // GetDeviceByName is used to associate with an existing VNIC relying on its
 // link name instead of its alias. Returns error if the VNIC does not exist
 func (v *Vnic) GetDeviceByName(linkName string) error {
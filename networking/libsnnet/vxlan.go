@@ -0,0 +1,295 @@
+//
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package libsnnet
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+)
+
+// vxlanPort is the IANA assigned VXLAN UDP port
+const vxlanPort = 4789
+
+// vxlanFloodMAC is the forwarding database MAC address used to flood
+// broadcast, unknown unicast and multicast traffic to a remote end point
+var vxlanFloodMAC = net.HardwareAddr{0, 0, 0, 0, 0, 0}
+
+// newVxlanEP is used to initialize the VXLAN tunnel properties
+// This has to be called prior to create() or getDevice()
+// The remoteIP is optional and if specified is added to the forwarding
+// database when the tunnel is created
+func newVxlanEP(id string, localIP net.IP, remoteIP net.IP, key uint32) (*VxlanEP, error) {
+	vxlan := &VxlanEP{}
+	vxlan.Link = &netlink.Vxlan{}
+	vxlan.GlobalID = id
+	vxlan.LocalIP = localIP
+	vxlan.RemoteIP = remoteIP
+	vxlan.Key = key
+	return vxlan, nil
+}
+
+// getDevice associates the tunnel with an existing VXLAN tunnel end point
+func (v *VxlanEP) getDevice() error {
+
+	if v.GlobalID == "" {
+		return netError(v, "get device unnamed vxlan device")
+	}
+
+	link, err := netlink.LinkByAlias(v.GlobalID)
+	if err != nil {
+		return netError(v, "get device interface does not exist: %v %v", v.GlobalID, err)
+	}
+
+	vl, ok := link.(*netlink.Vxlan)
+	if !ok {
+		return netError(v, "get device incorrect interface type %v %v", v.GlobalID, link.Type())
+	}
+	v.Link = vl
+	v.LinkName = vl.Name
+	v.LocalIP = vl.SrcAddr
+	v.Key = uint32(vl.VxlanId)
+
+	return nil
+}
+
+// create instantiates a tunnel end point
+func (v *VxlanEP) create() error {
+	var err error
+
+	if v.GlobalID == "" || v.Key == 0 {
+		return netError(v, "create cannot create an unnamed vxlan device")
+	}
+
+	if v.LinkName == "" {
+		if v.LinkName, err = genIface(v, false); err != nil {
+			return netError(v, "create geniface %v, %v", v.GlobalID, err)
+		}
+
+		if lerr, err := netlink.LinkByAlias(v.GlobalID); err == nil {
+			return netError(v, "create interface exists %v, %v", v.GlobalID, lerr)
+		}
+	}
+
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = v.LinkName
+
+	vxlan := &netlink.Vxlan{LinkAttrs: attrs,
+		VxlanId:  int(v.Key),
+		SrcAddr:  v.LocalIP,
+		Port:     vxlanPort,
+		Learning: true,
+	}
+
+	if err := netlink.LinkAdd(vxlan); err != nil {
+		return netError(v, "create link add %v %v", v.GlobalID, err)
+	}
+
+	link, err := netlink.LinkByName(v.LinkName)
+	if err != nil {
+		return netError(v, "create link by name %v %v", v.GlobalID, err)
+	}
+
+	vl, ok := link.(*netlink.Vxlan)
+	if !ok {
+		return netError(v, "create incorrect interface type %v, %v", v.GlobalID, link.Type())
+	}
+	v.Link = vl
+
+	if err := v.setAlias(v.GlobalID); err != nil {
+		_ = v.destroy()
+		return netError(v, "create link set alias %v %v", v.GlobalID, err)
+	}
+
+	if v.RemoteIP != nil {
+		if err := v.addRemote(v.RemoteIP); err != nil {
+			_ = v.destroy()
+			return netError(v, "create add remote %v %v", v.GlobalID, err)
+		}
+	}
+
+	return nil
+}
+
+// destroy an existing tunnel end point
+func (v *VxlanEP) destroy() error {
+
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "destroy invalid vxlan link: %v", v)
+	}
+
+	if err := netlink.LinkDel(v.Link); err != nil {
+		return netError(v, "destroy link del %v", err)
+	}
+
+	return nil
+}
+
+// enable the tunnel end point
+func (v *VxlanEP) enable() error {
+
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "enable invalid vxlan link: %v", v)
+	}
+
+	if err := netlink.LinkSetUp(v.Link); err != nil {
+		return netError(v, "enable link enable %v", err)
+	}
+
+	return nil
+}
+
+// disable the tunnel end point
+func (v *VxlanEP) disable() error {
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "disable invalid vxlan link: %v", v)
+	}
+
+	if err := netlink.LinkSetDown(v.Link); err != nil {
+		return netError(v, "disable link disable %v", err)
+	}
+	return nil
+}
+
+func (v *VxlanEP) setAlias(alias string) error {
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "set alias invalid vxlan link: %v", v)
+	}
+
+	if err := netlink.LinkSetAlias(v.Link, alias); err != nil {
+		return netError(v, "set alias link set alias %v %v", alias, err)
+	}
+
+	return nil
+}
+
+// attach the VXLAN tunnel end point to a device/bridge/switch
+func (v *VxlanEP) attach(dev interface{}) error {
+
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "attach vxlan tunnel unnitialized")
+	}
+
+	br, ok := dev.(*Bridge)
+	if !ok {
+		return netError(v, "attach unknown device %v, %T", dev, dev)
+	}
+
+	if br.Link == nil || br.Link.Index == 0 {
+		return netError(v, "attach bridge unnitialized")
+	}
+
+	err := netlink.LinkSetMaster(v.Link, br.Link)
+	if err != nil {
+		return netError(v, "attach link set master %v", err)
+	}
+
+	return nil
+}
+
+// detach the VXLAN tunnel end point from the device/bridge it is attached to
+func (v *VxlanEP) detach(dev interface{}) error {
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "detach invalid vxlan link: %v", v)
+	}
+
+	br, ok := dev.(*Bridge)
+	if !ok {
+		return netError(v, "detach incorrect device type %v, %T", dev, dev)
+	}
+
+	if br.Link == nil || br.Link.Index == 0 {
+		return netError(v, "detach bridge unnitialized")
+	}
+
+	if err := netlink.LinkSetNoMaster(v.Link); err != nil {
+		return netError(v, "detach link set no master %v", err)
+	}
+
+	return nil
+}
+
+func (v *VxlanEP) attrs() *Attrs {
+	return &v.Attrs
+}
+
+func (v *VxlanEP) link() netlink.Link {
+	return v.Link
+}
+
+func (v *VxlanEP) fdbEntry(remoteIP net.IP) *netlink.Neigh {
+	return &netlink.Neigh{
+		LinkIndex:    v.Link.Index,
+		Family:       syscall.AF_BRIDGE,
+		State:        netlink.NUD_PERMANENT | netlink.NUD_NOARP,
+		Flags:        netlink.NTF_SELF,
+		IP:           remoteIP,
+		HardwareAddr: vxlanFloodMAC,
+	}
+}
+
+// addRemote adds a remote tunnel end point to the forwarding database.
+// Broadcast, unknown unicast and multicast traffic is flooded to all the
+// remote end points of the tunnel
+func (v *VxlanEP) addRemote(remoteIP net.IP) error {
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "add remote invalid vxlan link: %v", v)
+	}
+
+	if err := netlink.NeighAppend(v.fdbEntry(remoteIP)); err != nil {
+		return netError(v, "add remote %v %v", remoteIP, err)
+	}
+
+	return nil
+}
+
+// delRemote removes a remote tunnel end point from the forwarding database
+func (v *VxlanEP) delRemote(remoteIP net.IP) error {
+	if v.Link == nil || v.Link.Index == 0 {
+		return netError(v, "del remote invalid vxlan link: %v", v)
+	}
+
+	if err := netlink.NeighDel(v.fdbEntry(remoteIP)); err != nil {
+		return netError(v, "del remote %v %v", remoteIP, err)
+	}
+
+	return nil
+}
+
+// remotes lists the remote tunnel end points present in the forwarding
+// database
+func (v *VxlanEP) remotes() ([]net.IP, error) {
+	if v.Link == nil || v.Link.Index == 0 {
+		return nil, netError(v, "remotes invalid vxlan link: %v", v)
+	}
+
+	neighs, err := netlink.NeighList(v.Link.Index, syscall.AF_BRIDGE)
+	if err != nil {
+		return nil, netError(v, "remotes neigh list %v", err)
+	}
+
+	var ips []net.IP
+	for _, n := range neighs {
+		if n.IP == nil || n.HardwareAddr.String() != vxlanFloodMAC.String() {
+			continue
+		}
+		ips = append(ips, n.IP)
+	}
+
+	return ips, nil
+}
@@ -0,0 +1,34 @@
+/*
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// ConcentratorUpdateCommand contains the CNCI that now serves a tenant
+// subnet.  It is sent to each CN agent running instances attached to the
+// subnet when a standby CNCI takes over the subnet.
+type ConcentratorUpdateCommand struct {
+	AgentUUID        string `yaml:"agent_uuid"`
+	TenantUUID       string `yaml:"tenant_uuid"`
+	TenantSubnet     string `yaml:"tenant_subnet"`
+	ConcentratorUUID string `yaml:"concentrator_uuid"`
+	ConcentratorIP   string `yaml:"concentrator_ip"`
+}
+
+// CommandUpdateConcentrator is a wrapper around ConcentratorUpdateCommand.
+// It is the UpdateConcentrator command payload.
+type CommandUpdateConcentrator struct {
+	UpdateConcentrator ConcentratorUpdateCommand `yaml:"update_concentrator"`
+}
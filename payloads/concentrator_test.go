@@ -0,0 +1,74 @@
+/*
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUpdateConcentratorUnmarshal(t *testing.T) {
+	var cmd CommandUpdateConcentrator
+
+	err := yaml.Unmarshal([]byte(testutil.UpdateConcentratorYaml), &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := cmd.UpdateConcentrator
+	if update.AgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong agent UUID field [%s]", update.AgentUUID)
+	}
+
+	if update.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", update.TenantUUID)
+	}
+
+	if update.TenantSubnet != testutil.TenantSubnet {
+		t.Errorf("Wrong tenant subnet field [%s]", update.TenantSubnet)
+	}
+
+	if update.ConcentratorUUID != testutil.CNCIUUID {
+		t.Errorf("Wrong concentrator UUID field [%s]", update.ConcentratorUUID)
+	}
+
+	if update.ConcentratorIP != testutil.CNCIIP {
+		t.Errorf("Wrong concentrator IP field [%s]", update.ConcentratorIP)
+	}
+}
+
+func TestUpdateConcentratorMarshal(t *testing.T) {
+	var cmd CommandUpdateConcentrator
+
+	cmd.UpdateConcentrator.AgentUUID = testutil.AgentUUID
+	cmd.UpdateConcentrator.TenantUUID = testutil.TenantUUID
+	cmd.UpdateConcentrator.TenantSubnet = testutil.TenantSubnet
+	cmd.UpdateConcentrator.ConcentratorUUID = testutil.CNCIUUID
+	cmd.UpdateConcentrator.ConcentratorIP = testutil.CNCIIP
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(y) != testutil.UpdateConcentratorYaml {
+		t.Errorf("UpdateConcentrator marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.UpdateConcentratorYaml)
+	}
+}
@@ -0,0 +1,63 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package payloads
+
+// OpenConsoleCmd contains the information needed to open an interactive
+// session on the serial console of an instance.
+type OpenConsoleCmd struct {
+	// InstanceUUID is the UUID of the instance whose console is to be
+	// opened.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// Token is a secret, generated by the controller, that the client
+	// connecting to the console session must present before it is
+	// granted access to the console.
+	Token string `yaml:"token"`
+}
+
+// OpenConsole represents the unmarshalled version of the contents of a
+// SSNTP OpenConsole payload.
+type OpenConsole struct {
+	Console OpenConsoleCmd `yaml:"open_console"`
+}
+
+// ConsoleOpenedEvent contains the address of a console session opened
+// in response to an OpenConsole command.
+type ConsoleOpenedEvent struct {
+	// InstanceUUID is the UUID of the instance to which the console
+	// belongs.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Token is the token passed in the OpenConsole command.  It allows
+	// the controller to match the event to its request.
+	Token string `yaml:"token"`
+
+	// Address is the host:port on which the console session is waiting
+	// for a connection.
+	Address string `yaml:"address"`
+}
+
+// EventConsoleOpened represents the unmarshalled version of the contents of
+// an SSNTP ssntp.ConsoleOpened event.  This event is sent by ciao-launcher in
+// response to an OpenConsole command.
+type EventConsoleOpened struct {
+	Console ConsoleOpenedEvent `yaml:"console_opened"`
+}
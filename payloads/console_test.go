@@ -0,0 +1,98 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestOpenConsoleUnmarshal(t *testing.T) {
+	var cmd OpenConsole
+	err := yaml.Unmarshal([]byte(testutil.OpenConsoleYaml), &cmd)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cmd.Console.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", cmd.Console.InstanceUUID)
+	}
+
+	if cmd.Console.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong agent UUID field [%s]", cmd.Console.WorkloadAgentUUID)
+	}
+
+	if cmd.Console.Token != testutil.ConsoleToken {
+		t.Errorf("Wrong token field [%s]", cmd.Console.Token)
+	}
+}
+
+func TestOpenConsoleMarshal(t *testing.T) {
+	var cmd OpenConsole
+
+	cmd.Console.InstanceUUID = testutil.InstanceUUID
+	cmd.Console.WorkloadAgentUUID = testutil.AgentUUID
+	cmd.Console.Token = testutil.ConsoleToken
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.OpenConsoleYaml {
+		t.Errorf("OpenConsole marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.OpenConsoleYaml)
+	}
+}
+
+func TestConsoleOpenedUnmarshal(t *testing.T) {
+	var event EventConsoleOpened
+	err := yaml.Unmarshal([]byte(testutil.ConsoleOpenedYaml), &event)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if event.Console.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", event.Console.InstanceUUID)
+	}
+
+	if event.Console.Token != testutil.ConsoleToken {
+		t.Errorf("Wrong token field [%s]", event.Console.Token)
+	}
+
+	if event.Console.Address != testutil.ConsoleAddress {
+		t.Errorf("Wrong address field [%s]", event.Console.Address)
+	}
+}
+
+func TestConsoleOpenedMarshal(t *testing.T) {
+	var event EventConsoleOpened
+
+	event.Console.InstanceUUID = testutil.InstanceUUID
+	event.Console.Token = testutil.ConsoleToken
+	event.Console.Address = testutil.ConsoleAddress
+
+	y, err := yaml.Marshal(&event)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ConsoleOpenedYaml {
+		t.Errorf("ConsoleOpened marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.ConsoleOpenedYaml)
+	}
+}
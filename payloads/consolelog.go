@@ -0,0 +1,65 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// ConsoleLogCmd contains the information needed to retrieve the serial
+// console log of an instance.
+type ConsoleLogCmd struct {
+	// InstanceUUID is the UUID of the instance whose console log is
+	// requested.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// Lines is the maximum number of lines, counted from the end of
+	// the log, to return.  0 requests the entire log.
+	Lines int `yaml:"lines"`
+
+	// RequestID identifies the request.  It is returned in the
+	// ConsoleLog event or ConsoleLogFailure error sent in reply.
+	RequestID string `yaml:"request_id"`
+}
+
+// GetConsoleLog represents the unmarshalled version of the contents of a
+// SSNTP GetConsoleLog payload.
+type GetConsoleLog struct {
+	ConsoleLog ConsoleLogCmd `yaml:"get_console_log"`
+}
+
+// ConsoleLogEvent contains the tail of the serial console log of an
+// instance.
+type ConsoleLogEvent struct {
+	// InstanceUUID is the UUID of the instance to which the log belongs.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Log contains the requested lines of the console log.
+	Log string `yaml:"log"`
+
+	// RequestID is the RequestID of the GetConsoleLog command to which
+	// this event is a reply.
+	RequestID string `yaml:"request_id"`
+}
+
+// EventConsoleLog represents the unmarshalled version of the contents of
+// an SSNTP ssntp.ConsoleLog event.  This event is sent by ciao-launcher in
+// response to a GetConsoleLog command.
+type EventConsoleLog struct {
+	ConsoleLog ConsoleLogEvent `yaml:"console_log"`
+}
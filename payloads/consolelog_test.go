@@ -0,0 +1,103 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestGetConsoleLogUnmarshal(t *testing.T) {
+	var cmd GetConsoleLog
+	err := yaml.Unmarshal([]byte(testutil.GetConsoleLogYaml), &cmd)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cmd.ConsoleLog.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", cmd.ConsoleLog.InstanceUUID)
+	}
+
+	if cmd.ConsoleLog.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong agent UUID field [%s]", cmd.ConsoleLog.WorkloadAgentUUID)
+	}
+
+	if cmd.ConsoleLog.Lines != 100 {
+		t.Errorf("Wrong lines field [%d]", cmd.ConsoleLog.Lines)
+	}
+
+	if cmd.ConsoleLog.RequestID != testutil.ConsoleLogRequestID {
+		t.Errorf("Wrong request ID field [%s]", cmd.ConsoleLog.RequestID)
+	}
+}
+
+func TestGetConsoleLogMarshal(t *testing.T) {
+	var cmd GetConsoleLog
+
+	cmd.ConsoleLog.InstanceUUID = testutil.InstanceUUID
+	cmd.ConsoleLog.WorkloadAgentUUID = testutil.AgentUUID
+	cmd.ConsoleLog.Lines = 100
+	cmd.ConsoleLog.RequestID = testutil.ConsoleLogRequestID
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.GetConsoleLogYaml {
+		t.Errorf("GetConsoleLog marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.GetConsoleLogYaml)
+	}
+}
+
+func TestConsoleLogUnmarshal(t *testing.T) {
+	var event EventConsoleLog
+	err := yaml.Unmarshal([]byte(testutil.ConsoleLogYaml), &event)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if event.ConsoleLog.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", event.ConsoleLog.InstanceUUID)
+	}
+
+	if event.ConsoleLog.Log != testutil.ConsoleLogOutput {
+		t.Errorf("Wrong log field [%s]", event.ConsoleLog.Log)
+	}
+
+	if event.ConsoleLog.RequestID != testutil.ConsoleLogRequestID {
+		t.Errorf("Wrong request ID field [%s]", event.ConsoleLog.RequestID)
+	}
+}
+
+func TestConsoleLogMarshal(t *testing.T) {
+	var event EventConsoleLog
+
+	event.ConsoleLog.InstanceUUID = testutil.InstanceUUID
+	event.ConsoleLog.Log = testutil.ConsoleLogOutput
+	event.ConsoleLog.RequestID = testutil.ConsoleLogRequestID
+
+	y, err := yaml.Marshal(&event)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.ConsoleLogYaml {
+		t.Errorf("ConsoleLog marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.ConsoleLogYaml)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// ConsoleLogFailureReason denotes the underlying error that prevented
+// an SSNTP GetConsoleLog command from retrieving the console log of an
+// instance.
+type ConsoleLogFailureReason string
+
+const (
+	// ConsoleLogNoInstance indicates that the console log could not be
+	// retrieved as the instance does not exist on the node to which the
+	// GetConsoleLog command was sent.
+	ConsoleLogNoInstance ConsoleLogFailureReason = "no_instance"
+
+	// ConsoleLogInvalidPayload indicates that the payload of the SSNTP
+	// GetConsoleLog command was corrupt and could not be unmarshalled.
+	ConsoleLogInvalidPayload = "invalid_payload"
+
+	// ConsoleLogInvalidData is returned by ciao-launcher if the contents
+	// of the GetConsoleLog payload are incorrect, e.g., the instance_uuid
+	// is missing.
+	ConsoleLogInvalidData = "invalid_data"
+
+	// ConsoleLogReadFailure indicates that the console log of the
+	// instance could not be read.
+	ConsoleLogReadFailure = "read_failure"
+
+	// ConsoleLogNotSupported indicates that console logs are not
+	// available for the given workload type, e.g., a container.
+	ConsoleLogNotSupported = "not_supported"
+)
+
+// ErrorConsoleLogFailure represents the unmarshalled version of the contents of a
+// SSNTP ERROR frame whose type is set to ssntp.ConsoleLogFailure.
+type ErrorConsoleLogFailure struct {
+	// NodeUUID is the UUID of the node that generated this error.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance whose console log could
+	// not be retrieved.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Reason provides the reason for the failure, e.g.,
+	// ConsoleLogNoInstance.
+	Reason ConsoleLogFailureReason `yaml:"reason"`
+
+	// RequestID is the RequestID of the GetConsoleLog command that
+	// failed.
+	RequestID string `yaml:"request_id"`
+}
+
+func (r ConsoleLogFailureReason) String() string {
+	switch r {
+	case ConsoleLogNoInstance:
+		return "Instance does not exist"
+	case ConsoleLogInvalidPayload:
+		return "YAML payload is corrupt"
+	case ConsoleLogInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case ConsoleLogReadFailure:
+		return "Failed to read console log"
+	case ConsoleLogNotSupported:
+		return "Not Supported"
+	}
+
+	return ""
+}
@@ -0,0 +1,41 @@
+/*
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// DNSRecord maps an instance name to its private IP address.
+type DNSRecord struct {
+	Name string `yaml:"name"`
+	IP   string `yaml:"ip"`
+}
+
+// DNSCommand contains the DNS configuration a CNCI serves for
+// one of its tenant subnets.  Records always holds the complete set
+// of records for the subnet, replacing any previously sent set.
+type DNSCommand struct {
+	ConcentratorUUID string      `yaml:"concentrator_uuid"`
+	TenantUUID       string      `yaml:"tenant_uuid"`
+	TenantSubnet     string      `yaml:"tenant_subnet"`
+	Domain           string      `yaml:"domain"`
+	Forwarders       []string    `yaml:"forwarders,omitempty"`
+	Records          []DNSRecord `yaml:"records,omitempty"`
+}
+
+// CommandUpdateDNS is a wrapper around DNSCommand. It is the
+// UpdateDNS command payload.
+type CommandUpdateDNS struct {
+	UpdateDNS DNSCommand `yaml:"update_dns"`
+}
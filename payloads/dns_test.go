@@ -0,0 +1,89 @@
+/*
+// Copyright (c) 2016 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUpdateDNSUnmarshal(t *testing.T) {
+	var cmd CommandUpdateDNS
+
+	err := yaml.Unmarshal([]byte(testutil.UpdateDNSYaml), &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dns := cmd.UpdateDNS
+	if dns.ConcentratorUUID != testutil.CNCIUUID {
+		t.Errorf("Wrong concentrator UUID field [%s]", dns.ConcentratorUUID)
+	}
+
+	if dns.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", dns.TenantUUID)
+	}
+
+	if dns.TenantSubnet != testutil.TenantSubnet {
+		t.Errorf("Wrong tenant subnet field [%s]", dns.TenantSubnet)
+	}
+
+	if dns.Domain != testutil.DNSDomain {
+		t.Errorf("Wrong domain field [%s]", dns.Domain)
+	}
+
+	if len(dns.Forwarders) != 1 || dns.Forwarders[0] != testutil.DNSForwarder {
+		t.Errorf("Wrong forwarders field %v", dns.Forwarders)
+	}
+
+	if len(dns.Records) != 1 {
+		t.Fatalf("Wrong number of records %d", len(dns.Records))
+	}
+
+	if dns.Records[0].Name != testutil.InstanceName {
+		t.Errorf("Wrong record name [%s]", dns.Records[0].Name)
+	}
+
+	if dns.Records[0].IP != testutil.InstancePrivateIP {
+		t.Errorf("Wrong record IP [%s]", dns.Records[0].IP)
+	}
+}
+
+func TestUpdateDNSMarshal(t *testing.T) {
+	var cmd CommandUpdateDNS
+
+	cmd.UpdateDNS.ConcentratorUUID = testutil.CNCIUUID
+	cmd.UpdateDNS.TenantUUID = testutil.TenantUUID
+	cmd.UpdateDNS.TenantSubnet = testutil.TenantSubnet
+	cmd.UpdateDNS.Domain = testutil.DNSDomain
+	cmd.UpdateDNS.Forwarders = []string{testutil.DNSForwarder}
+	cmd.UpdateDNS.Records = []DNSRecord{
+		{Name: testutil.InstanceName, IP: testutil.InstancePrivateIP},
+	}
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(y) != testutil.UpdateDNSYaml {
+		t.Errorf("UpdateDNS marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.UpdateDNSYaml)
+	}
+}
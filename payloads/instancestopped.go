@@ -16,10 +16,28 @@
 
 package payloads
 
+// StopMethod describes how a node brought down a running instance.
+type StopMethod string
+
+const (
+	// StopGraceful indicates that the instance shut down cleanly within
+	// its graceful timeout, in response to an ACPI power down request or,
+	// for containers, to a SIGTERM.
+	StopGraceful StopMethod = "graceful"
+
+	// StopForced indicates that the instance did not shut down within its
+	// graceful timeout and had to be terminated.
+	StopForced = "forced"
+)
+
 // InstanceStoppedEvent contains the UUID of an instance that has just been
 // deleted from a node for the purposes of migration.
 type InstanceStoppedEvent struct {
 	InstanceUUID string `yaml:"instance_uuid"`
+
+	// StopMethod indicates how the instance was brought down.  It is
+	// empty if the instance was not running.
+	StopMethod StopMethod `yaml:"stop_method,omitempty"`
 }
 
 // EventInstanceStopped represents the unmarshalled version of the contents of
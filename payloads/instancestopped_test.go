@@ -34,12 +34,17 @@ func TestInstanceStoppedUnmarshal(t *testing.T) {
 	if insStop.InstanceStopped.InstanceUUID != testutil.InstanceUUID {
 		t.Errorf("Wrong instance UUID field [%s]", insStop.InstanceStopped.InstanceUUID)
 	}
+
+	if insStop.InstanceStopped.StopMethod != StopGraceful {
+		t.Errorf("Wrong stop method field [%s]", insStop.InstanceStopped.StopMethod)
+	}
 }
 
 func TestInstanceStoppedMarshal(t *testing.T) {
 	var insStop EventInstanceStopped
 
 	insStop.InstanceStopped.InstanceUUID = testutil.InstanceUUID
+	insStop.InstanceStopped.StopMethod = StopGraceful
 
 	y, err := yaml.Marshal(&insStop)
 	if err != nil {
@@ -0,0 +1,91 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+const (
+	// LoadBalancerTCP is the protocol of load balancers which spread TCP
+	// connections across their members.
+	LoadBalancerTCP = "tcp"
+
+	// LoadBalancerHTTP is the protocol of load balancers which spread
+	// HTTP requests across their members.  Their health checks are HTTP
+	// GET requests.
+	LoadBalancerHTTP = "http"
+)
+
+// LoadBalancerMember is an instance receiving the traffic of a load
+// balancer.
+type LoadBalancerMember struct {
+	// IP is the address of the instance on the tenant subnet served
+	// by the CNCI.
+	IP string `yaml:"ip"`
+
+	// Port is the port of the instance traffic is forwarded to.
+	Port int `yaml:"port"`
+}
+
+// LoadBalancerHealthCheck describes how a CNCI checks the members of a
+// load balancer.  Members failing the check stop receiving traffic.
+type LoadBalancerHealthCheck struct {
+	// Interval is the number of seconds between two checks of a member.
+	Interval int `yaml:"interval"`
+
+	// Timeout is the number of seconds a member has to answer a check.
+	Timeout int `yaml:"timeout"`
+
+	// Retries is the number of consecutive checks a member has to fail,
+	// or to pass, before it is considered down, or up.
+	Retries int `yaml:"retries"`
+
+	// Path is the URI requested by the health checks of HTTP load
+	// balancers.
+	Path string `yaml:"path,omitempty"`
+}
+
+// LoadBalancerCommand contains the configuration of a tenant load balancer
+// served by a CNCI.
+type LoadBalancerCommand struct {
+	ConcentratorUUID string                  `yaml:"concentrator_uuid"`
+	TenantUUID       string                  `yaml:"tenant_uuid"`
+	LoadBalancerUUID string                  `yaml:"load_balancer_uuid"`
+	VIP              string                  `yaml:"vip"`
+	Port             int                     `yaml:"port"`
+	Protocol         string                  `yaml:"protocol"`
+	Members          []LoadBalancerMember    `yaml:"members"`
+	HealthCheck      LoadBalancerHealthCheck `yaml:"health_check"`
+}
+
+// CommandConfigureLoadBalancer is a wrapper around LoadBalancerCommand.
+// It is the ConfigureLoadBalancer command payload.
+type CommandConfigureLoadBalancer struct {
+	ConfigureLoadBalancer LoadBalancerCommand `yaml:"configure_load_balancer"`
+}
+
+// LoadBalancerRemoveCommand identifies a load balancer to be removed from
+// a CNCI.
+type LoadBalancerRemoveCommand struct {
+	ConcentratorUUID string `yaml:"concentrator_uuid"`
+	TenantUUID       string `yaml:"tenant_uuid"`
+	LoadBalancerUUID string `yaml:"load_balancer_uuid"`
+	VIP              string `yaml:"vip"`
+}
+
+// CommandRemoveLoadBalancer is a wrapper around LoadBalancerRemoveCommand.
+// It is the RemoveLoadBalancer command payload.
+type CommandRemoveLoadBalancer struct {
+	RemoveLoadBalancer LoadBalancerRemoveCommand `yaml:"remove_load_balancer"`
+}
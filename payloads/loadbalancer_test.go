@@ -0,0 +1,138 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfigureLoadBalancerUnmarshal(t *testing.T) {
+	var cmd CommandConfigureLoadBalancer
+
+	err := yaml.Unmarshal([]byte(testutil.ConfigureLoadBalancerYaml), &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lb := cmd.ConfigureLoadBalancer
+	if lb.ConcentratorUUID != testutil.CNCIUUID {
+		t.Errorf("Wrong concentrator UUID field [%s]", lb.ConcentratorUUID)
+	}
+
+	if lb.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", lb.TenantUUID)
+	}
+
+	if lb.LoadBalancerUUID != testutil.LoadBalancerUUID {
+		t.Errorf("Wrong load balancer UUID field [%s]", lb.LoadBalancerUUID)
+	}
+
+	if lb.VIP != testutil.InstancePublicIP {
+		t.Errorf("Wrong VIP field [%s]", lb.VIP)
+	}
+
+	if lb.Port != 80 || lb.Protocol != LoadBalancerHTTP {
+		t.Errorf("Wrong listener [%d/%s]", lb.Port, lb.Protocol)
+	}
+
+	if len(lb.Members) != 1 || lb.Members[0].IP != testutil.InstancePrivateIP ||
+		lb.Members[0].Port != 8080 {
+		t.Errorf("Wrong members field %v", lb.Members)
+	}
+
+	hc := lb.HealthCheck
+	if hc.Interval != 5 || hc.Timeout != 2 || hc.Retries != 3 || hc.Path != "/" {
+		t.Errorf("Wrong health check field %v", hc)
+	}
+}
+
+func TestConfigureLoadBalancerMarshal(t *testing.T) {
+	var cmd CommandConfigureLoadBalancer
+
+	cmd.ConfigureLoadBalancer.ConcentratorUUID = testutil.CNCIUUID
+	cmd.ConfigureLoadBalancer.TenantUUID = testutil.TenantUUID
+	cmd.ConfigureLoadBalancer.LoadBalancerUUID = testutil.LoadBalancerUUID
+	cmd.ConfigureLoadBalancer.VIP = testutil.InstancePublicIP
+	cmd.ConfigureLoadBalancer.Port = 80
+	cmd.ConfigureLoadBalancer.Protocol = LoadBalancerHTTP
+	cmd.ConfigureLoadBalancer.Members = []LoadBalancerMember{
+		{IP: testutil.InstancePrivateIP, Port: 8080},
+	}
+	cmd.ConfigureLoadBalancer.HealthCheck = LoadBalancerHealthCheck{
+		Interval: 5,
+		Timeout:  2,
+		Retries:  3,
+		Path:     "/",
+	}
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(y) != testutil.ConfigureLoadBalancerYaml {
+		t.Errorf("ConfigureLoadBalancer marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.ConfigureLoadBalancerYaml)
+	}
+}
+
+func TestRemoveLoadBalancerUnmarshal(t *testing.T) {
+	var cmd CommandRemoveLoadBalancer
+
+	err := yaml.Unmarshal([]byte(testutil.RemoveLoadBalancerYaml), &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lb := cmd.RemoveLoadBalancer
+	if lb.ConcentratorUUID != testutil.CNCIUUID {
+		t.Errorf("Wrong concentrator UUID field [%s]", lb.ConcentratorUUID)
+	}
+
+	if lb.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", lb.TenantUUID)
+	}
+
+	if lb.LoadBalancerUUID != testutil.LoadBalancerUUID {
+		t.Errorf("Wrong load balancer UUID field [%s]", lb.LoadBalancerUUID)
+	}
+
+	if lb.VIP != testutil.InstancePublicIP {
+		t.Errorf("Wrong VIP field [%s]", lb.VIP)
+	}
+}
+
+func TestRemoveLoadBalancerMarshal(t *testing.T) {
+	var cmd CommandRemoveLoadBalancer
+
+	cmd.RemoveLoadBalancer.ConcentratorUUID = testutil.CNCIUUID
+	cmd.RemoveLoadBalancer.TenantUUID = testutil.TenantUUID
+	cmd.RemoveLoadBalancer.LoadBalancerUUID = testutil.LoadBalancerUUID
+	cmd.RemoveLoadBalancer.VIP = testutil.InstancePublicIP
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(y) != testutil.RemoveLoadBalancerYaml {
+		t.Errorf("RemoveLoadBalancer marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.RemoveLoadBalancerYaml)
+	}
+}
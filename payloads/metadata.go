@@ -0,0 +1,55 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// MetadataNIC describes a NIC of an instance to the instance itself.
+type MetadataNIC struct {
+	MAC     string `yaml:"mac"`
+	IP      string `yaml:"ip"`
+	Subnet  string `yaml:"subnet"`
+	Gateway string `yaml:"gateway"`
+}
+
+// InstanceMetadata contains the metadata served to an instance by the
+// metadata service of a CNCI.  The first NIC is the primary NIC of the
+// instance, the one its default route goes through.
+type InstanceMetadata struct {
+	InstanceUUID string            `yaml:"instance_uuid"`
+	Name         string            `yaml:"name,omitempty"`
+	Hostname     string            `yaml:"hostname"`
+	NICs         []MetadataNIC     `yaml:"nics"`
+	PublicKeys   []string          `yaml:"public_keys,omitempty"`
+	Metadata     map[string]string `yaml:"metadata,omitempty"`
+	UserData     string            `yaml:"user_data,omitempty"`
+}
+
+// MetadataCommand contains the metadata a CNCI serves to the instances
+// attached to one of its tenant subnets.  Instances always holds the
+// complete set of instances of the subnet, replacing any previously sent
+// set.
+type MetadataCommand struct {
+	ConcentratorUUID string             `yaml:"concentrator_uuid"`
+	TenantUUID       string             `yaml:"tenant_uuid"`
+	TenantSubnet     string             `yaml:"tenant_subnet"`
+	Instances        []InstanceMetadata `yaml:"instances,omitempty"`
+}
+
+// CommandUpdateMetadata is a wrapper around MetadataCommand. It is the
+// UpdateMetadata command payload.
+type CommandUpdateMetadata struct {
+	UpdateMetadata MetadataCommand `yaml:"update_metadata"`
+}
@@ -0,0 +1,119 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+const testUserData = "#cloud-config\nhostname: " + testutil.InstanceName + "\n"
+
+func TestUpdateMetadataUnmarshal(t *testing.T) {
+	var cmd CommandUpdateMetadata
+
+	err := yaml.Unmarshal([]byte(testutil.UpdateMetadataYaml), &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	md := cmd.UpdateMetadata
+	if md.ConcentratorUUID != testutil.CNCIUUID {
+		t.Errorf("Wrong concentrator UUID field [%s]", md.ConcentratorUUID)
+	}
+
+	if md.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", md.TenantUUID)
+	}
+
+	if md.TenantSubnet != testutil.TenantSubnet {
+		t.Errorf("Wrong tenant subnet field [%s]", md.TenantSubnet)
+	}
+
+	if len(md.Instances) != 1 {
+		t.Fatalf("Wrong number of instances %d", len(md.Instances))
+	}
+
+	i := md.Instances[0]
+	if i.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", i.InstanceUUID)
+	}
+
+	if i.Name != testutil.InstanceName || i.Hostname != testutil.InstanceName {
+		t.Errorf("Wrong name fields [%s] [%s]", i.Name, i.Hostname)
+	}
+
+	if len(i.NICs) != 1 {
+		t.Fatalf("Wrong number of NICs %d", len(i.NICs))
+	}
+
+	nic := i.NICs[0]
+	if nic.MAC != testutil.VNICMAC || nic.IP != testutil.InstancePrivateIP ||
+		nic.Subnet != testutil.TenantSubnet || nic.Gateway != testutil.InstanceGateway {
+		t.Errorf("Wrong NIC fields %v", nic)
+	}
+
+	if len(i.PublicKeys) != 1 || i.PublicKeys[0] != testutil.InstanceSSHKey {
+		t.Errorf("Wrong public keys field %v", i.PublicKeys)
+	}
+
+	if len(i.Metadata) != 1 || i.Metadata["role"] != "web" {
+		t.Errorf("Wrong metadata field %v", i.Metadata)
+	}
+
+	if i.UserData != testUserData {
+		t.Errorf("Wrong user data field [%s]", i.UserData)
+	}
+}
+
+func TestUpdateMetadataMarshal(t *testing.T) {
+	var cmd CommandUpdateMetadata
+
+	cmd.UpdateMetadata.ConcentratorUUID = testutil.CNCIUUID
+	cmd.UpdateMetadata.TenantUUID = testutil.TenantUUID
+	cmd.UpdateMetadata.TenantSubnet = testutil.TenantSubnet
+	cmd.UpdateMetadata.Instances = []InstanceMetadata{
+		{
+			InstanceUUID: testutil.InstanceUUID,
+			Name:         testutil.InstanceName,
+			Hostname:     testutil.InstanceName,
+			NICs: []MetadataNIC{
+				{
+					MAC:     testutil.VNICMAC,
+					IP:      testutil.InstancePrivateIP,
+					Subnet:  testutil.TenantSubnet,
+					Gateway: testutil.InstanceGateway,
+				},
+			},
+			PublicKeys: []string{testutil.InstanceSSHKey},
+			Metadata:   map[string]string{"role": "web"},
+			UserData:   testUserData,
+		},
+	}
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(y) != testutil.UpdateMetadataYaml {
+		t.Errorf("UpdateMetadata marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.UpdateMetadataYaml)
+	}
+}
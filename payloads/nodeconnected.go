@@ -22,7 +22,7 @@ type NodeConnectedEvent struct {
 	// SSNTP UUID of the agent running on that node.
 	NodeUUID string `yaml:"node_uuid"`
 
-	// The type of the node, e.g., NetworkNode or ComputeNode.
+	// The type of the node, e.g., NetworkNode, ComputeNode or CNCINode.
 	NodeType Resource `yaml:"node_type"`
 }
 
@@ -0,0 +1,53 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// InstanceInventory describes a single instance hosted by a node.
+type InstanceInventory struct {
+	// InstanceUUID is the UUID of the instance.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// TenantUUID is the UUID of the tenant that owns the instance.
+	TenantUUID string `yaml:"tenant_uuid"`
+
+	// State is the state of the instance as known to the node, e.g.,
+	// Running or Exited.  It is set to Pending if the node has not yet
+	// determined whether an instance it has restored is still running.
+	State string `yaml:"state"`
+
+	// Lost is true if the instance was running when the node went down
+	// and did not survive the node's restart.  Instances that exited of
+	// their own accord are not marked as lost.
+	Lost bool `yaml:"lost,omitempty"`
+}
+
+// NodeInventoryEvent lists all the instances hosted by a node.
+type NodeInventoryEvent struct {
+	// NodeUUID is the UUID of the node sending the inventory.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// Instances contains an entry for every instance hosted by the node.
+	Instances []InstanceInventory `yaml:"instances"`
+}
+
+// EventNodeInventory represents the unmarshalled version of the contents of
+// an SSNTP ssntp.NodeInventory event.  This event is sent by ciao-launcher
+// each time it connects to the scheduler so that the controller can
+// reconcile its view of the node with the instances actually present.
+type EventNodeInventory struct {
+	Inventory NodeInventoryEvent `yaml:"node_inventory"`
+}
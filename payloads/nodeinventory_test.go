@@ -0,0 +1,76 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestNodeInventoryUnmarshal(t *testing.T) {
+	var inv EventNodeInventory
+	err := yaml.Unmarshal([]byte(testutil.NodeInventoryYaml), &inv)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if inv.Inventory.NodeUUID != testutil.AgentUUID {
+		t.Errorf("Wrong node UUID field [%s]", inv.Inventory.NodeUUID)
+	}
+
+	if len(inv.Inventory.Instances) != 1 {
+		t.Fatalf("Wrong number of instances %d", len(inv.Inventory.Instances))
+	}
+
+	ins := inv.Inventory.Instances[0]
+	if ins.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", ins.InstanceUUID)
+	}
+
+	if ins.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", ins.TenantUUID)
+	}
+
+	if ins.State != Running {
+		t.Errorf("Wrong state field [%s]", ins.State)
+	}
+}
+
+func TestNodeInventoryMarshal(t *testing.T) {
+	var inv EventNodeInventory
+
+	inv.Inventory.NodeUUID = testutil.AgentUUID
+	inv.Inventory.Instances = []InstanceInventory{
+		{
+			InstanceUUID: testutil.InstanceUUID,
+			TenantUUID:   testutil.TenantUUID,
+			State:        Running,
+		},
+	}
+
+	y, err := yaml.Marshal(&inv)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.NodeInventoryYaml {
+		t.Errorf("NodeInventory marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.NodeInventoryYaml)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package payloads
+
+// OpenConsoleFailureReason denotes the underlying error that prevented
+// an SSNTP OpenConsole command from opening a console session.
+type OpenConsoleFailureReason string
+
+const (
+	// OpenConsoleNoInstance indicates that the console could not be
+	// opened as the instance does not exist on the node to which the
+	// OpenConsole command was sent.
+	OpenConsoleNoInstance OpenConsoleFailureReason = "no_instance"
+
+	// OpenConsoleInvalidPayload indicates that the payload of the SSNTP
+	// OpenConsole command was corrupt and could not be unmarshalled.
+	OpenConsoleInvalidPayload = "invalid_payload"
+
+	// OpenConsoleInvalidData is returned by ciao-launcher if the contents
+	// of the OpenConsole payload are incorrect, e.g., the token is
+	// missing.
+	OpenConsoleInvalidData = "invalid_data"
+
+	// OpenConsoleNotRunning indicates that the console could not be
+	// opened as the instance is not running.
+	OpenConsoleNotRunning = "not_running"
+
+	// OpenConsoleAttachFailure indicates that the launcher was unable
+	// to attach to the console of the instance or to listen for a
+	// connection to the console session.
+	OpenConsoleAttachFailure = "attach_failure"
+)
+
+// ErrorOpenConsoleFailure represents the unmarshalled version of the contents of a
+// SSNTP ERROR frame whose type is set to ssntp.OpenConsoleFailure.
+type ErrorOpenConsoleFailure struct {
+	// NodeUUID is the UUID of the node that generated this error.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance whose console could not
+	// be opened.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Token is the token passed in the OpenConsole command.
+	Token string `yaml:"token"`
+
+	// Reason provides the reason for the failure, e.g.,
+	// OpenConsoleNoInstance.
+	Reason OpenConsoleFailureReason `yaml:"reason"`
+}
+
+func (r OpenConsoleFailureReason) String() string {
+	switch r {
+	case OpenConsoleNoInstance:
+		return "Instance does not exist"
+	case OpenConsoleInvalidPayload:
+		return "YAML payload is corrupt"
+	case OpenConsoleInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case OpenConsoleNotRunning:
+		return "Instance is not running"
+	case OpenConsoleAttachFailure:
+		return "Failed to attach to console"
+	}
+
+	return ""
+}
@@ -0,0 +1,90 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestOpenConsoleFailureUnmarshal(t *testing.T) {
+	var error ErrorOpenConsoleFailure
+	err := yaml.Unmarshal([]byte(testutil.OpenConsoleFailureYaml), &error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if error.NodeUUID != testutil.AgentUUID {
+		t.Error("Wrong Node UUID field")
+	}
+
+	if error.InstanceUUID != testutil.InstanceUUID {
+		t.Error("Wrong Instance UUID field")
+	}
+
+	if error.Token != testutil.ConsoleToken {
+		t.Error("Wrong Token field")
+	}
+
+	if error.Reason != OpenConsoleAttachFailure {
+		t.Error("Wrong Error field")
+	}
+}
+
+func TestOpenConsoleFailureMarshal(t *testing.T) {
+	error := ErrorOpenConsoleFailure{
+		NodeUUID:     testutil.AgentUUID,
+		InstanceUUID: testutil.InstanceUUID,
+		Token:        testutil.ConsoleToken,
+		Reason:       OpenConsoleAttachFailure,
+	}
+
+	y, err := yaml.Marshal(&error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.OpenConsoleFailureYaml {
+		t.Errorf("OpenConsoleFailure marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.OpenConsoleFailureYaml)
+	}
+}
+
+func TestOpenConsoleFailureString(t *testing.T) {
+	var stringTests = []struct {
+		r        OpenConsoleFailureReason
+		expected string
+	}{
+		{OpenConsoleNoInstance, "Instance does not exist"},
+		{OpenConsoleInvalidPayload, "YAML payload is corrupt"},
+		{OpenConsoleInvalidData, "Command section of YAML payload is corrupt or missing required information"},
+		{OpenConsoleNotRunning, "Instance is not running"},
+		{OpenConsoleAttachFailure, "Failed to attach to console"},
+	}
+	error := ErrorOpenConsoleFailure{
+		InstanceUUID: testutil.InstanceUUID,
+	}
+	for _, test := range stringTests {
+		error.Reason = test.r
+		s := error.Reason.String()
+		if s != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, s)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// PauseCmd contains the information needed to pause, unpause, suspend or
+// resume an instance.
+type PauseCmd struct {
+	// InstanceUUID is the UUID of the instance to act upon.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// WorkloadAgentUUID identifies the node on which the instance is
+	// running.  This information is needed by the scheduler to route
+	// the command to the correct CN/NN.
+	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// MemMB is the memory, in MB, of the instance.  It is only set in
+	// ResumeInstance commands, so that the scheduler can check that the
+	// node has enough memory left to restore the instance.
+	MemMB int `yaml:"mem_mb,omitempty"`
+}
+
+// Pause represents the unmarshalled version of the contents of a SSNTP
+// PauseInstance payload.
+type Pause struct {
+	Pause PauseCmd `yaml:"pause"`
+}
+
+// Unpause represents the unmarshalled version of the contents of a SSNTP
+// UnpauseInstance payload.
+type Unpause struct {
+	Unpause PauseCmd `yaml:"unpause"`
+}
+
+// Suspend represents the unmarshalled version of the contents of a SSNTP
+// SuspendInstance payload.
+type Suspend struct {
+	Suspend PauseCmd `yaml:"suspend"`
+}
+
+// Resume represents the unmarshalled version of the contents of a SSNTP
+// ResumeInstance payload.
+type Resume struct {
+	Resume PauseCmd `yaml:"resume"`
+}
@@ -0,0 +1,69 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPauseUnmarshal(t *testing.T) {
+	var cmd Pause
+	err := yaml.Unmarshal([]byte(testutil.PauseYaml), &cmd)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if cmd.Pause.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", cmd.Pause.InstanceUUID)
+	}
+
+	if cmd.Pause.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong agent UUID field [%s]", cmd.Pause.WorkloadAgentUUID)
+	}
+}
+
+func TestPauseCommandsMarshal(t *testing.T) {
+	cmd := PauseCmd{
+		InstanceUUID:      testutil.InstanceUUID,
+		WorkloadAgentUUID: testutil.AgentUUID,
+	}
+
+	var marshalTests = []struct {
+		payload  interface{}
+		expected string
+	}{
+		{&Pause{Pause: cmd}, testutil.PauseYaml},
+		{&Unpause{Unpause: cmd}, testutil.UnpauseYaml},
+		{&Suspend{Suspend: cmd}, testutil.SuspendYaml},
+		{&Resume{Resume: cmd}, testutil.ResumeYaml},
+	}
+
+	for _, test := range marshalTests {
+		y, err := yaml.Marshal(test.payload)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if string(y) != test.expected {
+			t.Errorf("Pause command marshalling failed\n[%s]\n vs\n[%s]", string(y), test.expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// PauseFailureReason denotes the underlying error that prevented an SSNTP
+// PauseInstance, UnpauseInstance, SuspendInstance or ResumeInstance command
+// from succeeding.
+type PauseFailureReason string
+
+const (
+	// PauseNoInstance indicates that the command failed as the instance
+	// does not exist on the node to which the command was sent.
+	PauseNoInstance PauseFailureReason = "no_instance"
+
+	// PauseInvalidPayload indicates that the payload of the SSNTP
+	// command was corrupt and could not be unmarshalled.
+	PauseInvalidPayload = "invalid_payload"
+
+	// PauseInvalidData is returned by ciao-launcher if the contents
+	// of the payload are incorrect, e.g., the instance_uuid is missing.
+	PauseInvalidData = "invalid_data"
+
+	// PauseInvalidState indicates that the instance is not in a state
+	// that permits the command, e.g., an attempt was made to unpause
+	// a running instance.
+	PauseInvalidState = "invalid_state"
+
+	// PauseNotSupported indicates that the command is not available for
+	// the given workload type, e.g., a container cannot be suspended.
+	PauseNotSupported = "not_supported"
+
+	// PauseActionFailure indicates that the hypervisor or container
+	// runtime failed to execute the command.
+	PauseActionFailure = "action_failure"
+
+	// PauseFullComputeNode is returned by the scheduler if the node of a
+	// suspended instance does not have enough memory left to resume it.
+	PauseFullComputeNode = "full_cn"
+)
+
+// ErrorPauseFailure represents the unmarshalled version of the contents of
+// a SSNTP ERROR frame whose type is set to ssntp.PauseFailure,
+// ssntp.UnpauseFailure, ssntp.SuspendFailure or ssntp.ResumeFailure.
+type ErrorPauseFailure struct {
+	// NodeUUID is the UUID of the node that generated this error.
+	NodeUUID string `yaml:"node_uuid"`
+
+	// InstanceUUID is the UUID of the instance the command failed for.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// Reason provides the reason for the failure, e.g.,
+	// PauseInvalidState.
+	Reason PauseFailureReason `yaml:"reason"`
+}
+
+func (r PauseFailureReason) String() string {
+	switch r {
+	case PauseNoInstance:
+		return "Instance does not exist"
+	case PauseInvalidPayload:
+		return "YAML payload is corrupt"
+	case PauseInvalidData:
+		return "Command section of YAML payload is corrupt or missing required information"
+	case PauseInvalidState:
+		return "Instance is not in a valid state for this action"
+	case PauseNotSupported:
+		return "Not Supported"
+	case PauseActionFailure:
+		return "Failed to execute action"
+	case PauseFullComputeNode:
+		return "Compute node has not enough memory to resume the instance"
+	}
+
+	return ""
+}
@@ -0,0 +1,87 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestPauseFailureUnmarshal(t *testing.T) {
+	var error ErrorPauseFailure
+	err := yaml.Unmarshal([]byte(testutil.PauseFailureYaml), &error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if error.NodeUUID != testutil.AgentUUID {
+		t.Error("Wrong Node UUID field")
+	}
+
+	if error.InstanceUUID != testutil.InstanceUUID {
+		t.Error("Wrong Instance UUID field")
+	}
+
+	if error.Reason != PauseInvalidState {
+		t.Error("Wrong Error field")
+	}
+}
+
+func TestPauseFailureMarshal(t *testing.T) {
+	error := ErrorPauseFailure{
+		NodeUUID:     testutil.AgentUUID,
+		InstanceUUID: testutil.InstanceUUID,
+		Reason:       PauseInvalidState,
+	}
+
+	y, err := yaml.Marshal(&error)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.PauseFailureYaml {
+		t.Errorf("PauseFailure marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.PauseFailureYaml)
+	}
+}
+
+func TestPauseFailureString(t *testing.T) {
+	var stringTests = []struct {
+		r        PauseFailureReason
+		expected string
+	}{
+		{PauseNoInstance, "Instance does not exist"},
+		{PauseInvalidPayload, "YAML payload is corrupt"},
+		{PauseInvalidData, "Command section of YAML payload is corrupt or missing required information"},
+		{PauseInvalidState, "Instance is not in a valid state for this action"},
+		{PauseNotSupported, "Not Supported"},
+		{PauseActionFailure, "Failed to execute action"},
+	}
+	error := ErrorPauseFailure{
+		InstanceUUID: testutil.InstanceUUID,
+	}
+	for _, test := range stringTests {
+		error.Reason = test.r
+		s := error.Reason.String()
+		if s != test.expected {
+			t.Errorf("expected \"%s\", got \"%s\"", test.expected, s)
+		}
+	}
+}
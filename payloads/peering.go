@@ -0,0 +1,42 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// PeerSubnet is the subnet of a network, of another tenant, peered with a
+// tenant subnet.  Traffic to the peer subnet is routed through the CNCI
+// serving the peer subnet.
+type PeerSubnet struct {
+	PeeringUUID    string `yaml:"peering_uuid"`
+	Subnet         string `yaml:"subnet"`
+	ConcentratorIP string `yaml:"concentrator_ip"`
+}
+
+// PeeringCommand contains the peer subnets routed to a tenant subnet by
+// the CNCI serving it.  Peers always holds the complete set of active
+// peerings of the subnet, replacing any previously sent set.
+type PeeringCommand struct {
+	ConcentratorUUID string       `yaml:"concentrator_uuid"`
+	TenantUUID       string       `yaml:"tenant_uuid"`
+	TenantSubnet     string       `yaml:"tenant_subnet"`
+	Peers            []PeerSubnet `yaml:"peers,omitempty"`
+}
+
+// CommandUpdatePeering is a wrapper around PeeringCommand. It is the
+// UpdatePeering command payload.
+type CommandUpdatePeering struct {
+	UpdatePeering PeeringCommand `yaml:"update_peering"`
+}
@@ -0,0 +1,81 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestUpdatePeeringUnmarshal(t *testing.T) {
+	var cmd CommandUpdatePeering
+
+	err := yaml.Unmarshal([]byte(testutil.UpdatePeeringYaml), &cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := cmd.UpdatePeering
+	if p.ConcentratorUUID != testutil.CNCIUUID {
+		t.Errorf("Wrong concentrator UUID field [%s]", p.ConcentratorUUID)
+	}
+
+	if p.TenantUUID != testutil.TenantUUID {
+		t.Errorf("Wrong tenant UUID field [%s]", p.TenantUUID)
+	}
+
+	if p.TenantSubnet != testutil.TenantSubnet {
+		t.Errorf("Wrong tenant subnet field [%s]", p.TenantSubnet)
+	}
+
+	if len(p.Peers) != 1 {
+		t.Fatalf("Wrong number of peers %d", len(p.Peers))
+	}
+
+	peer := p.Peers[0]
+	if peer.PeeringUUID != testutil.PeeringUUID || peer.Subnet != testutil.PeerSubnet ||
+		peer.ConcentratorIP != testutil.PeerCNCIIP {
+		t.Errorf("Wrong peer fields %v", peer)
+	}
+}
+
+func TestUpdatePeeringMarshal(t *testing.T) {
+	var cmd CommandUpdatePeering
+
+	cmd.UpdatePeering.ConcentratorUUID = testutil.CNCIUUID
+	cmd.UpdatePeering.TenantUUID = testutil.TenantUUID
+	cmd.UpdatePeering.TenantSubnet = testutil.TenantSubnet
+	cmd.UpdatePeering.Peers = []PeerSubnet{
+		{
+			PeeringUUID:    testutil.PeeringUUID,
+			Subnet:         testutil.PeerSubnet,
+			ConcentratorIP: testutil.PeerCNCIIP,
+		},
+	}
+
+	y, err := yaml.Marshal(&cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(y) != testutil.UpdatePeeringYaml {
+		t.Errorf("UpdatePeering marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.UpdatePeeringYaml)
+	}
+}
@@ -31,12 +31,21 @@ type Ready struct {
 	// proc/meminfo:MemFree + Active(file) + Inactive(file)
 	MemAvailableMB int `yaml:"mem_available_mb"`
 
-	// Size of the CN/NN RootFS in MB
+	// Size of the ephemeral storage pool of the CN/NN in MB
 	DiskTotalMB int `yaml:"disk_total_mb"`
 
-	// MBs available in the RootFS of the CN/NN
+	// MBs of the ephemeral storage pool of the CN/NN that are free and
+	// not reserved by instances
 	DiskAvailableMB int `yaml:"disk_available_mb"`
 
+	// MBs of the ephemeral storage pool of the CN/NN reserved by the
+	// local disks of the instances it hosts
+	DiskReservedMB int `yaml:"disk_reserved_mb"`
+
+	// MBs of the ephemeral storage pool of the CN/NN actually written
+	// by the local disks of the instances it hosts
+	DiskUsedMB int `yaml:"disk_used_mb"`
+
 	// Load of CN/NN, taken from /proc/loadavg (Average over last minute
 	// reported).
 	Load int `yaml:"load"`
@@ -45,6 +54,19 @@ type Ready struct {
 	// cpu[0-9]+ entries in /proc/stat.
 	CpusOnline int `yaml:"cpus_online"`
 
+	// Number of CPUs of the CN/NN that can be dedicated to instances and
+	// are not yet dedicated to one
+	DedicatedCpusAvailable int `yaml:"dedicated_cpus_available"`
+
+	// MBs of huge pages reserved on the CN/NN
+	HugePagesTotalMB int `yaml:"hugepages_total_mb"`
+
+	// MBs of the huge pages of the CN/NN not yet assigned to instances
+	HugePagesAvailableMB int `yaml:"hugepages_available_mb"`
+
+	// Array containing one entry for each NUMA node of the CN/NN
+	NUMANodes []NUMANodeStat `yaml:"numa_nodes,omitempty"`
+
 	// Array containing one entry for each network interface present on the
 	// CN/NN
 	Networks []NetworkStat
@@ -60,6 +82,11 @@ func (s *Ready) Init() {
 	s.MemAvailableMB = -1
 	s.DiskTotalMB = -1
 	s.DiskAvailableMB = -1
+	s.DiskReservedMB = -1
+	s.DiskUsedMB = -1
 	s.Load = -1
 	s.CpusOnline = -1
+	s.DedicatedCpusAvailable = -1
+	s.HugePagesTotalMB = -1
+	s.HugePagesAvailableMB = -1
 }
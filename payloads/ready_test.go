@@ -34,13 +34,19 @@ func TestReadyUnmarshal(t *testing.T) {
 
 func TestReadyMarshal(t *testing.T) {
 	cmd := Ready{
-		NodeUUID:        testutil.AgentUUID,
-		MemTotalMB:      3896,
-		MemAvailableMB:  3896,
-		DiskTotalMB:     500000,
-		DiskAvailableMB: 256000,
-		Load:            0,
-		CpusOnline:      4,
+		NodeUUID:               testutil.AgentUUID,
+		MemTotalMB:             3896,
+		MemAvailableMB:         3896,
+		DiskTotalMB:            500000,
+		DiskAvailableMB:        256000,
+		DiskReservedMB:         20000,
+		DiskUsedMB:             4000,
+		Load:                   0,
+		CpusOnline:             4,
+		DedicatedCpusAvailable: 2,
+		HugePagesTotalMB:       2048,
+		HugePagesAvailableMB:   1024,
+		NUMANodes:              testutil.NUMANodeStats,
 		Networks: []NetworkStat{
 			{NodeIP: "192.168.1.1", NodeMAC: "02:00:15:03:6f:49"},
 			{NodeIP: "10.168.1.1", NodeMAC: "02:00:8c:ba:f9:45"},
@@ -69,21 +75,31 @@ func TestReadyNodeNotAllStats(t *testing.T) {
 	}
 
 	expectedCmd := Ready{
-		NodeUUID:        testutil.AgentUUID,
-		MemTotalMB:      -1,
-		MemAvailableMB:  -1,
-		DiskTotalMB:     -1,
-		DiskAvailableMB: -1,
-		Load:            1,
-		CpusOnline:      -1,
+		NodeUUID:               testutil.AgentUUID,
+		MemTotalMB:             -1,
+		MemAvailableMB:         -1,
+		DiskTotalMB:            -1,
+		DiskAvailableMB:        -1,
+		DiskReservedMB:         -1,
+		DiskUsedMB:             -1,
+		Load:                   1,
+		CpusOnline:             -1,
+		DedicatedCpusAvailable: -1,
+		HugePagesTotalMB:       -1,
+		HugePagesAvailableMB:   -1,
 	}
 	if cmd.NodeUUID != expectedCmd.NodeUUID ||
 		cmd.MemTotalMB != expectedCmd.MemTotalMB ||
 		cmd.MemAvailableMB != expectedCmd.MemAvailableMB ||
 		cmd.DiskTotalMB != expectedCmd.DiskTotalMB ||
 		cmd.DiskAvailableMB != expectedCmd.DiskAvailableMB ||
+		cmd.DiskReservedMB != expectedCmd.DiskReservedMB ||
+		cmd.DiskUsedMB != expectedCmd.DiskUsedMB ||
 		cmd.Load != expectedCmd.Load ||
 		cmd.CpusOnline != expectedCmd.CpusOnline ||
+		cmd.DedicatedCpusAvailable != expectedCmd.DedicatedCpusAvailable ||
+		cmd.HugePagesTotalMB != expectedCmd.HugePagesTotalMB ||
+		cmd.HugePagesAvailableMB != expectedCmd.HugePagesAvailableMB ||
 		len(cmd.Networks) != 0 {
 		t.Error("Unexpected values in Ready")
 	}
@@ -72,6 +72,10 @@ const (
 	// command in which it is embedded applies to a compute node.
 	ComputeNode = "compute_node"
 
+	// CNCINode indicates that a NodeConnected or NodeDisconnected event
+	// refers to the agent running inside a CNCI instance.
+	CNCINode = "cnci_node"
+
 	// PhysicalNetwork indicates a resource is specifying an network on
 	// a network node (ie: only relevant when resource NetworkNode has
 	// value true.
@@ -93,6 +97,47 @@ const (
 	// SharedDiskGiB is used for shared storage across the cluster used for
 	// storing volume and images. (Measured in GiB)
 	SharedDiskGiB = "shared_disk_gib"
+
+	// DedicatedCPUs indicates that a resource struct specifies whether
+	// each of the VCPUs of an instance is to be pinned to a host CPU
+	// dedicated to that VCPU.
+	DedicatedCPUs = "dedicated_cpus"
+
+	// HugePages indicates that a resource struct specifies whether the
+	// memory of an instance is to be backed by huge pages.
+	HugePages = "hugepages"
+
+	// NUMALocal indicates that a resource struct specifies whether the
+	// VCPUs and the memory of an instance are to be placed on a single
+	// host NUMA node.
+	NUMALocal = "numa_local"
+
+	// GuestAgent indicates that a resource struct specifies whether an
+	// instance is to be given a channel to a guest agent running inside
+	// it.
+	GuestAgent = "guest_agent"
+
+	// Console indicates that a resource struct specifies whether a
+	// container is to be given a terminal and an open stdin, so that its
+	// console can be opened.  The console of a VM is always available.
+	Console = "console"
+
+	// DiskIOPS indicates that a resource struct specifies the maximum
+	// number of I/O operations per second an instance may issue to each
+	// of its disks.
+	DiskIOPS = "disk_iops"
+
+	// DiskMBps indicates that a resource struct specifies the maximum
+	// throughput, in MB/s, an instance may achieve on each of its disks.
+	DiskMBps = "disk_mbps"
+
+	// NetRxMbps indicates that a resource struct specifies the maximum
+	// rate, in Mbit/s, at which an instance may receive network traffic.
+	NetRxMbps = "net_rx_mbps"
+
+	// NetTxMbps indicates that a resource struct specifies the maximum
+	// rate, in Mbit/s, at which an instance may transmit network traffic.
+	NetTxMbps = "net_tx_mbps"
 )
 
 const (
@@ -133,6 +178,14 @@ type StorageResource struct {
 
 	// Size is the requested size for an auto-created storage resource
 	Size int `yaml:"size,omitempty"`
+
+	// ReadOnly indicates that the storage resource should be attached
+	// read only.
+	ReadOnly bool `yaml:"read_only,omitempty"`
+
+	// Shared indicates that the storage resource may be attached to more
+	// than one instance at the same time.
+	Shared bool `yaml:"shared,omitempty"`
 }
 
 // RequestedResource is used to specify an individual resource contained within
@@ -182,6 +235,30 @@ type NetworkResources struct {
 	// specified when creating CN instances.
 	Subnet string `yaml:"subnet"`
 
+	// Gateway is the default gateway of the subnet.  It is only specified
+	// for subnets belonging to tenant defined networks.  If empty, the
+	// first host address of the subnet is used.
+	Gateway string `yaml:"gateway,omitempty"`
+
+	// DHCPStart and DHCPEnd delimit the range of addresses of the
+	// subnet that can be assigned to instances.  They are only specified
+	// for subnets belonging to tenant defined networks.  If empty, all
+	// the host addresses of the subnet, other than the gateway, can be
+	// assigned.
+	DHCPStart string `yaml:"dhcp_start,omitempty"`
+	DHCPEnd   string `yaml:"dhcp_end,omitempty"`
+
+	// IPv6Subnet is the /64 IPv6 prefix of the subnet.  It is only
+	// specified for dual stack tenant defined networks.
+	IPv6Subnet string `yaml:"ipv6_subnet,omitempty"`
+
+	// IPv6Gateway is the IPv6 default gateway of a dual stack subnet.
+	IPv6Gateway string `yaml:"ipv6_gateway,omitempty"`
+
+	// IPv6Mode is the IPv6 address assignment mode of a dual stack
+	// subnet, either "slaac" or "dhcpv6".
+	IPv6Mode string `yaml:"ipv6_mode,omitempty"`
+
 	// SubnetKey is the subnet identifier to which the instance
 	// is assigned.
 	SubnetKey string `yaml:"subnet_key"`
@@ -235,6 +312,12 @@ type StartCmd struct {
 	// for the new instance.
 	Networking NetworkResources `yaml:"networking"`
 
+	// AdditionalNetworking contains the networking information of each
+	// of the NICs of the new instance other than the first, which is
+	// described by Networking.  Additional NICs are only supported for
+	// qemu instances running on CNs.
+	AdditionalNetworking []NetworkResources `yaml:"additional_networking,omitempty"`
+
 	// Storage contains all the information required to attach or boot
 	// from storage for the new instance.
 	Storage []StorageResource `yaml:"storage,omitempty"`
@@ -242,6 +325,11 @@ type StartCmd struct {
 	// Restart is set to true if the payload represents a request to
 	// restart an existing instance on a new node.
 	Restart bool
+
+	// ExcludedNodes contains the UUIDs of the nodes on which the instance
+	// must not be started, e.g., the node hosting the active CNCI of a
+	// subnet when starting its standby.
+	ExcludedNodes []string `yaml:"excluded_nodes,omitempty"`
 }
 
 // Start represents the unmarshalled version of the contents of a SSNTP START
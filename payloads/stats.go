@@ -52,6 +52,57 @@ type InstanceStat struct {
 
 	// List of volumes attached to the instance.
 	Volumes []string `yaml:"volumes"`
+
+	// Information reported by the guest agent running inside the
+	// instance.  Will be nil if the instance has no guest agent channel
+	// or if the agent has not yet responded.
+	Guest *GuestInfo `yaml:"guest,omitempty"`
+
+	// I/O counters of the disks of the instance, summed over all its
+	// disks.  Will be nil if the counters cannot be retrieved.
+	BlockIO *BlockIOStat `yaml:"block_io,omitempty"`
+
+	// Traffic counters of the vnic of the instance.  Will be nil if the
+	// instance has no vnic or if the counters cannot be retrieved.
+	NetworkIO *VnicIOStat `yaml:"network_io,omitempty"`
+}
+
+// BlockIOStat contains the number of bytes and I/O operations read and
+// written by an instance since it was started.
+type BlockIOStat struct {
+	ReadBytes  int64 `yaml:"read_bytes"`
+	WriteBytes int64 `yaml:"write_bytes"`
+	ReadOps    int64 `yaml:"read_ops"`
+	WriteOps   int64 `yaml:"write_ops"`
+}
+
+// VnicIOStat contains the traffic counters of the vnic of an instance, as
+// seen from inside the instance, i.e., Rx counts the traffic received by
+// the instance.
+type VnicIOStat struct {
+	RxBytes   int64 `yaml:"rx_bytes"`
+	RxPackets int64 `yaml:"rx_packets"`
+	RxDropped int64 `yaml:"rx_dropped"`
+	TxBytes   int64 `yaml:"tx_bytes"`
+	TxPackets int64 `yaml:"tx_packets"`
+	TxDropped int64 `yaml:"tx_dropped"`
+}
+
+// GuestInfo contains information about an instance reported by the guest
+// agent running inside it.
+type GuestInfo struct {
+	// Name of the guest operating system, e.g., Ubuntu 16.04.2 LTS
+	OSName string `yaml:"os_name"`
+
+	// Version of the guest operating system
+	OSVersion string `yaml:"os_version"`
+
+	// Release of the guest kernel
+	KernelRelease string `yaml:"kernel_release"`
+
+	// IP addresses of the network interfaces of the guest, excluding
+	// loopback addresses
+	IPAddresses []string `yaml:"ip_addresses"`
 }
 
 // NetworkStat contains information about a single network interface present on
@@ -61,6 +112,20 @@ type NetworkStat struct {
 	NodeMAC string `yaml:"mac"`
 }
 
+// NUMANodeStat contains information about the resources of a NUMA node of a
+// ciao compute or network node that can be dedicated to instances.
+type NUMANodeStat struct {
+	// The ID of the NUMA node
+	NodeID int `yaml:"node_id"`
+
+	// Number of CPUs of the NUMA node that can be dedicated to instances
+	// and are not yet dedicated to one
+	DedicatedCpusAvailable int `yaml:"dedicated_cpus_available"`
+
+	// MBs of the huge pages of the NUMA node not yet assigned to instances
+	HugePagesAvailableMB int `yaml:"hugepages_available_mb"`
+}
+
 // Stat represents a snapshot of the state of a compute or a network node.  This
 // information is sent periodically by ciao-launcher to the scheduler.
 type Stat struct {
@@ -78,12 +143,21 @@ type Stat struct {
 	// proc/meminfo:MemFree + Active(file) + Inactive(file)
 	MemAvailableMB int `yaml:"mem_available_mb"`
 
-	// Size of the CN/NN RootFS in MB
+	// Size of the ephemeral storage pool of the CN/NN in MB
 	DiskTotalMB int `yaml:"disk_total_mb"`
 
-	// MBs available in the RootFS of the CN/NN
+	// MBs of the ephemeral storage pool of the CN/NN that are free and
+	// not reserved by instances
 	DiskAvailableMB int `yaml:"disk_available_mb"`
 
+	// MBs of the ephemeral storage pool of the CN/NN reserved by the
+	// local disks of the instances it hosts
+	DiskReservedMB int `yaml:"disk_reserved_mb"`
+
+	// MBs of the ephemeral storage pool of the CN/NN actually written
+	// by the local disks of the instances it hosts
+	DiskUsedMB int `yaml:"disk_used_mb"`
+
 	// Load of CN/NN, taken from /proc/loadavg (Average over last minute
 	// reported
 	Load int `yaml:"load"`
@@ -92,6 +166,19 @@ type Stat struct {
 	// cpu[0-9]+ entries in /proc/stat
 	CpusOnline int `yaml:"cpus_online"`
 
+	// Number of CPUs of the CN/NN that can be dedicated to instances and
+	// are not yet dedicated to one
+	DedicatedCpusAvailable int `yaml:"dedicated_cpus_available"`
+
+	// MBs of huge pages reserved on the CN/NN
+	HugePagesTotalMB int `yaml:"hugepages_total_mb"`
+
+	// MBs of the huge pages of the CN/NN not yet assigned to instances
+	HugePagesAvailableMB int `yaml:"hugepages_available_mb"`
+
+	// Array containing one entry for each NUMA node of the CN/NN
+	NUMANodes []NUMANodeStat `yaml:"numa_nodes,omitempty"`
+
 	// Hostname of the CN/NN
 	NodeHostName string `yaml:"hostname"`
 
@@ -148,6 +235,16 @@ const (
 
 	// Hung indicates that an instance is not responding to commands.
 	Hung = "hung"
+
+	// Paused indicates that the execution of an instance has been frozen
+	// by a PauseInstance command.  The instance retains all the resources
+	// it was allocated on its node.
+	Paused = "paused"
+
+	// Suspended indicates that the state of a VM has been saved to its
+	// node by a SuspendInstance command and that the VM is no longer
+	// running.
+	Suspended = "suspended"
 )
 
 // Init initialises instances of the Stat structure.
@@ -157,6 +254,11 @@ func (s *Stat) Init() {
 	s.MemAvailableMB = -1
 	s.DiskTotalMB = -1
 	s.DiskAvailableMB = -1
+	s.DiskReservedMB = -1
+	s.DiskUsedMB = -1
 	s.Load = -1
 	s.CpusOnline = -1
+	s.DedicatedCpusAvailable = -1
+	s.HugePagesTotalMB = -1
+	s.HugePagesAvailableMB = -1
 }
@@ -69,6 +69,8 @@ func TestStatsNodeOnly(t *testing.T) {
 		MemAvailableMB:  3896,
 		DiskTotalMB:     500000,
 		DiskAvailableMB: 256000,
+		DiskReservedMB:  20000,
+		DiskUsedMB:      4000,
 		Load:            0,
 		CpusOnline:      4,
 		NodeHostName:    "test",
@@ -84,6 +86,8 @@ func TestStatsNodeOnly(t *testing.T) {
 		cmd.MemAvailableMB != expectedCmd.MemAvailableMB ||
 		cmd.DiskTotalMB != expectedCmd.DiskTotalMB ||
 		cmd.DiskAvailableMB != expectedCmd.DiskAvailableMB ||
+		cmd.DiskReservedMB != expectedCmd.DiskReservedMB ||
+		cmd.DiskUsedMB != expectedCmd.DiskUsedMB ||
 		cmd.Load != expectedCmd.Load ||
 		cmd.CpusOnline != expectedCmd.CpusOnline ||
 		cmd.NodeHostName != expectedCmd.NodeHostName ||
@@ -106,21 +110,31 @@ func TestStatsNodeNotAllStats(t *testing.T) {
 	}
 
 	expectedCmd := Stat{
-		NodeUUID:        testutil.AgentUUID,
-		MemTotalMB:      -1,
-		MemAvailableMB:  -1,
-		DiskTotalMB:     -1,
-		DiskAvailableMB: -1,
-		Load:            1,
-		CpusOnline:      -1,
+		NodeUUID:               testutil.AgentUUID,
+		MemTotalMB:             -1,
+		MemAvailableMB:         -1,
+		DiskTotalMB:            -1,
+		DiskAvailableMB:        -1,
+		DiskReservedMB:         -1,
+		DiskUsedMB:             -1,
+		Load:                   1,
+		CpusOnline:             -1,
+		DedicatedCpusAvailable: -1,
+		HugePagesTotalMB:       -1,
+		HugePagesAvailableMB:   -1,
 	}
 	if cmd.NodeUUID != expectedCmd.NodeUUID ||
 		cmd.MemTotalMB != expectedCmd.MemTotalMB ||
 		cmd.MemAvailableMB != expectedCmd.MemAvailableMB ||
 		cmd.DiskTotalMB != expectedCmd.DiskTotalMB ||
 		cmd.DiskAvailableMB != expectedCmd.DiskAvailableMB ||
+		cmd.DiskReservedMB != expectedCmd.DiskReservedMB ||
+		cmd.DiskUsedMB != expectedCmd.DiskUsedMB ||
 		cmd.Load != expectedCmd.Load ||
 		cmd.CpusOnline != expectedCmd.CpusOnline ||
+		cmd.DedicatedCpusAvailable != expectedCmd.DedicatedCpusAvailable ||
+		cmd.HugePagesTotalMB != expectedCmd.HugePagesTotalMB ||
+		cmd.HugePagesAvailableMB != expectedCmd.HugePagesAvailableMB ||
 		cmd.NodeHostName != expectedCmd.NodeHostName ||
 		cmd.Networks != nil ||
 		cmd.Instances != nil {
@@ -30,6 +30,11 @@ type StopCmd struct {
 	// In this case the delete command should only delete the instance from
 	// the node to which it is sent and not the entire cluster.
 	Stop bool
+
+	// GracefulTimeout is the number of seconds the node should wait for
+	// the instance to shut down cleanly before terminating it.  If 0,
+	// the node's default timeout is used.
+	GracefulTimeout int `yaml:"graceful_timeout,omitempty"`
 }
 
 // Stop represents the unmarshalled version of the contents of a SSNTP STOP
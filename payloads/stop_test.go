@@ -85,3 +85,19 @@ func TestDeleteMarshal(t *testing.T) {
 		t.Errorf("DELETE marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.DeleteYaml)
 	}
 }
+
+func TestGracefulDeleteMarshal(t *testing.T) {
+	var delete Delete
+	delete.Delete.InstanceUUID = testutil.InstanceUUID
+	delete.Delete.WorkloadAgentUUID = testutil.AgentUUID
+	delete.Delete.GracefulTimeout = 60
+
+	y, err := yaml.Marshal(&delete)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.GracefulDeleteYaml {
+		t.Errorf("DELETE marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.GracefulDeleteYaml)
+	}
+}
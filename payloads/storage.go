@@ -20,16 +20,24 @@ package payloads
 // to or detach a volume from an existing instance.
 type VolumeCmd struct {
 	// InstanceUUID is the UUID of the instance to which the volume is to be
-	// attached.
+	// attached or from which it is to be detached.
 	InstanceUUID string `yaml:"instance_uuid"`
 
-	// VolumeUUID is the UUID of the volume to attach.
+	// VolumeUUID is the UUID of the volume to attach or detach.
 	VolumeUUID string `yaml:"volume_uuid"`
 
 	// WorkloadAgentUUID identifies the node on which the instance is
 	// running.  This information is needed by the scheduler to route
 	// the command to the correct CN/NN.
 	WorkloadAgentUUID string `yaml:"workload_agent_uuid"`
+
+	// ReadOnly indicates that the volume should be attached read only.
+	// It is ignored when detaching a volume.
+	ReadOnly bool `yaml:"read_only,omitempty"`
+
+	// Shared indicates that the volume may be attached to more than one
+	// instance at the same time.  It is ignored when detaching a volume.
+	Shared bool `yaml:"shared,omitempty"`
 }
 
 // AttachVolume represents the unmarshalled version of the contents of a SSNTP
@@ -38,3 +46,10 @@ type VolumeCmd struct {
 type AttachVolume struct {
 	Attach VolumeCmd `yaml:"attach_volume"`
 }
+
+// DetachVolume represents the unmarshalled version of the contents of a SSNTP
+// DetachVolume payload.  The structure contains enough information to detach a
+// volume from an existing instance.
+type DetachVolume struct {
+	Detach VolumeCmd `yaml:"detach_volume"`
+}
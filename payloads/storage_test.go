@@ -60,3 +60,75 @@ func TestAttachVolmeMarshal(t *testing.T) {
 			string(y), testutil.AttachVolumeYaml)
 	}
 }
+
+func TestAttachSharedReadOnlyVolumeUnmarshal(t *testing.T) {
+	var attach AttachVolume
+	err := yaml.Unmarshal([]byte(testutil.AttachSharedReadOnlyVolumeYaml), &attach)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if !attach.Attach.ReadOnly {
+		t.Errorf("Expected ReadOnly to be true")
+	}
+
+	if !attach.Attach.Shared {
+		t.Errorf("Expected Shared to be true")
+	}
+}
+
+func TestAttachSharedReadOnlyVolumeMarshal(t *testing.T) {
+	var attach AttachVolume
+	attach.Attach.InstanceUUID = testutil.InstanceUUID
+	attach.Attach.VolumeUUID = testutil.VolumeUUID
+	attach.Attach.WorkloadAgentUUID = testutil.AgentUUID
+	attach.Attach.ReadOnly = true
+	attach.Attach.Shared = true
+
+	y, err := yaml.Marshal(&attach)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.AttachSharedReadOnlyVolumeYaml {
+		t.Errorf("AttachVolume marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.AttachSharedReadOnlyVolumeYaml)
+	}
+}
+
+func TestDetachVolumeUnmarshal(t *testing.T) {
+	var detach DetachVolume
+	err := yaml.Unmarshal([]byte(testutil.DetachVolumeYaml), &detach)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if detach.Detach.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", detach.Detach.InstanceUUID)
+	}
+
+	if detach.Detach.VolumeUUID != testutil.VolumeUUID {
+		t.Errorf("Wrong Volume UUID field [%s]", detach.Detach.VolumeUUID)
+	}
+
+	if detach.Detach.WorkloadAgentUUID != testutil.AgentUUID {
+		t.Errorf("Wrong WorkloadAgentUUID field [%s]", detach.Detach.WorkloadAgentUUID)
+	}
+}
+
+func TestDetachVolumeMarshal(t *testing.T) {
+	var detach DetachVolume
+	detach.Detach.InstanceUUID = testutil.InstanceUUID
+	detach.Detach.VolumeUUID = testutil.VolumeUUID
+	detach.Detach.WorkloadAgentUUID = testutil.AgentUUID
+
+	y, err := yaml.Marshal(&detach)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.DetachVolumeYaml {
+		t.Errorf("DetachVolume marshalling failed\n[%s]\n vs\n[%s]",
+			string(y), testutil.DetachVolumeYaml)
+	}
+}
@@ -33,6 +33,22 @@ type TenantAddedEvent struct {
 	// The subnet of the Tenant.
 	TenantSubnet string `yaml:"tenant_subnet"`
 
+	// The default gateway of the subnet of the tenant.  Only set for
+	// tenant defined networks.
+	TenantGateway string `yaml:"tenant_gateway,omitempty"`
+
+	// The range of addresses of the subnet served by the DHCP server of
+	// the concentrator.  Only set for tenant defined networks.
+	DHCPStart string `yaml:"dhcp_start,omitempty"`
+	DHCPEnd   string `yaml:"dhcp_end,omitempty"`
+
+	// The IPv6 prefix, gateway and address assignment mode of the
+	// subnet of the tenant.  Only set for dual stack tenant defined
+	// networks.
+	IPv6Subnet  string `yaml:"ipv6_subnet,omitempty"`
+	IPv6Gateway string `yaml:"ipv6_gateway,omitempty"`
+	IPv6Mode    string `yaml:"ipv6_mode,omitempty"`
+
 	// The UUID of the concentrator.
 	ConcentratorUUID string `yaml:"concentrator_uuid"`
 
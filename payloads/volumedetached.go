@@ -0,0 +1,35 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads
+
+// VolumeDetachedEvent contains the UUIDs of a volume and of the instance
+// from which it has just been detached.
+type VolumeDetachedEvent struct {
+	// InstanceUUID is the UUID of the instance from which the volume
+	// was detached.
+	InstanceUUID string `yaml:"instance_uuid"`
+
+	// VolumeUUID is the UUID of the volume that was detached.
+	VolumeUUID string `yaml:"volume_uuid"`
+}
+
+// EventVolumeDetached represents the unmarshalled version of the contents of
+// an SSNTP ssntp.VolumeDetached event. This event is sent by ciao-launcher
+// when it successfully detaches a volume from an instance.
+type EventVolumeDetached struct {
+	VolumeDetached VolumeDetachedEvent `yaml:"volume_detached"`
+}
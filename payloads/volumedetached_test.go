@@ -0,0 +1,57 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package payloads_test
+
+import (
+	"testing"
+
+	. "github.com/ciao-project/ciao/payloads"
+	"github.com/ciao-project/ciao/testutil"
+	"gopkg.in/yaml.v2"
+)
+
+func TestVolumeDetachedUnmarshal(t *testing.T) {
+	var volDet EventVolumeDetached
+	err := yaml.Unmarshal([]byte(testutil.VolumeDetachedYaml), &volDet)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if volDet.VolumeDetached.InstanceUUID != testutil.InstanceUUID {
+		t.Errorf("Wrong instance UUID field [%s]", volDet.VolumeDetached.InstanceUUID)
+	}
+
+	if volDet.VolumeDetached.VolumeUUID != testutil.VolumeUUID {
+		t.Errorf("Wrong volume UUID field [%s]", volDet.VolumeDetached.VolumeUUID)
+	}
+}
+
+func TestVolumeDetachedMarshal(t *testing.T) {
+	var volDet EventVolumeDetached
+
+	volDet.VolumeDetached.InstanceUUID = testutil.InstanceUUID
+	volDet.VolumeDetached.VolumeUUID = testutil.VolumeUUID
+
+	y, err := yaml.Marshal(&volDet)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(y) != testutil.VolumeDetachedYaml {
+		t.Errorf("VolumeDetached marshalling failed\n[%s]\n vs\n[%s]", string(y), testutil.VolumeDetachedYaml)
+	}
+}
@@ -72,11 +72,17 @@ const (
 	// VirtioBlock is the block device driver.
 	VirtioBlock = "virtio-blk"
 
+	// VirtioBlockPCI is the virt-io pci block device driver.
+	VirtioBlockPCI = "virtio-blk-pci"
+
 	// Console is the console device driver.
 	Console = "virtconsole"
 
 	// VirtioSerialPort is the serial port device driver.
 	VirtioSerialPort = "virtserialport"
+
+	// ISASerial is the legacy 16550A serial port device driver.
+	ISASerial = "isa-serial"
 )
 
 // ObjectType is a string representing a qemu object type.
@@ -285,6 +291,15 @@ type CharDevice struct {
 
 	// DisableModern prevents qemu from relying on fast MMIO.
 	DisableModern bool
+
+	// Client makes qemu connect to a Socket backend, rather than
+	// listening on it.
+	Client bool
+
+	// Reconnect is the number of seconds after which qemu tries to
+	// reconnect a Client socket whose connection has been lost.  0
+	// disables reconnection.
+	Reconnect uint
 }
 
 // Valid returns true if the CharDevice structure is valid and complete.
@@ -317,7 +332,12 @@ func (cdev CharDevice) QemuParams(config *Config) []string {
 
 	cdevParams = append(cdevParams, string(cdev.Backend))
 	cdevParams = append(cdevParams, fmt.Sprintf(",id=%s", cdev.ID))
-	if cdev.Backend == Socket {
+	if cdev.Backend == Socket && cdev.Client {
+		cdevParams = append(cdevParams, fmt.Sprintf(",path=%s", cdev.Path))
+		if cdev.Reconnect > 0 {
+			cdevParams = append(cdevParams, fmt.Sprintf(",reconnect=%d", cdev.Reconnect))
+		}
+	} else if cdev.Backend == Socket {
 		cdevParams = append(cdevParams, fmt.Sprintf(",path=%s,server,nowait", cdev.Path))
 	} else {
 		cdevParams = append(cdevParams, fmt.Sprintf(",path=%s", cdev.Path))
@@ -353,6 +373,10 @@ const (
 
 	// VHOSTUSER is a vhost-user port (socket)
 	VHOSTUSER = "vhostuser"
+
+	// USER is qemu's user mode network stack.  It needs no host
+	// interface and is mostly useful for testing.
+	USER = "user"
 )
 
 // QemuNetdevParam converts to the QEMU -netdev parameter notation
@@ -370,6 +394,8 @@ func (n NetDeviceType) QemuNetdevParam() string {
 		return "" // -device vfio-pci (no netdev)
 	case VHOSTUSER:
 		return "vhost-user" // -netdev type=vhost-user (no device)
+	case USER:
+		return "user"
 	default:
 		return ""
 
@@ -391,6 +417,8 @@ func (n NetDeviceType) QemuDeviceParam() string {
 		return "vfio-pci" // -device vfio-pci (no netdev)
 	case VHOSTUSER:
 		return "" // -netdev type=vhost-user (no device)
+	case USER:
+		return "virtio-net-pci" // -netdev type=user -device virtio-net-pci
 	default:
 		return ""
 
@@ -408,7 +436,7 @@ type NetDevice struct {
 	// ID is the netdevice identifier.
 	ID string
 
-	// IfName is the interface name,
+	// IfName is the interface name.  It is not needed for USER devices.
 	IFName string
 
 	// Bus is the bus path name of a PCI device.
@@ -430,7 +458,8 @@ type NetDevice struct {
 	// VHost enables virtio device emulation from the host kernel instead of from qemu.
 	VHost bool
 
-	// MACAddress is the networking device interface MAC address.
+	// MACAddress is the networking device interface MAC address.  If it
+	// is empty qemu generates an address.
 	MACAddress string
 
 	// DisableModern prevents qemu from relying on fast MMIO.
@@ -439,14 +468,16 @@ type NetDevice struct {
 
 // Valid returns true if the NetDevice structure is valid and complete.
 func (netdev NetDevice) Valid() bool {
-	if netdev.ID == "" || netdev.IFName == "" {
+	if netdev.ID == "" {
 		return false
 	}
 
 	switch netdev.Type {
 	case TAP:
-		return true
+		return netdev.IFName != ""
 	case MACVTAP:
+		return netdev.IFName != ""
+	case USER:
 		return true
 	default:
 		return false
@@ -464,7 +495,9 @@ func (netdev NetDevice) QemuDeviceParams(config *Config) []string {
 	deviceParams = append(deviceParams, "driver=")
 	deviceParams = append(deviceParams, netdev.Type.QemuDeviceParam())
 	deviceParams = append(deviceParams, fmt.Sprintf(",netdev=%s", netdev.ID))
-	deviceParams = append(deviceParams, fmt.Sprintf(",mac=%s", netdev.MACAddress))
+	if netdev.MACAddress != "" {
+		deviceParams = append(deviceParams, fmt.Sprintf(",mac=%s", netdev.MACAddress))
+	}
 
 	if netdev.Bus != "" {
 		deviceParams = append(deviceParams, fmt.Sprintf(",bus=%s", netdev.Bus))
@@ -525,7 +558,7 @@ func (netdev NetDevice) QemuNetdevParams(config *Config) []string {
 
 		netdevParams = append(netdevParams, fmt.Sprintf(",fds=%s", strings.Join(fdParams, ":")))
 
-	} else {
+	} else if netdev.Type != USER {
 		netdevParams = append(netdevParams, fmt.Sprintf(",ifname=%s", netdev.IFName))
 		if netdev.DownScript != "" {
 			netdevParams = append(netdevParams, fmt.Sprintf(",downscript=%s", netdev.DownScript))
@@ -614,12 +647,21 @@ type BlockDeviceAIO string
 // BlockDeviceFormat defines the image format used on a block device.
 type BlockDeviceFormat string
 
+// BlockDeviceBackend defines where the data of a block device is stored.
+type BlockDeviceBackend string
+
+// BlockDeviceCache defines how the host page cache is used by a block device.
+type BlockDeviceCache string
+
 const (
 	// NoInterface for block devices with no interfaces.
 	NoInterface BlockDeviceInterface = "none"
 
 	// SCSI represents a SCSI block device interface.
 	SCSI = "scsi"
+
+	// VirtioInterface represents a virtio block device interface.
+	VirtioInterface = "virtio"
 )
 
 const (
@@ -633,6 +675,27 @@ const (
 const (
 	// QCOW2 is the Qemu Copy On Write v2 image format.
 	QCOW2 BlockDeviceFormat = "qcow2"
+
+	// RAW is the raw image format.
+	RAW = "raw"
+)
+
+const (
+	// FileBackend stores the data of a block device in a file or device
+	// node on the host.  This is the default backend.
+	FileBackend BlockDeviceBackend = "file"
+
+	// RBDBackend stores the data of a block device in a ceph RADOS block
+	// device image.
+	RBDBackend = "rbd"
+)
+
+const (
+	// WriteBack caches both reads and writes in the host page cache.
+	WriteBack BlockDeviceCache = "writeback"
+
+	// NoCache bypasses the host page cache.
+	NoCache = "none"
 )
 
 // BlockDevice represents a qemu block device.
@@ -646,8 +709,48 @@ type BlockDevice struct {
 	SCSI      bool
 	WCE       bool
 
+	// DeviceID is the user defined device ID.
+	DeviceID string
+
+	// Bus is the bus path name of a PCI device.
+	Bus string
+
+	// Addr is the address offset of a PCI device.
+	Addr string
+
+	// Backend determines how File is interpreted.  For FileBackend, the
+	// default, File is a path on the host.  For RBDBackend, File is the
+	// name of a ceph image.
+	Backend BlockDeviceBackend
+
+	// RBDPool is the ceph pool containing the image of an RBDBackend
+	// device.  It defaults to rbd.
+	RBDPool string
+
+	// RBDUser is the ceph user used to access the image of an RBDBackend
+	// device.
+	RBDUser string
+
+	// Cache is the host cache mode of the drive.
+	Cache BlockDeviceCache
+
 	// DisableModern prevents qemu from relying on fast MMIO.
 	DisableModern bool
+
+	// ReadOnly opens the drive read only.
+	ReadOnly bool
+
+	// ShareRW allows the drive to be written to by other instances
+	// that share the same backing image.
+	ShareRW bool
+
+	// IOPS limits the total number of I/O operations per second the
+	// guest can issue to the drive.  0 means no limit.
+	IOPS int64
+
+	// BPS limits the total number of bytes per second the guest can
+	// read from and write to the drive.  0 means no limit.
+	BPS int64
 }
 
 // Valid returns true if the BlockDevice structure is valid and complete.
@@ -678,11 +781,51 @@ func (blkdev BlockDevice) QemuParams(config *Config) []string {
 		deviceParams = append(deviceParams, ",config-wce=off")
 	}
 
+	if blkdev.ShareRW {
+		deviceParams = append(deviceParams, ",share-rw=on")
+	}
+
+	if blkdev.DeviceID != "" {
+		deviceParams = append(deviceParams, fmt.Sprintf(",id=%s", blkdev.DeviceID))
+	}
+
+	if blkdev.Bus != "" {
+		deviceParams = append(deviceParams, fmt.Sprintf(",bus=%s", blkdev.Bus))
+	}
+
+	if blkdev.Addr != "" {
+		addr, err := strconv.Atoi(blkdev.Addr)
+		if err == nil && addr >= 0 {
+			deviceParams = append(deviceParams, fmt.Sprintf(",addr=%x", addr))
+		}
+	}
+
 	blkParams = append(blkParams, fmt.Sprintf("id=%s", blkdev.ID))
-	blkParams = append(blkParams, fmt.Sprintf(",file=%s", blkdev.File))
-	blkParams = append(blkParams, fmt.Sprintf(",aio=%s", blkdev.AIO))
-	blkParams = append(blkParams, fmt.Sprintf(",format=%s", blkdev.Format))
-	blkParams = append(blkParams, fmt.Sprintf(",if=%s", blkdev.Interface))
+	blkParams = append(blkParams, fmt.Sprintf(",file=%s", blkdev.fileParam()))
+	if blkdev.AIO != "" {
+		blkParams = append(blkParams, fmt.Sprintf(",aio=%s", blkdev.AIO))
+	}
+	if blkdev.Format != "" {
+		blkParams = append(blkParams, fmt.Sprintf(",format=%s", blkdev.Format))
+	}
+	if blkdev.Interface != "" {
+		blkParams = append(blkParams, fmt.Sprintf(",if=%s", blkdev.Interface))
+	}
+	if blkdev.Cache != "" {
+		blkParams = append(blkParams, fmt.Sprintf(",cache=%s", blkdev.Cache))
+	}
+
+	if blkdev.ReadOnly {
+		blkParams = append(blkParams, ",readonly=on")
+	}
+
+	if blkdev.IOPS > 0 {
+		blkParams = append(blkParams, fmt.Sprintf(",throttling.iops-total=%d", blkdev.IOPS))
+	}
+
+	if blkdev.BPS > 0 {
+		blkParams = append(blkParams, fmt.Sprintf(",throttling.bps-total=%d", blkdev.BPS))
+	}
 
 	qemuParams = append(qemuParams, "-device")
 	qemuParams = append(qemuParams, strings.Join(deviceParams, ""))
@@ -693,6 +836,95 @@ func (blkdev BlockDevice) QemuParams(config *Config) []string {
 	return qemuParams
 }
 
+// fileParam returns the value of the file option of the drive backing this
+// block device.
+func (blkdev BlockDevice) fileParam() string {
+	if blkdev.Backend != RBDBackend {
+		return blkdev.File
+	}
+
+	pool := blkdev.RBDPool
+	if pool == "" {
+		pool = "rbd"
+	}
+
+	file := fmt.Sprintf("rbd:%s/%s", pool, blkdev.File)
+	if blkdev.RBDUser != "" {
+		file += fmt.Sprintf(":id=%s", blkdev.RBDUser)
+	}
+
+	return file
+}
+
+// CDROMDevice represents a read only image presented to the guest as a
+// CD-ROM, e.g., a cloud-init config drive.
+type CDROMDevice struct {
+	// File is the path of the image on the host.
+	File string
+
+	// Interface is the interface the CD-ROM is connected to.
+	Interface BlockDeviceInterface
+}
+
+// Valid returns true if the CDROMDevice structure is valid and complete.
+func (cdrom CDROMDevice) Valid() bool {
+	return cdrom.File != ""
+}
+
+// QemuParams returns the qemu parameters built out of this CD-ROM device.
+func (cdrom CDROMDevice) QemuParams(config *Config) []string {
+	var driveParams []string
+	var qemuParams []string
+
+	driveParams = append(driveParams, fmt.Sprintf("file=%s", cdrom.File))
+	if cdrom.Interface != "" {
+		driveParams = append(driveParams, fmt.Sprintf(",if=%s", cdrom.Interface))
+	}
+	driveParams = append(driveParams, ",media=cdrom")
+
+	qemuParams = append(qemuParams, "-drive")
+	qemuParams = append(qemuParams, strings.Join(driveParams, ""))
+
+	return qemuParams
+}
+
+// maxPCISlot is the highest slot number available on a PCI bus.
+const maxPCISlot = 31
+
+// PCIAddressAllocator assigns slots on a PCI bus to devices that need a
+// fixed address, e.g., block devices that may later be unplugged with QMP.
+// qemu places devices without an address in the first free slot so devices
+// with fixed addresses should be allocated slots above those used by the
+// devices qemu creates by default.
+type PCIAddressAllocator struct {
+	// Bus is the bus path name of the PCI bus, e.g., pci.0.
+	Bus string
+
+	next int
+}
+
+// NewPCIAddressAllocator creates a PCIAddressAllocator that hands out the
+// slots of bus, starting with firstSlot.
+func NewPCIAddressAllocator(bus string, firstSlot int) *PCIAddressAllocator {
+	return &PCIAddressAllocator{
+		Bus:  bus,
+		next: firstSlot,
+	}
+}
+
+// Next returns the address of the next free slot on the bus, in the form
+// expected by the Addr fields of BlockDevice and NetDevice.  An error is
+// returned if the bus is full.
+func (a *PCIAddressAllocator) Next() (string, error) {
+	if a.next > maxPCISlot {
+		return "", fmt.Errorf("No free slots left on PCI bus %s", a.Bus)
+	}
+
+	addr := strconv.Itoa(a.next)
+	a.next++
+	return addr, nil
+}
+
 // VFIODevice represents a qemu vfio device meant for direct access by guest OS.
 type VFIODevice struct {
 	// Bus-Device-Function of device
@@ -847,6 +1079,78 @@ type Memory struct {
 	MaxMem string
 }
 
+// NUMANode describes a guest NUMA node and the host memory backing it.
+type NUMANode struct {
+	// ID is the ID of the guest NUMA node.
+	ID int
+
+	// CPUs is the list of guest vCPUs that belong to the node, e.g., 0-3.
+	CPUs string
+
+	// Size is the amount of guest memory assigned to the node.  It
+	// should be suffixed with M or G for sizes in megabytes or gigabytes
+	// respectively.
+	Size string
+
+	// MemPath is the path of a hugetlbfs mount point from which the memory
+	// of the node is allocated.  Anonymous host memory is used if it is
+	// empty.
+	MemPath string
+
+	// HostNodes is the list of host NUMA nodes to which the memory of the
+	// node is bound, e.g., 1 or 0-1.  The memory is not bound if it is
+	// empty.
+	HostNodes string
+
+	// Prealloc allocates all the memory of the node upfront.
+	Prealloc bool
+}
+
+// Valid returns true if the NUMANode structure is valid and complete.
+func (node NUMANode) Valid() bool {
+	return node.ID >= 0 && node.Size != ""
+}
+
+// QemuParams returns the qemu parameters built out of this NUMA node.
+func (node NUMANode) QemuParams(config *Config) []string {
+	var objectParams []string
+	var numaParams []string
+	var qemuParams []string
+
+	memdev := fmt.Sprintf("ram-node%d", node.ID)
+
+	if node.MemPath != "" {
+		objectParams = append(objectParams, "memory-backend-file")
+	} else {
+		objectParams = append(objectParams, "memory-backend-ram")
+	}
+	objectParams = append(objectParams, fmt.Sprintf(",id=%s", memdev))
+	objectParams = append(objectParams, fmt.Sprintf(",size=%s", node.Size))
+	if node.MemPath != "" {
+		objectParams = append(objectParams, fmt.Sprintf(",mem-path=%s,share=on", node.MemPath))
+	}
+	if node.Prealloc {
+		objectParams = append(objectParams, ",prealloc=on")
+	}
+	if node.HostNodes != "" {
+		objectParams = append(objectParams, fmt.Sprintf(",host-nodes=%s,policy=bind", node.HostNodes))
+	}
+
+	numaParams = append(numaParams, fmt.Sprintf("node,nodeid=%d", node.ID))
+	if node.CPUs != "" {
+		numaParams = append(numaParams, fmt.Sprintf(",cpus=%s", node.CPUs))
+	}
+	numaParams = append(numaParams, fmt.Sprintf(",memdev=%s", memdev))
+
+	qemuParams = append(qemuParams, "-object")
+	qemuParams = append(qemuParams, strings.Join(objectParams, ""))
+
+	qemuParams = append(qemuParams, "-numa")
+	qemuParams = append(qemuParams, strings.Join(numaParams, ""))
+
+	return qemuParams
+}
+
 // Kernel is the guest kernel configuration structure.
 type Kernel struct {
 	// Path is the guest kernel path on the host filesystem.
@@ -933,6 +1237,12 @@ type Config struct {
 	// SMP is the quest multi processors configuration.
 	SMP SMP
 
+	// NUMANodes describes the guest NUMA nodes and the host memory
+	// backing them.  The sizes of the nodes must add up to Memory.Size.
+	// NUMANodes should not be used together with the HugePages and
+	// MemPrealloc knobs.
+	NUMANodes []NUMANode
+
 	// GlobalParam is the -global parameter.
 	GlobalParam string
 
@@ -942,6 +1252,11 @@ type Config struct {
 	// Bios is the -bios parameter
 	Bios string
 
+	// Incoming, if not nil, is an open file containing the state of a VM
+	// saved by a previous migration.  The VM is restored from this state,
+	// rather than booted, when qemu starts.
+	Incoming *os.File
+
 	// fds is a list of open file descriptors to be passed to the spawned qemu process
 	fds []*os.File
 
@@ -1053,6 +1368,16 @@ func (config *Config) appendMemory() {
 	}
 }
 
+func (config *Config) appendNUMANodes() {
+	for _, n := range config.NUMANodes {
+		if n.Valid() == false {
+			continue
+		}
+
+		config.qemuParams = append(config.qemuParams, n.QemuParams(config)...)
+	}
+}
+
 func (config *Config) appendCPUs() {
 	if config.SMP.CPUs > 0 {
 		var SMPParams []string
@@ -1194,6 +1519,14 @@ func (config *Config) appendBios() {
 	}
 }
 
+func (config *Config) appendIncoming() {
+	if config.Incoming != nil {
+		fds := config.appendFDs([]*os.File{config.Incoming})
+		config.qemuParams = append(config.qemuParams, "-incoming")
+		config.qemuParams = append(config.qemuParams, fmt.Sprintf("fd:%d", fds[0]))
+	}
+}
+
 // LaunchQemu can be used to launch a new qemu instance.
 //
 // The Config parameter contains a set of qemu parameters and settings.
@@ -1204,6 +1537,15 @@ func (config *Config) appendBios() {
 // will be returned if the launch succeeds.  Otherwise a string containing
 // the contents of stderr + a Go error object will be returned.
 func LaunchQemu(config Config, logger QMPLog) (string, error) {
+	params, fds := config.QemuParams()
+	return LaunchCustomQemu(config.Ctx, config.Path, params, fds, logger)
+}
+
+// QemuParams returns the parameters and the list of open file descriptors
+// that LaunchQemu passes to qemu for this configuration.  It can be used to
+// inspect a configuration without launching qemu or to extend the generated
+// command line before passing it to LaunchCustomQemu.
+func (config Config) QemuParams() ([]string, []*os.File) {
 	config.appendName()
 	config.appendUUID()
 	config.appendMachine()
@@ -1211,6 +1553,7 @@ func LaunchQemu(config Config, logger QMPLog) (string, error) {
 	config.appendQMPSockets()
 	config.appendMemory()
 	config.appendCPUs()
+	config.appendNUMANodes()
 	config.appendDevices()
 	config.appendRTC()
 	config.appendGlobalParam()
@@ -1218,8 +1561,9 @@ func LaunchQemu(config Config, logger QMPLog) (string, error) {
 	config.appendKnobs()
 	config.appendKernel()
 	config.appendBios()
+	config.appendIncoming()
 
-	return LaunchCustomQemu(config.Ctx, config.Path, config.qemuParams, config.fds, logger)
+	return config.qemuParams, config.fds
 }
 
 // LaunchCustomQemu can be used to launch a new qemu instance.
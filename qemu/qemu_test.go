@@ -64,6 +64,10 @@ func testAppend(structure interface{}, expected string, t *testing.T) {
 	case RTC:
 		config.RTC = s
 		config.appendRTC()
+
+	case []NUMANode:
+		config.NUMANodes = s
+		config.appendNUMANodes()
 	}
 
 	result := strings.Join(config.qemuParams, " ")
@@ -246,6 +250,22 @@ func TestAppendDeviceSerialPort(t *testing.T) {
 	testAppend(chardev, deviceSerialPortString, t)
 }
 
+var deviceSerialClientString = "-device isa-serial,chardev=console0,id=serial0 -chardev socket,id=console0,path=/tmp/console.sock,reconnect=1"
+
+func TestAppendDeviceSerialClient(t *testing.T) {
+	chardev := CharDevice{
+		Driver:    ISASerial,
+		Backend:   Socket,
+		ID:        "console0",
+		DeviceID:  "serial0",
+		Path:      "/tmp/console.sock",
+		Client:    true,
+		Reconnect: 1,
+	}
+
+	testAppend(chardev, deviceSerialClientString, t)
+}
+
 var deviceBlockString = "-device virtio-blk,disable-modern=true,drive=hd0,scsi=off,config-wce=off -drive id=hd0,file=/var/lib/ciao.img,aio=threads,format=qcow2,if=none"
 
 func TestAppendDeviceBlock(t *testing.T) {
@@ -264,6 +284,115 @@ func TestAppendDeviceBlock(t *testing.T) {
 	testAppend(blkdev, deviceBlockString, t)
 }
 
+var deviceBlockSharedROString = "-device virtio-blk,drive=hd1,scsi=off,config-wce=off,share-rw=on -drive id=hd1,file=/var/lib/ciao-shared.img,aio=threads,format=qcow2,if=none,readonly=on"
+
+func TestAppendDeviceBlockSharedReadOnly(t *testing.T) {
+	blkdev := BlockDevice{
+		Driver:    VirtioBlock,
+		ID:        "hd1",
+		File:      "/var/lib/ciao-shared.img",
+		AIO:       Threads,
+		Format:    QCOW2,
+		Interface: NoInterface,
+		ReadOnly:  true,
+		ShareRW:   true,
+	}
+
+	testAppend(blkdev, deviceBlockSharedROString, t)
+}
+
+var deviceBlockThrottledString = "-device virtio-blk,drive=hd0,scsi=off,config-wce=off -drive id=hd0,file=/var/lib/ciao.img,format=qcow2,if=none,throttling.iops-total=500,throttling.bps-total=10000000"
+
+func TestAppendDeviceBlockThrottled(t *testing.T) {
+	blkdev := BlockDevice{
+		Driver:    VirtioBlock,
+		ID:        "hd0",
+		File:      "/var/lib/ciao.img",
+		Format:    QCOW2,
+		Interface: NoInterface,
+		IOPS:      500,
+		BPS:       10000000,
+	}
+
+	testAppend(blkdev, deviceBlockThrottledString, t)
+}
+
+var deviceBlockRBDString = "-device virtio-blk-pci,drive=drive0,scsi=off,id=device0,bus=pci.0,addr=1f -drive id=drive0,file=rbd:rbd/4e4b7a5c-2b43-49f2-a0b2-6a7a0d1c3f06:id=ciao,format=raw,if=none"
+
+func TestAppendDeviceBlockRBD(t *testing.T) {
+	blkdev := BlockDevice{
+		Driver:    VirtioBlockPCI,
+		ID:        "drive0",
+		DeviceID:  "device0",
+		File:      "4e4b7a5c-2b43-49f2-a0b2-6a7a0d1c3f06",
+		Backend:   RBDBackend,
+		RBDUser:   "ciao",
+		Format:    RAW,
+		Interface: NoInterface,
+		Bus:       "pci.0",
+		Addr:      "31",
+		WCE:       true,
+	}
+
+	testAppend(blkdev, deviceBlockRBDString, t)
+}
+
+var deviceBlockUncachedString = "-device virtio-blk-pci,drive=drive1,scsi=off -drive id=drive1,file=/var/lib/ciao/ephemeral/disk-0.raw,format=raw,if=none,cache=none"
+
+func TestAppendDeviceBlockUncached(t *testing.T) {
+	blkdev := BlockDevice{
+		Driver:    VirtioBlockPCI,
+		ID:        "drive1",
+		File:      "/var/lib/ciao/ephemeral/disk-0.raw",
+		Backend:   FileBackend,
+		Format:    RAW,
+		Interface: NoInterface,
+		Cache:     NoCache,
+		WCE:       true,
+	}
+
+	testAppend(blkdev, deviceBlockUncachedString, t)
+}
+
+var deviceCDROMString = "-drive file=/var/lib/ciao/instances/1/seed.iso,if=virtio,media=cdrom"
+
+func TestAppendDeviceCDROM(t *testing.T) {
+	cdrom := CDROMDevice{
+		File:      "/var/lib/ciao/instances/1/seed.iso",
+		Interface: VirtioInterface,
+	}
+
+	testAppend(cdrom, deviceCDROMString, t)
+}
+
+var deviceNetworkUserString = "-netdev user,id=net0 -device driver=virtio-net-pci,netdev=net0"
+
+func TestAppendDeviceNetworkUser(t *testing.T) {
+	netdev := NetDevice{
+		Type: USER,
+		ID:   "net0",
+	}
+
+	testAppend(netdev, deviceNetworkUserString, t)
+}
+
+func TestPCIAddressAllocator(t *testing.T) {
+	pciAddrs := NewPCIAddressAllocator("pci.0", 30)
+	for _, expected := range []string{"30", "31"} {
+		addr, err := pciAddrs.Next()
+		if err != nil {
+			t.Fatalf("Unable to allocate PCI address: %v", err)
+		}
+		if addr != expected {
+			t.Fatalf("Unexpected PCI address %s, expected %s", addr, expected)
+		}
+	}
+
+	if _, err := pciAddrs.Next(); err == nil {
+		t.Fatalf("Expected error when PCI bus is full")
+	}
+}
+
 var deviceVFIOString = "-device vfio-pci,host=02:10.0"
 
 func TestAppendDeviceVFIO(t *testing.T) {
@@ -421,3 +550,113 @@ func TestAppendRTC(t *testing.T) {
 
 	testAppend(rtc, rtcString, t)
 }
+
+var configMacvtapString = "-machine pc,accel=kvm -m 512M -netdev tap,id=macvtap0,vhost=on,fds=3:4 -device driver=virtio-net-pci,netdev=macvtap0,mac=01:02:de:ad:be:ef,mq=on,vectors=6 -rtc base=utc -daemonize"
+
+func TestConfigQemuParams(t *testing.T) {
+	foo, _ := ioutil.TempFile(os.TempDir(), "qemu-ciao-test")
+	bar, _ := ioutil.TempFile(os.TempDir(), "qemu-ciao-test")
+
+	defer func() {
+		foo.Close()
+		bar.Close()
+		os.Remove(foo.Name())
+		os.Remove(bar.Name())
+	}()
+
+	config := Config{
+		Machine: Machine{
+			Type:         "pc",
+			Acceleration: "kvm",
+		},
+		Memory: Memory{
+			Size: "512M",
+		},
+		Devices: []Device{
+			NetDevice{
+				Type:       MACVTAP,
+				ID:         "macvtap0",
+				IFName:     "macvtap0",
+				FDs:        []*os.File{foo, bar},
+				VHost:      true,
+				MACAddress: "01:02:de:ad:be:ef",
+			},
+		},
+		RTC: RTC{
+			Base: UTC,
+		},
+		Knobs: Knobs{
+			Daemonize: true,
+			Mlock:     true,
+		},
+	}
+
+	// Generating the parameters must not modify the configuration so
+	// they should be identical each time.
+
+	for i := 0; i < 2; i++ {
+		params, fds := config.QemuParams()
+		result := strings.Join(params, " ")
+		if result != configMacvtapString {
+			t.Fatalf("Failed to generate parameters [%s] != [%s]", result, configMacvtapString)
+		}
+
+		if len(fds) != 2 || fds[0] != foo || fds[1] != bar {
+			t.Fatalf("Unexpected file descriptors %v", fds)
+		}
+	}
+}
+
+var numaNodesString = "-object memory-backend-file,id=ram-node0,size=2048M,mem-path=/dev/hugepages,share=on,prealloc=on,host-nodes=1,policy=bind -numa node,nodeid=0,cpus=0-3,memdev=ram-node0 -object memory-backend-ram,id=ram-node1,size=1024M -numa node,nodeid=1,memdev=ram-node1"
+
+func TestAppendNUMANodes(t *testing.T) {
+	nodes := []NUMANode{
+		{
+			ID:        0,
+			CPUs:      "0-3",
+			Size:      "2048M",
+			MemPath:   "/dev/hugepages",
+			HostNodes: "1",
+			Prealloc:  true,
+		},
+		{
+			ID:   1,
+			Size: "1024M",
+		},
+		{
+			ID: 2,
+		},
+	}
+
+	testAppend(nodes, numaNodesString, t)
+}
+
+func TestAppendIncoming(t *testing.T) {
+	f, err := ioutil.TempFile("", "qemu-incoming")
+	if err != nil {
+		t.Fatalf("Unable to create temporary file: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	config := Config{
+		Incoming: f,
+	}
+
+	// Simulate a file descriptor already passed for a network device.
+	// The state file should be passed in the next slot.
+
+	config.appendFDs([]*os.File{nil})
+	config.appendIncoming()
+
+	result := strings.Join(config.qemuParams, " ")
+	if result != "-incoming fd:4" {
+		t.Fatalf("Failed to append parameters [%s] != [-incoming fd:4]", result)
+	}
+
+	if len(config.fds) != 2 || config.fds[1] != f {
+		t.Fatalf("State file not passed to qemu %v", config.fds)
+	}
+}
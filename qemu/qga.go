@@ -0,0 +1,329 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package qemu
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// GuestAgentChannel is the name of the virtio-serial port on which the
+// QEMU guest agent listens inside the guest.
+const GuestAgentChannel = "org.qemu.guest_agent.0"
+
+// QGAConfig is a configuration structure that can be used to specify a
+// logger for a QGA connection.  If no logger is specified, no logs will be
+// written.
+type QGAConfig struct {
+	// Logger is used by the QGA methods to log information.
+	Logger QMPLog
+}
+
+// QGA is a client for the QEMU guest agent (QGA) protocol.  It talks to a
+// qemu-guest-agent running inside a guest via the host side of the
+// virtio-serial channel of the guest agent, typically a unix socket.
+//
+// Unlike QMP, the guest agent does not send a greeting or events, and it
+// may not be running at all, e.g., while the guest is booting.  Commands
+// are executed synchronously and the contexts passed to the QGA methods
+// should therefore carry a deadline.  After a command fails the channel is
+// resynchronised with guest-sync before the next command is sent, so that
+// stale responses are not mistaken for the response to that command.
+//
+// The methods of QGA may be called from multiple go routines.
+type QGA struct {
+	mutex    sync.Mutex
+	conn     net.Conn
+	reader   *bufio.Reader
+	cfg      QGAConfig
+	syncID   int
+	needSync bool
+}
+
+// GuestIPAddress is an IP address of a network interface of a guest, as
+// reported by guest-network-get-interfaces.
+type GuestIPAddress struct {
+	// Type is either ipv4 or ipv6
+	Type    string `json:"ip-address-type"`
+	Address string `json:"ip-address"`
+	Prefix  int    `json:"prefix"`
+}
+
+// GuestNetworkInterface describes a network interface of a guest, as
+// reported by guest-network-get-interfaces.
+type GuestNetworkInterface struct {
+	Name            string           `json:"name"`
+	HardwareAddress string           `json:"hardware-address"`
+	IPAddresses     []GuestIPAddress `json:"ip-addresses"`
+}
+
+// GuestOSInfo describes the operating system of a guest, as reported by
+// guest-get-osinfo.  Fields the guest agent does not know about are empty.
+type GuestOSInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	PrettyName    string `json:"pretty-name"`
+	Version       string `json:"version"`
+	VersionID     string `json:"version-id"`
+	KernelRelease string `json:"kernel-release"`
+	KernelVersion string `json:"kernel-version"`
+	Machine       string `json:"machine"`
+}
+
+type qgaResponse struct {
+	Return json.RawMessage `json:"return"`
+	Error  *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+}
+
+// QGAStart connects to the unix socket of the guest agent channel of a
+// QEMU instance.  The connection is closed by calling Shutdown.
+func QGAStart(ctx context.Context, socket string, cfg QGAConfig) (*QGA, error) {
+	if cfg.Logger == nil {
+		cfg.Logger = qmpNullLogger{}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", socket)
+	if err != nil {
+		cfg.Logger.Warningf("Unable to connect to guest agent socket %s: %v",
+			socket, err)
+		return nil, err
+	}
+
+	return &QGA{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		cfg:    cfg,
+		syncID: int(time.Now().Unix() & 0xffffff),
+
+		// Discard anything left in the channel by a previous client.
+		needSync: true,
+	}, nil
+}
+
+// Shutdown closes the connection to the guest agent.
+func (q *QGA) Shutdown() {
+	_ = q.conn.Close()
+}
+
+func (q *QGA) setDeadline(ctx context.Context) error {
+	deadline, _ := ctx.Deadline()
+	return q.conn.SetDeadline(deadline)
+}
+
+func (q *QGA) send(name string, args map[string]interface{}) error {
+	cmd := map[string]interface{}{"execute": name}
+	if args != nil {
+		cmd["arguments"] = args
+	}
+
+	encoded, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	if q.cfg.Logger.V(2) {
+		q.cfg.Logger.Infof("%s", string(encoded))
+	}
+
+	_, err = q.conn.Write(append(encoded, '\n'))
+	return err
+}
+
+func (q *QGA) receive() (*qgaResponse, error) {
+	for {
+		line, err := q.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		// The guest agent may prefix responses with a 0xff sentinel
+		// byte which we simply skip.
+		line = bytes.TrimSpace(bytes.TrimLeft(line, "\xff"))
+		if len(line) == 0 {
+			continue
+		}
+
+		if q.cfg.Logger.V(2) {
+			q.cfg.Logger.Infof("%s", string(line))
+		}
+
+		var resp qgaResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			q.cfg.Logger.Warningf("Unable to decode guest agent response %s: %v",
+				string(line), err)
+			continue
+		}
+
+		if resp.Return == nil && resp.Error == nil {
+			continue
+		}
+
+		return &resp, nil
+	}
+}
+
+func (q *QGA) sync() error {
+	q.syncID++
+	id := q.syncID
+
+	err := q.send("guest-sync", map[string]interface{}{"id": id})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := q.receive()
+		if err != nil {
+			return err
+		}
+
+		var ret int
+		if resp.Return != nil && json.Unmarshal(resp.Return, &ret) == nil &&
+			ret == id {
+			return nil
+		}
+	}
+}
+
+func (q *QGA) execute(ctx context.Context, name string, args map[string]interface{},
+	ret interface{}) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	resp, err := q.roundTrip(ctx, name, args)
+	if err != nil {
+		q.needSync = true
+		return err
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("%s failed: %s: %s", name, resp.Error.Class,
+			resp.Error.Desc)
+	}
+
+	if ret == nil {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Return, ret)
+}
+
+func (q *QGA) roundTrip(ctx context.Context, name string,
+	args map[string]interface{}) (*qgaResponse, error) {
+	if err := q.setDeadline(ctx); err != nil {
+		return nil, err
+	}
+
+	if q.needSync {
+		if err := q.sync(); err != nil {
+			return nil, err
+		}
+		q.needSync = false
+	}
+
+	if err := q.send(name, args); err != nil {
+		return nil, err
+	}
+
+	return q.receive()
+}
+
+// ExecuteGuestSync resynchronises the channel to the guest agent, discarding
+// any responses to previous commands that may still be in flight.
+func (q *QGA) ExecuteGuestSync(ctx context.Context) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if err := q.setDeadline(ctx); err != nil {
+		return err
+	}
+
+	if err := q.sync(); err != nil {
+		q.needSync = true
+		return err
+	}
+
+	q.needSync = false
+	return nil
+}
+
+// ExecuteGuestPing checks whether the guest agent is running and responsive.
+func (q *QGA) ExecuteGuestPing(ctx context.Context) error {
+	return q.execute(ctx, "guest-ping", nil, nil)
+}
+
+// ExecuteGuestFsfreezeFreeze freezes all the freezable guest filesystems,
+// e.g., before a snapshot of the instance's disks is taken.  It returns the
+// number of filesystems frozen.
+func (q *QGA) ExecuteGuestFsfreezeFreeze(ctx context.Context) (int, error) {
+	var count int
+	err := q.execute(ctx, "guest-fsfreeze-freeze", nil, &count)
+	return count, err
+}
+
+// ExecuteGuestFsfreezeThaw unfreezes the guest filesystems frozen by
+// ExecuteGuestFsfreezeFreeze.  It returns the number of filesystems thawed.
+func (q *QGA) ExecuteGuestFsfreezeThaw(ctx context.Context) (int, error) {
+	var count int
+	err := q.execute(ctx, "guest-fsfreeze-thaw", nil, &count)
+	return count, err
+}
+
+// ExecuteGuestSetUserPassword sets the password of an existing guest user
+// account.  password is the plain text password.
+func (q *QGA) ExecuteGuestSetUserPassword(ctx context.Context, username,
+	password string) error {
+	args := map[string]interface{}{
+		"username": username,
+		"password": base64.StdEncoding.EncodeToString([]byte(password)),
+		"crypted":  false,
+	}
+	return q.execute(ctx, "guest-set-user-password", args, nil)
+}
+
+// ExecuteGuestNetworkGetInterfaces returns the network interfaces of the
+// guest and their addresses.
+func (q *QGA) ExecuteGuestNetworkGetInterfaces(ctx context.Context) ([]GuestNetworkInterface, error) {
+	var ifaces []GuestNetworkInterface
+	err := q.execute(ctx, "guest-network-get-interfaces", nil, &ifaces)
+	if err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// ExecuteGuestGetOSInfo returns information about the operating system of
+// the guest.
+func (q *QGA) ExecuteGuestGetOSInfo(ctx context.Context) (*GuestOSInfo, error) {
+	var info GuestOSInfo
+	err := q.execute(ctx, "guest-get-osinfo", nil, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
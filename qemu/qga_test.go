@@ -0,0 +1,288 @@
+/*
+// Copyright (c) 2017 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+*/
+
+package qemu
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type qgaTestCommand struct {
+	Execute   string                 `json:"execute"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// qgaTestAgent is a fake guest agent.  It answers guest-sync commands itself
+// and the other commands with the responses registered for them.  If
+// stale is set it sends a bogus response before the response to each
+// guest-sync.
+type qgaTestAgent struct {
+	t         *testing.T
+	ln        net.Listener
+	socket    string
+	responses map[string]string
+	stale     bool
+	received  chan qgaTestCommand
+	doneCh    chan struct{}
+}
+
+func startQGATestAgent(t *testing.T, responses map[string]string, stale bool) *qgaTestAgent {
+	dir, err := ioutil.TempDir("", "qga-tests")
+	if err != nil {
+		t.Fatalf("Unable to create temporary directory: %v", err)
+	}
+
+	socket := path.Join(dir, "qga.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		t.Fatalf("Unable to listen on %s: %v", socket, err)
+	}
+
+	agent := &qgaTestAgent{
+		t:         t,
+		ln:        ln,
+		socket:    socket,
+		responses: responses,
+		stale:     stale,
+		received:  make(chan qgaTestCommand, 16),
+		doneCh:    make(chan struct{}),
+	}
+	go agent.serve()
+
+	return agent
+}
+
+func (a *qgaTestAgent) serve() {
+	defer close(a.doneCh)
+
+	conn, err := a.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var cmd qgaTestCommand
+		if err := json.Unmarshal(scanner.Bytes(), &cmd); err != nil {
+			a.t.Errorf("Unable to decode command %s: %v", scanner.Text(), err)
+			return
+		}
+		a.received <- cmd
+
+		var resp string
+		if cmd.Execute == "guest-sync" {
+			if a.stale {
+				resp = "{\"return\": {}}\n"
+			}
+			id, _ := cmd.Arguments["id"].(float64)
+			resp += fmt.Sprintf("\xff{\"return\": %d}", int(id))
+		} else {
+			resp = a.responses[cmd.Execute]
+		}
+
+		if resp == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+func (a *qgaTestAgent) stop() {
+	_ = a.ln.Close()
+	<-a.doneCh
+	_ = os.RemoveAll(path.Dir(a.socket))
+}
+
+func (a *qgaTestAgent) connect(t *testing.T) *QGA {
+	q, err := QGAStart(context.Background(), a.socket, QGAConfig{})
+	if err != nil {
+		t.Fatalf("Unable to connect to guest agent: %v", err)
+	}
+	return q
+}
+
+func qgaTestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Second)
+}
+
+// Checks that guest-ping is sent after the channel is synchronised.
+//
+// Connect to a fake agent that sends a stale response before the response
+// to guest-sync and execute guest-ping.
+//
+// guest-sync and then guest-ping should be received by the agent and the
+// ping should succeed.
+func TestQGAPing(t *testing.T) {
+	agent := startQGATestAgent(t, map[string]string{
+		"guest-ping": `{"return": {}}`,
+	}, true)
+	defer agent.stop()
+
+	q := agent.connect(t)
+	defer q.Shutdown()
+
+	ctx, cancel := qgaTestContext()
+	defer cancel()
+	if err := q.ExecuteGuestPing(ctx); err != nil {
+		t.Fatalf("guest-ping failed: %v", err)
+	}
+
+	for _, expected := range []string{"guest-sync", "guest-ping"} {
+		cmd := <-agent.received
+		if cmd.Execute != expected {
+			t.Errorf("Expected %s, got %s", expected, cmd.Execute)
+		}
+	}
+}
+
+// Checks the parsing of guest-network-get-interfaces and guest-get-osinfo.
+//
+// Execute both commands against a fake agent.
+//
+// The interfaces and OS information returned should match those sent by
+// the agent.
+func TestQGAGuestInfo(t *testing.T) {
+	agent := startQGATestAgent(t, map[string]string{
+		"guest-network-get-interfaces": `{"return": [{"name": "eth0", ` +
+			`"hardware-address": "02:00:e6:f5:af:f9", "ip-addresses": [` +
+			`{"ip-address-type": "ipv4", "ip-address": "192.168.8.2", "prefix": 21}]}]}`,
+		"guest-get-osinfo": `{"return": {"id": "ubuntu", "pretty-name": "Ubuntu 16.04.2 LTS", ` +
+			`"kernel-release": "4.4.0-62-generic"}}`,
+	}, false)
+	defer agent.stop()
+
+	q := agent.connect(t)
+	defer q.Shutdown()
+
+	ctx, cancel := qgaTestContext()
+	defer cancel()
+
+	ifaces, err := q.ExecuteGuestNetworkGetInterfaces(ctx)
+	if err != nil {
+		t.Fatalf("guest-network-get-interfaces failed: %v", err)
+	}
+	expectedIfaces := []GuestNetworkInterface{
+		{
+			Name:            "eth0",
+			HardwareAddress: "02:00:e6:f5:af:f9",
+			IPAddresses: []GuestIPAddress{
+				{Type: "ipv4", Address: "192.168.8.2", Prefix: 21},
+			},
+		},
+	}
+	if !reflect.DeepEqual(ifaces, expectedIfaces) {
+		t.Errorf("Unexpected interfaces %+v", ifaces)
+	}
+
+	info, err := q.ExecuteGuestGetOSInfo(ctx)
+	if err != nil {
+		t.Fatalf("guest-get-osinfo failed: %v", err)
+	}
+	expectedInfo := GuestOSInfo{
+		ID:            "ubuntu",
+		PrettyName:    "Ubuntu 16.04.2 LTS",
+		KernelRelease: "4.4.0-62-generic",
+	}
+	if *info != expectedInfo {
+		t.Errorf("Unexpected OS info %+v", *info)
+	}
+}
+
+// Checks the arguments and results of the fsfreeze and password commands.
+//
+// Freeze and thaw the guest filesystems and set a user password.
+//
+// The number of filesystems reported by the agent should be returned and
+// the password should be sent base64 encoded.
+func TestQGAFreezeAndPassword(t *testing.T) {
+	agent := startQGATestAgent(t, map[string]string{
+		"guest-fsfreeze-freeze":   `{"return": 2}`,
+		"guest-fsfreeze-thaw":     `{"return": 2}`,
+		"guest-set-user-password": `{"return": {}}`,
+	}, false)
+	defer agent.stop()
+
+	q := agent.connect(t)
+	defer q.Shutdown()
+
+	ctx, cancel := qgaTestContext()
+	defer cancel()
+
+	if n, err := q.ExecuteGuestFsfreezeFreeze(ctx); err != nil || n != 2 {
+		t.Errorf("guest-fsfreeze-freeze returned %d %v", n, err)
+	}
+	if n, err := q.ExecuteGuestFsfreezeThaw(ctx); err != nil || n != 2 {
+		t.Errorf("guest-fsfreeze-thaw returned %d %v", n, err)
+	}
+	if err := q.ExecuteGuestSetUserPassword(ctx, "demouser", "secret"); err != nil {
+		t.Errorf("guest-set-user-password failed: %v", err)
+	}
+
+	var cmd qgaTestCommand
+	for cmd = range agent.received {
+		if cmd.Execute == "guest-set-user-password" {
+			break
+		}
+	}
+	if cmd.Arguments["username"] != "demouser" ||
+		cmd.Arguments["password"] != "c2VjcmV0" ||
+		cmd.Arguments["crypted"] != false {
+		t.Errorf("Unexpected guest-set-user-password arguments %v", cmd.Arguments)
+	}
+}
+
+// Checks errors reported by the guest agent and timeouts.
+//
+// Execute a command the agent fails and then one it never answers.
+//
+// An error should be returned for both commands.
+func TestQGAErrors(t *testing.T) {
+	agent := startQGATestAgent(t, map[string]string{
+		"guest-fsfreeze-freeze": `{"error": {"class": "GenericError", "desc": "fsfreeze unsupported"}}`,
+	}, false)
+	defer agent.stop()
+
+	q := agent.connect(t)
+	defer q.Shutdown()
+
+	ctx, cancel := qgaTestContext()
+	defer cancel()
+
+	if _, err := q.ExecuteGuestFsfreezeFreeze(ctx); err == nil {
+		t.Errorf("Expected guest-fsfreeze-freeze to fail")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := q.ExecuteGuestPing(ctx); err == nil {
+		t.Errorf("Expected guest-ping to time out")
+	}
+}
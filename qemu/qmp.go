@@ -107,7 +107,8 @@ type QMPEvent struct {
 }
 
 type qmpResult struct {
-	err error
+	response interface{}
+	err      error
 }
 
 type qmpCommand struct {
@@ -117,6 +118,7 @@ type qmpCommand struct {
 	args           map[string]interface{}
 	filter         *qmpEventFilter
 	resultReceived bool
+	response       interface{}
 }
 
 // QMP is a structure that contains the internal state used by startQMPLoop and
@@ -139,6 +141,67 @@ type QMPVersion struct {
 	Capabilities []string
 }
 
+// CPUInfo describes a vCPU of a QEMU instance, as reported by the
+// query-cpus command.
+type CPUInfo struct {
+	// CPU is the index of the vCPU.
+	CPU int `json:"CPU"`
+
+	// Current is true if this is the vCPU used by the monitor.
+	Current bool `json:"current"`
+
+	// Halted is true if the vCPU is halted.
+	Halted bool `json:"halted"`
+
+	// QOMPath is the path of the vCPU object in the QOM tree.
+	QOMPath string `json:"qom_path"`
+
+	// ThreadID is the ID of the host thread that runs the vCPU.
+	ThreadID int `json:"thread_id"`
+}
+
+// MigrationInfo describes the progress of a migration, as reported by the
+// query-migrate command.
+type MigrationInfo struct {
+	// Status is the state of the migration, e.g., active, completed or
+	// failed.  It is empty if no migration has been started.
+	Status string `json:"status"`
+
+	// ErrorDesc describes why a failed migration failed.  It is only
+	// reported by newer versions of QEMU.
+	ErrorDesc string `json:"error-desc"`
+}
+
+// BlockStats describes the I/O performed on a drive of a QEMU instance, as
+// reported by the query-blockstats command.
+type BlockStats struct {
+	// Device is the name of the drive.  It is empty for drives added
+	// with blockdev-add.
+	Device string `json:"device"`
+
+	// QDev is the path or ID of the guest device the drive is attached
+	// to.  It is only reported by newer versions of QEMU.
+	QDev string `json:"qdev"`
+
+	// Stats contains the I/O counters of the drive.
+	Stats BlockStatsCounters `json:"stats"`
+}
+
+// BlockStatsCounters contains the I/O counters of a drive.
+type BlockStatsCounters struct {
+	// RdBytes is the number of bytes read.
+	RdBytes int64 `json:"rd_bytes"`
+
+	// WrBytes is the number of bytes written.
+	WrBytes int64 `json:"wr_bytes"`
+
+	// RdOperations is the number of read operations.
+	RdOperations int64 `json:"rd_operations"`
+
+	// WrOperations is the number of write operations.
+	WrOperations int64 `json:"wr_operations"`
+}
+
 func (q *QMP) readLoop(fromVMCh chan<- []byte) {
 	scanner := bufio.NewScanner(q.conn)
 	for scanner.Scan() {
@@ -210,7 +273,7 @@ func (q *QMP) finaliseCommand(cmdEl *list.Element, cmdQueue *list.List, succeede
 	case <-cmd.ctx.Done():
 	default:
 		if succeeded {
-			cmd.res <- qmpResult{}
+			cmd.res <- qmpResult{response: cmd.response}
 		} else {
 			cmd.res <- qmpResult{err: fmt.Errorf("QMP command failed")}
 		}
@@ -233,7 +296,7 @@ func (q *QMP) processQMPInput(line []byte, cmdQueue *list.List) {
 		return
 	}
 
-	_, succeeded := vmData["return"]
+	response, succeeded := vmData["return"]
 	_, failed := vmData["error"]
 
 	if !succeeded && !failed {
@@ -247,6 +310,7 @@ func (q *QMP) processQMPInput(line []byte, cmdQueue *list.List) {
 		return
 	}
 	cmd := cmdEl.Value.(*qmpCommand)
+	cmd.response = response
 	if failed || cmd.filter == nil {
 		q.finaliseCommand(cmdEl, cmdQueue, succeeded)
 	} else {
@@ -465,7 +529,14 @@ func startQMPLoop(conn io.ReadWriteCloser, cfg QMPConfig,
 
 func (q *QMP) executeCommand(ctx context.Context, name string, args map[string]interface{},
 	filter *qmpEventFilter) error {
+	_, err := q.executeCommandWithResponse(ctx, name, args, filter)
+	return err
+}
+
+func (q *QMP) executeCommandWithResponse(ctx context.Context, name string, args map[string]interface{},
+	filter *qmpEventFilter) (interface{}, error) {
 	var err error
+	var response interface{}
 	resCh := make(chan qmpResult)
 	select {
 	case <-q.disconnectedCh:
@@ -480,17 +551,18 @@ func (q *QMP) executeCommand(ctx context.Context, name string, args map[string]i
 	}
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	select {
 	case res := <-resCh:
 		err = res.err
+		response = res.response
 	case <-ctx.Done():
 		err = ctx.Err()
 	}
 
-	return err
+	return response, err
 }
 
 // QMPStart connects to a unix domain socket maintained by a QMP instance.  It
@@ -602,6 +674,13 @@ func (q *QMP) ExecuteQuit(ctx context.Context) error {
 // used to name the device.  As this identifier will be passed directly to QMP,
 // it must obey QMP's naming rules, e,g., it must start with a letter.
 func (q *QMP) ExecuteBlockdevAdd(ctx context.Context, device, blockdevID string) error {
+	return q.ExecuteBlockdevAddWithOptions(ctx, device, blockdevID, false)
+}
+
+// ExecuteBlockdevAddWithOptions is identical to ExecuteBlockdevAdd except
+// that it allows the block device to be opened read only.  If ro is true the
+// guest cannot write to the device.
+func (q *QMP) ExecuteBlockdevAddWithOptions(ctx context.Context, device, blockdevID string, ro bool) error {
 	var args map[string]interface{}
 
 	blockdevArgs := map[string]interface{}{
@@ -612,6 +691,10 @@ func (q *QMP) ExecuteBlockdevAdd(ctx context.Context, device, blockdevID string)
 		},
 	}
 
+	if ro {
+		blockdevArgs["read-only"] = true
+	}
+
 	if q.version.Major > 2 || (q.version.Major == 2 && q.version.Minor >= 9) {
 		blockdevArgs["node-name"] = blockdevID
 		args = blockdevArgs
@@ -631,6 +714,14 @@ func (q *QMP) ExecuteBlockdevAdd(ctx context.Context, device, blockdevID string)
 // add.  Both strings must be valid QMP identifiers.  driver is the name of the
 // driver,e.g., virtio-blk-pci, and bus is the name of the bus.  bus is optional.
 func (q *QMP) ExecuteDeviceAdd(ctx context.Context, blockdevID, devID, driver, bus string) error {
+	return q.ExecuteDeviceAddWithOptions(ctx, blockdevID, devID, driver, bus, false)
+}
+
+// ExecuteDeviceAddWithOptions is identical to ExecuteDeviceAdd except that
+// it allows the device to be shared.  If shared is true the device is added
+// with share-rw=on, allowing the backing image to be written to by other
+// instances at the same time.
+func (q *QMP) ExecuteDeviceAddWithOptions(ctx context.Context, blockdevID, devID, driver, bus string, shared bool) error {
 	args := map[string]interface{}{
 		"id":     devID,
 		"driver": driver,
@@ -639,6 +730,9 @@ func (q *QMP) ExecuteDeviceAdd(ctx context.Context, blockdevID, devID, driver, b
 	if bus != "" {
 		args["bus"] = bus
 	}
+	if shared {
+		args["share-rw"] = "on"
+	}
 	return q.executeCommand(ctx, "device_add", args, nil)
 }
 
@@ -675,3 +769,109 @@ func (q *QMP) ExecuteDeviceDel(ctx context.Context, devID string) error {
 	}
 	return q.executeCommand(ctx, "device_del", args, filter)
 }
+
+// ExecuteBlockSetIOThrottle changes the I/O limits of the block device
+// identified by devID while the VM is running.  devID is the qdev ID of
+// the guest device, typically the devID passed to an earlier call to
+// ExecuteDeviceAdd.  bps is the total number of bytes per second and iops
+// the total number of I/O operations per second the guest may issue to the
+// device.  Passing 0 for both removes any existing limits.
+func (q *QMP) ExecuteBlockSetIOThrottle(ctx context.Context, devID string,
+	bps, iops int64) error {
+	args := map[string]interface{}{
+		"id":      devID,
+		"bps":     bps,
+		"bps_rd":  0,
+		"bps_wr":  0,
+		"iops":    iops,
+		"iops_rd": 0,
+		"iops_wr": 0,
+	}
+	return q.executeCommand(ctx, "block_set_io_throttle", args, nil)
+}
+
+// ExecuteQueryCpus returns information about the vCPUs of the QEMU instance,
+// including the IDs of the host threads that run them.  These IDs can be
+// used to pin the vCPUs to host CPUs.
+func (q *QMP) ExecuteQueryCpus(ctx context.Context) ([]CPUInfo, error) {
+	response, err := q.executeCommandWithResponse(ctx, "query-cpus", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rather than decoding the response by hand we round trip it through
+	// the json package.
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract CPU information: %v", err)
+	}
+
+	var cpus []CPUInfo
+	err = json.Unmarshal(data, &cpus)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract CPU information: %v", err)
+	}
+
+	return cpus, nil
+}
+
+// ExecuteQueryBlockstats returns the I/O counters of each of the drives of
+// the QEMU instance.
+func (q *QMP) ExecuteQueryBlockstats(ctx context.Context) ([]BlockStats, error) {
+	response, err := q.executeCommandWithResponse(ctx, "query-blockstats", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract block statistics: %v", err)
+	}
+
+	var stats []BlockStats
+	err = json.Unmarshal(data, &stats)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract block statistics: %v", err)
+	}
+
+	return stats, nil
+}
+
+// ExecuteMigrate starts a migration of the VM state to uri, e.g.,
+// exec:cat > /path/to/file.  This function returns as soon as the
+// migration has started.  ExecuteQueryMigrate can be used to determine
+// when it has finished.
+func (q *QMP) ExecuteMigrate(ctx context.Context, uri string) error {
+	args := map[string]interface{}{
+		"uri": uri,
+	}
+	return q.executeCommand(ctx, "migrate", args, nil)
+}
+
+// ExecuteMigrateCancel cancels the current migration.
+func (q *QMP) ExecuteMigrateCancel(ctx context.Context) error {
+	return q.executeCommand(ctx, "migrate_cancel", nil, nil)
+}
+
+// ExecuteQueryMigrate returns the status of the current or last migration
+// of the QEMU instance.
+func (q *QMP) ExecuteQueryMigrate(ctx context.Context) (*MigrationInfo, error) {
+	response, err := q.executeCommandWithResponse(ctx, "query-migrate", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract migration information: %v", err)
+	}
+
+	var info MigrationInfo
+	err = json.Unmarshal(data, &info)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to extract migration information: %v", err)
+	}
+
+	return &info, nil
+}
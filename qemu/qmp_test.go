@@ -75,7 +75,7 @@ type qmpTestEvent struct {
 
 type qmpTestResult struct {
 	result string
-	data   map[string]interface{}
+	data   interface{}
 }
 
 type qmpTestCommandBuffer struct {
@@ -132,7 +132,7 @@ func (b *qmpTestCommandBuffer) startEventLoop(wg *sync.WaitGroup) {
 }
 
 func (b *qmpTestCommandBuffer) AddCommand(name string, args map[string]interface{},
-	result string, data map[string]interface{}) {
+	result string, data interface{}) {
 	b.cmds = append(b.cmds, qmpTestCommand{name, args})
 	if data == nil {
 		data = make(map[string]interface{})
@@ -393,6 +393,55 @@ func TestQMPDeviceAdd(t *testing.T) {
 	<-disconnectedCh
 }
 
+// Checks that a read only blockdev-add command is correctly sent.
+//
+// We start a QMPLoop, send a read only blockdev-add command and stop the
+// loop.
+//
+// The blockdev-add command should be correctly sent and the QMP loop should
+// exit gracefully.
+func TestQMPBlockdevAddWithOptions(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("blockdev-add", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	q.version = checkVersion(t, connectedCh)
+	err := q.ExecuteBlockdevAddWithOptions(context.Background(), "/dev/rbd0",
+		fmt.Sprintf("drive_%s", testutil.VolumeUUID), true)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
+// Checks that a shared device_add command is correctly sent.
+//
+// We start a QMPLoop, send a shared device_add command and stop the loop.
+//
+// The device_add command should be correctly sent and the QMP loop should
+// exit gracefully.
+func TestQMPDeviceAddWithOptions(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("device_add", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	blockdevID := fmt.Sprintf("drive_%s", testutil.VolumeUUID)
+	devID := fmt.Sprintf("device_%s", testutil.VolumeUUID)
+	err := q.ExecuteDeviceAddWithOptions(context.Background(), blockdevID, devID,
+		"virtio-blk-pci", "", true)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
 // Checks that the x-blockdev-del command is correctly sent.
 //
 // We start a QMPLoop, send the x-blockdev-del command and stop the loop.
@@ -785,3 +834,174 @@ func TestQMPLostLoop(t *testing.T) {
 		t.Error("Expected executeQMPCapabilities to fail")
 	}
 }
+
+// Checks that the query-cpus command is correctly sent and that its
+// response is decoded.
+//
+// We start a QMPLoop, send the query-cpus command and stop the loop.
+//
+// The query-cpus command should be correctly sent and the information
+// about the two vCPUs returned by the test buffer should be decoded.
+func TestQMPQueryCpus(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("query-cpus", nil, "return", []interface{}{
+		map[string]interface{}{
+			"CPU":       0,
+			"current":   true,
+			"halted":    false,
+			"qom_path":  "/machine/unattached/device[0]",
+			"thread_id": 3134,
+		},
+		map[string]interface{}{
+			"CPU":       1,
+			"current":   false,
+			"halted":    true,
+			"qom_path":  "/machine/unattached/device[2]",
+			"thread_id": 3135,
+		},
+	})
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	cpus, err := q.ExecuteQueryCpus(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+
+	if len(cpus) != 2 {
+		t.Fatalf("Expected 2 vCPUs, found %d", len(cpus))
+	}
+
+	if cpus[0].CPU != 0 || !cpus[0].Current || cpus[0].ThreadID != 3134 {
+		t.Errorf("Unexpected information for vCPU 0: %+v", cpus[0])
+	}
+
+	if cpus[1].CPU != 1 || !cpus[1].Halted || cpus[1].ThreadID != 3135 ||
+		cpus[1].QOMPath != "/machine/unattached/device[2]" {
+		t.Errorf("Unexpected information for vCPU 1: %+v", cpus[1])
+	}
+}
+
+// Checks that the migrate, query-migrate and migrate_cancel commands are
+// correctly sent and that the migration status is decoded.
+//
+// We start a QMPLoop, send a migrate command followed by query-migrate and
+// migrate_cancel and stop the loop.
+//
+// All commands should be correctly sent and the status returned by the
+// test buffer should be decoded.
+func TestQMPMigrate(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("migrate", map[string]interface{}{
+		"uri": "exec:cat > /tmp/state",
+	}, "return", nil)
+	buf.AddCommand("query-migrate", nil, "return", map[string]interface{}{
+		"status": "completed",
+	})
+	buf.AddCommand("migrate_cancel", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	err := q.ExecuteMigrate(context.Background(), "exec:cat > /tmp/state")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	info, err := q.ExecuteQueryMigrate(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	err = q.ExecuteMigrateCancel(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+
+	if info.Status != "completed" {
+		t.Errorf("Unexpected migration status %s", info.Status)
+	}
+}
+
+// Checks that the block_set_io_throttle command is correctly sent.
+//
+// We start a QMPLoop, send the block_set_io_throttle command and stop the
+// loop.
+//
+// The command should be correctly sent and the QMP loop should exit.
+func TestQMPBlockSetIOThrottle(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("block_set_io_throttle", nil, "return", nil)
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	err := q.ExecuteBlockSetIOThrottle(context.Background(), "device_0", 10000000, 500)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+}
+
+// Checks that the query-blockstats command is correctly sent and its
+// response decoded.
+//
+// We start a QMPLoop, send the query-blockstats command and stop the loop.
+//
+// The command should be correctly sent and the counters of both drives
+// returned by the test buffer should be decoded.
+func TestQMPQueryBlockstats(t *testing.T) {
+	connectedCh := make(chan *QMPVersion)
+	disconnectedCh := make(chan struct{})
+	buf := newQMPTestCommandBuffer(t)
+	buf.AddCommand("query-blockstats", nil, "return", []interface{}{
+		map[string]interface{}{
+			"device": "drive_0",
+			"stats": map[string]interface{}{
+				"rd_bytes":      4096,
+				"wr_bytes":      8192,
+				"rd_operations": 1,
+				"wr_operations": 2,
+			},
+		},
+		map[string]interface{}{
+			"device": "",
+			"qdev":   "device_1",
+			"stats": map[string]interface{}{
+				"rd_bytes": 512,
+			},
+		},
+	})
+	cfg := QMPConfig{Logger: qmpTestLogger{}}
+	q := startQMPLoop(buf, cfg, connectedCh, disconnectedCh)
+	checkVersion(t, connectedCh)
+	stats, err := q.ExecuteQueryBlockstats(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	q.Shutdown()
+	<-disconnectedCh
+
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 drives, found %d", len(stats))
+	}
+	expected := BlockStatsCounters{
+		RdBytes:      4096,
+		WrBytes:      8192,
+		RdOperations: 1,
+		WrOperations: 2,
+	}
+	if stats[0].Device != "drive_0" || stats[0].Stats != expected {
+		t.Errorf("Unexpected stats for first drive %+v", stats[0])
+	}
+	if stats[1].QDev != "device_1" || stats[1].Stats.RdBytes != 512 {
+		t.Errorf("Unexpected stats for second drive %+v", stats[1])
+	}
+}
@@ -42,7 +42,10 @@ type Type uint8
 
 // Command is the SSNTP Command operand.
 // It can be CONNECT, START, STOP, STATS, EVACUATE, DELETE, RESTART,
-// AssignPublicIP, ReleasePublicIP, CONFIGURE or AttachVolume.
+// AssignPublicIP, ReleasePublicIP, CONFIGURE, AttachVolume, DetachVolume,
+// GetConsoleLog, OpenConsole, PauseInstance, UnpauseInstance, SuspendInstance,
+// ResumeInstance, UpdateDNS, UpdateConcentrator, ConfigureLoadBalancer,
+// RemoveLoadBalancer, UpdateMetadata or UpdatePeering.
 type Command uint8
 
 // Status is the SSNTP Status operand.
@@ -56,13 +59,17 @@ type Role uint32
 // Error is the SSNTP Error operand.
 // It can be InvalidFrameType Error, StartFailure,
 // StopFailure, ConnectionFailure, RestartFailure,
-// DeleteFailure, ConnectionAborted or InvalidConfiguration.
+// DeleteFailure, ConnectionAborted, InvalidConfiguration,
+// AttachVolumeFailure, DetachVolumeFailure, ConsoleLogFailure,
+// OpenConsoleFailure, PauseFailure, UnpauseFailure, SuspendFailure or
+// ResumeFailure.
 type Error uint8
 
 // Event is the SSNTP Event operand.
 // It can be TenantAdded, TenantRemoval, InstanceDeleted, InstanceStopped,
 // ConcentratorInstanceAdded, PublicIPAssigned, PublicIPUnassigned, TraceReport,
-// NodeConnected or NodeDisconnected
+// NodeConnected, NodeDisconnected, VolumeDetached, ConsoleLog, ConsoleOpened or
+// NodeInventory
 type Event uint8
 
 const (
@@ -229,6 +236,193 @@ const (
 	//	|       |       | (0x0) |  (0x4)  |                 |                             |
 	//	+---------------------------------------------------------------------------------+
 	Restore
+
+	// DetachVolume is a command sent to ciao-launcher for detaching a storage volume
+	// from a specific running or exited instance.
+	//
+	// The DetachVolume command payload includes a volume UUID and an instance UUID.
+	//
+	//                                       SSNTP DetachVolume Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xa)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	DetachVolume
+
+	// GetConsoleLog is a command sent to ciao-launcher to retrieve the tail of
+	// the serial console log of an instance.  The launcher replies with a
+	// ConsoleLog event or a ConsoleLogFailure error.
+	//
+	// The GetConsoleLog command payload includes an instance UUID and the
+	// maximum number of lines to return.
+	//
+	//                                       SSNTP GetConsoleLog Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xb)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	GetConsoleLog
+
+	// OpenConsole is a command sent to ciao-launcher to open an interactive
+	// session on the serial console of an instance.  The launcher replies with
+	// a ConsoleOpened event or an OpenConsoleFailure error.
+	//
+	// The OpenConsole command payload includes an instance UUID and the token
+	// that the client connecting to the console session must present.
+	//
+	//                                       SSNTP OpenConsole Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xc)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	OpenConsole
+
+	// PauseInstance is a command sent to ciao-launcher to freeze the execution
+	// of a running instance without shutting it down.  The instance keeps all
+	// its resources on the node.  A PauseFailure error is returned if the
+	// instance cannot be paused.
+	//
+	// The PauseInstance command payload includes an instance UUID.
+	//
+	//                                       SSNTP PauseInstance Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xd)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	PauseInstance
+
+	// UnpauseInstance is a command sent to ciao-launcher to resume the
+	// execution of an instance that was previously paused.  An UnpauseFailure
+	// error is returned if the instance cannot be unpaused.
+	//
+	// The UnpauseInstance command payload includes an instance UUID.
+	//
+	//                                       SSNTP UnpauseInstance Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xe)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	UnpauseInstance
+
+	// SuspendInstance is a command sent to ciao-launcher to save the state of
+	// a running VM to the instance directory and then shut the VM down.  A
+	// SuspendFailure error is returned if the instance cannot be suspended.
+	//
+	// The SuspendInstance command payload includes an instance UUID.
+	//
+	//                                       SSNTP SuspendInstance Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0xf)  |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	SuspendInstance
+
+	// ResumeInstance is a command sent to ciao-launcher to restart a suspended
+	// VM from the state saved by a previous SuspendInstance command.  A
+	// ResumeFailure error is returned if the instance cannot be resumed.
+	//
+	// The ResumeInstance command payload includes an instance UUID.
+	//
+	//                                       SSNTP ResumeInstance Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x10) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	ResumeInstance
+
+	// UpdateDNS is a command sent by the Controller to update the internal
+	// DNS records served by a CNCI for one of its tenant subnets. It is sent
+	// to the Scheduler and must be forwarded to the right CNCI.
+	//
+	// The UpdateDNS YAML payload schema is made of the CNCI and tenant
+	// UUIDs, the tenant subnet, the tenant DNS domain and upstream
+	// forwarders and the complete set of instance name records for
+	// the subnet.
+	//
+	//                                           SSNTP UpdateDNS Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x11) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	UpdateDNS
+
+	// UpdateConcentrator is a command sent by the Controller when a standby
+	// CNCI takes over a tenant subnet from a failed CNCI. It is sent to the
+	// Scheduler and must be forwarded to a CN agent running instances attached
+	// to the subnet. The agent re-points the subnet tunnel to the new CNCI.
+	//
+	// The UpdateConcentrator YAML payload schema is made of the CN agent and
+	// tenant UUIDs, the tenant subnet and the UUID and IP address of the CNCI
+	// now serving the subnet.
+	//
+	//                                      SSNTP UpdateConcentrator Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x12) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	UpdateConcentrator
+
+	// ConfigureLoadBalancer is a command sent by the Controller to create
+	// or update a tenant load balancer. It is sent to the Scheduler and
+	// must be forwarded to the CNCI serving the subnet of the load balancer
+	// members.
+	//
+	// The ConfigureLoadBalancer YAML payload schema is made of the CNCI,
+	// tenant and load balancer UUIDs, the virtual IP, listener port and
+	// protocol of the load balancer, its members and its health check.
+	//
+	//                                   SSNTP ConfigureLoadBalancer Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x13) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	ConfigureLoadBalancer
+
+	// RemoveLoadBalancer is a command sent by the Controller to delete a
+	// tenant load balancer. It is sent to the Scheduler and must be
+	// forwarded to the CNCI serving the load balancer.
+	//
+	// The RemoveLoadBalancer YAML payload schema is made of the CNCI,
+	// tenant and load balancer UUIDs and the virtual IP of the load
+	// balancer.
+	//
+	//                                      SSNTP RemoveLoadBalancer Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x14) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	RemoveLoadBalancer
+
+	// UpdateMetadata is a command sent by the Controller to update the
+	// metadata the CNCI serves to the instances of a tenant subnet. It is
+	// sent to the Scheduler and must be forwarded to the CNCI serving the
+	// subnet.
+	//
+	// The UpdateMetadata YAML payload schema is made of the CNCI and
+	// tenant UUIDs, the tenant subnet and the complete set of metadata
+	// of the instances attached to the subnet.
+	//
+	//                                          SSNTP UpdateMetadata Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x15) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	UpdateMetadata
+
+	// UpdatePeering is a command sent by the Controller to update the
+	// subnets of other tenants peered with a tenant subnet. It is sent to
+	// the Scheduler and must be forwarded to the CNCI serving the subnet.
+	//
+	// The UpdatePeering YAML payload schema is made of the CNCI and
+	// tenant UUIDs, the tenant subnet and the complete set of peer
+	// subnets along with the addresses of the CNCIs serving them.
+	//
+	//                                          SSNTP UpdatePeering Command frame
+	//	+-----------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted payload  |
+	//	|       |       | (0x0) |  (0x16) |                 |                         |
+	//	+-----------------------------------------------------------------------------+
+	UpdatePeering
 )
 
 const (
@@ -443,6 +637,54 @@ const (
 	//	|       |       | (0x3) |  (0x2)  |                 | instance information  |
 	//	+---------------------------------------------------------------------------+
 	InstanceStopped
+
+	// VolumeDetached is sent by workload agents to notify the scheduler and the Controller that
+	// a volume has been successfully detached from an instance.  The Controller uses this event
+	// to mark the volume as available once more.
+	//
+	//					 SSNTP VolumeDetached Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xa)  |                 | volume information    |
+	//	+---------------------------------------------------------------------------+
+	VolumeDetached
+
+	// ConsoleLog is sent by workload agents in response to a GetConsoleLog command.
+	// Its payload contains the tail of the serial console log of an instance.
+	//
+	//					 SSNTP ConsoleLog Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xb)  |                 | console log           |
+	//	+---------------------------------------------------------------------------+
+	ConsoleLog
+
+	// ConsoleOpened is sent by workload agents in response to an OpenConsole command.
+	// Its payload contains the address on which the console session is waiting for
+	// a connection.
+	//
+	//					 SSNTP ConsoleOpened Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xc)  |                 | console information   |
+	//	+---------------------------------------------------------------------------+
+	ConsoleOpened
+
+	// NodeInventory is sent by workload agents each time they (re)connect to
+	// the scheduler.  Its payload lists every instance hosted by the node
+	// together with its state, allowing the Controller to reconcile its view
+	// of the node with reality.
+	//
+	//					 SSNTP NodeInventory Event frame
+	//
+	//	+---------------------------------------------------------------------------+
+	//	| Major | Minor | Type  | Operand |  Payload Length | YAML formatted        |
+	//	|       |       | (0x3) |  (0xd)  |                 | instance inventory    |
+	//	+---------------------------------------------------------------------------+
+	NodeInventory
 )
 
 // SSNTP clients and servers can have one or several roles and are expected to declare their
@@ -534,6 +776,34 @@ const (
 	// UnassignPublicIPFailure is sent by the CNCI when a an external IP
 	// cannot be unassigned.
 	UnassignPublicIPFailure
+
+	// DetachVolumeFailure is sent by launcher agents to report a failure to detach
+	// a volume from an instance.
+	DetachVolumeFailure
+
+	// ConsoleLogFailure is sent by launcher agents to report a failure to
+	// retrieve the console log of an instance.
+	ConsoleLogFailure
+
+	// OpenConsoleFailure is sent by launcher agents to report a failure to
+	// open an interactive console session for an instance.
+	OpenConsoleFailure
+
+	// PauseFailure is sent by launcher agents to report a failure to pause
+	// an instance.
+	PauseFailure
+
+	// UnpauseFailure is sent by launcher agents to report a failure to
+	// unpause an instance.
+	UnpauseFailure
+
+	// SuspendFailure is sent by launcher agents to report a failure to
+	// suspend an instance.
+	SuspendFailure
+
+	// ResumeFailure is sent by launcher agents to report a failure to
+	// resume a suspended instance.
+	ResumeFailure
 )
 
 // Major is the SSNTP protocol major version
@@ -585,6 +855,32 @@ func (command Command) String() string {
 		return "Attach storage volume"
 	case Restore:
 		return "Restore"
+	case DetachVolume:
+		return "Detach storage volume"
+	case GetConsoleLog:
+		return "Get console log"
+	case OpenConsole:
+		return "Open console"
+	case PauseInstance:
+		return "Pause instance"
+	case UnpauseInstance:
+		return "Unpause instance"
+	case SuspendInstance:
+		return "Suspend instance"
+	case ResumeInstance:
+		return "Resume instance"
+	case UpdateDNS:
+		return "Update DNS"
+	case UpdateConcentrator:
+		return "Update concentrator"
+	case ConfigureLoadBalancer:
+		return "Configure load balancer"
+	case RemoveLoadBalancer:
+		return "Remove load balancer"
+	case UpdateMetadata:
+		return "Update metadata"
+	case UpdatePeering:
+		return "Update peering"
 	}
 
 	return ""
@@ -629,6 +925,14 @@ func (status Event) String() string {
 		return "Node Connected"
 	case NodeDisconnected:
 		return "Node Disconnected"
+	case VolumeDetached:
+		return "Volume Detached"
+	case ConsoleLog:
+		return "Console Log"
+	case ConsoleOpened:
+		return "Console Opened"
+	case NodeInventory:
+		return "Node Inventory"
 	}
 
 	return ""
@@ -652,6 +956,20 @@ func (error Error) String() string {
 		return "SSNTP Connection aborted"
 	case InvalidConfiguration:
 		return "Cluster configuration is invalid"
+	case DetachVolumeFailure:
+		return "Could not detach storage volume"
+	case ConsoleLogFailure:
+		return "Could not retrieve console log"
+	case OpenConsoleFailure:
+		return "Could not open console"
+	case PauseFailure:
+		return "Could not pause instance"
+	case UnpauseFailure:
+		return "Could not unpause instance"
+	case SuspendFailure:
+		return "Could not suspend instance"
+	case ResumeFailure:
+		return "Could not resume instance"
 	}
 
 	return ""
@@ -994,6 +1312,78 @@ func prepareTLS(caPEM, certPEM []byte, server bool, rand io.Reader) *tls.Config
 	}
 }
 
+// PeerTLSConfig returns a TLS configuration with which an SSNTP entity can
+// exchange data with another SSNTP entity outside of an SSNTP connection,
+// e.g., over the console sessions that ciao-launcher opens for
+// ciao-controller.  The entity authenticates itself with the certificate of
+// config and the peer must present a certificate signed by the CA of config
+// with one of the roles in peerRoles.  The certificates of agents do not
+// name the hosts on which they run so the host name of a server is not
+// verified.
+func PeerTLSConfig(config *Config, server bool, peerRoles Role) (*tls.Config, error) {
+	caPEM, err := ioutil.ReadFile(config.CAcert)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load CA certificate: %v", err)
+	}
+
+	certPEM, err := ioutil.ReadFile(config.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load certificate: %v", err)
+	}
+
+	tlsConfig := prepareTLS(caPEM, certPEM, server, config.Rand)
+	if tlsConfig == nil {
+		return nil, fmt.Errorf("Invalid certificates %s %s", config.CAcert, config.Cert)
+	}
+
+	if !server {
+		// The server's certificate is verified by
+		// verifyPeerCertificate instead.
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	roots := tlsConfig.RootCAs
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyPeerCertificate(rawCerts, roots, peerRoles)
+	}
+
+	return tlsConfig, nil
+}
+
+func verifyPeerCertificate(rawCerts [][]byte, roots *x509.CertPool, peerRoles Role) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("No certificate presented")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := certs[0].Verify(opts); err != nil {
+		return err
+	}
+
+	if GetRoleFromOIDs(certs[0].UnknownExtKeyUsage)&peerRoles == 0 {
+		return fmt.Errorf("Wrong certificate or missing/mismatched role OID")
+	}
+
+	return nil
+}
+
 var roleOID = []struct {
 	role Role
 	oid  asn1.ObjectIdentifier
@@ -18,6 +18,7 @@ package ssntp_test
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/asn1"
 	"flag"
 	"fmt"
@@ -2477,6 +2478,19 @@ func TestCommandStringer(t *testing.T) {
 		{ReleasePublicIP, "Release public IP"},
 		{CONFIGURE, "CONFIGURE"},
 		{AttachVolume, "Attach storage volume"},
+		{DetachVolume, "Detach storage volume"},
+		{GetConsoleLog, "Get console log"},
+		{OpenConsole, "Open console"},
+		{PauseInstance, "Pause instance"},
+		{UnpauseInstance, "Unpause instance"},
+		{SuspendInstance, "Suspend instance"},
+		{ResumeInstance, "Resume instance"},
+		{UpdateDNS, "Update DNS"},
+		{UpdateConcentrator, "Update concentrator"},
+		{ConfigureLoadBalancer, "Configure load balancer"},
+		{RemoveLoadBalancer, "Remove load balancer"},
+		{UpdateMetadata, "Update metadata"},
+		{UpdatePeering, "Update peering"},
 	}
 
 	for _, test := range stringTests {
@@ -2520,6 +2534,10 @@ func TestEventStringer(t *testing.T) {
 		{TraceReport, "Trace Report"},
 		{NodeConnected, "Node Connected"},
 		{NodeDisconnected, "Node Disconnected"},
+		{VolumeDetached, "Volume Detached"},
+		{ConsoleLog, "Console Log"},
+		{ConsoleOpened, "Console Opened"},
+		{NodeInventory, "Node Inventory"},
 	}
 
 	for _, test := range stringTests {
@@ -2543,6 +2561,13 @@ func TestErrorStringer(t *testing.T) {
 		{DeleteFailure, "Could not delete instance"},
 		{ConnectionAborted, "SSNTP Connection aborted"},
 		{InvalidConfiguration, "Cluster configuration is invalid"},
+		{DetachVolumeFailure, "Could not detach storage volume"},
+		{ConsoleLogFailure, "Could not retrieve console log"},
+		{OpenConsoleFailure, "Could not open console"},
+		{PauseFailure, "Could not pause instance"},
+		{UnpauseFailure, "Could not unpause instance"},
+		{SuspendFailure, "Could not suspend instance"},
+		{ResumeFailure, "Could not resume instance"},
 	}
 
 	for _, test := range stringTests {
@@ -2811,3 +2836,77 @@ func Benchmark100Clients100Frames65kB1msDelay(b *testing.B) {
 func BenchmarkDefaultMultiClientsMultiFrames(b *testing.B) {
 	benchmarkMultiClients(b, *payloadSize, *clients, *frames, *delay)
 }
+
+func testPeerTLSConfig(t *testing.T, clientRole Role, valid bool) {
+	serverConfig, err := buildTestConfig(AGENT)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	clientConfig, err := buildTestConfig(clientRole)
+	if err != nil {
+		t.Fatalf("Could not build a test config")
+	}
+
+	serverTLS, err := PeerTLSConfig(serverConfig, true, Controller)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientTLS, err := PeerTLSConfig(clientConfig, false, AGENT|NETAGENT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+
+	errCh := make(chan error, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			errCh <- err
+			return
+		}
+		errCh <- conn.(*tls.Conn).Handshake()
+		_ = conn.Close()
+	}()
+
+	conn, err := tls.Dial("tcp", l.Addr().String(), clientTLS)
+	if err == nil {
+		err = conn.Handshake()
+		_ = conn.Close()
+	}
+	serverErr := <-errCh
+
+	if valid && (err != nil || serverErr != nil) {
+		t.Fatalf("Failed to connect: %v %v", err, serverErr)
+	}
+
+	if !valid && serverErr == nil {
+		t.Fatalf("Wrong certificate, connection should not be allowed")
+	}
+}
+
+// Test that PeerTLSConfig authenticates both ends of a connection.
+//
+// A TLS server expecting a Controller is started with an agent certificate
+// and a client connects to it with a controller certificate.
+//
+// Test is expected to pass.
+func TestPeerTLSConfigPositive(t *testing.T) {
+	testPeerTLSConfig(t, Controller, true)
+}
+
+// Test that PeerTLSConfig rejects peers with the wrong role.
+//
+// A TLS server expecting a Controller is started with an agent certificate
+// and a client connects to it with a scheduler certificate.
+//
+// Test is expected to pass.
+func TestPeerTLSConfigNegative(t *testing.T) {
+	testPeerTLSConfig(t, SCHEDULER, false)
+}
@@ -46,6 +46,15 @@ type SsntpTestClient struct {
 	DeleteFailReason       payloads.DeleteFailureReason
 	AttachFail             bool
 	AttachVolumeFailReason payloads.AttachVolumeFailureReason
+	DetachFail             bool
+	DetachVolumeFailReason payloads.DetachVolumeFailureReason
+	ConsoleLogFail         bool
+	ConsoleLogFailReason   payloads.ConsoleLogFailureReason
+	OpenConsoleFail        bool
+	OpenConsoleFailReason  payloads.OpenConsoleFailureReason
+	OpenConsoleAddress     string
+	PauseFail              bool
+	PauseFailReason        payloads.PauseFailureReason
 	traces                 []*ssntp.Frame
 	tracesLock             *sync.Mutex
 
@@ -423,6 +432,119 @@ func (client *SsntpTestClient) handleAttachVolume(payload []byte) Result {
 	return result
 }
 
+func (client *SsntpTestClient) handleDetachVolume(payload []byte) Result {
+	var result Result
+	var cmd payloads.DetachVolume
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if client.DetachFail == true {
+		result.Err = errors.New(client.DetachVolumeFailReason.String())
+		client.sendDetachVolumeFailure(cmd.Detach.InstanceUUID, cmd.Detach.VolumeUUID, client.DetachVolumeFailReason)
+		client.SendResultAndDelErrorChan(ssntp.DetachVolumeFailure, result)
+		return result
+	}
+
+	// update statistics for volume
+	client.instancesLock.Lock()
+	for i, istat := range client.instances {
+		if istat.InstanceUUID != cmd.Detach.InstanceUUID {
+			continue
+		}
+		var volumes []string
+		for _, v := range istat.Volumes {
+			if v != cmd.Detach.VolumeUUID {
+				volumes = append(volumes, v)
+			}
+		}
+		client.instances[i].Volumes = volumes
+	}
+	client.instancesLock.Unlock()
+
+	client.sendVolumeDetachedEvent(cmd.Detach.InstanceUUID, cmd.Detach.VolumeUUID)
+
+	return result
+}
+
+func (client *SsntpTestClient) handleGetConsoleLog(payload []byte) Result {
+	var result Result
+	var cmd payloads.GetConsoleLog
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if client.ConsoleLogFail == true {
+		result.Err = errors.New(client.ConsoleLogFailReason.String())
+		client.sendConsoleLogFailure(cmd.ConsoleLog.InstanceUUID, cmd.ConsoleLog.RequestID,
+			client.ConsoleLogFailReason)
+		client.SendResultAndDelErrorChan(ssntp.ConsoleLogFailure, result)
+		return result
+	}
+
+	client.sendConsoleLogEvent(cmd.ConsoleLog.InstanceUUID, cmd.ConsoleLog.RequestID)
+
+	return result
+}
+
+func (client *SsntpTestClient) handleOpenConsole(payload []byte) Result {
+	var result Result
+	var cmd payloads.OpenConsole
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if client.OpenConsoleFail == true {
+		result.Err = errors.New(client.OpenConsoleFailReason.String())
+		client.sendOpenConsoleFailure(cmd.Console.InstanceUUID, cmd.Console.Token,
+			client.OpenConsoleFailReason)
+		client.SendResultAndDelErrorChan(ssntp.OpenConsoleFailure, result)
+		return result
+	}
+
+	client.sendConsoleOpenedEvent(cmd.Console.InstanceUUID, cmd.Console.Token)
+
+	return result
+}
+
+var pauseFailures = map[ssntp.Command]ssntp.Error{
+	ssntp.PauseInstance:   ssntp.PauseFailure,
+	ssntp.UnpauseInstance: ssntp.UnpauseFailure,
+	ssntp.SuspendInstance: ssntp.SuspendFailure,
+	ssntp.ResumeInstance:  ssntp.ResumeFailure,
+}
+
+func (client *SsntpTestClient) handlePause(command ssntp.Command, payload []byte) Result {
+	var result Result
+
+	cmd, err := unmarshalPauseCmd(command, payload)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.InstanceUUID = cmd.InstanceUUID
+
+	if client.PauseFail == true {
+		failure := pauseFailures[command]
+		result.Err = errors.New(client.PauseFailReason.String())
+		client.sendPauseFailure(failure, cmd.InstanceUUID, client.PauseFailReason)
+		client.SendResultAndDelErrorChan(failure, result)
+		return result
+	}
+
+	return result
+}
+
 // CommandNotify implements the SSNTP client CommandNotify callback for SsntpTestClient
 func (client *SsntpTestClient) CommandNotify(command ssntp.Command, frame *ssntp.Frame) {
 	payload := frame.Payload
@@ -454,6 +576,19 @@ func (client *SsntpTestClient) CommandNotify(command ssntp.Command, frame *ssntp
 	case ssntp.AttachVolume:
 		result = client.handleAttachVolume(payload)
 
+	case ssntp.DetachVolume:
+		result = client.handleDetachVolume(payload)
+
+	case ssntp.GetConsoleLog:
+		result = client.handleGetConsoleLog(payload)
+
+	case ssntp.OpenConsole:
+		result = client.handleOpenConsole(payload)
+
+	case ssntp.PauseInstance, ssntp.UnpauseInstance, ssntp.SuspendInstance,
+		ssntp.ResumeInstance:
+		result = client.handlePause(command, payload)
+
 	default:
 		fmt.Fprintf(os.Stderr, "client %s unhandled command %s\n", client.Role.String(), command.String())
 	}
@@ -620,6 +755,30 @@ func (client *SsntpTestClient) SendStoppedEvent(uuid string) {
 	go client.SendResultAndDelEventChan(ssntp.InstanceStopped, result)
 }
 
+// SendNodeInventoryEvent allows an SsntpTestClient to push an ssntp.NodeInventory event frame
+func (client *SsntpTestClient) SendNodeInventoryEvent(instances []payloads.InstanceInventory) {
+	var result Result
+
+	event := payloads.EventNodeInventory{
+		Inventory: payloads.NodeInventoryEvent{
+			NodeUUID:  client.UUID,
+			Instances: instances,
+		},
+	}
+
+	y, err := yaml.Marshal(event)
+	if err != nil {
+		result.Err = err
+	} else {
+		_, err = client.Ssntp.SendEvent(ssntp.NodeInventory, y)
+		if err != nil {
+			result.Err = err
+		}
+	}
+
+	go client.SendResultAndDelEventChan(ssntp.NodeInventory, result)
+}
+
 // SendTenantAddedEvent allows an SsntpTestClient to push an ssntp.TenantAdded event frame
 func (client *SsntpTestClient) SendTenantAddedEvent() {
 	var result Result
@@ -748,3 +907,151 @@ func (client *SsntpTestClient) sendAttachVolumeFailure(instanceUUID string, volu
 		fmt.Fprintln(os.Stderr, err)
 	}
 }
+
+func (client *SsntpTestClient) sendDetachVolumeFailure(instanceUUID string, volumeUUID string, reason payloads.DetachVolumeFailureReason) {
+	e := payloads.ErrorDetachVolumeFailure{
+		InstanceUUID: instanceUUID,
+		VolumeUUID:   volumeUUID,
+		Reason:       reason,
+	}
+
+	y, err := yaml.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	_, err = client.Ssntp.SendError(ssntp.DetachVolumeFailure, y)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (client *SsntpTestClient) sendVolumeDetachedEvent(instanceUUID string, volumeUUID string) {
+	var result Result
+
+	evt := payloads.EventVolumeDetached{
+		VolumeDetached: payloads.VolumeDetachedEvent{
+			InstanceUUID: instanceUUID,
+			VolumeUUID:   volumeUUID,
+		},
+	}
+
+	y, err := yaml.Marshal(evt)
+	if err != nil {
+		result.Err = err
+	} else {
+		_, err = client.Ssntp.SendEvent(ssntp.VolumeDetached, y)
+		if err != nil {
+			result.Err = err
+		}
+	}
+
+	go client.SendResultAndDelEventChan(ssntp.VolumeDetached, result)
+}
+
+func (client *SsntpTestClient) sendConsoleLogFailure(instanceUUID, requestID string, reason payloads.ConsoleLogFailureReason) {
+	e := payloads.ErrorConsoleLogFailure{
+		InstanceUUID: instanceUUID,
+		Reason:       reason,
+		RequestID:    requestID,
+	}
+
+	y, err := yaml.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	_, err = client.Ssntp.SendError(ssntp.ConsoleLogFailure, y)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (client *SsntpTestClient) sendConsoleLogEvent(instanceUUID, requestID string) {
+	var result Result
+
+	evt := payloads.EventConsoleLog{
+		ConsoleLog: payloads.ConsoleLogEvent{
+			InstanceUUID: instanceUUID,
+			Log:          ConsoleLogOutput,
+			RequestID:    requestID,
+		},
+	}
+
+	y, err := yaml.Marshal(evt)
+	if err != nil {
+		result.Err = err
+	} else {
+		_, err = client.Ssntp.SendEvent(ssntp.ConsoleLog, y)
+		if err != nil {
+			result.Err = err
+		}
+	}
+
+	go client.SendResultAndDelEventChan(ssntp.ConsoleLog, result)
+}
+
+func (client *SsntpTestClient) sendOpenConsoleFailure(instanceUUID, token string, reason payloads.OpenConsoleFailureReason) {
+	e := payloads.ErrorOpenConsoleFailure{
+		InstanceUUID: instanceUUID,
+		Token:        token,
+		Reason:       reason,
+	}
+
+	y, err := yaml.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	_, err = client.Ssntp.SendError(ssntp.OpenConsoleFailure, y)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (client *SsntpTestClient) sendPauseFailure(failure ssntp.Error, instanceUUID string, reason payloads.PauseFailureReason) {
+	e := payloads.ErrorPauseFailure{
+		NodeUUID:     client.UUID,
+		InstanceUUID: instanceUUID,
+		Reason:       reason,
+	}
+
+	y, err := yaml.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	_, err = client.Ssntp.SendError(failure, y)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+func (client *SsntpTestClient) sendConsoleOpenedEvent(instanceUUID, token string) {
+	var result Result
+
+	address := client.OpenConsoleAddress
+	if address == "" {
+		address = ConsoleAddress
+	}
+
+	evt := payloads.EventConsoleOpened{
+		Console: payloads.ConsoleOpenedEvent{
+			InstanceUUID: instanceUUID,
+			Token:        token,
+			Address:      address,
+		},
+	}
+
+	y, err := yaml.Marshal(evt)
+	if err != nil {
+		result.Err = err
+	} else {
+		_, err = client.Ssntp.SendEvent(ssntp.ConsoleOpened, y)
+		if err != nil {
+			result.Err = err
+		}
+	}
+
+	go client.SendResultAndDelEventChan(ssntp.ConsoleOpened, result)
+}
@@ -388,6 +388,288 @@ func TestAttachVolumeFailure(t *testing.T) {
 	}
 }
 
+func doDetachVolume(fail bool) error {
+	agentCh := agent.AddCmdChan(ssntp.DetachVolume)
+	serverCh := server.AddCmdChan(ssntp.DetachVolume)
+
+	var serverErrorCh chan Result
+	var controllerErrorCh chan Result
+
+	if fail == true {
+		serverErrorCh = server.AddErrorChan(ssntp.DetachVolumeFailure)
+		controllerErrorCh = controller.AddErrorChan(ssntp.DetachVolumeFailure)
+		fmt.Fprintf(os.Stderr, "Expecting server and controller to note: \"%s\"\n", ssntp.DetachVolumeFailure)
+
+		agent.DetachFail = true
+		agent.DetachVolumeFailReason = payloads.DetachVolumeNotAttached
+
+		defer func() {
+			agent.DetachFail = false
+			agent.DetachVolumeFailReason = ""
+		}()
+	}
+
+	go controller.Ssntp.SendCommand(ssntp.DetachVolume, []byte(DetachVolumeYaml))
+	_, err := server.GetCmdChanResult(serverCh, ssntp.DetachVolume)
+	if err != nil { // server sees the DetachVolume on its way down to agent
+		return err
+	}
+
+	_, err = agent.GetCmdChanResult(agentCh, ssntp.DetachVolume)
+	if fail == false && err != nil { // agent unexpected fail
+		return err
+	}
+
+	if fail == true {
+		if err == nil { // agent unexpected success
+			return errors.New("Success when Failure expected")
+		}
+		_, err = server.GetErrorChanResult(serverErrorCh, ssntp.DetachVolumeFailure)
+		if err != nil {
+			return err
+		}
+		_, err = controller.GetErrorChanResult(controllerErrorCh, ssntp.DetachVolumeFailure)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func TestDetachVolume(t *testing.T) {
+	fail := false
+
+	err := doDetachVolume(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetachVolumeFailure(t *testing.T) {
+	fail := true
+
+	err := doDetachVolume(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func doGetConsoleLog(fail bool) error {
+	agentCh := agent.AddCmdChan(ssntp.GetConsoleLog)
+	serverCh := server.AddCmdChan(ssntp.GetConsoleLog)
+
+	var serverErrorCh chan Result
+	var controllerErrorCh chan Result
+	var controllerEventCh chan Result
+
+	if fail == true {
+		serverErrorCh = server.AddErrorChan(ssntp.ConsoleLogFailure)
+		controllerErrorCh = controller.AddErrorChan(ssntp.ConsoleLogFailure)
+		fmt.Fprintf(os.Stderr, "Expecting server and controller to note: \"%s\"\n", ssntp.ConsoleLogFailure)
+
+		agent.ConsoleLogFail = true
+		agent.ConsoleLogFailReason = payloads.ConsoleLogReadFailure
+
+		defer func() {
+			agent.ConsoleLogFail = false
+			agent.ConsoleLogFailReason = ""
+		}()
+	} else {
+		controllerEventCh = controller.AddEventChan(ssntp.ConsoleLog)
+	}
+
+	go controller.Ssntp.SendCommand(ssntp.GetConsoleLog, []byte(GetConsoleLogYaml))
+	_, err := server.GetCmdChanResult(serverCh, ssntp.GetConsoleLog)
+	if err != nil { // server sees the GetConsoleLog on its way down to agent
+		return err
+	}
+
+	_, err = agent.GetCmdChanResult(agentCh, ssntp.GetConsoleLog)
+	if fail == false {
+		if err != nil { // agent unexpected fail
+			return err
+		}
+		_, err = controller.GetEventChanResult(controllerEventCh, ssntp.ConsoleLog)
+		return err
+	}
+
+	if err == nil { // agent unexpected success
+		return errors.New("Success when Failure expected")
+	}
+	_, err = server.GetErrorChanResult(serverErrorCh, ssntp.ConsoleLogFailure)
+	if err != nil {
+		return err
+	}
+	_, err = controller.GetErrorChanResult(controllerErrorCh, ssntp.ConsoleLogFailure)
+
+	return err
+}
+
+func TestGetConsoleLog(t *testing.T) {
+	fail := false
+
+	err := doGetConsoleLog(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetConsoleLogFailure(t *testing.T) {
+	fail := true
+
+	err := doGetConsoleLog(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func doOpenConsole(fail bool) error {
+	agentCh := agent.AddCmdChan(ssntp.OpenConsole)
+	serverCh := server.AddCmdChan(ssntp.OpenConsole)
+
+	var serverErrorCh chan Result
+	var controllerErrorCh chan Result
+	var controllerEventCh chan Result
+
+	if fail == true {
+		serverErrorCh = server.AddErrorChan(ssntp.OpenConsoleFailure)
+		controllerErrorCh = controller.AddErrorChan(ssntp.OpenConsoleFailure)
+		fmt.Fprintf(os.Stderr, "Expecting server and controller to note: \"%s\"\n", ssntp.OpenConsoleFailure)
+
+		agent.OpenConsoleFail = true
+		agent.OpenConsoleFailReason = payloads.OpenConsoleAttachFailure
+
+		defer func() {
+			agent.OpenConsoleFail = false
+			agent.OpenConsoleFailReason = ""
+		}()
+	} else {
+		controllerEventCh = controller.AddEventChan(ssntp.ConsoleOpened)
+	}
+
+	go controller.Ssntp.SendCommand(ssntp.OpenConsole, []byte(OpenConsoleYaml))
+	_, err := server.GetCmdChanResult(serverCh, ssntp.OpenConsole)
+	if err != nil { // server sees the OpenConsole on its way down to agent
+		return err
+	}
+
+	_, err = agent.GetCmdChanResult(agentCh, ssntp.OpenConsole)
+	if fail == false {
+		if err != nil { // agent unexpected fail
+			return err
+		}
+		_, err = controller.GetEventChanResult(controllerEventCh, ssntp.ConsoleOpened)
+		return err
+	}
+
+	if err == nil { // agent unexpected success
+		return errors.New("Success when Failure expected")
+	}
+	_, err = server.GetErrorChanResult(serverErrorCh, ssntp.OpenConsoleFailure)
+	if err != nil {
+		return err
+	}
+	_, err = controller.GetErrorChanResult(controllerErrorCh, ssntp.OpenConsoleFailure)
+
+	return err
+}
+
+func TestOpenConsole(t *testing.T) {
+	fail := false
+
+	err := doOpenConsole(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenConsoleFailure(t *testing.T) {
+	fail := true
+
+	err := doOpenConsole(fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func doPause(command ssntp.Command, failure ssntp.Error, payload string, fail bool) error {
+	agentCh := agent.AddCmdChan(command)
+	serverCh := server.AddCmdChan(command)
+
+	var serverErrorCh chan Result
+	var controllerErrorCh chan Result
+
+	if fail == true {
+		serverErrorCh = server.AddErrorChan(failure)
+		controllerErrorCh = controller.AddErrorChan(failure)
+		fmt.Fprintf(os.Stderr, "Expecting server and controller to note: \"%s\"\n", failure)
+
+		agent.PauseFail = true
+		agent.PauseFailReason = payloads.PauseInvalidState
+
+		defer func() {
+			agent.PauseFail = false
+			agent.PauseFailReason = ""
+		}()
+	}
+
+	go controller.Ssntp.SendCommand(command, []byte(payload))
+	_, err := server.GetCmdChanResult(serverCh, command)
+	if err != nil { // server sees the command on its way down to agent
+		return err
+	}
+
+	_, err = agent.GetCmdChanResult(agentCh, command)
+	if fail == false {
+		return err
+	}
+
+	if err == nil { // agent unexpected success
+		return errors.New("Success when Failure expected")
+	}
+	_, err = server.GetErrorChanResult(serverErrorCh, failure)
+	if err != nil {
+		return err
+	}
+	_, err = controller.GetErrorChanResult(controllerErrorCh, failure)
+
+	return err
+}
+
+var pauseTests = []struct {
+	command ssntp.Command
+	failure ssntp.Error
+	payload string
+}{
+	{ssntp.PauseInstance, ssntp.PauseFailure, PauseYaml},
+	{ssntp.UnpauseInstance, ssntp.UnpauseFailure, UnpauseYaml},
+	{ssntp.SuspendInstance, ssntp.SuspendFailure, SuspendYaml},
+	{ssntp.ResumeInstance, ssntp.ResumeFailure, ResumeYaml},
+}
+
+func TestPause(t *testing.T) {
+	fail := false
+
+	for _, test := range pauseTests {
+		err := doPause(test.command, test.failure, test.payload, fail)
+		if err != nil {
+			t.Fatalf("%s: %v", test.command, err)
+		}
+	}
+}
+
+func TestPauseFailure(t *testing.T) {
+	fail := true
+
+	for _, test := range pauseTests {
+		err := doPause(test.command, test.failure, test.payload, fail)
+		if err != nil {
+			t.Fatalf("%s: %v", test.command, err)
+		}
+	}
+}
+
 func TestTenantAdded(t *testing.T) {
 	serverCh := server.AddEventChan(ssntp.TenantAdded)
 	cnciAgentCh := cnciAgent.AddEventChan(ssntp.TenantAdded)
@@ -516,6 +798,29 @@ func TestPublicIPAssigned(t *testing.T) {
 	}
 }
 
+func TestNodeInventory(t *testing.T) {
+	serverCh := server.AddEventChan(ssntp.NodeInventory)
+	controllerCh := controller.AddEventChan(ssntp.NodeInventory)
+
+	instances := []payloads.InstanceInventory{
+		{
+			InstanceUUID: InstanceUUID,
+			TenantUUID:   TenantUUID,
+			State:        payloads.Running,
+		},
+	}
+	go agent.SendNodeInventoryEvent(instances)
+
+	_, err := server.GetEventChanResult(serverCh, ssntp.NodeInventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = controller.GetEventChanResult(controllerCh, ssntp.NodeInventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestMain(m *testing.M) {
 	var err error
 
@@ -323,6 +323,27 @@ func (ctl *SsntpTestController) EventNotify(event ssntp.Event, frame *ssntp.Fram
 		if err != nil {
 			result.Err = err
 		}
+	case ssntp.ConsoleLog:
+		var consoleLogEvent payloads.EventConsoleLog
+
+		err := yaml.Unmarshal(frame.Payload, &consoleLogEvent)
+		if err != nil {
+			result.Err = err
+		}
+	case ssntp.ConsoleOpened:
+		var consoleOpenedEvent payloads.EventConsoleOpened
+
+		err := yaml.Unmarshal(frame.Payload, &consoleOpenedEvent)
+		if err != nil {
+			result.Err = err
+		}
+	case ssntp.NodeInventory:
+		var inventoryEvent payloads.EventNodeInventory
+
+		err := yaml.Unmarshal(frame.Payload, &inventoryEvent)
+		if err != nil {
+			result.Err = err
+		}
 	default:
 		fmt.Fprintf(os.Stderr, "controller unhandled event: %s\n", event.String())
 	}
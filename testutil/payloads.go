@@ -21,7 +21,7 @@ import "github.com/ciao-project/ciao/payloads"
 // AgentIP is a test agent IP address
 const AgentIP = "10.2.3.4"
 
-//TenantSubnet is a test tenant subnet
+// TenantSubnet is a test tenant subnet
 const TenantSubnet = "10.2.0.0/16"
 
 // SubnetKey is a test tenant subnet key
@@ -286,6 +286,15 @@ const MigrateYaml = `delete:
   stop: true
 `
 
+// GracefulDeleteYaml is a sample workload DELETE ssntp.Command payload for
+// test cases that gives the instance 60 seconds to shut down cleanly.
+const GracefulDeleteYaml = `delete:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  stop: false
+  graceful_timeout: 60
+`
+
 // EvacuateYaml is a sample node EVACUATE ssntp.Command payload for test cases
 const EvacuateYaml = `evacuate:
   workload_agent_uuid: ` + AgentUUID + `
@@ -324,6 +333,115 @@ const ReleaseIPYaml = `release_public_ip:
   vnic_mac: ` + VNICMAC + `
 `
 
+// InstanceName is a test instance name
+const InstanceName = "test-instance"
+
+// DNSDomain is a test tenant DNS domain
+const DNSDomain = "tenant.ciao"
+
+// DNSForwarder is a test upstream DNS server
+const DNSForwarder = "8.8.8.8"
+
+// UpdateDNSYaml is a sample UpdateDNS ssntp.Command payload for test cases
+const UpdateDNSYaml = `update_dns:
+  concentrator_uuid: ` + CNCIUUID + `
+  tenant_uuid: ` + TenantUUID + `
+  tenant_subnet: ` + TenantSubnet + `
+  domain: ` + DNSDomain + `
+  forwarders:
+  - ` + DNSForwarder + `
+  records:
+  - name: ` + InstanceName + `
+    ip: ` + InstancePrivateIP + `
+`
+
+// UpdateConcentratorYaml is a sample UpdateConcentrator ssntp.Command payload for test cases
+const UpdateConcentratorYaml = `update_concentrator:
+  agent_uuid: ` + AgentUUID + `
+  tenant_uuid: ` + TenantUUID + `
+  tenant_subnet: ` + TenantSubnet + `
+  concentrator_uuid: ` + CNCIUUID + `
+  concentrator_ip: ` + CNCIIP + `
+`
+
+// LoadBalancerUUID is a test load balancer UUID
+const LoadBalancerUUID = "0f3f5ed6-6f47-4b5e-a0c3-9d1c8f1e5b27"
+
+// ConfigureLoadBalancerYaml is a sample ConfigureLoadBalancer ssntp.Command payload for test cases
+const ConfigureLoadBalancerYaml = `configure_load_balancer:
+  concentrator_uuid: ` + CNCIUUID + `
+  tenant_uuid: ` + TenantUUID + `
+  load_balancer_uuid: ` + LoadBalancerUUID + `
+  vip: ` + InstancePublicIP + `
+  port: 80
+  protocol: http
+  members:
+  - ip: ` + InstancePrivateIP + `
+    port: 8080
+  health_check:
+    interval: 5
+    timeout: 2
+    retries: 3
+    path: /
+`
+
+// RemoveLoadBalancerYaml is a sample RemoveLoadBalancer ssntp.Command payload for test cases
+const RemoveLoadBalancerYaml = `remove_load_balancer:
+  concentrator_uuid: ` + CNCIUUID + `
+  tenant_uuid: ` + TenantUUID + `
+  load_balancer_uuid: ` + LoadBalancerUUID + `
+  vip: ` + InstancePublicIP + `
+`
+
+// InstanceGateway is a test instance default gateway
+const InstanceGateway = "192.168.1.1"
+
+// InstanceSSHKey is a test instance SSH public key
+const InstanceSSHKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ test@ciao"
+
+// UpdateMetadataYaml is a sample UpdateMetadata ssntp.Command payload for test cases
+const UpdateMetadataYaml = `update_metadata:
+  concentrator_uuid: ` + CNCIUUID + `
+  tenant_uuid: ` + TenantUUID + `
+  tenant_subnet: ` + TenantSubnet + `
+  instances:
+  - instance_uuid: ` + InstanceUUID + `
+    name: ` + InstanceName + `
+    hostname: ` + InstanceName + `
+    nics:
+    - mac: ` + VNICMAC + `
+      ip: ` + InstancePrivateIP + `
+      subnet: ` + TenantSubnet + `
+      gateway: ` + InstanceGateway + `
+    public_keys:
+    - ` + InstanceSSHKey + `
+    metadata:
+      role: web
+    user_data: |
+      #cloud-config
+      hostname: ` + InstanceName + `
+`
+
+// PeeringUUID is a test network peering UUID
+const PeeringUUID = "7c7f3e3a-5d51-4b0e-a3bd-6bbf6e9e2c1d"
+
+// PeerSubnet is a test peer tenant subnet
+const PeerSubnet = "172.16.0.0/24"
+
+// PeerCNCIIP is a test IP address of the CNCI serving a peer subnet
+const PeerCNCIIP = "10.1.2.4"
+
+// UpdatePeeringYaml is a sample UpdatePeering ssntp.Command payload for test cases
+const UpdatePeeringYaml = `update_peering:
+  concentrator_uuid: ` + CNCIUUID + `
+  tenant_uuid: ` + TenantUUID + `
+  tenant_subnet: ` + TenantSubnet + `
+  peers:
+  - peering_uuid: ` + PeeringUUID + `
+    subnet: ` + PeerSubnet + `
+    concentrator_ip: ` + PeerCNCIIP + `
+`
+
 // AssignedIPYaml is a sample PublicIPAssigned ssntp.Event payload for test cases
 const AssignedIPYaml = `public_ip_assigned:
   concentrator_uuid: ` + CNCIUUID + `
@@ -405,6 +523,7 @@ const InsDelYaml = `instance_deleted:
 // InsStopYaml is a sample workload InstanceStopped ssntp.Event payload for test cases
 const InsStopYaml = `instance_stopped:
   instance_uuid: ` + InstanceUUID + `
+  stop_method: graceful
 `
 
 // NodeConnectedYaml is a sample node NodeConnected ssntp.Event payload for test cases
@@ -413,18 +532,34 @@ const NodeConnectedYaml = `node_connected:
   node_type: ` + payloads.NetworkNode + `
 `
 
+// NUMANodeStats is a sample list of payloads.NUMANodeStat describing a node
+// with a single NUMA node
+var NUMANodeStats = []payloads.NUMANodeStat{
+	{
+		NodeID:                 0,
+		DedicatedCpusAvailable: 2,
+		HugePagesAvailableMB:   1024,
+	},
+}
+
 // ReadyPayload is a helper to craft a mostly fixed ssntp.READY status
 // payload, with parameters to specify the source node uuid and available resources
 func ReadyPayload(uuid string, memTotal int, memAvail int, networks []payloads.NetworkStat) payloads.Ready {
 	p := payloads.Ready{
-		NodeUUID:        uuid,
-		MemTotalMB:      memTotal,
-		MemAvailableMB:  memAvail,
-		DiskTotalMB:     500000,
-		DiskAvailableMB: 256000,
-		Load:            0,
-		CpusOnline:      4,
-		Networks:        networks,
+		NodeUUID:               uuid,
+		MemTotalMB:             memTotal,
+		MemAvailableMB:         memAvail,
+		DiskTotalMB:            500000,
+		DiskAvailableMB:        256000,
+		DiskReservedMB:         20000,
+		DiskUsedMB:             4000,
+		Load:                   0,
+		CpusOnline:             4,
+		DedicatedCpusAvailable: 2,
+		HugePagesTotalMB:       2048,
+		HugePagesAvailableMB:   1024,
+		NUMANodes:              NUMANodeStats,
+		Networks:               networks,
 	}
 	return p
 }
@@ -435,8 +570,17 @@ mem_total_mb: 3896
 mem_available_mb: 3896
 disk_total_mb: 500000
 disk_available_mb: 256000
+disk_reserved_mb: 20000
+disk_used_mb: 4000
 load: 0
 cpus_online: 4
+dedicated_cpus_available: 2
+hugepages_total_mb: 2048
+hugepages_available_mb: 1024
+numa_nodes:
+- node_id: 0
+  dedicated_cpus_available: 2
+  hugepages_available_mb: 1024
 networks:
 - ip: 192.168.1.1
   mac: 02:00:15:03:6f:49
@@ -469,6 +613,26 @@ var InstanceStat002 = payloads.InstanceStat{
 	CPUUsage:      0,
 	SSHIP:         "172.168.2.2",
 	SSHPort:       8768,
+	Guest: &payloads.GuestInfo{
+		OSName:        "Ubuntu 16.04.2 LTS",
+		OSVersion:     "16.04.2 LTS (Xenial Xerus)",
+		KernelRelease: "4.4.0-62-generic",
+		IPAddresses:   []string{"192.168.8.2"},
+	},
+	BlockIO: &payloads.BlockIOStat{
+		ReadBytes:  104857600,
+		WriteBytes: 52428800,
+		ReadOps:    2560,
+		WriteOps:   1280,
+	},
+	NetworkIO: &payloads.VnicIOStat{
+		RxBytes:   1048576,
+		RxPackets: 1024,
+		RxDropped: 2,
+		TxBytes:   524288,
+		TxPackets: 512,
+		TxDropped: 1,
+	},
 }
 
 // InstanceStat003 is a sample payloads.InstanceStat
@@ -500,17 +664,23 @@ var NetworkStat002 = payloads.NetworkStat{
 // return a payloads.Stat matching the StatsYaml string.
 func StatsPayload(uuid string, name string, instances []payloads.InstanceStat, networks []payloads.NetworkStat) payloads.Stat {
 	p := payloads.Stat{
-		NodeUUID:        uuid,
-		Status:          "READY",
-		MemTotalMB:      3896,
-		MemAvailableMB:  3896,
-		DiskTotalMB:     500000,
-		DiskAvailableMB: 256000,
-		Load:            0,
-		CpusOnline:      4,
-		NodeHostName:    name,
-		Instances:       instances,
-		Networks:        networks,
+		NodeUUID:               uuid,
+		Status:                 "READY",
+		MemTotalMB:             3896,
+		MemAvailableMB:         3896,
+		DiskTotalMB:            500000,
+		DiskAvailableMB:        256000,
+		DiskReservedMB:         20000,
+		DiskUsedMB:             4000,
+		Load:                   0,
+		CpusOnline:             4,
+		DedicatedCpusAvailable: 2,
+		HugePagesTotalMB:       2048,
+		HugePagesAvailableMB:   1024,
+		NUMANodes:              NUMANodeStats,
+		NodeHostName:           name,
+		Instances:              instances,
+		Networks:               networks,
 	}
 
 	return p
@@ -523,8 +693,17 @@ mem_total_mb: 3896
 mem_available_mb: 3896
 disk_total_mb: 500000
 disk_available_mb: 256000
+disk_reserved_mb: 20000
+disk_used_mb: 4000
 load: 0
 cpus_online: 4
+dedicated_cpus_available: 2
+hugepages_total_mb: 2048
+hugepages_available_mb: 1024
+numa_nodes:
+- node_id: 0
+  dedicated_cpus_available: 2
+  hugepages_available_mb: 1024
 hostname: test
 networks:
 - ip: 192.168.1.1
@@ -548,6 +727,24 @@ instances:
   disk_usage_mb: 10
   cpu_usage: 0
   volumes: []
+  guest:
+    os_name: Ubuntu 16.04.2 LTS
+    os_version: 16.04.2 LTS (Xenial Xerus)
+    kernel_release: 4.4.0-62-generic
+    ip_addresses:
+    - 192.168.8.2
+  block_io:
+    read_bytes: 104857600
+    write_bytes: 52428800
+    read_ops: 2560
+    write_ops: 1280
+  network_io:
+    rx_bytes: 1048576
+    rx_packets: 1024
+    rx_dropped: 2
+    tx_bytes: 524288
+    tx_packets: 512
+    tx_dropped: 1
 - instance_uuid: 1f5b2fe6-4493-4561-904a-8f4e956218d9
   state: exited
   ssh_ip: ""
@@ -566,6 +763,8 @@ mem_total_mb: 3896
 mem_available_mb: 3896
 disk_total_mb: 500000
 disk_available_mb: 256000
+disk_reserved_mb: 20000
+disk_used_mb: 4000
 load: 0
 cpus_online: 4
 hostname: test
@@ -587,6 +786,16 @@ const AttachVolumeYaml = `attach_volume:
   workload_agent_uuid: ` + AgentUUID + `
 `
 
+// AttachSharedReadOnlyVolumeYaml is a sample yaml payload for the ssntp Attach
+// Volume command, attaching a shared volume read only.
+const AttachSharedReadOnlyVolumeYaml = `attach_volume:
+  instance_uuid: ` + InstanceUUID + `
+  volume_uuid: ` + VolumeUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  read_only: true
+  shared: true
+`
+
 // BadAttachVolumeYaml is a corrupt yaml payload for the ssntp Attach Volume command.
 const BadAttachVolumeYaml = `attach_volume:
   volume_uuid: ` + VolumeUUID + `
@@ -598,3 +807,124 @@ instance_uuid: ` + InstanceUUID + `
 volume_uuid: ` + VolumeUUID + `
 reason: attach_failure
 `
+
+// DetachVolumeYaml is a sample yaml payload for the ssntp Detach Volume command.
+const DetachVolumeYaml = `detach_volume:
+  instance_uuid: ` + InstanceUUID + `
+  volume_uuid: ` + VolumeUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// BadDetachVolumeYaml is a corrupt yaml payload for the ssntp Detach Volume command.
+const BadDetachVolumeYaml = `detach_volume:
+  volume_uuid: ` + VolumeUUID + `
+`
+
+// DetachVolumeFailureYaml is a sample DetachVolumeFailure ssntp.Error payload for test cases
+const DetachVolumeFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+volume_uuid: ` + VolumeUUID + `
+reason: detach_failure
+`
+
+// VolumeDetachedYaml is a sample VolumeDetached ssntp.Event payload for test cases
+const VolumeDetachedYaml = `volume_detached:
+  instance_uuid: ` + InstanceUUID + `
+  volume_uuid: ` + VolumeUUID + `
+`
+
+// ConsoleLogRequestID is the request ID used in the sample console log payloads
+const ConsoleLogRequestID = "1a7e5c63-0d2b-4b8e-9f5c-2d4e6a8b0c1d"
+
+// GetConsoleLogYaml is a sample yaml payload for the ssntp GetConsoleLog command.
+const GetConsoleLogYaml = `get_console_log:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  lines: 100
+  request_id: ` + ConsoleLogRequestID + `
+`
+
+// ConsoleLogOutput is the sample console log returned in ConsoleLog events
+const ConsoleLogOutput = "Booting from Hard Disk...\nlogin:\n"
+
+// ConsoleLogYaml is a sample ConsoleLog ssntp.Event payload for test cases
+const ConsoleLogYaml = `console_log:
+  instance_uuid: ` + InstanceUUID + `
+  log: |
+    Booting from Hard Disk...
+    login:
+  request_id: ` + ConsoleLogRequestID + `
+`
+
+// ConsoleLogFailureYaml is a sample ConsoleLogFailure ssntp.Error payload for test cases
+const ConsoleLogFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+reason: read_failure
+request_id: ` + ConsoleLogRequestID + `
+`
+
+// ConsoleToken is the token used in the sample console payloads
+const ConsoleToken = "4d6f0c3b8b1e5a7f2c9d0e1f3a5b7c9d"
+
+// ConsoleAddress is the address returned in the sample ConsoleOpened payload
+const ConsoleAddress = "198.51.100.4:40123"
+
+// OpenConsoleYaml is a sample yaml payload for the ssntp OpenConsole command.
+const OpenConsoleYaml = `open_console:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+  token: ` + ConsoleToken + `
+`
+
+// ConsoleOpenedYaml is a sample ConsoleOpened ssntp.Event payload for test cases
+const ConsoleOpenedYaml = `console_opened:
+  instance_uuid: ` + InstanceUUID + `
+  token: ` + ConsoleToken + `
+  address: ` + ConsoleAddress + `
+`
+
+// OpenConsoleFailureYaml is a sample OpenConsoleFailure ssntp.Error payload for test cases
+const OpenConsoleFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+token: ` + ConsoleToken + `
+reason: attach_failure
+`
+
+// PauseYaml is a sample yaml payload for the ssntp PauseInstance command.
+const PauseYaml = `pause:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// UnpauseYaml is a sample yaml payload for the ssntp UnpauseInstance command.
+const UnpauseYaml = `unpause:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// SuspendYaml is a sample yaml payload for the ssntp SuspendInstance command.
+const SuspendYaml = `suspend:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// ResumeYaml is a sample yaml payload for the ssntp ResumeInstance command.
+const ResumeYaml = `resume:
+  instance_uuid: ` + InstanceUUID + `
+  workload_agent_uuid: ` + AgentUUID + `
+`
+
+// PauseFailureYaml is a sample PauseFailure ssntp.Error payload for test cases
+const PauseFailureYaml = `node_uuid: ` + AgentUUID + `
+instance_uuid: ` + InstanceUUID + `
+reason: invalid_state
+`
+
+// NodeInventoryYaml is a sample NodeInventory ssntp.Event payload for test cases
+const NodeInventoryYaml = `node_inventory:
+  node_uuid: ` + AgentUUID + `
+  instances:
+  - instance_uuid: ` + InstanceUUID + `
+    tenant_uuid: ` + TenantUUID + `
+    state: ` + payloads.Running + `
+`
@@ -325,6 +325,79 @@ func getAttachVolumeResult(payload []byte, result *Result) {
 	}
 }
 
+func getDetachVolumeResult(payload []byte, result *Result) {
+	var volCmd payloads.DetachVolume
+
+	err := yaml.Unmarshal(payload, &volCmd)
+	result.Err = err
+	if err == nil {
+		result.NodeUUID = volCmd.Detach.WorkloadAgentUUID
+		result.InstanceUUID = volCmd.Detach.InstanceUUID
+		result.VolumeUUID = volCmd.Detach.VolumeUUID
+	}
+}
+
+func getConsoleLogResult(payload []byte, result *Result) {
+	var cmd payloads.GetConsoleLog
+
+	err := yaml.Unmarshal(payload, &cmd)
+	result.Err = err
+	if err == nil {
+		result.NodeUUID = cmd.ConsoleLog.WorkloadAgentUUID
+		result.InstanceUUID = cmd.ConsoleLog.InstanceUUID
+	}
+}
+
+func getOpenConsoleResult(payload []byte, result *Result) {
+	var cmd payloads.OpenConsole
+
+	err := yaml.Unmarshal(payload, &cmd)
+	result.Err = err
+	if err == nil {
+		result.NodeUUID = cmd.Console.WorkloadAgentUUID
+		result.InstanceUUID = cmd.Console.InstanceUUID
+	}
+}
+
+// unmarshalPauseCmd extracts the PauseCmd from the payload of a
+// PauseInstance, UnpauseInstance, SuspendInstance or ResumeInstance command.
+func unmarshalPauseCmd(command ssntp.Command, payload []byte) (payloads.PauseCmd, error) {
+	var err error
+	var cmd payloads.PauseCmd
+
+	switch command {
+	case ssntp.PauseInstance:
+		var pause payloads.Pause
+		err = yaml.Unmarshal(payload, &pause)
+		cmd = pause.Pause
+	case ssntp.UnpauseInstance:
+		var unpause payloads.Unpause
+		err = yaml.Unmarshal(payload, &unpause)
+		cmd = unpause.Unpause
+	case ssntp.SuspendInstance:
+		var suspend payloads.Suspend
+		err = yaml.Unmarshal(payload, &suspend)
+		cmd = suspend.Suspend
+	case ssntp.ResumeInstance:
+		var resume payloads.Resume
+		err = yaml.Unmarshal(payload, &resume)
+		cmd = resume.Resume
+	default:
+		err = fmt.Errorf("%s is not a pause command", command)
+	}
+
+	return cmd, err
+}
+
+func getPauseResult(command ssntp.Command, payload []byte, result *Result) {
+	cmd, err := unmarshalPauseCmd(command, payload)
+	result.Err = err
+	if err == nil {
+		result.NodeUUID = cmd.WorkloadAgentUUID
+		result.InstanceUUID = cmd.InstanceUUID
+	}
+}
+
 func getStartResults(payload []byte, result *Result) {
 	var startCmd payloads.Start
 	var nn bool
@@ -407,6 +480,19 @@ func (server *SsntpTestServer) CommandNotify(uuid string, command ssntp.Command,
 	case ssntp.AttachVolume:
 		getAttachVolumeResult(payload, &result)
 
+	case ssntp.DetachVolume:
+		getDetachVolumeResult(payload, &result)
+
+	case ssntp.GetConsoleLog:
+		getConsoleLogResult(payload, &result)
+
+	case ssntp.OpenConsole:
+		getOpenConsoleResult(payload, &result)
+
+	case ssntp.PauseInstance, ssntp.UnpauseInstance, ssntp.SuspendInstance,
+		ssntp.ResumeInstance:
+		getPauseResult(command, payload, &result)
+
 	default:
 		fmt.Fprintf(os.Stderr, "server unhandled command %s\n", command.String())
 	}
@@ -439,6 +525,22 @@ func (server *SsntpTestServer) EventNotify(uuid string, event ssntp.Event, frame
 		var stopEvent payloads.EventInstanceStopped
 
 		result.Err = yaml.Unmarshal(payload, &stopEvent)
+	case ssntp.VolumeDetached:
+		var detachedEvent payloads.EventVolumeDetached
+
+		result.Err = yaml.Unmarshal(payload, &detachedEvent)
+	case ssntp.ConsoleLog:
+		var consoleLogEvent payloads.EventConsoleLog
+
+		result.Err = yaml.Unmarshal(payload, &consoleLogEvent)
+	case ssntp.ConsoleOpened:
+		var consoleOpenedEvent payloads.EventConsoleOpened
+
+		result.Err = yaml.Unmarshal(payload, &consoleOpenedEvent)
+	case ssntp.NodeInventory:
+		var inventoryEvent payloads.EventNodeInventory
+
+		result.Err = yaml.Unmarshal(payload, &inventoryEvent)
 	case ssntp.ConcentratorInstanceAdded:
 		// forward rule auto-sends to controllers
 	case ssntp.TenantAdded:
@@ -601,6 +703,89 @@ func (server *SsntpTestServer) handleAttachVolume(payload []byte) ssntp.ForwardD
 	return dest
 }
 
+func (server *SsntpTestServer) handleDetachVolume(payload []byte) ssntp.ForwardDestination {
+	var cmd payloads.DetachVolume
+	var dest ssntp.ForwardDestination
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		return dest
+	}
+
+	server.clientsLock.Lock()
+	defer server.clientsLock.Unlock()
+
+	for _, c := range server.clients {
+		if c == cmd.Detach.WorkloadAgentUUID {
+			dest.AddRecipient(c)
+		}
+	}
+
+	return dest
+}
+
+func (server *SsntpTestServer) handleGetConsoleLog(payload []byte) ssntp.ForwardDestination {
+	var cmd payloads.GetConsoleLog
+	var dest ssntp.ForwardDestination
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		return dest
+	}
+
+	server.clientsLock.Lock()
+	defer server.clientsLock.Unlock()
+
+	for _, c := range server.clients {
+		if c == cmd.ConsoleLog.WorkloadAgentUUID {
+			dest.AddRecipient(c)
+		}
+	}
+
+	return dest
+}
+
+func (server *SsntpTestServer) handleOpenConsole(payload []byte) ssntp.ForwardDestination {
+	var cmd payloads.OpenConsole
+	var dest ssntp.ForwardDestination
+
+	err := yaml.Unmarshal(payload, &cmd)
+	if err != nil {
+		return dest
+	}
+
+	server.clientsLock.Lock()
+	defer server.clientsLock.Unlock()
+
+	for _, c := range server.clients {
+		if c == cmd.Console.WorkloadAgentUUID {
+			dest.AddRecipient(c)
+		}
+	}
+
+	return dest
+}
+
+func (server *SsntpTestServer) handlePause(command ssntp.Command, payload []byte) ssntp.ForwardDestination {
+	var dest ssntp.ForwardDestination
+
+	cmd, err := unmarshalPauseCmd(command, payload)
+	if err != nil {
+		return dest
+	}
+
+	server.clientsLock.Lock()
+	defer server.clientsLock.Unlock()
+
+	for _, c := range server.clients {
+		if c == cmd.WorkloadAgentUUID {
+			dest.AddRecipient(c)
+		}
+	}
+
+	return dest
+}
+
 // CommandForward implements an SSNTP CommandForward callback for SsntpTestServer
 func (server *SsntpTestServer) CommandForward(uuid string, command ssntp.Command, frame *ssntp.Frame) (dest ssntp.ForwardDestination) {
 	payload := frame.Payload
@@ -610,6 +795,15 @@ func (server *SsntpTestServer) CommandForward(uuid string, command ssntp.Command
 		dest = server.handleStart(payload)
 	case ssntp.AttachVolume:
 		dest = server.handleAttachVolume(payload)
+	case ssntp.DetachVolume:
+		dest = server.handleDetachVolume(payload)
+	case ssntp.GetConsoleLog:
+		dest = server.handleGetConsoleLog(payload)
+	case ssntp.OpenConsole:
+		dest = server.handleOpenConsole(payload)
+	case ssntp.PauseInstance, ssntp.UnpauseInstance, ssntp.SuspendInstance,
+		ssntp.ResumeInstance:
+		dest = server.handlePause(command, payload)
 	case ssntp.EVACUATE:
 		fallthrough
 	case ssntp.DELETE:
@@ -685,6 +879,50 @@ func StartTestServer() *SsntpTestServer {
 				Operand: ssntp.AttachVolumeFailure,
 				Dest:    ssntp.Controller,
 			},
+			{ // all DetachVolumeFailure errors go to all Controllers
+				Operand: ssntp.DetachVolumeFailure,
+				Dest:    ssntp.Controller,
+			},
+			{ // all VolumeDetached events go to all Controllers
+				Operand: ssntp.VolumeDetached,
+				Dest:    ssntp.Controller,
+			},
+			{ // all ConsoleLogFailure errors go to all Controllers
+				Operand: ssntp.ConsoleLogFailure,
+				Dest:    ssntp.Controller,
+			},
+			{ // all ConsoleLog events go to all Controllers
+				Operand: ssntp.ConsoleLog,
+				Dest:    ssntp.Controller,
+			},
+			{ // all OpenConsoleFailure errors go to all Controllers
+				Operand: ssntp.OpenConsoleFailure,
+				Dest:    ssntp.Controller,
+			},
+			{ // all ConsoleOpened events go to all Controllers
+				Operand: ssntp.ConsoleOpened,
+				Dest:    ssntp.Controller,
+			},
+			{ // all NodeInventory events go to all Controllers
+				Operand: ssntp.NodeInventory,
+				Dest:    ssntp.Controller,
+			},
+			{ // all PauseFailure errors go to all Controllers
+				Operand: ssntp.PauseFailure,
+				Dest:    ssntp.Controller,
+			},
+			{ // all UnpauseFailure errors go to all Controllers
+				Operand: ssntp.UnpauseFailure,
+				Dest:    ssntp.Controller,
+			},
+			{ // all SuspendFailure errors go to all Controllers
+				Operand: ssntp.SuspendFailure,
+				Dest:    ssntp.Controller,
+			},
+			{ // all ResumeFailure errors go to all Controllers
+				Operand: ssntp.ResumeFailure,
+				Dest:    ssntp.Controller,
+			},
 			{ // all PublicIPAssigned events go to all Controllers
 				Operand: ssntp.PublicIPAssigned,
 				Dest:    ssntp.Controller,
@@ -713,6 +951,34 @@ func StartTestServer() *SsntpTestServer {
 				Operand:        ssntp.AttachVolume,
 				CommandForward: server,
 			},
+			{ // all DetachVolume commands are processed by the Command forwarder
+				Operand:        ssntp.DetachVolume,
+				CommandForward: server,
+			},
+			{ // all GetConsoleLog commands are processed by the Command forwarder
+				Operand:        ssntp.GetConsoleLog,
+				CommandForward: server,
+			},
+			{ // all OpenConsole commands are processed by the Command forwarder
+				Operand:        ssntp.OpenConsole,
+				CommandForward: server,
+			},
+			{ // all PauseInstance commands are processed by the Command forwarder
+				Operand:        ssntp.PauseInstance,
+				CommandForward: server,
+			},
+			{ // all UnpauseInstance commands are processed by the Command forwarder
+				Operand:        ssntp.UnpauseInstance,
+				CommandForward: server,
+			},
+			{ // all SuspendInstance commands are processed by the Command forwarder
+				Operand:        ssntp.SuspendInstance,
+				CommandForward: server,
+			},
+			{ // all ResumeInstance commands are processed by the Command forwarder
+				Operand:        ssntp.ResumeInstance,
+				CommandForward: server,
+			},
 		},
 	}
 